@@ -0,0 +1,812 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/openshift/hypershift-oadp-plugin/pkg/backupsummary"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	plugtypes "github.com/openshift/hypershift-oadp-plugin/pkg/core/types"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/endpoints"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/guestsnapshot"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/hooks"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/notify"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/resourcemodifiers"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/secretcheck"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/tracing"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/volumebackup"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/label"
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// newClient returns the client of the cluster the commands act on, set with --kubeconfig
+// and --context. Tests replace it with a fake client.
+var newClient = common.GetClient
+
+const (
+	// backupCommand backs up a HostedCluster from outside of Velero, e.g.:
+	//
+	//	hypershift-oadp-plugin backup --hc clusters/my-hc --kubeconfig ~/.kube/mgmt
+	backupCommand = "backup"
+
+	// unpauseRestoreCommand resumes a HostedCluster restored with the restorePaused option.
+	// It runs from the plugin binary, e.g. inside the Velero pod:
+	//
+	//	/plugins/hypershift-oadp-plugin unpause-restore --namespace clusters --name my-hc
+	unpauseRestoreCommand = "unpause-restore"
+
+	// unprotectRestoreCommand lifts the deletion protection the deletionProtection option put
+	// on a restored HostedCluster and its etcd PVCs, e.g.:
+	//
+	//	/plugins/hypershift-oadp-plugin unprotect-restore --namespace clusters --name my-hc
+	unprotectRestoreCommand = "unprotect-restore"
+
+	// verifyRestoreCommand checks the critical Secrets and the load balancer endpoints of a
+	// restored HostedCluster, e.g.:
+	//
+	//	/plugins/hypershift-oadp-plugin verify-restore --namespace clusters --name my-hc --restore my-restore
+	verifyRestoreCommand = "verify-restore"
+
+	// migrationModifiersCommand generates the Velero resource modifiers rewriting a
+	// HostedCluster for a migration, read from the source cluster, e.g.:
+	//
+	//	hypershift-oadp-plugin migration-modifiers --hc clusters/my-hc --infra-id my-hc-x7k2p \
+	//		--target-kubeconfig ~/.kube/target --backup my-backup
+	migrationModifiersCommand = "migration-modifiers"
+)
+
+// isCLICommand reports whether the first argument of the binary selects a CLI subcommand
+// rather than the plugin server started by Velero.
+func isCLICommand(arg string) bool {
+	switch arg {
+	case backupCommand, unpauseRestoreCommand, unprotectRestoreCommand, verifyRestoreCommand, migrationModifiersCommand, notify.Command, "help", "-h", "--help":
+		return true
+	}
+	return false
+}
+
+func newCLI() *cobra.Command {
+	var kubeconfig, kubeContext string
+	root := &cobra.Command{
+		Use:          "hypershift-oadp-plugin",
+		Short:        "Velero plugin for HyperShift hosted control planes, and its companion commands",
+		SilenceUsage: true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			common.SetKubeconfig(kubeconfig, kubeContext)
+		},
+	}
+	root.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "path to the kubeconfig of the cluster, when running outside of it")
+	root.PersistentFlags().StringVar(&kubeContext, "context", "", "kubeconfig context to use")
+
+	root.AddCommand(newBackupCommand(), newUnpauseRestoreCommand(), newUnprotectRestoreCommand(), newVerifyRestoreCommand(), newMigrationModifiersCommand(), newNotifyCommand())
+	return root
+}
+
+func newBackupCommand() *cobra.Command {
+	var (
+		hostedCluster   string
+		name            string
+		storageLocation string
+		veleroNamespace string
+		ttl             time.Duration
+		fsBackup        bool
+		timeout         time.Duration
+		interval        time.Duration
+		uploadRetries   int
+	)
+	cmd := &cobra.Command{
+		Use:   backupCommand,
+		Short: "Pause a HostedCluster, back it up with Velero and resume it",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			namespace, hcName, ok := strings.Cut(hostedCluster, "/")
+			if !ok || namespace == "" || hcName == "" {
+				return fmt.Errorf("--hc must be in the namespace/name form, got %q", hostedCluster)
+			}
+			if name == "" {
+				name = label.GetValidName(fmt.Sprintf("%s-%s", hcName, time.Now().UTC().Format("20060102150405")))
+			}
+			var defaultVolumesToFsBackup *bool
+			if cmd.Flags().Changed("fs-backup") {
+				defaultVolumesToFsBackup = &fsBackup
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			// Spans are exported when the OTEL_EXPORTER_OTLP_ENDPOINT variable is set
+			if err := tracing.Setup(ctx, ""); err != nil {
+				fmt.Fprintf(os.Stderr, "error configuring tracing, spans will not be exported: %v\n", err)
+			}
+			ctx, span := tracing.Start(ctx, "backup", attribute.String("hostedCluster", hostedCluster))
+			defer func() {
+				tracing.End(span, err)
+				tracing.Flush(context.Background())
+			}()
+
+			client, err := newClient()
+			if err != nil {
+				return fmt.Errorf("error recovering the k8s client: %w", err)
+			}
+			if veleroNamespace == "" {
+				if veleroNamespace, err = common.GetCurrentNamespace(); err != nil {
+					return fmt.Errorf("error getting the Velero namespace, set --velero-namespace: %w", err)
+				}
+			}
+
+			hc := &hyperv1.HostedCluster{}
+			if err := client.Get(ctx, crclient.ObjectKey{Name: hcName, Namespace: namespace}, hc); err != nil {
+				return fmt.Errorf("error getting HostedCluster %s: %w", hostedCluster, err)
+			}
+			if !cmd.Flags().Changed("name") {
+				running, err := common.RunningBackupPausedFor(ctx, client, hc, veleroNamespace)
+				if err != nil {
+					return err
+				}
+				if running != "" {
+					fmt.Printf("Resuming Backup %s/%s started by an earlier run\n", veleroNamespace, running)
+					name = running
+				}
+			}
+			backup := &velerov1.Backup{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: veleroNamespace},
+				Spec: velerov1.BackupSpec{
+					IncludedNamespaces:       []string{namespace, common.GetHCPNamespace(hcName, namespace)},
+					IncludedResources:        plugtypes.PlatformResources([]hyperv1.PlatformType{hc.Spec.Platform.Type}),
+					StorageLocation:          storageLocation,
+					TTL:                      metav1.Duration{Duration: ttl},
+					DefaultVolumesToFsBackup: defaultVolumesToFsBackup,
+				},
+			}
+
+			config, err := getPluginConfig(ctx, client, veleroNamespace)
+			if err != nil {
+				return err
+			}
+			timeouts, err := common.ParseTimeouts(config[common.ConfigKeyTimeouts])
+			if err != nil {
+				return err
+			}
+			if config[common.ConfigKeyBackupCRDs] == "true" {
+				// The plugin adds the CRDs, Velero only backs up those it is allowed to
+				backup.Spec.IncludedResources = append(backup.Spec.IncludedResources, "customresourcedefinitions.apiextensions.k8s.io")
+			}
+			return runBackupWithRetries(ctx, client, common.ClientPauser{Client: client}, backup, namespace, hcName, config, timeouts, interval, uploadRetries)
+		},
+	}
+	cmd.Flags().StringVar(&hostedCluster, "hc", "", "HostedCluster to back up, as namespace/name")
+	cmd.Flags().StringVar(&name, "name", "", "name of the Velero Backup, <hc>-<timestamp> by default")
+	cmd.Flags().StringVar(&storageLocation, "storage-location", "", "BackupStorageLocation of the backup, the default one when empty")
+	cmd.Flags().StringVar(&veleroNamespace, "velero-namespace", "", "namespace Velero runs in, the current namespace by default")
+	cmd.Flags().DurationVar(&ttl, "ttl", 0, "how long the backup is kept, the Velero default when zero")
+	cmd.Flags().BoolVar(&fsBackup, "fs-backup", false, "back up the volumes with the node-agent rather than with CSI snapshots")
+	cmd.Flags().DurationVar(&timeout, "timeout", time.Hour, "how long to wait for the backup to finish, 0 for no limit")
+	cmd.Flags().DurationVar(&interval, "interval", 10*time.Second, "how often to check whether the backup finished")
+	cmd.Flags().IntVar(&uploadRetries, "upload-retries", 0, "how many times to run a new backup when only DataUploads failed")
+	_ = cmd.MarkFlagRequired("hc")
+	return cmd
+}
+
+// runBackupWithRetries runs the Backup with runPausedBackup and, as long as only its
+// DataUploads failed, up to uploadRetries new backups of the same spec.
+func runBackupWithRetries(ctx context.Context, client crclient.Client, pauser common.Pauser, backup *velerov1.Backup, namespace, hcName string, config map[string]string, timeouts common.Timeouts, interval time.Duration, uploadRetries int) error {
+	name := backup.Name
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			// Velero cannot run a single DataUpload again, the whole backup is run again under
+			// a new name, with the cluster paused anew so the volumes match the etcd snapshot
+			backup = &velerov1.Backup{
+				ObjectMeta: metav1.ObjectMeta{Name: label.GetValidName(fmt.Sprintf("%s-retry-%d", name, attempt)), Namespace: backup.Namespace},
+				Spec:       backup.Spec,
+			}
+		}
+		progress, err := runPausedBackup(ctx, client, pauser, backup, namespace, hcName, config, timeouts, interval)
+		if err == nil || attempt >= uploadRetries || !uploadsFailed(progress) {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%v, retrying the failed uploads with a new backup (%d/%d)\n", err, attempt+1, uploadRetries)
+	}
+}
+
+// runPausedBackup pauses the HostedCluster, runs the Backup and resumes the cluster, also
+// when the backup fails or the command is interrupted. It returns the volume backups of the
+// Backup once it finished.
+func runPausedBackup(ctx context.Context, client crclient.Client, pauser common.Pauser, backup *velerov1.Backup, namespace, hcName string, config map[string]string, timeouts common.Timeouts, interval time.Duration) (progress volumebackup.Progress, err error) {
+	hostedCluster := namespace + "/" + hcName
+	// Velero may run elsewhere than where its Backups are created
+	veleroNamespace := cmp.Or(config[common.ConfigKeyVeleroNamespace], backup.Namespace)
+	// The plugin only sees the cluster once paused, the hooks of the pause fire here
+	if err := runBeforePauseHooks(ctx, client, veleroNamespace, config, backup.Name, namespace, hcName); err != nil {
+		return progress, err
+	}
+	pausedElsewhere, err := pauser.Pause(ctx, namespace, hcName, backup.Name)
+	if err != nil {
+		return progress, err
+	}
+	pausedAt := time.Now()
+	var finished *velerov1.Backup
+	defer func() {
+		// The backup context may be cancelled or expired by now
+		resumeCtx := context.WithoutCancel(ctx)
+		pausedElsewhere, resumeErr := pauser.Unpause(resumeCtx, namespace, hcName)
+		if resumeErr != nil {
+			fmt.Fprintf(os.Stderr, "error resuming HostedCluster %s: %v\n", hostedCluster, resumeErr)
+			return
+		}
+		if finished != nil {
+			if err := backupsummary.Record(resumeCtx, client, veleroNamespace, finished, time.Since(pausedAt)); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+		for _, obj := range pausedElsewhere {
+			fmt.Printf("%s was paused by someone else during the backup, leaving it paused\n", obj)
+		}
+		// A cluster still frozen after a successful backup fails the command
+		if resumeErr = pauser.WaitForUnpaused(resumeCtx, namespace, hcName, timeouts); resumeErr != nil {
+			fmt.Fprintf(os.Stderr, "error waiting for HostedCluster %s to resume: %v\n", hostedCluster, resumeErr)
+			if err == nil {
+				err = resumeErr
+			}
+			return
+		}
+		fmt.Printf("HostedCluster %s resumed from the backup pause\n", hostedCluster)
+	}()
+	for _, obj := range pausedElsewhere {
+		fmt.Printf("%s was already paused by someone else, leaving it paused\n", obj)
+	}
+	// Backing up before cluster-api stopped would capture Machines it still changes
+	if err := pauser.WaitForPaused(ctx, namespace, hcName, timeouts); err != nil {
+		return progress, fmt.Errorf("error waiting for the pause of HostedCluster %s to propagate: %w", hostedCluster, err)
+	}
+	fmt.Printf("HostedCluster %s paused\n", hostedCluster)
+
+	// The etcd volumes are known before Velero creates their volume backups
+	var expected []string
+	if config[common.ConfigKeyEtcdBackupMethod] != common.EtcdBackupMethodEtcdSnapshot {
+		if expected, err = volumebackup.ExpectedPVCs(ctx, client, common.GetHCPNamespace(hcName, namespace)); err != nil {
+			return progress, err
+		}
+	}
+
+	if err := client.Create(ctx, backup); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return progress, fmt.Errorf("error creating Backup %s/%s: %w", backup.Namespace, backup.Name, err)
+		}
+		if err := client.Get(ctx, crclient.ObjectKeyFromObject(backup), backup); err != nil {
+			return progress, fmt.Errorf("error getting Backup %s/%s: %w", backup.Namespace, backup.Name, err)
+		}
+	}
+	fmt.Printf("Waiting for Backup %s/%s\n", backup.Namespace, backup.Name)
+
+	reportCtx, stopReport := context.WithCancel(ctx)
+	go reportVolumeProgress(reportCtx, client, veleroNamespace, backup, expected, interval)
+	notification, err := notify.WaitForCompletion(ctx, client, notify.OperationBackup, backup.Namespace, backup.Name, interval)
+	stopReport()
+	if err != nil {
+		return progress, fmt.Errorf("error waiting for Backup %s/%s: %w", backup.Namespace, backup.Name, err)
+	}
+	completed := &velerov1.Backup{}
+	if err := client.Get(ctx, crclient.ObjectKeyFromObject(backup), completed); err != nil {
+		return progress, fmt.Errorf("error getting Backup %s/%s: %w", backup.Namespace, backup.Name, err)
+	}
+	finished = completed
+	progress, err = volumebackup.Get(ctx, client, veleroNamespace, finished, expected)
+	if err != nil {
+		return progress, err
+	}
+	for _, volume := range progress.Unsuccessful() {
+		fmt.Fprintln(os.Stderr, volume)
+	}
+	// CSI snapshots kept without the data mover have no volume backup to account for
+	missing := progress.Missing()
+	for _, pvc := range missing {
+		fmt.Fprintf(os.Stderr, "No DataUpload or PodVolumeBackup of PVC %s, check it was snapshotted\n", pvc)
+	}
+	if !notification.Succeeded {
+		if reason, ok := finished.Annotations[common.PauseWindowExceededAnnotation]; ok {
+			fmt.Fprintln(os.Stderr, reason)
+		}
+		return progress, fmt.Errorf("backup %s/%s finished in phase %s with %d errors", backup.Namespace, backup.Name, notification.Phase, notification.Errors)
+	}
+	// Every volume of the control plane, not only the first, has to be backed up
+	if progress.Completed()+len(missing) < len(progress.Volumes) {
+		return progress, fmt.Errorf("backup %s/%s completed with %s", backup.Namespace, backup.Name, progress)
+	}
+	fmt.Printf("Backup %s/%s %s in %s, %s\n", backup.Namespace, backup.Name, notification.Phase, notification.Duration, progress)
+	return progress, nil
+}
+
+// uploadsFailed reports whether the backup failed only because of DataUploads, which a new
+// backup may retry past a transient node-agent or storage error.
+func uploadsFailed(progress volumebackup.Progress) bool {
+	unsuccessful := progress.Unsuccessful()
+	for _, volume := range unsuccessful {
+		if volume.Kind != volumebackup.KindDataUpload {
+			return false
+		}
+	}
+	return len(unsuccessful) > 0
+}
+
+func newUnpauseRestoreCommand() *cobra.Command {
+	var (
+		namespace   string
+		name        string
+		restoreName string
+		capiTimeout time.Duration
+		reconcile   bool
+	)
+	cmd := &cobra.Command{
+		Use:   unpauseRestoreCommand,
+		Short: "Resume a HostedCluster restored with the restorePaused option",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient()
+			if err != nil {
+				return fmt.Errorf("error recovering the k8s client: %w", err)
+			}
+			ctx := context.Background()
+			ns, config, err := loadPluginConfig(ctx, client)
+			if err != nil {
+				return err
+			}
+			pausedKinds, err := common.ParsePausedKinds(config[common.ConfigKeyPausedKinds])
+			if err != nil {
+				return err
+			}
+			timeouts, err := common.ParseTimeouts(config[common.ConfigKeyTimeouts])
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("capi-timeout") {
+				timeouts.CAPIProviders = capiTimeout
+			}
+			if restoreName != "" {
+				if err := checkVolumeRestores(ctx, client, cmp.Or(config[common.ConfigKeyVeleroNamespace], ns), ns, restoreName); err != nil {
+					return err
+				}
+			}
+			if err := common.UnpauseRestoredCluster(ctx, client, namespace, name, timeouts, pausedKinds); err != nil {
+				return err
+			}
+			fmt.Printf("HostedCluster %s/%s resumed\n", namespace, name)
+			if reconcile || config[common.ConfigKeyReconcileAfterUnpause] == "true" {
+				if err := common.RequestReconcile(ctx, client, namespace, name, time.Now()); err != nil {
+					return err
+				}
+				fmt.Printf("HostedCluster %s/%s reconciliation requested\n", namespace, name)
+			}
+
+			return runUnpauseHooks(ctx, client, ns, config, namespace, name)
+		},
+	}
+	cmd.Flags().StringVar(&namespace, "namespace", "", "namespace of the restored HostedCluster")
+	cmd.Flags().StringVar(&name, "name", "", "name of the restored HostedCluster")
+	cmd.Flags().StringVar(&restoreName, "restore", "", "Velero Restore of the HostedCluster, whose volumes must be restored before the cluster resumes")
+	cmd.Flags().BoolVar(&reconcile, "reconcile", false, "request the reconciliation of the HostedCluster once resumed, as the reconcileAfterUnpause option does")
+	cmd.Flags().DurationVar(&capiTimeout, "capi-timeout", common.DefaultTimeouts.CAPIProviders, "how long to wait for the cluster-api deployments to become available, overriding the capiProviders timeout")
+	_ = cmd.MarkFlagRequired("namespace")
+	_ = cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func newUnprotectRestoreCommand() *cobra.Command {
+	var (
+		namespace string
+		name      string
+		force     bool
+	)
+	cmd := &cobra.Command{
+		Use:   unprotectRestoreCommand,
+		Short: "Lift the deletion protection of a HostedCluster restored with the deletionProtection option",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient()
+			if err != nil {
+				return fmt.Errorf("error recovering the k8s client: %w", err)
+			}
+			lifted, err := common.RemoveDeletionProtection(context.Background(), client, namespace, name, time.Now(), force)
+			for _, object := range lifted {
+				fmt.Printf("Deletion protection lifted from %s\n", object)
+			}
+			if err != nil {
+				return err
+			}
+			if len(lifted) == 0 {
+				fmt.Printf("HostedCluster %s/%s is not protected from deletion\n", namespace, name)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&namespace, "namespace", "", "namespace of the restored HostedCluster")
+	cmd.Flags().StringVar(&name, "name", "", "name of the restored HostedCluster")
+	cmd.Flags().BoolVar(&force, "force", false, "lift the protection before its grace period has ended")
+	_ = cmd.MarkFlagRequired("namespace")
+	_ = cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func newVerifyRestoreCommand() *cobra.Command {
+	var (
+		namespace       string
+		name            string
+		restore         string
+		endpointTimeout time.Duration
+		guest           bool
+	)
+	cmd := &cobra.Command{
+		Use:   verifyRestoreCommand,
+		Short: "Check that the critical Secrets of a restored HostedCluster are well formed and report its new endpoints",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient()
+			if err != nil {
+				return fmt.Errorf("error recovering the k8s client: %w", err)
+			}
+			ctx := context.Background()
+			ns, config, err := loadPluginConfig(ctx, client)
+			if err != nil {
+				return err
+			}
+			timeouts, err := common.ParseTimeouts(config[common.ConfigKeyTimeouts])
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("endpoint-timeout") {
+				timeouts.LoadBalancers = endpointTimeout
+			}
+			hc := &hyperv1.HostedCluster{}
+			if err := client.Get(ctx, crclient.ObjectKey{Namespace: namespace, Name: name}, hc); err != nil {
+				return fmt.Errorf("error getting HostedCluster %s/%s: %w", namespace, name, err)
+			}
+
+			results := secretcheck.Verify(ctx, client, hc, time.Now())
+			for _, result := range results {
+				fmt.Println(result)
+			}
+			// Restored LoadBalancer Services get new addresses from the target cloud provider
+			endpointResults, err := endpoints.Verify(ctx, client, hc, timeouts)
+			if err != nil {
+				return err
+			}
+			for _, result := range endpointResults {
+				fmt.Println(result)
+			}
+			results = append(results, endpointResults...)
+			if guest {
+				guestResults, err := verifyGuest(ctx, client, hc)
+				if err != nil {
+					return err
+				}
+				for _, result := range guestResults {
+					fmt.Println(result)
+				}
+				results = append(results, guestResults...)
+			}
+			if restore != "" {
+				veleroRestore := &velerov1.Restore{}
+				if err := client.Get(ctx, crclient.ObjectKey{Namespace: ns, Name: restore}, veleroRestore); err != nil {
+					return fmt.Errorf("error getting Restore %s/%s: %w", ns, restore, err)
+				}
+				report, err := secretcheck.Save(ctx, client, veleroRestore, results)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Report saved in ConfigMap %s/%s\n", ns, report)
+			}
+
+			if !secretcheck.Passed(results) {
+				return fmt.Errorf("secrets, endpoints or guest cluster of HostedCluster %s/%s failed verification", namespace, name)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&namespace, "namespace", "", "namespace of the restored HostedCluster")
+	cmd.Flags().StringVar(&name, "name", "", "name of the restored HostedCluster")
+	cmd.Flags().StringVar(&restore, "restore", "", "Velero Restore to save the report for, in the current namespace")
+	cmd.Flags().BoolVar(&guest, "guest", false, "compare the Nodes and ClusterOperators of the hosted cluster with the guest snapshot of the backup")
+	cmd.Flags().DurationVar(&endpointTimeout, "endpoint-timeout", common.DefaultTimeouts.LoadBalancers, "how long to wait for the load balancers of the control plane to get an address, overriding the loadBalancers timeout")
+	_ = cmd.MarkFlagRequired("namespace")
+	_ = cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+// verifyGuest compares the restored hosted cluster with the guest snapshot of its backup. A
+// hosted cluster that cannot be reached fails the check, a backup without snapshot too.
+func verifyGuest(ctx context.Context, client crclient.Client, hc *hyperv1.HostedCluster) ([]secretcheck.Result, error) {
+	hcpNamespace := common.GetHCPNamespace(hc.Name, hc.Namespace)
+	result := secretcheck.Result{Check: fmt.Sprintf("guest snapshot %s/%s", hcpNamespace, guestsnapshot.ConfigMapName)}
+	saved, err := guestsnapshot.Load(ctx, client, hcpNamespace)
+	if err != nil {
+		return nil, err
+	}
+	if saved == nil {
+		result.Message = fmt.Sprintf("not found, back up with %s=true to verify the guest cluster", common.ConfigKeyGuestSnapshot)
+		return []secretcheck.Result{result}, nil
+	}
+	guest, err := guestsnapshot.NewGuestClient(ctx, client, hcpNamespace)
+	if err != nil {
+		result.Message = err.Error()
+		return []secretcheck.Result{result}, nil
+	}
+	return guestsnapshot.Verify(ctx, saved, guest), nil
+}
+
+func newMigrationModifiersCommand() *cobra.Command {
+	var (
+		hostedCluster    string
+		opts             resourcemodifiers.Options
+		targetKubeconfig string
+		targetContext    string
+		veleroNamespace  string
+		backup           string
+		restore          string
+	)
+	cmd := &cobra.Command{
+		Use:   migrationModifiersCommand,
+		Short: "Generate the Velero resource modifiers rewriting a HostedCluster restored on another management cluster",
+		Long: `Generate the Velero resource modifiers rewriting the infraID, region and service hostnames of a
+HostedCluster and its HostedControlPlane for a migration restore. The HostedCluster is read from
+the source cluster. The ConfigMap is printed, or registered on the target cluster when
+--target-kubeconfig is set, along with the Restore of --backup referencing it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, hcName, ok := strings.Cut(hostedCluster, "/")
+			if !ok || namespace == "" || hcName == "" {
+				return fmt.Errorf("--hc must be in the namespace/name form, got %q", hostedCluster)
+			}
+			if backup != "" && targetKubeconfig == "" {
+				return fmt.Errorf("--backup requires --target-kubeconfig")
+			}
+
+			client, err := newClient()
+			if err != nil {
+				return fmt.Errorf("error recovering the k8s client: %w", err)
+			}
+			ctx := context.Background()
+			hc := &hyperv1.HostedCluster{}
+			if err := client.Get(ctx, crclient.ObjectKey{Namespace: namespace, Name: hcName}, hc); err != nil {
+				return fmt.Errorf("error getting HostedCluster %s: %w", hostedCluster, err)
+			}
+			rules, err := resourcemodifiers.NewRules(hc, opts)
+			if err != nil {
+				return err
+			}
+			cm, err := resourcemodifiers.NewConfigMap(hc, veleroNamespace, rules)
+			if err != nil {
+				return err
+			}
+
+			if targetKubeconfig == "" {
+				out, err := yaml.Marshal(cm)
+				if err != nil {
+					return fmt.Errorf("error encoding ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+				}
+				fmt.Print(string(out))
+				return nil
+			}
+
+			// The source cluster is no longer needed, switch the client to the target
+			common.SetKubeconfig(targetKubeconfig, targetContext)
+			target, err := newClient()
+			if err != nil {
+				return fmt.Errorf("error recovering the k8s client of the target cluster: %w", err)
+			}
+			ref, err := resourcemodifiers.Register(ctx, target, cm)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Resource modifiers registered in ConfigMap %s/%s\n", cm.Namespace, cm.Name)
+			if backup == "" {
+				fmt.Printf("Set spec.resourceModifier of the Restore to {kind: %s, name: %s}\n", ref.Kind, ref.Name)
+				return nil
+			}
+
+			if restore == "" {
+				restore = label.GetValidName(fmt.Sprintf("%s-%s", backup, time.Now().UTC().Format("20060102150405")))
+			}
+			veleroRestore := &velerov1.Restore{
+				ObjectMeta: metav1.ObjectMeta{Name: restore, Namespace: veleroNamespace},
+				Spec: velerov1.RestoreSpec{
+					BackupName:       backup,
+					ResourceModifier: ref,
+				},
+			}
+			if err := target.Create(ctx, veleroRestore); err != nil {
+				return fmt.Errorf("error creating Restore %s/%s: %w", veleroNamespace, restore, err)
+			}
+			fmt.Printf("Restore %s/%s of Backup %s created\n", veleroNamespace, restore, backup)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&hostedCluster, "hc", "", "HostedCluster to migrate, as namespace/name, read from the source cluster")
+	cmd.Flags().StringVar(&opts.InfraID, "infra-id", "", "infraID of the HostedCluster on the target")
+	cmd.Flags().StringVar(&opts.Region, "region", "", "AWS region or Azure location of the target")
+	cmd.Flags().StringToStringVar(&opts.Hosts, "host", nil, "hostname of a published service on the source mapped to the target one, as source=target, repeatable")
+	cmd.Flags().StringVar(&targetKubeconfig, "target-kubeconfig", "", "kubeconfig of the target management cluster to register the ConfigMap on, printed when empty")
+	cmd.Flags().StringVar(&targetContext, "target-context", "", "kubeconfig context of the target management cluster")
+	cmd.Flags().StringVar(&veleroNamespace, "velero-namespace", "openshift-adp", "namespace Velero runs in on the target")
+	cmd.Flags().StringVar(&backup, "backup", "", "Backup to restore with the resource modifiers, the Restore is not created when empty")
+	cmd.Flags().StringVar(&restore, "restore", "", "name of the Velero Restore, <backup>-<timestamp> by default")
+	_ = cmd.MarkFlagRequired("hc")
+	return cmd
+}
+
+// runBeforePauseHooks fires the beforePause hook configured in the plugin ConfigMap, if any,
+// before the backup command pauses the HostedCluster for the backup.
+func runBeforePauseHooks(ctx context.Context, client crclient.Client, ns string, config map[string]string, backup, namespace, name string) error {
+	runner, err := hooks.NewRunner(config, client, ns, configureLogger(logrus.New()))
+	if err != nil {
+		return fmt.Errorf("error configuring hooks: %w", err)
+	}
+	return runner.Run(ctx, hooks.Payload{
+		Event:                  hooks.BeforePause,
+		Backup:                 backup,
+		HostedClusterName:      name,
+		HostedClusterNamespace: namespace,
+		ControlPlaneNamespace:  common.GetHCPNamespace(name, namespace),
+	})
+}
+
+// runUnpauseHooks fires the afterUnpause hook configured in the plugin ConfigMap, if any.
+func runUnpauseHooks(ctx context.Context, client crclient.Client, ns string, config map[string]string, namespace, name string) error {
+	runner, err := hooks.NewRunner(config, client, ns, configureLogger(logrus.New()))
+	if err != nil {
+		return fmt.Errorf("error configuring hooks: %w", err)
+	}
+	return runner.Run(ctx, hooks.Payload{
+		Event:                  hooks.AfterUnpause,
+		HostedClusterName:      name,
+		HostedClusterNamespace: namespace,
+	})
+}
+
+func newNotifyCommand() *cobra.Command {
+	var (
+		backup        string
+		restore       string
+		hostedCluster string
+		interval      time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   notify.Command,
+		Short: "Wait for a Velero Backup or Restore and report its outcome to the notification webhook",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			operation, name := notify.OperationBackup, backup
+			if restore != "" {
+				operation, name = notify.OperationRestore, restore
+			}
+
+			client, err := newClient()
+			if err != nil {
+				return fmt.Errorf("error recovering the k8s client: %w", err)
+			}
+			ctx := context.Background()
+			ns, config, err := loadPluginConfig(ctx, client)
+			if err != nil {
+				return err
+			}
+			notifier, err := notify.NewNotifier(config)
+			if err != nil {
+				return err
+			}
+			if notifier == nil {
+				return fmt.Errorf("%s is not set in the plugin configuration", common.ConfigKeyNotificationWebhookURL)
+			}
+
+			notification, err := notify.WaitForCompletion(ctx, client, operation, ns, name, interval)
+			if err != nil {
+				return err
+			}
+			notification.HostedCluster = hostedCluster
+			if operation == notify.OperationBackup {
+				recordBackupSummary(ctx, client, ns, name, config)
+			}
+			if err := notifier.Send(ctx, *notification); err != nil {
+				return err
+			}
+
+			fmt.Printf("Notified %s %s %s\n", operation, name, notification.Phase)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&backup, notify.OperationBackup, "", "name of the Velero Backup to report")
+	cmd.Flags().StringVar(&restore, notify.OperationRestore, "", "name of the Velero Restore to report")
+	cmd.Flags().StringVar(&hostedCluster, "hosted-cluster", "", "name of the HostedCluster included in the notification")
+	cmd.Flags().DurationVar(&interval, "interval", 10*time.Second, "how often to check whether the backup or restore finished")
+	cmd.MarkFlagsOneRequired(notify.OperationBackup, notify.OperationRestore)
+	cmd.MarkFlagsMutuallyExclusive(notify.OperationBackup, notify.OperationRestore)
+	return cmd
+}
+
+// recordBackupSummary annotates the finished Backup with its summary. Failing to is only
+// logged: the notification is still sent.
+func recordBackupSummary(ctx context.Context, client crclient.Client, namespace, name string, config map[string]string) {
+	backup := &velerov1.Backup{}
+	if err := client.Get(ctx, crclient.ObjectKey{Namespace: namespace, Name: name}, backup); err != nil {
+		fmt.Fprintf(os.Stderr, "error getting Backup %s/%s: %v\n", namespace, name, err)
+		return
+	}
+	if err := backupsummary.Record(ctx, client, cmp.Or(config[common.ConfigKeyVeleroNamespace], namespace), backup, 0); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// checkVolumeRestores checks the volume restores Velero created in its namespace for the
+// Restore all completed, so the restored cluster does not resume on partially restored etcd
+// data.
+func checkVolumeRestores(ctx context.Context, client crclient.Client, veleroNamespace, namespace, name string) error {
+	restore := &velerov1.Restore{}
+	if err := client.Get(ctx, crclient.ObjectKey{Namespace: namespace, Name: name}, restore); err != nil {
+		return fmt.Errorf("error getting Restore %s/%s: %w", namespace, name, err)
+	}
+	progress, err := volumebackup.GetRestore(ctx, client, veleroNamespace, restore)
+	if err != nil {
+		return err
+	}
+	if unsuccessful := progress.Unsuccessful(); len(unsuccessful) > 0 {
+		return fmt.Errorf("restore %s/%s did not restore every volume: %v", namespace, name, unsuccessful)
+	}
+	if !progress.Finished() {
+		return fmt.Errorf("restore %s/%s is still restoring volumes, %s: run the command again once it finished", namespace, name, progress)
+	}
+	return nil
+}
+
+// reportVolumeProgress prints the progress of the volume backups of the backup whenever it
+// changes, until the context is done.
+func reportVolumeProgress(ctx context.Context, client crclient.Client, veleroNamespace string, backup *velerov1.Backup, expected []string, interval time.Duration) {
+	last := ""
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		progress, err := volumebackup.Get(ctx, client, veleroNamespace, backup, expected)
+		if err != nil || len(progress.Volumes) == 0 || progress.String() == last {
+			return
+		}
+		last = progress.String()
+		fmt.Printf("Backup %s/%s: %s\n", backup.Namespace, backup.Name, last)
+	}, interval)
+}
+
+// loadPluginConfig returns the namespace the command runs in and the plugin ConfigMap data
+// found there, empty when the ConfigMap does not exist.
+func loadPluginConfig(ctx context.Context, client crclient.Client) (string, map[string]string, error) {
+	ns, err := common.GetCurrentNamespace()
+	if err != nil {
+		return "", nil, fmt.Errorf("error getting current namespace: %w", err)
+	}
+	config, err := getPluginConfig(ctx, client, ns)
+	return ns, config, err
+}
+
+// getPluginConfig reads the plugin configuration in the namespace, empty when there is none.
+func getPluginConfig(ctx context.Context, client crclient.Client, namespace string) (map[string]string, error) {
+	pluginConfig := corev1.ConfigMap{}
+	if err := client.Get(ctx, crclient.ObjectKey{Name: common.PluginConfigMapName, Namespace: namespace}, &pluginConfig); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("error getting plugin configuration: %w", err)
+		}
+	}
+	return pluginConfig.Data, nil
+}
@@ -0,0 +1,768 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/hooks"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/resourcemodifiers"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/secretcheck"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/volumebackup"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	velerov2alpha1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v2alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/utils/ptr"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakePauser records the pauses and resumes of the backup command.
+type fakePauser struct {
+	pauses, unpauses int
+	waitPausedErr    error
+}
+
+func (p *fakePauser) Pause(context.Context, string, string, string) ([]string, error) {
+	p.pauses++
+	return nil, nil
+}
+
+func (p *fakePauser) Unpause(context.Context, string, string) ([]string, error) {
+	p.unpauses++
+	return nil, nil
+}
+
+func (p *fakePauser) WaitForPaused(context.Context, string, string, common.Timeouts) error {
+	return p.waitPausedErr
+}
+
+func (p *fakePauser) WaitForUnpaused(context.Context, string, string, common.Timeouts) error {
+	return nil
+}
+
+// newFinishedBackup returns a Backup Velero finished in the phase, with a DataUpload of the
+// etcd PVC in each of the uploadPhases.
+func newFinishedBackup(name string, phase velerov1.BackupPhase, uploadPhases ...velerov2alpha1.DataUploadPhase) []crclient.Object {
+	uid := types.UID(name + "-uid")
+	objects := []crclient.Object{&velerov1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openshift-adp", UID: uid},
+		Status:     velerov1.BackupStatus{Phase: phase},
+	}}
+	for i, uploadPhase := range uploadPhases {
+		objects = append(objects, &velerov2alpha1.DataUpload{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name + "-" + string(rune('a'+i)), Namespace: "openshift-adp",
+				Labels: map[string]string{velerov1.BackupNameLabel: name, velerov1.BackupUIDLabel: string(uid)},
+			},
+			Spec:   velerov2alpha1.DataUploadSpec{SourceNamespace: "clusters-hc", SourcePVC: "data-etcd-" + string(rune('0'+i))},
+			Status: velerov2alpha1.DataUploadStatus{Phase: uploadPhase},
+		})
+	}
+	return objects
+}
+
+func TestUploadsFailed(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress volumebackup.Progress
+		want     bool
+	}{
+		{
+			name: "When only DataUploads failed, It Should report the uploads failed",
+			progress: volumebackup.Progress{Volumes: []volumebackup.Volume{
+				{Kind: volumebackup.KindDataUpload, Phase: volumebackup.PhaseCompleted},
+				{Kind: volumebackup.KindDataUpload, Phase: volumebackup.PhaseFailed},
+			}},
+			want: true,
+		},
+		{
+			name: "When a PodVolumeBackup failed too, It Should not report the uploads failed",
+			progress: volumebackup.Progress{Volumes: []volumebackup.Volume{
+				{Kind: volumebackup.KindDataUpload, Phase: volumebackup.PhaseCanceled},
+				{Kind: volumebackup.KindPodVolumeBackup, Phase: volumebackup.PhaseFailed},
+			}},
+		},
+		{
+			name: "When no volume backup failed, It Should not report the uploads failed",
+			progress: volumebackup.Progress{Volumes: []volumebackup.Volume{
+				{Kind: volumebackup.KindDataUpload, Phase: volumebackup.PhaseCompleted},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			NewWithT(t).Expect(uploadsFailed(tt.progress)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestRunPausedBackup(t *testing.T) {
+	tests := []struct {
+		name          string
+		objects       []crclient.Object
+		waitPausedErr error
+		timeout       time.Duration
+		expectError   string
+	}{
+		{
+			name:    "When the backup completes, It Should resume the hosted cluster",
+			objects: newFinishedBackup("daily", velerov1.BackupPhaseCompleted, velerov2alpha1.DataUploadPhaseCompleted),
+		},
+		{
+			name:          "When the pause does not propagate, It Should resume the hosted cluster and return error",
+			waitPausedErr: errors.New("timed out"),
+			expectError:   "error waiting for the pause",
+		},
+		{
+			name:        "When the backup does not finish in time, It Should resume the hosted cluster and return error",
+			timeout:     50 * time.Millisecond,
+			expectError: "error waiting for Backup openshift-adp/daily",
+		},
+		{
+			name:        "When the backup fails, It Should resume the hosted cluster and return error",
+			objects:     newFinishedBackup("daily", velerov1.BackupPhasePartiallyFailed, velerov2alpha1.DataUploadPhaseFailed),
+			expectError: "finished in phase PartiallyFailed",
+		},
+		{
+			name:        "When a DataUpload failed in a completed backup, It Should resume the hosted cluster and return error",
+			objects:     newFinishedBackup("daily", velerov1.BackupPhaseCompleted, velerov2alpha1.DataUploadPhaseCompleted, velerov2alpha1.DataUploadPhaseFailed),
+			expectError: "completed with 1/2 volumes backed up",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(tt.objects...).Build()
+			pauser := &fakePauser{waitPausedErr: tt.waitPausedErr}
+			backup := &velerov1.Backup{ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: "openshift-adp"}}
+
+			_, err := runPausedBackup(ctx, c, pauser, backup, "clusters", "hc", nil, common.Timeouts{}, 10*time.Millisecond)
+			if tt.expectError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.expectError)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			g.Expect(pauser.pauses).To(Equal(1))
+			g.Expect(pauser.unpauses).To(Equal(1))
+		})
+	}
+}
+
+func TestRunPausedBackupBeforePauseHooks(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		expectError string
+		wantPauses  int
+	}{
+		{
+			name:       "When the beforePause hook succeeds, It Should fire it before pausing the hosted cluster",
+			status:     http.StatusOK,
+			wantPauses: 1,
+		},
+		{
+			name:        "When the beforePause hook fails with the Fail policy, It Should not pause the hosted cluster",
+			status:      http.StatusForbidden,
+			expectError: "HTTP 403",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			pauser := &fakePauser{}
+			var received []hooks.Payload
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// The hosted cluster is not paused yet
+				g.Expect(pauser.pauses).To(Equal(0))
+				payload := hooks.Payload{}
+				g.Expect(json.NewDecoder(r.Body).Decode(&payload)).To(Succeed())
+				received = append(received, payload)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(newFinishedBackup("daily", velerov1.BackupPhaseCompleted, velerov2alpha1.DataUploadPhaseCompleted)...).Build()
+			backup := &velerov1.Backup{ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: "openshift-adp"}}
+			config := map[string]string{
+				common.ConfigKeyHookWebhookURL:    server.URL,
+				common.ConfigKeyHookEvents:        string(hooks.BeforePause),
+				common.ConfigKeyHookFailurePolicy: hooks.FailurePolicyFail,
+			}
+
+			_, err := runPausedBackup(context.Background(), c, pauser, backup, "clusters", "hc", config, common.Timeouts{}, 10*time.Millisecond)
+			if tt.expectError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.expectError)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			g.Expect(received).To(HaveLen(1))
+			g.Expect(received[0].Event).To(Equal(hooks.BeforePause))
+			g.Expect(received[0].Backup).To(Equal("daily"))
+			g.Expect(received[0].ControlPlaneNamespace).To(Equal("clusters-hc"))
+			g.Expect(pauser.pauses).To(Equal(tt.wantPauses))
+		})
+	}
+}
+
+func TestRunBackupWithRetries(t *testing.T) {
+	tests := []struct {
+		name          string
+		objects       [][]crclient.Object
+		uploadRetries int
+		expectError   string
+		wantAttempts  int
+	}{
+		{
+			name: "When the DataUploads of the backup failed, It Should retry with a new backup",
+			objects: [][]crclient.Object{
+				newFinishedBackup("daily", velerov1.BackupPhasePartiallyFailed, velerov2alpha1.DataUploadPhaseFailed),
+				newFinishedBackup("daily-retry-1", velerov1.BackupPhaseCompleted, velerov2alpha1.DataUploadPhaseCompleted),
+			},
+			uploadRetries: 2,
+			wantAttempts:  2,
+		},
+		{
+			name: "When the DataUploads keep failing, It Should give up after the retries",
+			objects: [][]crclient.Object{
+				newFinishedBackup("daily", velerov1.BackupPhasePartiallyFailed, velerov2alpha1.DataUploadPhaseFailed),
+				newFinishedBackup("daily-retry-1", velerov1.BackupPhasePartiallyFailed, velerov2alpha1.DataUploadPhaseFailed),
+				newFinishedBackup("daily-retry-2", velerov1.BackupPhasePartiallyFailed, velerov2alpha1.DataUploadPhaseCanceled),
+			},
+			uploadRetries: 2,
+			expectError:   "backup openshift-adp/daily-retry-2 finished in phase PartiallyFailed",
+			wantAttempts:  3,
+		},
+		{
+			name: "When the backup failed for another reason, It Should not retry",
+			objects: [][]crclient.Object{
+				newFinishedBackup("daily", velerov1.BackupPhaseFailed),
+			},
+			uploadRetries: 2,
+			expectError:   "backup openshift-adp/daily finished in phase Failed",
+			wantAttempts:  1,
+		},
+		{
+			name: "When no retry is allowed, It Should return the failed uploads",
+			objects: [][]crclient.Object{
+				newFinishedBackup("daily", velerov1.BackupPhasePartiallyFailed, velerov2alpha1.DataUploadPhaseFailed),
+			},
+			expectError:  "backup openshift-adp/daily finished in phase PartiallyFailed",
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			builder := fake.NewClientBuilder().WithScheme(common.CustomScheme)
+			for _, objects := range tt.objects {
+				builder = builder.WithObjects(objects...)
+			}
+			c := builder.Build()
+			pauser := &fakePauser{}
+			backup := &velerov1.Backup{ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: "openshift-adp"}}
+
+			err := runBackupWithRetries(context.Background(), c, pauser, backup, "clusters", "hc", nil, common.Timeouts{}, 10*time.Millisecond, tt.uploadRetries)
+			if tt.expectError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.expectError)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			g.Expect(pauser.pauses).To(Equal(tt.wantAttempts))
+			g.Expect(pauser.unpauses).To(Equal(tt.wantAttempts))
+		})
+	}
+}
+
+// runCommand runs the plugin binary with the arguments against the client, from the
+// openshift-adp namespace.
+func runCommand(t *testing.T, c crclient.Client, args ...string) error {
+	t.Setenv("POD_NAMESPACE", "openshift-adp")
+	newClient = func() (crclient.Client, error) { return c, nil }
+	t.Cleanup(func() {
+		newClient = common.GetClient
+		common.SetKubeconfig("", "")
+	})
+	cmd := newCLI()
+	cmd.SetArgs(args)
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	return cmd.Execute()
+}
+
+func newHostedCluster(annotations map[string]string, pausedUntil *string) *hyperv1.HostedCluster {
+	return &hyperv1.HostedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters", Annotations: annotations},
+		Spec: hyperv1.HostedClusterSpec{
+			Platform:    hyperv1.PlatformSpec{Type: hyperv1.NonePlatform},
+			PullSecret:  corev1.LocalObjectReference{Name: "pull-secret"},
+			PausedUntil: pausedUntil,
+		},
+	}
+}
+
+func TestBackupCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		objects     []crclient.Object
+		expectError string
+		wantUploads string
+	}{
+		{
+			name:        "When --hc is missing, It Should return error",
+			args:        []string{"backup"},
+			expectError: `required flag(s) "hc" not set`,
+		},
+		{
+			name:        "When --hc is not namespace/name, It Should return error",
+			args:        []string{"backup", "--hc", "hc"},
+			expectError: "--hc must be in the namespace/name form",
+		},
+		{
+			name:        "When the HostedCluster does not exist, It Should return error",
+			args:        []string{"backup", "--hc", "clusters/hc", "--velero-namespace", "openshift-adp"},
+			expectError: "error getting HostedCluster clusters/hc",
+		},
+		{
+			name:        "When the backup completes, It Should resume the HostedCluster and record the summary",
+			args:        []string{"backup", "--hc", "clusters/hc", "--name", "daily", "--velero-namespace", "openshift-adp", "--interval", "10ms"},
+			objects:     append(newFinishedBackup("daily", velerov1.BackupPhaseCompleted, velerov2alpha1.DataUploadPhaseCompleted), newHostedCluster(nil, nil)),
+			wantUploads: "1",
+		},
+		{
+			name:        "When the backup fails, It Should resume the HostedCluster and return error",
+			args:        []string{"backup", "--hc", "clusters/hc", "--name", "daily", "--velero-namespace", "openshift-adp", "--interval", "10ms"},
+			objects:     append(newFinishedBackup("daily", velerov1.BackupPhaseFailed), newHostedCluster(nil, nil)),
+			expectError: "backup openshift-adp/daily finished in phase Failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(tt.objects...).Build()
+
+			err := runCommand(t, c, tt.args...)
+			if tt.expectError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.expectError)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			if len(tt.objects) == 0 {
+				return
+			}
+			hc := &hyperv1.HostedCluster{}
+			g.Expect(c.Get(context.TODO(), crclient.ObjectKey{Namespace: "clusters", Name: "hc"}, hc)).To(Succeed())
+			g.Expect(hc.Spec.PausedUntil).To(BeNil())
+			g.Expect(hc.Annotations).NotTo(HaveKey(common.PausedForBackupAnnotation))
+			if tt.wantUploads != "" {
+				backup := &velerov1.Backup{}
+				g.Expect(c.Get(context.TODO(), crclient.ObjectKey{Namespace: "openshift-adp", Name: "daily"}, backup)).To(Succeed())
+				g.Expect(backup.Annotations).To(HaveKeyWithValue(common.BackupDataUploadsAnnotation, tt.wantUploads))
+			}
+		})
+	}
+}
+
+func TestUnpauseRestoreCommand(t *testing.T) {
+	available := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: common.CAPIManagerDeploymentName, Namespace: "clusters-hc"},
+		Status: appsv1.DeploymentStatus{Conditions: []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue},
+		}},
+	}
+	pending := map[string]string{common.RestorePendingAnnotation: "true"}
+	tests := []struct {
+		name          string
+		args          []string
+		objects       []crclient.Object
+		expectError   string
+		wantResumed   bool
+		wantReconcile bool
+	}{
+		{
+			name:        "When --name is missing, It Should return error",
+			args:        []string{"unpause-restore", "--namespace", "clusters"},
+			expectError: `required flag(s) "name" not set`,
+		},
+		{
+			name:        "When the HostedCluster is not pending a restore, It Should return error",
+			args:        []string{"unpause-restore", "--namespace", "clusters", "--name", "hc"},
+			objects:     []crclient.Object{newHostedCluster(nil, nil), available},
+			expectError: "is not pending a restore confirmation",
+		},
+		{
+			name:        "When the cluster-api deployments are not available, It Should leave the HostedCluster paused and return error",
+			args:        []string{"unpause-restore", "--namespace", "clusters", "--name", "hc", "--capi-timeout", "100ms"},
+			objects:     []crclient.Object{newHostedCluster(pending, ptr.To("true"))},
+			expectError: "cluster-api deployments [cluster-api] in namespace clusters-hc are not available",
+		},
+		{
+			name:        "When the Restore of --restore does not exist, It Should leave the HostedCluster paused and return error",
+			args:        []string{"unpause-restore", "--namespace", "clusters", "--name", "hc", "--restore", "restore"},
+			objects:     []crclient.Object{newHostedCluster(pending, ptr.To("true")), available},
+			expectError: "error getting Restore openshift-adp/restore",
+		},
+		{
+			name:        "When the cluster-api deployments are available, It Should resume the HostedCluster",
+			args:        []string{"unpause-restore", "--namespace", "clusters", "--name", "hc"},
+			objects:     []crclient.Object{newHostedCluster(pending, ptr.To("true")), available},
+			wantResumed: true,
+		},
+		{
+			name:          "When --reconcile is set, It Should resume the HostedCluster and request its reconciliation",
+			args:          []string{"unpause-restore", "--namespace", "clusters", "--name", "hc", "--reconcile"},
+			objects:       []crclient.Object{newHostedCluster(pending, ptr.To("true")), available},
+			wantResumed:   true,
+			wantReconcile: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(tt.objects...).Build()
+
+			err := runCommand(t, c, tt.args...)
+			if tt.expectError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.expectError)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			if len(tt.objects) == 0 {
+				return
+			}
+			hc := &hyperv1.HostedCluster{}
+			g.Expect(c.Get(context.TODO(), crclient.ObjectKey{Namespace: "clusters", Name: "hc"}, hc)).To(Succeed())
+			if tt.wantResumed {
+				g.Expect(hc.Spec.PausedUntil).To(BeNil())
+				g.Expect(hc.Annotations).NotTo(HaveKey(common.RestorePendingAnnotation))
+			} else if hc.Annotations[common.RestorePendingAnnotation] != "" {
+				g.Expect(hc.Spec.PausedUntil).To(Equal(ptr.To("true")))
+			}
+			if tt.wantReconcile {
+				g.Expect(hc.Annotations).To(HaveKey(common.ReconcileRequestedAnnotation))
+			} else {
+				g.Expect(hc.Annotations).NotTo(HaveKey(common.ReconcileRequestedAnnotation))
+			}
+		})
+	}
+}
+
+func TestUnprotectRestoreCommand(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		until         time.Time
+		noCluster     bool
+		expectError   string
+		wantProtected bool
+	}{
+		{
+			name:        "When --namespace is missing, It Should return error",
+			args:        []string{"unprotect-restore", "--name", "hc"},
+			noCluster:   true,
+			expectError: `required flag(s) "namespace" not set`,
+		},
+		{
+			name:        "When the HostedCluster does not exist, It Should return error",
+			args:        []string{"unprotect-restore", "--namespace", "clusters", "--name", "hc"},
+			noCluster:   true,
+			expectError: "error getting HostedCluster clusters/hc",
+		},
+		{
+			name:          "When the grace period has not ended, It Should keep the protection and return error",
+			args:          []string{"unprotect-restore", "--namespace", "clusters", "--name", "hc"},
+			until:         time.Now().Add(time.Hour),
+			expectError:   "is protected from deletion until",
+			wantProtected: true,
+		},
+		{
+			name:  "When the grace period has ended, It Should lift the protection",
+			args:  []string{"unprotect-restore", "--namespace", "clusters", "--name", "hc"},
+			until: time.Now().Add(-time.Hour),
+		},
+		{
+			name:  "When --force is set, It Should lift the protection within the grace period",
+			args:  []string{"unprotect-restore", "--namespace", "clusters", "--name", "hc", "--force"},
+			until: time.Now().Add(time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			builder := fake.NewClientBuilder().WithScheme(common.CustomScheme)
+			hc := newHostedCluster(nil, nil)
+			if !tt.noCluster {
+				common.ProtectFromDeletion(hc, tt.until)
+				builder = builder.WithObjects(hc)
+			}
+			c := builder.Build()
+
+			err := runCommand(t, c, tt.args...)
+			if tt.expectError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.expectError)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			if tt.noCluster {
+				return
+			}
+			g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(hc), hc)).To(Succeed())
+			if tt.wantProtected {
+				g.Expect(hc.Finalizers).To(ContainElement(common.DeletionProtectionFinalizer))
+			} else {
+				g.Expect(hc.Finalizers).NotTo(ContainElement(common.DeletionProtectionFinalizer))
+			}
+		})
+	}
+}
+
+func newTestCertificate(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: notAfter.Add(-24 * time.Hour), NotAfter: notAfter}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// newRestoredSecrets returns the Secrets verify-restore checks for the hc HostedCluster, all
+// well formed.
+func newRestoredSecrets(t *testing.T) []crclient.Object {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["cluster"] = &clientcmdapi.Cluster{Server: "https://kube-apiserver:6443", CertificateAuthorityData: newTestCertificate(t, time.Now().Add(24*time.Hour))}
+	config.AuthInfos["admin"] = &clientcmdapi.AuthInfo{ClientCertificateData: newTestCertificate(t, time.Now().Add(24*time.Hour))}
+	kubeconfig, err := clientcmd.Write(*config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := func(namespace, name string, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}, Data: data}
+	}
+	return []crclient.Object{
+		secret("clusters", "pull-secret", map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"quay.io":{"auth":"dXNlcjpwYXNz"}}}`)}),
+		secret("clusters-hc", "sa-signing-key", map[string][]byte{
+			"service-account.key": pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+			"service-account.pub": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}),
+		}),
+		secret("clusters-hc", "admin-kubeconfig", map[string][]byte{"kubeconfig": kubeconfig}),
+		secret("clusters-hc", "service-network-admin-kubeconfig", map[string][]byte{"kubeconfig": kubeconfig}),
+	}
+}
+
+func TestVerifyRestoreCommand(t *testing.T) {
+	restore := &velerov1.Restore{ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "openshift-adp", UID: "restore-uid"}}
+	secrets := newRestoredSecrets(t)
+	tests := []struct {
+		name        string
+		args        []string
+		objects     []crclient.Object
+		expectError string
+		wantReport  bool
+	}{
+		{
+			name:        "When --namespace is missing, It Should return error",
+			args:        []string{"verify-restore", "--name", "hc"},
+			expectError: `required flag(s) "namespace" not set`,
+		},
+		{
+			name:        "When the HostedCluster does not exist, It Should return error",
+			args:        []string{"verify-restore", "--namespace", "clusters", "--name", "hc"},
+			expectError: "error getting HostedCluster clusters/hc",
+		},
+		{
+			name:       "When the Secrets are well formed, It Should pass and save the report of --restore",
+			args:       []string{"verify-restore", "--namespace", "clusters", "--name", "hc", "--restore", "restore"},
+			objects:    append([]crclient.Object{newHostedCluster(nil, nil), restore}, secrets...),
+			wantReport: true,
+		},
+		{
+			name:        "When the pull secret is missing, It Should save the report and return error",
+			args:        []string{"verify-restore", "--namespace", "clusters", "--name", "hc", "--restore", "restore"},
+			objects:     append([]crclient.Object{newHostedCluster(nil, nil), restore}, secrets[1:]...),
+			expectError: "secrets, endpoints or guest cluster of HostedCluster clusters/hc failed verification",
+			wantReport:  true,
+		},
+		{
+			name:        "When --guest is set and the backup took no guest snapshot, It Should return error",
+			args:        []string{"verify-restore", "--namespace", "clusters", "--name", "hc", "--guest"},
+			objects:     append([]crclient.Object{newHostedCluster(nil, nil)}, secrets...),
+			expectError: "failed verification",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(tt.objects...).Build()
+
+			err := runCommand(t, c, tt.args...)
+			if tt.expectError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.expectError)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			if tt.wantReport {
+				report := &corev1.ConfigMap{}
+				g.Expect(c.Get(context.TODO(), crclient.ObjectKey{Namespace: "openshift-adp", Name: secretcheck.ConfigMapPrefix + "restore"}, report)).To(Succeed())
+			}
+		})
+	}
+}
+
+func TestMigrationModifiersCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		expectError string
+		wantRestore bool
+	}{
+		{
+			name:        "When --backup is set without --target-kubeconfig, It Should return error",
+			args:        []string{"migration-modifiers", "--hc", "clusters/hc", "--backup", "daily"},
+			expectError: "--backup requires --target-kubeconfig",
+		},
+		{
+			name:        "When --hc is not namespace/name, It Should return error",
+			args:        []string{"migration-modifiers", "--hc", "clusters"},
+			expectError: "--hc must be in the namespace/name form",
+		},
+		{
+			name:        "When the HostedCluster does not exist, It Should return error",
+			args:        []string{"migration-modifiers", "--hc", "clusters/other", "--infra-id", "hc-x7k2p"},
+			expectError: "error getting HostedCluster clusters/other",
+		},
+		{
+			name:        "When the region of a platform without one is changed, It Should return error",
+			args:        []string{"migration-modifiers", "--hc", "clusters/hc", "--region", "us-east-2"},
+			expectError: "cannot be changed",
+		},
+		{
+			name: "When no target cluster is set, It Should print the ConfigMap",
+			args: []string{"migration-modifiers", "--hc", "clusters/hc", "--infra-id", "hc-x7k2p"},
+		},
+		{
+			name:        "When the target cluster and --backup are set, It Should register the ConfigMap and create the Restore",
+			args:        []string{"migration-modifiers", "--hc", "clusters/hc", "--infra-id", "hc-x7k2p", "--target-kubeconfig", "target.kubeconfig", "--backup", "daily", "--restore", "migrate"},
+			wantRestore: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(newHostedCluster(nil, nil)).Build()
+
+			err := runCommand(t, c, tt.args...)
+			if tt.expectError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.expectError)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			if !tt.wantRestore {
+				return
+			}
+			restore := &velerov1.Restore{}
+			g.Expect(c.Get(context.TODO(), crclient.ObjectKey{Namespace: "openshift-adp", Name: "migrate"}, restore)).To(Succeed())
+			g.Expect(restore.Spec.BackupName).To(Equal("daily"))
+			g.Expect(restore.Spec.ResourceModifier).NotTo(BeNil())
+			cm := &corev1.ConfigMap{}
+			g.Expect(c.Get(context.TODO(), crclient.ObjectKey{Namespace: "openshift-adp", Name: restore.Spec.ResourceModifier.Name}, cm)).To(Succeed())
+			g.Expect(cm.Data[resourcemodifiers.DataKey]).To(ContainSubstring("hc-x7k2p"))
+		})
+	}
+}
+
+func TestNotifyCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		webhook      bool
+		expectError  string
+		wantNotified bool
+	}{
+		{
+			name:        "When neither --backup nor --restore is set, It Should return error",
+			args:        []string{"notify"},
+			webhook:     true,
+			expectError: "at least one of the flags in the group [backup restore] is required",
+		},
+		{
+			name:        "When the webhook is not configured, It Should return error",
+			args:        []string{"notify", "--backup", "daily"},
+			expectError: "notificationWebhookURL is not set",
+		},
+		{
+			name:         "When the backup finished, It Should record its summary and notify the webhook",
+			args:         []string{"notify", "--backup", "daily", "--hosted-cluster", "hc", "--interval", "10ms"},
+			webhook:      true,
+			wantNotified: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			notified := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				notified++
+			}))
+			defer server.Close()
+			objects := newFinishedBackup("daily", velerov1.BackupPhaseCompleted, velerov2alpha1.DataUploadPhaseCompleted)
+			if tt.webhook {
+				objects = append(objects, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: common.PluginConfigMapName, Namespace: "openshift-adp"},
+					Data:       map[string]string{common.ConfigKeyNotificationWebhookURL: server.URL},
+				})
+			}
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(objects...).Build()
+
+			err := runCommand(t, c, tt.args...)
+			if tt.expectError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.expectError)))
+				g.Expect(notified).To(BeZero())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(notified).To(Equal(1))
+			backup := &velerov1.Backup{}
+			g.Expect(c.Get(context.TODO(), crclient.ObjectKey{Namespace: "openshift-adp", Name: "daily"}, backup)).To(Succeed())
+			g.Expect(backup.Annotations).To(HaveKeyWithValue(common.BackupDataUploadsAnnotation, "1"))
+		})
+	}
+}
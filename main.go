@@ -1,11 +1,25 @@
 package main
 
 import (
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"sync"
+	"time"
+
 	"github.com/openshift/hypershift-oadp-plugin/pkg/core"
 	"github.com/sirupsen/logrus"
 	"github.com/vmware-tanzu/velero/pkg/plugin/framework"
 )
 
+// pprofAddrEnv enables the pprof debug listener of the plugin process when set to a listen
+// address, e.g. localhost:6060. Velero runs several plugin processes at once, so a fixed
+// port is only bound by the first one; use port 0 to get a free port logged per process.
+const pprofAddrEnv = "HYPERSHIFT_OADP_PLUGIN_PPROF_ADDR"
+
+var debugServerOnce sync.Once
+
 func configureLogger(logger logrus.FieldLogger) logrus.FieldLogger {
 	return logger.WithFields(
 		logrus.Fields{
@@ -15,6 +29,14 @@ func configureLogger(logger logrus.FieldLogger) logrus.FieldLogger {
 }
 
 func main() {
+	// The plugin binary doubles as a CLI. Velero starts it without a subcommand.
+	if len(os.Args) > 1 && isCLICommand(os.Args[1]) {
+		if err := newCLI().Execute(); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
 	framework.NewServer().
 		RegisterBackupItemAction("hypershift-oadp-plugin/backup-item-action", newHCPBackupPlugin).
 		RegisterRestoreItemAction("hypershift-oadp-plugin/restore-item-action", newHCPRestorePlugin).
@@ -22,9 +44,44 @@ func main() {
 }
 
 func newHCPBackupPlugin(logger logrus.FieldLogger) (interface{}, error) {
-	return core.NewBackupPlugin(configureLogger(logger))
+	logger = configureLogger(logger)
+	startDebugServer(logger)
+	return core.NewBackupPlugin(logger)
 }
 
 func newHCPRestorePlugin(logger logrus.FieldLogger) (interface{}, error) {
-	return core.NewRestorePlugin(configureLogger(logger))
+	logger = configureLogger(logger)
+	startDebugServer(logger)
+	return core.NewRestorePlugin(logger)
+}
+
+// startDebugServer serves the pprof endpoints on the pprofAddrEnv address, once per plugin
+// process. A listener that cannot be started is logged and does not affect the plugin.
+func startDebugServer(logger logrus.FieldLogger) {
+	debugServerOnce.Do(func() {
+		addr := os.Getenv(pprofAddrEnv)
+		if addr == "" {
+			return
+		}
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			logger.Warnf("pprof listener not started on %s: %v", addr, err)
+			return
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+		logger.Infof("pprof listening on %s (pid %d)", listener.Addr(), os.Getpid())
+		go func() {
+			if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+				logger.Warnf("pprof listener stopped: %v", err)
+			}
+		}()
+	})
 }
@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/label"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ConfigMapPrefix prefixes the name of the ConfigMap holding the audit trail of a backup.
+	ConfigMapPrefix = "hcp-audit-"
+
+	// DataKey is the ConfigMap key the records are appended to, one per line.
+	DataKey = "audit.log"
+	// TruncatedKey is set on the ConfigMap once records are dropped to stay under its size limit.
+	TruncatedKey = "truncated"
+
+	// maxBuffered records are written at once, and buffered records are written after
+	// flushDelay without new ones.
+	maxBuffered = 100
+	flushDelay  = 5 * time.Second
+	// maxDataSize keeps the trail below the 1MiB limit of a ConfigMap.
+	maxDataSize = 900 * 1024
+)
+
+// Actions the plugin took on an item.
+const (
+	// ActionHandled items went through their kind handler, which may change the item or act
+	// on the cluster, e.g. pause it or take the etcd snapshot.
+	ActionHandled = "handled"
+	// ActionLabeled items were only labeled with their HostedCluster.
+	ActionLabeled = "labeled"
+	// ActionExcluded items were left out of the backup.
+	ActionExcluded = "excluded"
+	// ActionFailed items failed the backup.
+	ActionFailed = "failed"
+)
+
+// Record is the audit record of a processed item.
+type Record struct {
+	Time     time.Time
+	Kind     string
+	Name     string
+	Action   string
+	Duration time.Duration
+}
+
+func (r Record) String() string {
+	return fmt.Sprintf("%s %s %s %s %s", r.Time.UTC().Format("2006-01-02T15:04:05Z"), r.Kind, r.Name, r.Action, r.Duration.Round(time.Millisecond))
+}
+
+// Trail buffers the audit records of a backup and appends them to the hcp-audit-<backup>
+// ConfigMap. Velero does not tell item actions when a backup ends, so the buffer is written
+// once it is full, and otherwise shortly after the last record.
+type Trail struct {
+	client crclient.Client
+	backup *velerov1.Backup
+	log    logrus.FieldLogger
+
+	mu     sync.Mutex
+	buffer []Record
+	timer  *time.Timer
+}
+
+// NewTrail returns the audit trail of the backup.
+func NewTrail(c crclient.Client, backup *velerov1.Backup, log logrus.FieldLogger) *Trail {
+	return &Trail{client: c, backup: backup, log: log}
+}
+
+// BackupName returns the name of the backup the trail records.
+func (t *Trail) BackupName() string {
+	return t.backup.Name
+}
+
+// Add buffers the record.
+func (t *Trail) Add(r Record) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buffer = append(t.buffer, r)
+	if len(t.buffer) >= maxBuffered {
+		t.flush(context.Background())
+		return
+	}
+	if t.timer == nil {
+		t.timer = time.AfterFunc(flushDelay, func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.flush(context.Background())
+		})
+	}
+}
+
+// Flush writes the buffered records.
+func (t *Trail) Flush(ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.flush(ctx)
+}
+
+// flush writes the buffered records, keeping them for the next flush when the write fails.
+// The caller holds the lock.
+func (t *Trail) flush(ctx context.Context) {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	if len(t.buffer) == 0 {
+		return
+	}
+
+	var lines strings.Builder
+	for _, r := range t.buffer {
+		lines.WriteString(r.String())
+		lines.WriteString("\n")
+	}
+	if err := Append(ctx, t.client, t.backup, lines.String()); err != nil {
+		t.log.Warnf("Could not write the audit trail of backup %s: %v", t.backup.Name, err)
+		return
+	}
+	t.buffer = nil
+}
+
+// Append appends the lines to the audit ConfigMap of the backup, creating it next to the
+// Backup and owned by it, so it is deleted with the Backup. Lines that would take the
+// ConfigMap over its size limit are dropped and the ConfigMap is flagged truncated.
+func Append(ctx context.Context, c crclient.Client, backup *velerov1.Backup, lines string) error {
+	key := crclient.ObjectKey{Name: label.GetValidName(ConfigMapPrefix + backup.Name), Namespace: backup.Namespace}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, key, cm); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return fmt.Errorf("error getting audit ConfigMap %s: %w", key, err)
+			}
+			cm = newConfigMap(key, backup)
+			cm.Data[DataKey] = lines
+			if err := c.Create(ctx, cm); err != nil {
+				if apierrors.IsAlreadyExists(err) {
+					// Created by another plugin process meanwhile, append to it instead
+					return apierrors.NewConflict(corev1.Resource("configmaps"), key.Name, err)
+				}
+				return fmt.Errorf("error creating audit ConfigMap %s: %w", key, err)
+			}
+			return nil
+		}
+
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		if len(cm.Data[DataKey])+len(lines) > maxDataSize {
+			if cm.Data[TruncatedKey] == "true" {
+				return nil
+			}
+			cm.Data[TruncatedKey] = "true"
+		} else {
+			cm.Data[DataKey] += lines
+		}
+		if err := c.Update(ctx, cm); err != nil {
+			return fmt.Errorf("error updating audit ConfigMap %s: %w", key, err)
+		}
+		return nil
+	})
+}
+
+func newConfigMap(key crclient.ObjectKey, backup *velerov1.Backup) *corev1.ConfigMap {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Labels:    map[string]string{velerov1.BackupNameLabel: label.GetValidName(backup.Name)},
+		},
+		Data: map[string]string{},
+	}
+	if backup.UID != "" {
+		cm.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: velerov1.SchemeGroupVersion.String(),
+			Kind:       "Backup",
+			Name:       backup.Name,
+			UID:        backup.UID,
+		}}
+	}
+	return cm
+}
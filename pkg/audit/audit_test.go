@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/sirupsen/logrus"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testBackup() *velerov1.Backup {
+	return &velerov1.Backup{ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: "openshift-adp", UID: "backup-uid"}}
+}
+
+func TestTrail(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	client := fake.NewClientBuilder().WithScheme(common.CustomScheme).Build()
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	getConfigMap := func() *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{}
+		g.Expect(client.Get(ctx, crclient.ObjectKey{Name: "hcp-audit-daily", Namespace: "openshift-adp"}, cm)).To(Succeed())
+		return cm
+	}
+
+	trail := NewTrail(client, testBackup(), logrus.New())
+	trail.Add(Record{Time: now, Kind: "HostedCluster", Name: "clusters/hc", Action: ActionHandled, Duration: 1500 * time.Millisecond})
+	trail.Add(Record{Time: now, Kind: "Pod", Name: "clusters-hc/etcd-0", Action: ActionExcluded})
+	trail.Flush(ctx)
+
+	cm := getConfigMap()
+	g.Expect(cm.Data[DataKey]).To(Equal("2026-01-01T10:00:00Z HostedCluster clusters/hc handled 1.5s\n" +
+		"2026-01-01T10:00:00Z Pod clusters-hc/etcd-0 excluded 0s\n"))
+	g.Expect(cm.Labels).To(HaveKeyWithValue(velerov1.BackupNameLabel, "daily"))
+	g.Expect(cm.OwnerReferences).To(ConsistOf(HaveField("UID", BeEquivalentTo("backup-uid"))))
+
+	// A full buffer is written without waiting, after the records of an earlier flush
+	for i := 0; i < maxBuffered; i++ {
+		trail.Add(Record{Time: now, Kind: "Secret", Name: "clusters/pull-secret", Action: ActionLabeled})
+	}
+	g.Expect(strings.Count(getConfigMap().Data[DataKey], "\n")).To(Equal(maxBuffered + 2))
+	g.Expect(trail.buffer).To(BeEmpty())
+}
+
+func TestAppendTruncates(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	client := fake.NewClientBuilder().WithScheme(common.CustomScheme).Build()
+
+	g.Expect(Append(ctx, client, testBackup(), strings.Repeat("x", maxDataSize-10)+"\n")).To(Succeed())
+	g.Expect(Append(ctx, client, testBackup(), "2026-01-01T10:00:00Z Secret clusters/pull-secret labeled 0s\n")).To(Succeed())
+
+	cm := &corev1.ConfigMap{}
+	g.Expect(client.Get(ctx, crclient.ObjectKey{Name: "hcp-audit-daily", Namespace: "openshift-adp"}, cm)).To(Succeed())
+	g.Expect(cm.Data[DataKey]).NotTo(ContainSubstring("pull-secret"))
+	g.Expect(cm.Data).To(HaveKeyWithValue(TruncatedKey, "true"))
+}
@@ -0,0 +1,61 @@
+// Package backupsummary annotates a finished Velero Backup with what the plugin and Velero
+// did for the hosted cluster, so `velero backup describe` shows it without going through
+// the logs.
+package backupsummary
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/volumebackup"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AddHCP adds the namespace/name of a backed up HostedControlPlane to the
+// BackupHCPsAnnotation of the Backup, unless it is already listed.
+func AddHCP(backup *velerov1.Backup, namespace, name string) {
+	hcp := namespace + "/" + name
+	var hcps []string
+	if value := backup.Annotations[common.BackupHCPsAnnotation]; value != "" {
+		hcps = strings.Split(value, ",")
+	}
+	for _, listed := range hcps {
+		if listed == hcp {
+			return
+		}
+	}
+	common.AddAnnotation(backup, common.BackupHCPsAnnotation, strings.Join(append(hcps, hcp), ","))
+}
+
+// Record annotates the finished Backup with the DataUploads, in the Velero namespace, and
+// the CSI VolumeSnapshots that completed for it and, when the backup command paused the
+// hosted cluster, how long it stayed paused. The annotations the plugin set while backing up
+// are left as they are.
+func Record(ctx context.Context, c crclient.Client, veleroNamespace string, backup *velerov1.Backup, pause time.Duration) error {
+	progress, err := volumebackup.Get(ctx, c, veleroNamespace, backup, nil)
+	if err != nil {
+		return err
+	}
+	uploads := 0
+	for _, volume := range progress.Volumes {
+		if volume.Kind == volumebackup.KindDataUpload && volume.Phase == volumebackup.PhaseCompleted {
+			uploads++
+		}
+	}
+
+	original := backup.DeepCopy()
+	common.AddAnnotation(backup, common.BackupDataUploadsAnnotation, strconv.Itoa(uploads))
+	common.AddAnnotation(backup, common.BackupVolumeSnapshotsAnnotation, strconv.Itoa(backup.Status.CSIVolumeSnapshotsCompleted))
+	if pause > 0 {
+		common.AddAnnotation(backup, common.BackupPauseDurationAnnotation, pause.Round(time.Second).String())
+	}
+	if err := c.Patch(ctx, backup, crclient.MergeFrom(original)); err != nil {
+		return fmt.Errorf("error recording the summary of Backup %s/%s: %w", backup.Namespace, backup.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package backupsummary
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	velerov2alpha1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v2alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newDataUpload(name, pvc string, phase velerov2alpha1.DataUploadPhase) *velerov2alpha1.DataUpload {
+	return &velerov2alpha1.DataUpload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: "openshift-adp",
+			Labels: map[string]string{velerov1.BackupNameLabel: "daily", velerov1.BackupUIDLabel: "backup-uid"},
+		},
+		Spec:   velerov2alpha1.DataUploadSpec{SourceNamespace: "clusters-hc", SourcePVC: pvc},
+		Status: velerov2alpha1.DataUploadStatus{Phase: phase},
+	}
+}
+
+func TestAddHCP(t *testing.T) {
+	g := NewWithT(t)
+	backup := &velerov1.Backup{}
+
+	AddHCP(backup, "clusters-hc", "hc")
+	AddHCP(backup, "clusters-other", "other")
+	AddHCP(backup, "clusters-hc", "hc")
+	g.Expect(backup.Annotations[common.BackupHCPsAnnotation]).To(Equal("clusters-hc/hc,clusters-other/other"))
+}
+
+func TestRecord(t *testing.T) {
+	tests := []struct {
+		name      string
+		pause     time.Duration
+		wantPause string
+	}{
+		{
+			name:      "When the backup command paused the hosted cluster, It Should record the pause duration",
+			pause:     4*time.Minute + 2*time.Second + 300*time.Millisecond,
+			wantPause: "4m2s",
+		},
+		{
+			name: "When the hosted cluster was not paused by the backup command, It Should only record the volumes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.TODO()
+			backup := &velerov1.Backup{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "daily", Namespace: "openshift-adp", UID: "backup-uid",
+					Annotations: map[string]string{common.BackupHCPsAnnotation: "clusters-hc/hc"},
+				},
+				Status: velerov1.BackupStatus{Phase: velerov1.BackupPhaseCompleted, CSIVolumeSnapshotsCompleted: 3},
+			}
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(
+				backup,
+				newDataUpload("daily-a", "data-etcd-0", velerov2alpha1.DataUploadPhaseCompleted),
+				newDataUpload("daily-b", "data-etcd-1", velerov2alpha1.DataUploadPhaseCompleted),
+				newDataUpload("daily-c", "data-etcd-2", velerov2alpha1.DataUploadPhaseFailed),
+			).Build()
+
+			g.Expect(Record(ctx, c, "openshift-adp", backup, tt.pause)).To(Succeed())
+
+			recorded := &velerov1.Backup{}
+			g.Expect(c.Get(ctx, crclient.ObjectKeyFromObject(backup), recorded)).To(Succeed())
+			g.Expect(recorded.Annotations).To(HaveKeyWithValue(common.BackupHCPsAnnotation, "clusters-hc/hc"))
+			g.Expect(recorded.Annotations).To(HaveKeyWithValue(common.BackupDataUploadsAnnotation, "2"))
+			g.Expect(recorded.Annotations).To(HaveKeyWithValue(common.BackupVolumeSnapshotsAnnotation, "3"))
+			if tt.wantPause != "" {
+				g.Expect(recorded.Annotations).To(HaveKeyWithValue(common.BackupPauseDurationAnnotation, tt.wantPause))
+			} else {
+				g.Expect(recorded.Annotations).NotTo(HaveKey(common.BackupPauseDurationAnnotation))
+			}
+		})
+	}
+}
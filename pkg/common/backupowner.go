@@ -0,0 +1,42 @@
+package common
+
+import (
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/label"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BackupLabels are the labels of the objects the plugin creates for a Velero Backup, e.g.
+// its HCPEtcdBackup, so another plugin process can find them. The UID tells them apart from
+// the objects of a deleted Backup of the same name.
+func BackupLabels(backup *velerov1.Backup) map[string]string {
+	return map[string]string{
+		velerov1.BackupNameLabel: label.GetValidName(backup.Name),
+		velerov1.BackupUIDLabel:  string(backup.UID),
+	}
+}
+
+// BelongsToBackup reports whether an object found through the backup name label was created
+// for this very Backup, rather than for a deleted and recreated Backup of the same name: its
+// backup UID label matches or, on objects of plugin versions that did not set one, it was
+// created once the Backup started.
+func BelongsToBackup(obj metav1.Object, backup *velerov1.Backup) bool {
+	return belongsTo(obj, velerov1.BackupUIDLabel, string(backup.UID), backup.Status.StartTimestamp)
+}
+
+// BelongsToRestore is BelongsToBackup for the objects Velero creates for a Restore, e.g. its
+// DataDownloads, which other restores running in the same namespace create too.
+func BelongsToRestore(obj metav1.Object, restore *velerov1.Restore) bool {
+	return belongsTo(obj, velerov1.RestoreUIDLabel, string(restore.UID), restore.Status.StartTimestamp)
+}
+
+func belongsTo(obj metav1.Object, uidLabel, uid string, start *metav1.Time) bool {
+	if value, ok := obj.GetLabels()[uidLabel]; ok {
+		return value == uid
+	}
+	if start == nil {
+		return true
+	}
+	created := obj.GetCreationTimestamp()
+	return !created.Before(start)
+}
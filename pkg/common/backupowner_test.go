@@ -0,0 +1,96 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBelongsToBackup(t *testing.T) {
+	started := metav1.NewTime(time.Date(2026, 5, 12, 14, 0, 0, 0, time.UTC))
+	backup := &velerov1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: "openshift-adp", UID: "new-uid"},
+		Status:     velerov1.BackupStatus{StartTimestamp: &started},
+	}
+	tests := []struct {
+		name    string
+		labels  map[string]string
+		created time.Time
+		want    bool
+	}{
+		{
+			name:   "When the object carries the backup UID, It Should belong to the backup",
+			labels: map[string]string{velerov1.BackupNameLabel: "daily", velerov1.BackupUIDLabel: "new-uid"},
+			want:   true,
+		},
+		{
+			name:    "When the object carries the UID of a deleted backup of the same name, It Should not belong to the backup",
+			labels:  map[string]string{velerov1.BackupNameLabel: "daily", velerov1.BackupUIDLabel: "old-uid"},
+			created: started.Add(time.Minute),
+		},
+		{
+			name:    "When an object without UID was created after the backup started, It Should belong to the backup",
+			labels:  map[string]string{velerov1.BackupNameLabel: "daily"},
+			created: started.Add(time.Minute),
+			want:    true,
+		},
+		{
+			name:    "When an object without UID predates the backup, It Should not belong to the backup",
+			labels:  map[string]string{velerov1.BackupNameLabel: "daily"},
+			created: started.Add(-time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			obj := &metav1.ObjectMeta{Labels: tt.labels, CreationTimestamp: metav1.NewTime(tt.created)}
+			g.Expect(BelongsToBackup(obj, backup)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestBelongsToRestore(t *testing.T) {
+	started := metav1.NewTime(time.Date(2026, 5, 12, 14, 0, 0, 0, time.UTC))
+	restore := &velerov1.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "dr", Namespace: "openshift-adp", UID: "new-uid"},
+		Status:     velerov1.RestoreStatus{StartTimestamp: &started},
+	}
+	tests := []struct {
+		name    string
+		labels  map[string]string
+		created time.Time
+		want    bool
+	}{
+		{
+			name:   "When the object carries the restore UID, It Should belong to the restore",
+			labels: map[string]string{velerov1.RestoreNameLabel: "dr", velerov1.RestoreUIDLabel: "new-uid"},
+			want:   true,
+		},
+		{
+			name:    "When the object carries the UID of another restore, It Should not belong to the restore",
+			labels:  map[string]string{velerov1.RestoreNameLabel: "dr", velerov1.RestoreUIDLabel: "old-uid"},
+			created: started.Add(time.Minute),
+		},
+		{
+			name:    "When an object without UID predates the restore, It Should not belong to the restore",
+			labels:  map[string]string{velerov1.RestoreNameLabel: "dr"},
+			created: started.Add(-time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			obj := &metav1.ObjectMeta{Labels: tt.labels, CreationTimestamp: metav1.NewTime(tt.created)}
+			g.Expect(BelongsToRestore(obj, restore)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestBackupLabels(t *testing.T) {
+	g := NewWithT(t)
+	backup := &velerov1.Backup{ObjectMeta: metav1.ObjectMeta{Name: "daily", UID: "uid"}}
+	g.Expect(BackupLabels(backup)).To(Equal(map[string]string{velerov1.BackupNameLabel: "daily", velerov1.BackupUIDLabel: "uid"}))
+}
@@ -0,0 +1,79 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+// BackupSchemaVersion is the version of the layout of the items the plugin backs up. Bump it
+// when backed up items gain annotations or fields the restore relies on, and make the restore
+// tolerate their absence from items of older schemas.
+const BackupSchemaVersion = 1
+
+const (
+	// BackupSchemaLegacy is the schema of the items backed up before the schema was recorded.
+	// Their HostedClusters may lack the SourceMetadataAnnotation.
+	BackupSchemaLegacy = 0
+	// BackupSchemaSourceMetadata is the first recorded schema. The HostedClusters of its
+	// backups carry the SourceMetadataAnnotation unless recording it was tolerated to fail.
+	BackupSchemaSourceMetadata = 1
+)
+
+// ParseBackupSchema returns the schema recorded in the BackupSchemaAnnotation, or
+// BackupSchemaLegacy when the annotation is missing.
+func ParseBackupSchema(annotations map[string]string) (int, error) {
+	value, ok := annotations[BackupSchemaAnnotation]
+	if !ok {
+		return BackupSchemaLegacy, nil
+	}
+	schema, err := strconv.Atoi(value)
+	if err != nil || schema < 1 {
+		return 0, fmt.Errorf("invalid %s annotation %q", BackupSchemaAnnotation, value)
+	}
+	return schema, nil
+}
+
+// Compatibility tells whether the running plugin can restore a backup of another plugin version.
+type Compatibility string
+
+const (
+	CompatibilitySupported             Compatibility = "Supported"
+	CompatibilitySupportedWithWarnings Compatibility = "SupportedWithWarnings"
+	CompatibilityUnsupported           Compatibility = "Unsupported"
+)
+
+// CheckPluginCompatibility returns whether the plugin at runningVersion can restore the items
+// of the given schema backed up by the plugin at backupVersion, with the reason when it is not
+// plainly supported:
+//   - a newer schema, or another major version, is unsupported: the restore would miss
+//     metadata the backup relies on;
+//   - an older schema, or a newer minor version, is supported with warnings;
+//   - versions that are not semantic, e.g. development builds, are only compared by schema.
+func CheckPluginCompatibility(schema int, backupVersion, runningVersion string) (Compatibility, string) {
+	if schema > BackupSchemaVersion {
+		return CompatibilityUnsupported, fmt.Sprintf("backup schema %d of plugin %s is newer than schema %d of the running plugin %s, upgrade the plugin to restore it",
+			schema, backupVersion, BackupSchemaVersion, runningVersion)
+	}
+
+	var warnings []string
+	if schema < BackupSchemaVersion {
+		warnings = append(warnings, fmt.Sprintf("backup schema %d is older than %d, checks relying on newer metadata are skipped", schema, BackupSchemaVersion))
+	}
+	backup, backupErr := utilversion.ParseGeneric(backupVersion)
+	running, runningErr := utilversion.ParseGeneric(runningVersion)
+	switch {
+	case backupErr != nil || runningErr != nil:
+		// Not comparable, the schema is the contract
+	case backup.Major() != running.Major():
+		return CompatibilityUnsupported, fmt.Sprintf("backup taken by plugin %s cannot be restored by plugin %s of another major version", backupVersion, runningVersion)
+	case backup.Minor() > running.Minor():
+		warnings = append(warnings, fmt.Sprintf("backup taken by the newer plugin %s than %s, consider upgrading the plugin", backupVersion, runningVersion))
+	}
+	if len(warnings) > 0 {
+		return CompatibilitySupportedWithWarnings, strings.Join(warnings, "; ")
+	}
+	return CompatibilitySupported, ""
+}
@@ -0,0 +1,88 @@
+package common
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseBackupSchema(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        int
+		wantErr     bool
+	}{
+		{name: "When the annotation is missing, It Should return the legacy schema", want: BackupSchemaLegacy},
+		{name: "When the schema is recorded, It Should return it", annotations: map[string]string{BackupSchemaAnnotation: "1"}, want: BackupSchemaSourceMetadata},
+		{name: "When the schema is newer than the plugin one, It Should return it", annotations: map[string]string{BackupSchemaAnnotation: "7"}, want: 7},
+		{name: "When the schema is not a number, It Should return an error", annotations: map[string]string{BackupSchemaAnnotation: "v1"}, wantErr: true},
+		{name: "When the schema is zero, It Should return an error", annotations: map[string]string{BackupSchemaAnnotation: "0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			schema, err := ParseBackupSchema(tt.annotations)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(schema).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestCheckPluginCompatibility(t *testing.T) {
+	tests := []struct {
+		name           string
+		schema         int
+		backupVersion  string
+		runningVersion string
+		want           Compatibility
+	}{
+		{
+			name:   "When the schema and versions match, It Should be supported",
+			schema: BackupSchemaVersion, backupVersion: "v1.3.0", runningVersion: "v1.3.2",
+			want: CompatibilitySupported,
+		},
+		{
+			name:   "When the backup is from an older minor version, It Should be supported",
+			schema: BackupSchemaVersion, backupVersion: "v1.1.0", runningVersion: "v1.3.0",
+			want: CompatibilitySupported,
+		},
+		{
+			name:   "When the backup is from a newer minor version, It Should be supported with warnings",
+			schema: BackupSchemaVersion, backupVersion: "v1.4.0", runningVersion: "v1.3.0",
+			want: CompatibilitySupportedWithWarnings,
+		},
+		{
+			name:   "When the backup predates the schema, It Should be supported with warnings",
+			schema: BackupSchemaLegacy, backupVersion: "", runningVersion: "v1.3.0",
+			want: CompatibilitySupportedWithWarnings,
+		},
+		{
+			name:   "When the backup has a newer schema, It Should be unsupported",
+			schema: BackupSchemaVersion + 1, backupVersion: "v1.4.0", runningVersion: "v1.3.0",
+			want: CompatibilityUnsupported,
+		},
+		{
+			name:   "When the backup is from another major version, It Should be unsupported",
+			schema: BackupSchemaVersion, backupVersion: "v2.0.0", runningVersion: "v1.3.0",
+			want: CompatibilityUnsupported,
+		},
+		{
+			name:   "When the versions are development builds, It Should compare the schema only",
+			schema: BackupSchemaVersion, backupVersion: "main", runningVersion: "unknown",
+			want: CompatibilitySupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got, reason := CheckPluginCompatibility(tt.schema, tt.backupVersion, tt.runningVersion)
+			g.Expect(got).To(Equal(tt.want))
+			g.Expect(reason == "").To(Equal(tt.want == CompatibilitySupported))
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openshift/hypershift-oadp-plugin/pkg/tracing"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	"go.opentelemetry.io/otel/attribute"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const capiGroup = "cluster.x-k8s.io"
+
+// DefaultPausedKinds are the cluster-api kinds HyperShift pauses through the paused
+// annotation, those unpause-restore resumes unless the pausedKinds option lists others.
+var DefaultPausedKinds = []schema.GroupKind{
+	{Group: capiGroup, Kind: "Cluster"},
+	{Group: capiGroup, Kind: "MachineDeployment"},
+	{Group: capiGroup, Kind: "MachineSet"},
+	{Group: capiGroup, Kind: "Machine"},
+}
+
+// ParsePausedKinds parses the pausedKinds option, a comma-separated list of Kind.group, e.g.
+// AWSMachine.infrastructure.cluster.x-k8s.io. An empty value returns DefaultPausedKinds.
+func ParsePausedKinds(value string) ([]schema.GroupKind, error) {
+	if strings.TrimSpace(value) == "" {
+		return DefaultPausedKinds, nil
+	}
+	var kinds []schema.GroupKind
+	for _, entry := range strings.Split(value, ",") {
+		gk := schema.ParseGroupKind(strings.TrimSpace(entry))
+		if gk.Kind == "" || gk.Group == "" {
+			return nil, NewValidationError("invalid %s %q: %q is not a Kind.group, e.g. Machine.%s", ConfigKeyPausedKinds, value, strings.TrimSpace(entry), capiGroup)
+		}
+		kinds = append(kinds, gk)
+	}
+	return kinds, nil
+}
+
+// WaitForCAPIProviders waits until the cluster-api manager and, on platforms that have
+// one, the provider deployment in the HCP namespace are Available, within the CAPIProviders
+// timeout.
+func WaitForCAPIProviders(ctx context.Context, c crclient.Client, hcpNamespace string, platform hyperv1.PlatformType, timeouts Timeouts) (err error) {
+	ctx, span := tracing.Start(ctx, "common.WaitForCAPIProviders", attribute.String("namespace", hcpNamespace))
+	defer func() { tracing.End(span, err) }()
+
+	deployments := []string{CAPIManagerDeploymentName}
+	if platform != hyperv1.NonePlatform {
+		deployments = append(deployments, CAPIProviderDeploymentName)
+	}
+
+	timeouts = timeouts.WithDefaults()
+	var pending []string
+	err = Poll(ctx, timeouts.Clock, timeouts.CAPIProvidersPoll, timeouts.CAPIProviders, true, func(ctx context.Context) (bool, error) {
+		pending = pending[:0]
+		for _, name := range deployments {
+			deployment := &appsv1.Deployment{}
+			if err := c.Get(ctx, crclient.ObjectKey{Name: name, Namespace: hcpNamespace}, deployment); err != nil {
+				if !apierrors.IsNotFound(err) {
+					return false, err
+				}
+				pending = append(pending, name)
+				continue
+			}
+			if !isDeploymentAvailable(deployment) {
+				pending = append(pending, name)
+			}
+		}
+		return len(pending) == 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("cluster-api deployments %v in namespace %s are not available: %w", pending, hcpNamespace,
+			WrapWaitError(err, "the cluster-api deployments", timeouts.CAPIProviders))
+	}
+	return nil
+}
+
+func isDeploymentAvailable(deployment *appsv1.Deployment) bool {
+	for _, cond := range deployment.Status.Conditions {
+		if cond.Type == appsv1.DeploymentAvailable {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// UnpauseCAPIResources removes the cluster-api paused annotation from the objects of the
+// kinds in the HCP namespace. Kinds not served by the cluster are skipped.
+func UnpauseCAPIResources(ctx context.Context, c crclient.Client, hcpNamespace string, kinds []schema.GroupKind) error {
+	for _, gk := range kinds {
+		objects, err := listObjects(ctx, c, hcpNamespace, gk)
+		if err != nil {
+			return err
+		}
+
+		for i := range objects {
+			obj := &objects[i]
+			if _, ok := obj.GetAnnotations()[CAPIPausedAnnotation]; !ok {
+				continue
+			}
+			patch := crclient.MergeFrom(obj.DeepCopy())
+			RemoveAnnotation(obj, CAPIPausedAnnotation)
+			if err := c.Patch(ctx, obj, patch); err != nil {
+				return fmt.Errorf("error unpausing %s %s/%s: %w", gk.Kind, hcpNamespace, obj.GetName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// listCAPIObjects lists the cluster-api objects of the kind in the namespace, none when the
+// kind is not served by the cluster.
+func listCAPIObjects(ctx context.Context, c crclient.Client, namespace, kind string) ([]unstructured.Unstructured, error) {
+	return listObjects(ctx, c, namespace, schema.GroupKind{Group: capiGroup, Kind: kind})
+}
+
+// listObjects lists the objects of the kind in the namespace, at the version the cluster
+// prefers, v1beta1 when its RESTMapper does not know the kind. Kinds not served by the
+// cluster have none.
+func listObjects(ctx context.Context, c crclient.Client, namespace string, gk schema.GroupKind) ([]unstructured.Unstructured, error) {
+	gvk := gk.WithVersion("v1beta1")
+	if mapping, err := c.RESTMapper().RESTMapping(gk); err == nil {
+		gvk = mapping.GroupVersionKind
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := c.List(ctx, list, crclient.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing %s objects in namespace %s: %w", gk.Kind, namespace, err)
+	}
+	return list.Items, nil
+}
@@ -0,0 +1,192 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/common/clocktest"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newCAPIDeployment(name, namespace string, available bool) *appsv1.Deployment {
+	status := corev1.ConditionFalse
+	if available {
+		status = corev1.ConditionTrue
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status: appsv1.DeploymentStatus{
+			Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: status}},
+		},
+	}
+}
+
+func newCAPIObject(kind, name, namespace string, annotations map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{Group: "cluster.x-k8s.io", Version: "v1beta1", Kind: kind})
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	obj.SetAnnotations(annotations)
+	return obj
+}
+
+func TestWaitForCAPIProviders(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+
+	tests := []struct {
+		name     string
+		platform hyperv1.PlatformType
+		objects  []crclient.Object
+		wantErr  bool
+	}{
+		{
+			name:     "When the manager and provider deployments are available, It Should return nil",
+			platform: hyperv1.AWSPlatform,
+			objects: []crclient.Object{
+				newCAPIDeployment(CAPIManagerDeploymentName, "clusters-test", true),
+				newCAPIDeployment(CAPIProviderDeploymentName, "clusters-test", true),
+			},
+		},
+		{
+			name:     "When the provider deployment is not available, It Should time out",
+			platform: hyperv1.AWSPlatform,
+			objects: []crclient.Object{
+				newCAPIDeployment(CAPIManagerDeploymentName, "clusters-test", true),
+				newCAPIDeployment(CAPIProviderDeploymentName, "clusters-test", false),
+			},
+			wantErr: true,
+		},
+		{
+			name:     "When the provider deployment is missing, It Should time out",
+			platform: hyperv1.AWSPlatform,
+			objects: []crclient.Object{
+				newCAPIDeployment(CAPIManagerDeploymentName, "clusters-test", true),
+			},
+			wantErr: true,
+		},
+		{
+			name:     "When the platform is None, It Should only wait for the manager deployment",
+			platform: hyperv1.NonePlatform,
+			objects: []crclient.Object{
+				newCAPIDeployment(CAPIManagerDeploymentName, "clusters-test", true),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.objects...).Build()
+
+			err := WaitForCAPIProviders(context.TODO(), c, "clusters-test", tt.platform, Timeouts{Clock: clocktest.New(t, DefaultTimeouts.CAPIProvidersPoll)})
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(CAPIProviderDeploymentName))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
+
+func TestUnpauseCAPIResources(t *testing.T) {
+	g := NewWithT(t)
+	paused := map[string]string{CAPIPausedAnnotation: "true", "other": "value"}
+
+	cluster := newCAPIObject("Cluster", "test", "clusters-test", paused)
+	machine := newCAPIObject("Machine", "test-machine", "clusters-test", paused)
+	otherNamespace := newCAPIObject("Machine", "other-machine", "clusters-other", paused)
+
+	c := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(cluster, machine, otherNamespace).Build()
+
+	g.Expect(UnpauseCAPIResources(context.TODO(), c, "clusters-test", DefaultPausedKinds)).To(Succeed())
+
+	for _, obj := range []*unstructured.Unstructured{cluster, machine} {
+		g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(obj), obj)).To(Succeed())
+		g.Expect(obj.GetAnnotations()).NotTo(HaveKey(CAPIPausedAnnotation))
+		g.Expect(obj.GetAnnotations()).To(HaveKeyWithValue("other", "value"))
+	}
+
+	g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(otherNamespace), otherNamespace)).To(Succeed())
+	g.Expect(otherNamespace.GetAnnotations()).To(HaveKey(CAPIPausedAnnotation))
+}
+
+func TestUnpauseCAPIResourcesPausedKinds(t *testing.T) {
+	g := NewWithT(t)
+	paused := map[string]string{CAPIPausedAnnotation: "true"}
+
+	cluster := newCAPIObject("Cluster", "test", "clusters-test", paused)
+	awsMachine := &unstructured.Unstructured{}
+	awsMachine.SetGroupVersionKind(schema.GroupVersionKind{Group: "infrastructure.cluster.x-k8s.io", Version: "v1beta1", Kind: "AWSMachine"})
+	awsMachine.SetName("test-machine")
+	awsMachine.SetNamespace("clusters-test")
+	awsMachine.SetAnnotations(paused)
+
+	c := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(cluster, awsMachine).Build()
+
+	kinds, err := ParsePausedKinds("AWSMachine.infrastructure.cluster.x-k8s.io")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(UnpauseCAPIResources(context.TODO(), c, "clusters-test", kinds)).To(Succeed())
+
+	// Only the listed kinds are unpaused
+	g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(awsMachine), awsMachine)).To(Succeed())
+	g.Expect(awsMachine.GetAnnotations()).NotTo(HaveKey(CAPIPausedAnnotation))
+	g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(cluster), cluster)).To(Succeed())
+	g.Expect(cluster.GetAnnotations()).To(HaveKey(CAPIPausedAnnotation))
+}
+
+func TestParsePausedKinds(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []schema.GroupKind
+		wantErr bool
+	}{
+		{
+			name:  "When the option is unset, It Should return the cluster-api kinds",
+			value: "",
+			want:  DefaultPausedKinds,
+		},
+		{
+			name:  "When the option lists kinds, It Should return exactly those",
+			value: "Machine.cluster.x-k8s.io, AWSMachine.infrastructure.cluster.x-k8s.io",
+			want: []schema.GroupKind{
+				{Group: "cluster.x-k8s.io", Kind: "Machine"},
+				{Group: "infrastructure.cluster.x-k8s.io", Kind: "AWSMachine"},
+			},
+		},
+		{
+			name:    "When a kind has no group, It Should return a validation error",
+			value:   "Machine.cluster.x-k8s.io,AWSMachine",
+			wantErr: true,
+		},
+		{
+			name:    "When an entry is empty, It Should return a validation error",
+			value:   "Machine.cluster.x-k8s.io,",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			kinds, err := ParsePausedKinds(tt.value)
+			if tt.wantErr {
+				g.Expect(ErrorClass(err)).To(Equal(ErrorClassValidation))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(kinds).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+// Package clocktest drives the fake clock the wait helpers of the plugin poll with, through
+// common.Timeouts, so tests expire their timeouts instantly instead of sleeping through them.
+package clocktest
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// New returns a fake clock that, until the test ends, moves forward by step whenever a timer
+// or ticker waits on it. Waits thus run through their poll intervals and timeouts at once:
+// a condition never met times out after timeout/step polls at most, without a real sleep.
+func New(t testing.TB, step time.Duration) *clocktesting.FakeClock {
+	clk := clocktesting.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if clk.HasWaiters() {
+				clk.Step(step)
+				runtime.Gosched()
+			} else {
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+	return clk
+}
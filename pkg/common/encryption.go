@@ -0,0 +1,110 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AESCBCKeySecretKey is the Secret key holding an aescbc encryption key.
+const AESCBCKeySecretKey = "key"
+
+// SecretEncryptionType describes how the secrets of the hosted cluster are encrypted in
+// etcd: "aescbc", or "kms/<provider>" such as "kms/AWS". It is empty without encryption.
+func SecretEncryptionType(spec *hyperv1.SecretEncryptionSpec) string {
+	if spec == nil {
+		return ""
+	}
+	if spec.Type == hyperv1.KMS && spec.KMS != nil {
+		return fmt.Sprintf("kms/%s", spec.KMS.Provider)
+	}
+	return strings.ToLower(string(spec.Type))
+}
+
+// EncryptionSecrets returns the names of the Secrets the hosted cluster needs to decrypt
+// its etcd data: the aescbc keys, or the credentials of an unmanaged IBM Cloud KMS. The
+// other KMS providers authenticate with cloud identities and need no Secret.
+func EncryptionSecrets(spec *hyperv1.SecretEncryptionSpec) []string {
+	if spec == nil {
+		return nil
+	}
+	var names []string
+	switch spec.Type {
+	case hyperv1.AESCBC:
+		if spec.AESCBC == nil {
+			return nil
+		}
+		names = append(names, spec.AESCBC.ActiveKey.Name)
+		if spec.AESCBC.BackupKey != nil && spec.AESCBC.BackupKey.Name != "" {
+			names = append(names, spec.AESCBC.BackupKey.Name)
+		}
+	case hyperv1.KMS:
+		if spec.KMS != nil && spec.KMS.IBMCloud != nil && spec.KMS.IBMCloud.Auth.Unmanaged != nil {
+			names = append(names, spec.KMS.IBMCloud.Auth.Unmanaged.Credentials.Name)
+		}
+	}
+	return names
+}
+
+// EncryptionKeyFingerprints returns the SHA-256 fingerprints of the aescbc keys of the
+// namespace by Secret name, for a restore to tell whether it decrypts with the backed up
+// keys. It returns nil for other encryption types.
+func EncryptionKeyFingerprints(ctx context.Context, c crclient.Client, namespace string, spec *hyperv1.SecretEncryptionSpec) (map[string]string, error) {
+	if spec == nil || spec.Type != hyperv1.AESCBC {
+		return nil, nil
+	}
+	fingerprints := map[string]string{}
+	for _, name := range EncryptionSecrets(spec) {
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, crclient.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+			return nil, fmt.Errorf("error getting encryption Secret %s/%s: %w", namespace, name, err)
+		}
+		fingerprints[name] = keyFingerprint(secret.Data[AESCBCKeySecretKey])
+	}
+	return fingerprints, nil
+}
+
+// CheckEncryptionSecrets checks the Secrets returned by EncryptionSecrets exist in the
+// namespace, with an aescbc key when the encryption is aescbc. With fingerprints, recorded
+// by EncryptionKeyFingerprints at backup time, the keys must also be the backed up ones:
+// a Secret already in the namespace is not overwritten by a restore. Problems are reported
+// together in a ValidationError, since the etcd data cannot be decrypted.
+func CheckEncryptionSecrets(ctx context.Context, c crclient.Client, namespace string, spec *hyperv1.SecretEncryptionSpec, fingerprints map[string]string) error {
+	var problems []string
+	for _, name := range EncryptionSecrets(spec) {
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, crclient.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return fmt.Errorf("error getting encryption Secret %s/%s: %w", namespace, name, err)
+			}
+			problems = append(problems, fmt.Sprintf("Secret %s/%s does not exist", namespace, name))
+			continue
+		}
+		if spec.Type != hyperv1.AESCBC {
+			continue
+		}
+		key := secret.Data[AESCBCKeySecretKey]
+		switch {
+		case len(key) == 0:
+			problems = append(problems, fmt.Sprintf("Secret %s/%s has no %q key", namespace, name, AESCBCKeySecretKey))
+		case fingerprints[name] != "" && fingerprints[name] != keyFingerprint(key):
+			problems = append(problems, fmt.Sprintf("Secret %s/%s holds a different key than the backed up one", namespace, name))
+		}
+	}
+	if len(problems) > 0 {
+		return NewValidationError("secrets are encrypted with %s but %s", SecretEncryptionType(spec), strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+func keyFingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
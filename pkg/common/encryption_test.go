@@ -0,0 +1,130 @@
+package common
+
+// Test scenario names follow: "When <action or context>, It Should <expected outcome>".
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckEncryptionSecrets(t *testing.T) {
+	aescbc := &hyperv1.SecretEncryptionSpec{
+		Type: hyperv1.AESCBC,
+		AESCBC: &hyperv1.AESCBCSpec{
+			ActiveKey: corev1.LocalObjectReference{Name: "etcd-key-2"},
+			BackupKey: &corev1.LocalObjectReference{Name: "etcd-key-1"},
+		},
+	}
+	ibmKMS := &hyperv1.SecretEncryptionSpec{
+		Type: hyperv1.KMS,
+		KMS: &hyperv1.KMSSpec{
+			Provider: hyperv1.IBMCloud,
+			IBMCloud: &hyperv1.IBMCloudKMSSpec{Auth: hyperv1.IBMCloudKMSAuthSpec{
+				Type:      hyperv1.IBMCloudKMSUnmanagedAuth,
+				Unmanaged: &hyperv1.IBMCloudKMSUnmanagedAuthSpec{Credentials: corev1.LocalObjectReference{Name: "kms-credentials"}},
+			}},
+		},
+	}
+	awsKMS := &hyperv1.SecretEncryptionSpec{
+		Type: hyperv1.KMS,
+		KMS:  &hyperv1.KMSSpec{Provider: hyperv1.AWS, AWS: &hyperv1.AWSKMSSpec{Region: "us-east-1"}},
+	}
+	newSecret := func(name string, data map[string][]byte) crclient.Object {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "clusters-test"}, Data: data}
+	}
+	key := map[string][]byte{AESCBCKeySecretKey: []byte("c2VjcmV0")}
+
+	tests := []struct {
+		name    string
+		spec    *hyperv1.SecretEncryptionSpec
+		objects []crclient.Object
+		// fingerprints are recorded from the objects when set
+		fingerprintsFrom []crclient.Object
+		wantType         string
+		wantSecrets      []string
+		errSubstr        string
+	}{
+		{
+			name: "When secrets are not encrypted, It Should need no Secret",
+		},
+		{
+			name:        "When aescbc keys exist, It Should return no error",
+			spec:        aescbc,
+			objects:     []crclient.Object{newSecret("etcd-key-2", key), newSecret("etcd-key-1", key)},
+			wantType:    "aescbc",
+			wantSecrets: []string{"etcd-key-2", "etcd-key-1"},
+		},
+		{
+			name:        "When an aescbc key is missing or empty, It Should report both",
+			spec:        aescbc,
+			objects:     []crclient.Object{newSecret("etcd-key-1", nil)},
+			wantType:    "aescbc",
+			wantSecrets: []string{"etcd-key-2", "etcd-key-1"},
+			errSubstr:   `Secret clusters-test/etcd-key-2 does not exist, Secret clusters-test/etcd-key-1 has no "key" key`,
+		},
+		{
+			name:             "When an aescbc key differs from the backed up one, It Should return an error",
+			spec:             aescbc,
+			objects:          []crclient.Object{newSecret("etcd-key-2", map[string][]byte{AESCBCKeySecretKey: []byte("b3RoZXI=")}), newSecret("etcd-key-1", key)},
+			fingerprintsFrom: []crclient.Object{newSecret("etcd-key-2", key), newSecret("etcd-key-1", key)},
+			wantType:         "aescbc",
+			wantSecrets:      []string{"etcd-key-2", "etcd-key-1"},
+			errSubstr:        "Secret clusters-test/etcd-key-2 holds a different key than the backed up one",
+		},
+		{
+			name:             "When the aescbc keys are the backed up ones, It Should return no error",
+			spec:             aescbc,
+			objects:          []crclient.Object{newSecret("etcd-key-2", key), newSecret("etcd-key-1", key)},
+			fingerprintsFrom: []crclient.Object{newSecret("etcd-key-2", key), newSecret("etcd-key-1", key)},
+			wantType:         "aescbc",
+			wantSecrets:      []string{"etcd-key-2", "etcd-key-1"},
+		},
+		{
+			name:        "When the unmanaged IBM Cloud KMS credentials are missing, It Should return an error",
+			spec:        ibmKMS,
+			wantType:    "kms/IBMCloud",
+			wantSecrets: []string{"kms-credentials"},
+			errSubstr:   "secrets are encrypted with kms/IBMCloud but Secret clusters-test/kms-credentials does not exist",
+		},
+		{
+			name:     "When AWS KMS is used, It Should need no Secret",
+			spec:     awsKMS,
+			wantType: "kms/AWS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(SecretEncryptionType(tt.spec)).To(Equal(tt.wantType))
+			g.Expect(EncryptionSecrets(tt.spec)).To(Equal(tt.wantSecrets))
+
+			var fingerprints map[string]string
+			if tt.fingerprintsFrom != nil {
+				source := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(tt.fingerprintsFrom...).Build()
+				var err error
+				fingerprints, err = EncryptionKeyFingerprints(context.TODO(), source, "clusters-test", tt.spec)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(fingerprints).To(HaveLen(len(tt.fingerprintsFrom)))
+			}
+
+			c := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(tt.objects...).Build()
+			err := CheckEncryptionSecrets(context.TODO(), c, "clusters-test", tt.spec, fingerprints)
+			if tt.errSubstr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errSubstr)))
+				var validationErr *ValidationError
+				g.Expect(errors.As(err, &validationErr)).To(BeTrue())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
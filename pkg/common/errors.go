@@ -0,0 +1,108 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Error classes reported by ErrorClass.
+const (
+	ErrorClassValidation = "validation"
+	ErrorClassTransient  = "transient"
+	ErrorClassTimeout    = "timeout"
+	ErrorClassInternal   = "internal"
+)
+
+// ValidationError reports a precondition of the backup or restore that is not met, e.g. an
+// invalid configuration or a cluster in the wrong state. Retrying does not help until the
+// user fixes it.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// TransientError reports a failure that may not happen again, e.g. an API server that is
+// briefly unavailable or a conflicting update. The operation can be retried.
+type TransientError struct {
+	Err error
+}
+
+func (e *TransientError) Error() string { return e.Err.Error() }
+func (e *TransientError) Unwrap() error { return e.Err }
+
+// TimeoutError reports a wait for a resource that did not end in time.
+type TimeoutError struct {
+	Operation string
+	Timeout   time.Duration
+	Err       error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for %s: %v", e.Timeout, e.Operation, e.Err)
+}
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// NewValidationError formats a ValidationError.
+func NewValidationError(format string, args ...any) error {
+	return &ValidationError{Err: fmt.Errorf(format, args...)}
+}
+
+// WrapWaitError turns the error of a wait that was interrupted by its timeout into a
+// TimeoutError for the operation. Other errors, and nil, are returned unchanged.
+func WrapWaitError(err error, operation string, timeout time.Duration) error {
+	if err == nil || !wait.Interrupted(err) {
+		return err
+	}
+	return &TimeoutError{Operation: operation, Timeout: timeout, Err: err}
+}
+
+// IsRetryable reports whether the error, or one it wraps, is a TransientError or a
+// transient error of the Kubernetes API.
+func IsRetryable(err error) bool {
+	var transient *TransientError
+	return errors.As(err, &transient) || isTransientAPIError(err)
+}
+
+// ErrorClass classifies the error for logs and tests: validation, transient, timeout, or
+// internal for any other error. It returns an empty string for nil.
+func ErrorClass(err error) string {
+	var (
+		validationErr *ValidationError
+		timeoutErr    *TimeoutError
+	)
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &validationErr):
+		return ErrorClassValidation
+	case errors.As(err, &timeoutErr):
+		return ErrorClassTimeout
+	case IsRetryable(err):
+		return ErrorClassTransient
+	}
+	return ErrorClassInternal
+}
+
+// TolerateError logs the error of a non-critical problem as a warning and returns nil when
+// the problem is listed in the tolerateErrors option, so the item goes on. Velero counts the
+// warning on the Backup or Restore. Other errors, and nil, are returned unchanged.
+func TolerateError(log logrus.FieldLogger, tolerated []string, problem string, err error) error {
+	if err == nil || !slices.Contains(tolerated, problem) {
+		return err
+	}
+	log.Warnf("Tolerating %s problem: %v", problem, err)
+	return nil
+}
+
+func isTransientAPIError(err error) bool {
+	return apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsInternalError(err) || apierrors.IsConflict(err)
+}
@@ -0,0 +1,97 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestErrorClass(t *testing.T) {
+	resource := schema.GroupResource{Group: "hypershift.openshift.io", Resource: "hostedclusters"}
+
+	tests := []struct {
+		name          string
+		err           error
+		wantClass     string
+		wantRetryable bool
+	}{
+		{
+			name:      "When there is no error, It Should return no class",
+			err:       nil,
+			wantClass: "",
+		},
+		{
+			name:      "When a validation error is wrapped, It Should classify it as validation",
+			err:       fmt.Errorf("refusing to back up the hosted cluster: %w", NewValidationError("HostedCluster %s has been destroyed", "hc")),
+			wantClass: ErrorClassValidation,
+		},
+		{
+			name:      "When a wait timed out, It Should classify it as timeout",
+			err:       fmt.Errorf("HCPEtcdBackup failed: %w", WrapWaitError(wait.ErrorInterrupted(context.DeadlineExceeded), "HCPEtcdBackup", time.Minute)),
+			wantClass: ErrorClassTimeout,
+		},
+		{
+			name:          "When the API server is unavailable, It Should classify it as a retryable transient error",
+			err:           fmt.Errorf("error getting HostedCluster: %w", apierrors.NewServiceUnavailable("etcd leader changed")),
+			wantClass:     ErrorClassTransient,
+			wantRetryable: true,
+		},
+		{
+			name:          "When an update conflicts, It Should classify it as a retryable transient error",
+			err:           apierrors.NewConflict(resource, "hc", errors.New("object was modified")),
+			wantClass:     ErrorClassTransient,
+			wantRetryable: true,
+		},
+		{
+			name:          "When a TransientError is wrapped, It Should classify it as retryable",
+			err:           fmt.Errorf("webhook: %w", &TransientError{Err: errors.New("connection refused")}),
+			wantClass:     ErrorClassTransient,
+			wantRetryable: true,
+		},
+		{
+			name:      "When an object is not found, It Should classify it as internal",
+			err:       apierrors.NewNotFound(resource, "hc"),
+			wantClass: ErrorClassInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(ErrorClass(tt.err)).To(Equal(tt.wantClass))
+			g.Expect(IsRetryable(tt.err)).To(Equal(tt.wantRetryable))
+		})
+	}
+}
+
+func TestWrapWaitError(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(WrapWaitError(nil, "HCPEtcdBackup", time.Minute)).To(Succeed())
+
+	failed := errors.New("HCPEtcdBackup rejected")
+	g.Expect(WrapWaitError(failed, "HCPEtcdBackup", time.Minute)).To(BeIdenticalTo(failed))
+
+	err := WrapWaitError(wait.ErrorInterrupted(context.DeadlineExceeded), "HCPEtcdBackup clusters-hc/etcd", 10*time.Minute)
+	var timeoutErr *TimeoutError
+	g.Expect(errors.As(err, &timeoutErr)).To(BeTrue())
+	g.Expect(timeoutErr.Timeout).To(Equal(10 * time.Minute))
+	g.Expect(err).To(MatchError(HavePrefix("timed out after 10m0s waiting for HCPEtcdBackup clusters-hc/etcd")))
+}
+
+func TestTolerateError(t *testing.T) {
+	g := NewWithT(t)
+	err := errors.New("error getting pull secret clusters/pull-secret: not found")
+	tolerated := []string{TolerateReleaseImage}
+
+	g.Expect(TolerateError(logrus.New(), tolerated, TolerateReleaseImage, err)).To(Succeed())
+	g.Expect(TolerateError(logrus.New(), tolerated, TolerateSourceMetadata, err)).To(BeIdenticalTo(err))
+	g.Expect(TolerateError(logrus.New(), tolerated, TolerateReleaseImage, nil)).To(Succeed())
+}
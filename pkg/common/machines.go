@@ -0,0 +1,101 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MachineNodesConfigMapName is the ConfigMap of the control plane namespace mapping the
+// Machines of each NodePool, by NodePool name, to their cloud instance and Node.
+const MachineNodesConfigMapName = "hcp-machine-nodes"
+
+// MachineNode is the cloud instance and Node of a Machine.
+type MachineNode struct {
+	ProviderID string `json:"providerID"`
+	NodeName   string `json:"nodeName,omitempty"`
+}
+
+// RecordMachineNodes records the providerID and Node of the Machines of the NodePool under the NodePool name in the MachineNodesConfigMapName ConfigMap of the
+// control plane namespace, and returns the ConfigMap. Machines without providerID have no
+// instance yet and are left out.
+func RecordMachineNodes(ctx context.Context, c crclient.Client, hcpNamespace string, nodePool types.NamespacedName) (*corev1.ConfigMap, error) {
+	machines, err := listCAPIObjects(ctx, c, hcpNamespace, "Machine")
+	if err != nil {
+		return nil, err
+	}
+	nodes := map[string]MachineNode{}
+	for _, machine := range machines {
+		if machine.GetAnnotations()[NodePoolAnnotation] != nodePool.String() {
+			continue
+		}
+		providerID, _, _ := unstructured.NestedString(machine.Object, "spec", "providerID")
+		if providerID == "" {
+			continue
+		}
+		nodeName, _, _ := unstructured.NestedString(machine.Object, "status", "nodeRef", "name")
+		nodes[machine.GetName()] = MachineNode{ProviderID: providerID, NodeName: nodeName}
+	}
+	data, err := json.Marshal(nodes)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding the Machines of NodePool %s: %w", nodePool, err)
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := c.Get(ctx, crclient.ObjectKey{Namespace: hcpNamespace, Name: MachineNodesConfigMapName}, cm); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: MachineNodesConfigMapName, Namespace: hcpNamespace},
+				Data:       map[string]string{nodePool.Name: string(data)},
+			}
+			if err := c.Create(ctx, cm); err != nil {
+				if apierrors.IsAlreadyExists(err) {
+					return apierrors.NewConflict(corev1.Resource("configmaps"), MachineNodesConfigMapName, err)
+				}
+				return err
+			}
+			return nil
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[nodePool.Name] = string(data)
+		return c.Update(ctx, cm)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error recording the Machines of NodePool %s in ConfigMap %s/%s: %w", nodePool, hcpNamespace, MachineNodesConfigMapName, err)
+	}
+	return cm, nil
+}
+
+// MachineNodes returns the Machines of the NodePool recorded in the MachineNodesConfigMapName
+// ConfigMap of the namespace, none when nothing was recorded.
+func MachineNodes(ctx context.Context, c crclient.Client, namespace, nodePoolName string) (map[string]MachineNode, error) {
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, crclient.ObjectKey{Namespace: namespace, Name: MachineNodesConfigMapName}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting ConfigMap %s/%s: %w", namespace, MachineNodesConfigMapName, err)
+	}
+	data, ok := cm.Data[nodePoolName]
+	if !ok {
+		return nil, nil
+	}
+	nodes := map[string]MachineNode{}
+	if err := json.Unmarshal([]byte(data), &nodes); err != nil {
+		return nil, fmt.Errorf("error decoding the Machines of NodePool %s in ConfigMap %s/%s: %w", nodePoolName, namespace, MachineNodesConfigMapName, err)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRecordMachineNodes(t *testing.T) {
+	g := NewWithT(t)
+	newMachine := func(name, nodePool, providerID, nodeName string) *unstructured.Unstructured {
+		machine := newCAPIObject("Machine", name, "clusters-test", map[string]string{NodePoolAnnotation: nodePool})
+		if providerID != "" {
+			g.Expect(unstructured.SetNestedField(machine.Object, providerID, "spec", "providerID")).To(Succeed())
+		}
+		if nodeName != "" {
+			g.Expect(unstructured.SetNestedField(machine.Object, nodeName, "status", "nodeRef", "name")).To(Succeed())
+		}
+		return machine
+	}
+	c := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(
+		newMachine("workers-abc12", "clusters/workers", "aws:///us-east-1a/i-0123", "ip-10-0-1-12.ec2.internal"),
+		newMachine("workers-def34", "clusters/workers", "", ""),
+		newMachine("infra-ghi56", "clusters/infra", "aws:///us-east-1b/i-0456", "ip-10-0-2-7.ec2.internal"),
+	).Build()
+
+	// Each NodePool records its own key, the second one updating the ConfigMap
+	for _, nodePool := range []string{"workers", "infra"} {
+		cm, err := RecordMachineNodes(context.TODO(), c, "clusters-test", types.NamespacedName{Namespace: "clusters", Name: nodePool})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(cm.Name).To(Equal(MachineNodesConfigMapName))
+	}
+
+	nodes, err := MachineNodes(context.TODO(), c, "clusters-test", "workers")
+	g.Expect(err).NotTo(HaveOccurred())
+	// Machines without instance are left out
+	g.Expect(nodes).To(Equal(map[string]MachineNode{
+		"workers-abc12": {ProviderID: "aws:///us-east-1a/i-0123", NodeName: "ip-10-0-1-12.ec2.internal"},
+	}))
+
+	nodes, err = MachineNodes(context.TODO(), c, "clusters-test", "infra")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(nodes).To(HaveKey("infra-ghi56"))
+
+	nodes, err = MachineNodes(context.TODO(), c, "clusters-other", "workers")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(nodes).To(BeEmpty())
+}
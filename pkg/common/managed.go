@@ -0,0 +1,22 @@
+package common
+
+import (
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+)
+
+// ManagedService returns the managed service running the HostedCluster, ROSA HCP on AWS
+// and ARO HCP on Azure, told by the labels of OpenShift Cluster Manager. It returns an
+// empty string for self-managed HostedClusters.
+func ManagedService(hc *hyperv1.HostedCluster) string {
+	_, hasID := hc.Labels[ManagedClusterIDLabel]
+	if hc.Labels[ManagedClusterLabel] != "true" && !hasID {
+		return ""
+	}
+	switch hc.Spec.Platform.Type {
+	case hyperv1.AWSPlatform:
+		return ManagedServiceROSA
+	case hyperv1.AzurePlatform:
+		return ManagedServiceARO
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestManagedService(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		platform hyperv1.PlatformType
+		want     string
+	}{
+		{
+			name:     "When an AWS HostedCluster is labeled managed, It Should return ROSA HCP",
+			labels:   map[string]string{ManagedClusterLabel: "true"},
+			platform: hyperv1.AWSPlatform,
+			want:     ManagedServiceROSA,
+		},
+		{
+			name:     "When an Azure HostedCluster has a cluster ID, It Should return ARO HCP",
+			labels:   map[string]string{ManagedClusterIDLabel: "2a3b4c5d6e7f"},
+			platform: hyperv1.AzurePlatform,
+			want:     ManagedServiceARO,
+		},
+		{
+			name:     "When the HostedCluster has no managed labels, It Should return none",
+			labels:   map[string]string{ManagedClusterLabel: "false"},
+			platform: hyperv1.AWSPlatform,
+		},
+		{
+			name:     "When a managed HostedCluster is on another platform, It Should return none",
+			labels:   map[string]string{ManagedClusterLabel: "true"},
+			platform: hyperv1.KubevirtPlatform,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			hc := &hyperv1.HostedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters", Labels: tt.labels},
+				Spec:       hyperv1.HostedClusterSpec{Platform: hyperv1.PlatformSpec{Type: tt.platform}},
+			}
+			g.Expect(ManagedService(hc)).To(Equal(tt.want))
+		})
+	}
+}
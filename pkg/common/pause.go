@@ -0,0 +1,513 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openshift/hypershift-oadp-plugin/pkg/tracing"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"go.opentelemetry.io/otel/attribute"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PauseHostedCluster pauses the reconciliation of the HostedCluster and its NodePools while
+// they are backed up, flagging the objects it paused with PausedForBackupAnnotation set to
+// the backup name. Objects already paused by someone else are left untouched, so
+// UnpauseHostedCluster does not resume them; they are returned as "Kind namespace/name
+// (pausedUntil value)" for the caller to report.
+func PauseHostedCluster(ctx context.Context, c crclient.Client, namespace, name, backupName string) (pausedElsewhere []string, err error) {
+	ctx, span := tracing.Start(ctx, "common.PauseHostedCluster", attribute.String("namespace", namespace), attribute.String("name", name))
+	defer func() { tracing.End(span, err) }()
+
+	hc := &hyperv1.HostedCluster{}
+	if err := c.Get(ctx, crclient.ObjectKey{Name: name, Namespace: namespace}, hc); err != nil {
+		return nil, fmt.Errorf("error getting HostedCluster %s/%s: %w", namespace, name, err)
+	}
+
+	// The HostedCluster goes first: it stops the NodePool changes it would propagate
+	if external, err := pauseForBackup(ctx, c, hc, &hc.Spec.PausedUntil, backupName); err != nil {
+		return nil, fmt.Errorf("error pausing HostedCluster %s/%s: %w", namespace, name, err)
+	} else if external {
+		pausedElsewhere = append(pausedElsewhere, describePaused(HostedClusterKind, hc, hc.Spec.PausedUntil))
+	}
+	if err := markBackupInProgress(ctx, c, hc, backupName); err != nil {
+		return nil, fmt.Errorf("error annotating HostedCluster %s/%s: %w", namespace, name, err)
+	}
+
+	nodePools, err := listNodePools(ctx, c, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	for i := range nodePools {
+		np := &nodePools[i]
+		if external, err := pauseForBackup(ctx, c, np, &np.Spec.PausedUntil, backupName); err != nil {
+			return nil, fmt.Errorf("error pausing NodePool %s/%s: %w", namespace, np.Name, err)
+		} else if external {
+			pausedElsewhere = append(pausedElsewhere, describePaused(NodePoolKind, np, np.Spec.PausedUntil))
+		}
+	}
+
+	// Remediation and autoscaling replace and add Machines even while HyperShift is paused
+	hcpNamespace := GetHCPNamespace(name, namespace)
+	mhcPausedElsewhere, err := pauseMachineHealthChecks(ctx, c, hcpNamespace, backupName)
+	if err != nil {
+		return nil, err
+	}
+	if err := pinAutoscaling(ctx, c, hcpNamespace, nodePools, backupName); err != nil {
+		return nil, err
+	}
+	return append(pausedElsewhere, mhcPausedElsewhere...), nil
+}
+
+// UnpauseHostedCluster resumes the HostedCluster and NodePools paused by PauseHostedCluster,
+// the HostedCluster last, so an interrupted run can be repeated safely. Objects whose
+// spec.pausedUntil was changed by someone else during the backup keep that value and are
+// returned like in PauseHostedCluster; only their PausedForBackupAnnotation is removed.
+func UnpauseHostedCluster(ctx context.Context, c crclient.Client, namespace, name string) (pausedElsewhere []string, err error) {
+	ctx, span := tracing.Start(ctx, "common.UnpauseHostedCluster", attribute.String("namespace", namespace), attribute.String("name", name))
+	defer func() { tracing.End(span, err) }()
+
+	hcpNamespace := GetHCPNamespace(name, namespace)
+	if err := resumeAutoscaling(ctx, c, hcpNamespace); err != nil {
+		return nil, err
+	}
+	if err := resumeMachineHealthChecks(ctx, c, hcpNamespace); err != nil {
+		return nil, err
+	}
+
+	nodePools, err := listNodePools(ctx, c, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	for i := range nodePools {
+		np := &nodePools[i]
+		if kept, err := unpauseForBackup(ctx, c, np, &np.Spec.PausedUntil); err != nil {
+			return nil, fmt.Errorf("error resuming NodePool %s/%s: %w", namespace, np.Name, err)
+		} else if kept {
+			pausedElsewhere = append(pausedElsewhere, describePaused(NodePoolKind, np, np.Spec.PausedUntil))
+		}
+	}
+
+	hc := &hyperv1.HostedCluster{}
+	if err := c.Get(ctx, crclient.ObjectKey{Name: name, Namespace: namespace}, hc); err != nil {
+		return nil, fmt.Errorf("error getting HostedCluster %s/%s: %w", namespace, name, err)
+	}
+	if kept, err := unpauseForBackup(ctx, c, hc, &hc.Spec.PausedUntil); err != nil {
+		return nil, fmt.Errorf("error resuming HostedCluster %s/%s: %w", namespace, name, err)
+	} else if kept {
+		pausedElsewhere = append(pausedElsewhere, describePaused(HostedClusterKind, hc, hc.Spec.PausedUntil))
+	}
+	if err := clearBackupInProgress(ctx, c, hc); err != nil {
+		return nil, fmt.Errorf("error annotating HostedCluster %s/%s: %w", namespace, name, err)
+	}
+	return pausedElsewhere, nil
+}
+
+// WaitForPausedPropagated waits until HyperShift has propagated the pause of the
+// HostedCluster and its NodePools: the HostedControlPlane holds spec.pausedUntil, and the
+// cluster-api Cluster and the MachineDeployments and MachineSets of the paused NodePools are
+// paused, so cluster-api does not act on Machines while they are backed up. Each object is
+// given the pausePropagation timeout of its own, so a cluster with many NodePools does not
+// share one deadline among them. Objects deleted meanwhile are not waited for.
+func WaitForPausedPropagated(ctx context.Context, c crclient.Client, namespace, name string, timeouts Timeouts) (err error) {
+	ctx, span := tracing.Start(ctx, "common.WaitForPausedPropagated", attribute.String("namespace", namespace), attribute.String("name", name))
+	defer func() { tracing.End(span, err) }()
+
+	return waitForPauseState(ctx, c, namespace, name, timeouts, true)
+}
+
+// WaitForUnpausedPropagated is the counterpart of WaitForPausedPropagated once the cluster
+// is resumed: the HostedControlPlane, the cluster-api Cluster and the machinery of the
+// resumed NodePools must no longer be paused, so a backup does not end while the cluster is
+// still effectively frozen. Objects someone else keeps paused are not waited for.
+func WaitForUnpausedPropagated(ctx context.Context, c crclient.Client, namespace, name string, timeouts Timeouts) (err error) {
+	ctx, span := tracing.Start(ctx, "common.WaitForUnpausedPropagated", attribute.String("namespace", namespace), attribute.String("name", name))
+	defer func() { tracing.End(span, err) }()
+
+	return waitForPauseState(ctx, c, namespace, name, timeouts, false)
+}
+
+// waitForPauseState waits until the objects HyperShift propagates the pause of the
+// HostedCluster and NodePools to are paused, or resumed, like their owner.
+func waitForPauseState(ctx context.Context, c crclient.Client, namespace, name string, timeouts Timeouts, paused bool) error {
+	timeouts = timeouts.WithDefaults()
+	state := "pause"
+	if !paused {
+		state = "resume"
+	}
+
+	hc := &hyperv1.HostedCluster{}
+	if err := c.Get(ctx, crclient.ObjectKey{Name: name, Namespace: namespace}, hc); err != nil {
+		return fmt.Errorf("error getting HostedCluster %s/%s: %w", namespace, name, err)
+	}
+	clusterInState := (hc.Spec.PausedUntil != nil) == paused
+	hcpNamespace := GetHCPNamespace(name, namespace)
+	if clusterInState {
+		hcpKey := crclient.ObjectKey{Name: name, Namespace: hcpNamespace}
+		err := waitForPropagation(ctx, timeouts, fmt.Sprintf("the %s of HostedControlPlane %s", state, hcpKey), func(ctx context.Context) (bool, error) {
+			hcp := &hyperv1.HostedControlPlane{}
+			if err := c.Get(ctx, hcpKey, hcp); err != nil {
+				return apierrors.IsNotFound(err), crclient.IgnoreNotFound(err)
+			}
+			return (hcp.Spec.PausedUntil != nil) == paused, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	nodePools, err := listNodePools(ctx, c, namespace, name)
+	if err != nil {
+		return err
+	}
+	nodePoolsInState := map[string]bool{}
+	for _, np := range nodePools {
+		if (np.Spec.PausedUntil != nil) == paused {
+			nodePoolsInState[namespace+"/"+np.Name] = true
+		}
+	}
+	for _, kind := range []string{"Cluster", "MachineDeployment", "MachineSet"} {
+		objects, err := listCAPIObjects(ctx, c, hcpNamespace, kind)
+		if err != nil {
+			return err
+		}
+		for i := range objects {
+			obj := &objects[i]
+			if kind == "Cluster" && !clusterInState || kind != "Cluster" && !nodePoolsInState[obj.GetAnnotations()[NodePoolAnnotation]] {
+				continue
+			}
+			key := crclient.ObjectKeyFromObject(obj)
+			err := waitForPropagation(ctx, timeouts, fmt.Sprintf("the %s of %s %s", state, kind, key), func(ctx context.Context) (bool, error) {
+				if err := c.Get(ctx, key, obj); err != nil {
+					return apierrors.IsNotFound(err), crclient.IgnoreNotFound(err)
+				}
+				return isCAPIPaused(obj) == paused, nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// waitForPropagation polls the condition within the pausePropagation timeout, which bounds
+// the propagation of the resume as well.
+func waitForPropagation(ctx context.Context, timeouts Timeouts, operation string, condition wait.ConditionWithContextFunc) error {
+	err := Poll(ctx, timeouts.Clock, timeouts.PausePropagationPoll, timeouts.PausePropagation, true, condition)
+	return WrapWaitError(err, operation, timeouts.PausePropagation)
+}
+
+// isCAPIPaused reports whether a cluster-api object is paused, through spec.paused, which
+// HyperShift sets on the Cluster, or the paused annotation it sets on the NodePool machinery.
+func isCAPIPaused(obj *unstructured.Unstructured) bool {
+	if paused, _, _ := unstructured.NestedBool(obj.Object, "spec", "paused"); paused {
+		return true
+	}
+	_, ok := obj.GetAnnotations()[CAPIPausedAnnotation]
+	return ok
+}
+
+// RunningBackupPausedFor returns the name of the Velero Backup an earlier run of the backup
+// command paused the HostedCluster for, when that Backup is still running, so a run
+// interrupted by a crash or a restart waits for it instead of starting another backup.
+// It returns an empty name otherwise.
+func RunningBackupPausedFor(ctx context.Context, c crclient.Client, hc *hyperv1.HostedCluster, veleroNamespace string) (string, error) {
+	name := hc.Annotations[PausedForBackupAnnotation]
+	if name == "" {
+		return "", nil
+	}
+	backup := &velerov1.Backup{}
+	if err := c.Get(ctx, crclient.ObjectKey{Name: name, Namespace: veleroNamespace}, backup); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error getting Backup %s/%s: %w", veleroNamespace, name, err)
+	}
+	switch backup.Status.Phase {
+	case velerov1.BackupPhaseCompleted, velerov1.BackupPhasePartiallyFailed, velerov1.BackupPhaseFailed,
+		velerov1.BackupPhaseFailedValidation, velerov1.BackupPhaseDeleting:
+		return "", nil
+	}
+	return name, nil
+}
+
+// EarlierBackupsOf returns, as namespace/name, the Velero Backups of any namespace that
+// include the HCP namespace, are still backing up items and started before the backup, with
+// the name breaking ties. Only later backups see the earlier ones, so two backups of the same
+// hosted cluster never wait for each other.
+func EarlierBackupsOf(ctx context.Context, c crclient.Client, backup *velerov1.Backup, hcpNamespace string) ([]string, error) {
+	backups := &velerov1.BackupList{}
+	if err := c.List(ctx, backups); err != nil {
+		return nil, fmt.Errorf("error listing Backups: %w", err)
+	}
+
+	var earlier []string
+	for _, other := range backups.Items {
+		if other.UID == backup.UID && other.Namespace == backup.Namespace && other.Name == backup.Name {
+			continue
+		}
+		if other.Status.Phase != velerov1.BackupPhaseInProgress || !slices.Contains(other.Spec.IncludedNamespaces, hcpNamespace) {
+			continue
+		}
+		if startedBefore(&other, backup) {
+			earlier = append(earlier, other.Namespace+"/"+other.Name)
+		}
+	}
+	return earlier, nil
+}
+
+// startedBefore reports whether backup a started before backup b. A backup not started
+// yet comes last.
+func startedBefore(a, b *velerov1.Backup) bool {
+	switch {
+	case a.Status.StartTimestamp == nil:
+		return false
+	case b.Status.StartTimestamp == nil:
+		return true
+	case !a.Status.StartTimestamp.Equal(b.Status.StartTimestamp):
+		return a.Status.StartTimestamp.Before(b.Status.StartTimestamp)
+	}
+	return a.Namespace+"/"+a.Name < b.Namespace+"/"+b.Name
+}
+
+// listNodePools returns the NodePools of the HostedCluster, matched on spec.clusterName:
+// HostedClusters may share a namespace, and pausing or resuming one must not touch the
+// NodePools of the others.
+func listNodePools(ctx context.Context, c crclient.Client, namespace, name string) ([]hyperv1.NodePool, error) {
+	nodePools := &hyperv1.NodePoolList{}
+	if err := c.List(ctx, nodePools, crclient.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("error listing NodePools in namespace %s: %w", namespace, err)
+	}
+	var owned []hyperv1.NodePool
+	for _, np := range nodePools.Items {
+		if np.Spec.ClusterName == name {
+			owned = append(owned, np)
+		}
+	}
+	return owned, nil
+}
+
+// pausedForBackup is the spec.pausedUntil value set by pauseForBackup.
+const pausedForBackup = "true"
+
+// pauseForBackup pauses the object unless it is already paused, reporting whether it was
+// paused by someone else rather than by an earlier run of the backup.
+func pauseForBackup(ctx context.Context, c crclient.Client, obj crclient.Object, pausedUntil **string, backupName string) (bool, error) {
+	if _, ok := obj.GetAnnotations()[PausedForBackupAnnotation]; ok {
+		return false, nil
+	}
+	if *pausedUntil != nil {
+		return true, nil
+	}
+	patch := crclient.MergeFrom(obj.DeepCopyObject().(crclient.Object))
+	AddAnnotation(obj, PausedForBackupAnnotation, backupName)
+	paused := pausedForBackup
+	*pausedUntil = &paused
+	return false, c.Patch(ctx, obj, patch)
+}
+
+// unpauseForBackup resumes the object paused by pauseForBackup. When spec.pausedUntil no
+// longer holds the value the backup set, someone else paused the object meanwhile: the
+// value is kept and reported.
+func unpauseForBackup(ctx context.Context, c crclient.Client, obj crclient.Object, pausedUntil **string) (bool, error) {
+	if _, ok := obj.GetAnnotations()[PausedForBackupAnnotation]; !ok {
+		return false, nil
+	}
+	patch := crclient.MergeFrom(obj.DeepCopyObject().(crclient.Object))
+	RemoveAnnotation(obj, PausedForBackupAnnotation)
+	kept := *pausedUntil != nil && **pausedUntil != pausedForBackup
+	if !kept {
+		*pausedUntil = nil
+	}
+	return kept, c.Patch(ctx, obj, patch)
+}
+
+// markBackupInProgress records on the HostedCluster the backup it is paused for and since
+// when, so other tooling and humans can tell why the cluster is paused. A retried run keeps
+// the time of the first pause. The annotations are set even when someone else paused the
+// cluster, since the backup is in progress all the same.
+func markBackupInProgress(ctx context.Context, c crclient.Client, hc *hyperv1.HostedCluster, backupName string) error {
+	if _, ok := hc.Annotations[BackupPausedAtAnnotation]; ok && hc.Annotations[BackupInProgressAnnotation] == backupName {
+		return nil
+	}
+	patch := crclient.MergeFrom(hc.DeepCopy())
+	AddAnnotation(hc, BackupInProgressAnnotation, backupName)
+	if _, ok := hc.Annotations[BackupPausedAtAnnotation]; !ok {
+		AddAnnotation(hc, BackupPausedAtAnnotation, time.Now().UTC().Format(time.RFC3339))
+	}
+	return c.Patch(ctx, hc, patch)
+}
+
+// clearBackupInProgress removes the annotations set by markBackupInProgress.
+func clearBackupInProgress(ctx context.Context, c crclient.Client, hc *hyperv1.HostedCluster) error {
+	_, inProgress := hc.Annotations[BackupInProgressAnnotation]
+	_, pausedAt := hc.Annotations[BackupPausedAtAnnotation]
+	if !inProgress && !pausedAt {
+		return nil
+	}
+	patch := crclient.MergeFrom(hc.DeepCopy())
+	RemoveAnnotation(hc, BackupInProgressAnnotation)
+	RemoveAnnotation(hc, BackupPausedAtAnnotation)
+	return c.Patch(ctx, hc, patch)
+}
+
+// pauseMachineHealthChecks pauses the MachineHealthChecks of the control plane namespace
+// through the cluster-api paused annotation, so no unhealthy Machine is remediated during
+// the backup. MachineHealthChecks already paused by someone else are returned.
+func pauseMachineHealthChecks(ctx context.Context, c crclient.Client, hcpNamespace, backupName string) ([]string, error) {
+	checks, err := listCAPIObjects(ctx, c, hcpNamespace, "MachineHealthCheck")
+	if err != nil {
+		return nil, err
+	}
+	var pausedElsewhere []string
+	for i := range checks {
+		mhc := &checks[i]
+		if _, ok := mhc.GetAnnotations()[PausedForBackupAnnotation]; ok {
+			continue
+		}
+		if _, ok := mhc.GetAnnotations()[CAPIPausedAnnotation]; ok {
+			pausedElsewhere = append(pausedElsewhere, fmt.Sprintf("MachineHealthCheck %s/%s (%s)", hcpNamespace, mhc.GetName(), CAPIPausedAnnotation))
+			continue
+		}
+		patch := crclient.MergeFrom(mhc.DeepCopy())
+		AddAnnotation(mhc, PausedForBackupAnnotation, backupName)
+		AddAnnotation(mhc, CAPIPausedAnnotation, "")
+		if err := c.Patch(ctx, mhc, patch); err != nil {
+			return nil, fmt.Errorf("error pausing MachineHealthCheck %s/%s: %w", hcpNamespace, mhc.GetName(), err)
+		}
+	}
+	return pausedElsewhere, nil
+}
+
+// resumeMachineHealthChecks resumes the MachineHealthChecks paused by pauseMachineHealthChecks.
+func resumeMachineHealthChecks(ctx context.Context, c crclient.Client, hcpNamespace string) error {
+	checks, err := listCAPIObjects(ctx, c, hcpNamespace, "MachineHealthCheck")
+	if err != nil {
+		return err
+	}
+	for i := range checks {
+		mhc := &checks[i]
+		if _, ok := mhc.GetAnnotations()[PausedForBackupAnnotation]; !ok {
+			continue
+		}
+		patch := crclient.MergeFrom(mhc.DeepCopy())
+		RemoveAnnotation(mhc, PausedForBackupAnnotation)
+		RemoveAnnotation(mhc, CAPIPausedAnnotation)
+		if err := c.Patch(ctx, mhc, patch); err != nil {
+			return fmt.Errorf("error resuming MachineHealthCheck %s/%s: %w", hcpNamespace, mhc.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// pinAutoscaling stops the cluster autoscaler from scaling the autoscaled NodePools during
+// the backup. NodePools have no switch for it, so the node group bounds of their
+// MachineDeployments, which the paused NodePool controller no longer reconciles, are set to
+// the current replicas and the original bounds kept in AutoscalingBeforeBackupAnnotation.
+func pinAutoscaling(ctx context.Context, c crclient.Client, hcpNamespace string, nodePools []hyperv1.NodePool, backupName string) error {
+	autoscaled := map[string]bool{}
+	for _, np := range nodePools {
+		if np.Spec.AutoScaling != nil {
+			autoscaled[np.Namespace+"/"+np.Name] = true
+		}
+	}
+	if len(autoscaled) == 0 {
+		return nil
+	}
+
+	deployments, err := listCAPIObjects(ctx, c, hcpNamespace, "MachineDeployment")
+	if err != nil {
+		return err
+	}
+	for i := range deployments {
+		md := &deployments[i]
+		annotations := md.GetAnnotations()
+		if _, ok := annotations[AutoscalingBeforeBackupAnnotation]; ok || !autoscaled[annotations[NodePoolAnnotation]] {
+			continue
+		}
+		minSize, hasMin := annotations[AutoscalerMinSizeAnnotation]
+		maxSize, hasMax := annotations[AutoscalerMaxSizeAnnotation]
+		replicas, hasReplicas, err := unstructured.NestedInt64(md.Object, "spec", "replicas")
+		if err != nil || !hasMin || !hasMax || !hasReplicas {
+			continue
+		}
+
+		patch := crclient.MergeFrom(md.DeepCopy())
+		AddAnnotation(md, PausedForBackupAnnotation, backupName)
+		AddAnnotation(md, AutoscalingBeforeBackupAnnotation, minSize+","+maxSize)
+		AddAnnotation(md, AutoscalerMinSizeAnnotation, strconv.FormatInt(replicas, 10))
+		AddAnnotation(md, AutoscalerMaxSizeAnnotation, strconv.FormatInt(replicas, 10))
+		if err := c.Patch(ctx, md, patch); err != nil {
+			return fmt.Errorf("error pinning the autoscaling of MachineDeployment %s/%s: %w", hcpNamespace, md.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// resumeAutoscaling restores the node group bounds pinned by pinAutoscaling.
+func resumeAutoscaling(ctx context.Context, c crclient.Client, hcpNamespace string) error {
+	deployments, err := listCAPIObjects(ctx, c, hcpNamespace, "MachineDeployment")
+	if err != nil {
+		return err
+	}
+	for i := range deployments {
+		md := &deployments[i]
+		if _, ok := md.GetAnnotations()[AutoscalingBeforeBackupAnnotation]; !ok {
+			continue
+		}
+		patch := crclient.MergeFrom(md.DeepCopy())
+		restoreAutoscalingBounds(md)
+		if err := c.Patch(ctx, md, patch); err != nil {
+			return fmt.Errorf("error resuming the autoscaling of MachineDeployment %s/%s: %w", hcpNamespace, md.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// restoreAutoscalingBounds sets back the node group bounds kept in
+// AutoscalingBeforeBackupAnnotation and removes the annotations of the backup pause.
+func restoreAutoscalingBounds(md metav1.Object) {
+	minSize, maxSize, _ := strings.Cut(md.GetAnnotations()[AutoscalingBeforeBackupAnnotation], ",")
+	AddAnnotation(md, AutoscalerMinSizeAnnotation, minSize)
+	AddAnnotation(md, AutoscalerMaxSizeAnnotation, maxSize)
+	RemoveAnnotation(md, AutoscalingBeforeBackupAnnotation)
+	RemoveAnnotation(md, PausedForBackupAnnotation)
+}
+
+// RevertBackupPause removes from a backed up item the pause the backup command applied to
+// it, so the item is restored as it was before the backup rather than paused.
+func RevertBackupPause(item *unstructured.Unstructured) {
+	RemoveAnnotation(item, BackupInProgressAnnotation)
+	RemoveAnnotation(item, BackupPausedAtAnnotation)
+	if _, ok := item.GetAnnotations()[PausedForBackupAnnotation]; !ok {
+		return
+	}
+	if _, ok := item.GetAnnotations()[AutoscalingBeforeBackupAnnotation]; ok {
+		restoreAutoscalingBounds(item)
+		return
+	}
+	RemoveAnnotation(item, PausedForBackupAnnotation)
+	switch item.GetKind() {
+	case "MachineHealthCheck":
+		RemoveAnnotation(item, CAPIPausedAnnotation)
+	case HostedClusterKind, NodePoolKind:
+		if pausedUntil, _, _ := unstructured.NestedString(item.Object, "spec", "pausedUntil"); pausedUntil == pausedForBackup {
+			unstructured.RemoveNestedField(item.Object, "spec", "pausedUntil")
+		}
+	}
+}
+
+func describePaused(kind string, obj crclient.Object, pausedUntil *string) string {
+	return fmt.Sprintf("%s %s/%s (pausedUntil %s)", kind, obj.GetNamespace(), obj.GetName(), ptr.Deref(pausedUntil, ""))
+}
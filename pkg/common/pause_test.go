@@ -0,0 +1,492 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/common/clocktest"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPauseHostedCluster(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	client := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(
+		&hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters"}},
+		&hyperv1.NodePool{
+			ObjectMeta: metav1.ObjectMeta{Name: "workers", Namespace: "clusters"},
+			Spec:       hyperv1.NodePoolSpec{ClusterName: "hc"},
+		},
+		&hyperv1.NodePool{
+			ObjectMeta: metav1.ObjectMeta{Name: "infra", Namespace: "clusters"},
+			Spec:       hyperv1.NodePoolSpec{ClusterName: "hc", PausedUntil: ptr.To("2030-01-01T00:00:00Z")},
+		},
+		&hyperv1.NodePool{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "clusters"},
+			Spec:       hyperv1.NodePoolSpec{ClusterName: "other-hc"},
+		},
+	).Build()
+
+	getHC := func() *hyperv1.HostedCluster {
+		hc := &hyperv1.HostedCluster{}
+		g.Expect(client.Get(ctx, crclient.ObjectKey{Name: "hc", Namespace: "clusters"}, hc)).To(Succeed())
+		return hc
+	}
+	getNodePool := func(name string) *hyperv1.NodePool {
+		np := &hyperv1.NodePool{}
+		g.Expect(client.Get(ctx, crclient.ObjectKey{Name: name, Namespace: "clusters"}, np)).To(Succeed())
+		return np
+	}
+
+	pausedElsewhere, err := PauseHostedCluster(ctx, client, "clusters", "hc", "daily")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(pausedElsewhere).To(ConsistOf("NodePool clusters/infra (pausedUntil 2030-01-01T00:00:00Z)"))
+	// A second run, e.g. after a retry, keeps the first pause and does not take it for someone else's
+	pausedElsewhere, err = PauseHostedCluster(ctx, client, "clusters", "hc", "daily-2")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(pausedElsewhere).To(ConsistOf("NodePool clusters/infra (pausedUntil 2030-01-01T00:00:00Z)"))
+
+	hc := getHC()
+	g.Expect(hc.Spec.PausedUntil).To(Equal(ptr.To("true")))
+	g.Expect(hc.Annotations).To(HaveKeyWithValue(PausedForBackupAnnotation, "daily"))
+	// The in-progress annotation follows the running backup, the pause time is the first one
+	g.Expect(hc.Annotations).To(HaveKeyWithValue(BackupInProgressAnnotation, "daily-2"))
+	pausedAt, err := time.Parse(time.RFC3339, hc.Annotations[BackupPausedAtAnnotation])
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(pausedAt).To(BeTemporally("~", time.Now(), time.Minute))
+	g.Expect(getNodePool("workers").Spec.PausedUntil).To(Equal(ptr.To("true")))
+	g.Expect(getNodePool("infra").Annotations).NotTo(HaveKey(PausedForBackupAnnotation))
+	g.Expect(getNodePool("other").Spec.PausedUntil).To(BeNil())
+
+	pausedElsewhere, err = UnpauseHostedCluster(ctx, client, "clusters", "hc")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(pausedElsewhere).To(BeEmpty())
+
+	hc = getHC()
+	g.Expect(hc.Spec.PausedUntil).To(BeNil())
+	g.Expect(hc.Annotations).NotTo(HaveKey(PausedForBackupAnnotation))
+	g.Expect(hc.Annotations).NotTo(HaveKey(BackupInProgressAnnotation))
+	g.Expect(hc.Annotations).NotTo(HaveKey(BackupPausedAtAnnotation))
+	g.Expect(getNodePool("workers").Spec.PausedUntil).To(BeNil())
+	// NodePools paused by someone else stay paused
+	g.Expect(getNodePool("infra").Spec.PausedUntil).To(Equal(ptr.To("2030-01-01T00:00:00Z")))
+}
+
+func TestWaitForPausedPropagated(t *testing.T) {
+	timeouts := Timeouts{Clock: clocktest.New(t, DefaultTimeouts.PausePropagationPoll)}
+	newObjects := func(workersMDPaused bool) []crclient.Object {
+		cluster := newCAPIObject("Cluster", "hc", "clusters-hc", nil)
+		_ = unstructured.SetNestedField(cluster.Object, true, "spec", "paused")
+		workersMD := newCAPIObject("MachineDeployment", "workers", "clusters-hc", map[string]string{NodePoolAnnotation: "clusters/workers"})
+		if workersMDPaused {
+			workersMD.SetAnnotations(map[string]string{NodePoolAnnotation: "clusters/workers", CAPIPausedAnnotation: "true"})
+		}
+		return []crclient.Object{
+			&hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters"}},
+			&hyperv1.HostedControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters-hc"},
+				Spec:       hyperv1.HostedControlPlaneSpec{PausedUntil: ptr.To("true")},
+			},
+			&hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{Name: "workers", Namespace: "clusters"},
+				Spec:       hyperv1.NodePoolSpec{ClusterName: "hc", PausedUntil: ptr.To("true")},
+			},
+			cluster, workersMD,
+			// The machinery of a NodePool not paused, e.g. of another cluster, is not waited for
+			newCAPIObject("MachineSet", "other", "clusters-hc", map[string]string{NodePoolAnnotation: "clusters/other"}),
+		}
+	}
+
+	t.Run("When the pause reached the control plane and the cluster-api objects, It Should return", func(t *testing.T) {
+		g := NewWithT(t)
+		client := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(newObjects(true)...).Build()
+		g.Expect(WaitForPausedPropagated(context.TODO(), client, "clusters", "hc", timeouts)).To(Succeed())
+	})
+
+	t.Run("When a MachineDeployment of a paused NodePool is not paused, It Should time out naming it", func(t *testing.T) {
+		g := NewWithT(t)
+		client := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(newObjects(false)...).Build()
+		err := WaitForPausedPropagated(context.TODO(), client, "clusters", "hc", timeouts)
+		var timeoutErr *TimeoutError
+		g.Expect(errors.As(err, &timeoutErr)).To(BeTrue())
+		g.Expect(err.Error()).To(ContainSubstring("MachineDeployment clusters-hc/workers"))
+	})
+
+	t.Run("When the HostedControlPlane is not paused yet, It Should time out naming it", func(t *testing.T) {
+		g := NewWithT(t)
+		client := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(
+			&hyperv1.HostedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters"},
+				Spec:       hyperv1.HostedClusterSpec{PausedUntil: ptr.To("true")},
+			},
+			&hyperv1.HostedControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters-hc"}},
+		).Build()
+		err := WaitForPausedPropagated(context.TODO(), client, "clusters", "hc", timeouts)
+		g.Expect(err).To(MatchError(ContainSubstring("the pause of HostedControlPlane clusters-hc/hc")))
+	})
+}
+
+func TestWaitForUnpausedPropagated(t *testing.T) {
+	timeouts := Timeouts{Clock: clocktest.New(t, DefaultTimeouts.PausePropagationPoll)}
+	newObjects := func(hcpPausedUntil *string) []crclient.Object {
+		return []crclient.Object{
+			&hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters"}},
+			&hyperv1.HostedControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters-hc"},
+				Spec:       hyperv1.HostedControlPlaneSpec{PausedUntil: hcpPausedUntil},
+			},
+			&hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{Name: "workers", Namespace: "clusters"},
+				Spec:       hyperv1.NodePoolSpec{ClusterName: "hc"},
+			},
+			&hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{Name: "infra", Namespace: "clusters"},
+				Spec:       hyperv1.NodePoolSpec{ClusterName: "hc", PausedUntil: ptr.To("2030-01-01T00:00:00Z")},
+			},
+			newCAPIObject("Cluster", "hc", "clusters-hc", nil),
+			newCAPIObject("MachineDeployment", "workers", "clusters-hc", map[string]string{NodePoolAnnotation: "clusters/workers"}),
+			// Kept paused by someone else, so not waited for
+			newCAPIObject("MachineDeployment", "infra", "clusters-hc", map[string]string{NodePoolAnnotation: "clusters/infra", CAPIPausedAnnotation: "true"}),
+		}
+	}
+
+	t.Run("When the resume reached the control plane and the cluster-api objects, It Should return", func(t *testing.T) {
+		g := NewWithT(t)
+		client := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(newObjects(nil)...).Build()
+		g.Expect(WaitForUnpausedPropagated(context.TODO(), client, "clusters", "hc", timeouts)).To(Succeed())
+	})
+
+	t.Run("When the HostedControlPlane is still paused, It Should time out naming it", func(t *testing.T) {
+		g := NewWithT(t)
+		client := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(newObjects(ptr.To("true"))...).Build()
+		err := WaitForUnpausedPropagated(context.TODO(), client, "clusters", "hc", timeouts)
+		g.Expect(err).To(MatchError(ContainSubstring("the resume of HostedControlPlane clusters-hc/hc")))
+	})
+}
+
+func TestPauseMachineHealthChecksAndAutoscaling(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+
+	mhc := newCAPIObject("MachineHealthCheck", "workers", "clusters-hc", nil)
+	pausedMHC := newCAPIObject("MachineHealthCheck", "infra", "clusters-hc", map[string]string{CAPIPausedAnnotation: ""})
+	autoscaledMD := newCAPIObject("MachineDeployment", "workers", "clusters-hc", map[string]string{
+		NodePoolAnnotation:          "clusters/workers",
+		AutoscalerMinSizeAnnotation: "2",
+		AutoscalerMaxSizeAnnotation: "10",
+	})
+	g.Expect(unstructured.SetNestedField(autoscaledMD.Object, int64(4), "spec", "replicas")).To(Succeed())
+	fixedMD := newCAPIObject("MachineDeployment", "infra", "clusters-hc", map[string]string{NodePoolAnnotation: "clusters/infra"})
+	client := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(
+		&hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters"}},
+		&hyperv1.NodePool{
+			ObjectMeta: metav1.ObjectMeta{Name: "workers", Namespace: "clusters"},
+			Spec:       hyperv1.NodePoolSpec{ClusterName: "hc", AutoScaling: &hyperv1.NodePoolAutoScaling{Min: ptr.To[int32](2), Max: 10}},
+		},
+		&hyperv1.NodePool{
+			ObjectMeta: metav1.ObjectMeta{Name: "infra", Namespace: "clusters"},
+			Spec:       hyperv1.NodePoolSpec{ClusterName: "hc", Replicas: ptr.To[int32](3)},
+		},
+		mhc, pausedMHC, autoscaledMD, fixedMD,
+	).Build()
+
+	get := func(obj *unstructured.Unstructured) map[string]string {
+		g.Expect(client.Get(ctx, crclient.ObjectKeyFromObject(obj), obj)).To(Succeed())
+		return obj.GetAnnotations()
+	}
+
+	pausedElsewhere, err := PauseHostedCluster(ctx, client, "clusters", "hc", "daily")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(pausedElsewhere).To(ConsistOf("MachineHealthCheck clusters-hc/infra (cluster.x-k8s.io/paused)"))
+
+	g.Expect(get(mhc)).To(HaveKey(CAPIPausedAnnotation))
+	g.Expect(get(pausedMHC)).NotTo(HaveKey(PausedForBackupAnnotation))
+	g.Expect(get(autoscaledMD)).To(And(
+		HaveKeyWithValue(AutoscalerMinSizeAnnotation, "4"),
+		HaveKeyWithValue(AutoscalerMaxSizeAnnotation, "4"),
+		HaveKeyWithValue(AutoscalingBeforeBackupAnnotation, "2,10"),
+	))
+	g.Expect(get(fixedMD)).NotTo(HaveKey(PausedForBackupAnnotation))
+
+	// The backed up MachineDeployment keeps its original bounds
+	backedUp := autoscaledMD.DeepCopy()
+	RevertBackupPause(backedUp)
+	g.Expect(backedUp.GetAnnotations()).To(Equal(map[string]string{
+		NodePoolAnnotation:          "clusters/workers",
+		AutoscalerMinSizeAnnotation: "2",
+		AutoscalerMaxSizeAnnotation: "10",
+	}))
+
+	_, err = UnpauseHostedCluster(ctx, client, "clusters", "hc")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(get(mhc)).To(BeEmpty())
+	g.Expect(get(pausedMHC)).To(HaveKey(CAPIPausedAnnotation))
+	g.Expect(get(autoscaledMD)).To(Equal(map[string]string{
+		NodePoolAnnotation:          "clusters/workers",
+		AutoscalerMinSizeAnnotation: "2",
+		AutoscalerMaxSizeAnnotation: "10",
+	}))
+}
+
+func TestRevertBackupPause(t *testing.T) {
+	g := NewWithT(t)
+
+	hc := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "hypershift.openshift.io/v1beta1",
+		"kind":       HostedClusterKind,
+		"metadata": map[string]any{"name": "hc", "annotations": map[string]any{
+			PausedForBackupAnnotation:  "daily",
+			BackupInProgressAnnotation: "daily",
+			BackupPausedAtAnnotation:   "2026-01-01T10:00:00Z",
+		}},
+		"spec": map[string]any{"pausedUntil": "true"},
+	}}
+	RevertBackupPause(hc)
+	g.Expect(hc.GetAnnotations()).To(BeEmpty())
+	g.Expect(hc.Object["spec"]).NotTo(HaveKey("pausedUntil"))
+
+	// A pause that was there before the backup is kept
+	np := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "hypershift.openshift.io/v1beta1",
+		"kind":       NodePoolKind,
+		"metadata":   map[string]any{"name": "infra"},
+		"spec":       map[string]any{"pausedUntil": "true"},
+	}}
+	RevertBackupPause(np)
+	g.Expect(np.Object["spec"]).To(HaveKeyWithValue("pausedUntil", "true"))
+}
+
+func TestUnpauseHostedClusterPausedElsewhere(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	client := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(
+		&hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters"}},
+	).Build()
+
+	_, err := PauseHostedCluster(ctx, client, "clusters", "hc", "daily")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Someone pauses the cluster until a maintenance window ends while it is backed up
+	hc := &hyperv1.HostedCluster{}
+	g.Expect(client.Get(ctx, crclient.ObjectKey{Name: "hc", Namespace: "clusters"}, hc)).To(Succeed())
+	hc.Spec.PausedUntil = ptr.To("2030-01-01T00:00:00Z")
+	g.Expect(client.Update(ctx, hc)).To(Succeed())
+
+	pausedElsewhere, err := UnpauseHostedCluster(ctx, client, "clusters", "hc")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(pausedElsewhere).To(ConsistOf("HostedCluster clusters/hc (pausedUntil 2030-01-01T00:00:00Z)"))
+
+	g.Expect(client.Get(ctx, crclient.ObjectKey{Name: "hc", Namespace: "clusters"}, hc)).To(Succeed())
+	g.Expect(hc.Spec.PausedUntil).To(Equal(ptr.To("2030-01-01T00:00:00Z")))
+	g.Expect(hc.Annotations).NotTo(HaveKey(PausedForBackupAnnotation))
+	g.Expect(hc.Annotations).NotTo(HaveKey(BackupInProgressAnnotation))
+}
+
+func TestRunningBackupPausedFor(t *testing.T) {
+	pausedHC := &hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{
+		Name: "hc", Namespace: "clusters",
+		Annotations: map[string]string{PausedForBackupAnnotation: "hc-20260101"},
+	}}
+
+	tests := []struct {
+		name   string
+		hc     *hyperv1.HostedCluster
+		phase  velerov1.BackupPhase
+		exists bool
+		want   string
+	}{
+		{
+			name: "When the HostedCluster was not paused for a backup, It Should return no backup",
+			hc:   &hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters"}},
+		},
+		{
+			name:   "When the Backup it was paused for is in progress, It Should return it",
+			hc:     pausedHC,
+			phase:  velerov1.BackupPhaseInProgress,
+			exists: true,
+			want:   "hc-20260101",
+		},
+		{
+			name:   "When the Backup it was paused for waits on plugin operations, It Should return it",
+			hc:     pausedHC,
+			phase:  velerov1.BackupPhaseWaitingForPluginOperations,
+			exists: true,
+			want:   "hc-20260101",
+		},
+		{
+			name:   "When the Backup it was paused for has finished, It Should return no backup",
+			hc:     pausedHC,
+			phase:  velerov1.BackupPhaseCompleted,
+			exists: true,
+		},
+		{
+			name: "When the Backup it was paused for was never created, It Should return no backup",
+			hc:   pausedHC,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			builder := fake.NewClientBuilder().WithScheme(CustomScheme)
+			if tt.exists {
+				builder = builder.WithObjects(&velerov1.Backup{
+					ObjectMeta: metav1.ObjectMeta{Name: "hc-20260101", Namespace: "openshift-adp"},
+					Status:     velerov1.BackupStatus{Phase: tt.phase},
+				})
+			}
+
+			name, err := RunningBackupPausedFor(context.TODO(), builder.Build(), tt.hc, "openshift-adp")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(name).To(Equal(tt.want))
+		})
+	}
+}
+
+// TestVeleroRestartMidBackup plays a Velero pod restart while a backup waits on its
+// DataUploads: a backup waiting on plugin operations is resumed by Velero, one still backing
+// up items is marked Failed. The backup command, rerun after the restart, must then wait for
+// the resumed backup, or start afresh and leave no cluster paused for the failed one.
+func TestVeleroRestartMidBackup(t *testing.T) {
+	ctx := context.TODO()
+	backup := &velerov1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "hc-20260101", Namespace: "openshift-adp"},
+		Status:     velerov1.BackupStatus{Phase: velerov1.BackupPhaseInProgress},
+	}
+	newClient := func(g Gomega) crclient.Client {
+		client := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(
+			&hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters"}},
+			&hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{Name: "workers", Namespace: "clusters"},
+				Spec:       hyperv1.NodePoolSpec{ClusterName: "hc"},
+			},
+			backup.DeepCopy(),
+		).Build()
+		_, err := PauseHostedCluster(ctx, client, "clusters", "hc", backup.Name)
+		g.Expect(err).NotTo(HaveOccurred())
+		return client
+	}
+	getHC := func(g Gomega, client crclient.Client) *hyperv1.HostedCluster {
+		hc := &hyperv1.HostedCluster{}
+		g.Expect(client.Get(ctx, crclient.ObjectKey{Name: "hc", Namespace: "clusters"}, hc)).To(Succeed())
+		return hc
+	}
+	restartVelero := func(g Gomega, client crclient.Client, phase velerov1.BackupPhase, reason string) {
+		running := &velerov1.Backup{}
+		g.Expect(client.Get(ctx, crclient.ObjectKeyFromObject(backup), running)).To(Succeed())
+		running.Status.Phase = phase
+		running.Status.FailureReason = reason
+		g.Expect(client.Update(ctx, running)).To(Succeed())
+	}
+
+	t.Run("When Velero resumes the plugin operations of the backup, It Should have the rerun wait for it", func(t *testing.T) {
+		g := NewWithT(t)
+		client := newClient(g)
+		restartVelero(g, client, velerov1.BackupPhaseWaitingForPluginOperations, "")
+
+		running, err := RunningBackupPausedFor(ctx, client, getHC(g, client), "openshift-adp")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(running).To(Equal(backup.Name))
+		g.Expect(getHC(g, client).Spec.PausedUntil).To(Equal(ptr.To("true")))
+	})
+
+	t.Run("When Velero marks the backup Failed on restart, It Should have the rerun start afresh and resume the cluster", func(t *testing.T) {
+		g := NewWithT(t)
+		client := newClient(g)
+		restartVelero(g, client, velerov1.BackupPhaseFailed, `found a backup with status "InProgress" during the server starting, mark it as "Failed"`)
+
+		running, err := RunningBackupPausedFor(ctx, client, getHC(g, client), "openshift-adp")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(running).To(BeEmpty())
+
+		// The rerun pauses for its own backup and resumes once it is done
+		_, err = PauseHostedCluster(ctx, client, "clusters", "hc", "hc-20260101-2")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(getHC(g, client).Annotations).To(HaveKeyWithValue(BackupInProgressAnnotation, "hc-20260101-2"))
+		pausedElsewhere, err := UnpauseHostedCluster(ctx, client, "clusters", "hc")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(pausedElsewhere).To(BeEmpty())
+
+		hc := getHC(g, client)
+		g.Expect(hc.Spec.PausedUntil).To(BeNil())
+		g.Expect(hc.Annotations).NotTo(HaveKey(PausedForBackupAnnotation))
+		g.Expect(hc.Annotations).NotTo(HaveKey(BackupInProgressAnnotation))
+		np := &hyperv1.NodePool{}
+		g.Expect(client.Get(ctx, crclient.ObjectKey{Name: "workers", Namespace: "clusters"}, np)).To(Succeed())
+		g.Expect(np.Spec.PausedUntil).To(BeNil())
+	})
+}
+
+func TestEarlierBackupsOf(t *testing.T) {
+	start := metav1.NewTime(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC))
+	newBackup := func(namespace, name string, phase velerov1.BackupPhase, started *metav1.Time, namespaces ...string) *velerov1.Backup {
+		return &velerov1.Backup{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       velerov1.BackupSpec{IncludedNamespaces: namespaces},
+			Status:     velerov1.BackupStatus{Phase: phase, StartTimestamp: started},
+		}
+	}
+	earlierStart := metav1.NewTime(start.Add(-time.Minute))
+	laterStart := metav1.NewTime(start.Add(time.Minute))
+
+	tests := []struct {
+		name   string
+		others []crclient.Object
+		want   []string
+	}{
+		{
+			name: "When no other backup runs, It Should return none",
+		},
+		{
+			name: "When an earlier backup of the HCP namespace runs, It Should return it",
+			others: []crclient.Object{
+				newBackup("openshift-adp", "earlier", velerov1.BackupPhaseInProgress, &earlierStart, "clusters", "clusters-hc"),
+				newBackup("acm-backup", "other-velero", velerov1.BackupPhaseInProgress, &earlierStart, "clusters-hc"),
+			},
+			want: []string{"acm-backup/other-velero", "openshift-adp/earlier"},
+		},
+		{
+			name: "When the other backups finished, started later or cover other namespaces, It Should return none",
+			others: []crclient.Object{
+				newBackup("openshift-adp", "finished", velerov1.BackupPhaseCompleted, &earlierStart, "clusters-hc"),
+				newBackup("openshift-adp", "later", velerov1.BackupPhaseInProgress, &laterStart, "clusters-hc"),
+				newBackup("openshift-adp", "not-started", velerov1.BackupPhaseInProgress, nil, "clusters-hc"),
+				newBackup("openshift-adp", "other-cluster", velerov1.BackupPhaseInProgress, &earlierStart, "clusters-other"),
+			},
+		},
+		{
+			name: "When another backup started at the same time, It Should order them by name",
+			others: []crclient.Object{
+				newBackup("openshift-adp", "a-backup", velerov1.BackupPhaseInProgress, &start, "clusters-hc"),
+				newBackup("openshift-adp", "z-backup", velerov1.BackupPhaseInProgress, &start, "clusters-hc"),
+			},
+			want: []string{"openshift-adp/a-backup"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			backup := newBackup("openshift-adp", "m-backup", velerov1.BackupPhaseInProgress, &start, "clusters", "clusters-hc")
+			c := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(append(tt.others, backup)...).Build()
+
+			earlier, err := EarlierBackupsOf(context.TODO(), c, backup, "clusters-hc")
+			g.Expect(err).NotTo(HaveOccurred())
+			if tt.want == nil {
+				g.Expect(earlier).To(BeEmpty())
+			} else {
+				g.Expect(earlier).To(ConsistOf(tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package common
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Permission is an access the plugin needs on the management cluster. An empty Namespace
+// means every namespace.
+type Permission struct {
+	Verb      string
+	Group     string
+	Resource  string
+	Namespace string
+}
+
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if p.Namespace == "" {
+		return fmt.Sprintf("%s %s", p.Verb, resource)
+	}
+	return fmt.Sprintf("%s %s in namespace %s", p.Verb, resource, p.Namespace)
+}
+
+// permissions expands the verbs on a resource into one Permission each.
+func permissions(group, resource, namespace string, verbs ...string) []Permission {
+	perms := make([]Permission, 0, len(verbs))
+	for _, verb := range verbs {
+		perms = append(perms, Permission{Verb: verb, Group: group, Resource: resource, Namespace: namespace})
+	}
+	return perms
+}
+
+const (
+	hypershiftGroup = "hypershift.openshift.io"
+	veleroGroup     = "velero.io"
+)
+
+// BackupPermissions returns the permissions the backup plugin running in the Velero
+// namespace needs. The etcdSnapshot method creates HCPEtcdBackups and copies the BSL
+// credentials into the HCP namespace, and hooks and notifications create Jobs.
+func BackupPermissions(veleroNamespace, etcdBackupMethod string, createsJobs bool) []Permission {
+	var perms []Permission
+	perms = append(perms, permissions(hypershiftGroup, "hostedcontrolplanes", "", "get", "list")...)
+	perms = append(perms, permissions(hypershiftGroup, "hostedclusters", "", "get", "list")...)
+	perms = append(perms, permissions(hypershiftGroup, "nodepools", "", "list")...)
+	perms = append(perms, permissions(veleroGroup, "backups", veleroNamespace, "get", "list", "patch")...)
+	perms = append(perms, permissions(veleroGroup, "backupstoragelocations", veleroNamespace, "get")...)
+	perms = append(perms, permissions("", "configmaps", "", "get", "create", "update")...)
+	perms = append(perms, permissions("", "pods", "", "list")...)
+	perms = append(perms, permissions("", "persistentvolumeclaims", "", "list")...)
+	perms = append(perms, permissions("", "persistentvolumes", "", "get", "patch")...)
+	perms = append(perms, permissions("apiextensions.k8s.io", "customresourcedefinitions", "", "get")...)
+	if etcdBackupMethod == EtcdBackupMethodEtcdSnapshot {
+		perms = append(perms, permissions(hypershiftGroup, "hcpetcdbackups", "", "get", "create")...)
+		perms = append(perms, permissions("", "secrets", "", "get", "create", "delete")...)
+	}
+	if createsJobs {
+		perms = append(perms, permissions("batch", "jobs", veleroNamespace, "create")...)
+	}
+	return perms
+}
+
+// RestorePermissions returns the permissions the restore plugin running in the Velero
+// namespace needs, with the HyperShift Operator in hoNamespace.
+func RestorePermissions(veleroNamespace, hoNamespace string, createsJobs bool) []Permission {
+	var perms []Permission
+	perms = append(perms, permissions(veleroGroup, "backups", veleroNamespace, "get")...)
+	perms = append(perms, permissions(veleroGroup, "backupstoragelocations", veleroNamespace, "get")...)
+	perms = append(perms, permissions(hypershiftGroup, "hostedclusters", "", "get", "list")...)
+	perms = append(perms, permissions(hypershiftGroup, "hostedcontrolplanes", "", "get", "list")...)
+	perms = append(perms, permissions("", "namespaces", "", "get", "create", "patch")...)
+	perms = append(perms, permissions("", "secrets", "", "get")...)
+	perms = append(perms, permissions("", "configmaps", "", "get")...)
+	perms = append(perms, permissions("apps", "deployments", hoNamespace, "get")...)
+	perms = append(perms, permissions("apiextensions.k8s.io", "customresourcedefinitions", "", "get")...)
+	if createsJobs {
+		perms = append(perms, permissions("batch", "jobs", veleroNamespace, "create")...)
+	}
+	return perms
+}
+
+// MissingPermissions checks each permission with a SelfSubjectAccessReview and returns
+// the ones the plugin service account is denied.
+func MissingPermissions(ctx context.Context, c crclient.Client, perms []Permission) ([]Permission, error) {
+	var missing []Permission
+	for _, perm := range perms {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: perm.Namespace,
+					Verb:      perm.Verb,
+					Group:     perm.Group,
+					Resource:  perm.Resource,
+				},
+			},
+		}
+		if err := c.Create(ctx, review); err != nil {
+			return nil, fmt.Errorf("error reviewing permission to %s: %w", perm, err)
+		}
+		if !review.Status.Allowed {
+			missing = append(missing, perm)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,77 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// reviewingClient answers SelfSubjectAccessReviews, denying the given resources.
+func reviewingClient(denied ...string) crclient.Client {
+	return fake.NewClientBuilder().WithScheme(CustomScheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(_ context.Context, _ crclient.WithWatch, obj crclient.Object, _ ...crclient.CreateOption) error {
+			review := obj.(*authorizationv1.SelfSubjectAccessReview)
+			review.Status.Allowed = true
+			for _, resource := range denied {
+				if review.Spec.ResourceAttributes.Resource == resource {
+					review.Status.Allowed = false
+				}
+			}
+			return nil
+		},
+	}).Build()
+}
+
+func TestPermissionString(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(Permission{Verb: "patch", Group: "velero.io", Resource: "backups", Namespace: "openshift-adp"}.String()).To(Equal("patch backups.velero.io in namespace openshift-adp"))
+	g.Expect(Permission{Verb: "list", Resource: "pods"}.String()).To(Equal("list pods"))
+}
+
+func TestBackupPermissions(t *testing.T) {
+	g := NewWithT(t)
+	hcpEtcdBackups := Permission{Verb: "create", Group: "hypershift.openshift.io", Resource: "hcpetcdbackups"}
+	jobs := Permission{Verb: "create", Group: "batch", Resource: "jobs", Namespace: "openshift-adp"}
+
+	g.Expect(BackupPermissions("openshift-adp", EtcdBackupMethodVolume, false)).NotTo(ContainElements(hcpEtcdBackups, jobs))
+	g.Expect(BackupPermissions("openshift-adp", EtcdBackupMethodEtcdSnapshot, true)).To(ContainElements(hcpEtcdBackups, jobs))
+}
+
+func TestMissingPermissions(t *testing.T) {
+	perms := BackupPermissions("openshift-adp", EtcdBackupMethodVolume, false)
+
+	t.Run("When every permission is granted, It Should return none", func(t *testing.T) {
+		g := NewWithT(t)
+		missing, err := MissingPermissions(context.TODO(), reviewingClient(), perms)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(missing).To(BeEmpty())
+	})
+
+	t.Run("When permissions are denied, It Should return each of them", func(t *testing.T) {
+		g := NewWithT(t)
+		missing, err := MissingPermissions(context.TODO(), reviewingClient("backups"), perms)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(missing).To(ConsistOf(
+			Permission{Verb: "get", Group: "velero.io", Resource: "backups", Namespace: "openshift-adp"},
+			Permission{Verb: "list", Group: "velero.io", Resource: "backups", Namespace: "openshift-adp"},
+			Permission{Verb: "patch", Group: "velero.io", Resource: "backups", Namespace: "openshift-adp"},
+		))
+	})
+
+	t.Run("When the review fails, It Should return an error", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(CustomScheme).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(_ context.Context, _ crclient.WithWatch, _ crclient.Object, _ ...crclient.CreateOption) error {
+				return errors.New("connection refused")
+			},
+		}).Build()
+		_, err := MissingPermissions(context.TODO(), c, perms)
+		g.Expect(err).To(MatchError(ContainSubstring("error reviewing permission to get hostedcontrolplanes.hypershift.openshift.io")))
+	})
+}
@@ -0,0 +1,98 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ProtectFromDeletion adds the DeletionProtectionFinalizer to a restored object, so deleting it
+// leaves it Terminating instead of removing it, and records in DeletionProtectedUntilAnnotation
+// when its grace period ends.
+func ProtectFromDeletion(obj metav1.Object, until time.Time) {
+	if !slices.Contains(obj.GetFinalizers(), DeletionProtectionFinalizer) {
+		obj.SetFinalizers(append(obj.GetFinalizers(), DeletionProtectionFinalizer))
+	}
+	AddAnnotation(obj, DeletionProtectedUntilAnnotation, until.UTC().Format(time.RFC3339))
+}
+
+// RemoveDeletionProtection lifts the protection the deletionProtection restore option put on a
+// HostedCluster and the etcd PVCs of its control plane, and returns the objects it was lifted
+// from. Protections whose grace period has not ended by now are only lifted with force, and
+// none is lifted otherwise. The HostedCluster comes last, so an interrupted run can be repeated.
+func RemoveDeletionProtection(ctx context.Context, c crclient.Client, namespace, name string, now time.Time, force bool) ([]string, error) {
+	hc := &hyperv1.HostedCluster{}
+	if err := c.Get(ctx, crclient.ObjectKey{Namespace: namespace, Name: name}, hc); err != nil {
+		return nil, fmt.Errorf("error getting HostedCluster %s/%s: %w", namespace, name, err)
+	}
+	hcpNamespace := GetHCPNamespace(name, namespace)
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := c.List(ctx, pvcs, crclient.InNamespace(hcpNamespace)); err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("error listing PVCs in namespace %s: %w", hcpNamespace, err)
+	}
+
+	protected := []crclient.Object{}
+	for i := range pvcs.Items {
+		if strings.HasPrefix(pvcs.Items[i].Name, EtcdPVCPrefix) {
+			protected = append(protected, &pvcs.Items[i])
+		}
+	}
+	protected = append(protected, hc)
+
+	if !force {
+		for _, obj := range protected {
+			until, err := protectedUntil(obj)
+			if err != nil {
+				return nil, err
+			}
+			if now.Before(until) {
+				return nil, fmt.Errorf("%s %s/%s is protected from deletion until %s, lift it earlier with --force",
+					kindOf(obj), obj.GetNamespace(), obj.GetName(), until.Format(time.RFC3339))
+			}
+		}
+	}
+
+	lifted := []string{}
+	for _, obj := range protected {
+		if _, ok := obj.GetAnnotations()[DeletionProtectedUntilAnnotation]; !ok && !slices.Contains(obj.GetFinalizers(), DeletionProtectionFinalizer) {
+			continue
+		}
+		patch := crclient.MergeFrom(obj.DeepCopyObject().(crclient.Object))
+		RemoveAnnotation(obj, DeletionProtectedUntilAnnotation)
+		obj.SetFinalizers(slices.DeleteFunc(obj.GetFinalizers(), func(f string) bool { return f == DeletionProtectionFinalizer }))
+		if err := c.Patch(ctx, obj, patch); err != nil {
+			return lifted, fmt.Errorf("error lifting the deletion protection of %s %s/%s: %w", kindOf(obj), obj.GetNamespace(), obj.GetName(), err)
+		}
+		lifted = append(lifted, fmt.Sprintf("%s %s/%s", kindOf(obj), obj.GetNamespace(), obj.GetName()))
+	}
+	return lifted, nil
+}
+
+// protectedUntil returns the end of the grace period of a protected object, zero for an
+// object the restore did not protect.
+func protectedUntil(obj crclient.Object) (time.Time, error) {
+	value, ok := obj.GetAnnotations()[DeletionProtectedUntilAnnotation]
+	if !ok {
+		return time.Time{}, nil
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s annotation %q on %s %s/%s: %w", DeletionProtectedUntilAnnotation, value, kindOf(obj), obj.GetNamespace(), obj.GetName(), err)
+	}
+	return until, nil
+}
+
+func kindOf(obj crclient.Object) string {
+	if _, ok := obj.(*hyperv1.HostedCluster); ok {
+		return HostedClusterKind
+	}
+	return PersistentVolumeClaimKind
+}
@@ -0,0 +1,84 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestProtectFromDeletion(t *testing.T) {
+	g := NewWithT(t)
+	until := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	hc := &hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{Finalizers: []string{"hypershift.openshift.io/finalizer"}}}
+
+	ProtectFromDeletion(hc, until)
+	ProtectFromDeletion(hc, until)
+	g.Expect(hc.Finalizers).To(Equal([]string{"hypershift.openshift.io/finalizer", DeletionProtectionFinalizer}))
+	g.Expect(hc.Annotations).To(HaveKeyWithValue(DeletionProtectedUntilAnnotation, "2026-03-01T12:00:00Z"))
+}
+
+func TestRemoveDeletionProtection(t *testing.T) {
+	until := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		now         time.Time
+		force       bool
+		expectError bool
+		wantLifted  []string
+	}{
+		{
+			name:       "When the grace period has ended, It Should lift the protection from the etcd PVCs and then the HostedCluster",
+			now:        until.Add(time.Minute),
+			wantLifted: []string{"PersistentVolumeClaim clusters-hc/data-etcd-0", "HostedCluster clusters/hc"},
+		},
+		{
+			name:        "When the grace period has not ended, It Should lift nothing and return error",
+			now:         until.Add(-time.Hour),
+			expectError: true,
+		},
+		{
+			name:       "When the grace period has not ended and force is set, It Should lift the protection",
+			now:        until.Add(-time.Hour),
+			force:      true,
+			wantLifted: []string{"PersistentVolumeClaim clusters-hc/data-etcd-0", "HostedCluster clusters/hc"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.TODO()
+			hc := &hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters"}}
+			pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-etcd-0", Namespace: "clusters-hc"}}
+			other := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "ovnkube-db", Namespace: "clusters-hc"}}
+			ProtectFromDeletion(hc, until)
+			ProtectFromDeletion(pvc, until)
+			c := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(hc, pvc, other).Build()
+
+			lifted, err := RemoveDeletionProtection(ctx, c, "clusters", "hc", tt.now, tt.force)
+			if tt.expectError {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring("protected from deletion until 2026-03-01T12:00:00Z"))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			g.Expect(lifted).To(Equal(tt.wantLifted))
+
+			for _, obj := range []crclient.Object{hc, pvc} {
+				g.Expect(c.Get(ctx, crclient.ObjectKeyFromObject(obj), obj)).To(Succeed())
+				if tt.expectError {
+					g.Expect(obj.GetFinalizers()).To(ContainElement(DeletionProtectionFinalizer))
+				} else {
+					g.Expect(obj.GetFinalizers()).NotTo(ContainElement(DeletionProtectionFinalizer))
+					g.Expect(obj.GetAnnotations()).NotTo(HaveKey(DeletionProtectedUntilAnnotation))
+				}
+			}
+		})
+	}
+}
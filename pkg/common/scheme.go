@@ -2,11 +2,17 @@ package common
 
 import (
 	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v8/apis/volumesnapshot/v1"
+	configv1 "github.com/openshift/api/config/v1"
 	hive "github.com/openshift/hive/apis/hive/v1"
 	hyperv1beta1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
 	veleroapiv1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
 	veleroapiv2alpha1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v2alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -24,6 +30,9 @@ func init() {
 	if err := corev1.AddToScheme(CustomScheme); err != nil {
 		errs = append(errs, err)
 	}
+	if err := appsv1.AddToScheme(CustomScheme); err != nil {
+		errs = append(errs, err)
+	}
 	if err := veleroapiv2alpha1.AddToScheme(CustomScheme); err != nil {
 		errs = append(errs, err)
 	}
@@ -39,6 +48,21 @@ func init() {
 	if err := apiextensionsv1.AddToScheme(CustomScheme); err != nil {
 		errs = append(errs, err)
 	}
+	if err := configv1.AddToScheme(CustomScheme); err != nil {
+		errs = append(errs, err)
+	}
+	if err := batchv1.AddToScheme(CustomScheme); err != nil {
+		errs = append(errs, err)
+	}
+	if err := storagev1.AddToScheme(CustomScheme); err != nil {
+		errs = append(errs, err)
+	}
+	if err := authorizationv1.AddToScheme(CustomScheme); err != nil {
+		errs = append(errs, err)
+	}
+	if err := schedulingv1.AddToScheme(CustomScheme); err != nil {
+		errs = append(errs, err)
+	}
 
 	if len(errs) > 0 {
 		panic(errs)
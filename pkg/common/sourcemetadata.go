@@ -0,0 +1,214 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	configv1 "github.com/openshift/api/config/v1"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SourceMetadata describes the HostedCluster and management cluster a backup was taken
+// from, so a restore can tell whether the target environment matches.
+type SourceMetadata struct {
+	ReleaseImage string `json:"releaseImage,omitempty"`
+	// ReleaseVersion is the OCP version of the release the HostedCluster was reconciled
+	// towards, which the HyperShift Operator of the target must support
+	ReleaseVersion           string `json:"releaseVersion,omitempty"`
+	Platform                 string `json:"platform,omitempty"`
+	InfraID                  string `json:"infraID,omitempty"`
+	ManagementClusterVersion string `json:"managementClusterVersion,omitempty"`
+	EtcdSize                 string `json:"etcdSize,omitempty"`
+	EtcdStorageClass         string `json:"etcdStorageClass,omitempty"`
+	// HyperShiftOperatorVersion is the newest OCP version the HyperShift Operator supports,
+	// which tells how recent the operator is
+	HyperShiftOperatorVersion string `json:"hyperShiftOperatorVersion,omitempty"`
+	// OVNDatabaseVolumes are the PVCs of the OVN databases in the control plane namespace,
+	// only found on releases older than OVN interconnect
+	OVNDatabaseVolumes []string `json:"ovnDatabaseVolumes,omitempty"`
+	// ManagementProxy is the host of the HTTPS, else HTTP, proxy of the management cluster,
+	// without credentials, and ManagementTrustedCA the ConfigMap of openshift-config holding
+	// the CA bundle trusted for it. The control plane egresses through the proxy.
+	ManagementProxy     string `json:"managementProxy,omitempty"`
+	ManagementTrustedCA string `json:"managementTrustedCA,omitempty"`
+}
+
+// BuildSourceMetadata collects the SourceMetadata of a HostedCluster being backed up, with
+// the HyperShift Operator running in hoNamespace.
+func BuildSourceMetadata(ctx context.Context, c crclient.Client, hc *hyperv1.HostedCluster, hoNamespace string) (*SourceMetadata, error) {
+	version, err := GetManagementClusterVersion(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	hoVersion, err := GetHyperShiftOperatorVersion(ctx, c, hoNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, trustedCA, err := GetManagementProxy(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	md := &SourceMetadata{
+		ReleaseImage:              hc.Spec.Release.Image,
+		Platform:                  string(hc.Spec.Platform.Type),
+		InfraID:                   hc.Spec.InfraID,
+		ManagementClusterVersion:  version,
+		HyperShiftOperatorVersion: hoVersion,
+		ManagementProxy:           proxy,
+		ManagementTrustedCA:       trustedCA,
+	}
+	if hc.Status.Version != nil {
+		md.ReleaseVersion = hc.Status.Version.Desired.Version
+	}
+	if managed := hc.Spec.Etcd.Managed; managed != nil && managed.Storage.PersistentVolume != nil {
+		if size := managed.Storage.PersistentVolume.Size; size != nil {
+			md.EtcdSize = size.String()
+		}
+		if sc := managed.Storage.PersistentVolume.StorageClassName; sc != nil {
+			md.EtcdStorageClass = *sc
+		}
+	}
+
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := c.List(ctx, pvcs, crclient.InNamespace(GetHCPNamespace(hc.Name, hc.Namespace)), crclient.MatchingLabels{"app": OVNKubeMasterApp}); err != nil {
+		return nil, fmt.Errorf("error listing the OVN database PVCs: %w", err)
+	}
+	for _, pvc := range pvcs.Items {
+		md.OVNDatabaseVolumes = append(md.OVNDatabaseVolumes, pvc.Name)
+	}
+	return md, nil
+}
+
+// ParseSourceMetadata decodes the SourceMetadataAnnotation value. It returns nil for
+// backups taken before the metadata was recorded.
+func ParseSourceMetadata(annotations map[string]string) (*SourceMetadata, error) {
+	value, ok := annotations[SourceMetadataAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	md := &SourceMetadata{}
+	if err := json.Unmarshal([]byte(value), md); err != nil {
+		return nil, fmt.Errorf("error parsing %s annotation: %w", SourceMetadataAnnotation, err)
+	}
+	return md, nil
+}
+
+// String encodes the metadata as the SourceMetadataAnnotation value.
+func (md *SourceMetadata) String() string {
+	data, _ := json.Marshal(md)
+	return string(data)
+}
+
+// GetManagementClusterVersion returns the OpenShift version of the cluster, or an empty
+// string when the cluster is not OpenShift.
+func GetManagementClusterVersion(ctx context.Context, c crclient.Client) (string, error) {
+	cv := &configv1.ClusterVersion{}
+	if err := c.Get(ctx, crclient.ObjectKey{Name: "version"}, cv); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error getting ClusterVersion: %w", err)
+	}
+	return cv.Status.Desired.Version, nil
+}
+
+// GetManagementProxy returns the host of the HTTPS, else HTTP, proxy of the cluster and the
+// openshift-config ConfigMap of its trusted CA bundle, empty when the cluster is not
+// OpenShift or uses no proxy. Credentials in the proxy URL are left out.
+func GetManagementProxy(ctx context.Context, c crclient.Client) (string, string, error) {
+	proxy := &configv1.Proxy{}
+	if err := c.Get(ctx, crclient.ObjectKey{Name: "cluster"}, proxy); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("error getting the Proxy configuration: %w", err)
+	}
+	proxyURL := proxy.Status.HTTPSProxy
+	if proxyURL == "" {
+		proxyURL = proxy.Status.HTTPProxy
+	}
+	var host string
+	if proxyURL != "" {
+		parsed, err := url.Parse(proxyURL)
+		if err != nil {
+			// The parse error quotes the URL, credentials included
+			return "", "", errors.New("error parsing the proxy URL of the Proxy configuration")
+		}
+		host = parsed.Host
+	}
+	return host, proxy.Spec.TrustedCA.Name, nil
+}
+
+// GetHOSupportedVersions reads the OCP versions the HyperShift Operator running in hoNamespace
+// supports from its supported-versions ConfigMap. It returns a NotFound error when the
+// operator is too old to publish it or not installed.
+func GetHOSupportedVersions(ctx context.Context, c crclient.Client, hoNamespace string) ([]string, error) {
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Name: HOSupportedVersionsConfigMapName, Namespace: hoNamespace}, cm); err != nil {
+		return nil, err
+	}
+
+	supported := struct {
+		Versions []string `json:"versions"`
+	}{}
+	if err := json.Unmarshal([]byte(cm.Data[HOSupportedVersionsKey]), &supported); err != nil {
+		return nil, fmt.Errorf("error parsing HyperShift Operator supported versions: %w", err)
+	}
+	return supported.Versions, nil
+}
+
+// GetHyperShiftOperatorVersion returns the newest OCP version the HyperShift Operator running
+// in hoNamespace supports, or an empty string when it publishes no supported versions.
+func GetHyperShiftOperatorVersion(ctx context.Context, c crclient.Client, hoNamespace string) (string, error) {
+	versions, err := GetHOSupportedVersions(ctx, c, hoNamespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error getting HyperShift Operator supported versions: %w", err)
+	}
+	var newest string
+	var newestVersion *utilversion.Version
+	for _, v := range versions {
+		parsed, err := utilversion.ParseGeneric(v)
+		if err != nil {
+			continue
+		}
+		if newestVersion == nil || parsed.GreaterThan(newestVersion) {
+			newest, newestVersion = v, parsed
+		}
+	}
+	return newest, nil
+}
+
+// IsVersionBehind reports whether the target version is more than skew minor versions behind
+// the source. An older major is always behind, a newer one never. An empty version, e.g. of
+// a cluster that is not OpenShift, is never behind nor ahead.
+func IsVersionBehind(target, source string, skew int) (bool, error) {
+	if target == "" || source == "" {
+		return false, nil
+	}
+	targetVersion, err := utilversion.ParseGeneric(target)
+	if err != nil {
+		return false, err
+	}
+	sourceVersion, err := utilversion.ParseGeneric(source)
+	if err != nil {
+		return false, err
+	}
+	if targetVersion.Major() != sourceVersion.Major() {
+		return targetVersion.Major() < sourceVersion.Major(), nil
+	}
+	return int(sourceVersion.Minor())-int(targetVersion.Minor()) > skew, nil
+}
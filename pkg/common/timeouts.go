@@ -0,0 +1,170 @@
+package common
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+// Timeouts gathers the timeouts and poll intervals of the waits of the plugin and its
+// commands, which the timeouts option overrides. A zero field means its default.
+type Timeouts struct {
+	// EtcdBackupVerify bounds the wait for the HyperShift Operator to accept an HCPEtcdBackup.
+	EtcdBackupVerify time.Duration
+	// EtcdBackupCompletion bounds the wait for an HCPEtcdBackup to complete.
+	EtcdBackupCompletion time.Duration
+	// EtcdBackupPoll is how often the HCPEtcdBackup status is checked.
+	EtcdBackupPoll time.Duration
+	// CAPIProviders bounds the wait for the cluster-api deployments after a restore.
+	CAPIProviders time.Duration
+	// CAPIProvidersPoll is how often the cluster-api deployments are checked.
+	CAPIProvidersPoll time.Duration
+	// AgentDatabaseSnapshot bounds the wait for the assisted-service database snapshot.
+	AgentDatabaseSnapshot time.Duration
+	// AgentDatabaseSnapshotPoll is how often the database snapshot is checked.
+	AgentDatabaseSnapshotPoll time.Duration
+	// PausePropagation bounds the wait for the pause of the HostedCluster to reach each
+	// object HyperShift propagates it to.
+	PausePropagation time.Duration
+	// PausePropagationPoll is how often the propagation of the pause is checked.
+	PausePropagationPoll time.Duration
+	// EarlierBackupsPoll is how often a backup waiting for earlier backups of the same
+	// hosted cluster checks whether they finished.
+	EarlierBackupsPoll time.Duration
+	// SnapshotURLExpiry is how long the presigned etcd snapshot URLs handed to a restored
+	// HostedCluster stay valid.
+	SnapshotURLExpiry time.Duration
+	// LoadBalancers bounds the wait for the cloud provider to assign an address to the
+	// LoadBalancer Services of a restored control plane.
+	LoadBalancers time.Duration
+	// LoadBalancersPoll is how often the LoadBalancer Services are checked.
+	LoadBalancersPoll time.Duration
+
+	// Clock measures the timeouts and poll intervals, the real clock unless a test sets a
+	// fake one to expire them instantly. It is not part of the timeouts option.
+	Clock clock.WithTicker
+}
+
+// DefaultTimeouts are the timeouts used when the timeouts option leaves them unset.
+var DefaultTimeouts = Timeouts{
+	EtcdBackupVerify:          30 * time.Second,
+	EtcdBackupCompletion:      10 * time.Minute,
+	EtcdBackupPoll:            5 * time.Second,
+	CAPIProviders:             10 * time.Minute,
+	CAPIProvidersPoll:         5 * time.Second,
+	AgentDatabaseSnapshot:     10 * time.Minute,
+	AgentDatabaseSnapshotPoll: 5 * time.Second,
+	PausePropagation:          2 * time.Minute,
+	PausePropagationPoll:      2 * time.Second,
+	EarlierBackupsPoll:        10 * time.Second,
+	SnapshotURLExpiry:         time.Hour,
+	LoadBalancers:             10 * time.Minute,
+	LoadBalancersPoll:         10 * time.Second,
+	Clock:                     clock.RealClock{},
+}
+
+// Bounds of the timeouts option: polling more often than MinPollInterval loads the API
+// server for no gain, and S3 signs URLs for at most MaxSnapshotURLExpiry.
+const (
+	MinPollInterval      = time.Second
+	MaxSnapshotURLExpiry = 7 * 24 * time.Hour
+)
+
+// timeoutField names a field of Timeouts in the timeouts option. A poll interval names the
+// timeouts of the waits it paces, which must be longer.
+type timeoutField struct {
+	name  string
+	field func(*Timeouts) *time.Duration
+	polls []string
+	min   time.Duration
+	max   time.Duration
+}
+
+var timeoutFields = []timeoutField{
+	{name: "etcdBackupVerify", field: func(t *Timeouts) *time.Duration { return &t.EtcdBackupVerify }},
+	{name: "etcdBackupCompletion", field: func(t *Timeouts) *time.Duration { return &t.EtcdBackupCompletion }},
+	{name: "etcdBackupPoll", field: func(t *Timeouts) *time.Duration { return &t.EtcdBackupPoll }, polls: []string{"etcdBackupVerify", "etcdBackupCompletion"}, min: MinPollInterval},
+	{name: "capiProviders", field: func(t *Timeouts) *time.Duration { return &t.CAPIProviders }},
+	{name: "capiProvidersPoll", field: func(t *Timeouts) *time.Duration { return &t.CAPIProvidersPoll }, polls: []string{"capiProviders"}, min: MinPollInterval},
+	{name: "agentDatabaseSnapshot", field: func(t *Timeouts) *time.Duration { return &t.AgentDatabaseSnapshot }},
+	{name: "agentDatabaseSnapshotPoll", field: func(t *Timeouts) *time.Duration { return &t.AgentDatabaseSnapshotPoll }, polls: []string{"agentDatabaseSnapshot"}, min: MinPollInterval},
+	{name: "pausePropagation", field: func(t *Timeouts) *time.Duration { return &t.PausePropagation }},
+	{name: "pausePropagationPoll", field: func(t *Timeouts) *time.Duration { return &t.PausePropagationPoll }, polls: []string{"pausePropagation"}, min: MinPollInterval},
+	{name: "earlierBackupsPoll", field: func(t *Timeouts) *time.Duration { return &t.EarlierBackupsPoll }, min: MinPollInterval},
+	{name: "snapshotURLExpiry", field: func(t *Timeouts) *time.Duration { return &t.SnapshotURLExpiry }, max: MaxSnapshotURLExpiry},
+	{name: "loadBalancers", field: func(t *Timeouts) *time.Duration { return &t.LoadBalancers }},
+	{name: "loadBalancersPoll", field: func(t *Timeouts) *time.Duration { return &t.LoadBalancersPoll }, polls: []string{"loadBalancers"}, min: MinPollInterval},
+}
+
+// ParseTimeouts parses the timeouts option, a comma-separated list of name=duration pairs
+// such as "etcdBackupCompletion=30m,capiProvidersPoll=10s". The timeouts it leaves out
+// keep their default.
+func ParseTimeouts(value string) (Timeouts, error) {
+	timeouts := Timeouts{}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		i := slices.IndexFunc(timeoutFields, func(f timeoutField) bool { return f.name == name })
+		if !ok || i < 0 {
+			return Timeouts{}, NewValidationError("invalid %s entry %q: must be name=duration with name one of %s", ConfigKeyTimeouts, entry, timeoutNames())
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil || duration <= 0 {
+			return Timeouts{}, NewValidationError("invalid %s entry %q: must be a positive duration, e.g. 15m", ConfigKeyTimeouts, entry)
+		}
+		if f := timeoutFields[i]; f.min > 0 && duration < f.min {
+			return Timeouts{}, NewValidationError("invalid %s entry %q: must be at least %s", ConfigKeyTimeouts, entry, f.min)
+		} else if f.max > 0 && duration > f.max {
+			return Timeouts{}, NewValidationError("invalid %s entry %q: must be at most %s", ConfigKeyTimeouts, entry, f.max)
+		}
+		*timeoutFields[i].field(&timeouts) = duration
+	}
+	if err := timeouts.validatePolls(); err != nil {
+		return Timeouts{}, err
+	}
+	return timeouts, nil
+}
+
+// validatePolls checks that each poll interval, overridden or not, is shorter than the
+// timeouts of the waits it paces, which would otherwise expire before their first check.
+func (t Timeouts) validatePolls() error {
+	t = t.WithDefaults()
+	for _, poll := range timeoutFields {
+		for _, name := range poll.polls {
+			i := slices.IndexFunc(timeoutFields, func(f timeoutField) bool { return f.name == name })
+			if interval, timeout := *poll.field(&t), *timeoutFields[i].field(&t); interval >= timeout {
+				return NewValidationError("invalid %s: %s (%s) must be shorter than %s (%s)", ConfigKeyTimeouts, poll.name, interval, name, timeout)
+			}
+		}
+	}
+	return nil
+}
+
+// WithDefaults returns the timeouts with the unset ones, and the clock, replaced by their
+// default.
+func (t Timeouts) WithDefaults() Timeouts {
+	defaults := DefaultTimeouts
+	for _, f := range timeoutFields {
+		if *f.field(&t) == 0 {
+			*f.field(&t) = *f.field(&defaults)
+		}
+	}
+	if t.Clock == nil {
+		t.Clock = defaults.Clock
+	}
+	return t
+}
+
+func timeoutNames() string {
+	names := make([]string, 0, len(timeoutFields))
+	for _, f := range timeoutFields {
+		names = append(names, f.name)
+	}
+	return fmt.Sprintf("%q", names)
+}
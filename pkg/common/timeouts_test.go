@@ -0,0 +1,59 @@
+package common
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    Timeouts
+		wantErr bool
+	}{
+		{name: "When the option is empty, It Should leave every timeout unset", value: ""},
+		{
+			name:  "When timeouts are overridden, It Should parse them",
+			value: "etcdBackupCompletion=30m, capiProvidersPoll=10s,snapshotURLExpiry=2h",
+			want:  Timeouts{EtcdBackupCompletion: 30 * time.Minute, CAPIProvidersPoll: 10 * time.Second, SnapshotURLExpiry: 2 * time.Hour},
+		},
+		{name: "When a timeout is unknown, It Should return an error", value: "dataUpload=1h", wantErr: true},
+		{name: "When an entry has no duration, It Should return an error", value: "etcdBackupPoll", wantErr: true},
+		{name: "When a duration is not positive, It Should return an error", value: "etcdBackupPoll=0s", wantErr: true},
+		{name: "When a poll interval is below the minimum, It Should return an error", value: "pausePropagationPoll=100ms", wantErr: true},
+		{name: "When the snapshot URL expiry is above the maximum, It Should return an error", value: "snapshotURLExpiry=240h", wantErr: true},
+		{name: "When a timeout is shorter than its default poll interval, It Should return an error", value: "capiProviders=3s", wantErr: true},
+		{name: "When a poll interval is as long as its timeout, It Should return an error", value: "loadBalancers=1m,loadBalancersPoll=1m", wantErr: true},
+		{
+			name:  "When a timeout is shortened with its poll interval, It Should parse them",
+			value: "capiProviders=3s,capiProvidersPoll=1s",
+			want:  Timeouts{CAPIProviders: 3 * time.Second, CAPIProvidersPoll: time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			timeouts, err := ParseTimeouts(tt.value)
+			if tt.wantErr {
+				var validationErr *ValidationError
+				g.Expect(errors.As(err, &validationErr)).To(BeTrue())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(timeouts).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestTimeoutsWithDefaults(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(Timeouts{}.WithDefaults()).To(Equal(DefaultTimeouts))
+
+	timeouts := Timeouts{EtcdBackupPoll: time.Second}.WithDefaults()
+	g.Expect(timeouts.EtcdBackupPoll).To(Equal(time.Second))
+	g.Expect(timeouts.EtcdBackupCompletion).To(Equal(DefaultTimeouts.EtcdBackupCompletion))
+}
@@ -1,5 +1,10 @@
 package common
 
+import (
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
 const (
 	CommonBackupAnnotationName  string = "hypershift.openshift.io/common-backup-plugin"
 	CommonRestoreAnnotationName string = "hypershift.openshift.io/common-restore-plugin"
@@ -14,6 +19,10 @@ const (
 	DefaultK8sSAFilePath string = "/var/run/secrets/kubernetes.io/serviceaccount"
 	KubevirtRHCOSLabel   string = "hypershift.openshift.io/is-kubevirt-rhcos"
 
+	// Environment variable selecting the kubeconfig context, along with KUBECONFIG, when the
+	// plugin binary runs out of the cluster
+	KubeContextEnv string = "HYPERSHIFT_OADP_PLUGIN_KUBECONTEXT"
+
 	// Integration with Hypershift, more info here: https://github.com/openshift/hypershift/pull/6195
 	HostedClusterRestoredFromBackupAnnotation string = "hypershift.openshift.io/restored-from-backup"
 	// Etcd snapshot URL annotation: set during backup so the restore plugin can read it
@@ -24,14 +33,23 @@ const (
 	HostedClusterKind         string = "HostedCluster"
 	HostedControlPlaneKind    string = "HostedControlPlane"
 	NodePoolKind              string = "NodePool"
+	MachineKind               string = "Machine"
 	PersistentVolumeKind      string = "PersistentVolume"
 	PersistentVolumeClaimKind string = "PersistentVolumeClaim"
 	ClusterDeploymentKind     string = "ClusterDeployment"
 	DataVolumeKind            string = "DataVolume"
 	HCPEtcdBackupKind         string = "HCPEtcdBackup"
+	AWSEndpointServiceKind    string = "AWSEndpointService"
+	IPAddressClaimKind        string = "IPAddressClaim"
+	Metal3IPClaimKind         string = "IPClaim"
 
 	// Default HyperShift Operator namespace
 	DefaultHONamespace string = "hypershift"
+	// HyperShift Operator deployment name
+	HODeploymentName string = "operator"
+	// ConfigMap published by the HyperShift Operator with the OCP versions it supports
+	HOSupportedVersionsConfigMapName string = "supported-versions"
+	HOSupportedVersionsKey           string = "supported-versions"
 	// ConfigMap key to override the HO namespace
 	ConfigKeyHONamespace string = "hoNamespace"
 
@@ -40,6 +58,245 @@ const (
 	EtcdBackupMethodVolume       string = "volumeSnapshot"
 	EtcdBackupMethodEtcdSnapshot string = "etcdSnapshot"
 
+	// Restore option to verify release images are pullable before restoring
+	ConfigKeyReleaseImageCheck string = "releaseImageCheck"
+	// Restore option to keep restored clusters paused until an operator resumes them
+	ConfigKeyRestorePaused string = "restorePaused"
+	// Restore option listing the kinds, as Kind.group, unpause-restore removes the cluster-api
+	// paused annotation from
+	ConfigKeyPausedKinds string = "pausedKinds"
+	// Restore option making unpause-restore request the reconciliation of the resumed
+	// HostedCluster, through ReconcileRequestedAnnotation
+	ConfigKeyReconcileAfterUnpause string = "reconcileAfterUnpause"
+	// Restore option pointing restored Machines to the cloud instances recorded at backup
+	ConfigKeyReadoptNodes string = "readoptNodes"
+	// Restore option deciding what happens to items whose live counterpart is the same object,
+	// e.g. repairing a partially alive HostedCluster
+	ConfigKeyExistingObjectPolicy string = "existingObjectPolicy"
+	ExistingObjectPolicyIgnore    string = "Ignore"
+	ExistingObjectPolicySkip      string = "Skip"
+	ExistingObjectPolicyPatch     string = "Patch"
+	ExistingObjectPolicyMerge     string = "Merge"
+	// Restore option estimating whether the target management cluster can schedule the control plane
+	ConfigKeyCapacityCheck string = "capacityCheck"
+	// Restore option acknowledging that HostedClusters of managed services, ROSA HCP or ARO
+	// HCP, are restored, with the guardrails of those services
+	ConfigKeyManagedServices string = "managedServices"
+	// Labels OpenShift Cluster Manager sets on the HostedClusters of managed services
+	ManagedClusterLabel   string = "api.openshift.com/managed"
+	ManagedClusterIDLabel string = "api.openshift.com/id"
+	// Managed services running HostedClusters
+	ManagedServiceROSA string = "ROSA HCP"
+	ManagedServiceARO  string = "ARO HCP"
+	// Taints of dedicated management cluster Nodes that HyperShift control plane pods tolerate,
+	// the cluster one when its value is their HCP namespace
+	ControlPlaneTaint string = "hypershift.openshift.io/control-plane"
+	ClusterTaint      string = "hypershift.openshift.io/cluster"
+	// Label set on every backed up item with the name of its HostedCluster
+	HostedClusterLabel string = "hypershift.openshift.io/hosted-cluster"
+	// Annotation opting a HostedCluster out of the plugin when set to PluginDisabled: its
+	// items are backed up and restored unchanged, as by Velero alone
+	PluginAnnotation string = "hypershift.openshift.io/oadp-plugin"
+	PluginDisabled   string = "disabled"
+
+	// Annotation recording the reclaim policy of a PersistentVolume before a migration backup set it to Retain
+	OriginalReclaimPolicyAnnotation string = "hypershift.openshift.io/original-reclaim-policy"
+
+	// Annotation recording the AWS resources an AWSEndpointService status points to, since
+	// Velero strips status during restore
+	AWSEndpointServiceStatusAnnotation string = "hypershift.openshift.io/aws-endpoint-service-status"
+
+	// Annotation recording the address an IPAddressClaim or metal3 IPClaim status points to,
+	// since Velero strips status during restore
+	IPClaimStatusAnnotation string = "hypershift.openshift.io/ip-claim-status"
+
+	// Annotation recording on the HostedControlPlane and the Backup how the secrets of the
+	// hosted cluster are encrypted in etcd, e.g. aescbc or kms/AWS
+	SecretEncryptionAnnotation string = "hypershift.openshift.io/secret-encryption"
+	// Annotation recording on the HostedControlPlane the SHA-256 fingerprints of its aescbc
+	// keys, as a JSON object by Secret name
+	SecretEncryptionKeysAnnotation string = "hypershift.openshift.io/secret-encryption-keys"
+
+	// Annotation recording the SourceMetadata of a backup on the Backup and its HostedClusters
+	SourceMetadataAnnotation string = "hypershift.openshift.io/backup-source-metadata"
+
+	// Annotations stamped on every backed up item and on the Backup with the BackupSchemaVersion
+	// of the backup and the version of the plugin that took it
+	BackupSchemaAnnotation        string = "hypershift.openshift.io/backup-schema"
+	BackupPluginVersionAnnotation string = "hypershift.openshift.io/backup-plugin-version"
+	// Annotation keeping the UID of a backed up HostedCluster, HostedControlPlane or NodePool,
+	// stored without its server populated metadata, so a restore recognizes the live object
+	SourceUIDAnnotation string = "hypershift.openshift.io/source-uid"
+
+	// Annotations summarizing on a Backup what was backed up for the hosted cluster: the
+	// HostedControlPlanes, as comma-separated namespace/name, how long the backup command
+	// kept the hosted cluster paused, and the DataUploads and CSI VolumeSnapshots completed
+	BackupHCPsAnnotation            string = "hypershift.openshift.io/hcps-backed-up"
+	BackupPauseDurationAnnotation   string = "hypershift.openshift.io/pause-duration"
+	BackupDataUploadsAnnotation     string = "hypershift.openshift.io/du-count"
+	BackupVolumeSnapshotsAnnotation string = "hypershift.openshift.io/vs-count"
+
+	// Annotation flagging objects restored paused and waiting for an operator to resume them
+	RestorePendingAnnotation string = "hypershift.openshift.io/restore-pending"
+	// Annotation bumped (RFC 3339) on a resumed HostedCluster to have it reconciled right away
+	ReconcileRequestedAnnotation string = "hypershift.openshift.io/reconcile-requested-at"
+
+	// Finalizer keeping a freshly restored HostedCluster or etcd PVC from being removed, and
+	// annotation telling until when (RFC 3339) unprotect-restore leaves it in place
+	DeletionProtectionFinalizer      string = "hypershift.openshift.io/deletion-protection"
+	DeletionProtectedUntilAnnotation string = "hypershift.openshift.io/deletion-protected-until"
+
+	// Annotation flagging the objects the backup command paused, with the backup name
+	PausedForBackupAnnotation string = "hypershift.openshift.io/paused-for-backup"
+	// Annotations telling, while the backup command runs, which backup the HostedCluster is
+	// paused for and since when (RFC 3339)
+	BackupInProgressAnnotation string = "hypershift.openshift.io/backup-in-progress"
+	BackupPausedAtAnnotation   string = "hypershift.openshift.io/backup-paused-at"
+
+	// Comma-separated platforms the plugin handles, overriding the detection from HostedClusters
+	ConfigKeyPlatforms string = "platforms"
+
+	// External hooks: webhook URL and/or Job template ConfigMap invoked at the hook events
+	ConfigKeyHookWebhookURL    string = "hookWebhookURL"
+	ConfigKeyHookJobTemplate   string = "hookJobTemplate"
+	ConfigKeyHookEvents        string = "hookEvents"
+	ConfigKeyHookFailurePolicy string = "hookFailurePolicy"
+
+	// Restore option deciding whether source/target environment mismatches fail the restore
+	ConfigKeySourceMismatchPolicy string = "sourceMismatchPolicy"
+	SourceMismatchPolicyWarn      string = "Warn"
+	SourceMismatchPolicyFail      string = "Fail"
+
+	// Restore option allowing the target management cluster, and its HyperShift Operator, to
+	// be this many minor versions behind the backup source
+	ConfigKeyManagementVersionSkew string = "managementVersionSkew"
+
+	// Restore option deciding what restoring an expired or expiring certificate does: warn
+	// (default), fail the restore, or leave control plane certificates to be reissued
+	ConfigKeyCertificateExpiryPolicy string = "certificateExpiryPolicy"
+	CertificateExpiryPolicyWarn      string = "Warn"
+	CertificateExpiryPolicyFail      string = "Fail"
+	CertificateExpiryPolicyRotate    string = "Rotate"
+	// Duration before expiry from which a restored certificate counts as expiring, e.g. 720h
+	ConfigKeyCertificateExpiryThreshold string = "certificateExpiryThreshold"
+
+	// Restore option protecting restored HostedClusters and etcd PVCs from deletion for a
+	// grace period, e.g. 72h
+	ConfigKeyDeletionProtection string = "deletionProtection"
+
+	// Backup option deciding whether a HostedCluster being deleted fails the backup or is left out
+	ConfigKeyDeletingClusterPolicy string = "deletingClusterPolicy"
+	DeletingClusterPolicyFail      string = "Fail"
+	DeletingClusterPolicySkip      string = "Skip"
+
+	// Backup option deciding whether an unhealthy HostedCluster is backed up, warned about or refused
+	ConfigKeyHealthGatePolicy string = "healthGatePolicy"
+	HealthGatePolicyIgnore    string = "Ignore"
+	HealthGatePolicyWarn      string = "Warn"
+	HealthGatePolicyFail      string = "Fail"
+
+	// Backup option deciding what happens when a Backup leaves defaultVolumesToFsBackup unset:
+	// not checked, refused when CSI snapshots cannot back up the control plane volumes, or
+	// fs-backup selected for them
+	ConfigKeyVolumeBackupModePolicy string = "volumeBackupModePolicy"
+	VolumeBackupModePolicyIgnore    string = "Ignore"
+	VolumeBackupModePolicyFail      string = "Fail"
+	VolumeBackupModePolicyAuto      string = "Auto"
+
+	// Backup option listing the PVCs, besides etcd, whose volumes are retained on migration backups
+	ConfigKeyMigrationRetainPVCs string = "migrationRetainPVCs"
+
+	// Backup option listing the control plane Pods, and optionally their volumes, labeled
+	// for fs-backup besides etcd, e.g. "ovnkube-master/ovnkube-db,image-registry"
+	ConfigKeyFSBackupPods string = "fsBackupPods"
+
+	// Backup options excluding DataVolumes and PVCs besides the KubeVirt RHCOS ones: labels as
+	// comma-separated key or key=value, names as comma-separated glob patterns
+	ConfigKeySkipVolumeLabels string = "skipVolumeLabels"
+	ConfigKeySkipVolumeNames  string = "skipVolumeNames"
+
+	// Restore option deciding which backed up Pods are restored
+	ConfigKeyPodRestorePolicy        string = "podRestorePolicy"
+	PodRestorePolicySkipAll          string = "SkipAll"
+	PodRestorePolicySkipControlPlane string = "SkipControlPlane"
+	PodRestorePolicySkipNone         string = "SkipNone"
+
+	// Completion notifications: webhook URL, payload format and optional watcher image override
+	ConfigKeyNotificationWebhookURL string = "notificationWebhookURL"
+	ConfigKeyNotificationFormat     string = "notificationFormat"
+	ConfigKeyNotificationImage      string = "notificationImage"
+
+	// Duration bounding each Execute call of the backup and restore plugins, e.g. 15m
+	ConfigKeyExecuteTimeout string = "executeTimeout"
+
+	// Comma-separated name=duration overrides of the plugin timeouts and poll intervals, e.g.
+	// etcdBackupCompletion=30m
+	ConfigKeyTimeouts string = "timeouts"
+
+	// Namespace of the Velero install, holding the DataUploads and PodVolumeBackups of its
+	// backups and the node-agent DaemonSet; the namespace the plugin runs in by default
+	ConfigKeyVeleroNamespace string = "veleroNamespace"
+
+	// Comma-separated non-critical problems logged as warnings instead of failing the item
+	ConfigKeyTolerateErrors  string = "tolerateErrors"
+	TolerateSourceMetadata   string = "sourceMetadata"
+	TolerateVolumeBackupMode string = "volumeBackupMode"
+	TolerateReleaseImage     string = "releaseImage"
+	ToleratePluginVersion    string = "pluginVersion"
+
+	// Fails plugin initialization on configuration keys neither plugin knows, e.g. misspelled
+	// ones, instead of logging them
+	ConfigKeyStrictConfig string = "strictConfig"
+
+	// OTLP/HTTP endpoint URL the plugin exports its trace spans to
+	ConfigKeyTracingEndpoint string = "tracingEndpoint"
+
+	// Backup option bounding how long the backup command may keep a hosted cluster paused
+	ConfigKeyMaxPauseDuration string = "maxPauseDuration"
+	// Annotation recording on the Backup why the plugin resumed its hosted cluster early
+	PauseWindowExceededAnnotation string = "hypershift.openshift.io/pause-window-exceeded"
+
+	// Backup option deciding what a backup does while an earlier one of the same hosted
+	// cluster is running: wait for it (default), fail, or ignore it
+	ConfigKeyConcurrentBackupPolicy string = "concurrentBackupPolicy"
+	ConcurrentBackupPolicyWait      string = "Wait"
+	ConcurrentBackupPolicyFail      string = "Fail"
+	ConcurrentBackupPolicyIgnore    string = "Ignore"
+
+	// Backup option capturing the Nodes, pending CSRs and ClusterOperators of the hosted
+	// cluster as a reference for disaster recovery verification
+	ConfigKeyGuestSnapshot string = "guestSnapshot"
+
+	// Options of the platform sections of the configuration, keyed <platform>.<option> with
+	// the lower case platform name, e.g. aws.regenPrivateLink
+	PlatformOptionRegenPrivateLink string = "regenPrivateLink"
+	PlatformOptionDatabaseSnapshot string = "databaseSnapshot"
+	PlatformOptionServiceNamespace string = "serviceNamespace"
+	PlatformOptionPreserveOnDelete string = "preserveOnDelete"
+	PlatformOptionFreezeVMs        string = "freezeVMs"
+	// Annotation recording on the HostedControlPlane the namespace/name of the VolumeSnapshot
+	// of the assisted-service database taken for the backup
+	AgentDatabaseSnapshotAnnotation string = "hypershift.openshift.io/agent-database-snapshot"
+
+	// Backup option adding the HyperShift, CAPI and platform CRDs to the backup
+	ConfigKeyBackupCRDs string = "backupCRDs"
+
+	// Backup option restricting the NodePools (and their CAPI machinery) that are backed up
+	ConfigKeyNodePoolSelector string = "nodePoolSelector"
+	// Annotation HyperShift sets on CAPI machinery with the owning NodePool as namespace/name
+	NodePoolAnnotation string = "hypershift.openshift.io/nodePool"
+
+	// cluster-api deployments in the HCP namespace and the annotation pausing CAPI objects
+	CAPIManagerDeploymentName  string = "cluster-api"
+	CAPIProviderDeploymentName string = "capi-provider"
+	CAPIPausedAnnotation       string = "cluster.x-k8s.io/paused"
+
+	// Node group bounds the cluster autoscaler reads from MachineDeployments, and the annotation
+	// keeping the bounds of a MachineDeployment as min,max while the backup command pins them
+	AutoscalerMinSizeAnnotation       string = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-min-size"
+	AutoscalerMaxSizeAnnotation       string = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-max-size"
+	AutoscalingBeforeBackupAnnotation string = "hypershift.openshift.io/autoscaling-before-backup"
+
 	// Fallback credential secret for standalone Velero (no DPA).
 	// Both ARO (Azure WI) and future ROSA (IRSA) use this convention.
 	DefaultCredentialSecretName string = "cloud-credentials"
@@ -48,22 +305,122 @@ const (
 	// DPA CRD name used to detect OADP+DPA vs standalone Velero
 	DPACRDName string = "dataprotectionapplications.oadp.openshift.io"
 
-	// Velero annotation to exclude specific volumes from backup
+	// Velero annotations to include or exclude specific volumes of a Pod from fs-backup
+	BackupVolumesAnnotation         string = "backup.velero.io/backup-volumes"
 	BackupVolumesExcludesAnnotation string = "backup.velero.io/backup-volumes-excludes"
 	// Etcd data volume name in the StatefulSet pod
 	EtcdDataVolumeName string = "data"
 	// Etcd PVC name prefix (StatefulSet pattern: {volumeName}-{stsName}-{index})
 	EtcdPVCPrefix string = "data-etcd-"
+	// App label of the ovnkube-master StatefulSet of control planes older than OVN
+	// interconnect, whose PVCs hold the OVN northbound and southbound databases
+	OVNKubeMasterApp string = "ovnkube-master"
 
+	// Labels the HyperShift Operator sets on the HostedControlPlane namespace
+	HostedControlPlaneNamespaceLabel string = "hypershift.openshift.io/hosted-control-plane"
+	PodSecurityEnforceLabel          string = "pod-security.kubernetes.io/enforce"
+	PodSecurityAuditLabel            string = "pod-security.kubernetes.io/audit"
+	PodSecurityWarnLabel             string = "pod-security.kubernetes.io/warn"
+	PodSecurityLabelSyncLabel        string = "security.openshift.io/scc.podSecurityLabelSync"
+
+	// Annotation the OpenShift router sets on Routes whose host it generated from its domain
+	RouteHostGeneratedAnnotation string = "openshift.io/host.generated"
+
+	// Secrets of the control plane namespace tied to the konnectivity and ignition server
+	// endpoints: the signers the guest cluster trusts, the serving certificates issued for the
+	// endpoint hostnames, and the NodePool ignition tokens and user data
+	KonnectivitySignerSecret        string = "konnectivity-signer"
+	KonnectivityServerSecret        string = "konnectivity-server"
+	IgnitionServerCASecret          string = "ignition-server-ca-cert"
+	IgnitionServerServingCertSecret string = "ignition-server-serving-cert"
+	IgnitionTokenSecretPrefix       string = "token-"
+	UserDataSecretPrefix            string = "user-data-"
 )
 
 var (
+	// GroupKinds of the kinds the plugin handles. Kind names alone are ambiguous across API
+	// groups, e.g. Machine is also an OpenShift machine-api kind.
+	HostedClusterGroupKind         = schema.GroupKind{Group: hyperv1.GroupVersion.Group, Kind: HostedClusterKind}
+	HostedControlPlaneGroupKind    = schema.GroupKind{Group: hyperv1.GroupVersion.Group, Kind: HostedControlPlaneKind}
+	NodePoolGroupKind              = schema.GroupKind{Group: hyperv1.GroupVersion.Group, Kind: NodePoolKind}
+	AWSEndpointServiceGroupKind    = schema.GroupKind{Group: hyperv1.GroupVersion.Group, Kind: AWSEndpointServiceKind}
+	MachineGroupKind               = schema.GroupKind{Group: "cluster.x-k8s.io", Kind: MachineKind}
+	IPAddressClaimGroupKind        = schema.GroupKind{Group: "ipam.cluster.x-k8s.io", Kind: IPAddressClaimKind}
+	Metal3IPClaimGroupKind         = schema.GroupKind{Group: "ipam.metal3.io", Kind: Metal3IPClaimKind}
+	ClusterDeploymentGroupKind     = schema.GroupKind{Group: "hive.openshift.io", Kind: ClusterDeploymentKind}
+	DataVolumeGroupKind            = schema.GroupKind{Group: "cdi.kubevirt.io", Kind: DataVolumeKind}
+	PersistentVolumeClaimGroupKind = schema.GroupKind{Kind: PersistentVolumeClaimKind}
+	PodGroupKind                   = schema.GroupKind{Kind: "Pod"}
+	SecretGroupKind                = schema.GroupKind{Kind: "Secret"}
+	StatefulSetGroupKind           = schema.GroupKind{Group: "apps", Kind: "StatefulSet"}
+	ServiceGroupKind               = schema.GroupKind{Kind: "Service"}
+	RouteGroupKind                 = schema.GroupKind{Group: "route.openshift.io", Kind: "Route"}
+	DeploymentGroupKind            = schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	PriorityClassGroupKind         = schema.GroupKind{Group: "scheduling.k8s.io", Kind: "PriorityClass"}
+	CSRGroupKind                   = schema.GroupKind{Group: "certificates.k8s.io", Kind: "CertificateSigningRequest"}
+	VolumeSnapshotContentGroupKind = schema.GroupKind{Group: "snapshot.storage.k8s.io", Kind: "VolumeSnapshotContent"}
+
 	MainKinds = map[string]bool{
 		HostedClusterKind:         true,
 		NodePoolKind:              true,
 		PersistentVolumeKind:      true,
 		PersistentVolumeClaimKind: true,
 	}
+
+	// RequiredCRDs are the HyperShift and cluster-api CRDs a management cluster
+	// must serve before HostedClusters can be restored into it.
+	RequiredCRDs = []string{
+		"hostedclusters.hypershift.openshift.io",
+		"hostedcontrolplanes.hypershift.openshift.io",
+		"nodepools.hypershift.openshift.io",
+		"clusters.cluster.x-k8s.io",
+		"machines.cluster.x-k8s.io",
+		"machinesets.cluster.x-k8s.io",
+		"machinedeployments.cluster.x-k8s.io",
+	}
+
+	// PlatformCRDs are the cluster-api provider CRDs required per platform.
+	PlatformCRDs = map[hyperv1.PlatformType][]string{
+		hyperv1.AWSPlatform:       {"awsmachines.infrastructure.cluster.x-k8s.io", "awsmachinetemplates.infrastructure.cluster.x-k8s.io"},
+		hyperv1.AzurePlatform:     {"azuremachines.infrastructure.cluster.x-k8s.io", "azuremachinetemplates.infrastructure.cluster.x-k8s.io"},
+		hyperv1.IBMCloudPlatform:  {"ibmpowervsmachines.infrastructure.cluster.x-k8s.io", "ibmpowervsmachinetemplates.infrastructure.cluster.x-k8s.io"},
+		hyperv1.KubevirtPlatform:  {"kubevirtmachines.infrastructure.cluster.x-k8s.io", "kubevirtmachinetemplates.infrastructure.cluster.x-k8s.io"},
+		hyperv1.OpenStackPlatform: {"openstackmachines.infrastructure.cluster.x-k8s.io", "openstackmachinetemplates.infrastructure.cluster.x-k8s.io"},
+		hyperv1.AgentPlatform:     {"agentmachines.capi-provider.agent-install.openshift.io", "agentmachinetemplates.capi-provider.agent-install.openshift.io"},
+	}
+
+	// CRDGroups are the API groups of the HyperShift and cluster-api CRDs, subgroups
+	// included, that the backupCRDs option adds to the backup.
+	CRDGroups = []string{"hypershift.openshift.io", "cluster.x-k8s.io"}
+
+	// PlatformCRDGroups are the API groups of the provider CRDs per platform outside of
+	// CRDGroups, subgroups included.
+	PlatformCRDGroups = map[hyperv1.PlatformType][]string{
+		hyperv1.AgentPlatform: {"agent-install.openshift.io", "ipam.metal3.io"},
+	}
+
+	// knownPlatforms indexes the HyperShift platform types by lowercase name, for config parsing.
+	knownPlatforms = map[string]hyperv1.PlatformType{
+		"aws":       hyperv1.AWSPlatform,
+		"azure":     hyperv1.AzurePlatform,
+		"ibmcloud":  hyperv1.IBMCloudPlatform,
+		"powervs":   hyperv1.PowerVSPlatform,
+		"openstack": hyperv1.OpenStackPlatform,
+		"kubevirt":  hyperv1.KubevirtPlatform,
+		"agent":     hyperv1.AgentPlatform,
+		"gcp":       hyperv1.GCPPlatform,
+		"none":      hyperv1.NonePlatform,
+	}
+
+	// ControlPlaneNamespaceLabels are the labels the control plane requires on the
+	// HCP namespace. Velero creates missing namespaces without them during restore.
+	ControlPlaneNamespaceLabels = map[string]string{
+		HostedControlPlaneNamespaceLabel: "true",
+		PodSecurityEnforceLabel:          "privileged",
+		PodSecurityAuditLabel:            "privileged",
+		PodSecurityWarnLabel:             "privileged",
+		PodSecurityLabelSyncLabel:        "false",
+	}
 )
 
 type BackupStatus string
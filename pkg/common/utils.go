@@ -6,13 +6,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/openshift/hypershift-oadp-plugin/pkg/tracing"
 	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
 	"github.com/sirupsen/logrus"
 	veleroapiv1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"go.opentelemetry.io/otel/attribute"
 
+	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -20,14 +25,20 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/rest"
-	cr "sigs.k8s.io/controller-runtime"
+	"k8s.io/client-go/tools/clientcmd"
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	crconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
 )
 
 var (
 	k8sSAFilePath   = DefaultK8sSAFilePath
 	k8sSAFilePathMu sync.RWMutex
+
+	kubeconfigPath    string
+	kubeconfigContext string
+	kubeconfigMu      sync.RWMutex
 )
 
 // SetK8sSAFilePath overrides the service account file path (for testing).
@@ -43,6 +54,20 @@ func getK8sSAFilePath() string {
 	return k8sSAFilePath
 }
 
+// SetKubeconfig overrides the kubeconfig file and context GetConfig uses, to run the plugin
+// binary against a cluster from outside of it. Empty values keep the defaults.
+func SetKubeconfig(path, context string) {
+	kubeconfigMu.Lock()
+	defer kubeconfigMu.Unlock()
+	kubeconfigPath, kubeconfigContext = path, context
+}
+
+func getKubeconfig() (string, string) {
+	kubeconfigMu.RLock()
+	defer kubeconfigMu.RUnlock()
+	return kubeconfigPath, kubeconfigContext
+}
+
 func getMetadataAndAnnotations(item runtime.Unstructured) (metav1.Object, map[string]string, error) {
 	metadata, err := meta.Accessor(item)
 	if err != nil {
@@ -71,8 +96,25 @@ func GetClient() (crclient.Client, error) {
 }
 
 // GetConfig retrieves the Kubernetes REST configuration using the client-go library.
+// The kubeconfig file and context set with SetKubeconfig take precedence. Otherwise the
+// KUBECONFIG and KubeContextEnv environment variables, the in-cluster configuration and
+// $HOME/.kube/config are used, in that order.
 func GetConfig() (*rest.Config, error) {
-	cfg, err := cr.GetConfig()
+	path, kubeContext := getKubeconfig()
+	if kubeContext == "" {
+		kubeContext = os.Getenv(KubeContextEnv)
+	}
+
+	var cfg *rest.Config
+	var err error
+	if path != "" {
+		cfg, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: path},
+			&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+		).ClientConfig()
+	} else {
+		cfg, err = crconfig.GetConfigWithContext(kubeContext)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -85,19 +127,35 @@ func GetConfig() (*rest.Config, error) {
 
 // GetCurrentNamespace reads the namespace from the Kubernetes service account
 // token file and returns it as a string. The file is expected to be located at
-// "/var/run/secrets/kubernetes.io/serviceaccount/namespace". If there is an error
-// reading the file, it returns an empty string and the error.
+// "/var/run/secrets/kubernetes.io/serviceaccount/namespace". When the file is missing
+// or empty, e.g. when running out of the cluster, it falls back to the POD_NAMESPACE
+// (downward API) and NAMESPACE environment variables. If none is set, it returns an
+// empty string and an error.
 func GetCurrentNamespace() (string, error) {
 	namespaceFilePath := filepath.Join(getK8sSAFilePath(), "namespace")
 	namespace, err := os.ReadFile(namespaceFilePath)
-	if err != nil {
-		return "", err
+	if err == nil && len(strings.TrimSpace(string(namespace))) > 0 {
+		return strings.TrimSpace(string(namespace)), nil
+	}
+	for _, env := range namespaceEnvVars {
+		if value := os.Getenv(env); value != "" {
+			return value, nil
+		}
 	}
-	return string(namespace), nil
+	if err == nil {
+		err = fmt.Errorf("namespace file %s is empty", namespaceFilePath)
+	}
+	return "", fmt.Errorf("%w, and none of %v is set", err, namespaceEnvVars)
 }
 
+// namespaceEnvVars are the environment variables GetCurrentNamespace falls back to, in order.
+var namespaceEnvVars = []string{"POD_NAMESPACE", "NAMESPACE"}
+
 // MatchSuffixKind checks if the given kind string ends with any of the provided suffixes.
 // It returns true if a match is found, otherwise it returns false.
+//
+// Deprecated: suffixes such as "clusters" also match unrelated kinds. Match explicit
+// GroupKinds instead, as the pausedKinds option does.
 func MatchSuffixKind(kind string, suffixes ...string) bool {
 	for _, suffix := range suffixes {
 		if strings.HasSuffix(kind, suffix) {
@@ -153,6 +211,67 @@ func RemoveLabel(metadata metav1.Object, key string) {
 	metadata.SetLabels(labels)
 }
 
+// RetainPersistentVolume switches the reclaim policy of a PersistentVolume to Retain, so
+// deleting its claim does not delete the volume. The original policy is recorded in the
+// OriginalReclaimPolicyAnnotation. It returns whether the volume was changed.
+func RetainPersistentVolume(ctx context.Context, c crclient.Client, name string) (bool, error) {
+	pv := &corev1.PersistentVolume{}
+	if err := c.Get(ctx, crclient.ObjectKey{Name: name}, pv); err != nil {
+		return false, fmt.Errorf("error getting PersistentVolume %s: %w", name, err)
+	}
+	if pv.Spec.PersistentVolumeReclaimPolicy == corev1.PersistentVolumeReclaimRetain {
+		return false, nil
+	}
+
+	original := pv.DeepCopy()
+	AddAnnotation(pv, OriginalReclaimPolicyAnnotation, string(pv.Spec.PersistentVolumeReclaimPolicy))
+	pv.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimRetain
+	if err := c.Patch(ctx, pv, crclient.MergeFrom(original)); err != nil {
+		return false, fmt.Errorf("error setting PersistentVolume %s reclaim policy to Retain: %w", name, err)
+	}
+	return true, nil
+}
+
+// serverPopulatedMetadata lists the metadata fields set by the API server, meaningless
+// once the object is restored elsewhere.
+var serverPopulatedMetadata = []string{
+	"resourceVersion",
+	"uid",
+	"generation",
+	"creationTimestamp",
+	"deletionTimestamp",
+	"deletionGracePeriodSeconds",
+	"managedFields",
+	"selfLink",
+}
+
+// StripServerPopulatedFields removes the status and the server populated metadata from an
+// unstructured object. The status fields listed in keepStatus are preserved.
+func StripServerPopulatedFields(content map[string]any, keepStatus ...string) {
+	if metadata, ok := content["metadata"].(map[string]any); ok {
+		for _, field := range serverPopulatedMetadata {
+			delete(metadata, field)
+		}
+	}
+
+	status, ok := content["status"].(map[string]any)
+	if !ok {
+		delete(content, "status")
+		return
+	}
+	kept := map[string]any{}
+	for _, field := range keepStatus {
+		if value, ok := status[field]; ok {
+			kept[field] = value
+		}
+	}
+	if len(kept) == 0 {
+		delete(content, "status")
+		return
+	}
+	content["status"] = kept
+}
+
 // GetHCP retrieves the first HostedControlPlane object from the provided list of namespaces.
 // It iterates through the namespaces and attempts to list HostedControlPlane objects in each namespace.
 // If a HostedControlPlane is found, it returns the first one encountered.
@@ -161,7 +280,7 @@ func GetHCP(ctx context.Context, nsList []string, c crclient.Client, log logrus.
 	for _, ns := range nsList {
 		hcpList := &hyperv1.HostedControlPlaneList{}
 		if err := c.List(ctx, hcpList, crclient.InNamespace(ns)); err != nil {
-			return nil, fmt.Errorf("error getting HostedControlPlane: %v", err)
+			return nil, fmt.Errorf("error getting HostedControlPlane: %w", err)
 		}
 
 		if len(hcpList.Items) <= 0 {
@@ -205,6 +324,29 @@ func GetHostedCluster(ctx context.Context, c crclient.Client, includedNamespaces
 	return nil, nil
 }
 
+// IsPluginDisabled reports whether the HostedCluster opted out of the plugin through
+// PluginAnnotation.
+func IsPluginDisabled(hc *hyperv1.HostedCluster) bool {
+	return hc != nil && hc.Annotations[PluginAnnotation] == PluginDisabled
+}
+
+// FindPluginDisabledHostedCluster returns, as namespace/name, a HostedCluster of the
+// namespaces that opted out of the plugin, empty when there is none.
+func FindPluginDisabledHostedCluster(ctx context.Context, c crclient.Client, namespaces []string) (string, error) {
+	for _, ns := range namespaces {
+		hcList := &hyperv1.HostedClusterList{}
+		if err := c.List(ctx, hcList, crclient.InNamespace(ns)); err != nil {
+			return "", fmt.Errorf("error listing HostedClusters in namespace %s: %w", ns, err)
+		}
+		for i := range hcList.Items {
+			if IsPluginDisabled(&hcList.Items[i]) {
+				return ns + "/" + hcList.Items[i].Name, nil
+			}
+		}
+	}
+	return "", nil
+}
+
 // ShouldEndPluginExecution checks if the plugin should end execution by verifying if the required
 // Hypershift resources (HostedControlPlane and HostedCluster) exist in the cluster.
 // Returns true if the plugin should end execution (i.e., if this is not a Hypershift cluster).
@@ -224,7 +366,7 @@ func ShouldEndPluginExecution(ctx context.Context, backup *veleroapiv1.Backup, c
 
 	exists, err := CRDExists(ctx, "hostedcontrolplanes.hypershift.openshift.io", c)
 	if err != nil {
-		return true, fmt.Errorf("error checking for HostedControlPlane CRD: %v", err)
+		return true, fmt.Errorf("error checking for HostedControlPlane CRD: %w", err)
 	}
 
 	if exists {
@@ -234,6 +376,78 @@ func ShouldEndPluginExecution(ctx context.Context, backup *veleroapiv1.Backup, c
 	return true, fmt.Errorf("no HostedControlPlane CRD found")
 }
 
+// IsPartialRestore reports whether the restore only covers a subset of the backup that
+// leaves the HostedCluster out, e.g. a single deleted NodePool. Such restores must not
+// touch the HostedCluster pause state.
+func IsPartialRestore(restore *veleroapiv1.Restore) bool {
+	for _, resource := range restore.Spec.ExcludedResources {
+		if strings.HasPrefix(resource, "hostedcluster") {
+			return true
+		}
+	}
+
+	if len(restore.Spec.IncludedResources) == 0 {
+		return false
+	}
+	for _, resource := range restore.Spec.IncludedResources {
+		if resource == "*" || strings.HasPrefix(resource, "hostedcluster") {
+			return false
+		}
+	}
+	return true
+}
+
+// TrustBundleConfigMaps returns the names of the ConfigMaps of the HostedCluster namespace
+// holding the CA bundles the HostedCluster trusts: its additionalTrustBundle and the
+// trustedCA of its proxy.
+func TrustBundleConfigMaps(hc *hyperv1.HostedCluster) []string {
+	var names []string
+	if hc.Spec.AdditionalTrustBundle != nil && hc.Spec.AdditionalTrustBundle.Name != "" {
+		names = append(names, hc.Spec.AdditionalTrustBundle.Name)
+	}
+	if config := hc.Spec.Configuration; config != nil && config.Proxy != nil && config.Proxy.TrustedCA.Name != "" &&
+		!slices.Contains(names, config.Proxy.TrustedCA.Name) {
+		names = append(names, config.Proxy.TrustedCA.Name)
+	}
+	return names
+}
+
+// BackupIncludesResource reports whether the resource selection of the backup covers the
+// resource, known by any of its names, e.g. "persistentvolumeclaims" and "pvc".
+func BackupIncludesResource(backup *veleroapiv1.Backup, names ...string) bool {
+	for _, resource := range backup.Spec.ExcludedResources {
+		if slices.Contains(names, resource) {
+			return false
+		}
+	}
+
+	if len(backup.Spec.IncludedResources) == 0 {
+		return true
+	}
+	for _, resource := range backup.Spec.IncludedResources {
+		if resource == "*" || slices.Contains(names, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePlatforms parses the comma-separated platforms option, regardless of case, without
+// duplicates.
+func ParsePlatforms(configured string) ([]hyperv1.PlatformType, error) {
+	var platforms []hyperv1.PlatformType
+	for _, value := range strings.Split(configured, ",") {
+		platform, ok := knownPlatforms[strings.ToLower(strings.TrimSpace(value))]
+		if !ok {
+			return nil, NewValidationError("unknown platform %q in %s", value, ConfigKeyPlatforms)
+		}
+		if !slices.Contains(platforms, platform) {
+			platforms = append(platforms, platform)
+		}
+	}
+	return platforms, nil
+}
+
 func CRDExists(ctx context.Context, crdName string, c crclient.Client) (bool, error) {
 	crd := &apiextensionsv1.CustomResourceDefinition{}
 	err := c.Get(ctx, client.ObjectKey{Name: crdName}, crd)
@@ -246,3 +460,126 @@ func CRDExists(ctx context.Context, crdName string, c crclient.Client) (bool, er
 	return true, nil
 }
 
+// EnsureNamespace makes sure the namespace exists and carries the given labels.
+// If the namespace is missing it is created with the labels. If it already exists
+// (e.g. created bare by Velero), only the missing or different labels are patched in.
+// A label with a different value is overridden on purpose, e.g. the pod security level
+// the control plane requires, and a warning names the value it replaced.
+func EnsureNamespace(ctx context.Context, c crclient.Client, log logrus.FieldLogger, name string, labels map[string]string) error {
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, crclient.ObjectKey{Name: name}, ns); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("error getting namespace %s: %w", name, err)
+		}
+		ns = &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: labels,
+			},
+		}
+		if err := c.Create(ctx, ns); err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("error creating namespace %s: %w", name, err)
+		}
+		return nil
+	}
+
+	patch := crclient.MergeFrom(ns.DeepCopy())
+	changed := false
+	for key, value := range labels {
+		current, ok := ns.Labels[key]
+		if ok && current == value {
+			continue
+		}
+		if ok {
+			log.Warnf("Overriding label %s=%s of namespace %s with %s", key, current, name, value)
+		}
+		AddLabel(ns, key, value)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	if err := c.Patch(ctx, ns, patch); err != nil {
+		return fmt.Errorf("error patching labels on namespace %s: %w", name, err)
+	}
+	return nil
+}
+
+// UnpauseRestoredCluster resumes a HostedCluster restored with the restorePaused option.
+// Once the cluster-api deployments in the HCP namespace are Available, the objects of the
+// pausedKinds are unpaused, then the restore-pending annotation and spec.pausedUntil are removed from
+// the NodePools and HostedControlPlane and from the HostedCluster last, so an interrupted
+// run can be repeated safely.
+func UnpauseRestoredCluster(ctx context.Context, c crclient.Client, namespace, name string, timeouts Timeouts, pausedKinds []schema.GroupKind) (err error) {
+	ctx, span := tracing.Start(ctx, "common.UnpauseRestoredCluster", attribute.String("namespace", namespace), attribute.String("name", name))
+	defer func() { tracing.End(span, err) }()
+
+	hc := &hyperv1.HostedCluster{}
+	if err := c.Get(ctx, crclient.ObjectKey{Name: name, Namespace: namespace}, hc); err != nil {
+		return fmt.Errorf("error getting HostedCluster %s/%s: %w", namespace, name, err)
+	}
+	if _, ok := hc.Annotations[RestorePendingAnnotation]; !ok {
+		return fmt.Errorf("HostedCluster %s/%s is not pending a restore confirmation", namespace, name)
+	}
+
+	// Machine controllers must not act on half-restored state
+	hcpNamespace := GetHCPNamespace(name, namespace)
+	if err := WaitForCAPIProviders(ctx, c, hcpNamespace, hc.Spec.Platform.Type, timeouts); err != nil {
+		return err
+	}
+	if err := UnpauseCAPIResources(ctx, c, hcpNamespace, pausedKinds); err != nil {
+		return err
+	}
+
+	nodePools, err := listNodePools(ctx, c, namespace, name)
+	if err != nil {
+		return err
+	}
+	for i := range nodePools {
+		np := &nodePools[i]
+		if err := clearRestorePending(ctx, c, np, &np.Spec.PausedUntil); err != nil {
+			return fmt.Errorf("error resuming NodePool %s/%s: %w", namespace, np.Name, err)
+		}
+	}
+
+	hcp := &hyperv1.HostedControlPlane{}
+	if err := c.Get(ctx, crclient.ObjectKey{Name: name, Namespace: hcpNamespace}, hcp); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("error getting HostedControlPlane %s/%s: %w", hcpNamespace, name, err)
+		}
+	} else if err := clearRestorePending(ctx, c, hcp, &hcp.Spec.PausedUntil); err != nil {
+		return fmt.Errorf("error resuming HostedControlPlane %s/%s: %w", hcpNamespace, name, err)
+	}
+
+	if err := clearRestorePending(ctx, c, hc, &hc.Spec.PausedUntil); err != nil {
+		return fmt.Errorf("error resuming HostedCluster %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// clearRestorePending removes the restore-pending annotation and the pause set by the
+// restore from obj. Objects not flagged by the restore are left untouched.
+func clearRestorePending(ctx context.Context, c crclient.Client, obj crclient.Object, pausedUntil **string) error {
+	if _, ok := obj.GetAnnotations()[RestorePendingAnnotation]; !ok {
+		return nil
+	}
+	patch := crclient.MergeFrom(obj.DeepCopyObject().(crclient.Object))
+	RemoveAnnotation(obj, RestorePendingAnnotation)
+	*pausedUntil = nil
+	return c.Patch(ctx, obj, patch)
+}
+
+// RequestReconcile stamps the HostedCluster with the ReconcileRequestedAnnotation, set to now,
+// so the HyperShift Operator reconciles it right away rather than at its next resync.
+func RequestReconcile(ctx context.Context, c crclient.Client, namespace, name string, now time.Time) error {
+	hc := &hyperv1.HostedCluster{}
+	if err := c.Get(ctx, crclient.ObjectKey{Name: name, Namespace: namespace}, hc); err != nil {
+		return fmt.Errorf("error getting HostedCluster %s/%s: %w", namespace, name, err)
+	}
+	patch := crclient.MergeFrom(hc.DeepCopy())
+	AddAnnotation(hc, ReconcileRequestedAnnotation, now.UTC().Format(time.RFC3339Nano))
+	if err := c.Patch(ctx, hc, patch); err != nil {
+		return fmt.Errorf("error requesting the reconciliation of HostedCluster %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
@@ -1,12 +1,17 @@
 package common
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/common/clocktest"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
@@ -432,10 +437,126 @@ func TestRemoveLabel(t *testing.T) {
 	}
 }
 
+func TestRetainPersistentVolume(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       corev1.PersistentVolumeReclaimPolicy
+		wantChanged  bool
+		wantOriginal string
+	}{
+		{
+			name:         "When the volume is deleted with its claim, It Should retain it and record the original policy",
+			policy:       corev1.PersistentVolumeReclaimDelete,
+			wantChanged:  true,
+			wantOriginal: "Delete",
+		},
+		{
+			name:   "When the volume is already retained, It Should leave it unchanged",
+			policy: corev1.PersistentVolumeReclaimRetain,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			pv := &corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-etcd-0"},
+				Spec:       corev1.PersistentVolumeSpec{PersistentVolumeReclaimPolicy: tt.policy},
+			}
+			c := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(pv).Build()
+
+			changed, err := RetainPersistentVolume(context.TODO(), c, "pv-etcd-0")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(changed).To(Equal(tt.wantChanged))
+
+			got := &corev1.PersistentVolume{}
+			g.Expect(c.Get(context.TODO(), client.ObjectKey{Name: "pv-etcd-0"}, got)).To(Succeed())
+			g.Expect(got.Spec.PersistentVolumeReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimRetain))
+			g.Expect(got.Annotations[OriginalReclaimPolicyAnnotation]).To(Equal(tt.wantOriginal))
+		})
+	}
+
+	t.Run("When the volume does not exist, It Should return an error", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(CustomScheme).Build()
+		_, err := RetainPersistentVolume(context.TODO(), c, "missing")
+		g.Expect(err).To(HaveOccurred())
+	})
+}
+
+func TestStripServerPopulatedFields(t *testing.T) {
+	newContent := func(status map[string]any) map[string]any {
+		content := map[string]any{
+			"metadata": map[string]any{
+				"name":              "test",
+				"namespace":         "clusters",
+				"labels":            map[string]any{"app": "test"},
+				"resourceVersion":   "12345",
+				"uid":               "0c5c4a2e-2b0e-4f43-9a0f-6d4c1f6ad0b1",
+				"generation":        int64(3),
+				"creationTimestamp": "2026-01-01T00:00:00Z",
+				"managedFields":     []any{map[string]any{"manager": "hypershift"}},
+			},
+			"spec": map[string]any{"release": map[string]any{"image": "quay.io/ocp-release:4.18.0"}},
+		}
+		if status != nil {
+			content["status"] = status
+		}
+		return content
+	}
+	wantMetadata := map[string]any{
+		"name":      "test",
+		"namespace": "clusters",
+		"labels":    map[string]any{"app": "test"},
+	}
+
+	tests := []struct {
+		name       string
+		content    map[string]any
+		keepStatus []string
+		wantStatus any
+	}{
+		{
+			name:    "strip status and server populated metadata",
+			content: newContent(map[string]any{"conditions": []any{}, "version": map[string]any{}}),
+		},
+		{
+			name:       "keep the listed status fields",
+			content:    newContent(map[string]any{"conditions": []any{}, "lastSuccessfulEtcdBackupURL": "s3://bucket/snapshot.db"}),
+			keepStatus: []string{"lastSuccessfulEtcdBackupURL"},
+			wantStatus: map[string]any{"lastSuccessfulEtcdBackupURL": "s3://bucket/snapshot.db"},
+		},
+		{
+			name:       "drop the status when no listed field is set",
+			content:    newContent(map[string]any{"conditions": []any{}}),
+			keepStatus: []string{"lastSuccessfulEtcdBackupURL"},
+		},
+		{
+			name:    "object without status",
+			content: newContent(nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			StripServerPopulatedFields(tt.content, tt.keepStatus...)
+			g.Expect(tt.content["metadata"]).To(Equal(wantMetadata))
+			g.Expect(tt.content["spec"]).NotTo(BeNil())
+			if tt.wantStatus == nil {
+				g.Expect(tt.content).NotTo(HaveKey("status"))
+				return
+			}
+			g.Expect(tt.content["status"]).To(Equal(tt.wantStatus))
+		})
+	}
+}
+
 func TestGetCurrentNamespace(t *testing.T) {
 	tests := []struct {
 		name          string
 		fileContent   string
+		env           map[string]string
 		expectError   bool
 		expectedValue string
 	}{
@@ -456,6 +577,22 @@ func TestGetCurrentNamespace(t *testing.T) {
 			fileContent: "",
 			expectError: true,
 		},
+		{
+			name:          "namespace file takes precedence over the environment",
+			fileContent:   "test-namespace\n",
+			env:           map[string]string{"POD_NAMESPACE": "pod-namespace"},
+			expectedValue: "test-namespace",
+		},
+		{
+			name:          "namespace file does not exist, falls back to POD_NAMESPACE",
+			env:           map[string]string{"POD_NAMESPACE": "pod-namespace", "NAMESPACE": "namespace"},
+			expectedValue: "pod-namespace",
+		},
+		{
+			name:          "namespace file does not exist, falls back to NAMESPACE",
+			env:           map[string]string{"NAMESPACE": "namespace"},
+			expectedValue: "namespace",
+		},
 	}
 
 	for _, tt := range tests {
@@ -465,6 +602,9 @@ func TestGetCurrentNamespace(t *testing.T) {
 			// Create a temporary directory to simulate the service account file path
 			tempDir := t.TempDir()
 			k8sSAFilePath = tempDir
+			for _, env := range namespaceEnvVars {
+				t.Setenv(env, tt.env[env])
+			}
 
 			// Create the namespace file if fileContent is provided
 			if tt.fileContent != "" {
@@ -639,3 +779,380 @@ func TestCRDExists(t *testing.T) {
 		})
 	}
 }
+
+func TestEnsureNamespace(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	labels := map[string]string{
+		HostedControlPlaneNamespaceLabel: "true",
+		PodSecurityEnforceLabel:          "privileged",
+	}
+
+	tests := []struct {
+		name           string
+		objects        []client.Object
+		expectedLabels map[string]string
+		expectWarning  string
+	}{
+		{
+			name:           "When the namespace does not exist, It Should create it with the labels",
+			objects:        []client.Object{},
+			expectedLabels: labels,
+		},
+		{
+			name: "When the namespace exists without labels, It Should add the labels",
+			objects: []client.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "clusters-test"}},
+			},
+			expectedLabels: labels,
+		},
+		{
+			name: "When the namespace exists with other labels, It Should keep them and add the missing ones",
+			objects: []client.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:   "clusters-test",
+					Labels: map[string]string{"foo": "bar", PodSecurityEnforceLabel: "privileged"},
+				}},
+			},
+			expectedLabels: map[string]string{
+				"foo":                            "bar",
+				HostedControlPlaneNamespaceLabel: "true",
+				PodSecurityEnforceLabel:          "privileged",
+			},
+		},
+		{
+			name: "When the namespace exists with a label of a different value, It Should override it and warn",
+			objects: []client.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:   "clusters-test",
+					Labels: map[string]string{PodSecurityEnforceLabel: "restricted"},
+				}},
+			},
+			expectedLabels: labels,
+			expectWarning:  "Overriding label " + PodSecurityEnforceLabel + "=restricted of namespace clusters-test with privileged",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.objects...).Build()
+			out := &bytes.Buffer{}
+			log := logrus.New()
+			log.SetOutput(out)
+
+			err := EnsureNamespace(context.TODO(), c, log, "clusters-test", labels)
+			g.Expect(err).NotTo(HaveOccurred())
+			if tt.expectWarning != "" {
+				g.Expect(out.String()).To(ContainSubstring(tt.expectWarning))
+			} else {
+				g.Expect(out.String()).NotTo(ContainSubstring("Overriding"))
+			}
+
+			ns := &corev1.Namespace{}
+			g.Expect(c.Get(context.TODO(), client.ObjectKey{Name: "clusters-test"}, ns)).To(Succeed())
+			g.Expect(ns.Labels).To(Equal(tt.expectedLabels))
+		})
+	}
+}
+
+func TestUnpauseRestoredCluster(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = hyperv1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+
+	paused := "true"
+	pending := map[string]string{RestorePendingAnnotation: "true"}
+
+	t.Run("When the HostedCluster is pending, It Should resume it with its NodePools and HostedControlPlane", func(t *testing.T) {
+		g := NewWithT(t)
+		hc := &hyperv1.HostedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-hc", Namespace: "clusters", Annotations: pending},
+			Spec:       hyperv1.HostedClusterSpec{PausedUntil: &paused},
+		}
+		hcp := &hyperv1.HostedControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-hc", Namespace: "clusters-my-hc", Annotations: pending},
+			Spec:       hyperv1.HostedControlPlaneSpec{PausedUntil: &paused},
+		}
+		np := &hyperv1.NodePool{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-np", Namespace: "clusters", Annotations: pending},
+			Spec:       hyperv1.NodePoolSpec{ClusterName: "my-hc", PausedUntil: &paused},
+		}
+		otherNP := &hyperv1.NodePool{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-np", Namespace: "clusters", Annotations: pending},
+			Spec:       hyperv1.NodePoolSpec{ClusterName: "other-hc", PausedUntil: &paused},
+		}
+		capiCluster := newCAPIObject("Cluster", "my-hc", "clusters-my-hc", map[string]string{CAPIPausedAnnotation: "true"})
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			hc, hcp, np, otherNP, capiCluster,
+			newCAPIDeployment(CAPIManagerDeploymentName, "clusters-my-hc", true),
+			newCAPIDeployment(CAPIProviderDeploymentName, "clusters-my-hc", true),
+		).Build()
+
+		g.Expect(UnpauseRestoredCluster(context.TODO(), c, "clusters", "my-hc", Timeouts{CAPIProviders: time.Second}, DefaultPausedKinds)).To(Succeed())
+
+		g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(capiCluster), capiCluster)).To(Succeed())
+		g.Expect(capiCluster.GetAnnotations()).NotTo(HaveKey(CAPIPausedAnnotation))
+
+		g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(hc), hc)).To(Succeed())
+		g.Expect(hc.Spec.PausedUntil).To(BeNil())
+		g.Expect(hc.Annotations).NotTo(HaveKey(RestorePendingAnnotation))
+
+		g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(hcp), hcp)).To(Succeed())
+		g.Expect(hcp.Spec.PausedUntil).To(BeNil())
+		g.Expect(hcp.Annotations).NotTo(HaveKey(RestorePendingAnnotation))
+
+		g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(np), np)).To(Succeed())
+		g.Expect(np.Spec.PausedUntil).To(BeNil())
+		g.Expect(np.Annotations).NotTo(HaveKey(RestorePendingAnnotation))
+
+		g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(otherNP), otherNP)).To(Succeed())
+		g.Expect(otherNP.Spec.PausedUntil).NotTo(BeNil())
+		g.Expect(otherNP.Annotations).To(HaveKey(RestorePendingAnnotation))
+	})
+
+	t.Run("When the HostedCluster is not pending, It Should return an error and leave it paused", func(t *testing.T) {
+		g := NewWithT(t)
+		hc := &hyperv1.HostedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-hc", Namespace: "clusters"},
+			Spec:       hyperv1.HostedClusterSpec{PausedUntil: &paused},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hc).Build()
+
+		err := UnpauseRestoredCluster(context.TODO(), c, "clusters", "my-hc", Timeouts{CAPIProviders: time.Second}, DefaultPausedKinds)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring("not pending"))
+
+		g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(hc), hc)).To(Succeed())
+		g.Expect(hc.Spec.PausedUntil).NotTo(BeNil())
+	})
+
+	t.Run("When the HostedCluster does not exist, It Should return an error", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		g.Expect(UnpauseRestoredCluster(context.TODO(), c, "clusters", "my-hc", Timeouts{CAPIProviders: time.Second}, DefaultPausedKinds)).NotTo(Succeed())
+	})
+
+	t.Run("When the cluster-api deployments are not available, It Should leave the cluster paused", func(t *testing.T) {
+		g := NewWithT(t)
+		hc := &hyperv1.HostedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-hc", Namespace: "clusters", Annotations: pending},
+			Spec:       hyperv1.HostedClusterSpec{PausedUntil: &paused},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			hc, newCAPIDeployment(CAPIManagerDeploymentName, "clusters-my-hc", false),
+		).Build()
+
+		err := UnpauseRestoredCluster(context.TODO(), c, "clusters", "my-hc", Timeouts{Clock: clocktest.New(t, DefaultTimeouts.CAPIProvidersPoll)}, DefaultPausedKinds)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring("not available"))
+
+		g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(hc), hc)).To(Succeed())
+		g.Expect(hc.Spec.PausedUntil).NotTo(BeNil())
+		g.Expect(hc.Annotations).To(HaveKey(RestorePendingAnnotation))
+	})
+}
+
+func TestRequestReconcile(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	hc := &hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{
+		Name: "hc", Namespace: "clusters",
+		Annotations: map[string]string{ReconcileRequestedAnnotation: "2026-02-01T00:00:00Z"},
+	}}
+	c := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(hc).Build()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 500, time.UTC)
+	g.Expect(RequestReconcile(ctx, c, "clusters", "hc", now)).To(Succeed())
+	g.Expect(c.Get(ctx, crclient.ObjectKeyFromObject(hc), hc)).To(Succeed())
+	g.Expect(hc.Annotations).To(HaveKeyWithValue(ReconcileRequestedAnnotation, "2026-03-01T12:00:00.0000005Z"))
+
+	g.Expect(RequestReconcile(ctx, c, "clusters", "missing", now)).NotTo(Succeed())
+}
+
+func TestIsPartialRestore(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     veleroapiv1.RestoreSpec
+		expected bool
+	}{
+		{
+			name:     "When the restore has no resource filters, It Should not be partial",
+			spec:     veleroapiv1.RestoreSpec{},
+			expected: false,
+		},
+		{
+			name:     "When the restore includes all resources, It Should not be partial",
+			spec:     veleroapiv1.RestoreSpec{IncludedResources: []string{"*"}},
+			expected: false,
+		},
+		{
+			name:     "When the restore includes HostedClusters, It Should not be partial",
+			spec:     veleroapiv1.RestoreSpec{IncludedResources: []string{"hostedclusters.hypershift.openshift.io", "nodepools"}},
+			expected: false,
+		},
+		{
+			name:     "When the restore only includes NodePools, It Should be partial",
+			spec:     veleroapiv1.RestoreSpec{IncludedResources: []string{"nodepools", "machinedeployments"}},
+			expected: true,
+		},
+		{
+			name:     "When the restore excludes HostedClusters, It Should be partial",
+			spec:     veleroapiv1.RestoreSpec{ExcludedResources: []string{"hostedclusters"}},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			restore := &veleroapiv1.Restore{Spec: tt.spec}
+			g.Expect(IsPartialRestore(restore)).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestBackupIncludesResource(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     veleroapiv1.BackupSpec
+		expected bool
+	}{
+		{
+			name:     "When the backup has no resource filters, It Should include the resource",
+			spec:     veleroapiv1.BackupSpec{},
+			expected: true,
+		},
+		{
+			name:     "When the backup includes all resources, It Should include the resource",
+			spec:     veleroapiv1.BackupSpec{IncludedResources: []string{"*"}},
+			expected: true,
+		},
+		{
+			name:     "When the backup includes the resource by its short name, It Should include it",
+			spec:     veleroapiv1.BackupSpec{IncludedResources: []string{"hostedclusters", "pvc"}},
+			expected: true,
+		},
+		{
+			name:     "When the backup only includes other resources, It Should not include it",
+			spec:     veleroapiv1.BackupSpec{IncludedResources: []string{"hostedclusters", "secrets"}},
+			expected: false,
+		},
+		{
+			name:     "When the backup excludes the resource, It Should not include it",
+			spec:     veleroapiv1.BackupSpec{IncludedResources: []string{"*"}, ExcludedResources: []string{"persistentvolumeclaims"}},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			backup := &veleroapiv1.Backup{Spec: tt.spec}
+			g.Expect(BackupIncludesResource(backup, "persistentvolumeclaims", "persistentvolumeclaim", "pvc")).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestParsePlatforms(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		expected   []hyperv1.PlatformType
+		wantErr    bool
+	}{
+		{
+			name:       "When platforms are configured, It Should return them regardless of case and spacing",
+			configured: "aws, Agent,AWS",
+			expected:   []hyperv1.PlatformType{hyperv1.AWSPlatform, hyperv1.AgentPlatform},
+		},
+		{
+			name:       "When an unknown platform is configured, It Should return an error",
+			configured: "aws,baremetal",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			platforms, err := ParsePlatforms(tt.configured)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(platforms).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestGetConfigKubeconfig(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	err := os.WriteFile(kubeconfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com:6443
+- name: prod
+  cluster:
+    server: https://prod.example.com:6443
+users:
+- name: admin
+  user:
+    token: abc
+contexts:
+- name: dev
+  context: {cluster: dev, user: admin}
+- name: prod
+  context: {cluster: prod, user: admin}
+current-context: dev
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		context  string
+		env      map[string]string
+		wantHost string
+	}{
+		{
+			name:     "When SetKubeconfig sets a path, It Should use its current context",
+			path:     kubeconfig,
+			wantHost: "https://dev.example.com:6443",
+		},
+		{
+			name:     "When SetKubeconfig sets a path and a context, It Should use the context",
+			path:     kubeconfig,
+			context:  "prod",
+			wantHost: "https://prod.example.com:6443",
+		},
+		{
+			name:     "When KUBECONFIG and the context variable are set, It Should use them",
+			env:      map[string]string{"KUBECONFIG": kubeconfig, KubeContextEnv: "prod"},
+			wantHost: "https://prod.example.com:6443",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			for _, env := range []string{"KUBECONFIG", KubeContextEnv} {
+				t.Setenv(env, tt.env[env])
+			}
+			SetKubeconfig(tt.path, tt.context)
+			defer SetKubeconfig("", "")
+
+			cfg, err := GetConfig()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(cfg.Host).To(Equal(tt.wantHost))
+			g.Expect(cfg.QPS).To(BeEquivalentTo(200))
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/clock"
+)
+
+// Poll checks the condition every interval, measured by the clock, until it is met, fails,
+// or the timeout expires. A zero timeout polls until the context is done. The first check
+// runs immediately when immediate is set. Like wait.PollUntilContextTimeout, the error of an
+// expired timeout or a done context satisfies wait.Interrupted, so WrapWaitError turns it
+// into a TimeoutError. With the real clock, the timeout also bounds the API calls of the
+// condition through its context; a fake clock leaves that to the caller.
+func Poll(ctx context.Context, clk clock.WithTicker, interval, timeout time.Duration, immediate bool, condition wait.ConditionWithContextFunc) error {
+	if clk == nil {
+		clk = clock.RealClock{}
+	}
+	var expired <-chan time.Time
+	if timeout > 0 {
+		if _, real := clk.(clock.RealClock); real {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		timer := clk.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C()
+	}
+	ticker := clk.NewTicker(interval)
+	defer ticker.Stop()
+
+	if immediate {
+		if done, err := condition(ctx); err != nil || done {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-expired:
+			return wait.ErrorInterrupted(context.DeadlineExceeded)
+		case <-ticker.C():
+		}
+		if done, err := condition(ctx); err != nil || done {
+			return err
+		}
+	}
+}
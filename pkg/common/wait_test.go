@@ -0,0 +1,64 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/common/clocktest"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestPoll(t *testing.T) {
+	t.Run("When the condition is met at once, It Should return without waiting", func(t *testing.T) {
+		g := NewWithT(t)
+		calls := 0
+		err := Poll(context.TODO(), nil, time.Hour, time.Hour, true, func(context.Context) (bool, error) {
+			calls++
+			return true, nil
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(calls).To(Equal(1))
+	})
+
+	t.Run("When the condition is met after some polls, It Should return once it is", func(t *testing.T) {
+		g := NewWithT(t)
+		calls := 0
+		err := Poll(context.TODO(), clocktest.New(t, time.Minute), time.Minute, 0, false, func(context.Context) (bool, error) {
+			calls++
+			return calls == 3, nil
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(calls).To(Equal(3))
+	})
+
+	t.Run("When the condition is never met, It Should time out on the clock", func(t *testing.T) {
+		g := NewWithT(t)
+		err := Poll(context.TODO(), clocktest.New(t, 10*time.Second), 10*time.Second, time.Hour, true, func(context.Context) (bool, error) {
+			return false, nil
+		})
+		g.Expect(wait.Interrupted(err)).To(BeTrue())
+		var timeoutErr *TimeoutError
+		g.Expect(errors.As(WrapWaitError(err, "the condition", time.Hour), &timeoutErr)).To(BeTrue())
+	})
+
+	t.Run("When the condition fails, It Should return its error", func(t *testing.T) {
+		g := NewWithT(t)
+		err := Poll(context.TODO(), clocktest.New(t, time.Second), time.Second, time.Hour, true, func(context.Context) (bool, error) {
+			return false, errors.New("boom")
+		})
+		g.Expect(err).To(MatchError("boom"))
+	})
+
+	t.Run("When the context is canceled, It Should stop polling", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx, cancel := context.WithCancel(context.TODO())
+		err := Poll(ctx, nil, time.Hour, 0, true, func(context.Context) (bool, error) {
+			cancel()
+			return false, nil
+		})
+		g.Expect(wait.Interrupted(err)).To(BeTrue())
+	})
+}
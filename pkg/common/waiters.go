@@ -0,0 +1,88 @@
+package common
+
+import (
+	"context"
+	"fmt"
+
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Pauser pauses the reconciliation of a HostedCluster and its NodePools for a backup and
+// resumes it, waiting for HyperShift to propagate either. ClientPauser is the implementation
+// acting on the API server; tests and alternative pause mechanisms provide their own.
+type Pauser interface {
+	Pause(ctx context.Context, namespace, name, backupName string) (pausedElsewhere []string, err error)
+	Unpause(ctx context.Context, namespace, name string) (pausedElsewhere []string, err error)
+	WaitForPaused(ctx context.Context, namespace, name string, timeouts Timeouts) error
+	WaitForUnpaused(ctx context.Context, namespace, name string, timeouts Timeouts) error
+}
+
+// SnapshotWaiter takes the etcd snapshot of a control plane for a backup and waits for it.
+// etcdbackup.Orchestrator implements it with an HCPEtcdBackup.
+type SnapshotWaiter interface {
+	// IsCreated reports whether a snapshot was requested, by CreateEtcdBackup or Resume.
+	IsCreated() bool
+	// Resume picks up the snapshot an earlier process requested for the backup, if any.
+	Resume(ctx context.Context, backup *velerov1.Backup, hcpNamespace string) (bool, error)
+	CreateEtcdBackup(ctx context.Context, backup *velerov1.Backup, hcpNamespace string, hc *hyperv1.HostedCluster) error
+	VerifyInProgress(ctx context.Context) error
+	// WaitForCompletion returns the URL of the snapshot once it is taken.
+	WaitForCompletion(ctx context.Context) (string, error)
+	CleanupCredentialSecret(ctx context.Context) error
+}
+
+// BackupWaiter finds the backups of a hosted cluster running ahead of a backup and waits for
+// them to finish. ClientBackupWaiter is the implementation acting on the API server.
+type BackupWaiter interface {
+	EarlierBackups(ctx context.Context, backup *velerov1.Backup, hcpNamespace string) ([]string, error)
+	WaitForEarlierBackups(ctx context.Context, backup *velerov1.Backup, hcpNamespace string, timeouts Timeouts) error
+}
+
+// ClientPauser is the Pauser of PauseHostedCluster, UnpauseHostedCluster,
+// WaitForPausedPropagated and WaitForUnpausedPropagated.
+type ClientPauser struct {
+	Client crclient.Client
+}
+
+func (p ClientPauser) Pause(ctx context.Context, namespace, name, backupName string) ([]string, error) {
+	return PauseHostedCluster(ctx, p.Client, namespace, name, backupName)
+}
+
+func (p ClientPauser) Unpause(ctx context.Context, namespace, name string) ([]string, error) {
+	return UnpauseHostedCluster(ctx, p.Client, namespace, name)
+}
+
+func (p ClientPauser) WaitForPaused(ctx context.Context, namespace, name string, timeouts Timeouts) error {
+	return WaitForPausedPropagated(ctx, p.Client, namespace, name, timeouts)
+}
+
+func (p ClientPauser) WaitForUnpaused(ctx context.Context, namespace, name string, timeouts Timeouts) error {
+	return WaitForUnpausedPropagated(ctx, p.Client, namespace, name, timeouts)
+}
+
+// ClientBackupWaiter is the BackupWaiter of EarlierBackupsOf.
+type ClientBackupWaiter struct {
+	Client crclient.Client
+}
+
+func (w ClientBackupWaiter) EarlierBackups(ctx context.Context, backup *velerov1.Backup, hcpNamespace string) ([]string, error) {
+	return EarlierBackupsOf(ctx, w.Client, backup, hcpNamespace)
+}
+
+// WaitForEarlierBackups polls the earlier backups every earlierBackupsPoll until none is
+// left, for as long as the context lasts.
+func (w ClientBackupWaiter) WaitForEarlierBackups(ctx context.Context, backup *velerov1.Backup, hcpNamespace string, timeouts Timeouts) error {
+	timeouts = timeouts.WithDefaults()
+	var earlier []string
+	err := Poll(ctx, timeouts.Clock, timeouts.EarlierBackupsPoll, 0, false, func(ctx context.Context) (bool, error) {
+		var err error
+		earlier, err = EarlierBackupsOf(ctx, w.Client, backup, hcpNamespace)
+		return len(earlier) == 0, err
+	})
+	if err != nil {
+		return fmt.Errorf("error waiting for the running backups %v of the hosted cluster: %w", earlier, err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/common/clocktest"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestClientBackupWaiter(t *testing.T) {
+	start := metav1.NewTime(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC))
+	earlierStart := metav1.NewTime(start.Add(-time.Minute))
+	backup := &velerov1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "later", Namespace: "openshift-adp"},
+		Status:     velerov1.BackupStatus{Phase: velerov1.BackupPhaseInProgress, StartTimestamp: &start},
+	}
+	earlier := &velerov1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "earlier", Namespace: "openshift-adp"},
+		Spec:       velerov1.BackupSpec{IncludedNamespaces: []string{"clusters-hc"}},
+		Status:     velerov1.BackupStatus{Phase: velerov1.BackupPhaseInProgress, StartTimestamp: &earlierStart},
+	}
+	timeouts := Timeouts{Clock: clocktest.New(t, time.Second), EarlierBackupsPoll: time.Second}
+
+	t.Run("When the earlier backup finishes, It Should stop waiting", func(t *testing.T) {
+		g := NewWithT(t)
+		lists := 0
+		client := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(earlier.DeepCopy()).WithInterceptorFuncs(interceptor.Funcs{
+			List: func(ctx context.Context, c crclient.WithWatch, list crclient.ObjectList, opts ...crclient.ListOption) error {
+				if lists++; lists == 3 {
+					finished := &velerov1.Backup{}
+					if err := c.Get(ctx, crclient.ObjectKeyFromObject(earlier), finished); err != nil {
+						return err
+					}
+					finished.Status.Phase = velerov1.BackupPhaseCompleted
+					if err := c.Update(ctx, finished); err != nil {
+						return err
+					}
+				}
+				return c.List(ctx, list, opts...)
+			},
+		}).Build()
+		waiter := ClientBackupWaiter{Client: client}
+
+		running, err := waiter.EarlierBackups(context.TODO(), backup, "clusters-hc")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(running).To(ConsistOf("openshift-adp/earlier"))
+		g.Expect(waiter.WaitForEarlierBackups(context.TODO(), backup, "clusters-hc", timeouts)).To(Succeed())
+	})
+
+	t.Run("When the context ends first, It Should name the running backups", func(t *testing.T) {
+		g := NewWithT(t)
+		client := fake.NewClientBuilder().WithScheme(CustomScheme).WithObjects(earlier.DeepCopy()).Build()
+		ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+		defer cancel()
+
+		err := ClientBackupWaiter{Client: client}.WaitForEarlierBackups(ctx, backup, "clusters-hc", timeouts)
+		g.Expect(err).To(MatchError(ContainSubstring("running backups [openshift-adp/earlier]")))
+	})
+}
@@ -1,23 +1,40 @@
 package core
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/openshift/hypershift-oadp-plugin/pkg/audit"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/backupsummary"
 	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
 	plugtypes "github.com/openshift/hypershift-oadp-plugin/pkg/core/types"
 	validation "github.com/openshift/hypershift-oadp-plugin/pkg/core/validation"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/diagnostics"
 	"github.com/openshift/hypershift-oadp-plugin/pkg/etcdbackup"
-	"github.com/openshift/hypershift-oadp-plugin/pkg/platform/agent"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/guestsnapshot"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/hooks"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/notify"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/tracing"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/version"
 	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
 	"github.com/sirupsen/logrus"
 	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
 	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	"go.opentelemetry.io/otel/attribute"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -34,11 +51,63 @@ type BackupPlugin struct {
 	*plugtypes.BackupOptions
 
 	// Etcd backup orchestration
-	etcdOrchestrator  *etcdbackup.Orchestrator
+	etcdOrchestrator  common.SnapshotWaiter
 	hoNamespace       string
 	etcdBackupMethod  string
 	etcdSnapshotURL   string // populated after HCPEtcdBackup completes
 	hasDPA            bool   // true when OADP+DPA is detected, false for standalone Velero
+
+	// nodePoolMatches caches the nodePoolSelector result per NodePool (namespace/name), a
+	// bool; a sync.Map since Velero may run Execute for several items at once
+	nodePoolMatches sync.Map
+
+	// hooks runs the user supplied hooks, nil when none is configured
+	hooks *hooks.Runner
+
+	// notifyWatcher starts the completion notification Job, nil when notifications are
+	// disabled, and notifyStartedBackup is the backup it was last started for
+	notifyWatcher       *notify.Watcher
+	notifyStartedBackup string
+
+	// disabledCheckedBackup is the backup the HostedCluster was last checked for
+	// PluginAnnotation for, and pluginDisabled is set when it opted out of the plugin
+	disabledCheckedBackup string
+	pluginDisabled        bool
+
+	// clusterCheckedBackup is the backup the HostedCluster state was last validated for, and
+	// skipCluster is set when the items of the hosted cluster are left out of it
+	clusterCheckedBackup string
+	skipCluster          bool
+
+	// autoFSBackup is set when volumeBackupModePolicy Auto selected fs-backup for the control
+	// plane volumes of a Backup leaving defaultVolumesToFsBackup unset
+	autoFSBackup bool
+
+	// pauseDeadline is when the pause of the hosted cluster for pauseDeadlineBackup exceeds
+	// maxPauseDuration, zero when the backup command did not pause it
+	pauseDeadlineBackup string
+	pauseDeadline       time.Time
+
+	// schemaStampedBackup is the backup last stamped with the backup schema
+	schemaStampedBackup string
+
+	// beforePauseBackup is the backup the beforePause hooks were last handled for
+	beforePauseBackup string
+
+	// diagnosticsSavedBackup is the failed backup whose diagnostics bundle was last stored
+	diagnosticsSavedBackup string
+
+	// auditTrail records the items of the HCP backup, created with its first item
+	auditTrail *audit.Trail
+
+	// newGuestClient returns a client of the hosted cluster, replaced in tests
+	newGuestClient func(ctx context.Context, c crclient.Client, hcpNamespace string) (crclient.Client, error)
+
+	// pauser, backupWaiter and newSnapshotWaiter run the pauses and waits of the plugin
+	// against the API server, replaced in tests to drive Execute through their outcomes
+	pauser            common.Pauser
+	backupWaiter      common.BackupWaiter
+	newSnapshotWaiter func(oadpNamespace string) common.SnapshotWaiter
 }
 
 // NewBackupPlugin instantiates BackupPlugin.
@@ -89,6 +158,10 @@ func NewBackupPlugin(logger logrus.FieldLogger) (*BackupPlugin, error) {
 		logger.Info("Standalone Velero detected, will use fallback credentials when BSL has no credential reference")
 	}
 
+	if err := tracing.Setup(ctx, pluginConfig.Data[common.ConfigKeyTracingEndpoint]); err != nil {
+		logger.Warnf("Could not configure tracing, spans will not be exported: %v", err)
+	}
+
 	hoNamespace := common.DefaultHONamespace
 	if v, ok := pluginConfig.Data[common.ConfigKeyHONamespace]; ok && v != "" {
 		hoNamespace = v
@@ -102,6 +175,20 @@ func NewBackupPlugin(logger logrus.FieldLogger) (*BackupPlugin, error) {
 		return nil, fmt.Errorf("invalid etcdBackupMethod %q: must be %q or %q", etcdBackupMethod, common.EtcdBackupMethodVolume, common.EtcdBackupMethodEtcdSnapshot)
 	}
 
+	hookRunner, err := hooks.NewRunner(pluginConfig.Data, client, ns, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring hooks: %s", err.Error())
+	}
+
+	notifyWatcher, err := notify.NewWatcher(pluginConfig.Data, client, ns)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring notifications: %s", err.Error())
+	}
+
+	if err := checkPermissions(ctx, client, common.BackupPermissions(ns, etcdBackupMethod, hookRunner != nil || notifyWatcher != nil), logger); err != nil {
+		return nil, err
+	}
+
 	bp := &BackupPlugin{
 		log:              logger,
 		client:           client,
@@ -111,11 +198,22 @@ func NewBackupPlugin(logger logrus.FieldLogger) (*BackupPlugin, error) {
 		hoNamespace:      hoNamespace,
 		etcdBackupMethod: etcdBackupMethod,
 		hasDPA:           hasDPA,
+		hooks:            hookRunner,
+		notifyWatcher:    notifyWatcher,
+		newGuestClient:   guestsnapshot.NewGuestClient,
+		pauser:           common.ClientPauser{Client: client},
+		backupWaiter:     common.ClientBackupWaiter{Client: client},
 	}
+	bp.newSnapshotWaiter = bp.newEtcdOrchestrator
 
 	if bp.BackupOptions, err = bp.validator.ValidatePluginConfig(bp.config); err != nil {
 		return nil, fmt.Errorf("error validating plugin configuration: %s", err.Error())
 	}
+	if bp.VeleroNamespace == "" {
+		// Velero creates its objects in the namespace it runs in, the plugin's
+		bp.VeleroNamespace = ns
+	}
+	validator.VeleroNamespace = bp.VeleroNamespace
 
 	bp.log.Infof("Backup plugin initialized with log level: %s", logrus.GetLevel())
 
@@ -134,9 +232,30 @@ func (p *BackupPlugin) AppliesTo() (velero.ResourceSelector, error) {
 }
 
 // Execute allows the ItemAction to perform arbitrary logic with the item being backed up,
+// within the executeTimeout option when set.
 func (p *BackupPlugin) Execute(item runtime.Unstructured, backup *velerov1.Backup) (runtime.Unstructured, []velero.ResourceIdentifier, error) {
+	var timeout time.Duration
+	if p.BackupOptions != nil {
+		timeout = p.ExecuteTimeout
+	}
+	ctx, cancel := executeContext(p.ctx, timeout)
+	defer cancel()
+
+	name := itemName(item)
+	ctx, span := tracing.Start(tracing.WithBackupTrace(ctx, backup.UID), "BackupPlugin.Execute",
+		attribute.String("velero.backup", backup.Name), attribute.String("item", name))
+	gk, objName := item.GetObjectKind().GroupVersionKind().GroupKind(), objectName(item)
+	start := time.Now()
+	result, additionalItems, err := p.execute(ctx, item, backup)
+	err = deadlineError(ctx, err, timeout, name)
+	p.recordAudit(backup, gk, objName, start, result, err)
+	tracing.End(span, err)
+	tracing.Flush(p.ctx)
+	return result, additionalItems, err
+}
+
+func (p *BackupPlugin) execute(ctx context.Context, item runtime.Unstructured, backup *velerov1.Backup) (runtime.Unstructured, []velero.ResourceIdentifier, error) {
 	p.log.Debug("Entering Hypershift backup plugin")
-	ctx := context.Context(p.ctx)
 
 	if returnEarly, err := common.ShouldEndPluginExecution(ctx, backup, p.client, p.log); returnEarly {
 		p.log.Infof("Skipping hypershift plugin execution - not a hypershift backup: %v", err)
@@ -151,127 +270,389 @@ func (p *BackupPlugin) Execute(item runtime.Unstructured, backup *velerov1.Backu
 				p.log.Infof("HCP not found, assuming not hypershift cluster to backup")
 				return item, nil, nil
 			}
-			return nil, nil, fmt.Errorf("error getting HCP namespace: %v", err)
+			return nil, nil, fmt.Errorf("error getting HCP namespace: %w", err)
 		}
 	}
 
-	kind := item.GetObjectKind().GroupVersionKind().Kind
-	switch {
-	case kind == common.HostedControlPlaneKind:
-		hcp := &hyperv1.HostedControlPlane{}
-		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.UnstructuredContent(), hcp); err != nil {
-			return nil, nil, fmt.Errorf("error converting item to HostedControlPlane: %v", err)
-		}
+	if disabled, err := p.checkPluginDisabled(ctx, backup); err != nil {
+		return nil, nil, err
+	} else if disabled {
+		return item, nil, nil
+	}
 
-		if err := p.validator.ValidatePlatformConfig(hcp, backup); err != nil {
-			return nil, nil, fmt.Errorf("error checking platform configuration: %v", err)
-		}
+	if skip, err := p.checkHostedClusterState(ctx, backup); err != nil {
+		return nil, nil, err
+	} else if skip {
+		return nil, nil, nil
+	}
 
-		// Etcd backup: create after validation, wait for completion
-		if p.etcdBackupMethod == common.EtcdBackupMethodEtcdSnapshot {
-			if err := p.createEtcdBackup(ctx, backup); err != nil {
-				return nil, nil, fmt.Errorf("error creating HCPEtcdBackup: %v", err)
-			}
-		}
-		if err := p.waitForEtcdBackupCompletion(ctx); err != nil {
+	if err := p.enforcePauseWindow(ctx, backup); err != nil {
+		return nil, nil, err
+	}
+
+	p.stampBackupSchema(ctx, backup)
+
+	if err := p.runBeforePauseHooks(ctx, backup); err != nil {
+		return nil, nil, err
+	}
+
+	p.startNotificationWatcher(ctx, backup.Name)
+
+	gk := item.GetObjectKind().GroupVersionKind().GroupKind()
+	kind := gk.Kind
+
+	if p.NodePoolSelector != nil {
+		excluded, err := p.isExcludedByNodePoolSelector(ctx, gk, item)
+		if err != nil {
 			return nil, nil, err
 		}
-		if p.etcdSnapshotURL != "" {
-			metadata, err := meta.Accessor(item)
-			if err != nil {
-				return nil, nil, fmt.Errorf("error getting metadata accessor: %v", err)
-			}
-			common.AddAnnotation(metadata, common.EtcdSnapshotURLAnnotation, p.etcdSnapshotURL)
-			p.log.Infof("Added etcd snapshot URL annotation to HostedControlPlane %s", metadata.GetName())
+		if excluded {
+			p.log.Debugf("Skipping %s not selected by %s", kind, common.ConfigKeyNodePoolSelector)
+			return nil, nil, nil
 		}
+	}
 
-	case kind == common.HostedClusterKind:
-		metadata, err := meta.Accessor(item)
-		if err != nil {
-			return nil, nil, fmt.Errorf("error getting metadata accessor: %v", err)
-		}
-		common.AddAnnotation(metadata, common.HostedClusterRestoredFromBackupAnnotation, "")
-		p.log.Infof("Added restore annotation to HostedCluster %s", metadata.GetName())
-
-		// Etcd backup: create if not yet created (HC may arrive before HCP),
-		// wait for completion, and inject snapshotURL into the HC item.
-		// Velero captures the item as-is from the API server before the HCPEtcdBackup
-		// controller updates the HC status with lastSuccessfulEtcdBackupURL.
-		// We must inject it here so the backed-up HC contains the URL for restore.
-		if p.etcdBackupMethod == common.EtcdBackupMethodEtcdSnapshot {
-			if err := p.createEtcdBackup(ctx, backup); err != nil {
-				return nil, nil, fmt.Errorf("error creating HCPEtcdBackup: %v", err)
+	var additionalItems []velero.ResourceIdentifier
+	if handler, ok := handlerFor(item); ok {
+		input := item
+		if err := retryTransient(func() (err error) {
+			item, err = handler.Backup(ctx, p, input, backup)
+			if err != nil || item == nil {
+				return err
 			}
-		}
-		if err := p.waitForEtcdBackupCompletion(ctx); err != nil {
+			if handler, ok := handler.(additionalItemsHandler); ok {
+				additionalItems, err = handler.AdditionalItems(ctx, p, item, backup)
+			}
+			return err
+		}); err != nil {
+			p.log.WithField("errorClass", common.ErrorClass(err)).Errorf("Error backing up %s: %v", kind, err)
+			// The diagnostics are collected even when the Execute deadline expired
+			p.saveDiagnostics(context.WithoutCancel(ctx), backup, err)
 			return nil, nil, err
 		}
-		if p.etcdSnapshotURL != "" {
-			// Persist as annotation so the restore plugin can read it
-			// (Velero strips status from items during restore)
-			common.AddAnnotation(metadata, common.EtcdSnapshotURLAnnotation, p.etcdSnapshotURL)
-			p.log.Infof("Added etcd snapshot URL annotation to HostedCluster %s: %s", metadata.GetName(), p.etcdSnapshotURL)
-
-			unstructuredContent := item.UnstructuredContent()
-			status, ok := unstructuredContent["status"].(map[string]interface{})
-			if !ok {
-				status = map[string]interface{}{}
-				unstructuredContent["status"] = status
-			}
-			status["lastSuccessfulEtcdBackupURL"] = p.etcdSnapshotURL
-			item.SetUnstructuredContent(unstructuredContent)
-			p.log.Infof("Injected lastSuccessfulEtcdBackupURL into HostedCluster %s: %s", metadata.GetName(), p.etcdSnapshotURL)
+		if item == nil {
+			return nil, nil, nil
 		}
+	}
 
-	case kind == "Pod":
-		metadata, err := meta.Accessor(item)
-		if err != nil {
-			return nil, nil, fmt.Errorf("error getting metadata accessor: %v", err)
+	// Objects paused by the backup command are stored as they were before the backup
+	paused := &unstructured.Unstructured{Object: item.UnstructuredContent()}
+	common.RevertBackupPause(paused)
+	item.SetUnstructuredContent(paused.Object)
+
+	// Label every backed up item with its HostedCluster, so the backup contents can be
+	// filtered per hosted cluster. The HostedControlPlane is named after its HostedCluster.
+	metadata, err := meta.Accessor(item)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	common.AddLabel(metadata, common.HostedClusterLabel, p.hcp.Name)
+	common.AddAnnotation(metadata, common.BackupSchemaAnnotation, strconv.Itoa(common.BackupSchemaVersion))
+	common.AddAnnotation(metadata, common.BackupPluginVersionAnnotation, version.Version)
+
+	// HyperShift objects are stored without status and server populated metadata, for clean
+	// restores. The etcd snapshot URL injected into the HostedCluster status is kept, and the
+	// UID too, in an annotation, for the existingObjectPolicy of the restore.
+	if slices.Contains(strippedKinds, gk) {
+		if uid := metadata.GetUID(); uid != "" {
+			common.AddAnnotation(metadata, common.SourceUIDAnnotation, string(uid))
 		}
+		content := item.UnstructuredContent()
+		common.StripServerPopulatedFields(content, "lastSuccessfulEtcdBackupURL")
+		item.SetUnstructuredContent(content)
+	}
 
-		if strings.Contains(metadata.GetName(), "etcd-") {
-			switch p.etcdBackupMethod {
-			case common.EtcdBackupMethodEtcdSnapshot:
-				// Skip etcd pods entirely, snapshot is handled by HCPEtcdBackup.
-				// This prevents both FSBackup and CSI VolumeSnapshots of etcd volumes.
-				p.log.Infof("Skipping etcd pod %s from backup (using etcdSnapshot method)", metadata.GetName())
-				return nil, nil, nil
-			case common.EtcdBackupMethodVolume:
-				if backup.Spec.DefaultVolumesToFsBackup != nil && !*backup.Spec.DefaultVolumesToFsBackup {
-					common.AddLabel(metadata, common.FSBackupLabelName, "true")
-				}
-			}
+	return item, additionalItems, nil
+}
+
+// strippedKinds are stored without status and server populated metadata.
+var strippedKinds = []schema.GroupKind{common.HostedClusterGroupKind, common.HostedControlPlaneGroupKind, common.NodePoolGroupKind}
+
+// checkPluginDisabled reports, checking once per backup, whether the HostedCluster opted
+// out of the plugin through PluginAnnotation, in which case its items are backed up
+// unchanged: it is not paused, validated or labeled, and no etcd backup is taken.
+func (p *BackupPlugin) checkPluginDisabled(ctx context.Context, backup *velerov1.Backup) (bool, error) {
+	if p.disabledCheckedBackup == backup.Name {
+		return p.pluginDisabled, nil
+	}
+	hc, err := common.GetHostedCluster(ctx, p.client, backup.Spec.IncludedNamespaces, p.hcp.Namespace)
+	if err != nil {
+		return false, fmt.Errorf("error getting HostedCluster: %w", err)
+	}
+	p.disabledCheckedBackup = backup.Name
+	p.pluginDisabled = common.IsPluginDisabled(hc)
+	if p.pluginDisabled {
+		p.log.Infof("HostedCluster %s/%s sets %s=%s, backing up its items unchanged", hc.Namespace, hc.Name, common.PluginAnnotation, common.PluginDisabled)
+	}
+	return p.pluginDisabled, nil
+}
+
+// checkHostedClusterState refuses, once per backup, to back up a HostedCluster being deleted,
+// to a storage location that is not available, or one whose volumes cannot be backed up in
+// the mode of the Backup, or an unhealthy one when
+// healthGatePolicy is Fail. The first item fails with the reason,
+// unless deletingClusterPolicy is Skip for a deleted cluster, and every item of the hosted
+// cluster is left out of the backup.
+func (p *BackupPlugin) checkHostedClusterState(ctx context.Context, backup *velerov1.Backup) (bool, error) {
+	if p.clusterCheckedBackup == backup.Name {
+		return p.skipCluster, nil
+	}
+	p.skipCluster, p.autoFSBackup = false, false
+
+	if err := p.waitForEarlierBackups(ctx, backup); err != nil {
+		return false, err
+	}
+
+	hc, err := common.GetHostedCluster(ctx, p.client, backup.Spec.IncludedNamespaces, p.hcp.Namespace)
+	if err != nil {
+		return false, fmt.Errorf("error getting HostedCluster: %w", err)
+	}
+	p.clusterCheckedBackup = backup.Name
+
+	if err := p.validator.ValidateHostedClusterState(hc, p.hcp); err != nil {
+		p.skipCluster = true
+		if p.SkipDeletingCluster {
+			p.log.Warnf("Leaving the hosted cluster out of backup %s: %v", backup.Name, err)
+			return true, nil
 		}
+		return false, fmt.Errorf("refusing to back up the hosted cluster: %w", err)
+	}
 
-	// Agent requirements
-	case kind == common.ClusterDeploymentKind:
-		if p.hcp.Spec.Platform.Type == hyperv1.AgentPlatform {
-			if err := agent.MigrationTasks(ctx, item, p.client, p.log, p.config, backup); err != nil {
-				return nil, nil, fmt.Errorf("error performing migration tasks for agent platform: %v", err)
+	if err := p.validator.ValidateStorageLocations(ctx, backup); err != nil {
+		p.skipCluster = true
+		return false, fmt.Errorf("refusing to back up the hosted cluster: %w", err)
+	}
+
+	autoFSBackup, err := p.validator.ValidateVolumeBackupMode(ctx, p.hcp, backup, p.etcdBackupMethod, p.VolumeBackupModePolicy)
+	if err := common.TolerateError(p.log, p.TolerateErrors, common.TolerateVolumeBackupMode, err); err != nil {
+		p.skipCluster = true
+		return false, fmt.Errorf("refusing to back up the hosted cluster: %w", err)
+	}
+	p.autoFSBackup = autoFSBackup
+
+	if p.HealthGatePolicy == common.HealthGatePolicyIgnore {
+		return false, nil
+	}
+	problems := p.validator.ValidateHostedClusterHealth(hc, p.hcp)
+	if len(problems) == 0 {
+		return false, nil
+	}
+	if p.HealthGatePolicy == common.HealthGatePolicyFail {
+		p.skipCluster = true
+		return false, fmt.Errorf("refusing to back up the unhealthy hosted cluster: %s", strings.Join(problems, "; "))
+	}
+	for _, problem := range problems {
+		p.log.Warnf("Backup %s archives an unhealthy hosted cluster: %s", backup.Name, problem)
+	}
+	return false, nil
+}
+
+// waitForEarlierBackups keeps the backup from running alongside an earlier backup of the
+// same hosted cluster, which would pause it twice and share the etcd backup and wait state
+// of this plugin process. With concurrentBackupPolicy Wait, the backup waits for the earlier
+// ones to finish, within the executeTimeout option when set. With Fail, it is refused.
+func (p *BackupPlugin) waitForEarlierBackups(ctx context.Context, backup *velerov1.Backup) error {
+	if p.ConcurrentBackupPolicy == common.ConcurrentBackupPolicyIgnore {
+		return nil
+	}
+	earlier, err := p.backupWaiter.EarlierBackups(ctx, backup, p.hcp.Namespace)
+	if err != nil || len(earlier) == 0 {
+		return err
+	}
+	if p.ConcurrentBackupPolicy == common.ConcurrentBackupPolicyFail {
+		p.clusterCheckedBackup = backup.Name
+		p.skipCluster = true
+		return fmt.Errorf("refusing to back up the hosted cluster while backups %v of it are running", earlier)
+	}
+
+	p.log.Infof("Backup %s waits for the running backups %v of the hosted cluster", backup.Name, earlier)
+	if err := p.backupWaiter.WaitForEarlierBackups(ctx, backup, p.hcp.Namespace, p.Timeouts); err != nil {
+		return err
+	}
+	p.log.Infof("Earlier backups of the hosted cluster finished, backup %s proceeds", backup.Name)
+	return nil
+}
+
+// enforcePauseWindow resumes the hosted cluster once the backup command has kept it paused
+// for the backup longer than the maxPauseDuration option, protecting it from long
+// reconciliation outages when snapshots or uploads are slow. The item fails, so the backup
+// ends PartiallyFailed, and the reason is recorded on the Backup. Later items are backed up
+// from the resumed cluster. The pause start is read once per backup.
+func (p *BackupPlugin) enforcePauseWindow(ctx context.Context, backup *velerov1.Backup) error {
+	if p.MaxPauseDuration == 0 {
+		return nil
+	}
+	if p.pauseDeadlineBackup != backup.Name {
+		hc, err := common.GetHostedCluster(ctx, p.client, backup.Spec.IncludedNamespaces, p.hcp.Namespace)
+		if err != nil {
+			return fmt.Errorf("error getting HostedCluster: %w", err)
+		}
+		p.pauseDeadlineBackup = backup.Name
+		p.pauseDeadline = time.Time{}
+		if hc != nil && hc.Annotations[common.BackupInProgressAnnotation] == backup.Name {
+			pausedAt, err := time.Parse(time.RFC3339, hc.Annotations[common.BackupPausedAtAnnotation])
+			if err != nil {
+				p.log.Warnf("Cannot enforce %s on HostedCluster %s/%s: %v", common.ConfigKeyMaxPauseDuration, hc.Namespace, hc.Name, err)
+			} else {
+				p.pauseDeadline = pausedAt.Add(p.MaxPauseDuration)
 			}
 		}
+	}
+	if p.pauseDeadline.IsZero() || time.Now().Before(p.pauseDeadline) {
+		return nil
+	}
+	p.pauseDeadline = time.Time{}
 
-	case kind == common.DataVolumeKind || kind == common.PersistentVolumeClaimKind:
-		metadata, err := meta.Accessor(item)
-		if err != nil {
-			return nil, nil, fmt.Errorf("error getting metadata accessor: %v", err)
+	hc, err := common.GetHostedCluster(ctx, p.client, backup.Spec.IncludedNamespaces, p.hcp.Namespace)
+	if err != nil {
+		return fmt.Errorf("error getting HostedCluster: %w", err)
+	}
+	if hc == nil || hc.Annotations[common.BackupInProgressAnnotation] != backup.Name {
+		return nil // resumed meanwhile
+	}
+	if _, err := p.pauser.Unpause(ctx, hc.Namespace, hc.Name); err != nil {
+		return fmt.Errorf("error resuming HostedCluster %s/%s paused longer than %s %s: %w", hc.Namespace, hc.Name, common.ConfigKeyMaxPauseDuration, p.MaxPauseDuration, err)
+	}
+	reason := fmt.Sprintf("HostedCluster %s/%s was paused longer than %s %s and was resumed, the items backed up after %s are not from the paused cluster",
+		hc.Namespace, hc.Name, common.ConfigKeyMaxPauseDuration, p.MaxPauseDuration, time.Now().UTC().Format(time.RFC3339))
+	p.log.Warn(reason)
+
+	original := backup.DeepCopy()
+	common.AddAnnotation(backup, common.PauseWindowExceededAnnotation, reason)
+	if err := p.client.Patch(ctx, backup, crclient.MergeFrom(original)); err != nil {
+		p.log.Warnf("Could not record the pause window on Backup %s: %v", backup.Name, err)
+	}
+	return errors.New(reason)
+}
+
+// stampBackupSchema annotates, once per backup, the Backup with the schema of its items, the
+// plugin version and the HostedControlPlane backed up, on a best effort basis. The items
+// carry the same schema and version annotations.
+func (p *BackupPlugin) stampBackupSchema(ctx context.Context, backup *velerov1.Backup) {
+	if p.schemaStampedBackup == backup.Name {
+		return
+	}
+	p.schemaStampedBackup = backup.Name
+
+	original := backup.DeepCopy()
+	common.AddAnnotation(backup, common.BackupSchemaAnnotation, strconv.Itoa(common.BackupSchemaVersion))
+	common.AddAnnotation(backup, common.BackupPluginVersionAnnotation, version.Version)
+	backupsummary.AddHCP(backup, p.hcp.Namespace, p.hcp.Name)
+	if err := p.client.Patch(ctx, backup, crclient.MergeFrom(original)); err != nil {
+		p.log.Warnf("Could not record the backup schema on Backup %s: %v", backup.Name, err)
+	}
+}
+
+// runBeforePauseHooks fires, once per backup, the beforePause hooks before the plugin acts on
+// the hosted cluster. A hosted cluster the backup command paused for the backup is skipped:
+// the command fired them before pausing it.
+func (p *BackupPlugin) runBeforePauseHooks(ctx context.Context, backup *velerov1.Backup) error {
+	if p.hooks == nil || p.beforePauseBackup == backup.Name {
+		return nil
+	}
+	hc, err := common.GetHostedCluster(ctx, p.client, backup.Spec.IncludedNamespaces, p.hcp.Namespace)
+	if err != nil {
+		return fmt.Errorf("error getting HostedCluster: %w", err)
+	}
+	if hc == nil || hc.Annotations[common.BackupInProgressAnnotation] != backup.Name {
+		if err := p.hooks.Run(ctx, hooks.Payload{
+			Event:                 hooks.BeforePause,
+			Backup:                backup.Name,
+			HostedClusterName:     p.hcp.Name,
+			ControlPlaneNamespace: p.hcp.Namespace,
+		}); err != nil {
+			return err
 		}
-		labels := metadata.GetLabels()
-		if _, exists := labels[common.KubevirtRHCOSLabel]; exists {
-			return nil, nil, nil
+	}
+	p.beforePauseBackup = backup.Name
+	return nil
+}
+
+// startNotificationWatcher starts, once per backup, the Job reporting its outcome to the
+// notification webhook. Failing to start it does not fail the backup.
+func (p *BackupPlugin) startNotificationWatcher(ctx context.Context, backupName string) {
+	if p.notifyWatcher == nil || p.notifyStartedBackup == backupName {
+		return
+	}
+	if err := p.notifyWatcher.Ensure(ctx, notify.OperationBackup, backupName, p.hcp.Name); err != nil {
+		p.log.Warnf("Completion notification disabled for backup %s: %v", backupName, err)
+	}
+	p.notifyStartedBackup = backupName
+}
+
+// saveDiagnostics stores, once per backup, the diagnostics bundle of a failed backup for
+// support cases. Failing to store it is only logged.
+func (p *BackupPlugin) saveDiagnostics(ctx context.Context, backup *velerov1.Backup, cause error) {
+	if p.diagnosticsSavedBackup == backup.Name {
+		return
+	}
+	p.diagnosticsSavedBackup = backup.Name
+
+	bundle := diagnostics.Collect(ctx, p.client, backup, cmp.Or(p.VeleroNamespace, backup.Namespace), p.hcp.Namespace, cause)
+	name, err := diagnostics.Save(ctx, p.client, backup, bundle)
+	if err != nil {
+		p.log.Warnf("Could not save the diagnostics of backup %s: %v", backup.Name, err)
+		return
+	}
+	p.log.Infof("Diagnostics of the failed backup %s saved in ConfigMap %s/%s", backup.Name, backup.Namespace, name)
+}
+
+// recordAudit adds the outcome of an item of an HCP backup to its audit trail.
+func (p *BackupPlugin) recordAudit(backup *velerov1.Backup, gk schema.GroupKind, name string, start time.Time, result runtime.Unstructured, err error) {
+	if p.hcp == nil {
+		return // not an HCP backup
+	}
+	if p.auditTrail == nil || p.auditTrail.BackupName() != backup.Name {
+		if p.auditTrail != nil {
+			p.auditTrail.Flush(p.ctx)
 		}
+		p.auditTrail = audit.NewTrail(p.client, backup, p.log)
+	}
 
-		// Exclude etcd data PVCs when using etcdSnapshot method.
-		// PVC names follow the StatefulSet pattern: data-etcd-{index}
-		if kind == common.PersistentVolumeClaimKind &&
-			strings.HasPrefix(metadata.GetName(), common.EtcdPVCPrefix) &&
-			p.etcdBackupMethod == common.EtcdBackupMethodEtcdSnapshot {
-			p.log.Infof("Excluding etcd PVC %s from backup (using etcdSnapshot method)", metadata.GetName())
-			return nil, nil, nil
+	action := audit.ActionLabeled
+	switch _, handled := kindHandlers[gk]; {
+	case err != nil:
+		action = audit.ActionFailed
+	case result == nil:
+		action = audit.ActionExcluded
+	case handled:
+		action = audit.ActionHandled
+	}
+	p.auditTrail.Add(audit.Record{Time: start, Kind: gk.Kind, Name: name, Action: action, Duration: time.Since(start)})
+}
+
+// isExcludedByNodePoolSelector reports whether the item is a NodePool, or CAPI machinery
+// owned by a NodePool, that does not match the nodePoolSelector. Machinery whose NodePool
+// no longer exists is kept.
+func (p *BackupPlugin) isExcludedByNodePoolSelector(ctx context.Context, gk schema.GroupKind, item runtime.Unstructured) (bool, error) {
+	metadata, err := meta.Accessor(item)
+	if err != nil {
+		return false, fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	if gk == common.NodePoolGroupKind {
+		return !p.NodePoolSelector.Matches(labels.Set(metadata.GetLabels())), nil
+	}
+
+	owner := metadata.GetAnnotations()[common.NodePoolAnnotation]
+	namespace, name, found := strings.Cut(owner, "/")
+	if !found {
+		return false, nil
+	}
+	if matches, ok := p.nodePoolMatches.Load(owner); ok {
+		return !matches.(bool), nil
+	}
+
+	nodePool := &hyperv1.NodePool{}
+	if err := p.client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, nodePool); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
 		}
+		return false, fmt.Errorf("error getting NodePool %s: %w", owner, err)
 	}
 
-	return item, nil, nil
+	matches := p.NodePoolSelector.Matches(labels.Set(nodePool.Labels))
+	p.nodePoolMatches.Store(owner, matches)
+	return !matches, nil
 }
 
 // createEtcdBackup creates an HCPEtcdBackup CR in the HCP namespace.
@@ -296,7 +677,12 @@ func (p *BackupPlugin) createEtcdBackup(ctx context.Context, backup *velerov1.Ba
 		return fmt.Errorf("failed to get OADP namespace: %w", err)
 	}
 
-	p.etcdOrchestrator = etcdbackup.NewOrchestrator(p.log, p.client, p.hoNamespace, oadpNS)
+	p.etcdOrchestrator = p.newSnapshotWaiter(oadpNS)
+
+	// A plugin process restarted mid-backup waits for the snapshot already taken
+	if resumed, err := p.etcdOrchestrator.Resume(ctx, backup, p.hcp.Namespace); err != nil || resumed {
+		return err
+	}
 
 	// Fetch the HostedCluster for encryption config
 	hc, err := common.GetHostedCluster(ctx, p.client, backup.Spec.IncludedNamespaces, p.hcp.Namespace)
@@ -321,6 +707,14 @@ func (p *BackupPlugin) createEtcdBackup(ctx context.Context, backup *velerov1.Ba
 	return nil
 }
 
+// newEtcdOrchestrator is the SnapshotWaiter of the plugin, taking the snapshot with an
+// HCPEtcdBackup.
+func (p *BackupPlugin) newEtcdOrchestrator(oadpNamespace string) common.SnapshotWaiter {
+	orchestrator := etcdbackup.NewOrchestrator(p.log, p.client, p.hoNamespace, oadpNamespace)
+	orchestrator.Timeouts = p.Timeouts
+	return orchestrator
+}
+
 // waitForEtcdBackupCompletion waits for the HCPEtcdBackup to finish and cleans up
 // the copied credential Secret. Caches the snapshotURL on the plugin struct so it
 // is available regardless of item processing order (HC before HCP or vice versa).
@@ -337,7 +731,13 @@ func (p *BackupPlugin) waitForEtcdBackupCompletion(ctx context.Context) error {
 
 	snapshotURL, err := p.etcdOrchestrator.WaitForCompletion(ctx)
 	if err != nil {
-		return fmt.Errorf("HCPEtcdBackup failed: %v", err)
+		// Errors that are not retried end the wait for good, e.g. the Execute deadline
+		if !common.IsRetryable(err) {
+			if cleanupErr := p.etcdOrchestrator.CleanupCredentialSecret(context.WithoutCancel(ctx)); cleanupErr != nil {
+				p.log.Warnf("Failed to cleanup etcd backup credential Secret after wait error: %v", cleanupErr)
+			}
+		}
+		return fmt.Errorf("HCPEtcdBackup failed: %w", err)
 	}
 	p.etcdSnapshotURL = snapshotURL
 	p.log.Infof("HCPEtcdBackup completed, snapshotURL: %s", snapshotURL)
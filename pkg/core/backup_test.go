@@ -4,34 +4,76 @@ package core
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/audit"
 	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
 	plugtypes "github.com/openshift/hypershift-oadp-plugin/pkg/core/types"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/diagnostics"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/guestsnapshot"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/hooks"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/notify"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/version"
 	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
 	"github.com/sirupsen/logrus"
 	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 // mockValidator implements validation.BackupValidator for testing.
 type mockValidator struct {
 	validatePlatformErr error
+	clusterStateErr     error
+	healthProblems      []string
+	volumeBackupModeErr error
+	autoFSBackup        bool
+	storageLocationsErr error
 }
 
 func (m *mockValidator) ValidatePluginConfig(_ map[string]string) (*plugtypes.BackupOptions, error) {
 	return &plugtypes.BackupOptions{}, nil
 }
 
-func (m *mockValidator) ValidatePlatformConfig(_ *hyperv1.HostedControlPlane, _ *velerov1.Backup) error {
+func (m *mockValidator) ValidatePlatformConfig(_ context.Context, _ *hyperv1.HostedControlPlane, _ *velerov1.Backup) error {
 	return m.validatePlatformErr
 }
 
+func (m *mockValidator) ValidateHostedClusterState(_ *hyperv1.HostedCluster, _ *hyperv1.HostedControlPlane) error {
+	return m.clusterStateErr
+}
+
+func (m *mockValidator) ValidateHostedClusterHealth(_ *hyperv1.HostedCluster, _ *hyperv1.HostedControlPlane) []string {
+	return m.healthProblems
+}
+
+func (m *mockValidator) ValidateStorageLocations(_ context.Context, _ *velerov1.Backup) error {
+	return m.storageLocationsErr
+}
+
+func (m *mockValidator) ValidateVolumeBackupMode(_ context.Context, _ *hyperv1.HostedControlPlane, _ *velerov1.Backup, _, _ string) (bool, error) {
+	return m.autoFSBackup, m.volumeBackupModeErr
+}
+
 func newTestBackupPlugin(objects ...runtime.Object) *BackupPlugin {
 	scheme := common.CustomScheme
 
@@ -54,7 +96,7 @@ func newTestBackupPlugin(objects ...runtime.Object) *BackupPlugin {
 		WithRuntimeObjects(allObjects...).
 		Build()
 
-	return &BackupPlugin{
+	bp := &BackupPlugin{
 		log:              logrus.New(),
 		ctx:              context.Background(),
 		client:           client,
@@ -64,7 +106,11 @@ func newTestBackupPlugin(objects ...runtime.Object) *BackupPlugin {
 		BackupOptions:    &plugtypes.BackupOptions{},
 		hoNamespace:      "hypershift",
 		etcdBackupMethod: common.EtcdBackupMethodVolume,
+		pauser:           common.ClientPauser{Client: client},
+		backupWaiter:     common.ClientBackupWaiter{Client: client},
 	}
+	bp.newSnapshotWaiter = bp.newEtcdOrchestrator
+	return bp
 }
 
 func newUnstructuredItem(kind, apiVersion, name, namespace string) *unstructured.Unstructured {
@@ -133,6 +179,119 @@ func TestExecute(t *testing.T) {
 				g.Expect(status["lastSuccessfulEtcdBackupURL"]).To(Equal("s3://bucket/backups/test/etcd-backup/snapshot.db"))
 			},
 		},
+		{
+			name: "When Execute processes a HostedCluster item, It Should record the source metadata on the item and the Backup",
+			setup: func(bp *BackupPlugin) {
+				_ = bp.client.Create(context.TODO(), newTestBackup())
+				_ = bp.client.Create(context.TODO(), &configv1.ClusterVersion{
+					ObjectMeta: metav1.ObjectMeta{Name: "version"},
+					Status:     configv1.ClusterVersionStatus{Desired: configv1.Release{Version: "4.18.5"}},
+				})
+			},
+			item: func() *unstructured.Unstructured {
+				item := newUnstructuredItem("HostedCluster", "hypershift.openshift.io/v1beta1", "my-hc", "clusters")
+				item.Object["spec"] = map[string]any{
+					"infraID":  "my-hc-abcde",
+					"platform": map[string]any{"type": "AWS"},
+					"release":  map[string]any{"image": "quay.io/openshift-release-dev/ocp-release:4.18.0-x86_64"},
+				}
+				item.Object["status"] = map[string]any{"version": map[string]any{"desired": map[string]any{"version": "4.18.0"}}}
+				return item
+			},
+			backup: newTestBackup,
+			assert: func(g *GomegaWithT, result runtime.Unstructured, bp *BackupPlugin) {
+				metadata := result.UnstructuredContent()["metadata"].(map[string]any)
+				annotations := metadata["annotations"].(map[string]any)
+				source, err := common.ParseSourceMetadata(map[string]string{common.SourceMetadataAnnotation: annotations[common.SourceMetadataAnnotation].(string)})
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(*source).To(Equal(common.SourceMetadata{
+					ReleaseImage:             "quay.io/openshift-release-dev/ocp-release:4.18.0-x86_64",
+					ReleaseVersion:           "4.18.0",
+					Platform:                 "AWS",
+					InfraID:                  "my-hc-abcde",
+					ManagementClusterVersion: "4.18.5",
+				}))
+
+				backup := &velerov1.Backup{}
+				g.Expect(bp.client.Get(context.TODO(), crclient.ObjectKey{Name: "test-backup", Namespace: "openshift-adp"}, backup)).To(Succeed())
+				g.Expect(backup.Annotations[common.SourceMetadataAnnotation]).To(Equal(annotations[common.SourceMetadataAnnotation]))
+			},
+		},
+		{
+			name: "When Execute processes an item without kind handler, It Should label it with the HostedCluster name",
+			item: func() *unstructured.Unstructured {
+				return newUnstructuredItem("ConfigMap", "v1", "some-config", "clusters-test")
+			},
+			backup: newTestBackup,
+			assert: func(g *GomegaWithT, result runtime.Unstructured, _ *BackupPlugin) {
+				metadata := result.UnstructuredContent()["metadata"].(map[string]any)
+				g.Expect(metadata["labels"]).To(HaveKeyWithValue(common.HostedClusterLabel, "test-hcp"))
+			},
+		},
+		{
+			name: "When Execute processes an item, It Should stamp it and the Backup with the backup schema and its HostedControlPlane",
+			setup: func(bp *BackupPlugin) {
+				_ = bp.client.Create(context.TODO(), newTestBackup())
+			},
+			item: func() *unstructured.Unstructured {
+				return newUnstructuredItem("ConfigMap", "v1", "some-config", "clusters-test")
+			},
+			backup: newTestBackup,
+			assert: func(g *GomegaWithT, result runtime.Unstructured, bp *BackupPlugin) {
+				metadata := result.UnstructuredContent()["metadata"].(map[string]any)
+				g.Expect(metadata["annotations"]).To(HaveKeyWithValue(common.BackupSchemaAnnotation, strconv.Itoa(common.BackupSchemaVersion)))
+				g.Expect(metadata["annotations"]).To(HaveKeyWithValue(common.BackupPluginVersionAnnotation, version.Version))
+
+				backup := &velerov1.Backup{}
+				g.Expect(bp.client.Get(context.TODO(), crclient.ObjectKey{Name: "test-backup", Namespace: "openshift-adp"}, backup)).To(Succeed())
+				g.Expect(backup.Annotations).To(HaveKeyWithValue(common.BackupSchemaAnnotation, strconv.Itoa(common.BackupSchemaVersion)))
+				g.Expect(backup.Annotations).To(HaveKeyWithValue(common.BackupHCPsAnnotation, bp.hcp.Namespace+"/"+bp.hcp.Name))
+			},
+		},
+		{
+			name: "When Execute processes a HostedCluster item, It Should label it with its name",
+			item: func() *unstructured.Unstructured {
+				return newUnstructuredItem("HostedCluster", "hypershift.openshift.io/v1beta1", "test-hcp", "clusters")
+			},
+			backup: newTestBackup,
+			assert: func(g *GomegaWithT, result runtime.Unstructured, _ *BackupPlugin) {
+				metadata := result.UnstructuredContent()["metadata"].(map[string]any)
+				g.Expect(metadata["labels"]).To(HaveKeyWithValue(common.HostedClusterLabel, "test-hcp"))
+			},
+		},
+		{
+			name: "When Execute processes a NodePool item, It Should strip its status and server populated metadata",
+			item: func() *unstructured.Unstructured {
+				item := newUnstructuredItem("NodePool", "hypershift.openshift.io/v1beta1", "my-np", "clusters")
+				metadata := item.Object["metadata"].(map[string]any)
+				metadata["resourceVersion"] = "42"
+				metadata["uid"] = "7d6c3b7e-4e3a-4d43-8e0a-2b1c6a8e1f0d"
+				item.Object["spec"] = map[string]any{"clusterName": "my-hc"}
+				item.Object["status"] = map[string]any{"replicas": int64(2)}
+				return item
+			},
+			backup: newTestBackup,
+			assert: func(g *GomegaWithT, result runtime.Unstructured, _ *BackupPlugin) {
+				content := result.UnstructuredContent()
+				g.Expect(content).NotTo(HaveKey("status"))
+				g.Expect(content["spec"]).To(HaveKeyWithValue("clusterName", "my-hc"))
+				metadata := content["metadata"].(map[string]any)
+				g.Expect(metadata).NotTo(HaveKey("resourceVersion"))
+				g.Expect(metadata).NotTo(HaveKey("uid"))
+			},
+		},
+		{
+			name: "When Execute processes an item of another kind, It Should keep its status",
+			item: func() *unstructured.Unstructured {
+				item := newUnstructuredItem("Deployment", "apps/v1", "kube-apiserver", "clusters-test")
+				item.Object["status"] = map[string]any{"replicas": int64(2)}
+				return item
+			},
+			backup: newTestBackup,
+			assert: func(g *GomegaWithT, result runtime.Unstructured, _ *BackupPlugin) {
+				g.Expect(result.UnstructuredContent()).To(HaveKey("status"))
+			},
+		},
 		// HostedControlPlane cases
 		{
 			name: "When Execute processes a HostedControlPlane with cached etcdSnapshotURL, It Should add etcd snapshot URL annotation",
@@ -184,6 +343,64 @@ func TestExecute(t *testing.T) {
 				g.Expect(labels[common.FSBackupLabelName]).To(Equal("true"))
 			},
 		},
+		{
+			name: "When Execute processes a control plane Pod listed in fsBackupPods with fsBackup disabled, It Should label it and opt its volumes in",
+			setup: func(bp *BackupPlugin) {
+				bp.FSBackupPods = map[string][]string{"ovnkube-master": {"ovnkube-db", "sbdb"}}
+			},
+			item: func() *unstructured.Unstructured {
+				item := newUnstructuredItem("Pod", "v1", "ovnkube-master-0", "clusters-test")
+				item.SetAnnotations(map[string]string{common.BackupVolumesAnnotation: "sbdb"})
+				return item
+			},
+			backup: func() *velerov1.Backup {
+				b := newTestBackup()
+				b.Spec.DefaultVolumesToFsBackup = &falseVal
+				return b
+			},
+			assert: func(g *GomegaWithT, result runtime.Unstructured, _ *BackupPlugin) {
+				item := result.(*unstructured.Unstructured)
+				g.Expect(item.GetLabels()).To(HaveKeyWithValue(common.FSBackupLabelName, "true"))
+				g.Expect(item.GetAnnotations()).To(HaveKeyWithValue(common.BackupVolumesAnnotation, "sbdb,ovnkube-db"))
+			},
+		},
+		{
+			name: "When Execute processes a control plane Pod after volumeBackupModePolicy Auto selected fs-backup, It Should opt its PVC volumes in",
+			setup: func(bp *BackupPlugin) {
+				bp.validator = &mockValidator{autoFSBackup: true}
+			},
+			item: func() *unstructured.Unstructured {
+				item := newUnstructuredItem("Pod", "v1", "etcd-0", "clusters-test")
+				item.Object["spec"] = map[string]any{"volumes": []any{
+					map[string]any{"name": "data", "persistentVolumeClaim": map[string]any{"claimName": "data-etcd-0"}},
+					map[string]any{"name": "etcd-ca", "configMap": map[string]any{"name": "etcd-ca"}},
+				}}
+				return item
+			},
+			backup: newTestBackup,
+			assert: func(g *GomegaWithT, result runtime.Unstructured, _ *BackupPlugin) {
+				item := result.(*unstructured.Unstructured)
+				g.Expect(item.GetLabels()).To(HaveKeyWithValue(common.FSBackupLabelName, "true"))
+				g.Expect(item.GetAnnotations()).To(HaveKeyWithValue(common.BackupVolumesAnnotation, "data"))
+			},
+		},
+		{
+			name: "When Execute processes a Pod listed in fsBackupPods outside the control plane namespace, It Should not label it",
+			setup: func(bp *BackupPlugin) {
+				bp.FSBackupPods = map[string][]string{"image-registry": nil}
+			},
+			item: func() *unstructured.Unstructured {
+				return newUnstructuredItem("Pod", "v1", "image-registry-0", "clusters")
+			},
+			backup: func() *velerov1.Backup {
+				b := newTestBackup()
+				b.Spec.DefaultVolumesToFsBackup = &falseVal
+				return b
+			},
+			assert: func(g *GomegaWithT, result runtime.Unstructured, _ *BackupPlugin) {
+				g.Expect(result.(*unstructured.Unstructured).GetLabels()).NotTo(HaveKey(common.FSBackupLabelName))
+			},
+		},
 		{
 			name: "When Execute processes an etcd Pod with etcdSnapshot method, It Should skip the pod",
 			setup: func(bp *BackupPlugin) {
@@ -230,6 +447,42 @@ func TestExecute(t *testing.T) {
 			backup:        newTestBackup,
 			wantNilResult: true,
 		},
+		{
+			name: "When Execute processes a PVC with a skipVolumeLabels label, It Should skip the PVC",
+			setup: func(bp *BackupPlugin) {
+				bp.BackupOptions = &plugtypes.BackupOptions{SkipVolumeLabels: []string{"example.com/cache=boot-image"}}
+			},
+			item: func() *unstructured.Unstructured {
+				item := newUnstructuredItem("PersistentVolumeClaim", "v1", "boot-cache", "clusters-test")
+				item.Object["metadata"].(map[string]any)["labels"] = map[string]any{"example.com/cache": "boot-image"}
+				return item
+			},
+			backup:        newTestBackup,
+			wantNilResult: true,
+		},
+		{
+			name: "When Execute processes a PVC whose label value differs from skipVolumeLabels, It Should pass through unchanged",
+			setup: func(bp *BackupPlugin) {
+				bp.BackupOptions = &plugtypes.BackupOptions{SkipVolumeLabels: []string{"example.com/cache=boot-image"}}
+			},
+			item: func() *unstructured.Unstructured {
+				item := newUnstructuredItem("PersistentVolumeClaim", "v1", "data-cache", "clusters-test")
+				item.Object["metadata"].(map[string]any)["labels"] = map[string]any{"example.com/cache": "data"}
+				return item
+			},
+			backup: newTestBackup,
+		},
+		{
+			name: "When Execute processes a DataVolume matching skipVolumeNames, It Should skip it",
+			setup: func(bp *BackupPlugin) {
+				bp.BackupOptions = &plugtypes.BackupOptions{SkipVolumeNames: []string{"*-image-cache"}}
+			},
+			item: func() *unstructured.Unstructured {
+				return newUnstructuredItem("DataVolume", "cdi.kubevirt.io/v1beta1", "windows-image-cache", "clusters-test")
+			},
+			backup:        newTestBackup,
+			wantNilResult: true,
+		},
 		{
 			name: "When Execute processes a regular PVC, It Should pass through unchanged",
 			item: func() *unstructured.Unstructured {
@@ -310,3 +563,970 @@ func TestWaitForEtcdBackupCompletion(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteNodePoolSelector(t *testing.T) {
+	selector, err := labels.Parse("pool-type=production")
+	if err != nil {
+		t.Fatalf("unexpected error parsing selector: %v", err)
+	}
+
+	productionNP := &hyperv1.NodePool{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod-np", Namespace: "clusters", Labels: map[string]string{"pool-type": "production"}},
+	}
+	experimentNP := &hyperv1.NodePool{
+		ObjectMeta: metav1.ObjectMeta{Name: "exp-np", Namespace: "clusters", Labels: map[string]string{"pool-type": "experiment"}},
+	}
+
+	newMachineDeployment := func(name, owner string) *unstructured.Unstructured {
+		item := newUnstructuredItem("MachineDeployment", "cluster.x-k8s.io/v1beta1", name, "clusters-test")
+		item.Object["metadata"].(map[string]any)["annotations"] = map[string]any{common.NodePoolAnnotation: owner}
+		return item
+	}
+
+	tests := []struct {
+		name         string
+		item         func() *unstructured.Unstructured
+		wantExcluded bool
+	}{
+		{
+			name: "When a NodePool matches the selector, It Should be backed up",
+			item: func() *unstructured.Unstructured {
+				item := newUnstructuredItem("NodePool", "hypershift.openshift.io/v1beta1", "prod-np", "clusters")
+				item.Object["metadata"].(map[string]any)["labels"] = map[string]any{"pool-type": "production"}
+				return item
+			},
+		},
+		{
+			name: "When a NodePool does not match the selector, It Should be skipped",
+			item: func() *unstructured.Unstructured {
+				item := newUnstructuredItem("NodePool", "hypershift.openshift.io/v1beta1", "exp-np", "clusters")
+				item.Object["metadata"].(map[string]any)["labels"] = map[string]any{"pool-type": "experiment"}
+				return item
+			},
+			wantExcluded: true,
+		},
+		{
+			name: "When CAPI machinery belongs to a matching NodePool, It Should be backed up",
+			item: func() *unstructured.Unstructured { return newMachineDeployment("prod-np", "clusters/prod-np") },
+		},
+		{
+			name:         "When CAPI machinery belongs to a non-matching NodePool, It Should be skipped",
+			item:         func() *unstructured.Unstructured { return newMachineDeployment("exp-np", "clusters/exp-np") },
+			wantExcluded: true,
+		},
+		{
+			name: "When CAPI machinery belongs to a deleted NodePool, It Should be backed up",
+			item: func() *unstructured.Unstructured { return newMachineDeployment("gone-np", "clusters/gone-np") },
+		},
+		{
+			name: "When an item is not NodePool machinery, It Should be backed up",
+			item: func() *unstructured.Unstructured {
+				return newUnstructuredItem("Secret", "v1", "some-secret", "clusters-test")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			bp := newTestBackupPlugin(productionNP, experimentNP)
+			bp.NodePoolSelector = selector
+
+			result, _, err := bp.Execute(tt.item(), newTestBackup())
+			g.Expect(err).NotTo(HaveOccurred())
+			if tt.wantExcluded {
+				g.Expect(result).To(BeNil())
+				return
+			}
+			g.Expect(result).NotTo(BeNil())
+		})
+	}
+}
+
+func TestIsExcludedByNodePoolSelectorConcurrent(t *testing.T) {
+	g := NewWithT(t)
+	selector, err := labels.Parse("pool-type=production")
+	g.Expect(err).NotTo(HaveOccurred())
+	bp := newTestBackupPlugin(
+		&hyperv1.NodePool{ObjectMeta: metav1.ObjectMeta{Name: "prod-np", Namespace: "clusters", Labels: map[string]string{"pool-type": "production"}}},
+		&hyperv1.NodePool{ObjectMeta: metav1.ObjectMeta{Name: "exp-np", Namespace: "clusters", Labels: map[string]string{"pool-type": "experiment"}}},
+	)
+	bp.NodePoolSelector = selector
+	gk := schema.GroupKind{Group: "cluster.x-k8s.io", Kind: "MachineDeployment"}
+
+	// Velero may run Execute for several items at once, all of them sharing the cache
+	var wg sync.WaitGroup
+	for i := range 20 {
+		owner, wantExcluded := "clusters/prod-np", false
+		if i%2 == 1 {
+			owner, wantExcluded = "clusters/exp-np", true
+		}
+		wg.Go(func() {
+			item := newUnstructuredItem("MachineDeployment", "cluster.x-k8s.io/v1beta1", "md-"+strconv.Itoa(i), "clusters-test")
+			item.Object["metadata"].(map[string]any)["annotations"] = map[string]any{common.NodePoolAnnotation: owner}
+			excluded, err := bp.isExcludedByNodePoolSelector(context.TODO(), gk, item)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(excluded).To(Equal(wantExcluded))
+		})
+	}
+	wg.Wait()
+}
+
+func TestExecuteNodePoolMachineNodes(t *testing.T) {
+	g := NewWithT(t)
+	machine := newUnstructuredItem("Machine", "cluster.x-k8s.io/v1beta1", "workers-abc12", "clusters-test")
+	machine.Object["metadata"].(map[string]any)["annotations"] = map[string]any{common.NodePoolAnnotation: "clusters/workers"}
+	machine.Object["spec"] = map[string]any{"providerID": "aws:///us-east-1a/i-0123"}
+	bp := newTestBackupPlugin(machine)
+
+	result, additionalItems, err := bp.Execute(newUnstructuredItem("NodePool", "hypershift.openshift.io/v1beta1", "workers", "clusters"), newTestBackup())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).NotTo(BeNil())
+	// The machine nodes ConfigMap is created during the backup, after Velero listed the
+	// ConfigMaps, so it is added to the backup with the NodePool
+	g.Expect(additionalItems).To(ConsistOf(velero.ResourceIdentifier{
+		GroupResource: schema.GroupResource{Resource: "configmaps"},
+		Namespace:     "clusters-test",
+		Name:          common.MachineNodesConfigMapName,
+	}))
+
+	nodes, err := common.MachineNodes(context.TODO(), bp.client, "clusters-test", "workers")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(nodes).To(HaveKeyWithValue("workers-abc12", common.MachineNode{ProviderID: "aws:///us-east-1a/i-0123"}))
+}
+
+func TestHostedControlPlaneGuestSnapshot(t *testing.T) {
+	g := NewWithT(t)
+	bp := newTestBackupPlugin()
+	bp.GuestSnapshot = true
+	bp.newGuestClient = func(context.Context, crclient.Client, string) (crclient.Client, error) {
+		return fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-0"}}).Build(), nil
+	}
+	hcp := newUnstructuredItem("HostedControlPlane", "hypershift.openshift.io/v1beta1", "test-hcp", "clusters-test")
+
+	additionalItems, err := hostedControlPlaneHandler{}.AdditionalItems(context.TODO(), bp, hcp, newTestBackup())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(additionalItems).To(ConsistOf(velero.ResourceIdentifier{
+		GroupResource: schema.GroupResource{Resource: "configmaps"},
+		Namespace:     "clusters-test",
+		Name:          guestsnapshot.ConfigMapName,
+	}))
+	cm := &corev1.ConfigMap{}
+	g.Expect(bp.client.Get(context.TODO(), crclient.ObjectKey{Namespace: "clusters-test", Name: guestsnapshot.ConfigMapName}, cm)).To(Succeed())
+	g.Expect(cm.Data["nodes.yaml"]).To(ContainSubstring("worker-0"))
+
+	// The snapshot is only a reference, an unreachable hosted cluster does not fail the backup
+	bp.newGuestClient = func(context.Context, crclient.Client, string) (crclient.Client, error) {
+		return nil, errors.New("connection refused")
+	}
+	additionalItems, err = hostedControlPlaneHandler{}.AdditionalItems(context.TODO(), bp, hcp, newTestBackup())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(additionalItems).To(BeEmpty())
+}
+
+func TestHostedControlPlaneSignerSecrets(t *testing.T) {
+	g := NewWithT(t)
+	// The ignition server is disabled, only the konnectivity signer exists
+	bp := newTestBackupPlugin(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: common.KonnectivitySignerSecret, Namespace: "clusters-test"}})
+	hcp := newUnstructuredItem("HostedControlPlane", "hypershift.openshift.io/v1beta1", "test-hcp", "clusters-test")
+
+	additionalItems, err := hostedControlPlaneHandler{}.AdditionalItems(context.TODO(), bp, hcp, newTestBackup())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(additionalItems).To(ConsistOf(velero.ResourceIdentifier{
+		GroupResource: schema.GroupResource{Resource: "secrets"},
+		Namespace:     "clusters-test",
+		Name:          common.KonnectivitySignerSecret,
+	}))
+}
+
+func TestHostedClusterCRDs(t *testing.T) {
+	crd := func(name, group string) *apiextensionsv1.CustomResourceDefinition {
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       apiextensionsv1.CustomResourceDefinitionSpec{Group: group},
+		}
+	}
+	crdItem := func(name string) velero.ResourceIdentifier {
+		return velero.ResourceIdentifier{
+			GroupResource: schema.GroupResource{Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions"},
+			Name:          name,
+		}
+	}
+	objects := []runtime.Object{
+		crd("hostedclusters.hypershift.openshift.io", "hypershift.openshift.io"),
+		crd("machines.cluster.x-k8s.io", "cluster.x-k8s.io"),
+		crd("awsmachines.infrastructure.cluster.x-k8s.io", "infrastructure.cluster.x-k8s.io"),
+		crd("agents.agent-install.openshift.io", "agent-install.openshift.io"),
+		crd("virtualmachines.kubevirt.io", "kubevirt.io"),
+	}
+
+	tests := []struct {
+		name       string
+		backupCRDs bool
+		platform   string
+		wantItems  []velero.ResourceIdentifier
+	}{
+		{
+			name:     "When backupCRDs is unset, It Should add no CRD",
+			platform: "AWS",
+		},
+		{
+			name:       "When backupCRDs is set, It Should add the HyperShift and CAPI CRDs",
+			backupCRDs: true,
+			platform:   "AWS",
+			wantItems: []velero.ResourceIdentifier{
+				crdItem("hostedclusters.hypershift.openshift.io"),
+				crdItem("machines.cluster.x-k8s.io"),
+				crdItem("awsmachines.infrastructure.cluster.x-k8s.io"),
+			},
+		},
+		{
+			name:       "When backupCRDs is set for an Agent cluster, It Should add the Agent CRDs too",
+			backupCRDs: true,
+			platform:   "Agent",
+			wantItems: []velero.ResourceIdentifier{
+				crdItem("hostedclusters.hypershift.openshift.io"),
+				crdItem("machines.cluster.x-k8s.io"),
+				crdItem("awsmachines.infrastructure.cluster.x-k8s.io"),
+				crdItem("agents.agent-install.openshift.io"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			bp := newTestBackupPlugin(objects...)
+			bp.BackupOptions = &plugtypes.BackupOptions{BackupCRDs: tt.backupCRDs}
+			hc := newUnstructuredItem("HostedCluster", "hypershift.openshift.io/v1beta1", "test", "clusters")
+			g.Expect(unstructured.SetNestedField(hc.Object, tt.platform, "spec", "platform", "type")).To(Succeed())
+
+			items, err := hostedClusterHandler{}.AdditionalItems(context.TODO(), bp, hc, newTestBackup())
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(items).To(ConsistOf(tt.wantItems))
+		})
+	}
+}
+
+func TestHostedClusterTrustBundles(t *testing.T) {
+	g := NewWithT(t)
+	bp := newTestBackupPlugin(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "user-ca-bundle", Namespace: "clusters"}})
+	hc := &hyperv1.HostedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "clusters"},
+		Spec: hyperv1.HostedClusterSpec{
+			AdditionalTrustBundle: &corev1.LocalObjectReference{Name: "user-ca-bundle"},
+			// The proxy CA bundle was deleted, the backup goes on without it
+			Configuration: &hyperv1.ClusterConfiguration{Proxy: &configv1.ProxySpec{TrustedCA: configv1.ConfigMapNameReference{Name: "proxy-ca"}}},
+		},
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(hc)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	items, err := hostedClusterHandler{}.AdditionalItems(context.TODO(), bp, &unstructured.Unstructured{Object: content}, newTestBackup())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(items).To(ConsistOf(velero.ResourceIdentifier{
+		GroupResource: schema.GroupResource{Resource: "configmaps"},
+		Namespace:     "clusters",
+		Name:          "user-ca-bundle",
+	}))
+}
+
+func TestHostedControlPlanePriorityClasses(t *testing.T) {
+	g := NewWithT(t)
+	podTemplate := func(priorityClass string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{Spec: corev1.PodSpec{PriorityClassName: priorityClass}}
+	}
+	bp := newTestBackupPlugin(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-apiserver", Namespace: "clusters-test"},
+			Spec:       appsv1.DeploymentSpec{Template: podTemplate("hypershift-api-critical")},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-version-operator", Namespace: "clusters-test"},
+			Spec:       appsv1.DeploymentSpec{Template: podTemplate("system-cluster-critical")},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "etcd", Namespace: "clusters-test"},
+			Spec:       appsv1.StatefulSetSpec{Template: podTemplate("hypershift-etcd")},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-app", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Template: podTemplate("my-app-critical")},
+		},
+	)
+	hcp := newUnstructuredItem("HostedControlPlane", "hypershift.openshift.io/v1beta1", "test-hcp", "clusters-test")
+
+	additionalItems, err := hostedControlPlaneHandler{}.AdditionalItems(context.TODO(), bp, hcp, newTestBackup())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(additionalItems).To(ConsistOf(
+		velero.ResourceIdentifier{GroupResource: schema.GroupResource{Group: "scheduling.k8s.io", Resource: "priorityclasses"}, Name: "hypershift-api-critical"},
+		velero.ResourceIdentifier{GroupResource: schema.GroupResource{Group: "scheduling.k8s.io", Resource: "priorityclasses"}, Name: "hypershift-etcd"},
+	))
+}
+
+func TestHostedControlPlaneSecretEncryption(t *testing.T) {
+	encryption := &hyperv1.SecretEncryptionSpec{
+		Type:   hyperv1.AESCBC,
+		AESCBC: &hyperv1.AESCBCSpec{ActiveKey: corev1.LocalObjectReference{Name: "etcd-key"}},
+	}
+	hc := &hyperv1.HostedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "clusters"},
+		Spec:       hyperv1.HostedClusterSpec{SecretEncryption: encryption},
+	}
+	newKey := func(namespace string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "etcd-key", Namespace: namespace},
+			Data:       map[string][]byte{common.AESCBCKeySecretKey: []byte("c2VjcmV0")},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		objects   []runtime.Object
+		wantItems []velero.ResourceIdentifier
+		errSubstr string
+	}{
+		{
+			name:    "When the aescbc keys exist, It Should add both copies to the backup",
+			objects: []runtime.Object{hc, newKey("clusters"), newKey("clusters-test")},
+			wantItems: []velero.ResourceIdentifier{
+				{GroupResource: schema.GroupResource{Resource: "secrets"}, Namespace: "clusters-test", Name: "etcd-key"},
+				{GroupResource: schema.GroupResource{Resource: "secrets"}, Namespace: "clusters", Name: "etcd-key"},
+			},
+		},
+		{
+			name:      "When the control plane aescbc key is missing, It Should fail the backup",
+			objects:   []runtime.Object{hc, newKey("clusters")},
+			errSubstr: "the backup could not be decrypted on restore",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			bp := newTestBackupPlugin(tt.objects...)
+			bp.hcp.Spec.SecretEncryption = encryption
+			backup := newTestBackup()
+			g.Expect(bp.client.Create(context.TODO(), backup)).To(Succeed())
+
+			item, err := runtime.DefaultUnstructuredConverter.ToUnstructured(bp.hcp)
+			g.Expect(err).NotTo(HaveOccurred())
+			result, err := hostedControlPlaneHandler{}.Backup(context.TODO(), bp, &unstructured.Unstructured{Object: item}, backup)
+			if tt.errSubstr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errSubstr)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			annotations := result.(*unstructured.Unstructured).GetAnnotations()
+			g.Expect(annotations).To(HaveKeyWithValue(common.SecretEncryptionAnnotation, "aescbc"))
+			g.Expect(annotations).To(HaveKeyWithValue(common.SecretEncryptionKeysAnnotation, ContainSubstring(`"etcd-key":"sha256:`)))
+			g.Expect(backup.Annotations).To(HaveKeyWithValue(common.SecretEncryptionAnnotation, "aescbc"))
+
+			items, err := hostedControlPlaneHandler{}.AdditionalItems(context.TODO(), bp, result, backup)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(items).To(Equal(tt.wantItems))
+		})
+	}
+}
+
+func TestExecuteMigrationRetainVolumes(t *testing.T) {
+	newPV := func(name string) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.PersistentVolumeSpec{PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete},
+		}
+	}
+	newPVC := func(name, volumeName string) *unstructured.Unstructured {
+		item := newUnstructuredItem("PersistentVolumeClaim", "v1", name, "clusters-test")
+		item.Object["spec"] = map[string]any{"volumeName": volumeName}
+		return item
+	}
+
+	tests := []struct {
+		name       string
+		options    plugtypes.BackupOptions
+		item       *unstructured.Unstructured
+		wantPolicy corev1.PersistentVolumeReclaimPolicy
+	}{
+		{
+			name:       "When the backup is a migration, It Should retain the etcd volume",
+			options:    plugtypes.BackupOptions{Migration: true},
+			item:       newPVC("data-etcd-0", "pv-etcd-0"),
+			wantPolicy: corev1.PersistentVolumeReclaimRetain,
+		},
+		{
+			name:       "When the backup is a migration, It Should retain the configured volumes",
+			options:    plugtypes.BackupOptions{Migration: true, MigrationRetainPVCs: []string{"logs"}},
+			item:       newPVC("logs", "pv-logs"),
+			wantPolicy: corev1.PersistentVolumeReclaimRetain,
+		},
+		{
+			name:       "When the backup is a migration, It Should leave other volumes alone",
+			options:    plugtypes.BackupOptions{Migration: true},
+			item:       newPVC("logs", "pv-logs"),
+			wantPolicy: corev1.PersistentVolumeReclaimDelete,
+		},
+		{
+			name:       "When the backup is not a migration, It Should leave the etcd volume alone",
+			options:    plugtypes.BackupOptions{},
+			item:       newPVC("data-etcd-0", "pv-etcd-0"),
+			wantPolicy: corev1.PersistentVolumeReclaimDelete,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			bp := newTestBackupPlugin(newPV("pv-etcd-0"), newPV("pv-logs"))
+			bp.BackupOptions = &tt.options
+			bp.etcdBackupMethod = common.EtcdBackupMethodVolume
+
+			result, _, err := bp.Execute(tt.item, newTestBackup())
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(result).NotTo(BeNil())
+
+			volumeName, _, _ := unstructured.NestedString(tt.item.Object, "spec", "volumeName")
+			pv := &corev1.PersistentVolume{}
+			g.Expect(bp.client.Get(context.TODO(), crclient.ObjectKey{Name: volumeName}, pv)).To(Succeed())
+			g.Expect(pv.Spec.PersistentVolumeReclaimPolicy).To(Equal(tt.wantPolicy))
+		})
+	}
+}
+
+func TestExecuteDeletingHostedCluster(t *testing.T) {
+	t.Run("When the HostedCluster is being deleted, It Should fail the first item and leave the others out", func(t *testing.T) {
+		g := NewWithT(t)
+		bp := newTestBackupPlugin()
+		bp.validator = &mockValidator{clusterStateErr: errors.New("HostedCluster clusters/test is being deleted")}
+
+		_, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), newTestBackup())
+		g.Expect(err).To(MatchError(ContainSubstring("refusing to back up the hosted cluster")))
+
+		result, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "second", "clusters-test"), newTestBackup())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result).To(BeNil())
+	})
+
+	t.Run("When the HostedCluster is being deleted with the Skip policy, It Should leave every item out", func(t *testing.T) {
+		g := NewWithT(t)
+		bp := newTestBackupPlugin()
+		bp.BackupOptions = &plugtypes.BackupOptions{SkipDeletingCluster: true}
+		bp.validator = &mockValidator{clusterStateErr: errors.New("HostedCluster clusters/test is being deleted")}
+
+		result, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), newTestBackup())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result).To(BeNil())
+	})
+
+	t.Run("When the next backup finds the HostedCluster no longer being deleted, It Should back it up", func(t *testing.T) {
+		g := NewWithT(t)
+		bp := newTestBackupPlugin()
+		validator := &mockValidator{clusterStateErr: errors.New("HostedCluster clusters/test is being deleted")}
+		bp.validator = validator
+
+		_, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), newTestBackup())
+		g.Expect(err).To(MatchError(ContainSubstring("refusing to back up the hosted cluster")))
+
+		validator.clusterStateErr = nil
+		next := newTestBackup()
+		next.Name = "next-backup"
+		result, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), next)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result).NotTo(BeNil())
+	})
+}
+
+func TestExecutePluginDisabled(t *testing.T) {
+	g := NewWithT(t)
+	bp := newTestBackupPlugin(&hyperv1.HostedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "clusters", Annotations: map[string]string{common.PluginAnnotation: common.PluginDisabled}},
+	})
+	// The cluster is not validated either
+	bp.validator = &mockValidator{clusterStateErr: errors.New("HostedCluster clusters/test is being deleted")}
+
+	for _, name := range []string{"first", "second"} {
+		item := newUnstructuredItem("ConfigMap", "v1", name, "clusters-test")
+		result, _, err := bp.Execute(item.DeepCopy(), newTestBackup())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result).To(Equal(item))
+	}
+
+	// The next backup finds the HostedCluster opted back in
+	hc := &hyperv1.HostedCluster{}
+	g.Expect(bp.client.Get(context.TODO(), crclient.ObjectKey{Name: "test", Namespace: "clusters"}, hc)).To(Succeed())
+	hc.Annotations = nil
+	g.Expect(bp.client.Update(context.TODO(), hc)).To(Succeed())
+	next := newTestBackup()
+	next.Name = "next-backup"
+	_, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), next)
+	g.Expect(err).To(MatchError(ContainSubstring("refusing to back up the hosted cluster")))
+}
+
+func TestExecuteUnhealthyHostedCluster(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr bool
+	}{
+		{
+			name:   "When the health gate policy is unset, It Should only warn and back up the item",
+			policy: "",
+		},
+		{
+			name:   "When the health gate policy is Ignore, It Should back up the item",
+			policy: common.HealthGatePolicyIgnore,
+		},
+		{
+			name:    "When the health gate policy is Fail, It Should refuse the backup",
+			policy:  common.HealthGatePolicyFail,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			bp := newTestBackupPlugin()
+			bp.BackupOptions = &plugtypes.BackupOptions{HealthGatePolicy: tt.policy}
+			bp.validator = &mockValidator{healthProblems: []string{"etcd is not available: quorum lost"}}
+
+			result, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), newTestBackup())
+			if tt.wantErr {
+				g.Expect(err).To(MatchError(ContainSubstring("etcd is not available")))
+				result, _, err = bp.Execute(newUnstructuredItem("ConfigMap", "v1", "second", "clusters-test"), newTestBackup())
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(result).To(BeNil())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(result).NotTo(BeNil())
+		})
+	}
+}
+
+func TestExecuteConcurrentBackups(t *testing.T) {
+	earlier := func() *velerov1.Backup {
+		return &velerov1.Backup{
+			ObjectMeta: metav1.ObjectMeta{Name: "earlier-backup", Namespace: "openshift-adp"},
+			Spec:       velerov1.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+			Status: velerov1.BackupStatus{
+				Phase:          velerov1.BackupPhaseInProgress,
+				StartTimestamp: &metav1.Time{Time: time.Now().Add(-time.Minute)},
+			},
+		}
+	}
+
+	t.Run("When an earlier backup of the hosted cluster runs with the Fail policy, It Should fail the first item and leave the others out", func(t *testing.T) {
+		g := NewWithT(t)
+		bp := newTestBackupPlugin(earlier())
+		bp.BackupOptions = &plugtypes.BackupOptions{ConcurrentBackupPolicy: common.ConcurrentBackupPolicyFail}
+
+		_, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), newTestBackup())
+		g.Expect(err).To(MatchError(ContainSubstring("backups [openshift-adp/earlier-backup] of it are running")))
+
+		result, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "second", "clusters-test"), newTestBackup())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result).To(BeNil())
+	})
+
+	t.Run("When an earlier backup of the hosted cluster runs with the Ignore policy, It Should back up the item", func(t *testing.T) {
+		g := NewWithT(t)
+		bp := newTestBackupPlugin(earlier())
+		bp.BackupOptions = &plugtypes.BackupOptions{ConcurrentBackupPolicy: common.ConcurrentBackupPolicyIgnore}
+
+		result, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), newTestBackup())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result).NotTo(BeNil())
+	})
+
+	t.Run("When an earlier backup of the hosted cluster runs, It Should wait for it to finish", func(t *testing.T) {
+		g := NewWithT(t)
+		bp := newTestBackupPlugin(earlier())
+		bp.BackupOptions = &plugtypes.BackupOptions{Timeouts: common.Timeouts{EarlierBackupsPoll: 10 * time.Millisecond}}
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			running := &velerov1.Backup{}
+			if err := bp.client.Get(context.TODO(), crclient.ObjectKey{Name: "earlier-backup", Namespace: "openshift-adp"}, running); err != nil {
+				return
+			}
+			running.Status.Phase = velerov1.BackupPhaseCompleted
+			_ = bp.client.Update(context.TODO(), running)
+		}()
+
+		result, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), newTestBackup())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result).NotTo(BeNil())
+	})
+
+	t.Run("When the wait for an earlier backup exceeds the executeTimeout, It Should fail the item", func(t *testing.T) {
+		g := NewWithT(t)
+		bp := newTestBackupPlugin(earlier())
+		bp.BackupOptions = &plugtypes.BackupOptions{ExecuteTimeout: 100 * time.Millisecond, Timeouts: common.Timeouts{EarlierBackupsPoll: 10 * time.Millisecond}}
+
+		_, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), newTestBackup())
+		g.Expect(err).To(HaveOccurred())
+	})
+}
+
+func TestExecutePauseWindow(t *testing.T) {
+	pausedHC := func(backupName string, pausedFor time.Duration) *hyperv1.HostedCluster {
+		return &hyperv1.HostedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "clusters", Annotations: map[string]string{
+				common.PausedForBackupAnnotation:  backupName,
+				common.BackupInProgressAnnotation: backupName,
+				common.BackupPausedAtAnnotation:   time.Now().Add(-pausedFor).UTC().Format(time.RFC3339),
+			}},
+			Spec: hyperv1.HostedClusterSpec{PausedUntil: ptr.To("true")},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		hc         *hyperv1.HostedCluster
+		wantResume bool
+	}{
+		{
+			name: "When the cluster is paused for the backup within maxPauseDuration, It Should back up the item",
+			hc:   pausedHC("test-backup", 10*time.Minute),
+		},
+		{
+			name: "When the cluster is paused for another backup, It Should back up the item",
+			hc:   pausedHC("other-backup", 2*time.Hour),
+		},
+		{
+			name:       "When the cluster is paused for the backup beyond maxPauseDuration, It Should resume it and fail the item",
+			hc:         pausedHC("test-backup", 2*time.Hour),
+			wantResume: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			backup := newTestBackup()
+			bp := newTestBackupPlugin(tt.hc, backup.DeepCopy())
+			bp.BackupOptions = &plugtypes.BackupOptions{MaxPauseDuration: time.Hour}
+
+			result, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), backup)
+			hc := &hyperv1.HostedCluster{}
+			g.Expect(bp.client.Get(context.TODO(), crclient.ObjectKey{Name: "test", Namespace: "clusters"}, hc)).To(Succeed())
+			if !tt.wantResume {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(result).NotTo(BeNil())
+				g.Expect(hc.Spec.PausedUntil).NotTo(BeNil())
+				return
+			}
+			g.Expect(err).To(MatchError(ContainSubstring("paused longer than maxPauseDuration 1h0m0s")))
+			g.Expect(hc.Spec.PausedUntil).To(BeNil())
+			g.Expect(hc.Annotations).NotTo(HaveKey(common.BackupInProgressAnnotation))
+
+			stored := &velerov1.Backup{}
+			g.Expect(bp.client.Get(context.TODO(), crclient.ObjectKey{Name: backup.Name, Namespace: backup.Namespace}, stored)).To(Succeed())
+			g.Expect(stored.Annotations).To(HaveKey(common.PauseWindowExceededAnnotation))
+
+			// Later items are backed up from the resumed cluster
+			_, _, err = bp.Execute(newUnstructuredItem("ConfigMap", "v1", "second", "clusters-test"), backup)
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
+
+func TestExecuteToleratedVolumeBackupMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		tolerate []string
+		wantErr  bool
+	}{
+		{
+			name:    "When the volume backup mode does not fit the cluster, It Should refuse the backup",
+			wantErr: true,
+		},
+		{
+			name:     "When tolerateErrors lists volumeBackupMode, It Should only warn and back up the item",
+			tolerate: []string{common.TolerateVolumeBackupMode},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			bp := newTestBackupPlugin()
+			bp.BackupOptions = &plugtypes.BackupOptions{TolerateErrors: tt.tolerate}
+			bp.validator = &mockValidator{volumeBackupModeErr: errors.New("PVC clusters-test/data has no VolumeSnapshotClass")}
+
+			result, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), newTestBackup())
+			if tt.wantErr {
+				g.Expect(err).To(MatchError(ContainSubstring("has no VolumeSnapshotClass")))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(result).NotTo(BeNil())
+		})
+	}
+}
+
+func TestExecuteAuditTrail(t *testing.T) {
+	g := NewWithT(t)
+	bp := newTestBackupPlugin()
+	bp.validator = &mockValidator{clusterStateErr: errors.New("HostedCluster clusters/test is being deleted")}
+	bp.BackupOptions = &plugtypes.BackupOptions{SkipDeletingCluster: true}
+
+	_, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), newTestBackup())
+	g.Expect(err).NotTo(HaveOccurred())
+	bp.auditTrail.Flush(context.TODO())
+
+	cm := &corev1.ConfigMap{}
+	g.Expect(bp.client.Get(context.TODO(), crclient.ObjectKey{Name: audit.ConfigMapPrefix + "test-backup", Namespace: "openshift-adp"}, cm)).To(Succeed())
+	g.Expect(cm.Data[audit.DataKey]).To(MatchRegexp(`^\S+ ConfigMap clusters-test/first excluded \S+\n$`))
+}
+
+func TestRunBeforePauseHooks(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantCalls   int
+	}{
+		{
+			name:      "When the backup command did not pause the hosted cluster, It Should fire the hooks once",
+			wantCalls: 1,
+		},
+		{
+			name:        "When the backup command paused the hosted cluster for the backup, It Should not fire the hooks again",
+			annotations: map[string]string{common.BackupInProgressAnnotation: "test-backup"},
+		},
+		{
+			name:        "When the backup command paused the hosted cluster for another backup, It Should fire the hooks once",
+			annotations: map[string]string{common.BackupInProgressAnnotation: "other-backup"},
+			wantCalls:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+			}))
+			defer server.Close()
+
+			bp := newTestBackupPlugin(&hyperv1.HostedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "clusters", Annotations: tt.annotations},
+			})
+			runner, err := hooks.NewRunner(map[string]string{common.ConfigKeyHookWebhookURL: server.URL}, bp.client, "openshift-adp", bp.log)
+			g.Expect(err).NotTo(HaveOccurred())
+			bp.hooks = runner
+
+			for _, name := range []string{"first", "second"} {
+				_, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", name, "clusters-test"), newTestBackup())
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			g.Expect(calls).To(Equal(tt.wantCalls))
+		})
+	}
+}
+
+func TestStartNotificationWatcher(t *testing.T) {
+	g := NewWithT(t)
+	bp := newTestBackupPlugin(newVeleroPod(t))
+	bp.notifyWatcher = &notify.Watcher{Client: bp.client, Namespace: "openshift-adp"}
+
+	bp.startNotificationWatcher(context.TODO(), "daily")
+	g.Expect(bp.client.Delete(context.TODO(), &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "hcp-notify-backup-daily", Namespace: "openshift-adp"}})).To(Succeed())
+	// The next items of the same backup do not start it again
+	bp.startNotificationWatcher(context.TODO(), "daily")
+	bp.startNotificationWatcher(context.TODO(), "hourly")
+
+	jobs := &batchv1.JobList{}
+	g.Expect(bp.client.List(context.TODO(), jobs)).To(Succeed())
+	g.Expect(jobs.Items).To(HaveLen(1))
+	g.Expect(jobs.Items[0].Name).To(Equal("hcp-notify-backup-hourly"))
+	g.Expect(jobs.Items[0].Spec.Template.Spec.Containers[0].Command).To(ContainElements("--backup", "hourly", "--hosted-cluster", "test-hcp"))
+}
+
+func TestSaveDiagnostics(t *testing.T) {
+	g := NewWithT(t)
+	bp := newTestBackupPlugin()
+	daily := newTestBackup()
+	daily.Name = "daily"
+	hourly := newTestBackup()
+	hourly.Name = "hourly"
+
+	bp.saveDiagnostics(context.TODO(), daily, errors.New("first failure"))
+	g.Expect(bp.client.Delete(context.TODO(), &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: diagnostics.ConfigMapPrefix + "daily", Namespace: "openshift-adp"}})).To(Succeed())
+	// The following failures of the same backup keep the bundle of the first one
+	bp.saveDiagnostics(context.TODO(), daily, errors.New("second failure"))
+	bp.saveDiagnostics(context.TODO(), hourly, errors.New("failure of the next backup"))
+
+	cms := &corev1.ConfigMapList{}
+	g.Expect(bp.client.List(context.TODO(), cms, crclient.InNamespace("openshift-adp"))).To(Succeed())
+	g.Expect(cms.Items).To(HaveLen(1))
+	g.Expect(cms.Items[0].Name).To(Equal(diagnostics.ConfigMapPrefix + "hourly"))
+}
+
+// mockPauser implements common.Pauser for testing.
+type mockPauser struct {
+	unpauseErr error
+	unpaused   []string
+}
+
+func (m *mockPauser) Pause(_ context.Context, _, _, _ string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockPauser) Unpause(_ context.Context, namespace, name string) ([]string, error) {
+	m.unpaused = append(m.unpaused, namespace+"/"+name)
+	return nil, m.unpauseErr
+}
+
+func (m *mockPauser) WaitForPaused(_ context.Context, _, _ string, _ common.Timeouts) error {
+	return nil
+}
+
+func (m *mockPauser) WaitForUnpaused(_ context.Context, _, _ string, _ common.Timeouts) error {
+	return nil
+}
+
+// mockBackupWaiter implements common.BackupWaiter for testing.
+type mockBackupWaiter struct {
+	earlier []string
+	waitErr error
+	waited  bool
+}
+
+func (m *mockBackupWaiter) EarlierBackups(_ context.Context, _ *velerov1.Backup, _ string) ([]string, error) {
+	return m.earlier, nil
+}
+
+func (m *mockBackupWaiter) WaitForEarlierBackups(_ context.Context, _ *velerov1.Backup, _ string, _ common.Timeouts) error {
+	m.waited = true
+	return m.waitErr
+}
+
+// mockSnapshotWaiter implements common.SnapshotWaiter for testing.
+type mockSnapshotWaiter struct {
+	created     bool
+	resumed     bool
+	createErr   error
+	waitErr     error
+	snapshotURL string
+	cleanups    int
+}
+
+func (m *mockSnapshotWaiter) IsCreated() bool {
+	return m.created
+}
+
+func (m *mockSnapshotWaiter) Resume(_ context.Context, _ *velerov1.Backup, _ string) (bool, error) {
+	m.created = m.resumed
+	return m.resumed, nil
+}
+
+func (m *mockSnapshotWaiter) CreateEtcdBackup(_ context.Context, _ *velerov1.Backup, _ string, _ *hyperv1.HostedCluster) error {
+	m.created = m.createErr == nil
+	return m.createErr
+}
+
+func (m *mockSnapshotWaiter) VerifyInProgress(_ context.Context) error {
+	return nil
+}
+
+func (m *mockSnapshotWaiter) WaitForCompletion(_ context.Context) (string, error) {
+	return m.snapshotURL, m.waitErr
+}
+
+func (m *mockSnapshotWaiter) CleanupCredentialSecret(_ context.Context) error {
+	m.cleanups++
+	return nil
+}
+
+func TestExecuteWaiters(t *testing.T) {
+	hcpItem := func() *unstructured.Unstructured {
+		item := newUnstructuredItem("HostedControlPlane", "hypershift.openshift.io/v1beta1", "test-hcp", "clusters-test")
+		item.Object["spec"] = map[string]any{"platform": map[string]any{"type": "AWS"}}
+		return item
+	}
+	newEtcdSnapshotPlugin := func(waiter *mockSnapshotWaiter) *BackupPlugin {
+		bp := newTestBackupPlugin(&apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "hcpetcdbackups.hypershift.openshift.io"},
+		})
+		bp.etcdBackupMethod = common.EtcdBackupMethodEtcdSnapshot
+		bp.newSnapshotWaiter = func(string) common.SnapshotWaiter { return waiter }
+		return bp
+	}
+
+	t.Run("When the etcd snapshot completes, It Should annotate the HostedControlPlane with its URL", func(t *testing.T) {
+		g := NewWithT(t)
+		t.Setenv("POD_NAMESPACE", "openshift-adp")
+		waiter := &mockSnapshotWaiter{snapshotURL: "s3://bucket/snapshot.db"}
+		bp := newEtcdSnapshotPlugin(waiter)
+
+		result, _, err := bp.Execute(hcpItem(), newTestBackup())
+		g.Expect(err).NotTo(HaveOccurred())
+		metadata := result.UnstructuredContent()["metadata"].(map[string]any)
+		g.Expect(metadata["annotations"]).To(HaveKeyWithValue(common.EtcdSnapshotURLAnnotation, "s3://bucket/snapshot.db"))
+		g.Expect(waiter.cleanups).To(Equal(1))
+	})
+
+	t.Run("When the etcd snapshot cannot be requested, It Should clean up and fail the item", func(t *testing.T) {
+		g := NewWithT(t)
+		t.Setenv("POD_NAMESPACE", "openshift-adp")
+		waiter := &mockSnapshotWaiter{createErr: errors.New("no storage")}
+		bp := newEtcdSnapshotPlugin(waiter)
+
+		_, _, err := bp.Execute(hcpItem(), newTestBackup())
+		g.Expect(err).To(MatchError(ContainSubstring("error creating HCPEtcdBackup: no storage")))
+		g.Expect(waiter.cleanups).To(Equal(1))
+	})
+
+	t.Run("When the etcd snapshot fails for good, It Should clean up and fail the item", func(t *testing.T) {
+		g := NewWithT(t)
+		t.Setenv("POD_NAMESPACE", "openshift-adp")
+		waiter := &mockSnapshotWaiter{resumed: true, waitErr: errors.New("snapshot upload failed")}
+		bp := newEtcdSnapshotPlugin(waiter)
+
+		_, _, err := bp.Execute(hcpItem(), newTestBackup())
+		g.Expect(err).To(MatchError(ContainSubstring("HCPEtcdBackup failed: snapshot upload failed")))
+		g.Expect(waiter.cleanups).To(Equal(1))
+	})
+
+	t.Run("When the wait for an earlier backup fails, It Should fail the item", func(t *testing.T) {
+		g := NewWithT(t)
+		waiter := &mockBackupWaiter{earlier: []string{"openshift-adp/earlier-backup"}, waitErr: errors.New("interrupted")}
+		bp := newTestBackupPlugin()
+		bp.backupWaiter = waiter
+
+		_, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), newTestBackup())
+		g.Expect(err).To(MatchError(ContainSubstring("interrupted")))
+		g.Expect(waiter.waited).To(BeTrue())
+	})
+
+	t.Run("When no earlier backup runs, It Should back up the item without waiting", func(t *testing.T) {
+		g := NewWithT(t)
+		waiter := &mockBackupWaiter{}
+		bp := newTestBackupPlugin()
+		bp.backupWaiter = waiter
+
+		result, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), newTestBackup())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result).NotTo(BeNil())
+		g.Expect(waiter.waited).To(BeFalse())
+	})
+
+	t.Run("When the cluster paused beyond maxPauseDuration cannot be resumed, It Should fail the item", func(t *testing.T) {
+		g := NewWithT(t)
+		hc := &hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "clusters", Annotations: map[string]string{
+			common.BackupInProgressAnnotation: "test-backup",
+			common.BackupPausedAtAnnotation:   time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339),
+		}}}
+		pauser := &mockPauser{unpauseErr: errors.New("conflict")}
+		bp := newTestBackupPlugin(hc)
+		bp.BackupOptions = &plugtypes.BackupOptions{MaxPauseDuration: time.Hour}
+		bp.pauser = pauser
+
+		_, _, err := bp.Execute(newUnstructuredItem("ConfigMap", "v1", "first", "clusters-test"), newTestBackup())
+		g.Expect(err).To(MatchError(ContainSubstring("error resuming HostedCluster clusters/test")))
+		g.Expect(pauser.unpaused).To(ConsistOf("clusters/test"))
+	})
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	plugtypes "github.com/openshift/hypershift-oadp-plugin/pkg/core/types"
+	"github.com/openshift/hypershift-oadp-plugin/tests/benchmark/fakecluster"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// BenchmarkBackupExecute measures Execute of the backup plugin for the items of a simulated
+// cluster with thousands of VolumeSnapshotContents, reporting the API calls made per item
+// next to its latency. Calls that grow with the size of the cluster show up as a per item
+// cost growing with it.
+func BenchmarkBackupExecute(b *testing.B) {
+	for _, size := range []int{100, 1000, 5000} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			spec := fakecluster.New(size)
+			objects := spec.Objects()
+			c, calls := fakecluster.NewClient(objects...)
+			logger := logrus.New()
+			logger.SetLevel(logrus.WarnLevel)
+			p := &BackupPlugin{
+				log:              logger,
+				ctx:              context.Background(),
+				client:           c,
+				config:           map[string]string{},
+				validator:        &mockValidator{},
+				hcp:              spec.HostedControlPlane(),
+				BackupOptions:    &plugtypes.BackupOptions{},
+				hoNamespace:      "hypershift",
+				etcdBackupMethod: common.EtcdBackupMethodVolume,
+				pauser:           common.ClientPauser{Client: c},
+				backupWaiter:     common.ClientBackupWaiter{Client: c},
+			}
+			backup := spec.Backup()
+
+			var items []*unstructured.Unstructured
+			for _, obj := range objects {
+				content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+				if err != nil {
+					b.Fatal(err)
+				}
+				item := &unstructured.Unstructured{Object: content}
+				gvks, _, err := c.Scheme().ObjectKinds(obj)
+				if err != nil {
+					b.Fatal(err)
+				}
+				item.SetGroupVersionKind(gvks[0])
+				if item.GetKind() == "VolumeSnapshotContent" {
+					items = append(items, item)
+				}
+			}
+
+			calls.Reset()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				if _, _, err := p.Execute(items[i%len(items)].DeepCopy(), backup); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(calls.Total())/float64(b.N), "apicalls/op")
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	registerKindHandler(awsEndpointServiceHandler{}, common.AWSEndpointServiceGroupKind)
+}
+
+// awsEndpointServiceHandler keeps the PrivateLink wiring of private AWS clusters: the
+// endpoint service, VPC endpoint, security group and private DNS records recorded in the
+// AWSEndpointService status. The HyperShift controllers create new AWS resources for an
+// AWSEndpointService without status, leaving the previous ones behind.
+type awsEndpointServiceHandler struct{}
+
+// awsEndpointServiceWiring are the AWSEndpointService status fields naming AWS resources.
+var awsEndpointServiceWiring = []string{"endpointServiceName", "endpointID", "dnsNames", "dnsZoneID", "securityGroupID"}
+
+func (awsEndpointServiceHandler) Backup(_ context.Context, p *BackupPlugin, item runtime.Unstructured, _ *velerov1.Backup) (runtime.Unstructured, error) {
+	metadata, err := meta.Accessor(item)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	kept, err := keepStatus(item, common.AWSEndpointServiceStatusAnnotation, awsEndpointServiceWiring...)
+	if err != nil {
+		return nil, err
+	}
+	if !kept {
+		p.log.Infof("AWSEndpointService %s has no AWS resources yet", metadata.GetName())
+		return item, nil
+	}
+	p.log.Infof("Recorded the PrivateLink wiring of AWSEndpointService %s", metadata.GetName())
+	return item, nil
+}
+
+func (awsEndpointServiceHandler) Restore(_ context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	if p.AWS.RegenPrivateLink {
+		metadata, err := meta.Accessor(input.Item)
+		if err != nil {
+			return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+		}
+		common.RemoveAnnotation(metadata, common.AWSEndpointServiceStatusAnnotation)
+		p.log.Infof("AWSEndpointService %s restored without its PrivateLink wiring, HyperShift creates new AWS resources", itemName(input.Item))
+		return nil, nil
+	}
+	restored, err := restoreKeptStatus(input.Item, common.AWSEndpointServiceStatusAnnotation)
+	if err != nil {
+		return nil, err
+	}
+	if restored {
+		p.log.Infof("Restored the PrivateLink wiring into the status of AWSEndpointService %s", itemName(input.Item))
+	}
+	return nil, nil
+}
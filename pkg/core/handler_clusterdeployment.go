@@ -0,0 +1,50 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	hive "github.com/openshift/hive/apis/hive/v1"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/platform/agent"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	registerKindHandler(clusterDeploymentHandler{}, common.ClusterDeploymentGroupKind)
+}
+
+// clusterDeploymentHandler runs the Agent platform migration tasks on backup and keeps
+// Hive from deprovisioning the cluster during restore, unless agent.preserveOnDelete is
+// false.
+type clusterDeploymentHandler struct{}
+
+func (clusterDeploymentHandler) Backup(ctx context.Context, p *BackupPlugin, item runtime.Unstructured, backup *velerov1.Backup) (runtime.Unstructured, error) {
+	if p.hcp.Spec.Platform.Type == hyperv1.AgentPlatform {
+		if err := agent.MigrationTasks(ctx, item, p.client, p.log, p.config, backup); err != nil {
+			return nil, fmt.Errorf("error performing migration tasks for agent platform: %w", err)
+		}
+	}
+	return item, nil
+}
+
+func (clusterDeploymentHandler) Restore(ctx context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	if p.Agent.SkipPreserveOnDelete {
+		return nil, nil
+	}
+	clusterdDeployment := &hive.ClusterDeployment{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(input.Item.UnstructuredContent(), clusterdDeployment); err != nil {
+		return nil, fmt.Errorf("error converting item to clusterdDeployment: %w", err)
+	}
+
+	clusterDeploymentCP := clusterdDeployment.DeepCopy()
+	clusterDeploymentCP.Spec.PreserveOnDelete = true
+
+	if err := p.client.Update(ctx, clusterDeploymentCP); err != nil {
+		return nil, fmt.Errorf("error updating ClusterDeployment resource with PreserveOnDelete option: %w", err)
+	}
+	return nil, nil
+}
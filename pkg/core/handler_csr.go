@@ -0,0 +1,29 @@
+package core
+
+import (
+	"context"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	registerKindHandler(csrHandler{}, common.CSRGroupKind)
+}
+
+// csrHandler keeps CertificateSigningRequests out of backups and restores. They are issued
+// by and for the management cluster they were created in, a backup including cluster
+// resources would otherwise restore requests the target never received, approved or not.
+type csrHandler struct{}
+
+func (csrHandler) Backup(_ context.Context, p *BackupPlugin, item runtime.Unstructured, _ *velerov1.Backup) (runtime.Unstructured, error) {
+	p.log.Debugf("Excluding CertificateSigningRequest %s from backup", objectName(item))
+	return nil, nil
+}
+
+func (csrHandler) Restore(_ context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	p.log.Debugf("CertificateSigningRequest %s found, skipping restore", objectName(input.Item))
+	return velero.NewRestoreItemActionExecuteOutput(input.Item).WithoutRestore(), nil
+}
@@ -0,0 +1,22 @@
+package core
+
+import (
+	"context"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+)
+
+func init() {
+	registerKindHandler(deploymentHandler{}, common.DeploymentGroupKind)
+}
+
+// deploymentHandler restores Deployments after the PriorityClass of their pods.
+type deploymentHandler struct {
+	passThroughHandler
+}
+
+func (deploymentHandler) Restore(_ context.Context, _ *RestorePlugin, input *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	return restoreAfterPriorityClass(input)
+}
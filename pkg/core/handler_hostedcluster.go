@@ -0,0 +1,358 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/hooks"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	registerKindHandler(hostedClusterHandler{}, common.HostedClusterGroupKind)
+}
+
+// hostedClusterHandler flags the HostedCluster as restored from backup, carries the etcd
+// snapshot URL across the backup, optionally backs up the CRDs it needs and prepares the
+// namespaces on restore.
+type hostedClusterHandler struct{}
+
+func (hostedClusterHandler) Backup(ctx context.Context, p *BackupPlugin, item runtime.Unstructured, backup *velerov1.Backup) (runtime.Unstructured, error) {
+	metadata, err := meta.Accessor(item)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	common.AddAnnotation(metadata, common.HostedClusterRestoredFromBackupAnnotation, "")
+	p.log.Infof("Added restore annotation to HostedCluster %s", metadata.GetName())
+
+	if err := p.recordSourceMetadata(ctx, item, metadata, backup); err != nil {
+		return nil, err
+	}
+
+	// Etcd backup: create if not yet created (HC may arrive before HCP),
+	// wait for completion, and inject snapshotURL into the HC item.
+	// Velero captures the item as-is from the API server before the HCPEtcdBackup
+	// controller updates the HC status with lastSuccessfulEtcdBackupURL.
+	// We must inject it here so the backed-up HC contains the URL for restore.
+	if p.etcdBackupMethod == common.EtcdBackupMethodEtcdSnapshot {
+		if err := p.createEtcdBackup(ctx, backup); err != nil {
+			return nil, fmt.Errorf("error creating HCPEtcdBackup: %w", err)
+		}
+	}
+	if err := p.waitForEtcdBackupCompletion(ctx); err != nil {
+		return nil, err
+	}
+	if p.etcdSnapshotURL != "" {
+		// Persist as annotation so the restore plugin can read it
+		// (Velero strips status from items during restore)
+		common.AddAnnotation(metadata, common.EtcdSnapshotURLAnnotation, p.etcdSnapshotURL)
+		p.log.Infof("Added etcd snapshot URL annotation to HostedCluster %s: %s", metadata.GetName(), p.etcdSnapshotURL)
+
+		unstructuredContent := item.UnstructuredContent()
+		status, ok := unstructuredContent["status"].(map[string]interface{})
+		if !ok {
+			status = map[string]interface{}{}
+			unstructuredContent["status"] = status
+		}
+		status["lastSuccessfulEtcdBackupURL"] = p.etcdSnapshotURL
+		item.SetUnstructuredContent(unstructuredContent)
+		p.log.Infof("Injected lastSuccessfulEtcdBackupURL into HostedCluster %s: %s", metadata.GetName(), p.etcdSnapshotURL)
+
+		if err := p.hooks.Run(ctx, hooks.Payload{
+			Event:                  hooks.AfterSnapshots,
+			Backup:                 backup.Name,
+			HostedClusterName:      metadata.GetName(),
+			HostedClusterNamespace: metadata.GetNamespace(),
+			ControlPlaneNamespace:  p.hcp.Namespace,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return item, nil
+}
+
+// AdditionalItems adds the ConfigMaps of the trust bundles the HostedCluster references, so
+// they are restored with it whatever selects the backed up ConfigMaps. With the backupCRDs
+// option, it adds the CRDs of the HyperShift, cluster-api and platform resources served by
+// the management cluster, so restoring the backup declares every API the HostedCluster
+// needs. Velero restores CRDs before custom resources.
+func (hostedClusterHandler) AdditionalItems(ctx context.Context, p *BackupPlugin, item runtime.Unstructured, _ *velerov1.Backup) ([]velero.ResourceIdentifier, error) {
+	hc := &hyperv1.HostedCluster{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.UnstructuredContent(), hc); err != nil {
+		return nil, fmt.Errorf("error converting item to HostedCluster: %w", err)
+	}
+	items, err := p.trustBundleItems(ctx, hc)
+	if err != nil {
+		return nil, err
+	}
+	if !p.BackupCRDs {
+		return items, nil
+	}
+	crds, err := p.crdItems(ctx, hc.Spec.Platform.Type)
+	if err != nil {
+		return nil, err
+	}
+	p.log.Infof("Adding %d CRDs to the backup of HostedCluster %s", len(crds), objectName(item))
+	return append(items, crds...), nil
+}
+
+// trustBundleItems returns the existing ConfigMaps of the additionalTrustBundle and of the
+// proxy trustedCA of the HostedCluster. The control plane trusts them to pull images from
+// mirror registries and to egress through the proxy.
+func (p *BackupPlugin) trustBundleItems(ctx context.Context, hc *hyperv1.HostedCluster) ([]velero.ResourceIdentifier, error) {
+	var items []velero.ResourceIdentifier
+	for _, name := range common.TrustBundleConfigMaps(hc) {
+		if err := p.client.Get(ctx, crclient.ObjectKey{Namespace: hc.Namespace, Name: name}, &corev1.ConfigMap{}); err != nil {
+			if apierrors.IsNotFound(err) {
+				p.log.Warnf("ConfigMap %s/%s referenced by HostedCluster %s does not exist", hc.Namespace, name, hc.Name)
+				continue
+			}
+			return nil, fmt.Errorf("error getting ConfigMap %s/%s: %w", hc.Namespace, name, err)
+		}
+		items = append(items, velero.ResourceIdentifier{
+			GroupResource: schema.GroupResource{Resource: "configmaps"},
+			Namespace:     hc.Namespace,
+			Name:          name,
+		})
+	}
+	return items, nil
+}
+
+// crdItems returns the CRDs of the CRDGroups and of the PlatformCRDGroups of the platform.
+func (p *BackupPlugin) crdItems(ctx context.Context, platform hyperv1.PlatformType) ([]velero.ResourceIdentifier, error) {
+	groups := slices.Concat(common.CRDGroups, common.PlatformCRDGroups[platform])
+	crds := &apiextensionsv1.CustomResourceDefinitionList{}
+	if err := p.client.List(ctx, crds); err != nil {
+		return nil, fmt.Errorf("error listing CRDs: %w", err)
+	}
+	var items []velero.ResourceIdentifier
+	for _, crd := range crds.Items {
+		if !slices.ContainsFunc(groups, func(group string) bool {
+			return crd.Spec.Group == group || strings.HasSuffix(crd.Spec.Group, "."+group)
+		}) {
+			continue
+		}
+		items = append(items, velero.ResourceIdentifier{
+			GroupResource: schema.GroupResource{Group: apiextensionsv1.GroupName, Resource: "customresourcedefinitions"},
+			Name:          crd.Name,
+		})
+	}
+	return items, nil
+}
+
+func (hostedClusterHandler) Restore(ctx context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, backup *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	metadata, err := meta.Accessor(input.Item)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	common.AddAnnotation(metadata, common.HostedClusterRestoredFromBackupAnnotation, "")
+	hcName := metadata.GetName()
+	p.log.Infof("Added restore annotation to HostedCluster %s", hcName)
+
+	hc := &hyperv1.HostedCluster{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(input.Item.UnstructuredContent(), hc); err != nil {
+		return nil, fmt.Errorf("error converting item to HostedCluster: %w", err)
+	}
+	managed, err := p.checkManagedService(hc)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.ensureNamespaces(ctx, metadata.GetNamespace(), hcName); err != nil {
+		return nil, err
+	}
+	p.startNotificationWatcher(ctx, input.Restore.Name, hcName)
+
+	if err := p.checkSourceMetadata(ctx, metadata.GetAnnotations(), hc); err != nil {
+		return nil, err
+	}
+	if err := p.checkNetworking(ctx, metadata.GetAnnotations(), hc, backup); err != nil {
+		return nil, err
+	}
+	if p.CapacityCheck || managed {
+		p.checkCapacity(ctx, hc)
+	}
+
+	if p.ReleaseImageCheck || managed {
+		if err := p.checkReleaseImage(ctx, hc.Namespace, hc.Spec.PullSecret.Name, hc.Spec.Release.Image); err != nil {
+			return nil, err
+		}
+	}
+
+	// Inject restoreSnapshotURL if etcd backup URL is available.
+	// Read from annotation because Velero strips status during restore.
+	annotations := metadata.GetAnnotations()
+	snapshotURL := annotations[common.EtcdSnapshotURLAnnotation]
+	if snapshotURL != "" {
+		snapshotURL, err = p.signSnapshotURL(ctx, backup, snapshotURL, hcName)
+		if err != nil {
+			return nil, err
+		}
+
+		hc := &hyperv1.HostedCluster{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(input.Item.UnstructuredContent(), hc); err != nil {
+			return nil, fmt.Errorf("error converting item to HostedCluster: %w", err)
+		}
+		if hc.Spec.Etcd.Managed != nil {
+			hc.Spec.Etcd.Managed.Storage.RestoreSnapshotURL = []string{snapshotURL}
+			p.log.Infof("Injected restoreSnapshotURL into HostedCluster %s", hc.Name)
+
+			unstructuredHC, err := runtime.DefaultUnstructuredConverter.ToUnstructured(hc)
+			if err != nil {
+				return nil, fmt.Errorf("error converting HostedCluster to unstructured: %w", err)
+			}
+			input.Item.SetUnstructuredContent(unstructuredHC)
+		}
+	}
+
+	if p.RestorePaused && !common.IsPartialRestore(input.Restore) {
+		if err := p.markRestorePending(input.Item, common.HostedClusterKind); err != nil {
+			return nil, err
+		}
+	}
+	if p.DeletionProtection > 0 {
+		if err := p.protectFromDeletion(input.Item, common.HostedClusterKind); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.hooks.Run(ctx, hooks.Payload{
+		Event:                  hooks.AfterRestore,
+		Backup:                 backup.Name,
+		Restore:                input.Restore.Name,
+		HostedClusterName:      hcName,
+		HostedClusterNamespace: metadata.GetNamespace(),
+	}); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// recordSourceMetadata annotates the backed up HostedCluster with the environment it is
+// backed up from, for the restore to compare with its target. The Backup gets the same
+// annotation for visibility, on a best effort basis.
+func (p *BackupPlugin) recordSourceMetadata(ctx context.Context, item runtime.Unstructured, metadata metav1.Object, backup *velerov1.Backup) error {
+	hc := &hyperv1.HostedCluster{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.UnstructuredContent(), hc); err != nil {
+		return fmt.Errorf("error converting item to HostedCluster: %w", err)
+	}
+	source, err := common.BuildSourceMetadata(ctx, p.client, hc, p.hoNamespace)
+	if err != nil {
+		return common.TolerateError(p.log, p.TolerateErrors, common.TolerateSourceMetadata,
+			fmt.Errorf("error collecting source metadata: %w", err))
+	}
+	common.AddAnnotation(metadata, common.SourceMetadataAnnotation, source.String())
+
+	original := backup.DeepCopy()
+	common.AddAnnotation(backup, common.SourceMetadataAnnotation, source.String())
+	if err := p.client.Patch(ctx, backup, crclient.MergeFrom(original)); err != nil {
+		p.log.Warnf("Could not record source metadata on Backup %s: %v", backup.Name, err)
+	}
+	return nil
+}
+
+// checkManagedService reports whether the HostedCluster belongs to a managed service, ROSA
+// HCP or ARO HCP, and refuses to restore it unless the managedServices option is set: the
+// service owns the cluster, so the restore must be deliberate.
+func (p *RestorePlugin) checkManagedService(hc *hyperv1.HostedCluster) (bool, error) {
+	service := common.ManagedService(hc)
+	if service == "" {
+		return false, nil
+	}
+	if !p.ManagedServices {
+		return false, common.NewValidationError("HostedCluster %s/%s is a %s cluster: set %s to true to restore it",
+			hc.Namespace, hc.Name, service, common.ConfigKeyManagedServices)
+	}
+	p.log.Infof("HostedCluster %s/%s is a %s cluster, checking its release image and capacity", hc.Namespace, hc.Name, service)
+	return true, nil
+}
+
+// checkSourceMetadata compares the source environment recorded at backup time with the
+// target cluster. A target management cluster behind the source beyond managementVersionSkew,
+// or whose HyperShift Operator does not support the release of the HostedCluster, fails the
+// restore. Other mismatches are logged, or fail the restore with
+// sourceMismatchPolicy Fail. Backups without recorded metadata are not checked.
+func (p *RestorePlugin) checkSourceMetadata(ctx context.Context, annotations map[string]string, hc *hyperv1.HostedCluster) error {
+	source, err := common.ParseSourceMetadata(annotations)
+	if err != nil {
+		return err
+	}
+	if source == nil {
+		// Items of older schemas never recorded it, newer ones only when collecting it failed
+		if schema, _ := common.ParseBackupSchema(annotations); schema >= common.BackupSchemaSourceMetadata {
+			p.log.Warnf("HostedCluster %s/%s was backed up without its source metadata, the target environment is not compared", hc.Namespace, hc.Name)
+		}
+		return nil
+	}
+	if err := p.validator.ValidateManagementVersions(ctx, source, p.hoNamespace, p.ManagementVersionSkew); err != nil {
+		return err
+	}
+	if err := p.validator.ValidateReleaseVersion(ctx, source, p.hoNamespace); err != nil {
+		return err
+	}
+	mismatches, err := p.validator.ValidateSourceMetadata(ctx, source, hc, p.platforms)
+	if err != nil {
+		return err
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+	message := fmt.Sprintf("HostedCluster %s/%s target environment differs from the backup source: %s", hc.Namespace, hc.Name, strings.Join(mismatches, "; "))
+	if p.FailOnSourceMismatch {
+		return errors.New(message)
+	}
+	p.log.Warn(message)
+	return nil
+}
+
+// checkNetworking compares the networking of the HostedCluster with the target management
+// cluster and the OVN database volumes of its source with the backup. Conflicts are logged,
+// or fail the restore with sourceMismatchPolicy Fail.
+func (p *RestorePlugin) checkNetworking(ctx context.Context, annotations map[string]string, hc *hyperv1.HostedCluster, backup *velerov1.Backup) error {
+	source, err := common.ParseSourceMetadata(annotations)
+	if err != nil {
+		return err
+	}
+	problems, err := p.validator.ValidateNetworking(ctx, hc, source, backup)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	message := fmt.Sprintf("HostedCluster %s/%s networking conflicts with the target: %s", hc.Namespace, hc.Name, strings.Join(problems, "; "))
+	if p.FailOnSourceMismatch {
+		return errors.New(message)
+	}
+	p.log.Warn(message)
+	return nil
+}
+
+// checkCapacity warns when the target management cluster does not look able to schedule the
+// control plane of the restored HostedCluster. It is an estimate, so it never fails the restore.
+func (p *RestorePlugin) checkCapacity(ctx context.Context, hc *hyperv1.HostedCluster) {
+	problems, err := p.validator.ValidateCapacity(ctx, hc)
+	if err != nil {
+		p.log.Warnf("Could not check the capacity for the control plane of HostedCluster %s/%s: %v", hc.Namespace, hc.Name, err)
+		return
+	}
+	if len(problems) > 0 {
+		p.log.Warnf("HostedCluster %s/%s control plane may stay Pending after the restore: %s", hc.Namespace, hc.Name, strings.Join(problems, "; "))
+	}
+}
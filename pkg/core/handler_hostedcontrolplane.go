@@ -0,0 +1,318 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/guestsnapshot"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/platform/agent"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/platform/aws"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	registerKindHandler(hostedControlPlaneHandler{}, common.HostedControlPlaneGroupKind)
+}
+
+// hostedControlPlaneHandler validates the platform, drives the etcd snapshot on backup
+// and injects the signed snapshot URL on restore.
+type hostedControlPlaneHandler struct{}
+
+func (hostedControlPlaneHandler) Backup(ctx context.Context, p *BackupPlugin, item runtime.Unstructured, backup *velerov1.Backup) (runtime.Unstructured, error) {
+	hcp := &hyperv1.HostedControlPlane{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.UnstructuredContent(), hcp); err != nil {
+		return nil, fmt.Errorf("error converting item to HostedControlPlane: %w", err)
+	}
+
+	if err := p.validator.ValidatePlatformConfig(ctx, hcp, backup); err != nil {
+		return nil, fmt.Errorf("error checking platform configuration: %w", err)
+	}
+
+	if hcp.Spec.SecretEncryption != nil {
+		if err := p.recordSecretEncryption(ctx, item, hcp, backup); err != nil {
+			return nil, err
+		}
+	}
+
+	// The host inventory is captured right before the etcd snapshot, the closest the two
+	// get to a consistent point
+	if p.Agent.DatabaseSnapshot && hcp.Spec.Platform.Type == hyperv1.AgentPlatform {
+		namespace := p.Agent.ServiceNamespace
+		if namespace == "" {
+			namespace = agent.DefaultServiceNamespace
+		}
+		snapshot, err := agent.SnapshotDatabase(ctx, p.client, p.log, namespace, backup, p.Timeouts)
+		if err != nil {
+			return nil, fmt.Errorf("error snapshotting the assisted-service database: %w", err)
+		}
+		metadata, err := meta.Accessor(item)
+		if err != nil {
+			return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+		}
+		common.AddAnnotation(metadata, common.AgentDatabaseSnapshotAnnotation, snapshot)
+	}
+
+	// Etcd backup: create after validation, wait for completion
+	if p.etcdBackupMethod == common.EtcdBackupMethodEtcdSnapshot {
+		if err := p.createEtcdBackup(ctx, backup); err != nil {
+			return nil, fmt.Errorf("error creating HCPEtcdBackup: %w", err)
+		}
+	}
+	if err := p.waitForEtcdBackupCompletion(ctx); err != nil {
+		return nil, err
+	}
+	if p.etcdSnapshotURL != "" {
+		metadata, err := meta.Accessor(item)
+		if err != nil {
+			return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+		}
+		common.AddAnnotation(metadata, common.EtcdSnapshotURLAnnotation, p.etcdSnapshotURL)
+		p.log.Infof("Added etcd snapshot URL annotation to HostedControlPlane %s", metadata.GetName())
+	}
+
+	return item, nil
+}
+
+// AdditionalItems adds the Secrets needed to decrypt the etcd data, the konnectivity and
+// ignition server signers, and the PriorityClasses of the control plane pods to the backup,
+// so it stays restorable whatever selects the backed up Secrets and cluster resources. With the guestSnapshot option, it also captures the reference view of the
+// hosted cluster.
+func (hostedControlPlaneHandler) AdditionalItems(ctx context.Context, p *BackupPlugin, _ runtime.Unstructured, backup *velerov1.Backup) ([]velero.ResourceIdentifier, error) {
+	items, err := p.encryptionSecretItems(ctx, backup)
+	if err != nil {
+		return nil, err
+	}
+	signers, err := p.signerSecretItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, signers...)
+	priorityClasses, err := p.priorityClassItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, priorityClasses...)
+	if p.GuestSnapshot {
+		if cm := p.saveGuestSnapshot(ctx, backup); cm != nil {
+			items = append(items, velero.ResourceIdentifier{
+				GroupResource: schema.GroupResource{Resource: "configmaps"},
+				Namespace:     cm.Namespace,
+				Name:          cm.Name,
+			})
+		}
+	}
+	return items, nil
+}
+
+func (hostedControlPlaneHandler) Restore(ctx context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, backup *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	hcp := &hyperv1.HostedControlPlane{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(input.Item.UnstructuredContent(), hcp); err != nil {
+		return nil, fmt.Errorf("error converting item to HostedControlPlane: %w", err)
+	}
+	if err := p.validator.ValidatePlatformConfig(ctx, hcp, p.config); err != nil {
+		return nil, fmt.Errorf("error checking platform configuration: %w", err)
+	}
+	if err := p.validator.ValidatePlatformCRDs(ctx, hcp.Spec.Platform.Type); err != nil {
+		return nil, fmt.Errorf("error checking platform CRDs: %w", err)
+	}
+
+	if err := common.EnsureNamespace(ctx, p.client, p.log, hcp.Namespace, common.ControlPlaneNamespaceLabels); err != nil {
+		return nil, fmt.Errorf("error ensuring HostedControlPlane namespace: %w", err)
+	}
+
+	metadata, err := meta.Accessor(input.Item)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	annotations := metadata.GetAnnotations()
+	if annotations[common.SecretEncryptionAnnotation] != "" {
+		if err := p.verifyEncryptionKeys(ctx, backup, annotations, hcp); err != nil {
+			return nil, fmt.Errorf("etcd data of HostedControlPlane %s cannot be decrypted: %w", objectName(input.Item), err)
+		}
+	}
+	snapshotURL := annotations[common.EtcdSnapshotURLAnnotation]
+	if snapshotURL != "" {
+		snapshotURL, err = p.signSnapshotURL(ctx, backup, snapshotURL, hcp.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if hcp.Spec.Etcd.Managed != nil {
+			hcp.Spec.Etcd.Managed.Storage.RestoreSnapshotURL = []string{snapshotURL}
+			p.log.Infof("Injected restoreSnapshotURL into HostedControlPlane %s", hcp.Name)
+
+			unstructuredHCP, err := runtime.DefaultUnstructuredConverter.ToUnstructured(hcp)
+			if err != nil {
+				return nil, fmt.Errorf("error converting HostedControlPlane to unstructured: %w", err)
+			}
+			input.Item.SetUnstructuredContent(unstructuredHCP)
+		}
+	}
+
+	// Partial restores (e.g. a single deleted NodePool) must leave the pause state alone
+	if p.RestorePaused && !common.IsPartialRestore(input.Restore) {
+		if err := p.markRestorePending(input.Item, common.HostedControlPlaneKind); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// recordSecretEncryption checks the control plane has the Secrets needed to decrypt its
+// etcd data, and annotates the backed up HostedControlPlane with the secret encryption and
+// the fingerprints of its aescbc keys, verified before its restore. The Backup gets the
+// encryption annotation for visibility, on a best effort basis.
+func (p *BackupPlugin) recordSecretEncryption(ctx context.Context, item runtime.Unstructured, hcp *hyperv1.HostedControlPlane, backup *velerov1.Backup) error {
+	spec := hcp.Spec.SecretEncryption
+	if err := common.CheckEncryptionSecrets(ctx, p.client, hcp.Namespace, spec, nil); err != nil {
+		return fmt.Errorf("the backup could not be decrypted on restore: %w", err)
+	}
+	fingerprints, err := common.EncryptionKeyFingerprints(ctx, p.client, hcp.Namespace, spec)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := meta.Accessor(item)
+	if err != nil {
+		return fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	encryption := common.SecretEncryptionType(spec)
+	common.AddAnnotation(metadata, common.SecretEncryptionAnnotation, encryption)
+	if len(fingerprints) > 0 {
+		data, err := json.Marshal(fingerprints)
+		if err != nil {
+			return fmt.Errorf("error encoding encryption key fingerprints: %w", err)
+		}
+		common.AddAnnotation(metadata, common.SecretEncryptionKeysAnnotation, string(data))
+	}
+
+	if backup.Annotations[common.SecretEncryptionAnnotation] == encryption {
+		return nil
+	}
+	original := backup.DeepCopy()
+	common.AddAnnotation(backup, common.SecretEncryptionAnnotation, encryption)
+	if err := p.client.Patch(ctx, backup, crclient.MergeFrom(original)); err != nil {
+		p.log.Warnf("Could not record secret encryption on Backup %s: %v", backup.Name, err)
+	}
+	return nil
+}
+
+// encryptionSecretItems returns the Secrets needed to decrypt the etcd data: the copies the
+// control plane uses, and the ones the HostedCluster references, which the HyperShift
+// Operator copies from after a restore.
+func (p *BackupPlugin) encryptionSecretItems(ctx context.Context, backup *velerov1.Backup) ([]velero.ResourceIdentifier, error) {
+	spec := p.hcp.Spec.SecretEncryption
+	if spec == nil {
+		return nil, nil
+	}
+	var items []velero.ResourceIdentifier
+	for _, name := range common.EncryptionSecrets(spec) {
+		items = append(items, secretItem(p.hcp.Namespace, name))
+	}
+
+	hc, err := common.GetHostedCluster(ctx, p.client, backup.Spec.IncludedNamespaces, p.hcp.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error getting HostedCluster: %w", err)
+	}
+	if hc != nil {
+		for _, name := range common.EncryptionSecrets(hc.Spec.SecretEncryption) {
+			items = append(items, secretItem(hc.Namespace, name))
+		}
+	}
+	p.log.Infof("Adding %d %s encryption Secrets to backup %s", len(items), common.SecretEncryptionType(spec), backup.Name)
+	return items, nil
+}
+
+// saveGuestSnapshot captures the reference view of the hosted cluster. The snapshot is only
+// informative: failing to capture it is logged and returns nil.
+func (p *BackupPlugin) saveGuestSnapshot(ctx context.Context, backup *velerov1.Backup) *corev1.ConfigMap {
+	guest, err := p.newGuestClient(ctx, p.client, p.hcp.Namespace)
+	if err != nil {
+		p.log.Warnf("Could not capture the guest cluster snapshot of backup %s: %v", backup.Name, err)
+		return nil
+	}
+	cm, err := guestsnapshot.Save(ctx, p.client, p.hcp.Namespace, backup, guestsnapshot.Collect(ctx, guest))
+	if err != nil {
+		p.log.Warnf("Could not save the guest cluster snapshot of backup %s: %v", backup.Name, err)
+		return nil
+	}
+	p.log.Infof("Guest cluster snapshot of backup %s saved in ConfigMap %s/%s", backup.Name, cm.Namespace, cm.Name)
+	return cm
+}
+
+// signerSecrets are the signers of the konnectivity and ignition server certificates. The
+// konnectivity agents and the nodes of the guest cluster trust them, so a control plane
+// restored without them issues certificates its guest cluster rejects.
+var signerSecrets = []string{common.KonnectivitySignerSecret, common.IgnitionServerCASecret}
+
+// signerSecretItems returns the signerSecrets of the control plane namespace, those that
+// exist: the ignition server is disabled on some clusters.
+func (p *BackupPlugin) signerSecretItems(ctx context.Context) ([]velero.ResourceIdentifier, error) {
+	var items []velero.ResourceIdentifier
+	for _, name := range signerSecrets {
+		secret := &corev1.Secret{}
+		if err := p.client.Get(ctx, crclient.ObjectKey{Namespace: p.hcp.Namespace, Name: name}, secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("error getting Secret %s/%s: %w", p.hcp.Namespace, name, err)
+		}
+		items = append(items, secretItem(p.hcp.Namespace, name))
+	}
+	return items, nil
+}
+
+func secretItem(namespace, name string) velero.ResourceIdentifier {
+	return velero.ResourceIdentifier{
+		GroupResource: schema.GroupResource{Resource: "secrets"},
+		Namespace:     namespace,
+		Name:          name,
+	}
+}
+
+// verifyEncryptionKeys checks, before the etcd data is restored, that the restored control
+// plane can decrypt it. Velero restores Secrets before custom resources, so the encryption
+// Secrets must be in place and hold the backed up aescbc keys, rather than keys of a Secret
+// that already existed. The active AWS KMS key must exist and be enabled; when the plugin
+// credentials cannot describe it, a warning is logged.
+func (p *RestorePlugin) verifyEncryptionKeys(ctx context.Context, backup *velerov1.Backup, annotations map[string]string, hcp *hyperv1.HostedControlPlane) error {
+	var fingerprints map[string]string
+	if data := annotations[common.SecretEncryptionKeysAnnotation]; data != "" {
+		if err := json.Unmarshal([]byte(data), &fingerprints); err != nil {
+			return fmt.Errorf("error parsing %s annotation: %w", common.SecretEncryptionKeysAnnotation, err)
+		}
+	}
+	spec := hcp.Spec.SecretEncryption
+	if err := common.CheckEncryptionSecrets(ctx, p.client, hcp.Namespace, spec, fingerprints); err != nil {
+		return err
+	}
+
+	if spec == nil || spec.KMS == nil || spec.KMS.Provider != hyperv1.AWS || spec.KMS.AWS == nil {
+		return nil
+	}
+	kms := spec.KMS.AWS
+	creds, err := p.awsCredentials(ctx, backup, fmt.Sprintf("restore-%s", hcp.Name))
+	if err == nil {
+		err = aws.CheckKMSKey(ctx, p.newKMSClient(), *creds, kms.Region, kms.ActiveKey.ARN)
+	}
+	var validationErr *common.ValidationError
+	if errors.As(err, &validationErr) {
+		return err
+	}
+	if err != nil {
+		p.log.Warnf("Could not verify the KMS key of HostedControlPlane %s, the control plane may not decrypt its etcd data: %v", hcp.Name, err)
+	}
+	return nil
+}
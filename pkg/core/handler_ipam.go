@@ -0,0 +1,46 @@
+package core
+
+import (
+	"context"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	registerKindHandler(ipClaimHandler{}, common.IPAddressClaimGroupKind, common.Metal3IPClaimGroupKind)
+}
+
+// ipClaimHandler keeps the addresses allocated to Machines on bare metal and agent clusters
+// using CAPI or metal3 IPAM. The IPAddress objects are restored as they are, with their
+// owner references to the claims repaired, but a claim restored without status would not
+// point to its address and the IPAM provider could allocate a new one.
+type ipClaimHandler struct{}
+
+// ipClaimAddress are the status fields pointing a CAPI IPAddressClaim (addressRef) or a
+// metal3 IPClaim (address) to its IPAddress.
+var ipClaimAddress = []string{"addressRef", "address"}
+
+func (ipClaimHandler) Backup(_ context.Context, p *BackupPlugin, item runtime.Unstructured, _ *velerov1.Backup) (runtime.Unstructured, error) {
+	kept, err := keepStatus(item, common.IPClaimStatusAnnotation, ipClaimAddress...)
+	if err != nil {
+		return nil, err
+	}
+	if kept {
+		p.log.Infof("Recorded the address allocated to %s", itemName(item))
+	}
+	return item, nil
+}
+
+func (ipClaimHandler) Restore(_ context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	restored, err := restoreKeptStatus(input.Item, common.IPClaimStatusAnnotation)
+	if err != nil {
+		return nil, err
+	}
+	if restored {
+		p.log.Infof("Restored the address allocated to %s", itemName(input.Item))
+	}
+	return nil, nil
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"strings"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func init() {
+	registerKindHandler(machineHandler{}, common.MachineGroupKind)
+}
+
+// machineHandler re-adopts the cloud instances of restored CAPI Machines with the
+// readoptNodes option, from the machine nodes ConfigMap recorded by the NodePool backup.
+// The ConfigMap is restored before the Machines.
+type machineHandler struct {
+	passThroughHandler
+}
+
+func (machineHandler) Restore(ctx context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	if !p.ReadoptNodes {
+		return nil, nil
+	}
+	machine := &unstructured.Unstructured{Object: input.Item.UnstructuredContent()}
+	if machine.GroupVersionKind().Group != "cluster.x-k8s.io" {
+		return nil, nil
+	}
+	_, nodePool, found := strings.Cut(machine.GetAnnotations()[common.NodePoolAnnotation], "/")
+	if !found {
+		return nil, nil
+	}
+
+	nodes, err := common.MachineNodes(ctx, p.client, machine.GetNamespace(), nodePool)
+	if err != nil {
+		return nil, err
+	}
+	node, ok := nodes[machine.GetName()]
+	if !ok {
+		p.log.Infof("No cloud instance recorded for Machine %s of NodePool %s, it is reprovisioned", objectName(input.Item), nodePool)
+		return nil, nil
+	}
+
+	if err := unstructured.SetNestedField(machine.Object, node.ProviderID, "spec", "providerID"); err != nil {
+		return nil, err
+	}
+	if node.NodeName != "" {
+		// Applied when the Restore lists machines in restoreStatus.includedResources, the
+		// Machine controller finds the Node from the providerID otherwise
+		if err := unstructured.SetNestedMap(machine.Object, map[string]any{"apiVersion": "v1", "kind": "Node", "name": node.NodeName}, "status", "nodeRef"); err != nil {
+			return nil, err
+		}
+	}
+	input.Item.SetUnstructuredContent(machine.Object)
+	p.log.Infof("Machine %s re-adopts instance %s of Node %s", objectName(input.Item), node.ProviderID, node.NodeName)
+	return nil, nil
+}
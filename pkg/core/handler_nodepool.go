@@ -0,0 +1,83 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func init() {
+	registerKindHandler(nodePoolHandler{}, common.NodePoolGroupKind)
+}
+
+// nodePoolHandler records the Machines of the NodePool on backup, and checks the NodePool
+// release image and its HostedCluster on restore.
+type nodePoolHandler struct {
+	passThroughHandler
+}
+
+// AdditionalItems records the cloud instance and Node of the Machines of the NodePool in
+// the machine nodes ConfigMap of the control plane namespace, and adds it to the backup,
+// so a restore with readoptNodes can point the restored Machines to them.
+func (nodePoolHandler) AdditionalItems(ctx context.Context, p *BackupPlugin, item runtime.Unstructured, _ *velerov1.Backup) ([]velero.ResourceIdentifier, error) {
+	metadata, err := meta.Accessor(item)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	cm, err := common.RecordMachineNodes(ctx, p.client, p.hcp.Namespace, types.NamespacedName{Namespace: metadata.GetNamespace(), Name: metadata.GetName()})
+	if err != nil {
+		return nil, err
+	}
+	return []velero.ResourceIdentifier{{
+		GroupResource: schema.GroupResource{Resource: "configmaps"},
+		Namespace:     cm.Namespace,
+		Name:          cm.Name,
+	}}, nil
+}
+
+func (nodePoolHandler) Restore(ctx context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	nodePool := &hyperv1.NodePool{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(input.Item.UnstructuredContent(), nodePool); err != nil {
+		return nil, fmt.Errorf("error converting item to NodePool: %w", err)
+	}
+	partialRestore := common.IsPartialRestore(input.Restore)
+
+	if p.ReleaseImageCheck {
+		// The HostedCluster is restored before its NodePools, use its pull secret
+		pullSecretName := ""
+		hc := &hyperv1.HostedCluster{}
+		if err := p.client.Get(ctx, types.NamespacedName{Name: nodePool.Spec.ClusterName, Namespace: nodePool.Namespace}, hc); err != nil {
+			p.log.Warnf("Could not get HostedCluster %s for NodePool %s, checking release image without credentials: %v", nodePool.Spec.ClusterName, nodePool.Name, err)
+		} else {
+			pullSecretName = hc.Spec.PullSecret.Name
+		}
+
+		if err := p.checkReleaseImage(ctx, nodePool.Namespace, pullSecretName, nodePool.Spec.Release.Image); err != nil {
+			return nil, err
+		}
+	}
+
+	if partialRestore {
+		hc := &hyperv1.HostedCluster{}
+		if err := p.client.Get(ctx, types.NamespacedName{Name: nodePool.Spec.ClusterName, Namespace: nodePool.Namespace}, hc); err != nil {
+			return nil, fmt.Errorf("partial restore of NodePool %s requires its HostedCluster %s to exist: %w", nodePool.Name, nodePool.Spec.ClusterName, err)
+		}
+		p.log.Infof("Partial restore, NodePool %s joins the existing HostedCluster %s", nodePool.Name, nodePool.Spec.ClusterName)
+	}
+
+	if p.RestorePaused && !partialRestore {
+		if err := p.markRestorePending(input.Item, common.NodePoolKind); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,164 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	registerKindHandler(podHandler{}, common.PodGroupKind)
+}
+
+// podHandler handles the volumes of the etcd pods and of the fsBackupPods on backup. On
+// restore, pods are skipped by default as their controllers recreate them, following the
+// podRestorePolicy.
+type podHandler struct{}
+
+func (podHandler) Backup(_ context.Context, p *BackupPlugin, item runtime.Unstructured, backup *velerov1.Backup) (runtime.Unstructured, error) {
+	metadata, err := meta.Accessor(item)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+
+	labelFSBackup := fsBackupDisabled(backup) || p.autoFSBackup
+	if labelFSBackup && metadata.GetNamespace() == p.hcp.Namespace {
+		for _, prefix := range slices.Sorted(maps.Keys(p.FSBackupPods)) {
+			volumes := p.FSBackupPods[prefix]
+			if !strings.HasPrefix(metadata.GetName(), prefix) {
+				continue
+			}
+			p.log.Infof("Labeling pod %s for fs-backup (fsBackupPods %s)", objectName(item), prefix)
+			common.AddLabel(metadata, common.FSBackupLabelName, "true")
+			if len(volumes) > 0 {
+				addBackupVolumes(metadata, volumes)
+			}
+		}
+	}
+
+	if strings.Contains(metadata.GetName(), "etcd-") {
+		switch p.etcdBackupMethod {
+		case common.EtcdBackupMethodEtcdSnapshot:
+			// Skip etcd pods entirely, snapshot is handled by HCPEtcdBackup.
+			// This prevents both FSBackup and CSI VolumeSnapshots of etcd volumes.
+			p.log.Infof("Skipping etcd pod %s from backup (using etcdSnapshot method)", metadata.GetName())
+			return nil, nil
+		case common.EtcdBackupMethodVolume:
+			if fsBackupDisabled(backup) {
+				common.AddLabel(metadata, common.FSBackupLabelName, "true")
+			}
+		}
+	}
+
+	if p.autoFSBackup && metadata.GetNamespace() == p.hcp.Namespace {
+		// CSI snapshots cannot back up the control plane volumes, opt every PVC backed
+		// volume into fs-backup whatever the default of the Velero server
+		volumes, err := pvcVolumes(item)
+		if err != nil {
+			return nil, err
+		}
+		if len(volumes) > 0 {
+			p.log.Infof("Opting the volumes %v of pod %s into fs-backup (volumeBackupModePolicy %s)", volumes, objectName(item), common.VolumeBackupModePolicyAuto)
+			common.AddLabel(metadata, common.FSBackupLabelName, "true")
+			addBackupVolumes(metadata, volumes)
+		}
+	}
+
+	return item, nil
+}
+
+func (podHandler) Restore(ctx context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	metadata, err := meta.Accessor(input.Item)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+
+	switch p.PodRestorePolicy {
+	case common.PodRestorePolicySkipNone:
+		return nil, nil
+	case common.PodRestorePolicySkipControlPlane:
+		controlPlane, err := isControlPlaneNamespace(ctx, p.client, metadata.GetNamespace())
+		if err != nil {
+			return nil, err
+		}
+		if !controlPlane {
+			return nil, nil
+		}
+	}
+
+	p.log.Debugf("Pod %s/%s found, skipping restore", metadata.GetNamespace(), metadata.GetName())
+	return velero.NewRestoreItemActionExecuteOutput(input.Item).WithoutRestore(), nil
+}
+
+// fsBackupDisabled reports whether the backup explicitly uses CSI snapshots by default, so
+// the volumes backed up with fs-backup have to be labeled.
+func fsBackupDisabled(backup *velerov1.Backup) bool {
+	return backup.Spec.DefaultVolumesToFsBackup != nil && !*backup.Spec.DefaultVolumesToFsBackup
+}
+
+// pvcVolumes returns the names of the pod volumes backed by a PersistentVolumeClaim.
+func pvcVolumes(item runtime.Unstructured) ([]string, error) {
+	volumes, _, err := unstructured.NestedSlice(item.UnstructuredContent(), "spec", "volumes")
+	if err != nil {
+		return nil, fmt.Errorf("error reading volumes of pod %s: %w", objectName(item), err)
+	}
+	var names []string
+	for _, volume := range volumes {
+		volume, ok := volume.(map[string]any)
+		if !ok {
+			continue
+		}
+		if _, ok := volume["persistentVolumeClaim"]; ok {
+			if name, ok := volume["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// addBackupVolumes opts the volumes into fs-backup through the Velero backup-volumes
+// annotation, keeping the volumes already listed there.
+func addBackupVolumes(metadata metav1.Object, volumes []string) {
+	annotations := metadata.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	var listed []string
+	if current := annotations[common.BackupVolumesAnnotation]; current != "" {
+		listed = strings.Split(current, ",")
+	}
+	for _, volume := range volumes {
+		if !slices.Contains(listed, volume) {
+			listed = append(listed, volume)
+		}
+	}
+	annotations[common.BackupVolumesAnnotation] = strings.Join(listed, ",")
+	metadata.SetAnnotations(annotations)
+}
+
+// isControlPlaneNamespace reports whether the namespace hosts a control plane, as flagged
+// by the HostedControlPlane namespace label.
+func isControlPlaneNamespace(ctx context.Context, c crclient.Client, name string) (bool, error) {
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, crclient.ObjectKey{Name: name}, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error getting namespace %s: %w", name, err)
+	}
+	return ns.Labels[common.HostedControlPlaneNamespaceLabel] == "true", nil
+}
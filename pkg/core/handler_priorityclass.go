@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	registerKindHandler(priorityClassHandler{}, common.PriorityClassGroupKind)
+}
+
+// priorityClassHandler restores the PriorityClasses of the control plane pods, e.g.
+// hypershift-control-plane and hypershift-etcd, the ones the target does not have yet. The
+// HyperShift Operator of the target usually created them, and their value is immutable.
+type priorityClassHandler struct {
+	passThroughHandler
+}
+
+func (priorityClassHandler) Restore(ctx context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	name := objectName(input.Item)
+	if err := p.client.Get(ctx, crclient.ObjectKey{Name: name}, &schedulingv1.PriorityClass{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting PriorityClass %s: %w", name, err)
+	}
+	p.log.Infof("PriorityClass %s exists on the target, skipping restore", name)
+	return velero.NewRestoreItemActionExecuteOutput(input.Item).WithoutRestore(), nil
+}
+
+// priorityClassItem returns the PriorityClass the pod template of a Deployment or
+// StatefulSet item runs with, unless none or a built-in system one.
+func priorityClassItem(item runtime.Unstructured) (*velero.ResourceIdentifier, error) {
+	name, _, err := unstructured.NestedString(item.UnstructuredContent(), "spec", "template", "spec", "priorityClassName")
+	if err != nil {
+		return nil, fmt.Errorf("error reading the priorityClassName of %s: %w", itemName(item), err)
+	}
+	if name == "" || strings.HasPrefix(name, "system-") {
+		return nil, nil
+	}
+	return &velero.ResourceIdentifier{
+		GroupResource: schema.GroupResource{Group: schedulingv1.GroupName, Resource: "priorityclasses"},
+		Name:          name,
+	}, nil
+}
+
+// priorityClassItems returns the PriorityClasses the Deployments and StatefulSets of the
+// control plane namespace run their pods with. They are cluster-scoped, so a backup of the
+// namespaces leaves them out, and a management cluster without them cannot run the
+// restored control plane.
+func (p *BackupPlugin) priorityClassItems(ctx context.Context) ([]velero.ResourceIdentifier, error) {
+	var templates []corev1.PodTemplateSpec
+	deployments := &appsv1.DeploymentList{}
+	if err := p.client.List(ctx, deployments, crclient.InNamespace(p.hcp.Namespace)); err != nil {
+		return nil, fmt.Errorf("error listing the Deployments of namespace %s: %w", p.hcp.Namespace, err)
+	}
+	for _, deployment := range deployments.Items {
+		templates = append(templates, deployment.Spec.Template)
+	}
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := p.client.List(ctx, statefulSets, crclient.InNamespace(p.hcp.Namespace)); err != nil {
+		return nil, fmt.Errorf("error listing the StatefulSets of namespace %s: %w", p.hcp.Namespace, err)
+	}
+	for _, statefulSet := range statefulSets.Items {
+		templates = append(templates, statefulSet.Spec.Template)
+	}
+
+	names := sets.New[string]()
+	for _, template := range templates {
+		if name := template.Spec.PriorityClassName; name != "" && !strings.HasPrefix(name, "system-") {
+			names.Insert(name)
+		}
+	}
+	var items []velero.ResourceIdentifier
+	for _, name := range sets.List(names) {
+		items = append(items, velero.ResourceIdentifier{
+			GroupResource: schema.GroupResource{Group: schedulingv1.GroupName, Resource: "priorityclasses"},
+			Name:          name,
+		})
+	}
+	return items, nil
+}
+
+// restoreAfterPriorityClass restores the item after the PriorityClass of its pod template.
+// Velero restores PriorityClasses after the workloads otherwise, whose pods are rejected
+// until it exists.
+func restoreAfterPriorityClass(input *velero.RestoreItemActionExecuteInput) (*velero.RestoreItemActionExecuteOutput, error) {
+	priorityClass, err := priorityClassItem(input.Item)
+	if err != nil || priorityClass == nil {
+		return nil, err
+	}
+	output := velero.NewRestoreItemActionExecuteOutput(input.Item)
+	output.AdditionalItems = []velero.ResourceIdentifier{*priorityClass}
+	return output, nil
+}
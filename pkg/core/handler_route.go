@@ -0,0 +1,43 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func init() {
+	registerKindHandler(routeHandler{}, common.RouteGroupKind)
+}
+
+// routeHandler restores the Routes publishing the control plane, e.g. the oauth and
+// konnectivity ones. A host the router generated belongs to the apps domain of the source
+// management cluster, so it is cleared for the router of the target to generate one. Hosts
+// set from the HostedCluster service publishing strategy are kept; the migration-modifiers
+// command rewrites them along with the HostedCluster.
+type routeHandler struct {
+	passThroughHandler
+}
+
+func (routeHandler) Restore(_ context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	metadata, err := meta.Accessor(input.Item)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	if metadata.GetAnnotations()[common.RouteHostGeneratedAnnotation] != "true" {
+		return nil, nil
+	}
+
+	content := input.Item.UnstructuredContent()
+	host, _, _ := unstructured.NestedString(content, "spec", "host")
+	unstructured.RemoveNestedField(content, "spec", "host")
+	unstructured.RemoveNestedField(content, "metadata", "annotations", common.RouteHostGeneratedAnnotation)
+	input.Item.SetUnstructuredContent(content)
+	p.log.Infof("Cleared the generated host %s of Route %s, the target router generates a new one", host, objectName(input.Item))
+	return nil, nil
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/secretcheck"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	registerKindHandler(secretHandler{}, common.SecretGroupKind)
+}
+
+// secretHandler checks the certificates of restored Secrets, following the
+// certificateExpiryPolicy. A backup kept for long restores a control plane whose
+// certificates have expired meanwhile, and its components fail with TLS errors. The
+// konnectivity and ignition server Secrets of the control plane are checked and, when
+// tied to an endpoint that no longer holds, left for HyperShift to regenerate.
+type secretHandler struct {
+	passThroughHandler
+}
+
+func (secretHandler) Restore(ctx context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	secret := &corev1.Secret{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(input.Item.UnstructuredContent(), secret); err != nil {
+		return nil, fmt.Errorf("error converting item to Secret: %w", err)
+	}
+	if endpointSecret(secret.Name) {
+		controlPlane, err := isControlPlaneNamespace(ctx, p.client, secret.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		if controlPlane {
+			if reason := p.regeneratedSecret(secret, time.Now()); reason != "" {
+				p.log.Infof("Not restoring Secret %s/%s, %s", secret.Namespace, secret.Name, reason)
+				return velero.NewRestoreItemActionExecuteOutput(input.Item).WithoutRestore(), nil
+			}
+			if slices.Contains(signerSecrets, secret.Name) && len(secret.Data["ca.key"]) == 0 && len(secret.Data[corev1.TLSPrivateKeyKey]) == 0 {
+				p.log.Warnf("Signer Secret %s/%s has no private key, the control plane operator cannot issue certificates the guest cluster trusts", secret.Namespace, secret.Name)
+			}
+		}
+	}
+	threshold := p.CertificateExpiryThreshold
+	if threshold == 0 {
+		threshold = secretcheck.DefaultExpiryThreshold
+	}
+	expiring, err := secretcheck.ExpiringCertificates(secret.Data, time.Now(), threshold)
+	if err != nil {
+		p.log.Warnf("Could not check the certificates of Secret %s/%s: %v", secret.Namespace, secret.Name, err)
+		return nil, nil
+	}
+	if len(expiring) == 0 {
+		return nil, nil
+	}
+
+	var expired bool
+	descriptions := make([]string, 0, len(expiring))
+	for _, e := range expiring {
+		expired = expired || e.Expired
+		descriptions = append(descriptions, e.String())
+	}
+	problem := fmt.Sprintf("Secret %s/%s: %s", secret.Namespace, secret.Name, strings.Join(descriptions, ", "))
+
+	switch p.CertificateExpiryPolicy {
+	case common.CertificateExpiryPolicyFail:
+		if expired {
+			return nil, common.NewValidationError("%s, refusing to restore it (%s %s)", problem, common.ConfigKeyCertificateExpiryPolicy, common.CertificateExpiryPolicyFail)
+		}
+	case common.CertificateExpiryPolicyRotate:
+		// The control plane operator issues the certificates of its namespace that are missing
+		controlPlane, err := isControlPlaneNamespace(ctx, p.client, secret.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		if controlPlane {
+			p.log.Infof("%s, not restoring it so the control plane operator issues new certificates", problem)
+			return velero.NewRestoreItemActionExecuteOutput(input.Item).WithoutRestore(), nil
+		}
+	}
+	p.log.Warn(problem)
+	return nil, nil
+}
+
+// endpointSecret reports whether the Secret name is one of the konnectivity and ignition
+// server Secrets, which are only acted on in the control plane namespace.
+func endpointSecret(name string) bool {
+	return slices.Contains(signerSecrets, name) || name == common.KonnectivityServerSecret || name == common.IgnitionServerServingCertSecret ||
+		strings.HasPrefix(name, common.IgnitionTokenSecretPrefix) || strings.HasPrefix(name, common.UserDataSecretPrefix)
+}
+
+// regeneratedSecret returns why HyperShift should regenerate the control plane Secret rather
+// than have it restored, or "" to restore it. Ignition tokens past their expiration were
+// being rotated out. On migration restores, the konnectivity and ignition server endpoints
+// usually change: the serving certificates issued for their hostnames are reissued by the
+// control plane operator from the restored signers, and the NodePool user data, which embeds
+// the ignition endpoint, is rendered again by the NodePool controller.
+func (p *RestorePlugin) regeneratedSecret(secret *corev1.Secret, now time.Time) string {
+	if strings.HasPrefix(secret.Name, common.IgnitionTokenSecretPrefix) {
+		if expiration, ok := secret.Annotations[hyperv1.IgnitionServerTokenExpirationTimestampAnnotation]; ok {
+			if expires, err := time.Parse(time.RFC3339, expiration); err == nil && expires.Before(now) {
+				return fmt.Sprintf("its ignition token expired at %s", expiration)
+			}
+		}
+	}
+	if !p.Migration {
+		return ""
+	}
+	switch {
+	case secret.Name == common.KonnectivityServerSecret || secret.Name == common.IgnitionServerServingCertSecret:
+		return "the control plane operator issues its serving certificate for the target endpoints"
+	case strings.HasPrefix(secret.Name, common.UserDataSecretPrefix):
+		return "the NodePool controller renders its user data for the target ignition endpoint"
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func init() {
+	registerKindHandler(serviceHandler{}, common.ServiceGroupKind)
+}
+
+// serviceHandler restores the Services publishing the control plane, e.g. the
+// kube-apiserver LoadBalancer one, without the load balancer of the source cluster: the
+// cloud provider of the target provisions a new one and records it in the status, which
+// a Restore listing services in restoreStatus would otherwise bring back stale.
+type serviceHandler struct {
+	passThroughHandler
+}
+
+func (serviceHandler) Restore(_ context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	content := input.Item.UnstructuredContent()
+	serviceType, _, err := unstructured.NestedString(content, "spec", "type")
+	if err != nil {
+		return nil, fmt.Errorf("error reading the type of %s: %w", itemName(input.Item), err)
+	}
+	if serviceType != string(corev1.ServiceTypeLoadBalancer) {
+		return nil, nil
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(content, "status", "loadBalancer"); found {
+		unstructured.RemoveNestedField(content, "status", "loadBalancer")
+		input.Item.SetUnstructuredContent(content)
+	}
+	p.log.Infof("Restoring LoadBalancer %s, the target provisions its load balancer", itemName(input.Item))
+	return nil, nil
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+func init() {
+	registerKindHandler(statefulSetHandler{}, common.StatefulSetGroupKind)
+}
+
+// statefulSetHandler skips the etcd StatefulSet on restore with the etcdSnapshot method,
+// etcd bootstraps from the snapshot URL instead. Others are restored after the
+// PriorityClass of their pods.
+type statefulSetHandler struct {
+	passThroughHandler
+}
+
+func (statefulSetHandler) Restore(_ context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	metadata, err := meta.Accessor(input.Item)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	if metadata.GetName() == "etcd" && p.config[common.ConfigKeyEtcdBackupMethod] == common.EtcdBackupMethodEtcdSnapshot {
+		p.log.Infof("etcd StatefulSet found, skipping restore (using etcdSnapshot method)")
+		return velero.NewRestoreItemActionExecuteOutput(input.Item).WithoutRestore(), nil
+	}
+	return restoreAfterPriorityClass(input)
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	registerKindHandler(volumeHandler{}, common.DataVolumeGroupKind, common.PersistentVolumeClaimGroupKind)
+}
+
+// volumeHandler excludes volumes that are recreated instead of restored: KubeVirt RHCOS
+// boot images, those matching skipVolumeLabels or skipVolumeNames and, with the
+// etcdSnapshot method, the etcd data PVCs. On migration backups
+// it protects the etcd and configured volumes from deletion along with the source cluster.
+// On restore, deletionProtection protects the etcd PVCs themselves for a grace period.
+type volumeHandler struct {
+	passThroughHandler
+}
+
+func (volumeHandler) Backup(ctx context.Context, p *BackupPlugin, item runtime.Unstructured, _ *velerov1.Backup) (runtime.Unstructured, error) {
+	metadata, err := meta.Accessor(item)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	labels := metadata.GetLabels()
+	if _, exists := labels[common.KubevirtRHCOSLabel]; exists {
+		return nil, nil
+	}
+	if reason := p.skippedVolume(metadata); reason != "" {
+		p.log.Infof("Excluding %s %s/%s from backup: %s", item.GetObjectKind().GroupVersionKind().Kind, metadata.GetNamespace(), metadata.GetName(), reason)
+		return nil, nil
+	}
+
+	// Exclude etcd data PVCs when using etcdSnapshot method.
+	// PVC names follow the StatefulSet pattern: data-etcd-{index}
+	if item.GetObjectKind().GroupVersionKind().Kind == common.PersistentVolumeClaimKind &&
+		strings.HasPrefix(metadata.GetName(), common.EtcdPVCPrefix) &&
+		p.etcdBackupMethod == common.EtcdBackupMethodEtcdSnapshot {
+		p.log.Infof("Excluding etcd PVC %s from backup (using etcdSnapshot method)", metadata.GetName())
+		return nil, nil
+	}
+
+	if p.Migration && item.GetObjectKind().GroupVersionKind().Kind == common.PersistentVolumeClaimKind &&
+		(strings.HasPrefix(metadata.GetName(), common.EtcdPVCPrefix) || slices.Contains(p.MigrationRetainPVCs, metadata.GetName())) {
+		if err := p.retainClaimVolume(ctx, item, metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	return item, nil
+}
+
+func (volumeHandler) Restore(_ context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	if p.DeletionProtection == 0 || input.Item.GetObjectKind().GroupVersionKind().Kind != common.PersistentVolumeClaimKind {
+		return nil, nil
+	}
+	metadata, err := meta.Accessor(input.Item)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	if !strings.HasPrefix(metadata.GetName(), common.EtcdPVCPrefix) {
+		return nil, nil
+	}
+	if err := p.protectFromDeletion(input.Item, common.PersistentVolumeClaimKind); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// skippedVolume returns why the skipVolumeLabels or skipVolumeNames options exclude the
+// volume, empty when they do not.
+func (p *BackupPlugin) skippedVolume(metadata metav1.Object) string {
+	for _, entry := range p.SkipVolumeLabels {
+		key, value, hasValue := strings.Cut(entry, "=")
+		if actual, ok := metadata.GetLabels()[key]; ok && (!hasValue || actual == value) {
+			return fmt.Sprintf("label %s matches %s", key, common.ConfigKeySkipVolumeLabels)
+		}
+	}
+	for _, pattern := range p.SkipVolumeNames {
+		if matched, _ := path.Match(pattern, metadata.GetName()); matched {
+			return fmt.Sprintf("name matches %s pattern %q", common.ConfigKeySkipVolumeNames, pattern)
+		}
+	}
+	return ""
+}
+
+// retainClaimVolume sets the reclaim policy of the volume bound to the PVC to Retain.
+func (p *BackupPlugin) retainClaimVolume(ctx context.Context, item runtime.Unstructured, metadata metav1.Object) error {
+	volumeName, _, _ := unstructured.NestedString(item.UnstructuredContent(), "spec", "volumeName")
+	if volumeName == "" {
+		p.log.Warnf("PVC %s/%s is not bound, no volume to retain", metadata.GetNamespace(), metadata.GetName())
+		return nil
+	}
+	changed, err := common.RetainPersistentVolume(ctx, p.client, volumeName)
+	if err != nil {
+		return err
+	}
+	if changed {
+		p.log.Infof("Set reclaim policy of PersistentVolume %s (PVC %s/%s) to Retain for migration", volumeName, metadata.GetNamespace(), metadata.GetName())
+	}
+	return nil
+}
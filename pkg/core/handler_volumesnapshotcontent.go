@@ -0,0 +1,37 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	registerKindHandler(volumeSnapshotContentHandler{}, common.VolumeSnapshotContentGroupKind)
+}
+
+// volumeSnapshotContentHandler keeps the VolumeSnapshotContents of the VolumeSnapshots of the
+// backed up namespaces only. A backup including cluster resources would otherwise store,
+// and label with the hosted cluster, the snapshots of every workload of the management
+// cluster.
+type volumeSnapshotContentHandler struct {
+	passThroughHandler
+}
+
+func (volumeSnapshotContentHandler) Backup(_ context.Context, p *BackupPlugin, item runtime.Unstructured, backup *velerov1.Backup) (runtime.Unstructured, error) {
+	namespace, _, err := unstructured.NestedString(item.UnstructuredContent(), "spec", "volumeSnapshotRef", "namespace")
+	if err != nil {
+		return nil, fmt.Errorf("error reading the VolumeSnapshot of %s: %w", itemName(item), err)
+	}
+	included := backup.Spec.IncludedNamespaces
+	if len(included) == 0 || slices.Contains(included, "*") || slices.Contains(included, namespace) {
+		return item, nil
+	}
+	p.log.Debugf("Excluding VolumeSnapshotContent %s of namespace %s from backup", objectName(item), namespace)
+	return nil, nil
+}
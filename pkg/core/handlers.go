@@ -0,0 +1,194 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/sirupsen/logrus"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// kindHandler holds the backup and restore logic of one or more resource kinds.
+// Handlers receive the plugin so they share its client, configuration and cached state.
+type kindHandler interface {
+	// Backup returns the item to back up, or nil to exclude it from the backup.
+	Backup(ctx context.Context, p *BackupPlugin, item runtime.Unstructured, backup *velerov1.Backup) (runtime.Unstructured, error)
+	// Restore returns the restore output, or nil to restore the item unchanged.
+	Restore(ctx context.Context, p *RestorePlugin, input *velero.RestoreItemActionExecuteInput, backup *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error)
+}
+
+// additionalItemsHandler is implemented by kind handlers whose items need other objects in
+// the backup, e.g. ones they create. It runs after Backup, on the item to back up.
+type additionalItemsHandler interface {
+	AdditionalItems(ctx context.Context, p *BackupPlugin, item runtime.Unstructured, backup *velerov1.Backup) ([]velero.ResourceIdentifier, error)
+}
+
+// kindHandlers maps a resource group and kind to its handler, so kinds sharing a name in
+// different API groups are told apart. Versions are not matched: handlers work on any
+// served version. Handlers register themselves from their own file with registerKindHandler.
+var kindHandlers = map[schema.GroupKind]kindHandler{}
+
+func registerKindHandler(handler kindHandler, kinds ...schema.GroupKind) {
+	for _, kind := range kinds {
+		if _, exists := kindHandlers[kind]; exists {
+			panic(fmt.Sprintf("kind handler already registered for %s", kind))
+		}
+		kindHandlers[kind] = handler
+	}
+}
+
+// handlerFor returns the kind handler of the item, if any.
+func handlerFor(item runtime.Unstructured) (kindHandler, bool) {
+	handler, ok := kindHandlers[item.GetObjectKind().GroupVersionKind().GroupKind()]
+	return handler, ok
+}
+
+// handlerBackoff bounds the retries of a handler failing with a retryable error.
+var handlerBackoff = retry.DefaultBackoff
+
+// retryTransient runs fn again while it fails with a retryable error, e.g. a conflicting
+// update or an API server that is briefly unavailable. Validation errors and timeouts are
+// returned at once. Handlers are safe to run again, their side effects being idempotent.
+func retryTransient(fn func() error) error {
+	return retry.OnError(handlerBackoff, common.IsRetryable, fn)
+}
+
+// executeContext bounds an Execute call with the executeTimeout option. A zero timeout
+// leaves the context unbounded.
+func executeContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// deadlineError turns an error caused by the expiry of the executeTimeout option into a
+// TimeoutError naming the item, so the failure says which limit was hit.
+func deadlineError(ctx context.Context, err error, timeout time.Duration, item string) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return &common.TimeoutError{
+		Operation: item,
+		Timeout:   timeout,
+		Err:       fmt.Errorf("%s exceeded: %w", common.ConfigKeyExecuteTimeout, err),
+	}
+}
+
+// checkPermissions fails the plugin initialization when its service account lacks any of
+// the permissions, listing them all, rather than failing a backup or restore halfway. Only
+// HyperShift management clusters are checked, and a review that cannot run is logged.
+func checkPermissions(ctx context.Context, c crclient.Client, perms []common.Permission, log logrus.FieldLogger) error {
+	if hypershift, err := common.CRDExists(ctx, "hostedcontrolplanes.hypershift.openshift.io", c); err != nil || !hypershift {
+		return nil
+	}
+	missing, err := common.MissingPermissions(ctx, c, perms)
+	if err != nil {
+		log.Warnf("Could not check the plugin permissions: %v", err)
+		return nil
+	}
+	if len(missing) == 0 {
+		log.Debugf("plugin has the %d permissions it needs", len(perms))
+		return nil
+	}
+	names := make([]string, 0, len(missing))
+	for _, perm := range missing {
+		names = append(names, perm.String())
+	}
+	return common.NewValidationError("plugin service account is missing permissions: %s", strings.Join(names, ", "))
+}
+
+// itemName names an item in errors as kind namespace/name.
+func itemName(item runtime.Unstructured) string {
+	return fmt.Sprintf("%s %s", item.GetObjectKind().GroupVersionKind().Kind, objectName(item))
+}
+
+// objectName names an item as namespace/name, or name for cluster scoped items.
+func objectName(item runtime.Unstructured) string {
+	u := &unstructured.Unstructured{Object: item.UnstructuredContent()}
+	if u.GetNamespace() == "" {
+		return u.GetName()
+	}
+	return u.GetNamespace() + "/" + u.GetName()
+}
+
+// labelOf returns the value of a label of an item, empty when it is not set.
+func labelOf(item runtime.Unstructured, key string) string {
+	return (&unstructured.Unstructured{Object: item.UnstructuredContent()}).GetLabels()[key]
+}
+
+// keepStatus records the given status fields of the item, those that are set, as JSON in
+// the annotation, for restoreKeptStatus to put back: Velero strips status during restore.
+// It reports whether any field was recorded.
+func keepStatus(item runtime.Unstructured, annotation string, fields ...string) (bool, error) {
+	u := &unstructured.Unstructured{Object: item.UnstructuredContent()}
+	status, _, err := unstructured.NestedMap(u.Object, "status")
+	if err != nil {
+		return false, fmt.Errorf("error reading the status of %s %s: %w", u.GetKind(), u.GetName(), err)
+	}
+	kept := map[string]any{}
+	for _, field := range fields {
+		if value, ok := status[field]; ok && value != "" {
+			kept[field] = value
+		}
+	}
+	if len(kept) == 0 {
+		return false, nil
+	}
+
+	data, err := json.Marshal(kept)
+	if err != nil {
+		return false, fmt.Errorf("error encoding the status of %s %s: %w", u.GetKind(), u.GetName(), err)
+	}
+	common.AddAnnotation(u, annotation, string(data))
+	item.SetUnstructuredContent(u.Object)
+	return true, nil
+}
+
+// restoreKeptStatus puts the status fields recorded by keepStatus back into the item. Velero
+// applies them when the Restore lists the resource in restoreStatus.includedResources. It
+// reports whether the annotation was found.
+func restoreKeptStatus(item runtime.Unstructured, annotation string) (bool, error) {
+	u := &unstructured.Unstructured{Object: item.UnstructuredContent()}
+	data, ok := u.GetAnnotations()[annotation]
+	if !ok {
+		return false, nil
+	}
+	kept := map[string]any{}
+	if err := json.Unmarshal([]byte(data), &kept); err != nil {
+		return false, fmt.Errorf("error decoding the %s annotation of %s %s: %w", annotation, u.GetKind(), u.GetName(), err)
+	}
+
+	status, ok := u.Object["status"].(map[string]any)
+	if !ok {
+		status = map[string]any{}
+		u.Object["status"] = status
+	}
+	for field, value := range kept {
+		status[field] = value
+	}
+	item.SetUnstructuredContent(u.Object)
+	return true, nil
+}
+
+// passThroughHandler is embedded by handlers that only act in one direction.
+type passThroughHandler struct{}
+
+func (passThroughHandler) Backup(_ context.Context, _ *BackupPlugin, item runtime.Unstructured, _ *velerov1.Backup) (runtime.Unstructured, error) {
+	return item, nil
+}
+
+func (passThroughHandler) Restore(_ context.Context, _ *RestorePlugin, _ *velero.RestoreItemActionExecuteInput, _ *velerov1.Backup) (*velero.RestoreItemActionExecuteOutput, error) {
+	return nil, nil
+}
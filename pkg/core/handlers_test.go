@@ -0,0 +1,677 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	hive "github.com/openshift/hive/apis/hive/v1"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	plugtypes "github.com/openshift/hypershift-oadp-plugin/pkg/core/types"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/platform/aws"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/s3presign"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	"github.com/sirupsen/logrus"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	veleroapiv1 "github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestKindHandlersRegistry(t *testing.T) {
+	t.Run("When the package is initialized, It Should register a handler for every handled kind", func(t *testing.T) {
+		g := NewWithT(t)
+		for _, kind := range []schema.GroupKind{
+			common.HostedControlPlaneGroupKind,
+			common.HostedClusterGroupKind,
+			common.NodePoolGroupKind,
+			common.ClusterDeploymentGroupKind,
+			common.DataVolumeGroupKind,
+			common.PersistentVolumeClaimGroupKind,
+			common.AWSEndpointServiceGroupKind,
+			common.IPAddressClaimGroupKind,
+			common.Metal3IPClaimGroupKind,
+			common.MachineGroupKind,
+			common.PodGroupKind,
+			common.SecretGroupKind,
+			common.StatefulSetGroupKind,
+			common.ServiceGroupKind,
+			common.RouteGroupKind,
+			common.DeploymentGroupKind,
+			common.PriorityClassGroupKind,
+			common.CSRGroupKind,
+			common.VolumeSnapshotContentGroupKind,
+		} {
+			g.Expect(kindHandlers).To(HaveKey(kind))
+		}
+	})
+
+	t.Run("When a kind is registered twice, It Should panic", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(func() { registerKindHandler(podHandler{}, common.PodGroupKind) }).To(Panic())
+	})
+
+	t.Run("When kinds share a name in different groups, It Should only dispatch the handled group", func(t *testing.T) {
+		g := NewWithT(t)
+		_, ok := handlerFor(newUnstructuredItem("Machine", "cluster.x-k8s.io/v1beta2", "workers-abc12", "clusters-test"))
+		g.Expect(ok).To(BeTrue(), "any version of the handled group")
+		_, ok = handlerFor(newUnstructuredItem("Machine", "machine.openshift.io/v1beta1", "worker-0", "openshift-machine-api"))
+		g.Expect(ok).To(BeFalse())
+		_, ok = handlerFor(newUnstructuredItem("IPClaim", "ipam.cluster.x-k8s.io/v1beta1", "claim", "clusters-test"))
+		g.Expect(ok).To(BeFalse())
+	})
+}
+
+func TestPassThroughHandler(t *testing.T) {
+	g := NewWithT(t)
+	handler := passThroughHandler{}
+	item := newUnstructuredItem("ConfigMap", "v1", "cm", "clusters-test")
+
+	backedUp, err := handler.Backup(context.TODO(), &BackupPlugin{log: logrus.New()}, item, newTestBackup())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(backedUp).To(Equal(item))
+
+	output, err := handler.Restore(context.TODO(), &RestorePlugin{log: logrus.New()}, &veleroapiv1.RestoreItemActionExecuteInput{Item: item}, newTestBackup())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(output).To(BeNil())
+}
+
+func TestRetryTransient(t *testing.T) {
+	resource := schema.GroupResource{Group: "hypershift.openshift.io", Resource: "hostedclusters"}
+
+	t.Run("When the handler fails with a transient error, It Should run it again", func(t *testing.T) {
+		g := NewWithT(t)
+		calls := 0
+		err := retryTransient(func() error {
+			if calls++; calls < 3 {
+				return fmt.Errorf("error updating HostedCluster: %w", apierrors.NewConflict(resource, "hc", errors.New("object was modified")))
+			}
+			return nil
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(calls).To(Equal(3))
+	})
+
+	t.Run("When the handler fails with a validation error, It Should not run it again", func(t *testing.T) {
+		g := NewWithT(t)
+		calls := 0
+		err := retryTransient(func() error {
+			calls++
+			return common.NewValidationError("unsupported platform type %s", "Foo")
+		})
+		g.Expect(common.ErrorClass(err)).To(Equal(common.ErrorClassValidation))
+		g.Expect(calls).To(Equal(1))
+	})
+}
+
+func TestDeadlineError(t *testing.T) {
+	item := newUnstructuredItem("HostedCluster", "hypershift.openshift.io/v1beta1", "test", "clusters")
+
+	t.Run("When the Execute deadline expired, It Should return a timeout naming the item", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx, cancel := executeContext(context.TODO(), time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+
+		err := deadlineError(ctx, fmt.Errorf("HCPEtcdBackup failed: %w", ctx.Err()), 15*time.Minute, itemName(item))
+		g.Expect(common.ErrorClass(err)).To(Equal(common.ErrorClassTimeout))
+		g.Expect(err).To(MatchError(HavePrefix("timed out after 15m0s waiting for HostedCluster clusters/test: executeTimeout exceeded")))
+	})
+
+	t.Run("When no timeout is set, It Should return the error unchanged", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx, cancel := executeContext(context.TODO(), 0)
+		defer cancel()
+		_, hasDeadline := ctx.Deadline()
+		g.Expect(hasDeadline).To(BeFalse())
+
+		failed := errors.New("error getting HostedCluster")
+		g.Expect(deadlineError(ctx, failed, 0, itemName(item))).To(BeIdenticalTo(failed))
+	})
+}
+
+func TestCheckPermissions(t *testing.T) {
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	perms := common.BackupPermissions("openshift-adp", common.EtcdBackupMethodVolume, false)
+	// denyPatch denies patching, allowing anything else
+	denyPatch := interceptor.Funcs{
+		Create: func(_ context.Context, _ crclient.WithWatch, obj crclient.Object, _ ...crclient.CreateOption) error {
+			review := obj.(*authorizationv1.SelfSubjectAccessReview)
+			review.Status.Allowed = review.Spec.ResourceAttributes.Verb != "patch"
+			return nil
+		},
+	}
+
+	t.Run("When permissions are missing, It Should list them all", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD).WithInterceptorFuncs(denyPatch).Build()
+
+		err := checkPermissions(context.TODO(), c, perms, logrus.New())
+		g.Expect(common.ErrorClass(err)).To(Equal(common.ErrorClassValidation))
+		g.Expect(err).To(MatchError(ContainSubstring("missing permissions: patch backups.velero.io in namespace openshift-adp, patch persistentvolumes")))
+	})
+
+	t.Run("When HyperShift is not installed, It Should not check", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithInterceptorFuncs(denyPatch).Build()
+		g.Expect(checkPermissions(context.TODO(), c, perms, logrus.New())).To(Succeed())
+	})
+
+	t.Run("When the review cannot run, It Should not fail", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(_ context.Context, _ crclient.WithWatch, _ crclient.Object, _ ...crclient.CreateOption) error {
+				return errors.New("connection refused")
+			},
+		}).Build()
+		g.Expect(checkPermissions(context.TODO(), c, perms, logrus.New())).To(Succeed())
+	})
+}
+
+func TestAWSEndpointServiceWiring(t *testing.T) {
+	g := NewWithT(t)
+	item := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "hypershift.openshift.io/v1beta1",
+		"kind":       common.AWSEndpointServiceKind,
+		"metadata":   map[string]any{"name": "private-router", "namespace": "clusters-test"},
+		"spec":       map[string]any{"networkLoadBalancerName": "router-nlb"},
+		"status": map[string]any{
+			"endpointServiceName": "com.amazonaws.vpce.us-east-1.vpce-svc-0123",
+			"endpointID":          "vpce-0456",
+			"dnsNames":            []any{"api.test.hypershift.local"},
+			"conditions":          []any{map[string]any{"type": "EndpointAvailable", "status": "True"}},
+		},
+	}}
+
+	backedUp, err := awsEndpointServiceHandler{}.Backup(context.TODO(), newTestBackupPlugin(), item, newTestBackup())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Velero strips the status before restore item actions run
+	restored := &unstructured.Unstructured{Object: backedUp.UnstructuredContent()}
+	unstructured.RemoveNestedField(restored.Object, "status")
+	plugin := &RestorePlugin{log: logrus.New(), RestoreOptions: &plugtypes.RestoreOptions{}}
+	output, err := awsEndpointServiceHandler{}.Restore(context.TODO(), plugin, &veleroapiv1.RestoreItemActionExecuteInput{Item: restored}, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(output).To(BeNil())
+
+	g.Expect(restored.Object["status"]).To(Equal(map[string]any{
+		"endpointServiceName": "com.amazonaws.vpce.us-east-1.vpce-svc-0123",
+		"endpointID":          "vpce-0456",
+		"dnsNames":            []any{"api.test.hypershift.local"},
+	}))
+}
+
+func TestAWSEndpointServiceRegenPrivateLink(t *testing.T) {
+	g := NewWithT(t)
+	item := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "hypershift.openshift.io/v1beta1",
+		"kind":       common.AWSEndpointServiceKind,
+		"metadata": map[string]any{
+			"name":        "private-router",
+			"namespace":   "clusters-test",
+			"annotations": map[string]any{common.AWSEndpointServiceStatusAnnotation: `{"endpointID":"vpce-0456"}`},
+		},
+	}}
+
+	plugin := &RestorePlugin{log: logrus.New(), RestoreOptions: &plugtypes.RestoreOptions{
+		PlatformOptions: plugtypes.PlatformOptions{AWS: plugtypes.AWSOptions{RegenPrivateLink: true}},
+	}}
+	output, err := awsEndpointServiceHandler{}.Restore(context.TODO(), plugin, &veleroapiv1.RestoreItemActionExecuteInput{Item: item}, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(output).To(BeNil())
+	g.Expect(item.Object).NotTo(HaveKey("status"))
+	g.Expect(item.GetAnnotations()).NotTo(HaveKey(common.AWSEndpointServiceStatusAnnotation))
+}
+
+func TestClusterDeploymentRestore(t *testing.T) {
+	tests := []struct {
+		name                 string
+		options              plugtypes.AgentOptions
+		wantPreserveOnDelete bool
+	}{
+		{
+			name:                 "When agent.preserveOnDelete is left unset, It Should preserve the hosts on delete",
+			wantPreserveOnDelete: true,
+		},
+		{
+			name:    "When agent.preserveOnDelete is false, It Should leave the ClusterDeployment as backed up",
+			options: plugtypes.AgentOptions{SkipPreserveOnDelete: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			cd := &hive.ClusterDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "clusters-test"}}
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(cd).Build()
+			g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(cd), cd)).To(Succeed())
+			content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cd)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			plugin := &RestorePlugin{log: logrus.New(), client: c, RestoreOptions: &plugtypes.RestoreOptions{
+				PlatformOptions: plugtypes.PlatformOptions{Agent: tt.options},
+			}}
+			input := &veleroapiv1.RestoreItemActionExecuteInput{Item: &unstructured.Unstructured{Object: content}}
+			_, err = clusterDeploymentHandler{}.Restore(context.TODO(), plugin, input, nil)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(cd), cd)).To(Succeed())
+			g.Expect(cd.Spec.PreserveOnDelete).To(Equal(tt.wantPreserveOnDelete))
+		})
+	}
+}
+
+func TestVolumeRestoreDeletionProtection(t *testing.T) {
+	tests := []struct {
+		name          string
+		pvc           string
+		gracePeriod   time.Duration
+		wantProtected bool
+	}{
+		{
+			name:          "When deletionProtection is set, It Should protect the etcd PVCs",
+			pvc:           "data-etcd-0",
+			gracePeriod:   72 * time.Hour,
+			wantProtected: true,
+		},
+		{
+			name:        "When deletionProtection is set, It Should leave the other PVCs unprotected",
+			pvc:         "ovnkube-db",
+			gracePeriod: 72 * time.Hour,
+		},
+		{
+			name: "When deletionProtection is unset, It Should leave the etcd PVCs unprotected",
+			pvc:  "data-etcd-0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			item := &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "v1",
+				"kind":       common.PersistentVolumeClaimKind,
+				"metadata":   map[string]any{"name": tt.pvc, "namespace": "clusters-test"},
+			}}
+			plugin := &RestorePlugin{log: logrus.New(), RestoreOptions: &plugtypes.RestoreOptions{DeletionProtection: tt.gracePeriod}}
+			output, err := volumeHandler{}.Restore(context.TODO(), plugin, &veleroapiv1.RestoreItemActionExecuteInput{Item: item}, nil)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(output).To(BeNil())
+
+			if !tt.wantProtected {
+				g.Expect(item.GetFinalizers()).To(BeEmpty())
+				g.Expect(item.GetAnnotations()).NotTo(HaveKey(common.DeletionProtectedUntilAnnotation))
+				return
+			}
+			g.Expect(item.GetFinalizers()).To(ConsistOf(common.DeletionProtectionFinalizer))
+			until, err := time.Parse(time.RFC3339, item.GetAnnotations()[common.DeletionProtectedUntilAnnotation])
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(until).To(BeTemporally("~", time.Now().Add(tt.gracePeriod), time.Minute))
+		})
+	}
+}
+
+func TestPublishingRestore(t *testing.T) {
+	plugin := &RestorePlugin{log: logrus.New(), RestoreOptions: &plugtypes.RestoreOptions{}}
+
+	t.Run("When a LoadBalancer Service is restored with its status, It Should drop the source load balancer", func(t *testing.T) {
+		g := NewWithT(t)
+		item := &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]any{"name": "kube-apiserver", "namespace": "clusters-test"},
+			"spec":       map[string]any{"type": "LoadBalancer"},
+			"status":     map[string]any{"loadBalancer": map[string]any{"ingress": []any{map[string]any{"hostname": "a1b2.elb.amazonaws.com"}}}},
+		}}
+		output, err := serviceHandler{}.Restore(context.TODO(), plugin, &veleroapiv1.RestoreItemActionExecuteInput{Item: item}, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(output).To(BeNil())
+		g.Expect(item.Object["status"]).To(BeEmpty())
+	})
+
+	t.Run("When a ClusterIP Service is restored, It Should restore it unchanged", func(t *testing.T) {
+		g := NewWithT(t)
+		item := newUnstructuredItem("Service", "v1", "openshift-apiserver", "clusters-test")
+		item.Object["spec"] = map[string]any{"type": "ClusterIP"}
+		expected := item.DeepCopy()
+		_, err := serviceHandler{}.Restore(context.TODO(), plugin, &veleroapiv1.RestoreItemActionExecuteInput{Item: item}, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(item).To(Equal(expected))
+	})
+
+	t.Run("When a Route has a generated host, It Should clear it for the target router", func(t *testing.T) {
+		g := NewWithT(t)
+		item := newUnstructuredItem("Route", "route.openshift.io/v1", "oauth", "clusters-test")
+		item.SetAnnotations(map[string]string{common.RouteHostGeneratedAnnotation: "true"})
+		item.Object["spec"] = map[string]any{"host": "oauth-clusters-test.apps.source.example.com", "to": map[string]any{"kind": "Service", "name": "oauth-openshift"}}
+		_, err := routeHandler{}.Restore(context.TODO(), plugin, &veleroapiv1.RestoreItemActionExecuteInput{Item: item}, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(item.Object["spec"]).To(Equal(map[string]any{"to": map[string]any{"kind": "Service", "name": "oauth-openshift"}}))
+		g.Expect(item.GetAnnotations()).NotTo(HaveKey(common.RouteHostGeneratedAnnotation))
+	})
+
+	t.Run("When a Route has a published hostname, It Should keep it", func(t *testing.T) {
+		g := NewWithT(t)
+		item := newUnstructuredItem("Route", "route.openshift.io/v1", "oauth", "clusters-test")
+		item.Object["spec"] = map[string]any{"host": "oauth.source.example.com"}
+		_, err := routeHandler{}.Restore(context.TODO(), plugin, &veleroapiv1.RestoreItemActionExecuteInput{Item: item}, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(item.Object["spec"]).To(Equal(map[string]any{"host": "oauth.source.example.com"}))
+	})
+}
+
+func TestClusterScopedItems(t *testing.T) {
+	priorityClass := &schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "hypershift-control-plane"}, Value: 100000000}
+	client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(priorityClass).Build()
+	restorePlugin := &RestorePlugin{log: logrus.New(), client: client, RestoreOptions: &plugtypes.RestoreOptions{}}
+	backupPlugin := &BackupPlugin{log: logrus.New(), BackupOptions: &plugtypes.BackupOptions{}}
+
+	t.Run("When a PriorityClass exists on the target, It Should skip its restore", func(t *testing.T) {
+		g := NewWithT(t)
+		item := newUnstructuredItem("PriorityClass", "scheduling.k8s.io/v1", "hypershift-control-plane", "")
+		output, err := priorityClassHandler{}.Restore(context.TODO(), restorePlugin, &veleroapiv1.RestoreItemActionExecuteInput{Item: item}, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(output.SkipRestore).To(BeTrue())
+	})
+
+	t.Run("When a PriorityClass is missing on the target, It Should restore it", func(t *testing.T) {
+		g := NewWithT(t)
+		item := newUnstructuredItem("PriorityClass", "scheduling.k8s.io/v1", "hypershift-etcd", "")
+		output, err := priorityClassHandler{}.Restore(context.TODO(), restorePlugin, &veleroapiv1.RestoreItemActionExecuteInput{Item: item}, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(output).To(BeNil())
+	})
+
+	t.Run("When a Deployment runs with a PriorityClass, It Should restore the PriorityClass first", func(t *testing.T) {
+		g := NewWithT(t)
+		item := newUnstructuredItem("Deployment", "apps/v1", "kube-apiserver", "clusters-test")
+		g.Expect(unstructured.SetNestedField(item.Object, "hypershift-api-critical", "spec", "template", "spec", "priorityClassName")).To(Succeed())
+		output, err := deploymentHandler{}.Restore(context.TODO(), restorePlugin, &veleroapiv1.RestoreItemActionExecuteInput{Item: item}, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(output.AdditionalItems).To(ConsistOf(veleroapiv1.ResourceIdentifier{
+			GroupResource: schema.GroupResource{Group: "scheduling.k8s.io", Resource: "priorityclasses"},
+			Name:          "hypershift-api-critical",
+		}))
+	})
+
+	t.Run("When a StatefulSet runs with a system PriorityClass, It Should restore it unchanged", func(t *testing.T) {
+		g := NewWithT(t)
+		item := newUnstructuredItem("StatefulSet", "apps/v1", "ovnkube-master", "clusters-test")
+		g.Expect(unstructured.SetNestedField(item.Object, "system-cluster-critical", "spec", "template", "spec", "priorityClassName")).To(Succeed())
+		output, err := statefulSetHandler{}.Restore(context.TODO(), restorePlugin, &veleroapiv1.RestoreItemActionExecuteInput{Item: item}, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(output).To(BeNil())
+	})
+
+	t.Run("When a CertificateSigningRequest is backed up, It Should exclude it", func(t *testing.T) {
+		g := NewWithT(t)
+		item := newUnstructuredItem("CertificateSigningRequest", "certificates.k8s.io/v1", "csr-abcde", "")
+		backedUp, err := csrHandler{}.Backup(context.TODO(), backupPlugin, item, newTestBackup())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(backedUp).To(BeNil())
+	})
+
+	t.Run("When a VolumeSnapshotContent belongs to a backed up namespace, It Should back it up", func(t *testing.T) {
+		g := NewWithT(t)
+		item := newUnstructuredItem("VolumeSnapshotContent", "snapshot.storage.k8s.io/v1", "snapcontent-1", "")
+		g.Expect(unstructured.SetNestedField(item.Object, "clusters-test", "spec", "volumeSnapshotRef", "namespace")).To(Succeed())
+		backedUp, err := volumeSnapshotContentHandler{}.Backup(context.TODO(), backupPlugin, item, newTestBackup())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(backedUp).To(Equal(item))
+	})
+
+	t.Run("When a VolumeSnapshotContent belongs to another namespace, It Should exclude it", func(t *testing.T) {
+		g := NewWithT(t)
+		item := newUnstructuredItem("VolumeSnapshotContent", "snapshot.storage.k8s.io/v1", "snapcontent-2", "")
+		g.Expect(unstructured.SetNestedField(item.Object, "my-app", "spec", "volumeSnapshotRef", "namespace")).To(Succeed())
+		backedUp, err := volumeSnapshotContentHandler{}.Backup(context.TODO(), backupPlugin, item, newTestBackup())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(backedUp).To(BeNil())
+	})
+}
+
+func TestIPClaimAddress(t *testing.T) {
+	tests := []struct {
+		name   string
+		claim  *unstructured.Unstructured
+		status map[string]any
+	}{
+		{
+			name: "When a CAPI IPAddressClaim is restored, It Should point to its IPAddress again",
+			claim: &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "ipam.cluster.x-k8s.io/v1beta1",
+				"kind":       common.IPAddressClaimKind,
+				"metadata":   map[string]any{"name": "worker-0-eth0", "namespace": "clusters-test"},
+				"spec":       map[string]any{"poolRef": map[string]any{"apiGroup": "ipam.cluster.x-k8s.io", "kind": "InClusterIPPool", "name": "workers"}},
+				"status": map[string]any{
+					"addressRef": map[string]any{"name": "worker-0-eth0"},
+					"conditions": []any{map[string]any{"type": "Ready", "status": "True"}},
+				},
+			}},
+			status: map[string]any{"addressRef": map[string]any{"name": "worker-0-eth0"}},
+		},
+		{
+			name: "When a metal3 IPClaim is restored, It Should point to its IPAddress again",
+			claim: &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "ipam.metal3.io/v1alpha1",
+				"kind":       common.Metal3IPClaimKind,
+				"metadata":   map[string]any{"name": "worker-0-provisioning", "namespace": "clusters-test"},
+				"spec":       map[string]any{"pool": map[string]any{"name": "provisioning"}},
+				"status":     map[string]any{"address": map[string]any{"name": "provisioning-192-168-111-20"}},
+			}},
+			status: map[string]any{"address": map[string]any{"name": "provisioning-192-168-111-20"}},
+		},
+		{
+			name: "When a claim has no address yet, It Should be restored without status",
+			claim: &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "ipam.cluster.x-k8s.io/v1beta1",
+				"kind":       common.IPAddressClaimKind,
+				"metadata":   map[string]any{"name": "worker-1-eth0", "namespace": "clusters-test"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			backedUp, err := ipClaimHandler{}.Backup(context.TODO(), newTestBackupPlugin(), tt.claim, newTestBackup())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			// Velero strips the status before restore item actions run
+			restored := &unstructured.Unstructured{Object: backedUp.UnstructuredContent()}
+			unstructured.RemoveNestedField(restored.Object, "status")
+			plugin := &RestorePlugin{log: logrus.New(), RestoreOptions: &plugtypes.RestoreOptions{}}
+			output, err := ipClaimHandler{}.Restore(context.TODO(), plugin, &veleroapiv1.RestoreItemActionExecuteInput{Item: restored}, nil)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(output).To(BeNil())
+
+			if tt.status == nil {
+				g.Expect(restored.Object).NotTo(HaveKey("status"))
+				return
+			}
+			g.Expect(restored.Object["status"]).To(Equal(tt.status))
+		})
+	}
+}
+
+func TestMachineReadoption(t *testing.T) {
+	nodes := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: common.MachineNodesConfigMapName, Namespace: "clusters-test"},
+		Data:       map[string]string{"workers": `{"workers-abc12":{"providerID":"aws:///us-east-1a/i-0123","nodeName":"ip-10-0-1-12.ec2.internal"}}`},
+	}
+	newMachine := func(name string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "cluster.x-k8s.io/v1beta1",
+			"kind":       common.MachineKind,
+			"metadata": map[string]any{
+				"name":        name,
+				"namespace":   "clusters-test",
+				"annotations": map[string]any{common.NodePoolAnnotation: "clusters/workers"},
+			},
+			"spec": map[string]any{"clusterName": "test"},
+		}}
+	}
+
+	tests := []struct {
+		name           string
+		readoptNodes   bool
+		machine        string
+		wantProviderID string
+		wantNodeRef    map[string]any
+	}{
+		{
+			name:           "When readoptNodes is set and the Machine was recorded, It Should point it to its instance and Node",
+			readoptNodes:   true,
+			machine:        "workers-abc12",
+			wantProviderID: "aws:///us-east-1a/i-0123",
+			wantNodeRef:    map[string]any{"apiVersion": "v1", "kind": "Node", "name": "ip-10-0-1-12.ec2.internal"},
+		},
+		{
+			name:         "When readoptNodes is set and the Machine was not recorded, It Should restore it unchanged",
+			readoptNodes: true,
+			machine:      "workers-def34",
+		},
+		{
+			name:    "When readoptNodes is not set, It Should restore the Machine unchanged",
+			machine: "workers-abc12",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			plugin := &RestorePlugin{
+				log:            logrus.New(),
+				client:         fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(nodes).Build(),
+				RestoreOptions: &plugtypes.RestoreOptions{ReadoptNodes: tt.readoptNodes},
+			}
+			machine := newMachine(tt.machine)
+
+			output, err := machineHandler{}.Restore(context.TODO(), plugin, &veleroapiv1.RestoreItemActionExecuteInput{Item: machine}, nil)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(output).To(BeNil())
+
+			providerID, _, _ := unstructured.NestedString(machine.Object, "spec", "providerID")
+			g.Expect(providerID).To(Equal(tt.wantProviderID))
+			nodeRef, _, _ := unstructured.NestedMap(machine.Object, "status", "nodeRef")
+			if tt.wantNodeRef == nil {
+				g.Expect(nodeRef).To(BeNil())
+			} else {
+				g.Expect(nodeRef).To(Equal(tt.wantNodeRef))
+			}
+		})
+	}
+}
+
+type mockKMSClient struct {
+	metadata *aws.KeyMetadata
+	err      error
+}
+
+func (m *mockKMSClient) DescribeKey(_ context.Context, _ s3presign.AWSCredentials, _, _ string) (*aws.KeyMetadata, error) {
+	return m.metadata, m.err
+}
+
+func TestVerifyEncryptionKeys(t *testing.T) {
+	origSAPath := common.DefaultK8sSAFilePath
+	nsDir := t.TempDir()
+	if err := os.WriteFile(nsDir+"/namespace", []byte("openshift-adp"), 0644); err != nil {
+		t.Fatalf("failed to write namespace file: %v", err)
+	}
+	common.SetK8sSAFilePath(nsDir)
+	t.Cleanup(func() { common.SetK8sSAFilePath(origSAPath) })
+
+	bsl := &velerov1.BackupStorageLocation{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "openshift-adp"}}
+	credentials := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: common.DefaultCredentialSecretName, Namespace: "openshift-adp"},
+		Data:       map[string][]byte{common.DefaultCredentialSecretKey: []byte("[default]\naws_access_key_id = AKIDEXAMPLE\naws_secret_access_key = secret\n")},
+	}
+	etcdKey := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "etcd-key", Namespace: "clusters-test"},
+		Data:       map[string][]byte{common.AESCBCKeySecretKey: []byte("b3RoZXI=")},
+	}
+	aescbc := &hyperv1.SecretEncryptionSpec{
+		Type:   hyperv1.AESCBC,
+		AESCBC: &hyperv1.AESCBCSpec{ActiveKey: corev1.LocalObjectReference{Name: "etcd-key"}},
+	}
+	awsKMS := &hyperv1.SecretEncryptionSpec{
+		Type: hyperv1.KMS,
+		KMS: &hyperv1.KMSSpec{Provider: hyperv1.AWS, AWS: &hyperv1.AWSKMSSpec{
+			Region:    "us-east-1",
+			ActiveKey: hyperv1.AWSKMSKeyEntry{ARN: "arn:aws:kms:us-east-1:123456789012:key/active"},
+		}},
+	}
+
+	tests := []struct {
+		name        string
+		spec        *hyperv1.SecretEncryptionSpec
+		annotations map[string]string
+		kms         *mockKMSClient
+		errSubstr   string
+	}{
+		{
+			name:        "When the restored aescbc key is the backed up one, It Should return no error",
+			spec:        aescbc,
+			annotations: map[string]string{common.SecretEncryptionKeysAnnotation: `{"etcd-key":"sha256:` + fmt.Sprintf("%x", sha256.Sum256([]byte("b3RoZXI="))) + `"}`},
+		},
+		{
+			name:        "When an existing Secret kept a different aescbc key, It Should fail",
+			spec:        aescbc,
+			annotations: map[string]string{common.SecretEncryptionKeysAnnotation: `{"etcd-key":"sha256:0000"}`},
+			errSubstr:   "holds a different key than the backed up one",
+		},
+		{
+			name:      "When the AWS KMS key is disabled, It Should fail",
+			spec:      awsKMS,
+			kms:       &mockKMSClient{metadata: &aws.KeyMetadata{KeyState: "Disabled"}},
+			errSubstr: "is Disabled, it must be Enabled",
+		},
+		{
+			name: "When the AWS KMS key cannot be described with the plugin credentials, It Should only warn",
+			spec: awsKMS,
+			kms:  &mockKMSClient{err: &aws.KMSError{Code: "AccessDeniedException", Message: "not authorized"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			kms := tt.kms
+			if kms == nil {
+				kms = &mockKMSClient{err: errors.New("unexpected KMS call")}
+			}
+			plugin := &RestorePlugin{
+				log:          logrus.New(),
+				client:       fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(bsl, credentials, etcdKey).Build(),
+				newKMSClient: func() aws.KMSKeyDescriber { return kms },
+			}
+			hcp := &hyperv1.HostedControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "clusters-test"},
+				Spec:       hyperv1.HostedControlPlaneSpec{SecretEncryption: tt.spec},
+			}
+			backup := &velerov1.Backup{Spec: velerov1.BackupSpec{StorageLocation: "default"}}
+
+			err := plugin.verifyEncryptionKeys(context.TODO(), backup, tt.annotations, hcp)
+			if tt.errSubstr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errSubstr)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
@@ -2,25 +2,37 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
+	"reflect"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
-	hive "github.com/openshift/hive/apis/hive/v1"
 	"github.com/openshift/hypershift-oadp-plugin/pkg/azblobsas"
 	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
 	plugtypes "github.com/openshift/hypershift-oadp-plugin/pkg/core/types"
 	validation "github.com/openshift/hypershift-oadp-plugin/pkg/core/validation"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/hooks"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/notify"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/platform/aws"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/releaseimage"
 	"github.com/openshift/hypershift-oadp-plugin/pkg/s3presign"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/tracing"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/version"
 	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
 	"github.com/sirupsen/logrus"
 	velerov1api "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
 	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	"go.opentelemetry.io/otel/attribute"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -37,23 +49,33 @@ type RestorePlugin struct {
 	fsBackup  bool
 	hasDPA    bool // true when OADP+DPA is detected, false for standalone Velero
 
+	hoNamespace                 string
+	platforms                   []hyperv1.PlatformType // platforms to register resources for, nil means all
+	environmentValidatedRestore string                 // the restore the target management cluster last passed the pre-restore checks for
+	compatibilityCheckedRestore string                 // the restore whose plugin compatibility was last checked
+	disabledCheckedRestore      string                 // the restore last checked for a HostedCluster opted out of the plugin
+	pluginDisabled              bool                   // set when the HostedCluster of disabledCheckedRestore opted out of the plugin
+
+	imageChecker  *releaseimage.Checker
+	// checkedImages holds the release images already checked, a sync.Map since Velero may run
+	// Execute for several items at once
+	checkedImages sync.Map
+
+	// hooks runs the user supplied hooks, nil when none is configured
+	hooks *hooks.Runner
+
+	// notifyWatcher starts the completion notification Job, nil when notifications are
+	// disabled, and notifyStartedRestore is the restore it was last started for
+	notifyWatcher        *notify.Watcher
+	notifyStartedRestore string
+
 	newTokenProvider func(creds *azblobsas.AADCredentials) (azblobsas.TokenProvider, error)
 	newSTSClient    func() s3presign.STSAssumeRoler
+	newKMSClient     func() aws.KMSKeyDescriber
 
 	*plugtypes.RestoreOptions
 }
 
-type RestoreOptions struct {
-	// Migration is a flag to indicate if the backup is for migration purposes.
-	migration bool
-	// Readopt Nodes is a flag to indicate if the nodes should be reprovisioned or not during restore.
-	readoptNodes bool
-	// ManagedServices is a flag to indicate if the backup is done for ManagedServices like ROSA, ARO, etc.
-	managedServices bool
-	// AWSRegenPrivateLink is a flag to indicate if the PrivateLink should be regenerated in AWS.
-	awsRegenPrivateLink bool
-}
-
 // NewRestorePlugin instantiates RestorePlugin.
 func NewRestorePlugin(logger logrus.FieldLogger) (*RestorePlugin, error) {
 	var (
@@ -97,22 +119,58 @@ func NewRestorePlugin(logger logrus.FieldLogger) (*RestorePlugin, error) {
 		logger.Info("Standalone Velero detected, will use fallback credentials when BSL has no credential reference")
 	}
 
+	if err := tracing.Setup(ctx, pluginConfig.Data[common.ConfigKeyTracingEndpoint]); err != nil {
+		logger.Warnf("Could not configure tracing, spans will not be exported: %v", err)
+	}
+
 	validator := &validation.RestorePluginValidator{
 		Log:       logger,
 		Client:    client,
 		LogHeader: "restore",
 	}
 
+	hoNamespace := common.DefaultHONamespace
+	if v, ok := pluginConfig.Data[common.ConfigKeyHONamespace]; ok && v != "" {
+		hoNamespace = v
+	}
+
+	platforms, err := restorePlatforms(pluginConfig.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error reading platforms: %s", err.Error())
+	}
+	if len(platforms) > 0 {
+		logger.Infof("Handling resources for platforms %v", platforms)
+	}
+
+	hookRunner, err := hooks.NewRunner(pluginConfig.Data, client, ns, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring hooks: %s", err.Error())
+	}
+
+	notifyWatcher, err := notify.NewWatcher(pluginConfig.Data, client, ns)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring notifications: %s", err.Error())
+	}
+
+	if err := checkPermissions(ctx, client, common.RestorePermissions(ns, hoNamespace, hookRunner != nil || notifyWatcher != nil), logger); err != nil {
+		return nil, err
+	}
+
 	rp := &RestorePlugin{
 		log:              logger,
 		ctx:              ctx,
 		client:           client,
 		fsBackup:         false,
 		hasDPA:           hasDPA,
+		hoNamespace:      hoNamespace,
+		platforms:        platforms,
 		config:           pluginConfig.Data,
 		validator:        validator,
+		hooks:            hookRunner,
+		notifyWatcher:    notifyWatcher,
 		newTokenProvider: azblobsas.NewAADTokenProvider,
 		newSTSClient:    func() s3presign.STSAssumeRoler { return s3presign.NewSTSClient() },
+		newKMSClient:     func() aws.KMSKeyDescriber { return aws.NewKMSClient() },
 	}
 
 	if rp.RestoreOptions, err = rp.validator.ValidatePluginConfig(rp.config); err != nil {
@@ -124,27 +182,49 @@ func NewRestorePlugin(logger logrus.FieldLogger) (*RestorePlugin, error) {
 	return rp, nil
 }
 
+// restorePlatforms returns the platforms the restore plugin registers the provider resources
+// of: the ones of the platforms option, or nil, every platform, when it is unset. The
+// HostedClusters already on the target tell nothing about the platform of the backup being
+// restored, which AppliesTo is asked for before any item is seen.
+func restorePlatforms(config map[string]string) ([]hyperv1.PlatformType, error) {
+	configured := config[common.ConfigKeyPlatforms]
+	if configured == "" {
+		return nil, nil
+	}
+	return common.ParsePlatforms(configured)
+}
+
 func (p *RestorePlugin) Name() string {
 	return "HCPRestorePlugin"
 }
 
 func (p *RestorePlugin) AppliesTo() (velero.ResourceSelector, error) {
 	return velero.ResourceSelector{
-		IncludedResources: slices.Concat(
-			plugtypes.BackupCommonResources,
-			plugtypes.BackupAWSResources,
-			plugtypes.BackupAzureResources,
-			plugtypes.BackupIBMPowerVSResources,
-			plugtypes.BackupOpenStackResources,
-			plugtypes.BackupKubevirtResources,
-			plugtypes.BackupAgentResources,
-		),
+		IncludedResources: plugtypes.PlatformResources(p.platforms),
 	}, nil
 }
 
+// Execute restores the item within the executeTimeout option when set.
 func (p *RestorePlugin) Execute(input *velero.RestoreItemActionExecuteInput) (*velero.RestoreItemActionExecuteOutput, error) {
+	var timeout time.Duration
+	if p.RestoreOptions != nil {
+		timeout = p.ExecuteTimeout
+	}
+	ctx, cancel := executeContext(p.ctx, timeout)
+	defer cancel()
+
+	name := itemName(input.Item)
+	ctx, span := tracing.Start(tracing.WithBackupTrace(ctx, input.Restore.UID), "RestorePlugin.Execute",
+		attribute.String("velero.restore", input.Restore.Name), attribute.String("item", name))
+	output, err := p.execute(ctx, input)
+	err = deadlineError(ctx, err, timeout, name)
+	tracing.End(span, err)
+	tracing.Flush(p.ctx)
+	return output, err
+}
+
+func (p *RestorePlugin) execute(ctx context.Context, input *velero.RestoreItemActionExecuteInput) (*velero.RestoreItemActionExecuteOutput, error) {
 	p.log.Debugf("Entering Hypershift restore plugin")
-	ctx := context.Context(p.ctx)
 
 	// get the backup associated with the restore
 	backup := new(velerov1api.Backup)
@@ -174,108 +254,444 @@ func (p *RestorePlugin) Execute(input *velero.RestoreItemActionExecuteInput) (*v
 		return nil, fmt.Errorf("included namespaces from backup object is nil")
 	}
 
+	if disabled, err := p.checkPluginDisabled(ctx, input.Restore.Name, backup); err != nil {
+		return nil, err
+	} else if disabled {
+		return velero.NewRestoreItemActionExecuteOutput(input.Item), nil
+	}
+
+	// Validate the target management cluster once per restore, before any hypershift item is restored
+	if p.environmentValidatedRestore != input.Restore.Name {
+		if err := p.validator.ValidateEnvironment(ctx, p.hoNamespace); err != nil {
+			return nil, err
+		}
+		p.environmentValidatedRestore = input.Restore.Name
+	}
+
+	if err := p.checkPluginCompatibility(input, backup.Name); err != nil {
+		return nil, err
+	}
+
+	// Partial restores carry no HostedCluster, whose handler otherwise starts the watcher.
+	// Its name is on the labels of the items backed up by the plugin.
+	if common.IsPartialRestore(input.Restore) {
+		if hcName := labelOf(input.Item, common.HostedClusterLabel); hcName != "" {
+			p.startNotificationWatcher(ctx, input.Restore.Name, hcName)
+		}
+	}
+
+	if existingOutput, err := p.restoreExisting(ctx, input); err != nil || existingOutput != nil {
+		return existingOutput, err
+	}
+
+	output := velero.NewRestoreItemActionExecuteOutput(input.Item)
 	kind := input.Item.GetObjectKind().GroupVersionKind().Kind
-	switch {
-	case kind == common.HostedControlPlaneKind:
-		hcp := &hyperv1.HostedControlPlane{}
-		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(input.Item.UnstructuredContent(), hcp); err != nil {
-			return nil, fmt.Errorf("error converting item to HostedControlPlane: %v", err)
+	if handler, ok := handlerFor(input.Item); ok {
+		var handlerOutput *velero.RestoreItemActionExecuteOutput
+		if err := retryTransient(func() (err error) {
+			handlerOutput, err = handler.Restore(ctx, p, input, backup)
+			return err
+		}); err != nil {
+			p.log.WithField("errorClass", common.ErrorClass(err)).Errorf("Error restoring %s: %v", kind, err)
+			return nil, err
 		}
-		if err := p.validator.ValidatePlatformConfig(hcp, p.config); err != nil {
-			return nil, fmt.Errorf("error checking platform configuration: %v", err)
+		if handlerOutput != nil {
+			output = handlerOutput
 		}
+	}
 
-		metadata, err := meta.Accessor(input.Item)
-		if err != nil {
-			return nil, fmt.Errorf("error getting metadata accessor: %v", err)
+	if !output.SkipRestore {
+		if err := p.repairOwnerReferences(ctx, output.UpdatedItem); err != nil {
+			return nil, err
 		}
-		annotations := metadata.GetAnnotations()
-		snapshotURL := annotations[common.EtcdSnapshotURLAnnotation]
-		if snapshotURL != "" {
-			snapshotURL, err = p.signSnapshotURL(ctx, backup, snapshotURL, hcp.Name)
-			if err != nil {
-				return nil, err
-			}
+	}
+	return output, nil
+}
 
-			if hcp.Spec.Etcd.Managed != nil {
-				hcp.Spec.Etcd.Managed.Storage.RestoreSnapshotURL = []string{snapshotURL}
-				p.log.Infof("Injected restoreSnapshotURL into HostedControlPlane %s", hcp.Name)
+// checkPluginDisabled reports, checking once per restore, whether a HostedCluster of the
+// backed up namespaces opted out of the plugin through PluginAnnotation, in which case the
+// items are restored unchanged. Only a HostedCluster present on the target cluster is seen,
+// as when restoring in place.
+func (p *RestorePlugin) checkPluginDisabled(ctx context.Context, restore string, backup *velerov1api.Backup) (bool, error) {
+	if p.disabledCheckedRestore == restore {
+		return p.pluginDisabled, nil
+	}
+	disabled, err := common.FindPluginDisabledHostedCluster(ctx, p.client, backup.Spec.IncludedNamespaces)
+	if err != nil {
+		return false, err
+	}
+	p.disabledCheckedRestore = restore
+	p.pluginDisabled = disabled != ""
+	if p.pluginDisabled {
+		p.log.Infof("HostedCluster %s sets %s=%s, restoring its items unchanged", disabled, common.PluginAnnotation, common.PluginDisabled)
+	}
+	return p.pluginDisabled, nil
+}
 
-				unstructuredHCP, err := runtime.DefaultUnstructuredConverter.ToUnstructured(hcp)
-				if err != nil {
-					return nil, fmt.Errorf("error converting HostedControlPlane to unstructured: %v", err)
-				}
-				input.Item.SetUnstructuredContent(unstructuredHCP)
-			}
+// checkPluginCompatibility checks, once per restore, that the running plugin can restore the
+// backup, from the backup schema and plugin version recorded on the first restored item.
+// Supported backups are restored silently and those supported with warnings with a warning.
+// Unsupported ones fail the restore up front, unless tolerateErrors lists pluginVersion.
+func (p *RestorePlugin) checkPluginCompatibility(input *velero.RestoreItemActionExecuteInput, backup string) error {
+	if p.compatibilityCheckedRestore == input.Restore.Name {
+		return nil
+	}
+	metadata, err := meta.Accessor(input.Item)
+	if err != nil {
+		return fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	annotations := metadata.GetAnnotations()
+	schema, err := common.ParseBackupSchema(annotations)
+	if err != nil {
+		return common.NewValidationError("%s of backup %s: %v", itemName(input.Item), backup, err)
+	}
+
+	backupVersion := annotations[common.BackupPluginVersionAnnotation]
+	if schema == common.BackupSchemaLegacy {
+		backupVersion = "predating the backup schema"
+	}
+	compatibility, reason := common.CheckPluginCompatibility(schema, backupVersion, version.Version)
+	switch compatibility {
+	case common.CompatibilityUnsupported:
+		err := common.NewValidationError("backup %s is not supported: %s", backup, reason)
+		if err := common.TolerateError(p.log, p.TolerateErrors, common.ToleratePluginVersion, err); err != nil {
+			return err
+		}
+	case common.CompatibilitySupportedWithWarnings:
+		p.log.Warnf("Backup %s is supported with warnings: %s", backup, reason)
+	}
+	p.compatibilityCheckedRestore = input.Restore.Name
+	return nil
+}
+
+// restoreExisting applies the existingObjectPolicy option to an item whose live counterpart
+// is the same object: it has the backed up UID, or for HostedClusters and HostedControlPlanes
+// the backed up infraID. Such items belong to a control plane that is still alive, and are
+// skipped, or have their labels, annotations and spec merged into the live object, instead of
+// conflicting with it. It returns nil when the item is restored as usual, through its handler.
+// The spec of a live HostedCluster is compared with the backed up one whatever the policy.
+func (p *RestorePlugin) restoreExisting(ctx context.Context, input *velero.RestoreItemActionExecuteInput) (*velero.RestoreItemActionExecuteOutput, error) {
+	var policy string
+	if p.RestoreOptions != nil && p.ExistingObjectPolicy != common.ExistingObjectPolicyIgnore {
+		policy = p.ExistingObjectPolicy
+	}
+	gvk := input.Item.GetObjectKind().GroupVersionKind()
+	if policy == "" && gvk.GroupKind() != common.HostedClusterGroupKind {
+		return nil, nil
+	}
+	backedUp := input.ItemFromBackup
+	if backedUp == nil {
+		backedUp = input.Item
+	}
+	metadata, err := meta.Accessor(backedUp)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	if err := p.client.Get(ctx, types.NamespacedName{Name: metadata.GetName(), Namespace: metadata.GetNamespace()}, live); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
 		}
+		return nil, fmt.Errorf("error getting live %s: %w", itemName(input.Item), err)
+	}
 
-	case kind == "Pod":
-		p.log.Debugf("Pod found, skipping restore")
-		return velero.NewRestoreItemActionExecuteOutput(input.Item).WithoutRestore(), nil
+	name := itemName(input.Item)
+	if gvk.GroupKind() == common.HostedClusterGroupKind {
+		p.reportSpecDrift(name, backedUp, live)
+	}
+	if policy == "" || !sameObject(backedUp, metadata, live) {
+		return nil, nil
+	}
 
-	case kind == "StatefulSet":
-		metadata, err := meta.Accessor(input.Item)
+	switch policy {
+	case common.ExistingObjectPolicyPatch, common.ExistingObjectPolicyMerge:
+		item, err := meta.Accessor(input.Item)
+		if err != nil {
+			return nil, fmt.Errorf("error getting metadata accessor: %w", err)
+		}
+		labels, annotations := item.GetLabels(), item.GetAnnotations()
+		spec, hasSpec := input.Item.UnstructuredContent()["spec"]
+		if policy == common.ExistingObjectPolicyMerge {
+			// The live values win, the backup only fills in what the live object lacks
+			labels, annotations = missingKeys(labels, live.GetLabels()), missingKeys(annotations, live.GetAnnotations())
+			if hasSpec {
+				spec = fillMissing(live.Object["spec"], spec)
+			}
+		}
+		patch := map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"labels":      labels,
+				"annotations": annotations,
+			},
+		}
+		if hasSpec {
+			patch["spec"] = spec
+		}
+		data, err := json.Marshal(patch)
 		if err != nil {
-			return nil, fmt.Errorf("error getting metadata accessor: %v", err)
+			return nil, fmt.Errorf("error building the patch of %s: %w", name, err)
 		}
-		if metadata.GetName() == "etcd" && p.config[common.ConfigKeyEtcdBackupMethod] == common.EtcdBackupMethodEtcdSnapshot {
-			p.log.Infof("etcd StatefulSet found, skipping restore (using etcdSnapshot method)")
-			return velero.NewRestoreItemActionExecuteOutput(input.Item).WithoutRestore(), nil
+		if err := p.client.Patch(ctx, live, crclient.RawPatch(types.MergePatchType, data)); err != nil {
+			return nil, fmt.Errorf("error patching live %s: %w", name, err)
 		}
+		if policy == common.ExistingObjectPolicyMerge {
+			p.log.Infof("Merged the backed up labels, annotations and spec missing from live %s", name)
+		} else {
+			p.log.Infof("Patched live %s with the backed up labels, annotations and spec", name)
+		}
+	default:
+		p.log.Infof("Skipping %s, the live object is the backed up one", name)
+	}
+	return velero.NewRestoreItemActionExecuteOutput(input.Item).WithoutRestore(), nil
+}
 
-	case common.MainKinds[kind]:
-		if kind == common.HostedClusterKind {
-			metadata, err := meta.Accessor(input.Item)
-			if err != nil {
-				return nil, fmt.Errorf("error getting metadata accessor: %v", err)
-			}
-			common.AddAnnotation(metadata, common.HostedClusterRestoredFromBackupAnnotation, "")
-			hcName := metadata.GetName()
-			p.log.Infof("Added restore annotation to HostedCluster %s", hcName)
-
-			// Inject restoreSnapshotURL if etcd backup URL is available.
-			// Read from annotation because Velero strips status during restore.
-			annotations := metadata.GetAnnotations()
-			snapshotURL := annotations[common.EtcdSnapshotURLAnnotation]
-			if snapshotURL != "" {
-				snapshotURL, err = p.signSnapshotURL(ctx, backup, snapshotURL, hcName)
-				if err != nil {
-					return nil, err
-				}
-
-				hc := &hyperv1.HostedCluster{}
-				if err := runtime.DefaultUnstructuredConverter.FromUnstructured(input.Item.UnstructuredContent(), hc); err != nil {
-					return nil, fmt.Errorf("error converting item to HostedCluster: %v", err)
-				}
-				if hc.Spec.Etcd.Managed != nil {
-					hc.Spec.Etcd.Managed.Storage.RestoreSnapshotURL = []string{snapshotURL}
-					p.log.Infof("Injected restoreSnapshotURL into HostedCluster %s", hc.Name)
-
-					unstructuredHC, err := runtime.DefaultUnstructuredConverter.ToUnstructured(hc)
-					if err != nil {
-						return nil, fmt.Errorf("error converting HostedCluster to unstructured: %v", err)
-					}
-					input.Item.SetUnstructuredContent(unstructuredHC)
-				}
+// reportSpecDrift warns about each spec field of the live object that differs from the
+// backed up one, so operators see what restoring over it would change before choosing an
+// existingObjectPolicy.
+func (p *RestorePlugin) reportSpecDrift(name string, backedUp runtime.Unstructured, live *unstructured.Unstructured) {
+	drift := specDrift("spec", backedUp.UnstructuredContent()["spec"], live.Object["spec"])
+	if len(drift) == 0 {
+		p.log.Infof("Live %s matches the backed up spec", name)
+		return
+	}
+	p.log.Warnf("Live %s differs from the backup in %d spec fields", name, len(drift))
+	for _, field := range drift {
+		p.log.Warnf("Live %s drifted: %s", name, field)
+	}
+}
+
+// specDrift returns the fields under path whose live value differs from the backed up one,
+// as "<path>: live <value>, backup <value>", sorted by path. Lists are compared whole.
+func specDrift(path string, backedUp, live any) []string {
+	backedUpFields, backedUpIsMap := backedUp.(map[string]any)
+	liveFields, liveIsMap := live.(map[string]any)
+	if backedUpIsMap && liveIsMap {
+		keys := slices.Collect(maps.Keys(backedUpFields))
+		for key := range liveFields {
+			if _, ok := backedUpFields[key]; !ok {
+				keys = append(keys, key)
 			}
 		}
+		slices.Sort(keys)
+		var drift []string
+		for _, key := range keys {
+			drift = append(drift, specDrift(path+"."+key, backedUpFields[key], liveFields[key])...)
+		}
+		return drift
+	}
+	if reflect.DeepEqual(backedUp, live) {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: live %s, backup %s", path, driftValue(live), driftValue(backedUp))}
+}
 
-	case kind == common.ClusterDeploymentKind:
-		clusterdDeployment := &hive.ClusterDeployment{}
-		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(input.Item.UnstructuredContent(), clusterdDeployment); err != nil {
-			return nil, fmt.Errorf("error converting item to clusterdDeployment: %v", err)
+func driftValue(value any) string {
+	if value == nil {
+		return "unset"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// fillMissing returns live with the fields it lacks taken from backedUp, recursing into
+// objects present in both. Values set on both sides keep the live one.
+func fillMissing(live, backedUp any) any {
+	liveFields, liveIsMap := live.(map[string]any)
+	backedUpFields, backedUpIsMap := backedUp.(map[string]any)
+	if live == nil {
+		return backedUp
+	}
+	if !liveIsMap || !backedUpIsMap {
+		return live
+	}
+	filled := make(map[string]any, len(liveFields))
+	for key, value := range liveFields {
+		filled[key] = value
+	}
+	for key, value := range backedUpFields {
+		filled[key] = fillMissing(liveFields[key], value)
+	}
+	return filled
+}
+
+// missingKeys returns the entries of backedUp whose key live lacks.
+func missingKeys(backedUp, live map[string]string) map[string]string {
+	missing := map[string]string{}
+	for key, value := range backedUp {
+		if _, ok := live[key]; !ok {
+			missing[key] = value
 		}
+	}
+	return missing
+}
 
-		clusterDeploymentCP := clusterdDeployment.DeepCopy()
-		clusterDeploymentCP.Spec.PreserveOnDelete = true
+// sameObject reports whether the live object is the backed up one, or for HostedClusters
+// and HostedControlPlanes one recreated for the same infrastructure. HyperShift objects are
+// backed up without their UID, kept in the SourceUIDAnnotation instead.
+func sameObject(backedUp runtime.Unstructured, metadata metav1.Object, live *unstructured.Unstructured) bool {
+	uid := metadata.GetUID()
+	if uid == "" {
+		uid = types.UID(metadata.GetAnnotations()[common.SourceUIDAnnotation])
+	}
+	if uid != "" && uid == live.GetUID() {
+		return true
+	}
+	switch live.GroupVersionKind().GroupKind() {
+	case common.HostedClusterGroupKind, common.HostedControlPlaneGroupKind:
+		infraID, _, _ := unstructured.NestedString(backedUp.UnstructuredContent(), "spec", "infraID")
+		liveInfraID, _, _ := unstructured.NestedString(live.Object, "spec", "infraID")
+		return infraID != "" && infraID == liveInfraID
+	}
+	return false
+}
 
-		if err := p.client.Update(ctx, clusterDeploymentCP); err != nil {
-			return nil, fmt.Errorf("error updating ClusterDeployment resource with PreserveOnDelete option: %w", err)
+// repairOwnerReferences points the ownerReferences of a restored item at the live owners.
+// The backed up references carry the source cluster UIDs, and the garbage collector deletes
+// dependents whose owner UID does not exist. References to owners not restored yet are
+// dropped: the HyperShift controllers set them again when they reconcile the owner.
+func (p *RestorePlugin) repairOwnerReferences(ctx context.Context, item runtime.Unstructured) error {
+	metadata, err := meta.Accessor(item)
+	if err != nil {
+		return fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	refs := metadata.GetOwnerReferences()
+	if len(refs) == 0 || metadata.GetNamespace() == "" {
+		return nil
+	}
+
+	repaired := make([]metav1.OwnerReference, 0, len(refs))
+	for _, ref := range refs {
+		owner := &unstructured.Unstructured{}
+		owner.SetAPIVersion(ref.APIVersion)
+		owner.SetKind(ref.Kind)
+		if err := p.client.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: metadata.GetNamespace()}, owner); err != nil {
+			if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				p.log.Infof("Dropping ownerReference of %s/%s to %s %s, not restored yet", metadata.GetNamespace(), metadata.GetName(), ref.Kind, ref.Name)
+				continue
+			}
+			return fmt.Errorf("error getting owner %s %s of %s/%s: %w", ref.Kind, ref.Name, metadata.GetNamespace(), metadata.GetName(), err)
 		}
+		if ref.UID != owner.GetUID() {
+			p.log.Debugf("Repointing ownerReference of %s/%s to %s %s UID %s", metadata.GetNamespace(), metadata.GetName(), ref.Kind, ref.Name, owner.GetUID())
+			ref.UID = owner.GetUID()
+		}
+		repaired = append(repaired, ref)
+	}
+	metadata.SetOwnerReferences(repaired)
+	return nil
+}
+
+// startNotificationWatcher starts, once per restore, the Job reporting its outcome to the
+// notification webhook. Failing to start it does not fail the restore.
+func (p *RestorePlugin) startNotificationWatcher(ctx context.Context, restoreName, hcName string) {
+	if p.notifyWatcher == nil || p.notifyStartedRestore == restoreName {
+		return
+	}
+	if err := p.notifyWatcher.Ensure(ctx, notify.OperationRestore, restoreName, hcName); err != nil {
+		p.log.Warnf("Completion notification disabled for restore %s: %v", restoreName, err)
+	}
+	p.notifyStartedRestore = restoreName
+}
+
+// ensureNamespaces makes sure the HostedCluster namespace and its HostedControlPlane
+// namespace exist before the HostedCluster is restored. The HCP namespace gets the
+// labels the control plane requires, which Velero's bare namespace creation omits.
+func (p *RestorePlugin) ensureNamespaces(ctx context.Context, hcNamespace, hcName string) error {
+	if err := common.EnsureNamespace(ctx, p.client, p.log, hcNamespace, nil); err != nil {
+		return fmt.Errorf("error ensuring HostedCluster namespace: %w", err)
+	}
 
+	hcpNamespace := common.GetHCPNamespace(hcName, hcNamespace)
+	if err := common.EnsureNamespace(ctx, p.client, p.log, hcpNamespace, common.ControlPlaneNamespaceLabels); err != nil {
+		return fmt.Errorf("error ensuring HostedControlPlane namespace: %w", err)
 	}
+	p.log.Infof("Ensured namespaces %s and %s for HostedCluster %s", hcNamespace, hcpNamespace, hcName)
 
-	return velero.NewRestoreItemActionExecuteOutput(input.Item), nil
+	return nil
+}
+
+// markRestorePending pauses reconciliation of the restored item through spec.pausedUntil
+// and flags it with the restore-pending annotation, so it stays untouched until an
+// operator resumes it with the unpause-restore command.
+func (p *RestorePlugin) markRestorePending(item runtime.Unstructured, kind string) error {
+	content := item.UnstructuredContent()
+	if err := unstructured.SetNestedField(content, "true", "spec", "pausedUntil"); err != nil {
+		return fmt.Errorf("error setting pausedUntil: %w", err)
+	}
+	item.SetUnstructuredContent(content)
+
+	metadata, err := meta.Accessor(item)
+	if err != nil {
+		return fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	common.AddAnnotation(metadata, common.RestorePendingAnnotation, "true")
+	p.log.Infof("%s %s restored paused, pending confirmation", kind, metadata.GetName())
+	return nil
+}
+
+// protectFromDeletion adds the deletion protection finalizer to the restored item for the
+// deletionProtection grace period, until unprotect-restore lifts it.
+func (p *RestorePlugin) protectFromDeletion(item runtime.Unstructured, kind string) error {
+	metadata, err := meta.Accessor(item)
+	if err != nil {
+		return fmt.Errorf("error getting metadata accessor: %w", err)
+	}
+	until := time.Now().Add(p.DeletionProtection)
+	common.ProtectFromDeletion(metadata, until)
+	p.log.Infof("%s %s/%s protected from deletion until %s", kind, metadata.GetNamespace(), metadata.GetName(), until.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// checkReleaseImage verifies the release image can be pulled from the target environment,
+// honoring the cluster image mirrors and using the given pull secret. Results are cached
+// per image, since NodePools usually share the HostedCluster release.
+// A failed check is tolerated, once per image, when tolerateErrors lists releaseImage.
+func (p *RestorePlugin) checkReleaseImage(ctx context.Context, namespace, pullSecretName, image string) error {
+	if image == "" {
+		return nil
+	}
+	if _, checked := p.checkedImages.Load(image); checked {
+		return nil
+	}
+
+	err := p.verifyReleaseImage(ctx, namespace, pullSecretName, image)
+	if tolerateErr := common.TolerateError(p.log, p.TolerateErrors, common.TolerateReleaseImage, err); tolerateErr != nil {
+		return tolerateErr
+	}
+	p.checkedImages.Store(image, true)
+	if err == nil {
+		p.log.Infof("Release image %s is available from the target environment", image)
+	}
+	return nil
+}
+
+func (p *RestorePlugin) verifyReleaseImage(ctx context.Context, namespace, pullSecretName, image string) error {
+	if p.imageChecker == nil {
+		mirrors, err := releaseimage.DiscoverMirrors(ctx, p.client)
+		if err != nil {
+			return fmt.Errorf("error discovering image mirrors: %w", err)
+		}
+		p.imageChecker = releaseimage.NewChecker(mirrors)
+	}
+
+	var pullSecret []byte
+	if pullSecretName != "" {
+		secret := &corev1.Secret{}
+		if err := p.client.Get(ctx, types.NamespacedName{Name: pullSecretName, Namespace: namespace}, secret); err != nil {
+			return fmt.Errorf("error getting pull secret %s/%s: %w", namespace, pullSecretName, err)
+		}
+		pullSecret = secret.Data[corev1.DockerConfigJsonKey]
+	}
+
+	if err := p.imageChecker.Check(ctx, image, pullSecret); err != nil {
+		return fmt.Errorf("release image check failed: %w", err)
+	}
+	return nil
 }
 
 // signSnapshotURL converts a raw snapshot URL (s3:// or Azure Blob https://) into a
@@ -373,7 +789,7 @@ func (p *RestorePlugin) presignAzBlobUrl(ctx context.Context, backup *velerov1ap
 		Container:  container,
 		Blob:       blob,
 		AccountKey: creds.StorageAccountAccessKey,
-		Expiry:     azblobsas.DefaultSASExpiry,
+		Expiry:     p.snapshotURLExpiry(),
 		Endpoint:   endpoint,
 	})
 }
@@ -408,8 +824,9 @@ func (p *RestorePlugin) presignAzBlobUrlWithAAD(ctx context.Context, account, co
 		return "", fmt.Errorf("error acquiring AAD token for storage: %w", err)
 	}
 
+	expiry := p.snapshotURLExpiry()
 	now := time.Now().UTC()
-	delegationKey, err := azblobsas.GetUserDelegationKey(ctx, account, token, now, now.Add(azblobsas.DefaultSASExpiry), endpoint)
+	delegationKey, err := azblobsas.GetUserDelegationKey(ctx, account, token, now, now.Add(expiry), endpoint)
 	if err != nil {
 		return "", fmt.Errorf("error getting user delegation key: %w", err)
 	}
@@ -419,11 +836,20 @@ func (p *RestorePlugin) presignAzBlobUrlWithAAD(ctx context.Context, account, co
 		Container:     container,
 		Blob:          blob,
 		DelegationKey: delegationKey,
-		Expiry:        azblobsas.DefaultSASExpiry,
+		Expiry:        expiry,
 		Endpoint:      endpoint,
 	})
 }
 
+// snapshotURLExpiry is how long the presigned etcd snapshot URLs stay valid, per the
+// snapshotURLExpiry timeout.
+func (p *RestorePlugin) snapshotURLExpiry() time.Duration {
+	if p.RestoreOptions == nil {
+		return common.DefaultTimeouts.SnapshotURLExpiry
+	}
+	return p.Timeouts.WithDefaults().SnapshotURLExpiry
+}
+
 // presignS3URL converts an s3:// URL into a pre-signed HTTPS GET URL using
 // credentials from the Velero BackupStorageLocation.
 func (p *RestorePlugin) presignS3URL(ctx context.Context, backup *velerov1api.Backup, s3URL, hcName string) (string, error) {
@@ -441,41 +867,57 @@ func (p *RestorePlugin) presignS3URL(ctx context.Context, backup *velerov1api.Ba
 	endpoint := bsl.Spec.Config["s3Url"]
 	forcePathStyle := bsl.Spec.Config["s3ForcePathStyle"] == "true"
 
+	creds, err := p.parseAWSCredentials(ctx, credData, fmt.Sprintf("restore-%s", hcName))
+	if err != nil {
+		return "", err
+	}
+
+	return s3presign.GeneratePresignedGetURL(s3presign.PresignOptions{
+		Bucket:          bucket,
+		Key:             key,
+		Region:          region,
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Expiry:          p.snapshotURLExpiry(),
+		Endpoint:        endpoint,
+		ForcePathStyle:  forcePathStyle,
+	})
+}
+
+// awsCredentials returns the AWS credentials of the BackupStorageLocation of the backup.
+func (p *RestorePlugin) awsCredentials(ctx context.Context, backup *velerov1api.Backup, sessionName string) (*s3presign.AWSCredentials, error) {
+	_, credData, _, err := p.fetchBSLCredentials(ctx, backup)
+	if err != nil {
+		return nil, err
+	}
+	return p.parseAWSCredentials(ctx, credData, sessionName)
+}
+
+// parseAWSCredentials parses static AWS credentials, or assumes the role of STS ones.
+func (p *RestorePlugin) parseAWSCredentials(ctx context.Context, credData []byte, sessionName string) (*s3presign.AWSCredentials, error) {
 	parsed, err := s3presign.ParseAWSCredentialData(credData, "default")
 	if err != nil {
-		return "", fmt.Errorf("error parsing AWS credentials: %w", err)
+		return nil, fmt.Errorf("error parsing AWS credentials: %w", err)
 	}
 
-	var creds *s3presign.AWSCredentials
 	switch parsed.Type {
 	case s3presign.STSRoleCredentialType:
 		stsClient := p.newSTSClient()
-		sessionName := fmt.Sprintf("restore-%s", hcName)
-		creds, err = stsClient.AssumeRoleWithWebIdentity(
+		creds, err := stsClient.AssumeRoleWithWebIdentity(
 			ctx,
 			parsed.STSRole.RoleARN,
 			parsed.STSRole.WebIdentityTokenFile,
 			sessionName,
 		)
 		if err != nil {
-			return "", fmt.Errorf("error assuming role via STS: %w", err)
+			return nil, fmt.Errorf("error assuming role via STS: %w", err)
 		}
-		p.log.Infof("Assumed role %s via STS for pre-signing", parsed.STSRole.RoleARN)
+		p.log.Infof("Assumed role %s via STS", parsed.STSRole.RoleARN)
+		return creds, nil
 	case s3presign.StaticCredentialType:
-		creds = parsed.Static
+		return parsed.Static, nil
 	default:
-		return "", fmt.Errorf("unsupported credential type %q", parsed.Type)
+		return nil, fmt.Errorf("unsupported credential type %q", parsed.Type)
 	}
-
-	return s3presign.GeneratePresignedGetURL(s3presign.PresignOptions{
-		Bucket:          bucket,
-		Key:             key,
-		Region:          region,
-		AccessKeyID:     creds.AccessKeyID,
-		SecretAccessKey: creds.SecretAccessKey,
-		SessionToken:    creds.SessionToken,
-		Expiry:          s3presign.DefaultPresignExpiry,
-		Endpoint:        endpoint,
-		ForcePathStyle:  forcePathStyle,
-	})
 }
@@ -2,29 +2,49 @@ package core
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/openshift/hypershift-oadp-plugin/pkg/azblobsas"
 	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/notify"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/releaseimage"
 	"github.com/openshift/hypershift-oadp-plugin/pkg/s3presign"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/version"
 	plugtypes "github.com/openshift/hypershift-oadp-plugin/pkg/core/types"
 	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
 	"github.com/sirupsen/logrus"
 	velerov1api "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
 	veleroapiv1 "github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -44,17 +64,53 @@ func (m *mockSTSClient) AssumeRoleWithWebIdentity(ctx context.Context, roleARN,
 }
 
 type mockRestoreValidator struct {
-	validatePlatformErr error
+	validatePlatformErr    error
+	validateEnvironmentErr error
+	sourceMismatches       []string
+	managementVersionsErr  error
+	releaseVersionErr      error
+	capacityProblems       []string
+	capacityChecked        bool
+	networkProblems        []string
 }
 
 func (m *mockRestoreValidator) ValidatePluginConfig(_ map[string]string) (*plugtypes.RestoreOptions, error) {
 	return &plugtypes.RestoreOptions{}, nil
 }
 
-func (m *mockRestoreValidator) ValidatePlatformConfig(_ *hyperv1.HostedControlPlane, _ map[string]string) error {
+func (m *mockRestoreValidator) ValidatePlatformConfig(_ context.Context, _ *hyperv1.HostedControlPlane, _ map[string]string) error {
 	return m.validatePlatformErr
 }
 
+func (m *mockRestoreValidator) ValidateEnvironment(_ context.Context, _ string) error {
+	return m.validateEnvironmentErr
+}
+
+func (m *mockRestoreValidator) ValidatePlatformCRDs(_ context.Context, _ hyperv1.PlatformType) error {
+	return nil
+}
+
+func (m *mockRestoreValidator) ValidateSourceMetadata(_ context.Context, _ *common.SourceMetadata, _ *hyperv1.HostedCluster, _ []hyperv1.PlatformType) ([]string, error) {
+	return m.sourceMismatches, nil
+}
+
+func (m *mockRestoreValidator) ValidateManagementVersions(_ context.Context, _ *common.SourceMetadata, _ string, _ int) error {
+	return m.managementVersionsErr
+}
+
+func (m *mockRestoreValidator) ValidateReleaseVersion(_ context.Context, _ *common.SourceMetadata, _ string) error {
+	return m.releaseVersionErr
+}
+
+func (m *mockRestoreValidator) ValidateCapacity(_ context.Context, _ *hyperv1.HostedCluster) ([]string, error) {
+	m.capacityChecked = true
+	return m.capacityProblems, nil
+}
+
+func (m *mockRestoreValidator) ValidateNetworking(_ context.Context, _ *hyperv1.HostedCluster, _ *common.SourceMetadata, _ *velerov1api.Backup) ([]string, error) {
+	return m.networkProblems, nil
+}
+
 func TestPresignS3URL(t *testing.T) {
 	scheme := runtime.NewScheme()
 	_ = hyperv1.AddToScheme(scheme)
@@ -828,10 +884,11 @@ func TestRestoreExecuteEtcdStatefulSet(t *testing.T) {
 				WithObjects(hcpCRD, backup).
 				Build()
 			plugin := &RestorePlugin{
-				log:       logrus.New(),
-				ctx:       context.Background(),
-				client:    fakeClient,
-				validator: &mockRestoreValidator{},
+				log:            logrus.New(),
+				ctx:            context.Background(),
+				client:         fakeClient,
+				validator:      &mockRestoreValidator{},
+				RestoreOptions: &plugtypes.RestoreOptions{},
 				config: map[string]string{
 					common.ConfigKeyEtcdBackupMethod: tt.etcdBackupMethod,
 				},
@@ -993,10 +1050,11 @@ func TestRestoreExecuteSnapshotURL(t *testing.T) {
 					WithObjects(hcpCRD, azBSL, azSecret, backup).
 					Build()
 				return &RestorePlugin{
-					log:       logrus.New(),
-					ctx:       context.Background(),
-					client:    client,
-					validator: &mockRestoreValidator{},
+					log:            logrus.New(),
+					ctx:            context.Background(),
+					client:         client,
+					validator:      &mockRestoreValidator{},
+					RestoreOptions: &plugtypes.RestoreOptions{},
 				}
 			},
 			assert: func(t *testing.T, output *veleroapiv1.RestoreItemActionExecuteOutput) {
@@ -1035,10 +1093,11 @@ func TestRestoreExecuteSnapshotURL(t *testing.T) {
 					WithObjects(hcpCRD, bsl, secret, backup).
 					Build()
 				plugin = &RestorePlugin{
-					log:       logrus.New(),
-					ctx:       context.Background(),
-					client:    fakeClient,
-					validator: &mockRestoreValidator{},
+					log:            logrus.New(),
+					ctx:            context.Background(),
+					client:         fakeClient,
+					validator:      &mockRestoreValidator{},
+					RestoreOptions: &plugtypes.RestoreOptions{},
 				}
 			}
 
@@ -1211,10 +1270,11 @@ func TestRestoreExecuteHCPSnapshotURL(t *testing.T) {
 					WithObjects(hcpCRD, azBSL, azSecret, backup).
 					Build()
 				return &RestorePlugin{
-					log:       logrus.New(),
-					ctx:       context.Background(),
-					client:    client,
-					validator: &mockRestoreValidator{},
+					log:            logrus.New(),
+					ctx:            context.Background(),
+					client:         client,
+					validator:      &mockRestoreValidator{},
+					RestoreOptions: &plugtypes.RestoreOptions{},
 				}
 			},
 			assert: func(t *testing.T, output *veleroapiv1.RestoreItemActionExecuteOutput) {
@@ -1256,10 +1316,11 @@ func TestRestoreExecuteHCPSnapshotURL(t *testing.T) {
 				}
 				fakeClient := builder.Build()
 				plugin = &RestorePlugin{
-					log:       logrus.New(),
-					ctx:       context.Background(),
-					client:    fakeClient,
-					validator: &mockRestoreValidator{},
+					log:            logrus.New(),
+					ctx:            context.Background(),
+					client:         fakeClient,
+					validator:      &mockRestoreValidator{},
+					RestoreOptions: &plugtypes.RestoreOptions{},
 				}
 			}
 
@@ -1284,3 +1345,1500 @@ func TestRestoreExecuteHCPSnapshotURL(t *testing.T) {
 		})
 	}
 }
+
+func TestRestoreExecuteEnvironmentValidation(t *testing.T) {
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+	}
+	restore := &velerov1api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+		Spec:       velerov1api.RestoreSpec{BackupName: "test-backup"},
+	}
+
+	t.Run("When the environment validation fails, It Should return the error", func(t *testing.T) {
+		client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup).Build()
+		plugin := &RestorePlugin{
+			log:            logrus.New(),
+			ctx:            context.Background(),
+			client:         client,
+			validator:      &mockRestoreValidator{validateEnvironmentErr: fmt.Errorf("missing required CRDs")},
+			RestoreOptions: &plugtypes.RestoreOptions{},
+		}
+
+		_, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{
+			Item:    newStatefulSetUnstructured("other-sts", "clusters-test"),
+			Restore: restore,
+		})
+		if err == nil || !strings.Contains(err.Error(), "missing required CRDs") {
+			t.Fatalf("expected environment validation error, got %v", err)
+		}
+		if plugin.environmentValidatedRestore != "" {
+			t.Error("expected environmentValidatedRestore to remain empty after a failure")
+		}
+	})
+
+	t.Run("When the environment validation succeeds, It Should mark the environment as validated", func(t *testing.T) {
+		client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup).Build()
+		plugin := &RestorePlugin{
+			log:            logrus.New(),
+			ctx:            context.Background(),
+			client:         client,
+			validator:      &mockRestoreValidator{},
+			RestoreOptions: &plugtypes.RestoreOptions{},
+		}
+
+		if _, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{
+			Item:    newStatefulSetUnstructured("other-sts", "clusters-test"),
+			Restore: restore,
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if plugin.environmentValidatedRestore != "test-restore" {
+			t.Errorf("expected environmentValidatedRestore to be test-restore, got %q", plugin.environmentValidatedRestore)
+		}
+	})
+
+	t.Run("When the target management cluster breaks after a restore, It Should fail the next restore", func(t *testing.T) {
+		client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup).Build()
+		validator := &mockRestoreValidator{}
+		plugin := &RestorePlugin{
+			log:            logrus.New(),
+			ctx:            context.Background(),
+			client:         client,
+			validator:      validator,
+			RestoreOptions: &plugtypes.RestoreOptions{},
+		}
+
+		if _, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{
+			Item:    newStatefulSetUnstructured("other-sts", "clusters-test"),
+			Restore: restore,
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		validator.validateEnvironmentErr = fmt.Errorf("missing required CRDs")
+		next := restore.DeepCopy()
+		next.Name = "next-restore"
+		_, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{
+			Item:    newStatefulSetUnstructured("other-sts", "clusters-test"),
+			Restore: next,
+		})
+		if err == nil || !strings.Contains(err.Error(), "missing required CRDs") {
+			t.Fatalf("expected environment validation error, got %v", err)
+		}
+	})
+}
+
+func TestRestoreExecuteReleaseImageCheck(t *testing.T) {
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/ocp/release/manifests/4.16.0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer registry.Close()
+	registryHost := strings.TrimPrefix(registry.URL, "https://")
+
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+	}
+	restore := &velerov1api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+		Spec:       velerov1api.RestoreSpec{BackupName: "test-backup"},
+	}
+	pullSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: "clusters"},
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+	}
+
+	newNodePoolUnstructured := func(image string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]any{
+				"apiVersion": "hypershift.openshift.io/v1beta1",
+				"kind":       "NodePool",
+				"metadata":   map[string]any{"name": "my-np", "namespace": "clusters"},
+				"spec": map[string]any{
+					"clusterName": "my-hc",
+					"release":     map[string]any{"image": image},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		item      func() *unstructured.Unstructured
+		errSubstr string
+	}{
+		{
+			name: "When the HostedCluster release image is available, It Should restore the item",
+			item: func() *unstructured.Unstructured {
+				hc := newHCUnstructured("my-hc", "clusters", nil)
+				hc.Object["spec"].(map[string]any)["release"] = map[string]any{"image": registryHost + "/ocp/release:4.16.0"}
+				return hc
+			},
+		},
+		{
+			name: "When the HostedCluster release image is missing, It Should fail the restore",
+			item: func() *unstructured.Unstructured {
+				hc := newHCUnstructured("my-hc", "clusters", nil)
+				hc.Object["spec"].(map[string]any)["release"] = map[string]any{"image": registryHost + "/ocp/release:4.17.0"}
+				return hc
+			},
+			errSubstr: "release image check failed",
+		},
+		{
+			name: "When the NodePool release image is available without its HostedCluster, It Should restore the item",
+			item: func() *unstructured.Unstructured {
+				return newNodePoolUnstructured(registryHost + "/ocp/release:4.16.0")
+			},
+		},
+		{
+			name: "When the NodePool release image is missing, It Should fail the restore",
+			item: func() *unstructured.Unstructured {
+				return newNodePoolUnstructured(registryHost + "/ocp/other:4.16.0")
+			},
+			errSubstr: "manifest not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup, pullSecret).Build()
+			plugin := &RestorePlugin{
+				log:            logrus.New(),
+				ctx:            context.Background(),
+				client:         client,
+				validator:      &mockRestoreValidator{},
+				imageChecker:   &releaseimage.Checker{HTTPClient: registry.Client()},
+				RestoreOptions: &plugtypes.RestoreOptions{ReleaseImageCheck: true},
+			}
+
+			_, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{
+				Item:    tt.item(),
+				Restore: restore,
+			})
+			if tt.errSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+					t.Fatalf("expected error containing %q, got %v", tt.errSubstr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	t.Run("When items sharing a release image are restored at once, It Should check it without racing", func(t *testing.T) {
+		client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(pullSecret).Build()
+		plugin := &RestorePlugin{
+			log:            logrus.New(),
+			client:         client,
+			imageChecker:   &releaseimage.Checker{HTTPClient: registry.Client()},
+			RestoreOptions: &plugtypes.RestoreOptions{ReleaseImageCheck: true},
+		}
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				if err := plugin.checkReleaseImage(context.Background(), "clusters", "pull-secret", registryHost+"/ocp/release:4.16.0"); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			})
+		}
+		wg.Wait()
+	})
+}
+
+func TestRestoreExecuteRestorePaused(t *testing.T) {
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+	}
+	restore := &velerov1api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+		Spec:       velerov1api.RestoreSpec{BackupName: "test-backup"},
+	}
+
+	tests := []struct {
+		name          string
+		restorePaused bool
+		item          func(t *testing.T) *unstructured.Unstructured
+		wantPaused    bool
+	}{
+		{
+			name:          "When restorePaused is enabled, It Should pause the HostedCluster and flag it pending",
+			restorePaused: true,
+			item:          func(_ *testing.T) *unstructured.Unstructured { return newHCUnstructured("my-hc", "clusters", nil) },
+			wantPaused:    true,
+		},
+		{
+			name:          "When restorePaused is enabled, It Should pause the HostedControlPlane and flag it pending",
+			restorePaused: true,
+			item: func(t *testing.T) *unstructured.Unstructured {
+				return newHCPUnstructured(t, "my-hc", "clusters-my-hc", nil)
+			},
+			wantPaused: true,
+		},
+		{
+			name:          "When restorePaused is enabled, It Should pause the NodePool and flag it pending",
+			restorePaused: true,
+			item: func(_ *testing.T) *unstructured.Unstructured {
+				return &unstructured.Unstructured{
+					Object: map[string]any{
+						"apiVersion": "hypershift.openshift.io/v1beta1",
+						"kind":       "NodePool",
+						"metadata":   map[string]any{"name": "my-np", "namespace": "clusters"},
+						"spec":       map[string]any{"clusterName": "my-hc"},
+					},
+				}
+			},
+			wantPaused: true,
+		},
+		{
+			name:          "When restorePaused is disabled, It Should restore the HostedCluster unpaused",
+			restorePaused: false,
+			item:          func(_ *testing.T) *unstructured.Unstructured { return newHCUnstructured("my-hc", "clusters", nil) },
+			wantPaused:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup).Build()
+			plugin := &RestorePlugin{
+				log:            logrus.New(),
+				ctx:            context.Background(),
+				client:         client,
+				validator:      &mockRestoreValidator{},
+				RestoreOptions: &plugtypes.RestoreOptions{RestorePaused: tt.restorePaused},
+			}
+
+			output, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{
+				Item:    tt.item(t),
+				Restore: restore,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			content := output.UpdatedItem.UnstructuredContent()
+			pausedUntil, _, _ := unstructured.NestedString(content, "spec", "pausedUntil")
+			pending, _, _ := unstructured.NestedString(content, "metadata", "annotations", common.RestorePendingAnnotation)
+			if tt.wantPaused {
+				if pausedUntil != "true" || pending != "true" {
+					t.Errorf("expected item paused and pending, got pausedUntil=%q pending=%q", pausedUntil, pending)
+				}
+				return
+			}
+			if pausedUntil != "" || pending != "" {
+				t.Errorf("expected item not paused, got pausedUntil=%q pending=%q", pausedUntil, pending)
+			}
+		})
+	}
+}
+
+func TestRestoreExecuteSourceMismatch(t *testing.T) {
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+	}
+	restore := &velerov1api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+		Spec:       velerov1api.RestoreSpec{BackupName: "test-backup"},
+	}
+	source := (&common.SourceMetadata{Platform: "AWS", ManagementClusterVersion: "4.18.0"}).String()
+
+	tests := []struct {
+		name        string
+		annotations        map[string]string
+		mismatches         []string
+		networkProblems    []string
+		managementVersions error
+		releaseVersion     error
+		failPolicy         bool
+		wantErr            bool
+	}{
+		{
+			name:        "When the source matches the target, It Should restore the HostedCluster",
+			annotations: map[string]string{common.SourceMetadataAnnotation: source},
+			failPolicy:  true,
+		},
+		{
+			name:        "When the source differs with the Warn policy, It Should restore the HostedCluster",
+			annotations: map[string]string{common.SourceMetadataAnnotation: source},
+			mismatches:  []string{"management cluster version 4.17.0 is older than the source 4.18.0"},
+		},
+		{
+			name:        "When the source differs with the Fail policy, It Should return an error",
+			annotations: map[string]string{common.SourceMetadataAnnotation: source},
+			mismatches:  []string{"management cluster version 4.17.0 is older than the source 4.18.0"},
+			failPolicy:  true,
+			wantErr:     true,
+		},
+		{
+			name:               "When the target management cluster is too old, It Should return an error whatever the policy",
+			annotations:        map[string]string{common.SourceMetadataAnnotation: source},
+			managementVersions: common.NewValidationError("refusing to restore on an older management cluster"),
+			wantErr:            true,
+		},
+		{
+			name:           "When the HyperShift Operator of the target does not support the release, It Should return an error whatever the policy",
+			annotations:    map[string]string{common.SourceMetadataAnnotation: source},
+			releaseVersion: common.NewValidationError("HostedCluster release 4.14.2 is not supported"),
+			wantErr:        true,
+		},
+		{
+			name:            "When the networking conflicts with the Warn policy, It Should restore the HostedCluster",
+			annotations:     map[string]string{common.SourceMetadataAnnotation: source},
+			networkProblems: []string{"clusterNetwork 10.128.0.0/14 overlaps the management cluster clusterNetwork 10.128.0.0/14"},
+		},
+		{
+			name:            "When the networking conflicts with the Fail policy, It Should return an error",
+			networkProblems: []string{"clusterNetwork 10.132.0.0/14 overlaps serviceNetwork 10.132.0.0/16"},
+			failPolicy:      true,
+			wantErr:         true,
+		},
+		{
+			name:       "When the backup has no source metadata, It Should not check the environment",
+			mismatches: []string{"unexpected"},
+			failPolicy: true,
+		},
+		{
+			name:        "When the source metadata is malformed, It Should return an error",
+			annotations: map[string]string{common.SourceMetadataAnnotation: "{"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup).Build()
+			plugin := &RestorePlugin{
+				log:            logrus.New(),
+				ctx:            context.Background(),
+				client:         client,
+				validator:      &mockRestoreValidator{sourceMismatches: tt.mismatches, networkProblems: tt.networkProblems, managementVersionsErr: tt.managementVersions, releaseVersionErr: tt.releaseVersion},
+				RestoreOptions: &plugtypes.RestoreOptions{FailOnSourceMismatch: tt.failPolicy},
+			}
+
+			_, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{
+				Item:    newHCUnstructured("my-hc", "clusters", tt.annotations),
+				Restore: restore,
+			})
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("wantErr %v, got error: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRestoreExecutePluginCompatibility(t *testing.T) {
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+	}
+	restore := &velerov1api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+		Spec:       velerov1api.RestoreSpec{BackupName: "test-backup"},
+	}
+	current := strconv.Itoa(common.BackupSchemaVersion)
+	runningVersion := version.Version
+	version.Version = "v1.4.0"
+	t.Cleanup(func() { version.Version = runningVersion })
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		tolerate    []string
+		wantErr     bool
+	}{
+		{
+			name: "When the backup predates the schema, It Should restore the HostedCluster",
+		},
+		{
+			name:        "When the backup has the current schema, It Should restore the HostedCluster",
+			annotations: map[string]string{common.BackupSchemaAnnotation: current, common.BackupPluginVersionAnnotation: "v1.2.0"},
+		},
+		{
+			name:        "When the backup was taken by a newer minor version, It Should restore the HostedCluster",
+			annotations: map[string]string{common.BackupSchemaAnnotation: current, common.BackupPluginVersionAnnotation: "v1.6.0"},
+		},
+		{
+			name:        "When the backup has a newer schema, It Should return an error",
+			annotations: map[string]string{common.BackupSchemaAnnotation: strconv.Itoa(common.BackupSchemaVersion + 1), common.BackupPluginVersionAnnotation: "v1.9.0"},
+			wantErr:     true,
+		},
+		{
+			name:        "When the backup was taken by another major version, It Should return an error",
+			annotations: map[string]string{common.BackupSchemaAnnotation: current, common.BackupPluginVersionAnnotation: "v2.0.0"},
+			wantErr:     true,
+		},
+		{
+			name:        "When an unsupported backup is tolerated, It Should restore the HostedCluster",
+			annotations: map[string]string{common.BackupSchemaAnnotation: current, common.BackupPluginVersionAnnotation: "v2.0.0"},
+			tolerate:    []string{common.ToleratePluginVersion},
+		},
+		{
+			name:        "When the backup schema is malformed, It Should return an error",
+			annotations: map[string]string{common.BackupSchemaAnnotation: "latest"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup).Build()
+			plugin := &RestorePlugin{
+				log:            logrus.New(),
+				ctx:            context.Background(),
+				client:         client,
+				validator:      &mockRestoreValidator{},
+				RestoreOptions: &plugtypes.RestoreOptions{TolerateErrors: tt.tolerate},
+			}
+
+			_, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{
+				Item:    newHCUnstructured("my-hc", "clusters", tt.annotations),
+				Restore: restore,
+			})
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("wantErr %v, got error: %v", tt.wantErr, err)
+			}
+			if err != nil && !errors.As(err, new(*common.ValidationError)) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRestoreExecutePluginDisabled(t *testing.T) {
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+	}
+	restore := &velerov1api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+		Spec:       velerov1api.RestoreSpec{BackupName: "test-backup"},
+	}
+	hc := &hyperv1.HostedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "clusters", Annotations: map[string]string{common.PluginAnnotation: common.PluginDisabled}},
+	}
+	client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(&apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}, backup, hc).Build()
+	plugin := &RestorePlugin{
+		log:            logrus.New(),
+		ctx:            context.Background(),
+		client:         client,
+		validator:      &mockRestoreValidator{validateEnvironmentErr: errors.New("no HyperShift Operator")},
+		RestoreOptions: &plugtypes.RestoreOptions{RestorePaused: true},
+	}
+
+	item := newHCUnstructured("test", "clusters", nil)
+	output, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{Item: item.DeepCopy(), Restore: restore})
+	if err != nil {
+		t.Fatalf("expected the item to be restored unchanged, got error: %v", err)
+	}
+	if output.SkipRestore || !reflect.DeepEqual(output.UpdatedItem, item) {
+		t.Errorf("expected the item to be restored unchanged, got %v", output.UpdatedItem)
+	}
+}
+
+func TestRestoreExecuteManagedServices(t *testing.T) {
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+	}
+	restore := &velerov1api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+		Spec:       velerov1api.RestoreSpec{BackupName: "test-backup"},
+	}
+
+	tests := []struct {
+		name            string
+		labels          map[string]string
+		managedServices bool
+		wantRefused     bool
+		wantChecked     bool
+	}{
+		{
+			name:        "When a ROSA HCP cluster is restored without managedServices, It Should return an error",
+			labels:      map[string]string{common.ManagedClusterLabel: "true"},
+			wantRefused: true,
+		},
+		{
+			name:            "When a ROSA HCP cluster is restored with managedServices, It Should check its capacity and release image",
+			labels:          map[string]string{common.ManagedClusterLabel: "true"},
+			managedServices: true,
+			wantChecked:     true,
+		},
+		{
+			name: "When a self-managed cluster is restored, It Should restore it with the configured checks",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup).Build()
+			validator := &mockRestoreValidator{}
+			plugin := &RestorePlugin{
+				log:            logrus.New(),
+				ctx:            context.Background(),
+				client:         client,
+				validator:      validator,
+				RestoreOptions: &plugtypes.RestoreOptions{ManagedServices: tt.managedServices},
+			}
+			item := newHCUnstructured("my-hc", "clusters", nil)
+			item.SetLabels(tt.labels)
+			_ = unstructured.SetNestedField(item.Object, string(hyperv1.AWSPlatform), "spec", "platform", "type")
+
+			_, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{Item: item, Restore: restore})
+			if tt.wantRefused {
+				if common.ErrorClass(err) != common.ErrorClassValidation {
+					t.Errorf("expected a validation error, got %v", err)
+				}
+				// Refused before anything is created on the target cluster
+				if err := client.Get(context.TODO(), crclient.ObjectKey{Name: "clusters"}, &corev1.Namespace{}); !apierrors.IsNotFound(err) {
+					t.Errorf("expected the namespace not to be created, got %v", err)
+				}
+				return
+			}
+			if validator.capacityChecked != tt.wantChecked {
+				t.Errorf("capacity checked %v, want %v", validator.capacityChecked, tt.wantChecked)
+			}
+			// The release image check needs the pull secret, absent here
+			if releaseChecked := err != nil && strings.Contains(err.Error(), "pull secret"); releaseChecked != tt.wantChecked {
+				t.Errorf("release image checked %v, want %v: %v", releaseChecked, tt.wantChecked, err)
+			}
+		})
+	}
+}
+
+func TestRestoreExecuteOwnerReferences(t *testing.T) {
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+	}
+	restore := &velerov1api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+		Spec:       velerov1api.RestoreSpec{BackupName: "test-backup"},
+	}
+	liveHCP := &hyperv1.HostedControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "clusters-test", UID: "live-uid"},
+	}
+	hcpRef := metav1.OwnerReference{APIVersion: "hypershift.openshift.io/v1beta1", Kind: "HostedControlPlane", Name: "test", UID: "source-uid"}
+	missingRef := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "gone", UID: "source-uid-2"}
+
+	tests := []struct {
+		name     string
+		objects  []crclient.Object
+		refs     []metav1.OwnerReference
+		wantRefs []metav1.OwnerReference
+	}{
+		{
+			name:     "When the owner is already restored, It Should point the reference at its live UID",
+			objects:  []crclient.Object{liveHCP},
+			refs:     []metav1.OwnerReference{hcpRef},
+			wantRefs: []metav1.OwnerReference{{APIVersion: hcpRef.APIVersion, Kind: hcpRef.Kind, Name: hcpRef.Name, UID: "live-uid"}},
+		},
+		{
+			name:     "When the owner is not restored yet, It Should drop the reference",
+			objects:  []crclient.Object{liveHCP},
+			refs:     []metav1.OwnerReference{hcpRef, missingRef},
+			wantRefs: []metav1.OwnerReference{{APIVersion: hcpRef.APIVersion, Kind: hcpRef.Kind, Name: hcpRef.Name, UID: "live-uid"}},
+		},
+		{
+			name: "When the item has no owner, It Should leave it unchanged",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]crclient.Object{hcpCRD, backup}, tt.objects...)
+			client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(objects...).Build()
+			plugin := &RestorePlugin{
+				log:            logrus.New(),
+				ctx:            context.Background(),
+				client:         client,
+				validator:      &mockRestoreValidator{},
+				RestoreOptions: &plugtypes.RestoreOptions{},
+			}
+
+			item := &unstructured.Unstructured{}
+			item.SetAPIVersion("v1")
+			item.SetKind("Secret")
+			item.SetName("kubeadmin-password")
+			item.SetNamespace("clusters-test")
+			item.SetOwnerReferences(tt.refs)
+
+			output, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{Item: item, Restore: restore})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := output.UpdatedItem.(*unstructured.Unstructured).GetOwnerReferences()
+			if len(got) != len(tt.wantRefs) {
+				t.Fatalf("got ownerReferences %+v, want %+v", got, tt.wantRefs)
+			}
+			for i := range got {
+				if got[i] != tt.wantRefs[i] {
+					t.Errorf("got ownerReference %+v, want %+v", got[i], tt.wantRefs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRestoreExecutePodRestorePolicy(t *testing.T) {
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+	}
+	restore := &velerov1api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+		Spec:       velerov1api.RestoreSpec{BackupName: "test-backup"},
+	}
+	hcpNamespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "clusters-test", Labels: common.ControlPlaneNamespaceLabels},
+	}
+	hcNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "clusters"}}
+
+	tests := []struct {
+		name      string
+		policy    string
+		namespace string
+		wantSkip  bool
+	}{
+		{
+			name:      "When the policy is unset, It Should skip every pod",
+			namespace: "clusters",
+			wantSkip:  true,
+		},
+		{
+			name:      "When the policy is SkipControlPlane, It Should skip control plane pods",
+			policy:    common.PodRestorePolicySkipControlPlane,
+			namespace: "clusters-test",
+			wantSkip:  true,
+		},
+		{
+			name:      "When the policy is SkipControlPlane, It Should restore pods outside the control plane",
+			policy:    common.PodRestorePolicySkipControlPlane,
+			namespace: "clusters",
+			wantSkip:  false,
+		},
+		{
+			name:      "When the policy is SkipNone, It Should restore control plane pods",
+			policy:    common.PodRestorePolicySkipNone,
+			namespace: "clusters-test",
+			wantSkip:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup, hcpNamespace, hcNamespace).Build()
+			plugin := &RestorePlugin{
+				log:            logrus.New(),
+				ctx:            context.Background(),
+				client:         client,
+				validator:      &mockRestoreValidator{},
+				RestoreOptions: &plugtypes.RestoreOptions{PodRestorePolicy: tt.policy},
+			}
+
+			item := &unstructured.Unstructured{}
+			item.SetAPIVersion("v1")
+			item.SetKind("Pod")
+			item.SetName("some-pod")
+			item.SetNamespace(tt.namespace)
+
+			output, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{Item: item, Restore: restore})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output.SkipRestore != tt.wantSkip {
+				t.Errorf("got SkipRestore %v, want %v", output.SkipRestore, tt.wantSkip)
+			}
+		})
+	}
+}
+
+// newTestCertificate returns a self-signed PEM certificate valid until notAfter.
+func newTestCertificate(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "root-ca"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestRestoreExecuteCertificateExpiry(t *testing.T) {
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+	}
+	restore := &velerov1api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+		Spec:       velerov1api.RestoreSpec{BackupName: "test-backup"},
+	}
+	hcpNamespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "clusters-test", Labels: common.ControlPlaneNamespaceLabels},
+	}
+	hcNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "clusters"}}
+
+	expired := newTestCertificate(t, time.Now().Add(-time.Hour))
+	expiring := newTestCertificate(t, time.Now().Add(24*time.Hour))
+	valid := newTestCertificate(t, time.Now().Add(365*24*time.Hour))
+
+	tests := []struct {
+		name      string
+		policy    string
+		threshold time.Duration
+		namespace string
+		cert      []byte
+		wantErr   bool
+		wantSkip  bool
+	}{
+		{
+			name:      "When the policy is unset, It Should restore an expired certificate",
+			namespace: "clusters-test",
+			cert:      expired,
+		},
+		{
+			name:      "When the policy is Fail, It Should refuse an expired certificate",
+			policy:    common.CertificateExpiryPolicyFail,
+			namespace: "clusters-test",
+			cert:      expired,
+			wantErr:   true,
+		},
+		{
+			name:      "When the policy is Fail, It Should restore a certificate close to expiry",
+			policy:    common.CertificateExpiryPolicyFail,
+			namespace: "clusters-test",
+			cert:      expiring,
+		},
+		{
+			name:      "When the policy is Rotate, It Should skip an expiring control plane certificate",
+			policy:    common.CertificateExpiryPolicyRotate,
+			namespace: "clusters-test",
+			cert:      expiring,
+			wantSkip:  true,
+		},
+		{
+			name:      "When the policy is Rotate and the threshold is short, It Should restore a certificate expiring later",
+			policy:    common.CertificateExpiryPolicyRotate,
+			threshold: time.Hour,
+			namespace: "clusters-test",
+			cert:      expiring,
+		},
+		{
+			name:      "When the policy is Rotate, It Should restore an expired certificate outside the control plane",
+			policy:    common.CertificateExpiryPolicyRotate,
+			namespace: "clusters",
+			cert:      expired,
+		},
+		{
+			name:      "When the policy is Fail, It Should restore a valid certificate",
+			policy:    common.CertificateExpiryPolicyFail,
+			namespace: "clusters-test",
+			cert:      valid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup, hcpNamespace, hcNamespace).Build()
+			plugin := &RestorePlugin{
+				log:            logrus.New(),
+				ctx:            context.Background(),
+				client:         client,
+				validator:      &mockRestoreValidator{},
+				RestoreOptions: &plugtypes.RestoreOptions{CertificateExpiryPolicy: tt.policy, CertificateExpiryThreshold: tt.threshold},
+			}
+
+			secret := &corev1.Secret{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+				ObjectMeta: metav1.ObjectMeta{Name: "root-ca", Namespace: tt.namespace},
+				Data:       map[string][]byte{"ca.crt": tt.cert, "ca.key": []byte("key")},
+			}
+			content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{Item: &unstructured.Unstructured{Object: content}, Restore: restore})
+			if tt.wantErr {
+				var validationErr *common.ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("got error %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output.SkipRestore != tt.wantSkip {
+				t.Errorf("got SkipRestore %v, want %v", output.SkipRestore, tt.wantSkip)
+			}
+		})
+	}
+}
+
+func TestRestoreExecuteEndpointSecrets(t *testing.T) {
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+	}
+	restore := &velerov1api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+		Spec:       velerov1api.RestoreSpec{BackupName: "test-backup"},
+	}
+	hcpNamespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "clusters-test", Labels: common.ControlPlaneNamespaceLabels},
+	}
+	hcNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "clusters"}}
+	expiration := map[string]string{hyperv1.IgnitionServerTokenExpirationTimestampAnnotation: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)}
+
+	tests := []struct {
+		name        string
+		migration   bool
+		namespace   string
+		secret      string
+		annotations map[string]string
+		wantSkip    bool
+	}{
+		{
+			name:        "When an ignition token expired, It Should skip it",
+			namespace:   "clusters-test",
+			secret:      "token-workers-4a5b6c",
+			annotations: expiration,
+			wantSkip:    true,
+		},
+		{
+			name:      "When an ignition token is current, It Should restore it",
+			namespace: "clusters-test",
+			secret:    "token-workers-4a5b6c",
+		},
+		{
+			name:      "When migrating, It Should skip the konnectivity serving certificate",
+			migration: true,
+			namespace: "clusters-test",
+			secret:    common.KonnectivityServerSecret,
+			wantSkip:  true,
+		},
+		{
+			name:      "When migrating, It Should skip the NodePool user data",
+			migration: true,
+			namespace: "clusters-test",
+			secret:    "user-data-workers-4a5b6c",
+			wantSkip:  true,
+		},
+		{
+			name:      "When migrating, It Should restore the konnectivity signer",
+			migration: true,
+			namespace: "clusters-test",
+			secret:    common.KonnectivitySignerSecret,
+		},
+		{
+			name:      "When restoring in place, It Should restore the ignition serving certificate",
+			namespace: "clusters-test",
+			secret:    common.IgnitionServerServingCertSecret,
+		},
+		{
+			name:      "When migrating, It Should restore Secrets outside the control plane",
+			migration: true,
+			namespace: "clusters",
+			secret:    "user-data-secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup, hcpNamespace, hcNamespace).Build()
+			plugin := &RestorePlugin{
+				log:            logrus.New(),
+				ctx:            context.Background(),
+				client:         client,
+				validator:      &mockRestoreValidator{},
+				RestoreOptions: &plugtypes.RestoreOptions{Migration: tt.migration},
+			}
+
+			secret := &corev1.Secret{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+				ObjectMeta: metav1.ObjectMeta{Name: tt.secret, Namespace: tt.namespace, Annotations: tt.annotations},
+				Data:       map[string][]byte{"ca.key": []byte("key")},
+			}
+			content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{Item: &unstructured.Unstructured{Object: content}, Restore: restore})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output.SkipRestore != tt.wantSkip {
+				t.Errorf("got SkipRestore %v, want %v", output.SkipRestore, tt.wantSkip)
+			}
+		})
+	}
+}
+
+func TestRestoreExecutePartialRestore(t *testing.T) {
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+	}
+	restore := &velerov1api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+		Spec: velerov1api.RestoreSpec{
+			BackupName:        "test-backup",
+			IncludedResources: []string{"nodepools"},
+		},
+	}
+	liveHC := &hyperv1.HostedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-hc", Namespace: "clusters"},
+	}
+	nodePool := func() *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]any{
+				"apiVersion": "hypershift.openshift.io/v1beta1",
+				"kind":       "NodePool",
+				"metadata":   map[string]any{"name": "my-np", "namespace": "clusters"},
+				"spec":       map[string]any{"clusterName": "my-hc"},
+			},
+		}
+	}
+
+	t.Run("When a NodePool is restored on its own, It Should leave the pause state untouched", func(t *testing.T) {
+		client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup, liveHC).Build()
+		plugin := &RestorePlugin{
+			log:            logrus.New(),
+			ctx:            context.Background(),
+			client:         client,
+			validator:      &mockRestoreValidator{},
+			RestoreOptions: &plugtypes.RestoreOptions{RestorePaused: true},
+		}
+
+		output, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{Item: nodePool(), Restore: restore})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		content := output.UpdatedItem.UnstructuredContent()
+		if _, found, _ := unstructured.NestedString(content, "spec", "pausedUntil"); found {
+			t.Error("expected pausedUntil not to be set on a partial restore")
+		}
+		if _, found, _ := unstructured.NestedString(content, "metadata", "annotations", common.RestorePendingAnnotation); found {
+			t.Error("expected restore-pending annotation not to be set on a partial restore")
+		}
+	})
+
+	t.Run("When a NodePool is restored on its own without its HostedCluster, It Should return an error", func(t *testing.T) {
+		client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup).Build()
+		plugin := &RestorePlugin{
+			log:            logrus.New(),
+			ctx:            context.Background(),
+			client:         client,
+			validator:      &mockRestoreValidator{},
+			RestoreOptions: &plugtypes.RestoreOptions{},
+		}
+
+		_, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{Item: nodePool(), Restore: restore})
+		if err == nil || !strings.Contains(err.Error(), "requires its HostedCluster my-hc to exist") {
+			t.Fatalf("expected missing HostedCluster error, got %v", err)
+		}
+	})
+}
+
+// newVeleroPod returns the pod the notification watcher takes its image and service account
+// from, named after the host running the tests.
+func newVeleroPod(t *testing.T) *corev1.Pod {
+	podName, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("unexpected error getting the hostname: %v", err)
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: "openshift-adp"},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: "velero",
+			InitContainers:     []corev1.Container{{Name: "hypershift-velero-plugin", Image: "quay.io/konveyor/hypershift-velero-plugin:latest"}},
+		},
+	}
+}
+
+func TestRestoreExecutePartialRestoreNotification(t *testing.T) {
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+	}
+	partialRestore := func(name string) *velerov1api.Restore {
+		return &velerov1api.Restore{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openshift-adp"},
+			Spec:       velerov1api.RestoreSpec{BackupName: "test-backup", IncludedResources: []string{"configmaps"}},
+		}
+	}
+	configMap := func(labels map[string]any) *unstructured.Unstructured {
+		metadata := map[string]any{"name": "my-cm", "namespace": "clusters-test"}
+		if labels != nil {
+			metadata["labels"] = labels
+		}
+		return &unstructured.Unstructured{Object: map[string]any{"apiVersion": "v1", "kind": "ConfigMap", "metadata": metadata}}
+	}
+	labeled := map[string]any{common.HostedClusterLabel: "my-hc"}
+
+	tests := []struct {
+		name     string
+		items    []*unstructured.Unstructured
+		restores []string
+		wantJobs map[string][]string
+	}{
+		{
+			name:     "When a partial restore restores items labeled with their HostedCluster, It Should start the watcher with its name",
+			items:    []*unstructured.Unstructured{configMap(labeled), configMap(labeled)},
+			restores: []string{"restore-1", "restore-1"},
+			wantJobs: map[string][]string{"hcp-notify-restore-restore-1": {"--restore", "restore-1", "--hosted-cluster", "my-hc"}},
+		},
+		{
+			name:     "When the first item of a partial restore has no HostedCluster label, It Should start the watcher on a labeled one",
+			items:    []*unstructured.Unstructured{configMap(nil), configMap(labeled)},
+			restores: []string{"restore-1", "restore-1"},
+			wantJobs: map[string][]string{"hcp-notify-restore-restore-1": {"--restore", "restore-1", "--hosted-cluster", "my-hc"}},
+		},
+		{
+			name:     "When no item of a partial restore has a HostedCluster label, It Should not start the watcher",
+			items:    []*unstructured.Unstructured{configMap(nil)},
+			restores: []string{"restore-1"},
+			wantJobs: map[string][]string{},
+		},
+		{
+			name:     "When the plugin process runs a second restore, It Should start a watcher for it too",
+			items:    []*unstructured.Unstructured{configMap(labeled), configMap(labeled)},
+			restores: []string{"restore-1", "restore-2"},
+			wantJobs: map[string][]string{
+				"hcp-notify-restore-restore-1": {"--restore", "restore-1", "--hosted-cluster", "my-hc"},
+				"hcp-notify-restore-restore-2": {"--restore", "restore-2", "--hosted-cluster", "my-hc"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup, newVeleroPod(t)).Build()
+			plugin := &RestorePlugin{
+				log:            logrus.New(),
+				ctx:            context.Background(),
+				client:         client,
+				validator:      &mockRestoreValidator{},
+				RestoreOptions: &plugtypes.RestoreOptions{},
+				notifyWatcher:  &notify.Watcher{Client: client, Namespace: "openshift-adp"},
+			}
+
+			for i, item := range tt.items {
+				if _, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{Item: item, Restore: partialRestore(tt.restores[i])}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			jobs := &batchv1.JobList{}
+			if err := client.List(context.TODO(), jobs); err != nil {
+				t.Fatalf("unexpected error listing Jobs: %v", err)
+			}
+			got := map[string][]string{}
+			for _, job := range jobs.Items {
+				got[job.Name] = job.Spec.Template.Spec.Containers[0].Command[2:]
+			}
+			if !reflect.DeepEqual(got, tt.wantJobs) {
+				t.Errorf("got watcher Jobs %v, want %v", got, tt.wantJobs)
+			}
+		})
+	}
+}
+
+func TestRestoreExecuteExistingObjectPolicy(t *testing.T) {
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-test"}},
+	}
+	restore := &velerov1api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+		Spec:       velerov1api.RestoreSpec{BackupName: "test-backup"},
+	}
+	configMap := func(uid string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]any{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]any{"name": "my-cm", "namespace": "clusters-test", "uid": uid},
+			},
+		}
+	}
+	nodePool := func(uid, arch string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]any{
+				"apiVersion": "hypershift.openshift.io/v1beta1",
+				"kind":       "NodePool",
+				"metadata":   map[string]any{"name": "my-np", "namespace": "clusters", "uid": uid, "labels": map[string]any{"team": arch}},
+				"spec":       map[string]any{"clusterName": "my-hc", "arch": arch},
+			},
+		}
+	}
+	hostedCluster := func(uid, infraID string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]any{
+				"apiVersion": "hypershift.openshift.io/v1beta1",
+				"kind":       "HostedCluster",
+				"metadata":   map[string]any{"name": "my-hc", "namespace": "clusters", "uid": uid},
+				"spec":       map[string]any{"infraID": infraID},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		policy   string
+		live     []crclient.Object
+		item     *unstructured.Unstructured
+		wantSkip bool
+	}{
+		{
+			name:     "When the policy is unset, It Should restore an item whose live object has its UID",
+			live:     []crclient.Object{configMap("uid-1")},
+			item:     configMap("uid-1"),
+			wantSkip: false,
+		},
+		{
+			name:     "When the policy is Skip and the live object has the backed up UID, It Should skip the item",
+			policy:   common.ExistingObjectPolicySkip,
+			live:     []crclient.Object{configMap("uid-1")},
+			item:     configMap("uid-1"),
+			wantSkip: true,
+		},
+		{
+			name:     "When the policy is Skip and the live object has another UID, It Should restore the item",
+			policy:   common.ExistingObjectPolicySkip,
+			live:     []crclient.Object{configMap("uid-2")},
+			item:     configMap("uid-1"),
+			wantSkip: false,
+		},
+		{
+			name:     "When the policy is Skip and there is no live object, It Should restore the item",
+			policy:   common.ExistingObjectPolicySkip,
+			item:     configMap("uid-1"),
+			wantSkip: false,
+		},
+		{
+			name:     "When the policy is Skip and a live HostedCluster has the backed up infraID, It Should skip it",
+			policy:   common.ExistingObjectPolicySkip,
+			live:     []crclient.Object{hostedCluster("uid-2", "test-abcde")},
+			item:     hostedCluster("uid-1", "test-abcde"),
+			wantSkip: true,
+		},
+		{
+			name:     "When the policy is Patch and the live object has the backed up UID, It Should patch it and skip the item",
+			policy:   common.ExistingObjectPolicyPatch,
+			live:     []crclient.Object{nodePool("uid-1", "amd64")},
+			item:     nodePool("uid-1", "arm64"),
+			wantSkip: true,
+		},
+		{
+			name:     "When the policy is Merge and the live object has the backed up UID, It Should keep the live values and skip the item",
+			policy:   common.ExistingObjectPolicyMerge,
+			live:     []crclient.Object{nodePool("uid-1", "amd64")},
+			item:     nodePool("uid-1", "arm64"),
+			wantSkip: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]crclient.Object{hcpCRD, backup}, tt.live...)
+			client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(objects...).Build()
+			plugin := &RestorePlugin{
+				log:            logrus.New(),
+				ctx:            context.Background(),
+				client:         client,
+				validator:      &mockRestoreValidator{},
+				RestoreOptions: &plugtypes.RestoreOptions{ExistingObjectPolicy: tt.policy},
+			}
+
+			output, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{Item: tt.item.DeepCopy(), ItemFromBackup: tt.item, Restore: restore})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output.SkipRestore != tt.wantSkip {
+				t.Errorf("got SkipRestore %v, want %v", output.SkipRestore, tt.wantSkip)
+			}
+			if tt.policy != common.ExistingObjectPolicyPatch && tt.policy != common.ExistingObjectPolicyMerge {
+				return
+			}
+			live := &hyperv1.NodePool{}
+			if err := client.Get(context.Background(), crclient.ObjectKey{Name: "my-np", Namespace: "clusters"}, live); err != nil {
+				t.Fatalf("unexpected error getting the live NodePool: %v", err)
+			}
+			wantArch := "arm64"
+			if tt.policy == common.ExistingObjectPolicyMerge {
+				wantArch = "amd64"
+			}
+			if live.Spec.Arch != wantArch || live.Labels["team"] != wantArch {
+				t.Errorf("expected the live NodePool arch and labels to be %s, got arch %q and labels %v", wantArch, live.Spec.Arch, live.Labels)
+			}
+		})
+	}
+}
+
+func TestRestoreExecuteExistingObjectPolicyBackedUpNodePool(t *testing.T) {
+	tests := []struct {
+		name     string
+		liveUID  string
+		wantSkip bool
+	}{
+		{
+			name:     "When the live NodePool is the backed up one, It Should skip the NodePool the backup plugin stored",
+			liveUID:  "uid-1",
+			wantSkip: true,
+		},
+		{
+			name:    "When the live NodePool was recreated, It Should restore the NodePool the backup plugin stored",
+			liveUID: "uid-2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := newUnstructuredItem("NodePool", "hypershift.openshift.io/v1beta1", "my-np", "clusters")
+			item.SetUID("uid-1")
+			item.Object["spec"] = map[string]any{"clusterName": "my-hc"}
+			backedUp, _, err := newTestBackupPlugin().Execute(item, newTestBackup())
+			if err != nil {
+				t.Fatalf("unexpected error backing up the NodePool: %v", err)
+			}
+			if uid := backedUp.(*unstructured.Unstructured).GetUID(); uid != "" {
+				t.Fatalf("expected the backed up NodePool to be stored without UID, got %q", uid)
+			}
+
+			live := &hyperv1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-np", Namespace: "clusters", UID: k8stypes.UID(tt.liveUID)},
+				Spec:       hyperv1.NodePoolSpec{ClusterName: "my-hc"},
+			}
+			client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(
+				&apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"}},
+				newTestBackup(), live,
+			).Build()
+			plugin := &RestorePlugin{
+				log:            logrus.New(),
+				ctx:            context.Background(),
+				client:         client,
+				validator:      &mockRestoreValidator{},
+				RestoreOptions: &plugtypes.RestoreOptions{ExistingObjectPolicy: common.ExistingObjectPolicySkip},
+			}
+			restore := &velerov1api.Restore{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+				Spec:       velerov1api.RestoreSpec{BackupName: "test-backup"},
+			}
+
+			output, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{Item: backedUp.DeepCopyObject().(runtime.Unstructured), ItemFromBackup: backedUp, Restore: restore})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output.SkipRestore != tt.wantSkip {
+				t.Errorf("got SkipRestore %v, want %v", output.SkipRestore, tt.wantSkip)
+			}
+		})
+	}
+}
+
+func TestSpecDrift(t *testing.T) {
+	backedUp := map[string]any{
+		"release":  map[string]any{"image": "quay.io/ocp-release:4.18.1"},
+		"dns":      map[string]any{"baseDomain": "example.com"},
+		"services": []any{map[string]any{"service": "APIServer"}},
+		"infraID":  "test-abcde",
+	}
+	live := map[string]any{
+		"release":  map[string]any{"image": "quay.io/ocp-release:4.18.5"},
+		"dns":      map[string]any{"baseDomain": "example.com"},
+		"services": []any{map[string]any{"service": "APIServer"}, map[string]any{"service": "OAuthServer"}},
+		"infraID":  "test-abcde",
+		"paused":   "true",
+	}
+
+	drift := specDrift("spec", backedUp, live)
+	want := []string{
+		`spec.paused: live "true", backup unset`,
+		`spec.release.image: live "quay.io/ocp-release:4.18.5", backup "quay.io/ocp-release:4.18.1"`,
+		`spec.services: live [{"service":"APIServer"},{"service":"OAuthServer"}], backup [{"service":"APIServer"}]`,
+	}
+	if !slices.Equal(drift, want) {
+		t.Errorf("got drift %q, want %q", drift, want)
+	}
+	if drift := specDrift("spec", backedUp, backedUp); drift != nil {
+		t.Errorf("got drift %q for identical specs", drift)
+	}
+
+	filled := fillMissing(map[string]any{"release": map[string]any{"image": "live"}}, backedUp).(map[string]any)
+	if image := filled["release"].(map[string]any)["image"]; image != "live" {
+		t.Errorf("got release image %v, want the live one", image)
+	}
+	if filled["infraID"] != "test-abcde" {
+		t.Errorf("got infraID %v, want the backed up one", filled["infraID"])
+	}
+}
+
+func TestRestorePlatformAbsentFromTarget(t *testing.T) {
+	hcpCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"},
+	}
+	backup := &velerov1api.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-backup", Namespace: "openshift-adp"},
+		Spec:       velerov1api.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-agent"}},
+	}
+	restore := &velerov1api.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-restore", Namespace: "openshift-adp"},
+		Spec:       velerov1api.RestoreSpec{BackupName: "test-backup"},
+	}
+	// The target management cluster only hosts AWS clusters
+	awsHC := &hyperv1.HostedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws-hc", Namespace: "aws-clusters"},
+		Spec:       hyperv1.HostedClusterSpec{Platform: hyperv1.PlatformSpec{Type: hyperv1.AWSPlatform}},
+	}
+
+	platforms, err := restorePlatforms(map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plugin := &RestorePlugin{
+		log:            logrus.New(),
+		ctx:            context.Background(),
+		client:         fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(hcpCRD, backup, awsHC).Build(),
+		platforms:      platforms,
+		validator:      &mockRestoreValidator{},
+		RestoreOptions: &plugtypes.RestoreOptions{},
+	}
+
+	selector, err := plugin.AppliesTo()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, resource := range []string{"agents", "agentmachines", "agentclusters", "infraenvs"} {
+		if !slices.Contains(selector.IncludedResources, resource) {
+			t.Errorf("expected the Agent resource %q of the restored cluster to be included", resource)
+		}
+	}
+
+	agent := newUnstructuredItem("Agent", "agent-install.openshift.io/v1beta1", "agent-0", "clusters-agent")
+	output, err := plugin.Execute(&veleroapiv1.RestoreItemActionExecuteInput{Item: agent, Restore: restore})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.SkipRestore {
+		t.Error("expected the Agent to be restored")
+	}
+
+	// Only the platforms option narrows the registered resources
+	if configured, err := restorePlatforms(map[string]string{common.ConfigKeyPlatforms: "AWS"}); err != nil || !slices.Equal(configured, []hyperv1.PlatformType{hyperv1.AWSPlatform}) {
+		t.Errorf("got platforms %v and error %v, want the configured AWS", configured, err)
+	}
+}
+
+func TestRestoreAppliesTo(t *testing.T) {
+	tests := []struct {
+		name        string
+		platforms   []hyperv1.PlatformType
+		included    []string
+		notIncluded []string
+	}{
+		{
+			name:      "When platforms are unknown, It Should include every platform's resources",
+			platforms: nil,
+			included:  []string{"hostedclusters", "nodepools", "awsmachines", "azuremachines", "ibmpowervsmachines", "openstackmachines", "kubevirtcluster", "agents"},
+		},
+		{
+			name:        "When only AWS is present, It Should only include the AWS provider resources",
+			platforms:   []hyperv1.PlatformType{hyperv1.AWSPlatform},
+			included:    []string{"hostedclusters", "nodepools", "awsmachines"},
+			notIncluded: []string{"azuremachines", "ibmpowervsmachines", "openstackmachines", "kubevirtcluster", "agents"},
+		},
+		{
+			name:        "When Agent and KubeVirt are present, It Should include both provider resources",
+			platforms:   []hyperv1.PlatformType{hyperv1.AgentPlatform, hyperv1.KubevirtPlatform},
+			included:    []string{"hostedclusters", "agents", "kubevirtcluster", "datavolume"},
+			notIncluded: []string{"awsmachines", "azuremachines"},
+		},
+		{
+			name:        "When only the None platform is present, It Should only include the common resources",
+			platforms:   []hyperv1.PlatformType{hyperv1.NonePlatform},
+			included:    []string{"hostedclusters", "machines"},
+			notIncluded: []string{"awsmachines", "agents"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &RestorePlugin{platforms: tt.platforms}
+			selector, err := plugin.AppliesTo()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, resource := range tt.included {
+				if !slices.Contains(selector.IncludedResources, resource) {
+					t.Errorf("expected %q to be included", resource)
+				}
+			}
+			for _, resource := range tt.notIncluded {
+				if slices.Contains(selector.IncludedResources, resource) {
+					t.Errorf("expected %q not to be included", resource)
+				}
+			}
+		})
+	}
+}
@@ -1,5 +1,14 @@
 package types
 
+import (
+	"slices"
+	"time"
+
+	"github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
 var (
 	BackupCommonResources = []string{
 		"hostedclusters", "hostedcluster", "hostedcontrolplanes", "hostedcontrolplane", "nodepools", "nodepool",
@@ -8,22 +17,181 @@ var (
 		"clusters", "cluster", "machines", "machine", "machinedeployments", "machinedeployment", "machinesets", "machineset",
 		"serviceaccounts", "serviceaccount", "roles", "role", "rolebindings", "rolebinding",
 		"priorityclasses", "priorityclass", "poddisruptionbudgets", "poddisruptionbudget",
+		"services", "service", "routes.route.openshift.io",
+		// Cluster-scoped, only backed up when related to the hosted cluster
+		"volumesnapshotcontents", "volumesnapshotcontent", "certificatesigningrequests", "certificatesigningrequest",
+	}
+
+	// BackupIPAMResources are the CAPI and metal3 IPAM resources holding the address
+	// allocations of Machines. They are group qualified: names like ippools clash with other
+	// projects, e.g. Calico.
+	BackupIPAMResources = []string{
+		"ipaddresses.ipam.cluster.x-k8s.io", "ipaddressclaims.ipam.cluster.x-k8s.io",
+		"inclusterippools.ipam.cluster.x-k8s.io", "globalinclusterippools.ipam.cluster.x-k8s.io",
+		"ippools.ipam.metal3.io", "ipclaims.ipam.metal3.io", "ipaddresses.ipam.metal3.io",
 	}
 
-	BackupAWSResources        = []string{"awsmachinepools", "awsmachines", "awsmachinetemplates", "awsmanagedmachinepools", "awsmanagedmachinepooltemplates"}
+	BackupAWSResources        = []string{"awsmachinepools", "awsmachines", "awsmachinetemplates", "awsmanagedmachinepools", "awsmanagedmachinepooltemplates", "awsendpointservices", "awsendpointservice"}
 	BackupAzureResources      = []string{"azuremachines", "azuremachinetemplates", "azuremanagedmachinepools", "azuremanagedmachinepooltemplates"}
-	BackupIBMPowerVSResources = []string{"ibmpowervsmachines", "ibmpowervsmachinetemplates", "ibmpowervsclusters", "ibmpowervsclustertemplates"}
+	BackupIBMPowerVSResources = []string{"ibmpowervsmachines", "ibmpowervsmachinetemplates", "ibmpowervsclusters", "ibmpowervsclustertemplates", "ibmvpcmachines", "ibmvpcmachinetemplates", "ibmvpcclusters", "ibmvpcclustertemplates"}
 	BackupOpenStackResources  = []string{"openstackmachines", "openstackmachinetemplates", "openstackclusters", "openstackclustertemplates"}
 	BackupKubevirtResources   = []string{"kubevirtcluster", "kubevirtmachinetemplate", "datavolume"}
 	BackupAgentResources      = []string{"agents", "agentmachines", "agentmachinetemplates", "agentmachinepools", "agentclusters", "nmstateconfigs", "nmstateconfig", "infraenvs", "infraenv"}
+
+	// platformResources lists the provider resources per platform, in registration order.
+	platformResources = []struct {
+		platforms []hyperv1.PlatformType
+		resources []string
+	}{
+		{[]hyperv1.PlatformType{hyperv1.AWSPlatform}, BackupAWSResources},
+		{[]hyperv1.PlatformType{hyperv1.AzurePlatform}, BackupAzureResources},
+		{[]hyperv1.PlatformType{hyperv1.IBMCloudPlatform, hyperv1.PowerVSPlatform}, BackupIBMPowerVSResources},
+		{[]hyperv1.PlatformType{hyperv1.OpenStackPlatform}, BackupOpenStackResources},
+		{[]hyperv1.PlatformType{hyperv1.KubevirtPlatform}, BackupKubevirtResources},
+		{[]hyperv1.PlatformType{hyperv1.AgentPlatform}, BackupAgentResources},
+	}
 )
 
+// PlatformResources returns the common and IPAM resources plus the provider resources of
+// the given platforms. An empty list means the platforms are unknown and every provider is included.
+func PlatformResources(platforms []hyperv1.PlatformType) []string {
+	resources := slices.Concat(BackupCommonResources, BackupIPAMResources)
+	for _, entry := range platformResources {
+		if len(platforms) == 0 || slices.ContainsFunc(entry.platforms, func(p hyperv1.PlatformType) bool {
+			return slices.Contains(platforms, p)
+		}) {
+			resources = append(resources, entry.resources...)
+		}
+	}
+	return resources
+}
+
+// PlatformOptions gathers the options of the platform sections of the configuration, keys
+// prefixed with the lower case platform name, e.g. agent.databaseSnapshot. Both plugins
+// parse every section, each reading the options it acts on.
+type PlatformOptions struct {
+	AWS   AWSOptions
+	Agent AgentOptions
+}
+
+// AWSOptions are the options of the aws. section.
+type AWSOptions struct {
+	// RegenPrivateLink leaves the PrivateLink wiring recorded at backup out of restored
+	// AWSEndpointServices, so HyperShift creates new AWS resources for them, e.g. when the
+	// source ones were deleted or belong to another account.
+	RegenPrivateLink bool
+}
+
+// AgentOptions are the options of the agent. section.
+type AgentOptions struct {
+	// DatabaseSnapshot snapshots the assisted-service database volume, in ServiceNamespace,
+	// together with the hosted cluster.
+	DatabaseSnapshot bool
+	ServiceNamespace string
+	// SkipPreserveOnDelete restores ClusterDeployments with the preserveOnDelete they were
+	// backed up with, instead of setting it so Hive leaves the hosts alone.
+	SkipPreserveOnDelete bool
+}
+
 type BackupOptions struct {
+	PlatformOptions
+
 	// Migration is a flag to indicate if the backup is for migration purposes.
 	Migration bool
+	// NodePoolSelector restricts the backup to the matching NodePools and their CAPI machinery.
+	// Nil selects every NodePool.
+	NodePoolSelector labels.Selector
+	// MigrationRetainPVCs lists the PVC names, besides the etcd ones, whose PersistentVolumes
+	// are switched to the Retain reclaim policy on migration backups.
+	MigrationRetainPVCs []string
+	// VolumeBackupModePolicy decides what a Backup leaving defaultVolumesToFsBackup unset
+	// does: Ignore (default), Fail when CSI snapshots cannot work, or Auto to use fs-backup.
+	VolumeBackupModePolicy string
+	// FSBackupPods maps the name prefix of control plane Pods labeled for fs-backup, like the
+	// etcd ones, to the volumes opted in. No volumes leaves the choice to the fs-backup policy.
+	FSBackupPods map[string][]string
+	// SkipVolumeLabels and SkipVolumeNames exclude DataVolumes and PVCs recreated instead of
+	// restored, e.g. boot image caches, besides the KubeVirt RHCOS ones. Labels are key, any
+	// value, or key=value; names are glob patterns.
+	SkipVolumeLabels []string
+	SkipVolumeNames  []string
+	// SkipDeletingCluster leaves a HostedCluster being deleted out of the backup instead of
+	// failing it.
+	SkipDeletingCluster bool
+	// HealthGatePolicy decides what an unhealthy HostedCluster does to the backup: Ignore,
+	// Warn (default) or Fail.
+	HealthGatePolicy string
+	// MaxPauseDuration bounds how long the backup command keeps the hosted cluster paused for
+	// the backup. Past it the plugin resumes the cluster and fails the backup. Zero leaves it
+	// unbounded.
+	MaxPauseDuration time.Duration
+	// ConcurrentBackupPolicy decides what the backup does while an earlier backup of the same
+	// hosted cluster is running: Wait (default) for it to finish, Fail, or Ignore it.
+	ConcurrentBackupPolicy string
+	// ExecuteTimeout bounds each Execute call, so no item blocks a Velero worker longer.
+	// Zero leaves it unbounded.
+	ExecuteTimeout time.Duration
+	// TolerateErrors lists the non-critical problems logged as warnings instead of failing
+	// the item.
+	TolerateErrors []string
+	// Timeouts overrides the timeouts and poll intervals of the plugin waits.
+	Timeouts common.Timeouts
+	// GuestSnapshot captures the Nodes, pending CSRs and ClusterOperators of the hosted
+	// cluster in a ConfigMap added to the backup, as a reference for DR verification.
+	GuestSnapshot bool
+	// BackupCRDs adds the HyperShift, CAPI and platform CRDs to the backup, so a management
+	// cluster serving none of them can be restored from it.
+	BackupCRDs bool
+	// VeleroNamespace is the namespace Velero creates the DataUploads and PodVolumeBackups
+	// of its backups in and runs the node-agent in, when it is not the Backup one.
+	VeleroNamespace string
 }
 
 type RestoreOptions struct {
+	PlatformOptions
 	// Migration is a flag to indicate if the backup is for migration purposes.
 	Migration bool
+	// ReleaseImageCheck verifies HostedCluster and NodePool release images are pullable before restoring them.
+	ReleaseImageCheck bool
+	// RestorePaused restores HostedClusters, HostedControlPlanes and NodePools paused
+	// and flagged as pending, so they can be inspected before reconciliation resumes.
+	RestorePaused bool
+	// ReadoptNodes points restored CAPI Machines to the cloud instances and Nodes recorded
+	// at backup, so the instances are re-adopted instead of recreated.
+	ReadoptNodes bool
+	// ExistingObjectPolicy decides what happens to items whose live counterpart is the same
+	// object: restored as usual (Ignore, default), skipped (Skip), patched onto it (Patch), or
+	// merged into it keeping the live values (Merge).
+	ExistingObjectPolicy string
+	// CapacityCheck warns when the target management cluster has no room for the control
+	// plane of a restored HostedCluster, which would otherwise sit Pending.
+	CapacityCheck bool
+	// ManagedServices allows restoring HostedClusters of managed services, ROSA HCP or ARO
+	// HCP, whose release image and capacity are then always checked.
+	ManagedServices bool
+	// FailOnSourceMismatch fails restoring a HostedCluster whose recorded source environment
+	// does not match the target, instead of only warning.
+	FailOnSourceMismatch bool
+	// ManagementVersionSkew is how many minor versions the target management cluster, and
+	// its HyperShift Operator, may be behind the backup source before the restore is refused.
+	ManagementVersionSkew int
+	// CertificateExpiryPolicy decides what restoring a Secret holding an expired certificate,
+	// or one expiring within CertificateExpiryThreshold, does: Warn (default), Fail, or Rotate
+	// to leave control plane certificates out so the control plane operator reissues them.
+	CertificateExpiryPolicy    string
+	CertificateExpiryThreshold time.Duration
+	// DeletionProtection is the grace period during which restored HostedClusters and etcd
+	// PVCs keep a finalizer protecting them from deletion. Zero leaves them unprotected.
+	DeletionProtection time.Duration
+	// PodRestorePolicy decides which Pods are restored: SkipAll (default), SkipControlPlane
+	// or SkipNone.
+	PodRestorePolicy string
+	// ExecuteTimeout bounds each Execute call, so no item blocks a Velero worker longer.
+	// Zero leaves it unbounded.
+	ExecuteTimeout time.Duration
+	// TolerateErrors lists the non-critical problems logged as warnings instead of failing
+	// the item.
+	TolerateErrors []string
+	// Timeouts overrides the timeouts and poll intervals of the plugin waits.
+	Timeouts common.Timeouts
 }
@@ -1,23 +1,54 @@
 package validation
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
 
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v8/apis/volumesnapshot/v1"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
 	plugtypes "github.com/openshift/hypershift-oadp-plugin/pkg/core/types"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/hooks"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/platform/ibm"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/platform/kubevirt"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/platform/openstack"
 	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
 	"github.com/sirupsen/logrus"
 	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// nodeAgentDaemonSet is the Velero DaemonSet running fs-backup, in the Velero namespace.
+const nodeAgentDaemonSet = "node-agent"
+
 type BackupValidator interface {
 	ValidatePluginConfig(config map[string]string) (*plugtypes.BackupOptions, error)
-	ValidatePlatformConfig(hcp *hyperv1.HostedControlPlane, backup *velerov1.Backup) error
+	ValidatePlatformConfig(ctx context.Context, hcp *hyperv1.HostedControlPlane, backup *velerov1.Backup) error
+	ValidateHostedClusterState(hc *hyperv1.HostedCluster, hcp *hyperv1.HostedControlPlane) error
+	ValidateHostedClusterHealth(hc *hyperv1.HostedCluster, hcp *hyperv1.HostedControlPlane) []string
+	ValidateVolumeBackupMode(ctx context.Context, hcp *hyperv1.HostedControlPlane, backup *velerov1.Backup, etcdBackupMethod, unsetPolicy string) (bool, error)
+	ValidateStorageLocations(ctx context.Context, backup *velerov1.Backup) error
 }
 
 type BackupPluginValidator struct {
 	Log    logrus.FieldLogger
 	Client crclient.Client
+	// VeleroNamespace is where the node-agent runs, the Backup namespace when empty
+	VeleroNamespace string
 }
 
 func (p *BackupPluginValidator) ValidatePluginConfig(config map[string]string) (*plugtypes.BackupOptions, error) {
@@ -31,14 +62,162 @@ func (p *BackupPluginValidator) ValidatePluginConfig(config map[string]string) (
 
 	for key, value := range config {
 		p.Log.Debugf("configuration key: %s, value: %s", key, value)
+		if platform, option, ok := platformKey(key); ok {
+			if err := parsePlatformOption(p.Log, &bo.PlatformOptions, platform, option, config, key, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		switch key {
 		case "migration":
 			p.Log.Debugf("reading/parsing migration %s", value)
 			bo.Migration = value == "true"
-		case "etcdBackupMethod", "hoNamespace":
+		case common.ConfigKeyNodePoolSelector:
+			p.Log.Debugf("reading/parsing nodePoolSelector %s", value)
+			selector, err := labels.Parse(value)
+			if err != nil {
+				return nil, common.NewValidationError("invalid %s %q: %w", common.ConfigKeyNodePoolSelector, value, err)
+			}
+			bo.NodePoolSelector = selector
+		case common.ConfigKeyMigrationRetainPVCs:
+			p.Log.Debugf("reading/parsing migrationRetainPVCs %s", value)
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					bo.MigrationRetainPVCs = append(bo.MigrationRetainPVCs, name)
+				}
+			}
+		case common.ConfigKeyFSBackupPods:
+			p.Log.Debugf("reading/parsing fsBackupPods %s", value)
+			pods, err := parseFSBackupPods(value)
+			if err != nil {
+				return nil, err
+			}
+			bo.FSBackupPods = pods
+		case common.ConfigKeySkipVolumeLabels:
+			p.Log.Debugf("reading/parsing skipVolumeLabels %s", value)
+			skipLabels, err := parseSkipVolumeLabels(value)
+			if err != nil {
+				return nil, err
+			}
+			bo.SkipVolumeLabels = skipLabels
+		case common.ConfigKeySkipVolumeNames:
+			p.Log.Debugf("reading/parsing skipVolumeNames %s", value)
+			skipNames, err := parseSkipVolumeNames(value)
+			if err != nil {
+				return nil, err
+			}
+			bo.SkipVolumeNames = skipNames
+		case common.ConfigKeyVolumeBackupModePolicy:
+			p.Log.Debugf("reading/parsing volumeBackupModePolicy %s", value)
+			switch value {
+			case common.VolumeBackupModePolicyIgnore, common.VolumeBackupModePolicyFail, common.VolumeBackupModePolicyAuto:
+				bo.VolumeBackupModePolicy = value
+			default:
+				return nil, common.NewValidationError("invalid %s %q: must be one of %q, %q or %q", common.ConfigKeyVolumeBackupModePolicy, value,
+					common.VolumeBackupModePolicyIgnore, common.VolumeBackupModePolicyFail, common.VolumeBackupModePolicyAuto)
+			}
+		case common.ConfigKeyDeletingClusterPolicy:
+			p.Log.Debugf("reading/parsing deletingClusterPolicy %s", value)
+			switch value {
+			case common.DeletingClusterPolicyFail:
+			case common.DeletingClusterPolicySkip:
+				bo.SkipDeletingCluster = true
+			default:
+				return nil, common.NewValidationError("invalid %s %q: must be %q or %q", common.ConfigKeyDeletingClusterPolicy, value, common.DeletingClusterPolicyFail, common.DeletingClusterPolicySkip)
+			}
+		case common.ConfigKeyHealthGatePolicy:
+			p.Log.Debugf("reading/parsing healthGatePolicy %s", value)
+			switch value {
+			case common.HealthGatePolicyIgnore, common.HealthGatePolicyWarn, common.HealthGatePolicyFail:
+				bo.HealthGatePolicy = value
+			default:
+				return nil, common.NewValidationError("invalid %s %q: must be one of %q, %q or %q", common.ConfigKeyHealthGatePolicy, value,
+					common.HealthGatePolicyIgnore, common.HealthGatePolicyWarn, common.HealthGatePolicyFail)
+			}
+		case common.ConfigKeyConcurrentBackupPolicy:
+			p.Log.Debugf("reading/parsing concurrentBackupPolicy %s", value)
+			switch value {
+			case common.ConcurrentBackupPolicyWait, common.ConcurrentBackupPolicyFail, common.ConcurrentBackupPolicyIgnore:
+				bo.ConcurrentBackupPolicy = value
+			default:
+				return nil, common.NewValidationError("invalid %s %q: must be one of %q, %q or %q", common.ConfigKeyConcurrentBackupPolicy, value,
+					common.ConcurrentBackupPolicyWait, common.ConcurrentBackupPolicyFail, common.ConcurrentBackupPolicyIgnore)
+			}
+		case common.ConfigKeyMaxPauseDuration:
+			p.Log.Debugf("reading/parsing maxPauseDuration %s", value)
+			duration, err := parseDuration(common.ConfigKeyMaxPauseDuration, value)
+			if err != nil {
+				return nil, err
+			}
+			bo.MaxPauseDuration = duration
+		case common.ConfigKeyExecuteTimeout:
+			p.Log.Debugf("reading/parsing executeTimeout %s", value)
+			timeout, err := parseExecuteTimeout(value)
+			if err != nil {
+				return nil, err
+			}
+			bo.ExecuteTimeout = timeout
+		case common.ConfigKeyTimeouts:
+			p.Log.Debugf("reading/parsing timeouts %s", value)
+			timeouts, err := common.ParseTimeouts(value)
+			if err != nil {
+				return nil, err
+			}
+			bo.Timeouts = timeouts
+		case common.ConfigKeyTolerateErrors:
+			p.Log.Debugf("reading/parsing tolerateErrors %s", value)
+			tolerated, err := parseTolerateErrors(value)
+			if err != nil {
+				return nil, err
+			}
+			bo.TolerateErrors = tolerated
+		case common.ConfigKeyGuestSnapshot:
+			p.Log.Debugf("reading/parsing guestSnapshot %s", value)
+			bo.GuestSnapshot = value == "true"
+		case common.ConfigKeyBackupCRDs:
+			p.Log.Debugf("reading/parsing backupCRDs %s", value)
+			bo.BackupCRDs = value == "true"
+		case common.ConfigKeyVeleroNamespace:
+			p.Log.Debugf("reading/parsing veleroNamespace %s", value)
+			if errs := k8svalidation.IsDNS1123Label(value); len(errs) > 0 {
+				return nil, common.NewValidationError("invalid %s %q: %s", common.ConfigKeyVeleroNamespace, value, strings.Join(errs, ", "))
+			}
+			bo.VeleroNamespace = value
+		case "etcdBackupMethod", "hoNamespace", common.ConfigKeyPlatforms,
+			common.ConfigKeyHookWebhookURL, common.ConfigKeyHookJobTemplate, common.ConfigKeyHookEvents, common.ConfigKeyHookFailurePolicy,
+			common.ConfigKeyNotificationWebhookURL, common.ConfigKeyNotificationFormat, common.ConfigKeyNotificationImage,
+			common.ConfigKeyTracingEndpoint, common.ConfigKeyStrictConfig:
 			p.Log.Debugf("configuration key %s=%s handled by plugin init", key, value)
 		default:
-			p.Log.Warnf("unknown configuration key: %s with value %s", key, value)
+			if slices.Contains(restoreOnlyKeys, key) {
+				p.Log.Debugf("configuration key %s=%s only read by the restore plugin", key, value)
+				continue
+			}
+			if err := unknownKey(p.Log, config, key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if bo.ExecuteTimeout > 0 && bo.ConcurrentBackupPolicy != common.ConcurrentBackupPolicyFail && bo.ConcurrentBackupPolicy != common.ConcurrentBackupPolicyIgnore {
+		if poll := bo.Timeouts.WithDefaults().EarlierBackupsPoll; poll >= bo.ExecuteTimeout {
+			return nil, common.NewValidationError("%s %s must be longer than the earlierBackupsPoll %s of the %s %q wait it bounds",
+				common.ConfigKeyExecuteTimeout, bo.ExecuteTimeout, poll, common.ConfigKeyConcurrentBackupPolicy, common.ConcurrentBackupPolicyWait)
+		}
+	}
+	if hookEvents := config[common.ConfigKeyHookEvents]; hookEvents != "" && config[common.ConfigKeyEtcdBackupMethod] != common.EtcdBackupMethodEtcdSnapshot {
+		// The plugin does not see the volume snapshots Velero takes after its items
+		for _, event := range strings.Split(hookEvents, ",") {
+			if hooks.Event(strings.TrimSpace(event)) == hooks.AfterSnapshots {
+				return nil, common.NewValidationError("%s %q requires %s %q: the plugin only sees the etcd snapshot of that method complete",
+					common.ConfigKeyHookEvents, hooks.AfterSnapshots, common.ConfigKeyEtcdBackupMethod, common.EtcdBackupMethodEtcdSnapshot)
+			}
+		}
+	}
+	if bo.MaxPauseDuration > 0 {
+		if propagation := bo.Timeouts.WithDefaults().PausePropagation; bo.MaxPauseDuration <= propagation {
+			return nil, common.NewValidationError("%s %s must be longer than the pausePropagation %s, or the pause window ends before the pause is propagated",
+				common.ConfigKeyMaxPauseDuration, bo.MaxPauseDuration, propagation)
 		}
 	}
 
@@ -48,25 +227,373 @@ func (p *BackupPluginValidator) ValidatePluginConfig(config map[string]string) (
 
 }
 
-func (p *BackupPluginValidator) ValidatePlatformConfig(hcp *hyperv1.HostedControlPlane, backup *velerov1.Backup) error {
+// parseDuration parses a duration option, such as executeTimeout, which must be positive.
+func parseDuration(key, value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, common.NewValidationError("invalid %s %q: must be a positive duration, e.g. 15m", key, value)
+	}
+	return duration, nil
+}
+
+// tolerableErrors are the problems the tolerateErrors option accepts. The backup and restore
+// plugins share the ConfigMap, so both accept all of them.
+var tolerableErrors = []string{common.TolerateSourceMetadata, common.TolerateVolumeBackupMode, common.TolerateReleaseImage, common.ToleratePluginVersion}
+
+// parseTolerateErrors parses the comma-separated tolerateErrors option.
+func parseTolerateErrors(value string) ([]string, error) {
+	var tolerated []string
+	for _, problem := range strings.Split(value, ",") {
+		if problem = strings.TrimSpace(problem); problem == "" {
+			continue
+		}
+		if !slices.Contains(tolerableErrors, problem) {
+			return nil, common.NewValidationError("invalid %s %q: unknown problem %q, must be one of %v", common.ConfigKeyTolerateErrors, value, problem, tolerableErrors)
+		}
+		tolerated = append(tolerated, problem)
+	}
+	return tolerated, nil
+}
+
+// MinExecuteTimeout is the shortest executeTimeout, below which the HCPEtcdBackup waits
+// cannot complete.
+const MinExecuteTimeout = time.Minute
+
+// parseExecuteTimeout parses the executeTimeout option, which must be at least
+// MinExecuteTimeout.
+func parseExecuteTimeout(value string) (time.Duration, error) {
+	timeout, err := parseDuration(common.ConfigKeyExecuteTimeout, value)
+	if err != nil {
+		return 0, err
+	}
+	if timeout < MinExecuteTimeout {
+		return 0, common.NewValidationError("invalid %s %q: must be at least %s", common.ConfigKeyExecuteTimeout, value, MinExecuteTimeout)
+	}
+	return timeout, nil
+}
+
+// backupOnlyKeys and restoreOnlyKeys are the options read by one plugin only. The plugins
+// share the ConfigMap, so each one accepts the options of the other.
+var (
+	backupOnlyKeys = []string{
+		common.ConfigKeyNodePoolSelector, common.ConfigKeyMigrationRetainPVCs, common.ConfigKeyFSBackupPods,
+		common.ConfigKeySkipVolumeLabels, common.ConfigKeySkipVolumeNames, common.ConfigKeyVolumeBackupModePolicy,
+		common.ConfigKeyDeletingClusterPolicy, common.ConfigKeyHealthGatePolicy, common.ConfigKeyConcurrentBackupPolicy,
+		common.ConfigKeyMaxPauseDuration, common.ConfigKeyGuestSnapshot, common.ConfigKeyBackupCRDs,
+		common.ConfigKeyVeleroNamespace,
+	}
+	restoreOnlyKeys = []string{
+		common.ConfigKeyReleaseImageCheck, common.ConfigKeyRestorePaused, common.ConfigKeyPausedKinds,
+		common.ConfigKeyReadoptNodes, common.ConfigKeyCapacityCheck, common.ConfigKeyManagedServices,
+		common.ConfigKeyPodRestorePolicy, common.ConfigKeyExistingObjectPolicy, common.ConfigKeySourceMismatchPolicy,
+		common.ConfigKeyManagementVersionSkew, common.ConfigKeyCertificateExpiryPolicy, common.ConfigKeyCertificateExpiryThreshold,
+		common.ConfigKeyDeletionProtection, common.ConfigKeyReconcileAfterUnpause,
+	}
+)
+
+// platformKey splits a key of a platform section, <platform>.<option> with the lower case
+// platform name.
+func platformKey(key string) (hyperv1.PlatformType, string, bool) {
+	prefix, option, found := strings.Cut(key, ".")
+	if !found || prefix != strings.ToLower(prefix) {
+		return "", "", false
+	}
+	platforms, err := common.ParsePlatforms(prefix)
+	if err != nil {
+		return "", "", false
+	}
+	return platforms[0], option, true
+}
+
+// parsePlatformOption parses an option of a platform section into the options of the
+// platform. An option the platform does not have is an unknown key.
+func parsePlatformOption(log logrus.FieldLogger, opts *plugtypes.PlatformOptions, platform hyperv1.PlatformType, option string, config map[string]string, key, value string) error {
+	log.Debugf("reading/parsing %s %s", key, value)
+	switch {
+	case platform == hyperv1.AWSPlatform && option == common.PlatformOptionRegenPrivateLink:
+		opts.AWS.RegenPrivateLink = value == "true"
+	case platform == hyperv1.AgentPlatform && option == common.PlatformOptionDatabaseSnapshot:
+		opts.Agent.DatabaseSnapshot = value == "true"
+	case platform == hyperv1.AgentPlatform && option == common.PlatformOptionServiceNamespace:
+		if errs := k8svalidation.IsDNS1123Label(value); len(errs) > 0 {
+			return common.NewValidationError("invalid %s %q: %s", key, value, strings.Join(errs, ", "))
+		}
+		opts.Agent.ServiceNamespace = value
+	case platform == hyperv1.AgentPlatform && option == common.PlatformOptionPreserveOnDelete:
+		opts.Agent.SkipPreserveOnDelete = value == "false"
+	case platform == hyperv1.KubevirtPlatform && option == common.PlatformOptionFreezeVMs:
+		// Refused rather than ignored, so nobody relies on VMs being frozen for the backup
+		return common.NewValidationError("%s is not supported: the plugin does not freeze the file systems of the KubeVirt VMs", key)
+	default:
+		return unknownKey(log, config, key, value)
+	}
+	return nil
+}
+
+// unknownKey logs a configuration key neither plugin knows, or refuses it with strictConfig.
+func unknownKey(log logrus.FieldLogger, config map[string]string, key, value string) error {
+	if config[common.ConfigKeyStrictConfig] == "true" {
+		return common.NewValidationError("unknown configuration key %q, refused with %s", key, common.ConfigKeyStrictConfig)
+	}
+	log.Warnf("unknown configuration key: %s with value %s", key, value)
+	return nil
+}
+
+// parseSkipVolumeLabels parses the skipVolumeLabels option, comma-separated label keys,
+// optionally followed by =<value>.
+func parseSkipVolumeLabels(value string) ([]string, error) {
+	var skipLabels []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		key, labelValue, _ := strings.Cut(entry, "=")
+		if errs := k8svalidation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, common.NewValidationError("invalid %s %q: %q is not a label key: %s", common.ConfigKeySkipVolumeLabels, value, key, strings.Join(errs, ", "))
+		}
+		if errs := k8svalidation.IsValidLabelValue(labelValue); len(errs) > 0 {
+			return nil, common.NewValidationError("invalid %s %q: %q is not a label value: %s", common.ConfigKeySkipVolumeLabels, value, labelValue, strings.Join(errs, ", "))
+		}
+		skipLabels = append(skipLabels, entry)
+	}
+	return skipLabels, nil
+}
+
+// parseSkipVolumeNames parses the skipVolumeNames option, comma-separated glob patterns.
+func parseSkipVolumeNames(value string) ([]string, error) {
+	var patterns []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		if _, err := path.Match(entry, ""); err != nil {
+			return nil, common.NewValidationError("invalid %s %q: %q is not a glob pattern", common.ConfigKeySkipVolumeNames, value, entry)
+		}
+		patterns = append(patterns, entry)
+	}
+	return patterns, nil
+}
+
+// parseFSBackupPods parses the fsBackupPods option, comma-separated Pod name prefixes, each
+// optionally followed by /<volume>. A prefix may be listed once per volume.
+func parseFSBackupPods(value string) (map[string][]string, error) {
+	pods := map[string][]string{}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		prefix, volume, hasVolume := strings.Cut(entry, "/")
+		prefix, volume = strings.TrimSpace(prefix), strings.TrimSpace(volume)
+		if prefix == "" || (hasVolume && volume == "") || strings.Contains(volume, "/") {
+			return nil, common.NewValidationError("invalid %s %q: entries must be <pod name prefix> or <pod name prefix>/<volume>, got %q", common.ConfigKeyFSBackupPods, value, entry)
+		}
+		volumes := pods[prefix]
+		if hasVolume && !slices.Contains(volumes, volume) {
+			volumes = append(volumes, volume)
+		}
+		pods[prefix] = volumes
+	}
+	return pods, nil
+}
+
+func (p *BackupPluginValidator) ValidatePlatformConfig(ctx context.Context, hcp *hyperv1.HostedControlPlane, backup *velerov1.Backup) error {
 	switch hcp.Spec.Platform.Type {
 	case hyperv1.AWSPlatform:
 		return p.checkAWSPlatform(hcp)
 	case hyperv1.AzurePlatform:
 		return p.checkAzurePlatform(hcp)
-	case hyperv1.IBMCloudPlatform:
-		return p.checkIBMCloudPlatform(hcp)
+	case hyperv1.IBMCloudPlatform, hyperv1.PowerVSPlatform:
+		return p.checkIBMCloudPlatform(ctx, hcp)
 	case hyperv1.KubevirtPlatform:
-		return p.checkKubevirtPlatform(hcp)
+		return p.checkKubevirtPlatform(ctx, hcp)
 	case hyperv1.OpenStackPlatform:
-		return p.checkOpenStackPlatform(hcp)
+		return p.checkOpenStackPlatform(ctx, hcp)
 	case hyperv1.AgentPlatform, hyperv1.NonePlatform:
 		return p.checkAgentPlatform(hcp)
 	default:
-		return fmt.Errorf("unsupported platform type %s", hcp.Spec.Platform.Type)
+		return common.NewValidationError("unsupported platform type %s", hcp.Spec.Platform.Type)
 	}
 }
 
+// ValidateHostedClusterState returns an error when the HostedCluster or its HostedControlPlane
+// is being deleted or already destroyed, so a cluster mid-teardown is not backed up. The
+// HostedCluster is nil when it was not found.
+func (p *BackupPluginValidator) ValidateHostedClusterState(hc *hyperv1.HostedCluster, hcp *hyperv1.HostedControlPlane) error {
+	if hc != nil {
+		if hc.DeletionTimestamp != nil {
+			return common.NewValidationError("HostedCluster %s/%s is being deleted since %s", hc.Namespace, hc.Name, hc.DeletionTimestamp.UTC().Format(time.RFC3339))
+		}
+		if meta.IsStatusConditionTrue(hc.Status.Conditions, string(hyperv1.HostedClusterDestroyed)) {
+			return common.NewValidationError("HostedCluster %s/%s has been destroyed", hc.Namespace, hc.Name)
+		}
+	}
+	if hcp.DeletionTimestamp != nil {
+		return common.NewValidationError("HostedControlPlane %s/%s is being deleted since %s", hcp.Namespace, hcp.Name, hcp.DeletionTimestamp.UTC().Format(time.RFC3339))
+	}
+	if meta.IsStatusConditionTrue(hcp.Status.Conditions, string(hyperv1.CloudResourcesDestroyed)) {
+		return common.NewValidationError("the cloud resources of HostedControlPlane %s/%s have been destroyed", hcp.Namespace, hcp.Name)
+	}
+	return nil
+}
+
+// ValidateHostedClusterHealth returns the reasons the hosted cluster state is not worth
+// archiving as a DR point: the HostedCluster is Degraded, etcd is unavailable or a cluster
+// version update is progressing. The HostedControlPlane conditions are used when the
+// HostedCluster was not found.
+func (p *BackupPluginValidator) ValidateHostedClusterHealth(hc *hyperv1.HostedCluster, hcp *hyperv1.HostedControlPlane) []string {
+	conditions := hcp.Status.Conditions
+	if hc != nil {
+		conditions = hc.Status.Conditions
+	}
+
+	var problems []string
+	if condition := meta.FindStatusCondition(conditions, string(hyperv1.HostedClusterDegraded)); condition != nil && condition.Status == metav1.ConditionTrue {
+		problems = append(problems, fmt.Sprintf("the hosted cluster is degraded: %s", condition.Message))
+	}
+	if condition := meta.FindStatusCondition(conditions, string(hyperv1.EtcdAvailable)); condition != nil && condition.Status == metav1.ConditionFalse {
+		problems = append(problems, fmt.Sprintf("etcd is not available: %s", condition.Message))
+	}
+	if condition := meta.FindStatusCondition(conditions, string(hyperv1.ClusterVersionProgressing)); condition != nil && condition.Status == metav1.ConditionTrue {
+		problems = append(problems, fmt.Sprintf("a cluster version update is progressing: %s", condition.Message))
+	}
+	return problems
+}
+
+// ValidateStorageLocations checks the BackupStorageLocation of the Backup, or the default one
+// when the Backup names none, is Available, and that none of its VolumeSnapshotLocations is
+// reported Unavailable. Velero rarely sets the VolumeSnapshotLocation phase, so an unset
+// phase is accepted.
+func (p *BackupPluginValidator) ValidateStorageLocations(ctx context.Context, backup *velerov1.Backup) error {
+	bsl := &velerov1.BackupStorageLocation{}
+	if name := backup.Spec.StorageLocation; name != "" {
+		if err := p.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: backup.Namespace}, bsl); err != nil {
+			return fmt.Errorf("error getting BackupStorageLocation %s/%s: %w", backup.Namespace, name, err)
+		}
+	} else {
+		bslList := &velerov1.BackupStorageLocationList{}
+		if err := p.Client.List(ctx, bslList, crclient.InNamespace(backup.Namespace)); err != nil {
+			return fmt.Errorf("error listing BackupStorageLocations in namespace %s: %w", backup.Namespace, err)
+		}
+		i := slices.IndexFunc(bslList.Items, func(bsl velerov1.BackupStorageLocation) bool { return bsl.Spec.Default })
+		if i < 0 {
+			return common.NewValidationError("backup %s names no BackupStorageLocation and there is no default one in namespace %s", backup.Name, backup.Namespace)
+		}
+		bsl = &bslList.Items[i]
+	}
+	if bsl.Status.Phase != velerov1.BackupStorageLocationPhaseAvailable {
+		return common.NewValidationError("BackupStorageLocation %s is not Available (phase %q): %s", bsl.Name, bsl.Status.Phase, bsl.Status.Message)
+	}
+
+	for _, name := range backup.Spec.VolumeSnapshotLocations {
+		vsl := &velerov1.VolumeSnapshotLocation{}
+		if err := p.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: backup.Namespace}, vsl); err != nil {
+			return fmt.Errorf("error getting VolumeSnapshotLocation %s/%s: %w", backup.Namespace, name, err)
+		}
+		if vsl.Status.Phase == velerov1.VolumeSnapshotLocationPhaseUnavailable {
+			return common.NewValidationError("VolumeSnapshotLocation %s is Unavailable", name)
+		}
+	}
+	return nil
+}
+
+// ValidateVolumeBackupMode checks the volume backup mode of the Backup can work with the
+// platform and storage of the hosted cluster: fs-backup needs the node-agent DaemonSet, and
+// CSI snapshots need a VolumeSnapshotClass for the CSI driver of every control plane PVC.
+// Etcd PVCs are not checked with the etcdSnapshot method, which does not back them up.
+//
+// When the Backup leaves defaultVolumesToFsBackup unset, the mode is decided by the Velero
+// server and is only checked following the volumeBackupModePolicy: Fail requires CSI
+// snapshots to work, and Auto returns true when they cannot but fs-backup can, so the plugin
+// opts the control plane volumes into fs-backup. Ignore, the default, does not check it.
+func (p *BackupPluginValidator) ValidateVolumeBackupMode(ctx context.Context, hcp *hyperv1.HostedControlPlane, backup *velerov1.Backup, etcdBackupMethod, unsetPolicy string) (bool, error) {
+	fsBackup := backup.Spec.DefaultVolumesToFsBackup
+	switch {
+	case fsBackup == nil:
+		return p.selectVolumeBackupMode(ctx, hcp, backup, etcdBackupMethod, unsetPolicy)
+	case *fsBackup:
+		return false, p.checkNodeAgent(ctx, backup)
+	case backup.Spec.SnapshotVolumes != nil && !*backup.Spec.SnapshotVolumes:
+		return false, nil
+	}
+	return false, p.checkCSISnapshots(ctx, hcp, backup, etcdBackupMethod)
+}
+
+// selectVolumeBackupMode checks the mode of a Backup leaving defaultVolumesToFsBackup unset,
+// following the volumeBackupModePolicy.
+func (p *BackupPluginValidator) selectVolumeBackupMode(ctx context.Context, hcp *hyperv1.HostedControlPlane, backup *velerov1.Backup, etcdBackupMethod, unsetPolicy string) (bool, error) {
+	if unsetPolicy != common.VolumeBackupModePolicyFail && unsetPolicy != common.VolumeBackupModePolicyAuto {
+		p.Log.Debugf("defaultVolumesToFsBackup is not set on backup %s, not checking the volume backup mode", backup.Name)
+		return false, nil
+	}
+	if backup.Spec.SnapshotVolumes != nil && !*backup.Spec.SnapshotVolumes {
+		return false, nil
+	}
+
+	csiErr := p.checkCSISnapshots(ctx, hcp, backup, etcdBackupMethod)
+	var validationErr *common.ValidationError
+	if csiErr == nil || !errors.As(csiErr, &validationErr) {
+		return false, csiErr
+	}
+	if unsetPolicy == common.VolumeBackupModePolicyFail {
+		return false, common.NewValidationError("defaultVolumesToFsBackup is not set on backup %s and CSI snapshots cannot back up the control plane volumes: %w", backup.Name, csiErr)
+	}
+
+	if err := p.checkNodeAgent(ctx, backup); err != nil {
+		return false, common.NewValidationError("neither CSI snapshots nor fs-backup can back up the control plane volumes of backup %s: %w; %w", backup.Name, csiErr, err)
+	}
+	p.Log.Infof("CSI snapshots cannot back up the control plane volumes of backup %s, using fs-backup: %v", backup.Name, csiErr)
+	return true, nil
+}
+
+// checkNodeAgent checks the node-agent DaemonSet running fs-backup is deployed.
+func (p *BackupPluginValidator) checkNodeAgent(ctx context.Context, backup *velerov1.Backup) error {
+	namespace := cmp.Or(p.VeleroNamespace, backup.Namespace)
+	ds := &appsv1.DaemonSet{}
+	if err := p.Client.Get(ctx, types.NamespacedName{Name: nodeAgentDaemonSet, Namespace: namespace}, ds); err != nil {
+		if apierrors.IsNotFound(err) {
+			return common.NewValidationError("backup %s uses fs-backup but the %s DaemonSet is not deployed in namespace %s: enable the node agent in the DataProtectionApplication, or set defaultVolumesToFsBackup to false to use CSI snapshots",
+				backup.Name, nodeAgentDaemonSet, namespace)
+		}
+		return fmt.Errorf("error getting DaemonSet %s/%s: %w", namespace, nodeAgentDaemonSet, err)
+	}
+	return nil
+}
+
+// checkCSISnapshots checks every control plane PVC has a VolumeSnapshotClass for its driver.
+func (p *BackupPluginValidator) checkCSISnapshots(ctx context.Context, hcp *hyperv1.HostedControlPlane, backup *velerov1.Backup, etcdBackupMethod string) error {
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := p.Client.List(ctx, pvcs, crclient.InNamespace(hcp.Namespace)); err != nil {
+		return fmt.Errorf("error listing PVCs in namespace %s: %w", hcp.Namespace, err)
+	}
+	classes := &snapshotv1.VolumeSnapshotClassList{}
+	if err := p.Client.List(ctx, classes); err != nil {
+		if meta.IsNoMatchError(err) {
+			return common.NewValidationError("backup %s uses CSI snapshots but the VolumeSnapshotClass API is not installed on the %s platform cluster: set defaultVolumesToFsBackup to true to use fs-backup",
+				backup.Name, hcp.Spec.Platform.Type)
+		}
+		return fmt.Errorf("error listing VolumeSnapshotClasses: %w", err)
+	}
+
+	for _, pvc := range pvcs.Items {
+		if etcdBackupMethod == common.EtcdBackupMethodEtcdSnapshot && strings.HasPrefix(pvc.Name, common.EtcdPVCPrefix) {
+			continue
+		}
+		if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+			continue
+		}
+		sc := &storagev1.StorageClass{}
+		if err := p.Client.Get(ctx, types.NamespacedName{Name: *pvc.Spec.StorageClassName}, sc); err != nil {
+			return fmt.Errorf("error getting StorageClass %s of PVC %s/%s: %w", *pvc.Spec.StorageClassName, pvc.Namespace, pvc.Name, err)
+		}
+		if !slices.ContainsFunc(classes.Items, func(class snapshotv1.VolumeSnapshotClass) bool {
+			return class.Driver == sc.Provisioner
+		}) {
+			return common.NewValidationError("backup %s uses CSI snapshots but PVC %s/%s uses StorageClass %s, whose driver %s has no VolumeSnapshotClass on the %s platform cluster: create a VolumeSnapshotClass for %s labeled velero.io/csi-volumesnapshot-class, or set defaultVolumesToFsBackup to true to use fs-backup",
+				backup.Name, pvc.Namespace, pvc.Name, sc.Name, sc.Provisioner, hcp.Spec.Platform.Type, sc.Provisioner)
+		}
+	}
+	return nil
+}
 
 func (p *BackupPluginValidator) checkAWSPlatform(hcp *hyperv1.HostedControlPlane) error {
 	// Check if the AWS platform is configured properly
@@ -82,20 +609,31 @@ func (p *BackupPluginValidator) checkAzurePlatform(hcp *hyperv1.HostedControlPla
 	return nil
 }
 
-func (p *BackupPluginValidator) checkIBMCloudPlatform(hcp *hyperv1.HostedControlPlane) error {
+func (p *BackupPluginValidator) checkIBMCloudPlatform(ctx context.Context, hcp *hyperv1.HostedControlPlane) error {
 	// Check if the IBM Cloud platform is configured properly
+	if err := ibm.ValidateCredentials(ctx, p.Client, hcp); err != nil {
+		return err
+	}
 	p.Log.Infof("IBM platform configuration is valid for HCP: %s", hcp.Name)
 	return nil
 }
 
-func (p *BackupPluginValidator) checkKubevirtPlatform(hcp *hyperv1.HostedControlPlane) error {
-	// Check if the Kubevirt platform is configured properly
+func (p *BackupPluginValidator) checkKubevirtPlatform(ctx context.Context, hcp *hyperv1.HostedControlPlane) error {
+	// Check if the Kubevirt platform is configured properly. The infra kubeconfig secret of
+	// an external infra cluster is backed up with the control plane namespace, check it is
+	// worth restoring.
+	if err := kubevirt.ValidateInfraKubeconfig(ctx, p.Client, hcp); err != nil {
+		return err
+	}
 	p.Log.Infof("Kubevirt platform configuration is valid for HCP: %s", hcp.Name)
 	return nil
 }
 
-func (p *BackupPluginValidator) checkOpenStackPlatform(hcp *hyperv1.HostedControlPlane) error {
+func (p *BackupPluginValidator) checkOpenStackPlatform(ctx context.Context, hcp *hyperv1.HostedControlPlane) error {
 	// Check if the OpenStack platform is configured properly
+	if err := openstack.ValidateIdentityRef(ctx, p.Client, hcp); err != nil {
+		return err
+	}
 	p.Log.Infof("OpenStack platform configuration is valid for HCP: %s", hcp.Name)
 	return nil
 }
@@ -3,21 +3,46 @@ package validation
 // Test scenario names follow: "When <action or context>, It Should <expected outcome>".
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v8/apis/volumesnapshot/v1"
 	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	plugtypes "github.com/openshift/hypershift-oadp-plugin/pkg/core/types"
 	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
 	"github.com/sirupsen/logrus"
 	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestBackupValidatePluginConfig(t *testing.T) {
 	tests := []struct {
-		name        string
-		config      map[string]string
-		wantMigr    bool
-		expectError bool
+		name           string
+		config         map[string]string
+		wantMigr       bool
+		wantNPSel      string
+		wantRetain     []string
+		wantSkipDel    bool
+		wantHealth     string
+		wantConc       string
+		wantTol        []string
+		wantTimeout    time.Duration
+		wantTimeouts   common.Timeouts
+		wantSkipLabels []string
+		wantSkipNames  []string
+		wantPause      time.Duration
+		wantFSPods     map[string][]string
+		wantVeleroNS   string
+		wantPlatform   plugtypes.PlatformOptions
+		expectError    bool
 	}{
 		{
 			name:   "When config is empty, It Should return default options without error",
@@ -36,6 +61,206 @@ func TestBackupValidatePluginConfig(t *testing.T) {
 			name:   "When config contains hoNamespace, It Should accept it without error",
 			config: map[string]string{"hoNamespace": "my-hypershift"},
 		},
+		{
+			name:      "When config has a valid nodePoolSelector, It Should parse it",
+			config:    map[string]string{"nodePoolSelector": "pool-type in (production,critical)"},
+			wantNPSel: "pool-type in (critical,production)",
+		},
+		{
+			name:       "When config has migrationRetainPVCs, It Should parse the PVC names",
+			config:     map[string]string{"migrationRetainPVCs": "data, logs ,"},
+			wantRetain: []string{"data", "logs"},
+		},
+		{
+			name:       "When config has fsBackupPods, It Should group the volumes by pod name prefix",
+			config:     map[string]string{"fsBackupPods": "ovnkube-master/ovnkube-db, image-registry,ovnkube-master/sbdb"},
+			wantFSPods: map[string][]string{"ovnkube-master": {"ovnkube-db", "sbdb"}, "image-registry": nil},
+		},
+		{
+			name:        "When config has an fsBackupPods entry without volume name, It Should return error",
+			config:      map[string]string{"fsBackupPods": "ovnkube-master/"},
+			expectError: true,
+		},
+		{
+			name:        "When config has an invalid volumeBackupModePolicy, It Should return error",
+			config:      map[string]string{"volumeBackupModePolicy": "Warn"},
+			expectError: true,
+		},
+		{
+			name:        "When config has deletingClusterPolicy Skip, It Should skip deleting clusters",
+			config:      map[string]string{"deletingClusterPolicy": "Skip"},
+			wantSkipDel: true,
+		},
+		{
+			name:        "When config has an invalid deletingClusterPolicy, It Should return error",
+			config:      map[string]string{"deletingClusterPolicy": "Ignore"},
+			expectError: true,
+		},
+		{
+			name:       "When config has healthGatePolicy Fail, It Should parse it",
+			config:     map[string]string{"healthGatePolicy": "Fail"},
+			wantHealth: "Fail",
+		},
+		{
+			name:        "When config has an invalid healthGatePolicy, It Should return error",
+			config:      map[string]string{"healthGatePolicy": "Block"},
+			expectError: true,
+		},
+		{
+			name:     "When config has concurrentBackupPolicy Fail, It Should parse it",
+			config:   map[string]string{"concurrentBackupPolicy": "Fail"},
+			wantConc: "Fail",
+		},
+		{
+			name:        "When config has an invalid concurrentBackupPolicy, It Should return error",
+			config:      map[string]string{"concurrentBackupPolicy": "Queue"},
+			expectError: true,
+		},
+		{
+			name:        "When config has executeTimeout, It Should parse the duration",
+			config:      map[string]string{"executeTimeout": "15m"},
+			wantTimeout: 15 * time.Minute,
+		},
+		{
+			name:      "When config has maxPauseDuration, It Should parse the duration",
+			config:    map[string]string{"maxPauseDuration": "45m"},
+			wantPause: 45 * time.Minute,
+		},
+		{
+			name:        "When config has a negative maxPauseDuration, It Should return error",
+			config:      map[string]string{"maxPauseDuration": "-1h"},
+			expectError: true,
+		},
+		{
+			name:        "When config has a non positive executeTimeout, It Should return error",
+			config:      map[string]string{"executeTimeout": "0s"},
+			expectError: true,
+		},
+		{
+			name:           "When config has skipVolumeLabels and skipVolumeNames, It Should parse them",
+			config:         map[string]string{"skipVolumeLabels": "example.com/cache, tier=boot", "skipVolumeNames": "*-cache,boot-*"},
+			wantSkipLabels: []string{"example.com/cache", "tier=boot"},
+			wantSkipNames:  []string{"*-cache", "boot-*"},
+		},
+		{
+			name:        "When config has an invalid skipVolumeLabels key, It Should return error",
+			config:      map[string]string{"skipVolumeLabels": "not a key"},
+			expectError: true,
+		},
+		{
+			name:        "When config has an invalid skipVolumeNames pattern, It Should return error",
+			config:      map[string]string{"skipVolumeNames": "[cache"},
+			expectError: true,
+		},
+		{
+			name:         "When config has timeouts, It Should parse the overrides",
+			config:       map[string]string{"timeouts": "etcdBackupCompletion=30m, earlierBackupsPoll=1s"},
+			wantTimeouts: common.Timeouts{EtcdBackupCompletion: 30 * time.Minute, EarlierBackupsPoll: time.Second},
+		},
+		{
+			name:         "When config has veleroNamespace, It Should set it",
+			config:       map[string]string{"veleroNamespace": "velero"},
+			wantVeleroNS: "velero",
+		},
+		{
+			name:        "When config has an invalid veleroNamespace, It Should return error",
+			config:      map[string]string{"veleroNamespace": "Velero_NS"},
+			expectError: true,
+		},
+		{
+			name:        "When config has an unknown timeout, It Should return error",
+			config:      map[string]string{"timeouts": "dataUpload=1h"},
+			expectError: true,
+		},
+		{
+			name:    "When config has tolerateErrors, It Should parse the tolerated problems",
+			config:  map[string]string{"tolerateErrors": "sourceMetadata, releaseImage"},
+			wantTol: []string{"sourceMetadata", "releaseImage"},
+		},
+		{
+			name:        "When config tolerates an unknown problem, It Should return error",
+			config:      map[string]string{"tolerateErrors": "etcdSnapshot"},
+			expectError: true,
+		},
+		{
+			name:        "When config has an invalid nodePoolSelector, It Should return error",
+			config:      map[string]string{"nodePoolSelector": "pool-type in production"},
+			expectError: true,
+		},
+		{
+			name:         "When config has agent section options, It Should parse them into the Agent options",
+			config:       map[string]string{"agent.databaseSnapshot": "true", "agent.serviceNamespace": "assisted", "agent.preserveOnDelete": "false"},
+			wantPlatform: plugtypes.PlatformOptions{Agent: plugtypes.AgentOptions{DatabaseSnapshot: true, ServiceNamespace: "assisted", SkipPreserveOnDelete: true}},
+		},
+		{
+			name:        "When strictConfig is set and the agent options lack their section, It Should return error",
+			config:      map[string]string{"agentDatabaseSnapshot": "true", "strictConfig": "true"},
+			expectError: true,
+		},
+		{
+			name:         "When config has aws section options, It Should parse them into the AWS options",
+			config:       map[string]string{"aws.regenPrivateLink": "true"},
+			wantPlatform: plugtypes.PlatformOptions{AWS: plugtypes.AWSOptions{RegenPrivateLink: true}},
+		},
+		{
+			name:        "When config has an invalid agent.serviceNamespace, It Should return error",
+			config:      map[string]string{"agent.serviceNamespace": "Assisted_NS"},
+			expectError: true,
+		},
+		{
+			name:   "When config has an unknown option of a platform section, It Should not return error",
+			config: map[string]string{"kubevirt.freezeVm": "true"},
+		},
+		{
+			name:        "When config has kubevirt.freezeVMs, It Should return error as it is not supported",
+			config:      map[string]string{"kubevirt.freezeVMs": "true"},
+			expectError: true,
+		},
+		{
+			name:        "When strictConfig is set and an option of a platform section is unknown, It Should return error",
+			config:      map[string]string{"strictConfig": "true", "aws.regenPrivatelink": "true"},
+			expectError: true,
+		},
+		{
+			name:        "When config has an executeTimeout below the minimum, It Should return error",
+			config:      map[string]string{"executeTimeout": "30s"},
+			expectError: true,
+		},
+		{
+			name:        "When executeTimeout is not longer than the earlierBackupsPoll of the Wait policy, It Should return error",
+			config:      map[string]string{"executeTimeout": "1m", "timeouts": "earlierBackupsPoll=1m"},
+			expectError: true,
+		},
+		{
+			name:         "When executeTimeout is not longer than the earlierBackupsPoll with the Ignore policy, It Should accept them",
+			config:       map[string]string{"executeTimeout": "1m", "timeouts": "earlierBackupsPoll=1m", "concurrentBackupPolicy": "Ignore"},
+			wantTimeout:  time.Minute,
+			wantTimeouts: common.Timeouts{EarlierBackupsPoll: time.Minute},
+			wantConc:     "Ignore",
+		},
+		{
+			name:        "When hookEvents has afterSnapshots without the etcdSnapshot method, It Should return error",
+			config:      map[string]string{"hookEvents": "beforePause, afterSnapshots"},
+			expectError: true,
+		},
+		{
+			name:   "When hookEvents has afterSnapshots with the etcdSnapshot method, It Should not return error",
+			config: map[string]string{"hookEvents": "afterSnapshots", "etcdBackupMethod": "etcdSnapshot"},
+		},
+		{
+			name:        "When maxPauseDuration is not longer than the pausePropagation timeout, It Should return error",
+			config:      map[string]string{"maxPauseDuration": "2m"},
+			expectError: true,
+		},
+		{
+			name:        "When strictConfig is set and a key is unknown, It Should return error",
+			config:      map[string]string{"strictConfig": "true", "etcdBackupMetod": "etcdSnapshot"},
+			expectError: true,
+		},
+		{
+			name:   "When strictConfig is set with restore options, It Should accept them without error",
+			config: map[string]string{"strictConfig": "true", "readoptNodes": "true", "podRestorePolicy": "SkipNone"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -49,6 +274,24 @@ func TestBackupValidatePluginConfig(t *testing.T) {
 			} else {
 				g.Expect(err).ToNot(HaveOccurred())
 				g.Expect(opts.Migration).To(Equal(tt.wantMigr))
+				g.Expect(opts.MigrationRetainPVCs).To(Equal(tt.wantRetain))
+				g.Expect(opts.SkipDeletingCluster).To(Equal(tt.wantSkipDel))
+				g.Expect(opts.HealthGatePolicy).To(Equal(tt.wantHealth))
+				g.Expect(opts.ConcurrentBackupPolicy).To(Equal(tt.wantConc))
+				g.Expect(opts.TolerateErrors).To(Equal(tt.wantTol))
+				g.Expect(opts.ExecuteTimeout).To(Equal(tt.wantTimeout))
+				g.Expect(opts.Timeouts).To(Equal(tt.wantTimeouts))
+				g.Expect(opts.SkipVolumeLabels).To(Equal(tt.wantSkipLabels))
+				g.Expect(opts.SkipVolumeNames).To(Equal(tt.wantSkipNames))
+				g.Expect(opts.MaxPauseDuration).To(Equal(tt.wantPause))
+				g.Expect(opts.FSBackupPods).To(Equal(tt.wantFSPods))
+				g.Expect(opts.VeleroNamespace).To(Equal(tt.wantVeleroNS))
+				g.Expect(opts.PlatformOptions).To(Equal(tt.wantPlatform))
+				if tt.wantNPSel != "" {
+					g.Expect(opts.NodePoolSelector.String()).To(Equal(tt.wantNPSel))
+				} else {
+					g.Expect(opts.NodePoolSelector).To(BeNil())
+				}
 			}
 		})
 	}
@@ -58,6 +301,8 @@ func TestBackupValidatePlatformConfig(t *testing.T) {
 	tests := []struct {
 		name         string
 		platformType hyperv1.PlatformType
+		openStack    *hyperv1.OpenStackPlatformSpec
+		objects      []crclient.Object
 		wantErr      bool
 		errSubstr    string
 	}{
@@ -73,6 +318,10 @@ func TestBackupValidatePlatformConfig(t *testing.T) {
 			name:         "When ValidatePlatformConfig runs with IBMCloud platform, It Should return no error",
 			platformType: hyperv1.IBMCloudPlatform,
 		},
+		{
+			name:         "When ValidatePlatformConfig runs with PowerVS platform, It Should return no error",
+			platformType: hyperv1.PowerVSPlatform,
+		},
 		{
 			name:         "When ValidatePlatformConfig runs with Kubevirt platform, It Should return no error",
 			platformType: hyperv1.KubevirtPlatform,
@@ -80,6 +329,18 @@ func TestBackupValidatePlatformConfig(t *testing.T) {
 		{
 			name:         "When ValidatePlatformConfig runs with OpenStack platform, It Should return no error",
 			platformType: hyperv1.OpenStackPlatform,
+			openStack:    &hyperv1.OpenStackPlatformSpec{IdentityRef: hyperv1.OpenStackIdentityReference{Name: "openstack-credentials", CloudName: "openstack"}},
+			objects: []crclient.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "openstack-credentials", Namespace: "test-ns"},
+				Data:       map[string][]byte{"clouds.yaml": []byte("clouds:\n  openstack:\n    auth:\n      auth_url: https://keystone.example.com:5000\n")},
+			}},
+		},
+		{
+			name:         "When ValidatePlatformConfig runs with OpenStack platform without its credentials secret, It Should return error",
+			platformType: hyperv1.OpenStackPlatform,
+			openStack:    &hyperv1.OpenStackPlatformSpec{IdentityRef: hyperv1.OpenStackIdentityReference{Name: "openstack-credentials", CloudName: "openstack"}},
+			wantErr:      true,
+			errSubstr:    "identityRef secret test-ns/openstack-credentials of OpenStack HostedControlPlane test-hcp not found",
 		},
 		{
 			name:         "When ValidatePlatformConfig runs with Agent platform, It Should return no error",
@@ -100,19 +361,22 @@ func TestBackupValidatePlatformConfig(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			g := NewWithT(t)
-			p := &BackupPluginValidator{Log: logrus.New()}
+			p := &BackupPluginValidator{
+				Log:    logrus.New(),
+				Client: fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(tt.objects...).Build(),
+			}
 
 			hcp := &hyperv1.HostedControlPlane{
 				ObjectMeta: metav1.ObjectMeta{Name: "test-hcp", Namespace: "test-ns"},
 				Spec: hyperv1.HostedControlPlaneSpec{
-					Platform: hyperv1.PlatformSpec{Type: tt.platformType},
+					Platform: hyperv1.PlatformSpec{Type: tt.platformType, OpenStack: tt.openStack},
 				},
 			}
 			backup := &velerov1.Backup{
 				ObjectMeta: metav1.ObjectMeta{Name: "test-backup"},
 			}
 
-			err := p.ValidatePlatformConfig(hcp, backup)
+			err := p.ValidatePlatformConfig(context.TODO(), hcp, backup)
 			if tt.wantErr {
 				g.Expect(err).To(HaveOccurred())
 				g.Expect(err.Error()).To(ContainSubstring(tt.errSubstr))
@@ -122,3 +386,320 @@ func TestBackupValidatePlatformConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestBackupValidateHostedClusterState(t *testing.T) {
+	deletionTimestamp := metav1.NewTime(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name      string
+		hc        *hyperv1.HostedCluster
+		hcp       *hyperv1.HostedControlPlane
+		errSubstr string
+	}{
+		{
+			name: "When the HostedCluster is running, It Should return no error",
+			hc:   &hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters"}},
+			hcp:  &hyperv1.HostedControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters-hc"}},
+		},
+		{
+			name: "When the HostedCluster is not found, It Should only check the HostedControlPlane",
+			hcp:  &hyperv1.HostedControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters-hc"}},
+		},
+		{
+			name: "When the HostedCluster has a deletionTimestamp, It Should return error",
+			hc: &hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{
+				Name: "hc", Namespace: "clusters", DeletionTimestamp: &deletionTimestamp, Finalizers: []string{"hypershift.openshift.io/finalizer"},
+			}},
+			hcp:       &hyperv1.HostedControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters-hc"}},
+			errSubstr: "HostedCluster clusters/hc is being deleted since 2026-01-01T10:00:00Z",
+		},
+		{
+			name: "When the HostedCluster is destroyed, It Should return error",
+			hc: &hyperv1.HostedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters"},
+				Status: hyperv1.HostedClusterStatus{Conditions: []metav1.Condition{
+					{Type: string(hyperv1.HostedClusterDestroyed), Status: metav1.ConditionTrue},
+				}},
+			},
+			hcp:       &hyperv1.HostedControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters-hc"}},
+			errSubstr: "has been destroyed",
+		},
+		{
+			name: "When the HostedControlPlane has a deletionTimestamp, It Should return error",
+			hcp: &hyperv1.HostedControlPlane{ObjectMeta: metav1.ObjectMeta{
+				Name: "hc", Namespace: "clusters-hc", DeletionTimestamp: &deletionTimestamp,
+			}},
+			errSubstr: "HostedControlPlane clusters-hc/hc is being deleted",
+		},
+		{
+			name: "When the cloud resources of the HostedControlPlane are destroyed, It Should return error",
+			hcp: &hyperv1.HostedControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters-hc"},
+				Status: hyperv1.HostedControlPlaneStatus{Conditions: []metav1.Condition{
+					{Type: string(hyperv1.CloudResourcesDestroyed), Status: metav1.ConditionTrue},
+				}},
+			},
+			errSubstr: "cloud resources",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			p := &BackupPluginValidator{Log: logrus.New()}
+
+			err := p.ValidateHostedClusterState(tt.hc, tt.hcp)
+			if tt.errSubstr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errSubstr)))
+				g.Expect(common.ErrorClass(err)).To(Equal(common.ErrorClassValidation))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestBackupValidateHostedClusterHealth(t *testing.T) {
+	hcp := &hyperv1.HostedControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters-hc"},
+		Status: hyperv1.HostedControlPlaneStatus{Conditions: []metav1.Condition{
+			{Type: string(hyperv1.EtcdAvailable), Status: metav1.ConditionFalse, Message: "quorum lost"},
+		}},
+	}
+
+	tests := []struct {
+		name         string
+		hc           *hyperv1.HostedCluster
+		wantProblems []string
+	}{
+		{
+			name: "When the HostedCluster is healthy, It Should return no problem",
+			hc: &hyperv1.HostedCluster{Status: hyperv1.HostedClusterStatus{Conditions: []metav1.Condition{
+				{Type: string(hyperv1.HostedClusterDegraded), Status: metav1.ConditionFalse},
+				{Type: string(hyperv1.EtcdAvailable), Status: metav1.ConditionTrue},
+				{Type: string(hyperv1.ClusterVersionProgressing), Status: metav1.ConditionFalse},
+			}}},
+		},
+		{
+			name: "When the HostedCluster is degraded and updating, It Should return both problems",
+			hc: &hyperv1.HostedCluster{Status: hyperv1.HostedClusterStatus{Conditions: []metav1.Condition{
+				{Type: string(hyperv1.HostedClusterDegraded), Status: metav1.ConditionTrue, Message: "kube-apiserver crashlooping"},
+				{Type: string(hyperv1.ClusterVersionProgressing), Status: metav1.ConditionTrue, Message: "updating to 4.20.1"},
+			}}},
+			wantProblems: []string{
+				"the hosted cluster is degraded: kube-apiserver crashlooping",
+				"a cluster version update is progressing: updating to 4.20.1",
+			},
+		},
+		{
+			name:         "When the HostedCluster is not found, It Should use the HostedControlPlane conditions",
+			wantProblems: []string{"etcd is not available: quorum lost"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			p := &BackupPluginValidator{Log: logrus.New()}
+			g.Expect(p.ValidateHostedClusterHealth(tt.hc, hcp)).To(Equal(tt.wantProblems))
+		})
+	}
+}
+
+func TestBackupValidateVolumeBackupMode(t *testing.T) {
+	trueVal, falseVal := true, false
+	storageClass := "gp3-csi"
+
+	hcp := &hyperv1.HostedControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters-hc"},
+		Spec:       hyperv1.HostedControlPlaneSpec{Platform: hyperv1.PlatformSpec{Type: hyperv1.AgentPlatform}},
+	}
+	etcdPVC := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data-etcd-0", Namespace: "clusters-hc"},
+		Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &storageClass},
+	}
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: storageClass}, Provisioner: "ebs.csi.aws.com"}
+	snapshotClass := &snapshotv1.VolumeSnapshotClass{ObjectMeta: metav1.ObjectMeta{Name: "csi-aws-vsc"}, Driver: "ebs.csi.aws.com"}
+	nodeAgent := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "node-agent", Namespace: "openshift-adp"}}
+
+	tests := []struct {
+		name             string
+		fsBackup         *bool
+		etcdBackupMethod string
+		policy           string
+		veleroNamespace  string
+		objects          []crclient.Object
+		wantAuto         bool
+		errSubstr        string
+	}{
+		{
+			name: "When defaultVolumesToFsBackup is unset, It Should not check the mode",
+		},
+		{
+			name:    "When defaultVolumesToFsBackup is unset with policy Fail and CSI snapshots work, It Should return no error",
+			policy:  common.VolumeBackupModePolicyFail,
+			objects: []crclient.Object{etcdPVC, sc, snapshotClass},
+		},
+		{
+			name:      "When defaultVolumesToFsBackup is unset with policy Fail and CSI snapshots cannot work, It Should return an actionable error",
+			policy:    common.VolumeBackupModePolicyFail,
+			objects:   []crclient.Object{etcdPVC, sc},
+			errSubstr: "defaultVolumesToFsBackup is not set on backup daily",
+		},
+		{
+			name:     "When defaultVolumesToFsBackup is unset with policy Auto and CSI snapshots cannot work, It Should select fs-backup",
+			policy:   common.VolumeBackupModePolicyAuto,
+			objects:  []crclient.Object{etcdPVC, sc, nodeAgent},
+			wantAuto: true,
+		},
+		{
+			name:      "When defaultVolumesToFsBackup is unset with policy Auto and neither mode can work, It Should return an actionable error",
+			policy:    common.VolumeBackupModePolicyAuto,
+			objects:   []crclient.Object{etcdPVC, sc},
+			errSubstr: "neither CSI snapshots nor fs-backup",
+		},
+		{
+			name:     "When fs-backup is used with the node agent deployed, It Should return no error",
+			fsBackup: &trueVal,
+			objects:  []crclient.Object{nodeAgent},
+		},
+		{
+			name:            "When fs-backup is used with the node agent in another Velero namespace, It Should look for it there",
+			fsBackup:        &trueVal,
+			veleroNamespace: "velero",
+			objects:         []crclient.Object{&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "node-agent", Namespace: "velero"}}},
+		},
+		{
+			name:            "When fs-backup is used and the node agent is not in the Velero namespace, It Should return an actionable error",
+			fsBackup:        &trueVal,
+			veleroNamespace: "velero",
+			objects:         []crclient.Object{nodeAgent},
+			errSubstr:       "not deployed in namespace velero",
+		},
+		{
+			name:      "When fs-backup is used without the node agent, It Should return an actionable error",
+			fsBackup:  &trueVal,
+			errSubstr: "enable the node agent",
+		},
+		{
+			name:     "When CSI snapshots are used with a VolumeSnapshotClass for the driver, It Should return no error",
+			fsBackup: &falseVal,
+			objects:  []crclient.Object{etcdPVC, sc, snapshotClass},
+		},
+		{
+			name:      "When CSI snapshots are used without a VolumeSnapshotClass for the driver, It Should return an actionable error",
+			fsBackup:  &falseVal,
+			objects:   []crclient.Object{etcdPVC, sc},
+			errSubstr: "driver ebs.csi.aws.com has no VolumeSnapshotClass on the Agent platform cluster",
+		},
+		{
+			name:             "When CSI snapshots are used with the etcdSnapshot method, It Should not check the etcd PVCs",
+			fsBackup:         &falseVal,
+			etcdBackupMethod: common.EtcdBackupMethodEtcdSnapshot,
+			objects:          []crclient.Object{etcdPVC, sc},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(tt.objects...).Build()
+			p := &BackupPluginValidator{Log: logrus.New(), Client: client, VeleroNamespace: tt.veleroNamespace}
+			backup := &velerov1.Backup{
+				ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: "openshift-adp"},
+				Spec:       velerov1.BackupSpec{DefaultVolumesToFsBackup: tt.fsBackup},
+			}
+			etcdBackupMethod := tt.etcdBackupMethod
+			if etcdBackupMethod == "" {
+				etcdBackupMethod = common.EtcdBackupMethodVolume
+			}
+
+			autoFSBackup, err := p.ValidateVolumeBackupMode(context.TODO(), hcp, backup, etcdBackupMethod, tt.policy)
+			if tt.errSubstr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errSubstr)))
+				var validationErr *common.ValidationError
+				g.Expect(errors.As(err, &validationErr)).To(BeTrue())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			g.Expect(autoFSBackup).To(Equal(tt.wantAuto))
+		})
+	}
+}
+
+func TestBackupValidateStorageLocations(t *testing.T) {
+	newBSL := func(name string, isDefault bool, phase velerov1.BackupStorageLocationPhase) *velerov1.BackupStorageLocation {
+		return &velerov1.BackupStorageLocation{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openshift-adp"},
+			Spec:       velerov1.BackupStorageLocationSpec{Default: isDefault},
+			Status:     velerov1.BackupStorageLocationStatus{Phase: phase, Message: "credentials rejected"},
+		}
+	}
+	unavailableVSL := &velerov1.VolumeSnapshotLocation{
+		ObjectMeta: metav1.ObjectMeta{Name: "vsl-1", Namespace: "openshift-adp"},
+		Status:     velerov1.VolumeSnapshotLocationStatus{Phase: velerov1.VolumeSnapshotLocationPhaseUnavailable},
+	}
+	unknownVSL := &velerov1.VolumeSnapshotLocation{ObjectMeta: metav1.ObjectMeta{Name: "vsl-2", Namespace: "openshift-adp"}}
+
+	tests := []struct {
+		name      string
+		spec      velerov1.BackupSpec
+		objects   []crclient.Object
+		errSubstr string
+	}{
+		{
+			name:    "When the named BSL is Available, It Should return no error",
+			spec:    velerov1.BackupSpec{StorageLocation: "bsl-1"},
+			objects: []crclient.Object{newBSL("bsl-1", false, velerov1.BackupStorageLocationPhaseAvailable)},
+		},
+		{
+			name:      "When the named BSL is Unavailable, It Should return error",
+			spec:      velerov1.BackupSpec{StorageLocation: "bsl-1"},
+			objects:   []crclient.Object{newBSL("bsl-1", false, velerov1.BackupStorageLocationPhaseUnavailable)},
+			errSubstr: "BackupStorageLocation bsl-1 is not Available (phase \"Unavailable\"): credentials rejected",
+		},
+		{
+			name: "When no BSL is named, It Should check the default one",
+			objects: []crclient.Object{
+				newBSL("bsl-1", false, velerov1.BackupStorageLocationPhaseAvailable),
+				newBSL("bsl-2", true, velerov1.BackupStorageLocationPhaseUnavailable),
+			},
+			errSubstr: "BackupStorageLocation bsl-2 is not Available",
+		},
+		{
+			name:      "When no BSL is named and there is no default one, It Should return error",
+			objects:   []crclient.Object{newBSL("bsl-1", false, velerov1.BackupStorageLocationPhaseAvailable)},
+			errSubstr: "no default one",
+		},
+		{
+			name:      "When a VSL is Unavailable, It Should return error",
+			spec:      velerov1.BackupSpec{StorageLocation: "bsl-1", VolumeSnapshotLocations: []string{"vsl-2", "vsl-1"}},
+			objects:   []crclient.Object{newBSL("bsl-1", false, velerov1.BackupStorageLocationPhaseAvailable), unavailableVSL, unknownVSL},
+			errSubstr: "VolumeSnapshotLocation vsl-1 is Unavailable",
+		},
+		{
+			name:    "When a VSL reports no phase, It Should return no error",
+			spec:    velerov1.BackupSpec{StorageLocation: "bsl-1", VolumeSnapshotLocations: []string{"vsl-2"}},
+			objects: []crclient.Object{newBSL("bsl-1", false, velerov1.BackupStorageLocationPhaseAvailable), unknownVSL},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(tt.objects...).Build()
+			p := &BackupPluginValidator{Log: logrus.New(), Client: client}
+			backup := &velerov1.Backup{
+				ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: "openshift-adp"},
+				Spec:       tt.spec,
+			}
+
+			err := p.ValidateStorageLocations(context.TODO(), backup)
+			if tt.errSubstr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.errSubstr)))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
@@ -1,17 +1,44 @@
 package validation
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
 
+	configv1 "github.com/openshift/api/config/v1"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
 	plugtypes "github.com/openshift/hypershift-oadp-plugin/pkg/core/types"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/platform/ibm"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/platform/kubevirt"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/platform/openstack"
 	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
 	"github.com/sirupsen/logrus"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type RestoreValidator interface {
 	ValidatePluginConfig(config map[string]string) (*plugtypes.RestoreOptions, error)
-	ValidatePlatformConfig(hcp *hyperv1.HostedControlPlane, config map[string]string) error
+	ValidatePlatformConfig(ctx context.Context, hcp *hyperv1.HostedControlPlane, config map[string]string) error
+	ValidateEnvironment(ctx context.Context, hoNamespace string) error
+	ValidatePlatformCRDs(ctx context.Context, platform hyperv1.PlatformType) error
+	ValidateSourceMetadata(ctx context.Context, source *common.SourceMetadata, hc *hyperv1.HostedCluster, platforms []hyperv1.PlatformType) ([]string, error)
+	ValidateManagementVersions(ctx context.Context, source *common.SourceMetadata, hoNamespace string, skew int) error
+	ValidateReleaseVersion(ctx context.Context, source *common.SourceMetadata, hoNamespace string) error
+	ValidateCapacity(ctx context.Context, hc *hyperv1.HostedCluster) ([]string, error)
+	ValidateNetworking(ctx context.Context, hc *hyperv1.HostedCluster, source *common.SourceMetadata, backup *velerov1.Backup) ([]string, error)
 }
 
 type RestorePluginValidator struct {
@@ -28,17 +55,166 @@ func (p *RestorePluginValidator) ValidatePluginConfig(config map[string]string)
 		return &plugtypes.RestoreOptions{}, nil
 	}
 	bo := &plugtypes.RestoreOptions{}
+	var platforms []hyperv1.PlatformType
 
 	for key, value := range config {
 		p.Log.Debugf("%s configuration key: %s, value: %s", p.LogHeader, key, value)
+		if platform, option, ok := platformKey(key); ok {
+			if err := parsePlatformOption(p.Log, &bo.PlatformOptions, platform, option, config, key, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		switch key {
 		case "migration":
 			p.Log.Debugf("reading/parsing migration %s", value)
 			bo.Migration = value == "true"
-		case "etcdBackupMethod", "hoNamespace":
+		case common.ConfigKeyReleaseImageCheck:
+			p.Log.Debugf("reading/parsing releaseImageCheck %s", value)
+			bo.ReleaseImageCheck = value == "true"
+		case common.ConfigKeyRestorePaused:
+			p.Log.Debugf("reading/parsing restorePaused %s", value)
+			bo.RestorePaused = value == "true"
+		case common.ConfigKeyPausedKinds:
+			p.Log.Debugf("reading/parsing pausedKinds %s", value)
+			// Read by unpause-restore, checked here so a typo fails the restore early
+			if _, err := common.ParsePausedKinds(value); err != nil {
+				return nil, err
+			}
+		case common.ConfigKeyReconcileAfterUnpause:
+			p.Log.Debugf("reading/parsing reconcileAfterUnpause %s", value)
+			// Read by unpause-restore
+		case common.ConfigKeyReadoptNodes:
+			p.Log.Debugf("reading/parsing readoptNodes %s", value)
+			bo.ReadoptNodes = value == "true"
+		case common.ConfigKeyCapacityCheck:
+			p.Log.Debugf("reading/parsing capacityCheck %s", value)
+			bo.CapacityCheck = value == "true"
+		case common.ConfigKeyManagedServices:
+			p.Log.Debugf("reading/parsing managedServices %s", value)
+			bo.ManagedServices = value == "true"
+		case common.ConfigKeyPodRestorePolicy:
+			p.Log.Debugf("reading/parsing podRestorePolicy %s", value)
+			switch value {
+			case common.PodRestorePolicySkipAll, common.PodRestorePolicySkipControlPlane, common.PodRestorePolicySkipNone:
+				bo.PodRestorePolicy = value
+			default:
+				return nil, common.NewValidationError("invalid %s %q: must be one of %q, %q or %q", common.ConfigKeyPodRestorePolicy, value,
+					common.PodRestorePolicySkipAll, common.PodRestorePolicySkipControlPlane, common.PodRestorePolicySkipNone)
+			}
+		case common.ConfigKeyExistingObjectPolicy:
+			p.Log.Debugf("reading/parsing existingObjectPolicy %s", value)
+			switch value {
+			case common.ExistingObjectPolicyIgnore, common.ExistingObjectPolicySkip, common.ExistingObjectPolicyPatch, common.ExistingObjectPolicyMerge:
+				bo.ExistingObjectPolicy = value
+			default:
+				return nil, common.NewValidationError("invalid %s %q: must be one of %q, %q, %q or %q", common.ConfigKeyExistingObjectPolicy, value,
+					common.ExistingObjectPolicyIgnore, common.ExistingObjectPolicySkip, common.ExistingObjectPolicyPatch, common.ExistingObjectPolicyMerge)
+			}
+		case common.ConfigKeySourceMismatchPolicy:
+			p.Log.Debugf("reading/parsing sourceMismatchPolicy %s", value)
+			switch value {
+			case common.SourceMismatchPolicyWarn:
+			case common.SourceMismatchPolicyFail:
+				bo.FailOnSourceMismatch = true
+			default:
+				return nil, common.NewValidationError("invalid %s %q: must be %q or %q", common.ConfigKeySourceMismatchPolicy, value, common.SourceMismatchPolicyWarn, common.SourceMismatchPolicyFail)
+			}
+		case common.ConfigKeyManagementVersionSkew:
+			p.Log.Debugf("reading/parsing managementVersionSkew %s", value)
+			skew, err := strconv.Atoi(value)
+			if err != nil || skew < 0 {
+				return nil, common.NewValidationError("invalid %s %q: must be a number of minor versions, 0 or more", common.ConfigKeyManagementVersionSkew, value)
+			}
+			bo.ManagementVersionSkew = skew
+		case common.ConfigKeyCertificateExpiryPolicy:
+			p.Log.Debugf("reading/parsing certificateExpiryPolicy %s", value)
+			switch value {
+			case common.CertificateExpiryPolicyWarn, common.CertificateExpiryPolicyFail, common.CertificateExpiryPolicyRotate:
+				bo.CertificateExpiryPolicy = value
+			default:
+				return nil, common.NewValidationError("invalid %s %q: must be one of %q, %q or %q", common.ConfigKeyCertificateExpiryPolicy, value,
+					common.CertificateExpiryPolicyWarn, common.CertificateExpiryPolicyFail, common.CertificateExpiryPolicyRotate)
+			}
+		case common.ConfigKeyCertificateExpiryThreshold:
+			p.Log.Debugf("reading/parsing certificateExpiryThreshold %s", value)
+			threshold, err := parseDuration(common.ConfigKeyCertificateExpiryThreshold, value)
+			if err != nil {
+				return nil, err
+			}
+			bo.CertificateExpiryThreshold = threshold
+		case common.ConfigKeyDeletionProtection:
+			p.Log.Debugf("reading/parsing deletionProtection %s", value)
+			gracePeriod, err := parseDuration(common.ConfigKeyDeletionProtection, value)
+			if err != nil {
+				return nil, err
+			}
+			bo.DeletionProtection = gracePeriod
+		case common.ConfigKeyExecuteTimeout:
+			p.Log.Debugf("reading/parsing executeTimeout %s", value)
+			timeout, err := parseExecuteTimeout(value)
+			if err != nil {
+				return nil, err
+			}
+			bo.ExecuteTimeout = timeout
+		case common.ConfigKeyTimeouts:
+			p.Log.Debugf("reading/parsing timeouts %s", value)
+			timeouts, err := common.ParseTimeouts(value)
+			if err != nil {
+				return nil, err
+			}
+			bo.Timeouts = timeouts
+		case common.ConfigKeyTolerateErrors:
+			p.Log.Debugf("reading/parsing tolerateErrors %s", value)
+			tolerated, err := parseTolerateErrors(value)
+			if err != nil {
+				return nil, err
+			}
+			bo.TolerateErrors = tolerated
+		case common.ConfigKeyPlatforms:
+			p.Log.Debugf("reading/parsing platforms %s", value)
+			// Read by plugin init, parsed here for the readoptNodes check below
+			parsed, err := common.ParsePlatforms(value)
+			if err != nil {
+				return nil, err
+			}
+			platforms = parsed
+		case "etcdBackupMethod", "hoNamespace",
+			common.ConfigKeyHookWebhookURL, common.ConfigKeyHookJobTemplate, common.ConfigKeyHookEvents, common.ConfigKeyHookFailurePolicy,
+			common.ConfigKeyNotificationWebhookURL, common.ConfigKeyNotificationFormat, common.ConfigKeyNotificationImage,
+			common.ConfigKeyTracingEndpoint, common.ConfigKeyStrictConfig:
 			p.Log.Debugf("configuration key %s=%s handled by plugin init", key, value)
 		default:
-			p.Log.Warnf("unknown configuration key: %s with value %s", key, value)
+			if slices.Contains(backupOnlyKeys, key) {
+				p.Log.Debugf("configuration key %s=%s only read by the backup plugin", key, value)
+				continue
+			}
+			if err := unknownKey(p.Log, config, key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// The instances of Agent and KubeVirt NodePools belong to the source management cluster:
+	// the assisted-service inventory of the Agents, the virtual machines themselves
+	if bo.Migration && bo.ReadoptNodes {
+		for _, platform := range platforms {
+			if platform == hyperv1.AgentPlatform || platform == hyperv1.KubevirtPlatform {
+				return nil, common.NewValidationError("%s cannot be used with migration on the %s platform: its instances are not re-adopted by another management cluster",
+					common.ConfigKeyReadoptNodes, platform)
+			}
+		}
+	}
+
+	// Managed services own the lifecycle of their clusters' machines and live objects
+	if bo.ManagedServices {
+		if bo.ExistingObjectPolicy == common.ExistingObjectPolicyPatch {
+			return nil, common.NewValidationError("%s %q cannot be used with %s: it overwrites objects the managed service reconciles",
+				common.ConfigKeyExistingObjectPolicy, common.ExistingObjectPolicyPatch, common.ConfigKeyManagedServices)
+		}
+		if bo.ReadoptNodes {
+			return nil, common.NewValidationError("%s cannot be used with %s: the managed service provisions the cluster machines",
+				common.ConfigKeyReadoptNodes, common.ConfigKeyManagedServices)
 		}
 	}
 
@@ -48,22 +224,22 @@ func (p *RestorePluginValidator) ValidatePluginConfig(config map[string]string)
 
 }
 
-func (p *RestorePluginValidator) ValidatePlatformConfig(hcp *hyperv1.HostedControlPlane, config map[string]string) error {
+func (p *RestorePluginValidator) ValidatePlatformConfig(ctx context.Context, hcp *hyperv1.HostedControlPlane, config map[string]string) error {
 	switch hcp.Spec.Platform.Type {
 	case hyperv1.AWSPlatform:
 		return p.validateAWSPlatform(hcp, config)
 	case hyperv1.AzurePlatform:
 		return p.validateAzurePlatform(hcp, config)
-	case hyperv1.IBMCloudPlatform:
-		return p.validateIBMCloudPlatform(hcp, config)
+	case hyperv1.IBMCloudPlatform, hyperv1.PowerVSPlatform:
+		return p.validateIBMCloudPlatform(ctx, hcp, config)
 	case hyperv1.KubevirtPlatform:
-		return p.validateKubevirtPlatform(hcp, config)
+		return p.validateKubevirtPlatform(ctx, hcp, config)
 	case hyperv1.OpenStackPlatform:
-		return p.validateOpenStackPlatform(hcp, config)
+		return p.validateOpenStackPlatform(ctx, hcp, config)
 	case hyperv1.AgentPlatform, hyperv1.NonePlatform:
 		return p.validateAgentPlatform(hcp, config)
 	default:
-		return fmt.Errorf("unsupported platform type %s", hcp.Spec.Platform.Type)
+		return common.NewValidationError("unsupported platform type %s", hcp.Spec.Platform.Type)
 	}
 
 }
@@ -82,20 +258,31 @@ func (p *RestorePluginValidator) validateAzurePlatform(hcp *hyperv1.HostedContro
 	return nil
 }
 
-func (p *RestorePluginValidator) validateIBMCloudPlatform(hcp *hyperv1.HostedControlPlane, config map[string]string) error {
+func (p *RestorePluginValidator) validateIBMCloudPlatform(ctx context.Context, hcp *hyperv1.HostedControlPlane, config map[string]string) error {
 	// Validate if the IBM Cloud platform is configured properly
+	if err := ibm.ValidateCredentials(ctx, p.Client, hcp); err != nil {
+		return err
+	}
 	p.Log.Infof("%s IBM platform configuration is valid for HCP: %s", p.LogHeader, hcp.Name)
 	return nil
 }
 
-func (p *RestorePluginValidator) validateKubevirtPlatform(hcp *hyperv1.HostedControlPlane, config map[string]string) error {
-	// Validate if the Kubevirt platform is configured properly
+func (p *RestorePluginValidator) validateKubevirtPlatform(ctx context.Context, hcp *hyperv1.HostedControlPlane, config map[string]string) error {
+	// Validate if the Kubevirt platform is configured properly. Secrets are restored before
+	// the HostedControlPlane, so the infra kubeconfig secret of an external infra cluster
+	// is already there.
+	if err := kubevirt.ValidateInfraKubeconfig(ctx, p.Client, hcp); err != nil {
+		return err
+	}
 	p.Log.Infof("%s Kubevirt platform configuration is valid for HCP: %s", p.LogHeader, hcp.Name)
 	return nil
 }
 
-func (p *RestorePluginValidator) validateOpenStackPlatform(hcp *hyperv1.HostedControlPlane, config map[string]string) error {
+func (p *RestorePluginValidator) validateOpenStackPlatform(ctx context.Context, hcp *hyperv1.HostedControlPlane, config map[string]string) error {
 	// Validate if the OpenStack platform is configured properly
+	if err := openstack.ValidateIdentityRef(ctx, p.Client, hcp); err != nil {
+		return err
+	}
 	p.Log.Infof("%s OpenStack platform configuration is valid for HCP: %s", p.LogHeader, hcp.Name)
 	return nil
 }
@@ -105,3 +292,496 @@ func (p *RestorePluginValidator) validateAgentPlatform(hcp *hyperv1.HostedContro
 	p.Log.Infof("%s Agent platform configuration is valid for HCP: %s", p.LogHeader, hcp.Name)
 	return nil
 }
+
+// ValidateEnvironment checks that the target management cluster is able to host the
+// restored HostedClusters: the HyperShift Operator must be installed and available
+// and every HyperShift and cluster-api CRD must be present. All problems found are
+// returned together in a single error.
+func (p *RestorePluginValidator) ValidateEnvironment(ctx context.Context, hoNamespace string) error {
+	var errs []error
+
+	if err := p.checkHyperShiftOperator(ctx, hoNamespace); err != nil {
+		errs = append(errs, err)
+	}
+
+	missing, err := p.missingCRDs(ctx, common.RequiredCRDs)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(missing) > 0 {
+		errs = append(errs, common.NewValidationError("missing required CRDs: %v", missing))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s environment validation failed: %w", p.LogHeader, errors.Join(errs...))
+	}
+
+	p.Log.Infof("%s environment validated: HyperShift Operator and required CRDs are present", p.LogHeader)
+	return nil
+}
+
+// ValidatePlatformCRDs checks that the cluster-api provider CRDs for the given platform are present.
+func (p *RestorePluginValidator) ValidatePlatformCRDs(ctx context.Context, platform hyperv1.PlatformType) error {
+	missing, err := p.missingCRDs(ctx, common.PlatformCRDs[platform])
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return common.NewValidationError("missing %s platform CRDs: %v", platform, missing)
+	}
+	return nil
+}
+
+// ValidateSourceMetadata compares the environment a HostedCluster was backed up from with
+// the target management cluster, and returns the mismatches found.
+func (p *RestorePluginValidator) ValidateSourceMetadata(ctx context.Context, source *common.SourceMetadata, hc *hyperv1.HostedCluster, platforms []hyperv1.PlatformType) ([]string, error) {
+	var mismatches []string
+
+	if source.ReleaseImage != "" && source.ReleaseImage != hc.Spec.Release.Image {
+		mismatches = append(mismatches, fmt.Sprintf("release image changed from %s to %s since the backup", source.ReleaseImage, hc.Spec.Release.Image))
+	}
+
+	if len(platforms) > 0 && source.Platform != "" && !slices.Contains(platforms, hyperv1.PlatformType(source.Platform)) {
+		mismatches = append(mismatches, fmt.Sprintf("platform %s is not among the platforms handled on this cluster %v", source.Platform, platforms))
+	}
+
+	if source.InfraID != "" {
+		hcList := &hyperv1.HostedClusterList{}
+		if err := p.Client.List(ctx, hcList); err != nil {
+			return nil, fmt.Errorf("error listing HostedClusters: %w", err)
+		}
+		for _, other := range hcList.Items {
+			if other.Spec.InfraID == source.InfraID && (other.Namespace != hc.Namespace || other.Name != hc.Name) {
+				mismatches = append(mismatches, fmt.Sprintf("infraID %s is already used by HostedCluster %s/%s", source.InfraID, other.Namespace, other.Name))
+			}
+		}
+	}
+
+	if source.EtcdStorageClass != "" {
+		sc := &storagev1.StorageClass{}
+		if err := p.Client.Get(ctx, types.NamespacedName{Name: source.EtcdStorageClass}, sc); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("error getting StorageClass %s: %w", source.EtcdStorageClass, err)
+			}
+			mismatches = append(mismatches, fmt.Sprintf("etcd StorageClass %s (%s volumes) does not exist", source.EtcdStorageClass, source.EtcdSize))
+		}
+	}
+
+	return mismatches, nil
+}
+
+// ValidateManagementVersions refuses to restore on a target management cluster whose OpenShift
+// version, or whose HyperShift Operator, is more than skew minor versions behind the source
+// recorded at backup time: the older target may not run the restored control plane.
+// Versions that were not recorded, or that the target does not have, are not compared.
+func (p *RestorePluginValidator) ValidateManagementVersions(ctx context.Context, source *common.SourceMetadata, hoNamespace string, skew int) error {
+	var problems []string
+	if source.ManagementClusterVersion != "" {
+		target, err := common.GetManagementClusterVersion(ctx, p.Client)
+		if err != nil {
+			return err
+		}
+		if behind, err := common.IsVersionBehind(target, source.ManagementClusterVersion, skew); err != nil {
+			p.Log.Warnf("%s could not compare management cluster versions: %v", p.LogHeader, err)
+		} else if behind {
+			problems = append(problems, fmt.Sprintf("management cluster version %s is older than the source %s", target, source.ManagementClusterVersion))
+		}
+	}
+	if source.HyperShiftOperatorVersion != "" {
+		target, err := common.GetHyperShiftOperatorVersion(ctx, p.Client, hoNamespace)
+		if err != nil {
+			return err
+		}
+		if behind, err := common.IsVersionBehind(target, source.HyperShiftOperatorVersion, skew); err != nil {
+			p.Log.Warnf("%s could not compare HyperShift Operator versions: %v", p.LogHeader, err)
+		} else if behind {
+			problems = append(problems, fmt.Sprintf("HyperShift Operator supports OCP up to %s, older than the source operator supporting %s", target, source.HyperShiftOperatorVersion))
+		}
+	}
+	if len(problems) > 0 {
+		return common.NewValidationError("refusing to restore on an older management cluster (%s %d): %s",
+			common.ConfigKeyManagementVersionSkew, skew, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ValidateReleaseVersion refuses to restore a HostedCluster whose release is outside the range
+// of OCP versions the HyperShift Operator of the target supports, from the oldest to the newest
+// of its supported-versions ConfigMap: the operator would not reconcile it. Only the minor
+// versions are compared. A release version that was not recorded is not checked.
+func (p *RestorePluginValidator) ValidateReleaseVersion(ctx context.Context, source *common.SourceMetadata, hoNamespace string) error {
+	if source.ReleaseVersion == "" {
+		return nil
+	}
+	release, err := utilversion.ParseGeneric(source.ReleaseVersion)
+	if err != nil {
+		p.Log.Warnf("%s could not parse the HostedCluster release version %s: %v", p.LogHeader, source.ReleaseVersion, err)
+		return nil
+	}
+	versions, err := GetSupportedVersions(ctx, p.Client, hoNamespace)
+	if err != nil {
+		return err
+	}
+
+	var oldest, newest *utilversion.Version
+	for _, v := range versions {
+		parsed, err := utilversion.ParseGeneric(v)
+		if err != nil {
+			p.Log.Warnf("%s ignoring the unparsable HyperShift Operator supported version %s: %v", p.LogHeader, v, err)
+			continue
+		}
+		if oldest == nil || parsed.LessThan(oldest) {
+			oldest = parsed
+		}
+		if newest == nil || parsed.GreaterThan(newest) {
+			newest = parsed
+		}
+	}
+	if oldest == nil {
+		p.Log.Warnf("%s HyperShift Operator publishes no supported versions, the HostedCluster release %s is not checked", p.LogHeader, source.ReleaseVersion)
+		return nil
+	}
+
+	minor := utilversion.MajorMinor(release.Major(), release.Minor())
+	if minor.LessThan(utilversion.MajorMinor(oldest.Major(), oldest.Minor())) || minor.GreaterThan(utilversion.MajorMinor(newest.Major(), newest.Minor())) {
+		return common.NewValidationError("HostedCluster release %s is not supported by the HyperShift Operator of the target, which supports OCP %d.%d to %d.%d",
+			source.ReleaseVersion, oldest.Major(), oldest.Minor(), newest.Major(), newest.Minor())
+	}
+	return nil
+}
+
+// controlPlaneComponent is a rough estimate of the resource requests of a hosted control
+// plane component, per replica, and of its replicas in HighlyAvailable mode. SingleReplica
+// control planes run one replica of each.
+type controlPlaneComponent struct {
+	name       string
+	cpu        resource.Quantity
+	memory     resource.Quantity
+	haReplicas int
+}
+
+// controlPlaneComponents are the largest components of a hosted control plane, which
+// account for most of its requests.
+var controlPlaneComponents = []controlPlaneComponent{
+	{"etcd", resource.MustParse("300m"), resource.MustParse("600Mi"), 3},
+	{"kube-apiserver", resource.MustParse("350m"), resource.MustParse("2000Mi"), 3},
+	{"kube-controller-manager", resource.MustParse("60m"), resource.MustParse("200Mi"), 2},
+	{"kube-scheduler", resource.MustParse("25m"), resource.MustParse("150Mi"), 2},
+	{"openshift-apiserver", resource.MustParse("100m"), resource.MustParse("500Mi"), 3},
+	{"openshift-oauth-apiserver", resource.MustParse("25m"), resource.MustParse("80Mi"), 3},
+	{"oauth-openshift", resource.MustParse("25m"), resource.MustParse("40Mi"), 3},
+	{"openshift-controller-manager", resource.MustParse("100m"), resource.MustParse("100Mi"), 2},
+	{"cluster-version-operator", resource.MustParse("20m"), resource.MustParse("70Mi"), 1},
+	{"control-plane-operator", resource.MustParse("10m"), resource.MustParse("80Mi"), 1},
+}
+
+// ValidateCapacity estimates the resource requests of the control plane of the HostedCluster
+// from its availability policy, and returns the reasons the target management cluster would
+// leave it Pending: no schedulable Node matching its nodeSelector and tolerations, fewer such
+// Nodes than the HighlyAvailable replicas spread over, or not enough free CPU or memory on them.
+func (p *RestorePluginValidator) ValidateCapacity(ctx context.Context, hc *hyperv1.HostedCluster) ([]string, error) {
+	nodes := &corev1.NodeList{}
+	if err := p.Client.List(ctx, nodes); err != nil {
+		return nil, fmt.Errorf("error listing Nodes: %w", err)
+	}
+	pods := &corev1.PodList{}
+	if err := p.Client.List(ctx, pods); err != nil {
+		return nil, fmt.Errorf("error listing Pods: %w", err)
+	}
+
+	requested := map[string]corev1.ResourceList{}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if requested[pod.Spec.NodeName] == nil {
+			requested[pod.Spec.NodeName] = corev1.ResourceList{}
+		}
+		for _, container := range pod.Spec.Containers {
+			for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+				if quantity, ok := container.Resources.Requests[name]; ok {
+					total := requested[pod.Spec.NodeName][name]
+					total.Add(quantity)
+					requested[pod.Spec.NodeName][name] = total
+				}
+			}
+		}
+	}
+
+	tolerations := append([]corev1.Toleration{
+		{Key: common.ControlPlaneTaint, Operator: corev1.TolerationOpExists},
+		{Key: common.ClusterTaint, Operator: corev1.TolerationOpEqual, Value: common.GetHCPNamespace(hc.Name, hc.Namespace)},
+	}, hc.Spec.Tolerations...)
+
+	var eligible int
+	var freeCPU, freeMemory resource.Quantity
+	var fitsLargest bool
+	largest := controlPlaneComponents[1]
+	for _, node := range nodes.Items {
+		if !schedulable(&node, hc.Spec.NodeSelector, tolerations) {
+			continue
+		}
+		eligible++
+		cpu := node.Status.Allocatable.Cpu().DeepCopy()
+		cpu.Sub(requested[node.Name][corev1.ResourceCPU])
+		memory := node.Status.Allocatable.Memory().DeepCopy()
+		memory.Sub(requested[node.Name][corev1.ResourceMemory])
+		freeCPU.Add(cpu)
+		freeMemory.Add(memory)
+		if cpu.Cmp(largest.cpu) >= 0 && memory.Cmp(largest.memory) >= 0 {
+			fitsLargest = true
+		}
+	}
+
+	if eligible == 0 {
+		return []string{fmt.Sprintf("no schedulable Node matches the control plane nodeSelector %v and tolerations", hc.Spec.NodeSelector)}, nil
+	}
+
+	highlyAvailable := hc.Spec.ControllerAvailabilityPolicy != hyperv1.SingleReplica
+	var cpu, memory resource.Quantity
+	spread := 1
+	for _, component := range controlPlaneComponents {
+		replicas := 1
+		if highlyAvailable {
+			replicas = component.haReplicas
+		}
+		spread = max(spread, replicas)
+		for range replicas {
+			cpu.Add(component.cpu)
+			memory.Add(component.memory)
+		}
+	}
+
+	var problems []string
+	if eligible < spread {
+		problems = append(problems, fmt.Sprintf("the HighlyAvailable control plane spreads its etcd and kube-apiserver replicas over %d Nodes, only %d are schedulable",
+			spread, eligible))
+	}
+	if freeCPU.Cmp(cpu) < 0 || freeMemory.Cmp(memory) < 0 {
+		problems = append(problems, fmt.Sprintf("the control plane requests about %s CPU and %s memory, the schedulable Nodes have %s CPU and %s memory free",
+			cpu.String(), memory.String(), freeCPU.String(), freeMemory.String()))
+	} else if !fitsLargest {
+		problems = append(problems, fmt.Sprintf("no schedulable Node has the %s CPU and %s memory a %s replica requests",
+			largest.cpu.String(), largest.memory.String(), largest.name))
+	}
+	return problems, nil
+}
+
+// schedulable reports whether control plane pods with the nodeSelector and tolerations can be
+// scheduled on the Node: it is Ready, not cordoned, carries the selected labels, and every
+// NoSchedule or NoExecute taint is tolerated.
+func schedulable(node *corev1.Node, nodeSelector map[string]string, tolerations []corev1.Toleration) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	ready := false
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			ready = condition.Status == corev1.ConditionTrue
+		}
+	}
+	if !ready {
+		return false
+	}
+	for key, value := range nodeSelector {
+		if node.Labels[key] != value {
+			return false
+		}
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		if !slices.ContainsFunc(tolerations, func(t corev1.Toleration) bool { return tolerates(t, taint) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// tolerates reports whether the toleration matches the taint, following the scheduler rules
+// for the Equal and Exists operators.
+func tolerates(toleration corev1.Toleration, taint corev1.Taint) bool {
+	if toleration.Effect != "" && toleration.Effect != taint.Effect {
+		return false
+	}
+	if toleration.Key != "" && toleration.Key != taint.Key {
+		return false
+	}
+	switch toleration.Operator {
+	case corev1.TolerationOpExists:
+		return true
+	case "", corev1.TolerationOpEqual:
+		return toleration.Value == taint.Value
+	}
+	return false
+}
+
+// network is a CIDR of the networking of a cluster, named after the field it is set in.
+type network struct {
+	field string
+	cidr  *net.IPNet
+}
+
+// ValidateNetworking returns the conflicts of the networking of the HostedCluster with
+// itself and with the target management cluster: overlapping cluster, service and machine
+// networks, and on KubeVirt, whose nodes run on the pod network of the management cluster,
+// networks overlapping its own or a management cluster not running OVNKubernetes. The OVN
+// database PVCs the source control plane had are checked to be in the backup, without
+// which the restored OVN control plane starts from empty databases. The trust bundle
+// ConfigMaps of the HostedCluster must exist, Velero restores them before it, and the
+// proxy of the target management cluster match the source one, or the control plane fails
+// to pull images and to egress.
+func (p *RestorePluginValidator) ValidateNetworking(ctx context.Context, hc *hyperv1.HostedCluster, source *common.SourceMetadata, backup *velerov1.Backup) ([]string, error) {
+	var hosted []network
+	for _, entry := range hc.Spec.Networking.ClusterNetwork {
+		hosted = append(hosted, network{"clusterNetwork", (*net.IPNet)(&entry.CIDR)})
+	}
+	for _, entry := range hc.Spec.Networking.ServiceNetwork {
+		hosted = append(hosted, network{"serviceNetwork", (*net.IPNet)(&entry.CIDR)})
+	}
+	for _, entry := range hc.Spec.Networking.MachineNetwork {
+		hosted = append(hosted, network{"machineNetwork", (*net.IPNet)(&entry.CIDR)})
+	}
+
+	var problems []string
+	for i, a := range hosted {
+		for _, b := range hosted[i+1:] {
+			if a.field != b.field && overlaps(a.cidr, b.cidr) {
+				problems = append(problems, fmt.Sprintf("%s %s overlaps %s %s", a.field, a.cidr, b.field, b.cidr))
+			}
+		}
+	}
+
+	if hc.Spec.Platform.Type == hyperv1.KubevirtPlatform {
+		management, err := p.managementNetworks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if management != nil {
+			if management.Status.NetworkType != "" && management.Status.NetworkType != string(hyperv1.OVNKubernetes) {
+				problems = append(problems, fmt.Sprintf("the management cluster network type is %s, KubeVirt HostedClusters need %s",
+					management.Status.NetworkType, hyperv1.OVNKubernetes))
+			}
+			var managed []network
+			for _, entry := range management.Status.ClusterNetwork {
+				if _, cidr, err := net.ParseCIDR(entry.CIDR); err == nil {
+					managed = append(managed, network{"clusterNetwork", cidr})
+				}
+			}
+			for _, entry := range management.Status.ServiceNetwork {
+				if _, cidr, err := net.ParseCIDR(entry); err == nil {
+					managed = append(managed, network{"serviceNetwork", cidr})
+				}
+			}
+			for _, a := range hosted {
+				for _, b := range managed {
+					if a.field != "machineNetwork" && overlaps(a.cidr, b.cidr) {
+						problems = append(problems, fmt.Sprintf("%s %s overlaps the management cluster %s %s", a.field, a.cidr, b.field, b.cidr))
+					}
+				}
+			}
+		}
+	}
+
+	if source != nil && len(source.OVNDatabaseVolumes) > 0 &&
+		!common.BackupIncludesResource(backup, "persistentvolumeclaims", "persistentvolumeclaim", "pvc") {
+		problems = append(problems, fmt.Sprintf("backup %s excludes PersistentVolumeClaims, the OVN database volumes %s of the source control plane are not restored",
+			backup.Name, strings.Join(source.OVNDatabaseVolumes, ", ")))
+	}
+
+	for _, name := range common.TrustBundleConfigMaps(hc) {
+		if err := p.Client.Get(ctx, types.NamespacedName{Namespace: hc.Namespace, Name: name}, &corev1.ConfigMap{}); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("error getting ConfigMap %s/%s: %w", hc.Namespace, name, err)
+			}
+			problems = append(problems, fmt.Sprintf("trust bundle ConfigMap %s/%s does not exist", hc.Namespace, name))
+		}
+	}
+
+	if source != nil {
+		proxy, trustedCA, err := common.GetManagementProxy(ctx, p.Client)
+		if err != nil {
+			return nil, err
+		}
+		if proxy != source.ManagementProxy {
+			problems = append(problems, fmt.Sprintf("management cluster proxy %q differs from the source %q", proxy, source.ManagementProxy))
+		}
+		if source.ManagementTrustedCA != "" && trustedCA == "" {
+			problems = append(problems, fmt.Sprintf("management cluster trusts no proxy CA bundle, the source trusted openshift-config/%s", source.ManagementTrustedCA))
+		}
+	}
+	return problems, nil
+}
+
+// managementNetworks returns the cluster Network configuration of the management cluster, or
+// nil when it is not OpenShift.
+func (p *RestorePluginValidator) managementNetworks(ctx context.Context) (*configv1.Network, error) {
+	network := &configv1.Network{}
+	if err := p.Client.Get(ctx, types.NamespacedName{Name: "cluster"}, network); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting the management cluster Network configuration: %w", err)
+	}
+	return network, nil
+}
+
+// overlaps reports whether two CIDRs share addresses, which for CIDRs means one contains
+// the other.
+func overlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// checkHyperShiftOperator verifies the HyperShift Operator deployment is available and
+// that it publishes the supported-versions ConfigMap, which also tells us it is recent
+// enough to reconcile restored HostedClusters. The release of each HostedCluster is checked
+// against the supported versions by ValidateReleaseVersion.
+func (p *RestorePluginValidator) checkHyperShiftOperator(ctx context.Context, hoNamespace string) error {
+	deployment := &appsv1.Deployment{}
+	if err := p.Client.Get(ctx, types.NamespacedName{Name: common.HODeploymentName, Namespace: hoNamespace}, deployment); err != nil {
+		if apierrors.IsNotFound(err) {
+			return common.NewValidationError("HyperShift Operator deployment %s/%s not found", hoNamespace, common.HODeploymentName)
+		}
+		return fmt.Errorf("error getting HyperShift Operator deployment: %w", err)
+	}
+	if deployment.Status.AvailableReplicas < 1 {
+		return common.NewValidationError("HyperShift Operator deployment %s/%s has no available replicas", hoNamespace, common.HODeploymentName)
+	}
+
+	versions, err := GetSupportedVersions(ctx, p.Client, hoNamespace)
+	if err != nil {
+		return err
+	}
+	p.Log.Debugf("HyperShift Operator supports OCP versions: %v", versions)
+
+	return nil
+}
+
+// missingCRDs returns the subset of the given CRD names that are not present in the cluster.
+func (p *RestorePluginValidator) missingCRDs(ctx context.Context, crds []string) ([]string, error) {
+	var missing []string
+	for _, crd := range crds {
+		exists, err := common.CRDExists(ctx, crd, p.Client)
+		if err != nil {
+			return nil, fmt.Errorf("error checking CRD %s: %w", crd, err)
+		}
+		if !exists {
+			missing = append(missing, crd)
+		}
+	}
+	return missing, nil
+}
+
+// GetSupportedVersions reads the list of OCP versions supported by the HyperShift
+// Operator from its supported-versions ConfigMap.
+func GetSupportedVersions(ctx context.Context, c crclient.Client, hoNamespace string) ([]string, error) {
+	versions, err := common.GetHOSupportedVersions(ctx, c, hoNamespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, common.NewValidationError("HyperShift Operator %s ConfigMap not found in namespace %s, the operator is too old or not installed", common.HOSupportedVersionsConfigMapName, hoNamespace)
+		}
+		return nil, fmt.Errorf("error getting HyperShift Operator supported versions: %w", err)
+	}
+	return versions, nil
+}
@@ -0,0 +1,244 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v8/apis/volumesnapshot/v1"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	velerov2alpha1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v2alpha1"
+	"github.com/vmware-tanzu/velero/pkg/label"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ConfigMapPrefix prefixes the name of the ConfigMap holding the bundle of a backup.
+	ConfigMapPrefix = "hcp-diagnostics-"
+
+	// maxEvents bounds the events kept in a bundle, the most recent first.
+	maxEvents = 30
+)
+
+// Bundle maps the name of a diagnostics entry to its content.
+type Bundle map[string]string
+
+// objectStatus is the part of an object status kept in a bundle.
+type objectStatus struct {
+	Name       string             `json:"name"`
+	Phase      string             `json:"phase,omitempty"`
+	Message    string             `json:"message,omitempty"`
+	Progress   string             `json:"progress,omitempty"`
+	ReadyToUse *bool              `json:"readyToUse,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// Collect gathers the diagnostics of a failed HCP backup: the HostedCluster, HostedControlPlane
+// and HCPEtcdBackup conditions, the DataUpload, PodVolumeBackup, VolumeSnapshot and
+// VolumeSnapshotContent statuses of the backup, and the recent warning events of the control
+// plane namespace. The DataUploads and PodVolumeBackups are those Velero created in its
+// veleroNamespace. The objects labeled with the backup name are only kept if they belong to
+// this Backup rather than to a deleted one of the same name. Sources that cannot be read are
+// noted in the bundle rather than failing the collection.
+func Collect(ctx context.Context, c crclient.Client, backup *velerov1.Backup, veleroNamespace, hcpNamespace string, cause error) Bundle {
+	b := Bundle{"error": cause.Error()}
+	selector := crclient.MatchingLabelsSelector{Selector: label.NewSelectorForBackup(backup.Name)}
+
+	b.add("hostedcluster.yaml", func() (any, error) {
+		hc, err := common.GetHostedCluster(ctx, c, backup.Spec.IncludedNamespaces, hcpNamespace)
+		if err != nil {
+			return nil, err
+		}
+		if hc == nil {
+			return nil, fmt.Errorf("no HostedCluster of %s in the backup", hcpNamespace)
+		}
+		return objectStatus{Name: hc.Namespace + "/" + hc.Name, Conditions: hc.Status.Conditions}, nil
+	})
+	b.add("hostedcontrolplanes.yaml", func() (any, error) {
+		list := &hyperv1.HostedControlPlaneList{}
+		if err := c.List(ctx, list, crclient.InNamespace(hcpNamespace)); err != nil {
+			return nil, err
+		}
+		statuses := []objectStatus{}
+		for _, hcp := range list.Items {
+			statuses = append(statuses, objectStatus{Name: hcp.Name, Conditions: hcp.Status.Conditions})
+		}
+		return statuses, nil
+	})
+	b.add("hcpetcdbackups.yaml", func() (any, error) {
+		list := &hyperv1.HCPEtcdBackupList{}
+		if err := c.List(ctx, list, crclient.InNamespace(hcpNamespace)); err != nil {
+			return nil, err
+		}
+		statuses := []objectStatus{}
+		for _, eb := range list.Items {
+			statuses = append(statuses, objectStatus{Name: eb.Name, Conditions: eb.Status.Conditions})
+		}
+		return statuses, nil
+	})
+	b.add("datauploads.yaml", func() (any, error) {
+		list := &velerov2alpha1.DataUploadList{}
+		if err := c.List(ctx, list, crclient.InNamespace(veleroNamespace), selector); err != nil {
+			return nil, err
+		}
+		statuses := []objectStatus{}
+		for _, du := range list.Items {
+			if !common.BelongsToBackup(&du, backup) {
+				continue
+			}
+			statuses = append(statuses, objectStatus{
+				Name:     du.Name,
+				Phase:    string(du.Status.Phase),
+				Message:  du.Status.Message,
+				Progress: fmt.Sprintf("%d/%d bytes", du.Status.Progress.BytesDone, du.Status.Progress.TotalBytes),
+			})
+		}
+		return statuses, nil
+	})
+	b.add("podvolumebackups.yaml", func() (any, error) {
+		list := &velerov1.PodVolumeBackupList{}
+		if err := c.List(ctx, list, crclient.InNamespace(veleroNamespace), selector); err != nil {
+			return nil, err
+		}
+		statuses := []objectStatus{}
+		for _, pvb := range list.Items {
+			if !common.BelongsToBackup(&pvb, backup) {
+				continue
+			}
+			statuses = append(statuses, objectStatus{
+				Name:     pvb.Name,
+				Phase:    string(pvb.Status.Phase),
+				Message:  pvb.Status.Message,
+				Progress: fmt.Sprintf("%d/%d bytes", pvb.Status.Progress.BytesDone, pvb.Status.Progress.TotalBytes),
+			})
+		}
+		return statuses, nil
+	})
+	b.add("volumesnapshots.yaml", func() (any, error) {
+		list := &snapshotv1.VolumeSnapshotList{}
+		if err := c.List(ctx, list, selector); err != nil {
+			return nil, err
+		}
+		statuses := []objectStatus{}
+		for _, vs := range list.Items {
+			if !common.BelongsToBackup(&vs, backup) {
+				continue
+			}
+			status := objectStatus{Name: vs.Namespace + "/" + vs.Name}
+			if vs.Status != nil {
+				status.ReadyToUse = vs.Status.ReadyToUse
+				status.Message = snapshotError(vs.Status.Error)
+			}
+			statuses = append(statuses, status)
+		}
+		return statuses, nil
+	})
+	b.add("volumesnapshotcontents.yaml", func() (any, error) {
+		list := &snapshotv1.VolumeSnapshotContentList{}
+		if err := c.List(ctx, list, selector); err != nil {
+			return nil, err
+		}
+		statuses := []objectStatus{}
+		for _, vsc := range list.Items {
+			if !common.BelongsToBackup(&vsc, backup) {
+				continue
+			}
+			status := objectStatus{Name: vsc.Name}
+			if vsc.Status != nil {
+				status.ReadyToUse = vsc.Status.ReadyToUse
+				status.Message = snapshotError(vsc.Status.Error)
+			}
+			statuses = append(statuses, status)
+		}
+		return statuses, nil
+	})
+
+	events, err := warningEvents(ctx, c, hcpNamespace)
+	if err != nil {
+		b["events.txt"] = fmt.Sprintf("error collecting events: %v", err)
+	} else {
+		b["events.txt"] = events
+	}
+
+	return b
+}
+
+// Save stores the bundle in a ConfigMap next to the Backup and owned by it, so it is deleted
+// with the Backup. The bundle of the first failure of a backup is kept, as the following
+// failures usually derive from it.
+func Save(ctx context.Context, c crclient.Client, backup *velerov1.Backup, b Bundle) (string, error) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      label.GetValidName(ConfigMapPrefix + backup.Name),
+			Namespace: backup.Namespace,
+			Labels:    map[string]string{velerov1.BackupNameLabel: label.GetValidName(backup.Name)},
+		},
+		Data: b,
+	}
+	if backup.UID != "" {
+		cm.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: velerov1.SchemeGroupVersion.String(),
+			Kind:       "Backup",
+			Name:       backup.Name,
+			UID:        backup.UID,
+		}}
+	}
+
+	if err := c.Create(ctx, cm); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("error creating diagnostics ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+	}
+	return cm.Name, nil
+}
+
+// add stores the YAML encoding of the collected value, or the collection error.
+func (b Bundle) add(key string, collect func() (any, error)) {
+	value, err := collect()
+	if err == nil {
+		var data []byte
+		if data, err = yaml.Marshal(value); err == nil {
+			b[key] = string(data)
+			return
+		}
+	}
+	b[key] = fmt.Sprintf("error collecting %s: %v", key, err)
+}
+
+// warningEvents renders the most recent warning events of the namespace, one per line.
+func warningEvents(ctx context.Context, c crclient.Client, namespace string) (string, error) {
+	list := &corev1.EventList{}
+	if err := c.List(ctx, list, crclient.InNamespace(namespace)); err != nil {
+		return "", err
+	}
+	events := []corev1.Event{}
+	for _, event := range list.Items {
+		if event.Type == corev1.EventTypeWarning {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[j].LastTimestamp.Before(&events[i].LastTimestamp)
+	})
+	if len(events) > maxEvents {
+		events = events[:maxEvents]
+	}
+
+	text := ""
+	for _, event := range events {
+		text += fmt.Sprintf("%s %s %s/%s: %s\n", event.LastTimestamp.UTC().Format("2006-01-02T15:04:05Z"),
+			event.Reason, event.InvolvedObject.Kind, event.InvolvedObject.Name, event.Message)
+	}
+	return text, nil
+}
+
+func snapshotError(err *snapshotv1.VolumeSnapshotError) string {
+	if err == nil || err.Message == nil {
+		return ""
+	}
+	return *err.Message
+}
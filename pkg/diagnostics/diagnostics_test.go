@@ -0,0 +1,114 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	velerov2alpha1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testBackup() *velerov1.Backup {
+	return &velerov1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: "openshift-adp", UID: "backup-uid"},
+		Spec:       velerov1.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-hc"}},
+	}
+}
+
+func TestCollect(t *testing.T) {
+	g := NewWithT(t)
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	objects := []runtime.Object{
+		&hyperv1.HostedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters"},
+			Status: hyperv1.HostedClusterStatus{Conditions: []metav1.Condition{
+				{Type: "Degraded", Status: metav1.ConditionTrue, Reason: "EtcdUnavailable"},
+			}},
+		},
+		&hyperv1.HostedControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: "clusters-hc"},
+			Status: hyperv1.HostedControlPlaneStatus{Conditions: []metav1.Condition{
+				{Type: "EtcdAvailable", Status: metav1.ConditionFalse, Reason: "QuorumLost"},
+			}},
+		},
+		&velerov2alpha1.DataUpload{
+			ObjectMeta: metav1.ObjectMeta{Name: "daily-abcde", Namespace: "openshift-adp", Labels: map[string]string{velerov1.BackupNameLabel: "daily"}},
+			Status:     velerov2alpha1.DataUploadStatus{Phase: velerov2alpha1.DataUploadPhaseFailed, Message: "node-agent pod restarted"},
+		},
+		&velerov2alpha1.DataUpload{
+			ObjectMeta: metav1.ObjectMeta{Name: "daily-stale", Namespace: "openshift-adp", Labels: map[string]string{
+				velerov1.BackupNameLabel: "daily", velerov1.BackupUIDLabel: "deleted-backup-uid",
+			}},
+		},
+		&velerov2alpha1.DataUpload{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-abcde", Namespace: "openshift-adp", Labels: map[string]string{velerov1.BackupNameLabel: "other"}},
+		},
+	}
+	for i := 0; i < maxEvents+5; i++ {
+		objects = append(objects, &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: fmt.Sprintf("event-%d", i), Namespace: "clusters-hc"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			Message:        fmt.Sprintf("restarting etcd-%d", i),
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "etcd-0"},
+			LastTimestamp:  metav1.NewTime(now.Add(time.Duration(i) * time.Minute)),
+		})
+	}
+	objects = append(objects, &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{Name: "normal", Namespace: "clusters-hc"},
+		Type:       corev1.EventTypeNormal,
+		Reason:     "Pulled",
+	})
+	client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithRuntimeObjects(objects...).Build()
+
+	bundle := Collect(context.TODO(), client, testBackup(), "openshift-adp", "clusters-hc", errors.New("HCPEtcdBackup failed: timed out"))
+
+	g.Expect(bundle).To(HaveKeyWithValue("error", "HCPEtcdBackup failed: timed out"))
+	g.Expect(bundle["hostedcluster.yaml"]).To(ContainSubstring("EtcdUnavailable"))
+	g.Expect(bundle["hostedcontrolplanes.yaml"]).To(ContainSubstring("QuorumLost"))
+	g.Expect(bundle["datauploads.yaml"]).To(ContainSubstring("node-agent pod restarted"))
+	g.Expect(bundle["datauploads.yaml"]).NotTo(ContainSubstring("other-abcde"))
+	g.Expect(bundle["datauploads.yaml"]).NotTo(ContainSubstring("daily-stale"))
+	g.Expect(bundle).To(HaveKey("podvolumebackups.yaml"))
+	g.Expect(bundle).To(HaveKey("volumesnapshotcontents.yaml"))
+
+	g.Expect(bundle["events.txt"]).To(HavePrefix(fmt.Sprintf("2026-01-01T10:%02d:00Z BackOff Pod/etcd-0: restarting etcd-%d", maxEvents+4, maxEvents+4)))
+	g.Expect(bundle["events.txt"]).NotTo(ContainSubstring("restarting etcd-4\n"))
+	g.Expect(bundle["events.txt"]).NotTo(ContainSubstring("Pulled"))
+
+	// A backup without HostedCluster is noted rather than failing the collection
+	bundle = Collect(context.TODO(), fake.NewClientBuilder().WithScheme(common.CustomScheme).Build(), testBackup(), "openshift-adp", "clusters-hc", errors.New("failed"))
+	g.Expect(bundle["hostedcluster.yaml"]).To(Equal("error collecting hostedcluster.yaml: no HostedCluster of clusters-hc in the backup"))
+}
+
+func TestSave(t *testing.T) {
+	g := NewWithT(t)
+	client := fake.NewClientBuilder().WithScheme(common.CustomScheme).Build()
+	backup := testBackup()
+
+	name, err := Save(context.TODO(), client, backup, Bundle{"error": "first"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(name).To(Equal("hcp-diagnostics-daily"))
+	// A later failure of the same backup keeps the first bundle
+	_, err = Save(context.TODO(), client, backup, Bundle{"error": "second"})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	cm := &corev1.ConfigMap{}
+	g.Expect(client.Get(context.TODO(), crclient.ObjectKey{Namespace: backup.Namespace, Name: name}, cm)).To(Succeed())
+	g.Expect(cm.Data).To(HaveKeyWithValue("error", "first"))
+	g.Expect(cm.Labels).To(HaveKeyWithValue(velerov1.BackupNameLabel, "daily"))
+	g.Expect(cm.OwnerReferences).To(HaveLen(1))
+	g.Expect(cm.OwnerReferences[0].UID).To(BeEquivalentTo("backup-uid"))
+}
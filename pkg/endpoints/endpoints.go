@@ -0,0 +1,156 @@
+package endpoints
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/secretcheck"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ExternalDNSHostnameAnnotation holds the DNS name HyperShift publishes a Service on,
+	// from the hostname of its service publishing strategy.
+	ExternalDNSHostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+
+	kubeAPIServerService = "kube-apiserver"
+	adminKubeconfig      = "admin-kubeconfig"
+	kubeconfigKey        = "kubeconfig"
+)
+
+// Endpoint is a LoadBalancer Service of a control plane and the address the cloud provider
+// of the management cluster assigned it.
+type Endpoint struct {
+	// Service is the namespace/name of the Service.
+	Service string
+	// Address is the hostname or IP of its load balancer, empty until one is assigned.
+	Address string
+	// Hostname is the DNS name the Service is published on, if any, which has to resolve
+	// to the address.
+	Hostname string
+}
+
+// List returns the LoadBalancer Services of the control plane namespace, sorted by name.
+func List(ctx context.Context, c crclient.Client, hcpNamespace string) ([]Endpoint, error) {
+	services := &corev1.ServiceList{}
+	if err := c.List(ctx, services, crclient.InNamespace(hcpNamespace)); err != nil {
+		return nil, fmt.Errorf("error listing the Services of namespace %s: %w", hcpNamespace, err)
+	}
+	var endpoints []Endpoint
+	for _, service := range services.Items {
+		if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		endpoint := Endpoint{
+			Service:  service.Namespace + "/" + service.Name,
+			Hostname: service.Annotations[ExternalDNSHostnameAnnotation],
+		}
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			if endpoint.Address = ingress.Hostname; endpoint.Address == "" {
+				endpoint.Address = ingress.IP
+			}
+			if endpoint.Address != "" {
+				break
+			}
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	slices.SortFunc(endpoints, func(a, b Endpoint) int { return strings.Compare(a.Service, b.Service) })
+	return endpoints, nil
+}
+
+// Wait waits, within the LoadBalancers timeout, for the cloud provider to assign an address
+// to every LoadBalancer Service of the control plane namespace. Restored Services lose the
+// load balancer of the source cluster, so clients of the hosted cluster only learn the new
+// endpoints once they are assigned. It returns the endpoints as last seen, with the pending
+// ones left without address when the timeout expires.
+func Wait(ctx context.Context, c crclient.Client, hcpNamespace string, timeouts common.Timeouts) ([]Endpoint, error) {
+	timeouts = timeouts.WithDefaults()
+	var endpoints []Endpoint
+	err := common.Poll(ctx, timeouts.Clock, timeouts.LoadBalancersPoll, timeouts.LoadBalancers, true, func(ctx context.Context) (bool, error) {
+		var err error
+		if endpoints, err = List(ctx, c, hcpNamespace); err != nil {
+			return false, err
+		}
+		return !slices.ContainsFunc(endpoints, func(e Endpoint) bool { return e.Address == "" }), nil
+	})
+	if err != nil && endpoints == nil {
+		return nil, common.WrapWaitError(err, "the load balancers of namespace "+hcpNamespace, timeouts.LoadBalancers)
+	}
+	return endpoints, nil
+}
+
+// Verify waits for the load balancers of the restored HostedCluster and returns a result
+// per endpoint, failed for those left without address. The admin kubeconfig of the
+// control plane is checked to point at the new kube-apiserver endpoint, which the control
+// plane operator rewrites once it reconciles the restored cluster.
+func Verify(ctx context.Context, c crclient.Client, hc *hyperv1.HostedCluster, timeouts common.Timeouts) ([]secretcheck.Result, error) {
+	hcpNamespace := common.GetHCPNamespace(hc.Name, hc.Namespace)
+	endpoints, err := Wait(ctx, c, hcpNamespace, timeouts)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []secretcheck.Result
+	for _, endpoint := range endpoints {
+		result := secretcheck.Result{Check: "endpoint " + endpoint.Service, Passed: endpoint.Address != ""}
+		switch {
+		case !result.Passed:
+			result.Message = fmt.Sprintf("no load balancer address assigned within %s", timeouts.WithDefaults().LoadBalancers)
+		case endpoint.Hostname != "":
+			result.Message = fmt.Sprintf("%s, the DNS name %s must resolve to it", endpoint.Address, endpoint.Hostname)
+		default:
+			result.Message = endpoint.Address
+		}
+		results = append(results, result)
+
+		if endpoint.Service == hcpNamespace+"/"+kubeAPIServerService && result.Passed {
+			results = append(results, checkKubeconfigServer(ctx, c, hcpNamespace, clientHost(endpoint)))
+		}
+	}
+	return results, nil
+}
+
+// clientHost is the host clients reach the endpoint on: its DNS name when published on one.
+func clientHost(endpoint Endpoint) string {
+	if endpoint.Hostname != "" {
+		return endpoint.Hostname
+	}
+	return endpoint.Address
+}
+
+// checkKubeconfigServer checks the server of the admin kubeconfig is the host.
+func checkKubeconfigServer(ctx context.Context, c crclient.Client, hcpNamespace, host string) secretcheck.Result {
+	result := secretcheck.Result{Check: fmt.Sprintf("kubeconfig server %s/%s", hcpNamespace, adminKubeconfig)}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, crclient.ObjectKey{Namespace: hcpNamespace, Name: adminKubeconfig}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			result.Message = "not found"
+		} else {
+			result.Message = err.Error()
+		}
+		return result
+	}
+	config, err := clientcmd.Load(secret.Data[kubeconfigKey])
+	if err != nil {
+		result.Message = fmt.Sprintf("invalid kubeconfig: %v", err)
+		return result
+	}
+	for _, cluster := range config.Clusters {
+		server, err := url.Parse(cluster.Server)
+		if err != nil || server.Hostname() != host {
+			result.Message = fmt.Sprintf("server %s does not point at %s yet", cluster.Server, host)
+			return result
+		}
+	}
+	result.Passed, result.Message = true, host
+	return result
+}
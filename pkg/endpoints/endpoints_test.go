@@ -0,0 +1,108 @@
+package endpoints
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/common/clocktest"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newService(name string, serviceType corev1.ServiceType, annotations map[string]string, ingress ...corev1.LoadBalancerIngress) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "clusters-my-hc", Annotations: annotations},
+		Spec:       corev1.ServiceSpec{Type: serviceType},
+		Status:     corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: ingress}},
+	}
+}
+
+func newAdminKubeconfig(t *testing.T, server string) *corev1.Secret {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["cluster"] = &clientcmdapi.Cluster{Server: server}
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "admin-kubeconfig", Namespace: "clusters-my-hc"},
+		Data:       map[string][]byte{"kubeconfig": data},
+	}
+}
+
+func TestVerify(t *testing.T) {
+	hc := &hyperv1.HostedCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-hc", Namespace: "clusters"}}
+	timeouts := common.Timeouts{Clock: clocktest.New(t, common.DefaultTimeouts.LoadBalancersPoll)}
+
+	tests := []struct {
+		name    string
+		objects []crclient.Object
+		results []string
+	}{
+		{
+			name:    "When the control plane is published without load balancers, It Should report nothing",
+			objects: []crclient.Object{newService("openshift-apiserver", corev1.ServiceTypeClusterIP, nil)},
+		},
+		{
+			name: "When the kube-apiserver load balancer got an address, It Should report it and check the kubeconfig points at it",
+			objects: []crclient.Object{
+				newService("kube-apiserver", corev1.ServiceTypeLoadBalancer, nil, corev1.LoadBalancerIngress{Hostname: "a1b2.elb.amazonaws.com"}),
+				newAdminKubeconfig(t, "https://a1b2.elb.amazonaws.com:6443"),
+			},
+			results: []string{
+				"PASS endpoint clusters-my-hc/kube-apiserver: a1b2.elb.amazonaws.com",
+				"PASS kubeconfig server clusters-my-hc/admin-kubeconfig: a1b2.elb.amazonaws.com",
+			},
+		},
+		{
+			name: "When the kubeconfig still points at the source load balancer, It Should fail its check",
+			objects: []crclient.Object{
+				newService("kube-apiserver", corev1.ServiceTypeLoadBalancer, nil, corev1.LoadBalancerIngress{IP: "203.0.113.10"}),
+				newAdminKubeconfig(t, "https://c3d4.elb.amazonaws.com:6443"),
+			},
+			results: []string{
+				"PASS endpoint clusters-my-hc/kube-apiserver: 203.0.113.10",
+				"FAIL kubeconfig server clusters-my-hc/admin-kubeconfig: server https://c3d4.elb.amazonaws.com:6443 does not point at 203.0.113.10 yet",
+			},
+		},
+		{
+			name: "When a load balancer is published on a DNS name, It Should name the record to update",
+			objects: []crclient.Object{
+				newService("kube-apiserver", corev1.ServiceTypeLoadBalancer, map[string]string{ExternalDNSHostnameAnnotation: "api.my-hc.example.com"},
+					corev1.LoadBalancerIngress{Hostname: "a1b2.elb.amazonaws.com"}),
+				newAdminKubeconfig(t, "https://api.my-hc.example.com:443"),
+			},
+			results: []string{
+				"PASS endpoint clusters-my-hc/kube-apiserver: a1b2.elb.amazonaws.com, the DNS name api.my-hc.example.com must resolve to it",
+				"PASS kubeconfig server clusters-my-hc/admin-kubeconfig: api.my-hc.example.com",
+			},
+		},
+		{
+			name:    "When a load balancer gets no address in time, It Should fail its check",
+			objects: []crclient.Object{newService("private-router", corev1.ServiceTypeLoadBalancer, nil)},
+			results: []string{"FAIL endpoint clusters-my-hc/private-router: no load balancer address assigned within 10m0s"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(tt.objects...).Build()
+
+			results, err := Verify(context.TODO(), c, hc, timeouts)
+			g.Expect(err).NotTo(HaveOccurred())
+			lines := []string{}
+			for _, result := range results {
+				lines = append(lines, result.String())
+			}
+			g.Expect(lines).To(HaveExactElements(tt.results))
+		})
+	}
+}
@@ -3,28 +3,26 @@ package etcdbackup
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/tracing"
 	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
 	"github.com/sirupsen/logrus"
 	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/label"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	utilrand "k8s.io/apimachinery/pkg/util/rand"
-	"k8s.io/apimachinery/pkg/util/wait"
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
-	verifyTimeout     = 30 * time.Second
-	completionTimeout = 10 * time.Minute
-	pollInterval      = 5 * time.Second
-
 	// maxLabelValueLen is the maximum length of a Kubernetes label value (RFC 1123).
 	// The HyperShift hcpetcdbackup controller sets the CR name as a label value on
 	// the Job it creates, so the CR name must stay within this limit.
@@ -42,8 +40,13 @@ type Orchestrator struct {
 	HONamespace     string
 	OADPNamespace   string
 	CredSecretName  string
+
+	// Timeouts overrides the waits for the HCPEtcdBackup, the defaults when unset.
+	Timeouts common.Timeouts
 }
 
+var _ common.SnapshotWaiter = (*Orchestrator)(nil)
+
 // NewOrchestrator creates a new Orchestrator.
 func NewOrchestrator(log logrus.FieldLogger, client crclient.Client, hoNamespace, oadpNamespace string) *Orchestrator {
 	return &Orchestrator{
@@ -96,6 +99,8 @@ func (o *Orchestrator) CreateEtcdBackup(ctx context.Context, backup *velerov1.Ba
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      crName,
 			Namespace: hcpNamespace,
+			// Lets Resume find the CR from another plugin process
+			Labels: common.BackupLabels(backup),
 		},
 		Spec: hyperv1.HCPEtcdBackupSpec{
 			Storage: *storage,
@@ -117,9 +122,40 @@ func (o *Orchestrator) CreateEtcdBackup(ctx context.Context, backup *velerov1.Ba
 	return nil
 }
 
+// Resume recovers the HCPEtcdBackup created for the Velero backup by an earlier plugin
+// process, e.g. one restarted mid-backup, so the wait resumes instead of taking another
+// snapshot. It reports whether one was found. HCPEtcdBackups left by a deleted Backup of
+// the same name are not resumed.
+func (o *Orchestrator) Resume(ctx context.Context, backup *velerov1.Backup, hcpNamespace string) (bool, error) {
+	list := &hyperv1.HCPEtcdBackupList{}
+	if err := o.client.List(ctx, list, crclient.InNamespace(hcpNamespace),
+		crclient.MatchingLabels{velerov1.BackupNameLabel: label.GetValidName(backup.Name)}); err != nil {
+		return false, fmt.Errorf("failed to list HCPEtcdBackups: %w", err)
+	}
+	i := slices.IndexFunc(list.Items, func(eb hyperv1.HCPEtcdBackup) bool {
+		if !common.BelongsToBackup(&eb, backup) {
+			o.log.Infof("Ignoring HCPEtcdBackup %s/%s of an earlier backup named %s", hcpNamespace, eb.Name, backup.Name)
+			return false
+		}
+		return true
+	})
+	if i < 0 {
+		return false, nil
+	}
+
+	o.BackupName = list.Items[i].Name
+	o.BackupNamespace = hcpNamespace
+	o.CredSecretName = credentialSecretName(backup.Name)
+	o.log.Infof("Resuming the wait for HCPEtcdBackup %s/%s created by an earlier plugin process", hcpNamespace, o.BackupName)
+	return true, nil
+}
+
 // VerifyInProgress polls the HCPEtcdBackup until the controller acknowledges it.
-func (o *Orchestrator) VerifyInProgress(ctx context.Context) error {
-	return o.pollCondition(ctx, verifyTimeout, func(cond *metav1.Condition) (bool, error) {
+func (o *Orchestrator) VerifyInProgress(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "etcdbackup.VerifyInProgress")
+	defer func() { tracing.End(span, err) }()
+
+	return o.pollCondition(ctx, o.Timeouts.WithDefaults().EtcdBackupVerify, func(cond *metav1.Condition) (bool, error) {
 		if cond == nil {
 			return false, nil // no condition yet, keep polling
 		}
@@ -143,10 +179,13 @@ func (o *Orchestrator) VerifyInProgress(ctx context.Context) error {
 
 // WaitForCompletion polls the HCPEtcdBackup until it reaches a terminal state.
 // Returns the snapshotURL on success.
-func (o *Orchestrator) WaitForCompletion(ctx context.Context) (string, error) {
+func (o *Orchestrator) WaitForCompletion(ctx context.Context) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "etcdbackup.WaitForCompletion")
+	defer func() { tracing.End(span, err) }()
+
 	var snapshotURL string
 
-	err := o.pollCondition(ctx, completionTimeout, func(cond *metav1.Condition) (bool, error) {
+	err = o.pollCondition(ctx, o.Timeouts.WithDefaults().EtcdBackupCompletion, func(cond *metav1.Condition) (bool, error) {
 		if cond == nil {
 			return false, nil
 		}
@@ -276,7 +315,7 @@ func mapAzureBSLToStorage(bsl *velerov1.BackupStorageLocation, keyPrefix string)
 // If the destination Secret already exists, it is reused. The credential data
 // contains an STS IAM Role ARN (not rotatable keys), so it is safe to reuse.
 func (o *Orchestrator) copyCredentialSecret(ctx context.Context, credRef *corev1.SecretKeySelector, fromNS, toNS, backupName string) (string, error) {
-	dstName := fmt.Sprintf("etcd-backup-creds-%s", backupName)
+	dstName := credentialSecretName(backupName)
 
 	// Check if the destination Secret already exists
 	if err := o.client.Get(ctx, types.NamespacedName{Name: dstName, Namespace: toNS}, &corev1.Secret{}); err == nil {
@@ -326,6 +365,11 @@ func (o *Orchestrator) copyCredentialSecret(ctx context.Context, credRef *corev1
 	return dstName, nil
 }
 
+// credentialSecretName names the credential Secret copied for the Velero backup.
+func credentialSecretName(backupName string) string {
+	return fmt.Sprintf("etcd-backup-creds-%s", backupName)
+}
+
 // setCredentialRef sets the credential reference on the storage config.
 func setCredentialRef(storage *hyperv1.HCPEtcdBackupStorage, secretName string) {
 	ref := hyperv1.SecretReference{Name: secretName}
@@ -374,7 +418,8 @@ func safeResourceName(prefix, name string, randLen int) string {
 // returns true (done) or an error (terminal failure), or until timeout.
 // The first check runs immediately (before the first interval wait).
 func (o *Orchestrator) pollCondition(ctx context.Context, timeout time.Duration, check func(*metav1.Condition) (bool, error)) error {
-	return wait.PollUntilContextTimeout(ctx, pollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+	timeouts := o.Timeouts.WithDefaults()
+	err := common.Poll(ctx, timeouts.Clock, timeouts.EtcdBackupPoll, timeout, true, func(ctx context.Context) (bool, error) {
 		eb := &hyperv1.HCPEtcdBackup{}
 		if err := o.client.Get(ctx, types.NamespacedName{Name: o.BackupName, Namespace: o.BackupNamespace}, eb); err != nil {
 			if apierrors.IsNotFound(err) {
@@ -386,5 +431,6 @@ func (o *Orchestrator) pollCondition(ctx context.Context, timeout time.Duration,
 		cond := meta.FindStatusCondition(eb.Status.Conditions, string(hyperv1.BackupCompleted))
 		return check(cond)
 	})
+	return common.WrapWaitError(err, fmt.Sprintf("HCPEtcdBackup %s/%s", o.BackupNamespace, o.BackupName), timeout)
 }
 
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	. "github.com/onsi/gomega"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/common/clocktest"
 	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
 	"github.com/sirupsen/logrus"
 	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
@@ -630,6 +632,93 @@ func TestIsCreated(t *testing.T) {
 	}
 }
 
+func TestResume(t *testing.T) {
+	backup := &velerov1.Backup{ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: "openshift-adp"}}
+
+	tests := []struct {
+		name        string
+		objects     []crclient.Object
+		wantResumed bool
+	}{
+		{
+			name: "When no HCPEtcdBackup exists for the backup, It Should not resume",
+			objects: []crclient.Object{
+				&hyperv1.HCPEtcdBackup{ObjectMeta: metav1.ObjectMeta{
+					Name: "oadp-other-abcd", Namespace: "clusters-hc",
+					Labels: map[string]string{velerov1.BackupNameLabel: "other"},
+				}},
+			},
+		},
+		{
+			name: "When an earlier process created the HCPEtcdBackup, It Should resume its wait",
+			objects: []crclient.Object{
+				&hyperv1.HCPEtcdBackup{ObjectMeta: metav1.ObjectMeta{
+					Name: "oadp-daily-abcd", Namespace: "clusters-hc",
+					Labels: map[string]string{velerov1.BackupNameLabel: "daily"},
+				}},
+			},
+			wantResumed: true,
+		},
+		{
+			name: "When the HCPEtcdBackup belongs to a deleted backup of the same name, It Should not resume",
+			objects: []crclient.Object{
+				&hyperv1.HCPEtcdBackup{ObjectMeta: metav1.ObjectMeta{
+					Name: "oadp-daily-wxyz", Namespace: "clusters-hc",
+					Labels: map[string]string{velerov1.BackupNameLabel: "daily", velerov1.BackupUIDLabel: "deleted-backup-uid"},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			o := NewOrchestrator(logrus.New(), testClient(testScheme(), tt.objects...), "hypershift", "openshift-adp")
+
+			resumed, err := o.Resume(context.TODO(), backup, "clusters-hc")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(resumed).To(Equal(tt.wantResumed))
+			g.Expect(o.IsCreated()).To(Equal(tt.wantResumed))
+			if tt.wantResumed {
+				g.Expect(o.BackupName).To(Equal("oadp-daily-abcd"))
+				g.Expect(o.BackupNamespace).To(Equal("clusters-hc"))
+				g.Expect(o.CredSecretName).To(Equal("etcd-backup-creds-daily"))
+			}
+		})
+	}
+
+	t.Run("When another orchestrator created the HCPEtcdBackup of a long backup name, It Should resume it", func(t *testing.T) {
+		g := NewWithT(t)
+		longBackup := &velerov1.Backup{
+			ObjectMeta: metav1.ObjectMeta{Name: "2q8dk2uepjpfcvm4lqg92rlpjshj219a-hourly-20260512140053", Namespace: "openshift-adp"},
+			Spec:       velerov1.BackupSpec{StorageLocation: "default"},
+		}
+		client := testClient(testScheme(),
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "cloud-credentials", Namespace: "openshift-adp"},
+				Data:       map[string][]byte{"cloud": []byte("aws-creds")},
+			},
+			&velerov1.BackupStorageLocation{
+				ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "openshift-adp"},
+				Spec: velerov1.BackupStorageLocationSpec{
+					Provider:    "aws",
+					StorageType: velerov1.StorageType{ObjectStorage: &velerov1.ObjectStorageLocation{Bucket: "my-bucket"}},
+					Config:      map[string]string{"region": "us-east-1"},
+				},
+			},
+		)
+		first := NewOrchestrator(logrus.New(), client, "hypershift", "openshift-adp")
+		g.Expect(first.CreateEtcdBackup(context.TODO(), longBackup, "clusters-hc", nil)).To(Succeed())
+
+		second := NewOrchestrator(logrus.New(), client, "hypershift", "openshift-adp")
+		resumed, err := second.Resume(context.TODO(), longBackup, "clusters-hc")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(resumed).To(BeTrue())
+		g.Expect(second.BackupName).To(Equal(first.BackupName))
+		g.Expect(second.CredSecretName).To(Equal(first.CredSecretName))
+	})
+}
+
 func TestFetchBSL(t *testing.T) {
 	scheme := testScheme()
 
@@ -934,6 +1023,16 @@ func TestPollCondition(t *testing.T) {
 			wantErr:   true,
 			errSubstr: "backup failed",
 		},
+		{
+			name:   "When the condition is never satisfied, It Should time out",
+			reason: hyperv1.BackupFailedReason,
+			status: metav1.ConditionFalse,
+			check: func(cond *metav1.Condition) (bool, error) {
+				return false, nil
+			},
+			wantErr:   true,
+			errSubstr: "timed out after 10m0s waiting for HCPEtcdBackup clusters-test/test-eb",
+		},
 	}
 
 	for _, tt := range tests {
@@ -956,9 +1055,10 @@ func TestPollCondition(t *testing.T) {
 				client:          client,
 				BackupName:      "test-eb",
 				BackupNamespace: "clusters-test",
+				Timeouts:        common.Timeouts{Clock: clocktest.New(t, common.DefaultTimeouts.EtcdBackupPoll)},
 			}
 
-			err := o.pollCondition(context.TODO(), 5*time.Second, tt.check)
+			err := o.pollCondition(context.TODO(), 10*time.Minute, tt.check)
 			if tt.wantErr {
 				g.Expect(err).To(HaveOccurred())
 				g.Expect(err.Error()).To(ContainSubstring(tt.errSubstr))
@@ -0,0 +1,232 @@
+// Package guestsnapshot captures a reference view of the hosted cluster itself at backup
+// time: its Nodes, pending CSRs and ClusterOperators. It is not restored from, only compared
+// with the restored cluster to verify a disaster recovery.
+package guestsnapshot
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/label"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ConfigMapName is the ConfigMap of the control plane namespace holding the snapshot. It
+	// is added to the backup of the HostedControlPlane.
+	ConfigMapName = "hcp-guest-snapshot"
+
+	// kubeconfigSecret is the admin kubeconfig of the hosted cluster for clients on the
+	// management cluster, with the KAS service as server.
+	kubeconfigSecret = "service-network-admin-kubeconfig"
+	kubeconfigKey    = "kubeconfig"
+
+	// requestTimeout bounds every request to the hosted cluster, which may be unreachable
+	requestTimeout = 30 * time.Second
+)
+
+var guestScheme = runtime.NewScheme()
+
+func init() {
+	for _, add := range []func(*runtime.Scheme) error{corev1.AddToScheme, certificatesv1.AddToScheme, configv1.AddToScheme} {
+		if err := add(guestScheme); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Snapshot maps the name of a snapshot entry to its YAML content.
+type Snapshot map[string]string
+
+// nodeStatus is the part of a Node kept in a snapshot.
+type nodeStatus struct {
+	Name           string `json:"name"`
+	Ready          string `json:"ready"`
+	ProviderID     string `json:"providerID,omitempty"`
+	KubeletVersion string `json:"kubeletVersion"`
+	Unschedulable  bool   `json:"unschedulable,omitempty"`
+}
+
+// csrStatus is the part of a pending CertificateSigningRequest kept in a snapshot.
+type csrStatus struct {
+	Name       string      `json:"name"`
+	SignerName string      `json:"signerName"`
+	Username   string      `json:"username"`
+	Created    metav1.Time `json:"created"`
+}
+
+// clusterOperatorStatus is the part of a ClusterOperator kept in a snapshot.
+type clusterOperatorStatus struct {
+	Name        string `json:"name"`
+	Available   string `json:"available"`
+	Progressing string `json:"progressing"`
+	Degraded    string `json:"degraded"`
+	Version     string `json:"version,omitempty"`
+}
+
+// NewGuestClient returns a client of the hosted cluster of the control plane namespace,
+// built from its service network admin kubeconfig. The kubeconfig server is the KAS
+// service of the namespace, which is qualified so it resolves from the Velero namespace.
+func NewGuestClient(ctx context.Context, c crclient.Client, hcpNamespace string) (crclient.Client, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, crclient.ObjectKey{Namespace: hcpNamespace, Name: kubeconfigSecret}, secret); err != nil {
+		return nil, fmt.Errorf("error getting admin kubeconfig %s/%s: %w", hcpNamespace, kubeconfigSecret, err)
+	}
+	config, err := restConfig(secret.Data[kubeconfigKey], hcpNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("error reading admin kubeconfig %s/%s: %w", hcpNamespace, kubeconfigSecret, err)
+	}
+	return crclient.New(config, crclient.Options{Scheme: guestScheme})
+}
+
+// restConfig returns the REST config of the kubeconfig, with an unqualified server host
+// qualified with the namespace.
+func restConfig(kubeconfig []byte, namespace string) (*rest.Config, error) {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	server, err := url.Parse(config.Host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server %q: %w", config.Host, err)
+	}
+	if host, port, err := net.SplitHostPort(server.Host); err == nil && net.ParseIP(host) == nil && !strings.Contains(host, ".") {
+		// The serving certificate of the KAS is valid for its service names
+		server.Host = net.JoinHostPort(host+"."+namespace+".svc", port)
+		config.Host = server.String()
+	}
+	config.Timeout = requestTimeout
+	return config, nil
+}
+
+// Collect captures the Nodes, the pending CertificateSigningRequests and the
+// ClusterOperators of the hosted cluster. Sources that cannot be read are noted in the
+// snapshot rather than failing the collection.
+func Collect(ctx context.Context, guest crclient.Client) Snapshot {
+	s := Snapshot{"collected": time.Now().UTC().Format(time.RFC3339)}
+
+	s.add("nodes.yaml", func() (any, error) {
+		list := &corev1.NodeList{}
+		if err := guest.List(ctx, list); err != nil {
+			return nil, err
+		}
+		nodes := []nodeStatus{}
+		for _, node := range list.Items {
+			status := nodeStatus{
+				Name:           node.Name,
+				Ready:          string(corev1.ConditionUnknown),
+				ProviderID:     node.Spec.ProviderID,
+				KubeletVersion: node.Status.NodeInfo.KubeletVersion,
+				Unschedulable:  node.Spec.Unschedulable,
+			}
+			for _, condition := range node.Status.Conditions {
+				if condition.Type == corev1.NodeReady {
+					status.Ready = string(condition.Status)
+				}
+			}
+			nodes = append(nodes, status)
+		}
+		return nodes, nil
+	})
+	s.add("pending-csrs.yaml", func() (any, error) {
+		list := &certificatesv1.CertificateSigningRequestList{}
+		if err := guest.List(ctx, list); err != nil {
+			return nil, err
+		}
+		csrs := []csrStatus{}
+		for _, csr := range list.Items {
+			if len(csr.Status.Conditions) > 0 {
+				continue // approved or denied
+			}
+			csrs = append(csrs, csrStatus{Name: csr.Name, SignerName: csr.Spec.SignerName, Username: csr.Spec.Username, Created: csr.CreationTimestamp})
+		}
+		return csrs, nil
+	})
+	s.add("clusteroperators.yaml", func() (any, error) {
+		list := &configv1.ClusterOperatorList{}
+		if err := guest.List(ctx, list); err != nil {
+			return nil, err
+		}
+		operators := []clusterOperatorStatus{}
+		for _, co := range list.Items {
+			status := clusterOperatorStatus{
+				Name:        co.Name,
+				Available:   operatorCondition(co, configv1.OperatorAvailable),
+				Progressing: operatorCondition(co, configv1.OperatorProgressing),
+				Degraded:    operatorCondition(co, configv1.OperatorDegraded),
+			}
+			for _, version := range co.Status.Versions {
+				if version.Name == "operator" {
+					status.Version = version.Version
+				}
+			}
+			operators = append(operators, status)
+		}
+		return operators, nil
+	})
+	return s
+}
+
+// Save stores the snapshot in the ConfigMapName ConfigMap of the control plane namespace,
+// labeled with the backup, replacing the snapshot of an earlier backup.
+func Save(ctx context.Context, c crclient.Client, hcpNamespace string, backup *velerov1.Backup, s Snapshot) (*corev1.ConfigMap, error) {
+	cm := &corev1.ConfigMap{}
+	err := c.Get(ctx, crclient.ObjectKey{Namespace: hcpNamespace, Name: ConfigMapName}, cm)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("error getting guest snapshot ConfigMap %s/%s: %w", hcpNamespace, ConfigMapName, err)
+	}
+	exists := err == nil
+
+	cm.Name, cm.Namespace = ConfigMapName, hcpNamespace
+	if cm.Labels == nil {
+		cm.Labels = map[string]string{}
+	}
+	cm.Labels[velerov1.BackupNameLabel] = label.GetValidName(backup.Name)
+	cm.Data = s
+
+	if exists {
+		err = c.Update(ctx, cm)
+	} else {
+		err = c.Create(ctx, cm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error saving guest snapshot ConfigMap %s/%s: %w", hcpNamespace, ConfigMapName, err)
+	}
+	return cm, nil
+}
+
+// add stores the YAML encoding of the collected value, or the collection error.
+func (s Snapshot) add(key string, collect func() (any, error)) {
+	value, err := collect()
+	if err == nil {
+		var data []byte
+		if data, err = yaml.Marshal(value); err == nil {
+			s[key] = string(data)
+			return
+		}
+	}
+	s[key] = fmt.Sprintf("error collecting %s: %v", key, err)
+}
+
+func operatorCondition(co configv1.ClusterOperator, conditionType configv1.ClusterStatusConditionType) string {
+	for _, condition := range co.Status.Conditions {
+		if condition.Type == conditionType {
+			return string(condition.Status)
+		}
+	}
+	return string(configv1.ConditionUnknown)
+}
@@ -0,0 +1,143 @@
+package guestsnapshot
+
+// Test scenario names follow: "When <action or context>, It Should <expected outcome>".
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCollect(t *testing.T) {
+	g := NewWithT(t)
+	guest := fake.NewClientBuilder().WithScheme(guestScheme).WithObjects(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-0"},
+			Spec:       corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-0123"},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+				NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: "v1.31.1"},
+			},
+		},
+		&certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: "csr-pending"},
+			Spec:       certificatesv1.CertificateSigningRequestSpec{SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName, Username: "system:node:worker-1"},
+		},
+		&certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: "csr-approved"},
+			Status: certificatesv1.CertificateSigningRequestStatus{Conditions: []certificatesv1.CertificateSigningRequestCondition{
+				{Type: certificatesv1.CertificateApproved, Status: corev1.ConditionTrue},
+			}},
+		},
+		&configv1.ClusterOperator{
+			ObjectMeta: metav1.ObjectMeta{Name: "ingress"},
+			Status: configv1.ClusterOperatorStatus{
+				Conditions: []configv1.ClusterOperatorStatusCondition{
+					{Type: configv1.OperatorAvailable, Status: configv1.ConditionTrue},
+					{Type: configv1.OperatorDegraded, Status: configv1.ConditionFalse},
+				},
+				Versions: []configv1.OperandVersion{{Name: "operator", Version: "4.18.3"}},
+			},
+		},
+	).Build()
+
+	s := Collect(context.TODO(), guest)
+	g.Expect(s).To(HaveKey("collected"))
+	g.Expect(s["nodes.yaml"]).To(ContainSubstring("name: worker-0"))
+	g.Expect(s["nodes.yaml"]).To(ContainSubstring(`ready: "True"`))
+	g.Expect(s["nodes.yaml"]).To(ContainSubstring("providerID: aws:///us-east-1a/i-0123"))
+	// Only the CSRs neither approved nor denied are kept
+	g.Expect(s["pending-csrs.yaml"]).To(ContainSubstring("name: csr-pending"))
+	g.Expect(s["pending-csrs.yaml"]).NotTo(ContainSubstring("csr-approved"))
+	g.Expect(s["clusteroperators.yaml"]).To(ContainSubstring(`available: "True"`))
+	g.Expect(s["clusteroperators.yaml"]).To(ContainSubstring(`progressing: Unknown`))
+	g.Expect(s["clusteroperators.yaml"]).To(ContainSubstring("version: 4.18.3"))
+
+	// A source the client cannot read is noted instead of failing the snapshot
+	s = Collect(context.TODO(), fake.NewClientBuilder().WithScheme(common.CustomScheme).Build())
+	g.Expect(s["nodes.yaml"]).To(Equal("[]\n"))
+	g.Expect(s["pending-csrs.yaml"]).To(HavePrefix("error collecting pending-csrs.yaml"))
+}
+
+func TestRestConfig(t *testing.T) {
+	kubeconfig := func(server string) []byte {
+		return []byte(`apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: ` + server + `
+    insecure-skip-tls-verify: true
+  name: cluster
+contexts:
+- context:
+    cluster: cluster
+    user: admin
+  name: admin
+current-context: admin
+users:
+- name: admin
+  user:
+    token: secret
+`)
+	}
+
+	tests := []struct {
+		name     string
+		server   string
+		wantHost string
+	}{
+		{
+			name:     "When the server is the KAS service name, It Should qualify it with the namespace",
+			server:   "https://kube-apiserver:6443",
+			wantHost: "https://kube-apiserver.clusters-test.svc:6443",
+		},
+		{
+			name:     "When the server is already qualified, It Should keep it",
+			server:   "https://api.test.example.com:6443",
+			wantHost: "https://api.test.example.com:6443",
+		},
+		{
+			name:     "When the server is an IP address, It Should keep it",
+			server:   "https://172.30.0.10:6443",
+			wantHost: "https://172.30.0.10:6443",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			config, err := restConfig(kubeconfig(tt.server), "clusters-test")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(config.Host).To(Equal(tt.wantHost))
+			g.Expect(config.Timeout).To(Equal(requestTimeout))
+		})
+	}
+}
+
+func TestSave(t *testing.T) {
+	g := NewWithT(t)
+	c := fake.NewClientBuilder().WithScheme(common.CustomScheme).Build()
+	ctx := context.TODO()
+
+	_, err := Save(ctx, c, "clusters-test", &velerov1.Backup{ObjectMeta: metav1.ObjectMeta{Name: "first"}}, Snapshot{"nodes.yaml": "[]\n"})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// A later backup replaces the snapshot of the earlier one
+	cm, err := Save(ctx, c, "clusters-test", &velerov1.Backup{ObjectMeta: metav1.ObjectMeta{Name: "second"}}, Snapshot{"collected": "now"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cm.Name).To(Equal(ConfigMapName))
+
+	got := &corev1.ConfigMap{}
+	g.Expect(c.Get(ctx, crclient.ObjectKey{Namespace: "clusters-test", Name: ConfigMapName}, got)).To(Succeed())
+	g.Expect(got.Labels).To(HaveKeyWithValue(velerov1.BackupNameLabel, "second"))
+	g.Expect(got.Data).To(Equal(map[string]string{"collected": "now"}))
+}
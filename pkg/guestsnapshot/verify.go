@@ -0,0 +1,117 @@
+package guestsnapshot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openshift/hypershift-oadp-plugin/pkg/secretcheck"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Load returns the snapshot restored in the ConfigMapName ConfigMap of the control plane
+// namespace, or nil when the backup took none.
+func Load(ctx context.Context, c crclient.Client, hcpNamespace string) (Snapshot, error) {
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, crclient.ObjectKey{Namespace: hcpNamespace, Name: ConfigMapName}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting guest snapshot ConfigMap %s/%s: %w", hcpNamespace, ConfigMapName, err)
+	}
+	return Snapshot(cm.Data), nil
+}
+
+// Verify compares the restored hosted cluster, through the guest client, with the snapshot
+// taken at backup time, so a restore is judged on the workloads of the cluster and not only
+// on its restored objects. At least as many Nodes as then must be Ready and schedulable,
+// though not the same ones since Machines may have been replaced, and every ClusterOperator
+// Available then must be Available and not Degraded again.
+func Verify(ctx context.Context, saved Snapshot, guest crclient.Client) []secretcheck.Result {
+	restored := Collect(ctx, guest)
+	results := []secretcheck.Result{verifyNodes(saved, restored)}
+	return append(results, verifyClusterOperators(saved, restored)...)
+}
+
+func verifyNodes(saved, restored Snapshot) secretcheck.Result {
+	result := secretcheck.Result{Check: "guest Nodes"}
+	before, err := decode[nodeStatus](saved, "nodes.yaml")
+	if err != nil {
+		result.Passed, result.Message = true, "not compared, "+err.Error()
+		return result
+	}
+	after, err := decode[nodeStatus](restored, "nodes.yaml")
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	want, got := schedulable(before), schedulable(after)
+	result.Passed = got >= want
+	result.Message = fmt.Sprintf("%d of %d Ready and schedulable, %d at backup time", got, len(after), want)
+	return result
+}
+
+func verifyClusterOperators(saved, restored Snapshot) []secretcheck.Result {
+	before, err := decode[clusterOperatorStatus](saved, "clusteroperators.yaml")
+	if err != nil {
+		return []secretcheck.Result{{Check: "guest ClusterOperators", Passed: true, Message: "not compared, " + err.Error()}}
+	}
+	after, err := decode[clusterOperatorStatus](restored, "clusteroperators.yaml")
+	if err != nil {
+		return []secretcheck.Result{{Check: "guest ClusterOperators", Message: err.Error()}}
+	}
+	current := map[string]clusterOperatorStatus{}
+	for _, co := range after {
+		current[co.Name] = co
+	}
+
+	results := []secretcheck.Result{}
+	for _, co := range before {
+		if co.Available != "True" {
+			continue // not expected back either
+		}
+		result := secretcheck.Result{Check: "guest ClusterOperator " + co.Name}
+		now, ok := current[co.Name]
+		switch {
+		case !ok:
+			result.Message = "not found"
+		case now.Available != "True" || now.Degraded == "True":
+			result.Message = fmt.Sprintf("Available=%s Degraded=%s, Available at backup time", now.Available, now.Degraded)
+		default:
+			result.Passed = true
+			result.Message = now.Version
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// decode returns the entries of the snapshot key, or the error noted in their place when
+// they could not be collected.
+func decode[T any](s Snapshot, key string) ([]T, error) {
+	data, ok := s[key]
+	if !ok {
+		return nil, fmt.Errorf("no %s in the guest snapshot", key)
+	}
+	if strings.HasPrefix(data, "error collecting") {
+		return nil, fmt.Errorf("%s", data)
+	}
+	var entries []T
+	if err := yaml.Unmarshal([]byte(data), &entries); err != nil {
+		return nil, fmt.Errorf("error reading %s of the guest snapshot: %w", key, err)
+	}
+	return entries, nil
+}
+
+func schedulable(nodes []nodeStatus) int {
+	count := 0
+	for _, node := range nodes {
+		if node.Ready == string(corev1.ConditionTrue) && !node.Unschedulable {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,116 @@
+package guestsnapshot
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLoad(t *testing.T) {
+	g := NewWithT(t)
+	c := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: "clusters-hc"},
+		Data:       map[string]string{"nodes.yaml": "[]\n"},
+	}).Build()
+
+	s, err := Load(context.TODO(), c, "clusters-hc")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s).To(HaveKeyWithValue("nodes.yaml", "[]\n"))
+
+	s, err = Load(context.TODO(), c, "clusters-other")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s).To(BeNil())
+}
+
+func TestVerify(t *testing.T) {
+	node := func(name string, ready corev1.ConditionStatus, unschedulable bool) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+			Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}}},
+		}
+	}
+	operator := func(name string, available, degraded configv1.ConditionStatus) *configv1.ClusterOperator {
+		return &configv1.ClusterOperator{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: configv1.ClusterOperatorStatus{
+				Conditions: []configv1.ClusterOperatorStatusCondition{
+					{Type: configv1.OperatorAvailable, Status: available},
+					{Type: configv1.OperatorDegraded, Status: degraded},
+				},
+				Versions: []configv1.OperandVersion{{Name: "operator", Version: "4.18.3"}},
+			},
+		}
+	}
+	saved := Collect(context.TODO(), fake.NewClientBuilder().WithScheme(guestScheme).WithObjects(
+		node("worker-0", corev1.ConditionTrue, false),
+		node("worker-1", corev1.ConditionTrue, false),
+		node("worker-2", corev1.ConditionTrue, true),
+		operator("ingress", configv1.ConditionTrue, configv1.ConditionFalse),
+		operator("dns", configv1.ConditionTrue, configv1.ConditionFalse),
+		operator("monitoring", configv1.ConditionFalse, configv1.ConditionTrue),
+	).Build())
+
+	tests := []struct {
+		name    string
+		saved   Snapshot
+		objects []crclient.Object
+		results []string
+	}{
+		{
+			name:  "When the workloads of the cluster came back on new Nodes, It Should pass",
+			saved: saved,
+			objects: []crclient.Object{
+				node("worker-3", corev1.ConditionTrue, false),
+				node("worker-4", corev1.ConditionTrue, false),
+				operator("ingress", configv1.ConditionTrue, configv1.ConditionFalse),
+				operator("dns", configv1.ConditionTrue, configv1.ConditionFalse),
+			},
+			results: []string{
+				"PASS guest Nodes: 2 of 2 Ready and schedulable, 2 at backup time",
+				"PASS guest ClusterOperator dns: 4.18.3",
+				"PASS guest ClusterOperator ingress: 4.18.3",
+			},
+		},
+		{
+			name:  "When fewer Nodes are schedulable and an operator is degraded or gone, It Should fail them",
+			saved: saved,
+			objects: []crclient.Object{
+				node("worker-0", corev1.ConditionTrue, false),
+				node("worker-1", corev1.ConditionFalse, false),
+				operator("ingress", configv1.ConditionTrue, configv1.ConditionTrue),
+			},
+			results: []string{
+				"FAIL guest Nodes: 1 of 2 Ready and schedulable, 2 at backup time",
+				"FAIL guest ClusterOperator dns: not found",
+				"FAIL guest ClusterOperator ingress: Available=True Degraded=True, Available at backup time",
+			},
+		},
+		{
+			name:  "When the snapshot could not collect a source, It Should leave it out of the comparison",
+			saved: Snapshot{"nodes.yaml": "error collecting nodes.yaml: forbidden", "clusteroperators.yaml": "[]\n"},
+			results: []string{
+				"PASS guest Nodes: not compared, error collecting nodes.yaml: forbidden",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			guest := fake.NewClientBuilder().WithScheme(guestScheme).WithObjects(tt.objects...).Build()
+
+			results := []string{}
+			for _, result := range Verify(context.TODO(), tt.saved, guest) {
+				results = append(results, result.String())
+			}
+			g.Expect(results).To(Equal(tt.results))
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/tracing"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Event identifies the point of the backup or restore flow a hook runs at.
+type Event string
+
+const (
+	// BeforePause runs before the backup command pauses the HostedCluster for a backup, or
+	// before the plugin acts on the HostedCluster of a backup the command did not run.
+	BeforePause Event = "beforePause"
+	// AfterSnapshots runs once the etcd snapshot of a backup has completed. Only the
+	// etcdSnapshot method has one, the backup validation rejects it for the others.
+	AfterSnapshots Event = "afterSnapshots"
+	// AfterUnpause runs once a cluster restored paused has been resumed.
+	AfterUnpause Event = "afterUnpause"
+	// AfterRestore runs once the plugin has processed the restored HostedCluster.
+	AfterRestore Event = "afterRestore"
+
+	// JobTemplateKey is the ConfigMap key holding the Job manifest used as hook template.
+	JobTemplateKey = "job.yaml"
+	// EventLabel is set on the Jobs created for a hook.
+	EventLabel = "hypershift.openshift.io/hook-event"
+
+	FailurePolicyIgnore = "Ignore"
+	FailurePolicyFail   = "Fail"
+)
+
+var allEvents = []Event{BeforePause, AfterSnapshots, AfterUnpause, AfterRestore}
+
+// Payload is the context handed to hooks, as the webhook JSON body and as Job environment.
+type Payload struct {
+	Event                  Event     `json:"event"`
+	Backup                 string    `json:"backup,omitempty"`
+	Restore                string    `json:"restore,omitempty"`
+	HostedClusterName      string    `json:"hostedClusterName,omitempty"`
+	HostedClusterNamespace string    `json:"hostedClusterNamespace,omitempty"`
+	ControlPlaneNamespace  string    `json:"controlPlaneNamespace,omitempty"`
+	Timestamp              time.Time `json:"timestamp"`
+}
+
+// Runner invokes the user supplied webhook and/or Job template at the configured events.
+// A nil Runner (no hook configured) does nothing.
+type Runner struct {
+	Log    logrus.FieldLogger
+	Client crclient.Client
+	// Namespace where hook Jobs are created, and where the Job template ConfigMap lives.
+	Namespace   string
+	WebhookURL  string
+	JobTemplate string
+	Events      map[Event]bool
+	// FailOnError turns hook failures into backup/restore failures instead of warnings.
+	FailOnError bool
+	HTTPClient  *http.Client
+
+	// mu serializes the hooks, so concurrent items run each event once, and outcomes holds
+	// the result of each event that ran, keyed by event, backup/restore and HostedCluster
+	mu       sync.Mutex
+	outcomes map[string]error
+}
+
+// NewRunner builds a Runner from the plugin configuration. It returns nil when neither a
+// webhook nor a Job template is configured.
+func NewRunner(config map[string]string, c crclient.Client, namespace string, log logrus.FieldLogger) (*Runner, error) {
+	webhookURL := config[common.ConfigKeyHookWebhookURL]
+	jobTemplate := config[common.ConfigKeyHookJobTemplate]
+	if webhookURL == "" && jobTemplate == "" {
+		return nil, nil
+	}
+
+	r := &Runner{
+		Log:         log,
+		Client:      c,
+		Namespace:   namespace,
+		WebhookURL:  webhookURL,
+		JobTemplate: jobTemplate,
+		Events:      map[Event]bool{},
+		HTTPClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if events := config[common.ConfigKeyHookEvents]; events != "" {
+		for _, value := range strings.Split(events, ",") {
+			event := Event(strings.TrimSpace(value))
+			if !isKnownEvent(event) {
+				return nil, fmt.Errorf("unknown hook event %q in %s, must be one of %v", event, common.ConfigKeyHookEvents, allEvents)
+			}
+			r.Events[event] = true
+		}
+	} else {
+		for _, event := range allEvents {
+			r.Events[event] = true
+		}
+	}
+
+	switch policy := config[common.ConfigKeyHookFailurePolicy]; policy {
+	case "", FailurePolicyIgnore:
+	case FailurePolicyFail:
+		r.FailOnError = true
+	default:
+		return nil, fmt.Errorf("invalid %s %q: must be %q or %q", common.ConfigKeyHookFailurePolicy, policy, FailurePolicyIgnore, FailurePolicyFail)
+	}
+
+	return r, nil
+}
+
+func isKnownEvent(event Event) bool {
+	for _, known := range allEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Run invokes the hooks for the payload event. Each event runs at most once per
+// backup/restore and HostedCluster, since the plugin sees every item of the flow, and
+// every later item gets the outcome of that run. Failures are logged, and only returned
+// when the failure policy is Fail. A transient failure is not kept, so a retry of the
+// item, or the next item, runs the hooks again.
+func (r *Runner) Run(ctx context.Context, payload Payload) error {
+	if r == nil || !r.Events[payload.Event] {
+		return nil
+	}
+
+	key := strings.Join([]string{string(payload.Event), payload.Backup, payload.Restore, payload.HostedClusterNamespace, payload.HostedClusterName}, "/")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err, ran := r.outcomes[key]; ran {
+		return err
+	}
+
+	err := r.run(ctx, payload)
+	var transient *common.TransientError
+	if err != nil && errors.As(err, &transient) {
+		return err
+	}
+	if r.outcomes == nil {
+		r.outcomes = map[string]error{}
+	}
+	r.outcomes[key] = err
+	return err
+}
+
+// run invokes the webhook and the Job template for the payload event.
+func (r *Runner) run(ctx context.Context, payload Payload) error {
+	if payload.Timestamp.IsZero() {
+		payload.Timestamp = time.Now().UTC()
+	}
+
+	ctx, span := tracing.Start(ctx, "hooks.Run", attribute.String("event", string(payload.Event)))
+	var errs []error
+	if r.WebhookURL != "" {
+		if err := r.callWebhook(ctx, payload); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	if r.JobTemplate != "" {
+		if err := r.createJob(ctx, payload); err != nil {
+			errs = append(errs, fmt.Errorf("job: %w", err))
+		}
+	}
+
+	err := errors.Join(errs...)
+	tracing.End(span, err)
+	if err != nil {
+		if r.FailOnError {
+			return fmt.Errorf("%s hook failed: %w", payload.Event, err)
+		}
+		r.Log.Warnf("%s hook failed, continuing: %v", payload.Event, err)
+		return nil
+	}
+	r.Log.Infof("%s hook invoked", payload.Event)
+	return nil
+}
+
+func (r *Runner) callWebhook(ctx context.Context, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return &common.TransientError{Err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("unexpected response HTTP %d", resp.StatusCode)
+		// The webhook may answer again once the service recovers
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return &common.TransientError{Err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// createJob creates a Job from the template ConfigMap, with the payload exposed to every
+// container as HOOK_* environment variables. The Job is not waited for.
+func (r *Runner) createJob(ctx context.Context, payload Payload) error {
+	cm := &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, crclient.ObjectKey{Name: r.JobTemplate, Namespace: r.Namespace}, cm); err != nil {
+		return fmt.Errorf("error getting Job template ConfigMap %s/%s: %w", r.Namespace, r.JobTemplate, err)
+	}
+	manifest, ok := cm.Data[JobTemplateKey]
+	if !ok {
+		return fmt.Errorf("Job template ConfigMap %s/%s has no %s key", r.Namespace, r.JobTemplate, JobTemplateKey)
+	}
+
+	job := &batchv1.Job{}
+	if err := yaml.Unmarshal([]byte(manifest), job); err != nil {
+		return fmt.Errorf("error parsing Job template: %w", err)
+	}
+	job.Namespace = r.Namespace
+	job.Name = ""
+	job.GenerateName = fmt.Sprintf("%s-%s-", r.JobTemplate, strings.ToLower(string(payload.Event)))
+	if job.Labels == nil {
+		job.Labels = map[string]string{}
+	}
+	job.Labels[EventLabel] = string(payload.Event)
+
+	env := []corev1.EnvVar{
+		{Name: "HOOK_EVENT", Value: string(payload.Event)},
+		{Name: "HOOK_BACKUP", Value: payload.Backup},
+		{Name: "HOOK_RESTORE", Value: payload.Restore},
+		{Name: "HOOK_HOSTEDCLUSTER_NAME", Value: payload.HostedClusterName},
+		{Name: "HOOK_HOSTEDCLUSTER_NAMESPACE", Value: payload.HostedClusterNamespace},
+		{Name: "HOOK_CONTROLPLANE_NAMESPACE", Value: payload.ControlPlaneNamespace},
+	}
+	for i := range job.Spec.Template.Spec.Containers {
+		container := &job.Spec.Template.Spec.Containers[i]
+		container.Env = append(container.Env, env...)
+	}
+
+	if err := r.Client.Create(ctx, job); err != nil {
+		return fmt.Errorf("error creating hook Job: %w", err)
+	}
+	r.Log.Infof("Created %s hook Job %s/%s", payload.Event, job.Namespace, job.Name)
+	return nil
+}
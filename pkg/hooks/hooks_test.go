@@ -0,0 +1,266 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const jobTemplate = `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: ignored
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: hook
+        image: quay.io/example/hook:latest
+        env:
+        - name: EXISTING
+          value: "true"
+`
+
+func TestNewRunner(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     map[string]string
+		wantNil    bool
+		wantErr    bool
+		wantEvents []Event
+		wantFail   bool
+	}{
+		{
+			name:    "When no hook is configured, It Should return a nil Runner",
+			config:  map[string]string{common.ConfigKeyHookEvents: "afterRestore"},
+			wantNil: true,
+		},
+		{
+			name:       "When only a webhook is configured, It Should enable every event with the Ignore policy",
+			config:     map[string]string{common.ConfigKeyHookWebhookURL: "https://hooks.example.com"},
+			wantEvents: allEvents,
+		},
+		{
+			name: "When events and the Fail policy are set, It Should restrict the events and fail on error",
+			config: map[string]string{
+				common.ConfigKeyHookJobTemplate:   "hook",
+				common.ConfigKeyHookEvents:        "beforePause, afterRestore",
+				common.ConfigKeyHookFailurePolicy: FailurePolicyFail,
+			},
+			wantEvents: []Event{BeforePause, AfterRestore},
+			wantFail:   true,
+		},
+		{
+			name: "When an unknown event is set, It Should return an error",
+			config: map[string]string{
+				common.ConfigKeyHookWebhookURL: "https://hooks.example.com",
+				common.ConfigKeyHookEvents:     "afterPause",
+			},
+			wantErr: true,
+		},
+		{
+			name: "When an invalid failure policy is set, It Should return an error",
+			config: map[string]string{
+				common.ConfigKeyHookWebhookURL:    "https://hooks.example.com",
+				common.ConfigKeyHookFailurePolicy: "Retry",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			r, err := NewRunner(tt.config, nil, "openshift-adp", logrus.New())
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			if tt.wantNil {
+				g.Expect(r).To(BeNil())
+				return
+			}
+			g.Expect(r.Events).To(HaveLen(len(tt.wantEvents)))
+			for _, event := range tt.wantEvents {
+				g.Expect(r.Events[event]).To(BeTrue())
+			}
+			g.Expect(r.FailOnError).To(Equal(tt.wantFail))
+		})
+	}
+}
+
+func TestRunWebhook(t *testing.T) {
+	t.Run("When the event is enabled, It Should post the payload once per backup", func(t *testing.T) {
+		g := NewWithT(t)
+		var received []Payload
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			payload := Payload{}
+			g.Expect(json.NewDecoder(r.Body).Decode(&payload)).To(Succeed())
+			received = append(received, payload)
+		}))
+		defer server.Close()
+
+		r, err := NewRunner(map[string]string{
+			common.ConfigKeyHookWebhookURL: server.URL,
+			common.ConfigKeyHookEvents:     string(BeforePause),
+		}, nil, "openshift-adp", logrus.New())
+		g.Expect(err).NotTo(HaveOccurred())
+
+		payload := Payload{Event: BeforePause, Backup: "backup-1", HostedClusterName: "hc", ControlPlaneNamespace: "clusters-hc"}
+		g.Expect(r.Run(context.TODO(), payload)).To(Succeed())
+		g.Expect(r.Run(context.TODO(), payload)).To(Succeed())
+		g.Expect(r.Run(context.TODO(), Payload{Event: AfterRestore, Restore: "restore-1"})).To(Succeed())
+
+		g.Expect(received).To(HaveLen(1))
+		g.Expect(received[0].Event).To(Equal(BeforePause))
+		g.Expect(received[0].Backup).To(Equal("backup-1"))
+		g.Expect(received[0].ControlPlaneNamespace).To(Equal("clusters-hc"))
+		g.Expect(received[0].Timestamp.IsZero()).To(BeFalse())
+	})
+
+	t.Run("When the webhook fails with the Ignore policy, It Should not return an error", func(t *testing.T) {
+		g := NewWithT(t)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		r, err := NewRunner(map[string]string{common.ConfigKeyHookWebhookURL: server.URL}, nil, "openshift-adp", logrus.New())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(r.Run(context.TODO(), Payload{Event: AfterRestore, Restore: "restore-1"})).To(Succeed())
+	})
+
+	t.Run("When the webhook fails with the Fail policy, It Should return an error", func(t *testing.T) {
+		g := NewWithT(t)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		r, err := NewRunner(map[string]string{
+			common.ConfigKeyHookWebhookURL:    server.URL,
+			common.ConfigKeyHookFailurePolicy: FailurePolicyFail,
+		}, nil, "openshift-adp", logrus.New())
+		g.Expect(err).NotTo(HaveOccurred())
+		err = r.Run(context.TODO(), Payload{Event: AfterRestore, Restore: "restore-1"})
+		g.Expect(err).To(MatchError(ContainSubstring("HTTP 500")))
+	})
+
+	t.Run("When the webhook fails with the Fail policy, It Should return the failure to every later item", func(t *testing.T) {
+		g := NewWithT(t)
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		r, err := NewRunner(map[string]string{
+			common.ConfigKeyHookWebhookURL:    server.URL,
+			common.ConfigKeyHookFailurePolicy: FailurePolicyFail,
+		}, nil, "openshift-adp", logrus.New())
+		g.Expect(err).NotTo(HaveOccurred())
+		for range 3 {
+			err := r.Run(context.TODO(), Payload{Event: BeforePause, Backup: "backup-1"})
+			g.Expect(err).To(MatchError(ContainSubstring("HTTP 403")))
+		}
+		g.Expect(calls).To(Equal(1))
+	})
+
+	t.Run("When the webhook fails transiently with the Fail policy, It Should call it again on the retry", func(t *testing.T) {
+		g := NewWithT(t)
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer server.Close()
+
+		r, err := NewRunner(map[string]string{
+			common.ConfigKeyHookWebhookURL:    server.URL,
+			common.ConfigKeyHookFailurePolicy: FailurePolicyFail,
+		}, nil, "openshift-adp", logrus.New())
+		g.Expect(err).NotTo(HaveOccurred())
+		payload := Payload{Event: BeforePause, Backup: "backup-1"}
+
+		err = r.Run(context.TODO(), payload)
+		var transient *common.TransientError
+		g.Expect(errors.As(err, &transient)).To(BeTrue())
+		g.Expect(r.Run(context.TODO(), payload)).To(Succeed())
+		g.Expect(r.Run(context.TODO(), payload)).To(Succeed())
+		g.Expect(calls).To(Equal(2))
+	})
+
+	t.Run("When the Runner is nil, It Should do nothing", func(t *testing.T) {
+		g := NewWithT(t)
+		var r *Runner
+		g.Expect(r.Run(context.TODO(), Payload{Event: AfterRestore})).To(Succeed())
+	})
+}
+
+func TestRunJob(t *testing.T) {
+	t.Run("When a Job template is configured, It Should create a Job with the hook environment", func(t *testing.T) {
+		g := NewWithT(t)
+		template := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "hook", Namespace: "openshift-adp"},
+			Data:       map[string]string{JobTemplateKey: jobTemplate},
+		}
+		client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(template).Build()
+
+		r, err := NewRunner(map[string]string{common.ConfigKeyHookJobTemplate: "hook"}, client, "openshift-adp", logrus.New())
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(r.Run(context.TODO(), Payload{
+			Event:                  AfterRestore,
+			Restore:                "restore-1",
+			HostedClusterName:      "hc",
+			HostedClusterNamespace: "clusters",
+		})).To(Succeed())
+
+		jobs := &batchv1.JobList{}
+		g.Expect(client.List(context.TODO(), jobs)).To(Succeed())
+		g.Expect(jobs.Items).To(HaveLen(1))
+		job := jobs.Items[0]
+		g.Expect(job.Namespace).To(Equal("openshift-adp"))
+		g.Expect(job.GenerateName).To(Equal("hook-afterrestore-"))
+		g.Expect(job.Labels[EventLabel]).To(Equal(string(AfterRestore)))
+
+		env := map[string]string{}
+		for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+			env[e.Name] = e.Value
+		}
+		g.Expect(env).To(HaveKeyWithValue("EXISTING", "true"))
+		g.Expect(env).To(HaveKeyWithValue("HOOK_EVENT", "afterRestore"))
+		g.Expect(env).To(HaveKeyWithValue("HOOK_RESTORE", "restore-1"))
+		g.Expect(env).To(HaveKeyWithValue("HOOK_HOSTEDCLUSTER_NAME", "hc"))
+		g.Expect(env).To(HaveKeyWithValue("HOOK_HOSTEDCLUSTER_NAMESPACE", "clusters"))
+	})
+
+	t.Run("When the Job template ConfigMap is missing with the Fail policy, It Should return an error", func(t *testing.T) {
+		g := NewWithT(t)
+		client := fake.NewClientBuilder().WithScheme(common.CustomScheme).Build()
+
+		r, err := NewRunner(map[string]string{
+			common.ConfigKeyHookJobTemplate:   "hook",
+			common.ConfigKeyHookFailurePolicy: FailurePolicyFail,
+		}, client, "openshift-adp", logrus.New())
+		g.Expect(err).NotTo(HaveOccurred())
+		err = r.Run(context.TODO(), Payload{Event: AfterUnpause, HostedClusterName: "hc", HostedClusterNamespace: "clusters"})
+		g.Expect(err).To(MatchError(ContainSubstring("Job template ConfigMap")))
+	})
+}
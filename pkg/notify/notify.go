@@ -0,0 +1,200 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/tracing"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"go.opentelemetry.io/otel/attribute"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// FormatGeneric posts the Notification as JSON.
+	FormatGeneric = "generic"
+	// FormatSlack posts a Slack incoming webhook message, also understood by Mattermost
+	// and Rocket.Chat.
+	FormatSlack = "slack"
+
+	// OperationBackup and OperationRestore identify the Velero object a notification is about.
+	OperationBackup  = "backup"
+	OperationRestore = "restore"
+)
+
+// Notification describes a finished HCP backup or restore.
+type Notification struct {
+	Operation     string `json:"operation"`
+	Name          string `json:"name"`
+	HostedCluster string `json:"hostedCluster,omitempty"`
+	Phase         string `json:"phase"`
+	Succeeded     bool   `json:"succeeded"`
+	// Duration is the time between the Velero start and completion timestamps.
+	Duration time.Duration `json:"-"`
+	Errors   int           `json:"errors"`
+	Warnings int           `json:"warnings"`
+}
+
+// MarshalJSON encodes the duration in seconds, which every receiver understands.
+func (n Notification) MarshalJSON() ([]byte, error) {
+	type alias Notification
+	return json.Marshal(struct {
+		alias
+		DurationSeconds int64 `json:"durationSeconds"`
+	}{alias(n), int64(n.Duration.Seconds())})
+}
+
+// Notifier posts Notifications to a webhook.
+type Notifier struct {
+	WebhookURL string
+	Format     string
+	HTTPClient *http.Client
+}
+
+// NewNotifier builds a Notifier from the plugin configuration. It returns nil when no
+// notification webhook is configured.
+func NewNotifier(config map[string]string) (*Notifier, error) {
+	webhookURL := config[common.ConfigKeyNotificationWebhookURL]
+	if webhookURL == "" {
+		return nil, nil
+	}
+
+	format := config[common.ConfigKeyNotificationFormat]
+	switch format {
+	case "":
+		format = FormatGeneric
+	case FormatGeneric, FormatSlack:
+	default:
+		return nil, fmt.Errorf("invalid %s %q: must be %q or %q", common.ConfigKeyNotificationFormat, format, FormatGeneric, FormatSlack)
+	}
+
+	return &Notifier{
+		WebhookURL: webhookURL,
+		Format:     format,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Send posts the notification in the configured format.
+func (n *Notifier) Send(ctx context.Context, notification Notification) error {
+	var payload any = notification
+	if n.Format == FormatSlack {
+		payload = map[string]string{"text": notification.Text()}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response HTTP %d from notification webhook", resp.StatusCode)
+	}
+	return nil
+}
+
+// Text renders the notification as a one line chat message.
+func (n Notification) Text() string {
+	status := ":white_check_mark: succeeded"
+	if !n.Succeeded {
+		status = ":x: failed"
+	}
+	cluster := ""
+	if n.HostedCluster != "" {
+		cluster = fmt.Sprintf(" of HostedCluster %s", n.HostedCluster)
+	}
+	return fmt.Sprintf("HCP %s %s%s %s (phase %s) in %s, %d errors, %d warnings",
+		n.Operation, n.Name, cluster, status, n.Phase, n.Duration.Round(time.Second), n.Errors, n.Warnings)
+}
+
+// WaitForCompletion polls the Velero Backup or Restore until it reaches a terminal phase
+// and returns the matching Notification.
+func WaitForCompletion(ctx context.Context, c crclient.Client, operation, namespace, name string, interval time.Duration) (_ *Notification, err error) {
+	ctx, span := tracing.Start(ctx, "notify.WaitForCompletion", attribute.String("operation", operation), attribute.String("name", name))
+	defer func() { tracing.End(span, err) }()
+
+	var notification *Notification
+	err = wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+		var err error
+		notification, err = getNotification(ctx, c, operation, namespace, name)
+		if err != nil {
+			return false, err
+		}
+		return notification != nil, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for %s %s/%s to finish: %w", operation, namespace, name, err)
+	}
+	return notification, nil
+}
+
+// getNotification returns the Notification for a finished Backup or Restore, or nil while
+// it is still running.
+func getNotification(ctx context.Context, c crclient.Client, operation, namespace, name string) (*Notification, error) {
+	key := crclient.ObjectKey{Namespace: namespace, Name: name}
+	switch operation {
+	case OperationBackup:
+		backup := &velerov1.Backup{}
+		if err := c.Get(ctx, key, backup); err != nil {
+			return nil, err
+		}
+		switch backup.Status.Phase {
+		case velerov1.BackupPhaseCompleted, velerov1.BackupPhasePartiallyFailed, velerov1.BackupPhaseFailed, velerov1.BackupPhaseFailedValidation:
+		default:
+			return nil, nil
+		}
+		return &Notification{
+			Operation: operation,
+			Name:      name,
+			Phase:     string(backup.Status.Phase),
+			Succeeded: backup.Status.Phase == velerov1.BackupPhaseCompleted,
+			Duration:  duration(backup.Status.StartTimestamp, backup.Status.CompletionTimestamp),
+			Errors:    backup.Status.Errors,
+			Warnings:  backup.Status.Warnings,
+		}, nil
+	case OperationRestore:
+		restore := &velerov1.Restore{}
+		if err := c.Get(ctx, key, restore); err != nil {
+			return nil, err
+		}
+		switch restore.Status.Phase {
+		case velerov1.RestorePhaseCompleted, velerov1.RestorePhasePartiallyFailed, velerov1.RestorePhaseFailed, velerov1.RestorePhaseFailedValidation:
+		default:
+			return nil, nil
+		}
+		return &Notification{
+			Operation: operation,
+			Name:      name,
+			Phase:     string(restore.Status.Phase),
+			Succeeded: restore.Status.Phase == velerov1.RestorePhaseCompleted,
+			Duration:  duration(restore.Status.StartTimestamp, restore.Status.CompletionTimestamp),
+			Errors:    restore.Status.Errors,
+			Warnings:  restore.Status.Warnings,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown operation %q", operation)
+	}
+}
+
+func duration(start, completion *metav1.Time) time.Duration {
+	if start == nil || completion == nil {
+		return 0
+	}
+	return completion.Sub(start.Time)
+}
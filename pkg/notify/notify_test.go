@@ -0,0 +1,242 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNewNotifier(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     map[string]string
+		wantNil    bool
+		wantErr    bool
+		wantFormat string
+	}{
+		{
+			name:    "When no webhook is configured, It Should return a nil Notifier",
+			config:  map[string]string{common.ConfigKeyNotificationFormat: FormatSlack},
+			wantNil: true,
+		},
+		{
+			name:       "When only the webhook is configured, It Should default to the generic format",
+			config:     map[string]string{common.ConfigKeyNotificationWebhookURL: "https://hooks.example.com"},
+			wantFormat: FormatGeneric,
+		},
+		{
+			name: "When the slack format is configured, It Should use it",
+			config: map[string]string{
+				common.ConfigKeyNotificationWebhookURL: "https://hooks.slack.com/services/x",
+				common.ConfigKeyNotificationFormat:     FormatSlack,
+			},
+			wantFormat: FormatSlack,
+		},
+		{
+			name: "When an unknown format is configured, It Should return an error",
+			config: map[string]string{
+				common.ConfigKeyNotificationWebhookURL: "https://hooks.example.com",
+				common.ConfigKeyNotificationFormat:     "teams",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			n, err := NewNotifier(tt.config)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			if tt.wantNil {
+				g.Expect(n).To(BeNil())
+				return
+			}
+			g.Expect(n.Format).To(Equal(tt.wantFormat))
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	notification := Notification{
+		Operation:     OperationBackup,
+		Name:          "daily",
+		HostedCluster: "hc",
+		Phase:         string(velerov1.BackupPhasePartiallyFailed),
+		Duration:      90 * time.Second,
+		Errors:        2,
+	}
+
+	t.Run("When the format is generic, It Should post the notification as JSON", func(t *testing.T) {
+		g := NewWithT(t)
+		var body map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			g.Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		}))
+		defer server.Close()
+
+		n := &Notifier{WebhookURL: server.URL, Format: FormatGeneric, HTTPClient: server.Client()}
+		g.Expect(n.Send(context.TODO(), notification)).To(Succeed())
+		g.Expect(body).To(HaveKeyWithValue("operation", "backup"))
+		g.Expect(body).To(HaveKeyWithValue("hostedCluster", "hc"))
+		g.Expect(body).To(HaveKeyWithValue("succeeded", false))
+		g.Expect(body).To(HaveKeyWithValue("durationSeconds", float64(90)))
+		g.Expect(body).To(HaveKeyWithValue("errors", float64(2)))
+	})
+
+	t.Run("When the format is slack, It Should post a text message", func(t *testing.T) {
+		g := NewWithT(t)
+		var body map[string]string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			g.Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+		}))
+		defer server.Close()
+
+		n := &Notifier{WebhookURL: server.URL, Format: FormatSlack, HTTPClient: server.Client()}
+		g.Expect(n.Send(context.TODO(), notification)).To(Succeed())
+		g.Expect(body).To(HaveLen(1))
+		g.Expect(body["text"]).To(ContainSubstring("HCP backup daily of HostedCluster hc :x: failed (phase PartiallyFailed) in 1m30s"))
+	})
+
+	t.Run("When the webhook answers an error, It Should return an error", func(t *testing.T) {
+		g := NewWithT(t)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		n := &Notifier{WebhookURL: server.URL, Format: FormatGeneric, HTTPClient: server.Client()}
+		g.Expect(n.Send(context.TODO(), notification)).To(MatchError(ContainSubstring("HTTP 403")))
+	})
+}
+
+func TestWaitForCompletion(t *testing.T) {
+	start := metav1.NewTime(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC))
+	completion := metav1.NewTime(start.Add(5 * time.Minute))
+
+	t.Run("When the backup is completed, It Should return a successful notification", func(t *testing.T) {
+		g := NewWithT(t)
+		backup := &velerov1.Backup{
+			ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: "openshift-adp"},
+			Status: velerov1.BackupStatus{
+				Phase:               velerov1.BackupPhaseCompleted,
+				StartTimestamp:      &start,
+				CompletionTimestamp: &completion,
+				Warnings:            1,
+			},
+		}
+		client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(backup).Build()
+
+		n, err := WaitForCompletion(context.TODO(), client, OperationBackup, "openshift-adp", "daily", time.Millisecond)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(n.Succeeded).To(BeTrue())
+		g.Expect(n.Duration).To(Equal(5 * time.Minute))
+		g.Expect(n.Warnings).To(Equal(1))
+	})
+
+	t.Run("When the restore is still running, It Should wait until the context is done", func(t *testing.T) {
+		g := NewWithT(t)
+		restore := &velerov1.Restore{
+			ObjectMeta: metav1.ObjectMeta{Name: "restore-1", Namespace: "openshift-adp"},
+			Status:     velerov1.RestoreStatus{Phase: velerov1.RestorePhaseInProgress},
+		}
+		client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(restore).Build()
+
+		ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+		defer cancel()
+		_, err := WaitForCompletion(ctx, client, OperationRestore, "openshift-adp", "restore-1", time.Millisecond)
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("When Velero restarts and fails the running backup, It Should return a failed notification", func(t *testing.T) {
+		g := NewWithT(t)
+		backup := &velerov1.Backup{
+			ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: "openshift-adp"},
+			Status:     velerov1.BackupStatus{Phase: velerov1.BackupPhaseInProgress, StartTimestamp: &start},
+		}
+		client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(backup).Build()
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			backup.Status.Phase = velerov1.BackupPhaseFailed
+			backup.Status.FailureReason = `found a backup with status "InProgress" during the server starting, mark it as "Failed"`
+			_ = client.Update(context.TODO(), backup)
+		}()
+
+		n, err := WaitForCompletion(context.TODO(), client, OperationBackup, "openshift-adp", "daily", time.Millisecond)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(n.Succeeded).To(BeFalse())
+		g.Expect(n.Phase).To(Equal("Failed"))
+	})
+
+	t.Run("When the restore failed, It Should return a failed notification", func(t *testing.T) {
+		g := NewWithT(t)
+		restore := &velerov1.Restore{
+			ObjectMeta: metav1.ObjectMeta{Name: "restore-1", Namespace: "openshift-adp"},
+			Status:     velerov1.RestoreStatus{Phase: velerov1.RestorePhaseFailed, Errors: 3},
+		}
+		client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(restore).Build()
+
+		n, err := WaitForCompletion(context.TODO(), client, OperationRestore, "openshift-adp", "restore-1", time.Millisecond)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(n.Succeeded).To(BeFalse())
+		g.Expect(n.Phase).To(Equal("Failed"))
+		g.Expect(n.Errors).To(Equal(3))
+	})
+}
+
+func TestWatcherEnsure(t *testing.T) {
+	g := NewWithT(t)
+	podName, err := os.Hostname()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: "openshift-adp"},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: "velero",
+			InitContainers: []corev1.Container{
+				{Name: "openshift-velero-plugin", Image: "quay.io/konveyor/openshift-velero-plugin:latest"},
+				{Name: "hypershift-velero-plugin", Image: "quay.io/konveyor/hypershift-velero-plugin:latest"},
+			},
+		},
+	}
+	client := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(pod).Build()
+	w := &Watcher{Client: client, Namespace: "openshift-adp"}
+
+	g.Expect(w.Ensure(context.TODO(), OperationBackup, "daily", "hc")).To(Succeed())
+	// A second plugin process for the same backup finds the existing Job
+	g.Expect(w.Ensure(context.TODO(), OperationBackup, "daily", "hc")).To(Succeed())
+
+	jobs := &batchv1.JobList{}
+	g.Expect(client.List(context.TODO(), jobs)).To(Succeed())
+	g.Expect(jobs.Items).To(HaveLen(1))
+	job := jobs.Items[0]
+	g.Expect(job.Name).To(Equal("hcp-notify-backup-daily"))
+	g.Expect(job.Spec.Template.Spec.ServiceAccountName).To(Equal("velero"))
+	container := job.Spec.Template.Spec.Containers[0]
+	g.Expect(container.Image).To(Equal("quay.io/konveyor/hypershift-velero-plugin:latest"))
+	g.Expect(container.Command).To(Equal([]string{PluginBinary, Command, "--backup", "daily", "--hosted-cluster", "hc"}))
+}
+
+func TestJobName(t *testing.T) {
+	g := NewWithT(t)
+	long := jobName(OperationRestore, strings.Repeat("a", 80))
+	g.Expect(long).To(HaveLen(63))
+	g.Expect(long).NotTo(Equal(jobName(OperationRestore, strings.Repeat("a", 81))))
+}
@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Command is the plugin binary subcommand run by the watcher Job.
+	Command = "notify"
+	// PluginBinary is the path of the plugin binary in the plugin image.
+	PluginBinary = "/plugins/hypershift-oadp-plugin"
+
+	// OperationLabel is set on the watcher Jobs.
+	OperationLabel = "hypershift.openshift.io/notify-operation"
+
+	watcherBackoffLimit = int32(2)
+	watcherTTL          = int32(3600)
+)
+
+// Watcher starts the Job that waits for a backup or restore to finish and sends its
+// notification. Velero stops the plugin processes before the backup or restore reaches
+// its final phase, so the wait cannot happen in the plugin itself.
+type Watcher struct {
+	Client crclient.Client
+	// Namespace is the Velero namespace, where the Backup and Restore objects live.
+	Namespace string
+	// Image is the plugin image run by the watcher Job. When empty it is discovered from
+	// the init containers of the Velero pod.
+	Image string
+}
+
+// NewWatcher builds a Watcher from the plugin configuration, validating the notification
+// settings. It returns nil when no notification webhook is configured.
+func NewWatcher(config map[string]string, c crclient.Client, namespace string) (*Watcher, error) {
+	notifier, err := NewNotifier(config)
+	if err != nil || notifier == nil {
+		return nil, err
+	}
+	return &Watcher{
+		Client:    c,
+		Namespace: namespace,
+		Image:     config[common.ConfigKeyNotificationImage],
+	}, nil
+}
+
+// Ensure creates the watcher Job for the operation unless it already exists.
+func (w *Watcher) Ensure(ctx context.Context, operation, name, hostedCluster string) error {
+	pod, err := w.currentPod(ctx)
+	if err != nil {
+		return err
+	}
+	image := w.Image
+	if image == "" {
+		if image = pluginImage(pod); image == "" {
+			return fmt.Errorf("could not find the plugin image in the init containers of pod %s/%s", pod.Namespace, pod.Name)
+		}
+	}
+
+	backoffLimit, ttl := watcherBackoffLimit, watcherTTL
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName(operation, name),
+			Namespace: w.Namespace,
+			Labels:    map[string]string{OperationLabel: operation},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyOnFailure,
+					ServiceAccountName: pod.Spec.ServiceAccountName,
+					Containers: []corev1.Container{{
+						Name:    "notify",
+						Image:   image,
+						Command: []string{PluginBinary, Command, "--" + operation, name, "--hosted-cluster", hostedCluster},
+					}},
+				},
+			},
+		},
+	}
+
+	if err := w.Client.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("error creating notification Job for %s %s: %w", operation, name, err)
+	}
+	return nil
+}
+
+// currentPod returns the Velero pod the plugin runs in.
+func (w *Watcher) currentPod(ctx context.Context) (*corev1.Pod, error) {
+	podName, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("error getting the pod name: %w", err)
+	}
+	pod := &corev1.Pod{}
+	if err := w.Client.Get(ctx, crclient.ObjectKey{Name: podName, Namespace: w.Namespace}, pod); err != nil {
+		return nil, fmt.Errorf("error getting pod %s/%s: %w", w.Namespace, podName, err)
+	}
+	return pod, nil
+}
+
+// pluginImage returns the image of the init container that installed this plugin.
+func pluginImage(pod *corev1.Pod) string {
+	for _, container := range pod.Spec.InitContainers {
+		if strings.Contains(container.Image, "hypershift") {
+			return container.Image
+		}
+	}
+	return ""
+}
+
+// jobName returns a stable Job name for the operation, hashing long names so the Job
+// name stays a valid label value.
+func jobName(operation, name string) string {
+	jobName := fmt.Sprintf("hcp-notify-%s-%s", operation, name)
+	if len(jobName) <= 63 {
+		return jobName
+	}
+	sum := sha256.Sum256([]byte(jobName))
+	return jobName[:52] + "-" + hex.EncodeToString(sum[:])[:10]
+}
@@ -16,7 +16,7 @@ func MigrationTasks(ctx context.Context, item runtime.Unstructured, client crcli
 	clusterdDeployment := &hive.ClusterDeployment{}
 
 	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.UnstructuredContent(), clusterdDeployment); err != nil {
-		return fmt.Errorf("error converting item to CusterdDeployment: %v", err)
+		return fmt.Errorf("error converting item to CusterdDeployment: %w", err)
 	}
 
 	clusterDeploymentCP := clusterdDeployment.DeepCopy()
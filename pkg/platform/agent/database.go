@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v8/apis/volumesnapshot/v1"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/tracing"
+	"github.com/sirupsen/logrus"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/label"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultServiceNamespace is the namespace the multicluster engine runs assisted-service in.
+	DefaultServiceNamespace = "multicluster-engine"
+	// DatabasePVC is the PVC of the assisted-service PostgreSQL database, holding the host
+	// inventory of the agents.
+	DatabasePVC = "postgres"
+	// databaseSnapshotPrefix prefixes the name of the VolumeSnapshot taken for a backup.
+	databaseSnapshotPrefix = "assisted-service-db-"
+)
+
+// SnapshotDatabase takes a CSI VolumeSnapshot of the assisted-service database volume in
+// the namespace and waits until it is ready, so the host inventory of the agents is
+// captured together with the hosted cluster. The snapshot is named after the backup and
+// labeled with it; a plugin process restarted mid-backup waits for the existing snapshot.
+// The snapshot is crash consistent, which PostgreSQL recovers from. It returns the
+// namespace/name of the snapshot. The wait is bounded by the AgentDatabaseSnapshot timeout.
+func SnapshotDatabase(ctx context.Context, c crclient.Client, log logrus.FieldLogger, namespace string, backup *velerov1.Backup, timeouts common.Timeouts) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "agent.SnapshotDatabase")
+	defer func() { tracing.End(span, err) }()
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := c.Get(ctx, crclient.ObjectKey{Namespace: namespace, Name: DatabasePVC}, pvc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", common.NewValidationError("assisted-service database PVC %s/%s not found: set agent.%s to the namespace assisted-service runs in", namespace, DatabasePVC, common.PlatformOptionServiceNamespace)
+		}
+		return "", fmt.Errorf("error getting assisted-service database PVC %s/%s: %w", namespace, DatabasePVC, err)
+	}
+
+	snapshot := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      label.GetValidName(databaseSnapshotPrefix + backup.Name),
+			Namespace: namespace,
+			Labels:    common.BackupLabels(backup),
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source: snapshotv1.VolumeSnapshotSource{PersistentVolumeClaimName: &pvc.Name},
+		},
+	}
+	key := crclient.ObjectKeyFromObject(snapshot)
+	if err := c.Create(ctx, snapshot); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return "", fmt.Errorf("error creating VolumeSnapshot %s of the assisted-service database: %w", key, err)
+		}
+		existing := &snapshotv1.VolumeSnapshot{}
+		if err := c.Get(ctx, key, existing); err != nil {
+			return "", fmt.Errorf("error getting VolumeSnapshot %s: %w", key, err)
+		}
+		// A snapshot left by a deleted Backup of the same name holds an older inventory
+		if !common.BelongsToBackup(existing, backup) {
+			if err := c.Delete(ctx, existing); err != nil && !apierrors.IsNotFound(err) {
+				return "", fmt.Errorf("error deleting VolumeSnapshot %s of an earlier backup: %w", key, err)
+			}
+			return "", &common.TransientError{Err: fmt.Errorf("VolumeSnapshot %s of an earlier backup named %s is being replaced", key, backup.Name)}
+		}
+		log.Infof("Waiting for the VolumeSnapshot %s of the assisted-service database created by an earlier plugin process", key)
+	} else {
+		log.Infof("Created VolumeSnapshot %s of the assisted-service database", key)
+	}
+
+	timeouts = timeouts.WithDefaults()
+	err = common.Poll(ctx, timeouts.Clock, timeouts.AgentDatabaseSnapshotPoll, timeouts.AgentDatabaseSnapshot, true, func(ctx context.Context) (bool, error) {
+		if err := c.Get(ctx, key, snapshot); err != nil {
+			return false, fmt.Errorf("error getting VolumeSnapshot %s: %w", key, err)
+		}
+		if snapshot.Status == nil {
+			return false, nil
+		}
+		if snapshot.Status.Error != nil && snapshot.Status.Error.Message != nil {
+			return false, fmt.Errorf("VolumeSnapshot %s failed: %s", key, *snapshot.Status.Error.Message)
+		}
+		return snapshot.Status.ReadyToUse != nil && *snapshot.Status.ReadyToUse, nil
+	})
+	if err != nil {
+		return "", common.WrapWaitError(err, fmt.Sprintf("VolumeSnapshot %s", key), timeouts.AgentDatabaseSnapshot)
+	}
+	log.Infof("VolumeSnapshot %s of the assisted-service database is ready", key)
+	return key.String(), nil
+}
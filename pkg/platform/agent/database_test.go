@@ -0,0 +1,83 @@
+package agent
+
+// Test scenario names follow: "When <action or context>, It Should <expected outcome>".
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v8/apis/volumesnapshot/v1"
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/common/clocktest"
+	"github.com/sirupsen/logrus"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSnapshotDatabase(t *testing.T) {
+	backup := &velerov1.Backup{ObjectMeta: metav1.ObjectMeta{Name: "hc-backup", Namespace: "openshift-adp"}}
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: DatabasePVC, Namespace: DefaultServiceNamespace}}
+	newSnapshot := func(status *snapshotv1.VolumeSnapshotStatus) *snapshotv1.VolumeSnapshot {
+		return &snapshotv1.VolumeSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: "assisted-service-db-hc-backup", Namespace: DefaultServiceNamespace},
+			Spec:       snapshotv1.VolumeSnapshotSpec{Source: snapshotv1.VolumeSnapshotSource{PersistentVolumeClaimName: ptr.To(DatabasePVC)}},
+			Status:     status,
+		}
+	}
+
+	t.Run("When the snapshot of an earlier plugin process is ready, It Should return it", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(common.CustomScheme).
+			WithObjects(pvc, newSnapshot(&snapshotv1.VolumeSnapshotStatus{ReadyToUse: ptr.To(true)})).Build()
+
+		snapshot, err := SnapshotDatabase(context.TODO(), c, logrus.New(), DefaultServiceNamespace, backup, common.Timeouts{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(snapshot).To(Equal("multicluster-engine/assisted-service-db-hc-backup"))
+	})
+
+	t.Run("When the snapshot was left by a deleted backup of the same name, It Should replace it", func(t *testing.T) {
+		g := NewWithT(t)
+		stale := newSnapshot(&snapshotv1.VolumeSnapshotStatus{ReadyToUse: ptr.To(true)})
+		stale.Labels = map[string]string{velerov1.BackupNameLabel: "hc-backup", velerov1.BackupUIDLabel: "deleted-backup-uid"}
+		c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(pvc, stale).Build()
+
+		_, err := SnapshotDatabase(context.TODO(), c, logrus.New(), DefaultServiceNamespace, backup, common.Timeouts{})
+		g.Expect(common.IsRetryable(err)).To(BeTrue())
+
+		// The retry takes a new snapshot for the backup
+		_, err = SnapshotDatabase(context.TODO(), c, logrus.New(), DefaultServiceNamespace, backup, common.Timeouts{Clock: clocktest.New(t, common.DefaultTimeouts.AgentDatabaseSnapshotPoll)})
+		g.Expect(err).To(HaveOccurred())
+		snapshot := &snapshotv1.VolumeSnapshot{}
+		g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(stale), snapshot)).To(Succeed())
+		g.Expect(snapshot.Labels).To(Equal(common.BackupLabels(backup)))
+	})
+
+	t.Run("When the snapshot fails, It Should return its error", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(common.CustomScheme).
+			WithObjects(pvc, newSnapshot(&snapshotv1.VolumeSnapshotStatus{Error: &snapshotv1.VolumeSnapshotError{Message: ptr.To("no default VolumeSnapshotClass")}})).Build()
+
+		_, err := SnapshotDatabase(context.TODO(), c, logrus.New(), DefaultServiceNamespace, backup, common.Timeouts{})
+		g.Expect(err).To(MatchError(ContainSubstring("no default VolumeSnapshotClass")))
+	})
+
+	t.Run("When the database PVC is not in the namespace, It Should return a validation error", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(common.CustomScheme).Build()
+
+		_, err := SnapshotDatabase(context.TODO(), c, logrus.New(), DefaultServiceNamespace, backup, common.Timeouts{})
+		g.Expect(err).To(MatchError(ContainSubstring("agent." + common.PlatformOptionServiceNamespace)))
+		var validationErr *common.ValidationError
+		g.Expect(errors.As(err, &validationErr)).To(BeTrue())
+
+		snapshots := &snapshotv1.VolumeSnapshotList{}
+		g.Expect(c.List(context.TODO(), snapshots, crclient.InNamespace(DefaultServiceNamespace))).To(Succeed())
+		g.Expect(snapshots.Items).To(BeEmpty())
+	})
+}
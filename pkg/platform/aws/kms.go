@@ -0,0 +1,124 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/s3presign"
+)
+
+// KeyStateEnabled is the state of a KMS key that can encrypt and decrypt.
+const KeyStateEnabled = "Enabled"
+
+// KMSKeyDescriber abstracts the KMS DescribeKey operation so callers can inject a mock
+// for testing.
+type KMSKeyDescriber interface {
+	DescribeKey(ctx context.Context, creds s3presign.AWSCredentials, region, keyID string) (*KeyMetadata, error)
+}
+
+// KeyMetadata is the part of the DescribeKey response the plugin uses.
+type KeyMetadata struct {
+	Arn      string `json:"Arn"`
+	KeyState string `json:"KeyState"`
+}
+
+// KMSError is an error returned by the KMS API, e.g. NotFoundException.
+type KMSError struct {
+	Code    string
+	Message string
+}
+
+func (e *KMSError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// KMSClient calls the KMS API using pure stdlib (no AWS SDK).
+type KMSClient struct {
+	HTTPClient *http.Client
+	// Endpoint overrides the regional kms.<region>.amazonaws.com endpoint
+	Endpoint string
+}
+
+// NewKMSClient creates a KMSClient for the regional endpoints.
+func NewKMSClient() *KMSClient {
+	return &KMSClient{HTTPClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// DescribeKey returns the metadata of the KMS key, an ID or ARN, in the region.
+func (c *KMSClient) DescribeKey(ctx context.Context, creds s3presign.AWSCredentials, region, keyID string) (*KeyMetadata, error) {
+	body, err := json.Marshal(map[string]string{"KeyId": keyID})
+	if err != nil {
+		return nil, err
+	}
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com/", region)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating KMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.DescribeKey")
+	if err := s3presign.SignRequest(req, body, creds, region, "kms"); err != nil {
+		return nil, fmt.Errorf("error signing KMS request: %w", err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("KMS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading KMS response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var kmsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(data, &kmsErr); err != nil || kmsErr.Type == "" {
+			return nil, fmt.Errorf("KMS returned status %d: %s", resp.StatusCode, string(data))
+		}
+		// The type may be namespaced, e.g. com.amazonaws.kms#NotFoundException
+		code := kmsErr.Type[strings.LastIndex(kmsErr.Type, "#")+1:]
+		return nil, &KMSError{Code: code, Message: kmsErr.Message}
+	}
+
+	var out struct {
+		KeyMetadata KeyMetadata `json:"KeyMetadata"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("error parsing KMS response: %w", err)
+	}
+	return &out.KeyMetadata, nil
+}
+
+// CheckKMSKey checks the KMS key can decrypt the etcd data. A key that does not exist or is
+// not Enabled, e.g. disabled or pending deletion, returns a ValidationError. Other errors,
+// such as the credentials not being allowed to describe the key, are returned as is since
+// they do not tell whether the control plane can use it.
+func CheckKMSKey(ctx context.Context, kms KMSKeyDescriber, creds s3presign.AWSCredentials, region, keyARN string) error {
+	metadata, err := kms.DescribeKey(ctx, creds, region, keyARN)
+	if err != nil {
+		var kmsErr *KMSError
+		if errors.As(err, &kmsErr) && kmsErr.Code == "NotFoundException" {
+			return common.NewValidationError("KMS key %s does not exist in region %s", keyARN, region)
+		}
+		return fmt.Errorf("error describing KMS key %s: %w", keyARN, err)
+	}
+	if metadata.KeyState != KeyStateEnabled {
+		return common.NewValidationError("KMS key %s is %s, it must be %s to decrypt the etcd data", keyARN, metadata.KeyState, KeyStateEnabled)
+	}
+	return nil
+}
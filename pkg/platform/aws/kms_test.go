@@ -0,0 +1,80 @@
+package aws
+
+// Test scenario names follow: "When <action or context>, It Should <expected outcome>".
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/s3presign"
+)
+
+const keyARN = "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+func TestCheckKMSKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		response       string
+		wantValidation bool
+		errSubstr      string
+	}{
+		{
+			name:     "When the key is enabled, It Should return no error",
+			status:   http.StatusOK,
+			response: `{"KeyMetadata":{"Arn":"` + keyARN + `","KeyState":"Enabled"}}`,
+		},
+		{
+			name:           "When the key is pending deletion, It Should return a validation error",
+			status:         http.StatusOK,
+			response:       `{"KeyMetadata":{"Arn":"` + keyARN + `","KeyState":"PendingDeletion"}}`,
+			wantValidation: true,
+			errSubstr:      "is PendingDeletion, it must be Enabled",
+		},
+		{
+			name:           "When the key does not exist, It Should return a validation error",
+			status:         http.StatusBadRequest,
+			response:       `{"__type":"com.amazonaws.kms#NotFoundException","message":"Key not found"}`,
+			wantValidation: true,
+			errSubstr:      "does not exist in region us-east-1",
+		},
+		{
+			name:      "When the credentials cannot describe the key, It Should return a plain error",
+			status:    http.StatusBadRequest,
+			response:  `{"__type":"AccessDeniedException","message":"not authorized to perform kms:DescribeKey"}`,
+			errSubstr: "AccessDeniedException: not authorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				g.Expect(r.Header.Get("X-Amz-Target")).To(Equal("TrentService.DescribeKey"))
+				g.Expect(r.Header.Get("Authorization")).To(ContainSubstring("/us-east-1/kms/aws4_request"))
+				body, _ := io.ReadAll(r.Body)
+				g.Expect(string(body)).To(Equal(`{"KeyId":"` + keyARN + `"}`))
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+			client := &KMSClient{HTTPClient: server.Client(), Endpoint: server.URL}
+			creds := s3presign.AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}
+
+			err := CheckKMSKey(context.TODO(), client, creds, "us-east-1", keyARN)
+			if tt.errSubstr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errSubstr)))
+			var validationErr *common.ValidationError
+			g.Expect(errors.As(err, &validationErr)).To(Equal(tt.wantValidation))
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package ibm
+
+import (
+	"context"
+	"fmt"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// APIKeyKey is the key of the PowerVS credentials secrets holding the IBM Cloud API key.
+const APIKeyKey = "ibmcloud_api_key"
+
+// ValidateCredentials checks the IBM Cloud references of a HostedControlPlane. PowerVS
+// clusters must name their resource group, and the API key secrets of their components
+// must exist in the control plane namespace and hold an API key. Clusters encrypting
+// secrets with Key Protect using their own credentials must have that secret. Without them
+// the restored cluster cannot manage its IBM Cloud resources, or read its own secrets.
+func ValidateCredentials(ctx context.Context, c crclient.Client, hcp *hyperv1.HostedControlPlane) error {
+	if powerVS := hcp.Spec.Platform.PowerVS; powerVS != nil {
+		if powerVS.ResourceGroup == "" {
+			return common.NewValidationError("PowerVS HostedControlPlane %s has no resource group", hcp.Name)
+		}
+		for _, ref := range []struct {
+			field string
+			name  string
+		}{
+			{"kubeCloudControllerCreds", powerVS.KubeCloudControllerCreds.Name},
+			{"nodePoolManagementCreds", powerVS.NodePoolManagementCreds.Name},
+			{"ingressOperatorCloudCreds", powerVS.IngressOperatorCloudCreds.Name},
+			{"storageOperatorCloudCreds", powerVS.StorageOperatorCloudCreds.Name},
+			{"imageRegistryOperatorCloudCreds", powerVS.ImageRegistryOperatorCloudCreds.Name},
+		} {
+			secret, err := getSecret(ctx, c, hcp, ref.field, ref.name)
+			if err != nil {
+				return err
+			}
+			if len(secret.Data[APIKeyKey]) == 0 {
+				return common.NewValidationError("%s secret %s/%s of HostedControlPlane %s has no %q key", ref.field, hcp.Namespace, ref.name, hcp.Name, APIKeyKey)
+			}
+		}
+	}
+
+	if encryption := hcp.Spec.SecretEncryption; encryption != nil && encryption.KMS != nil && encryption.KMS.IBMCloud != nil {
+		auth := encryption.KMS.IBMCloud.Auth
+		if auth.Type == hyperv1.IBMCloudKMSUnmanagedAuth && auth.Unmanaged != nil {
+			if _, err := getSecret(ctx, c, hcp, "KMS credentials", auth.Unmanaged.Credentials.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getSecret gets the secret the field of the HostedControlPlane refers to, in its namespace.
+func getSecret(ctx context.Context, c crclient.Client, hcp *hyperv1.HostedControlPlane, field, name string) (*corev1.Secret, error) {
+	if name == "" {
+		return nil, common.NewValidationError("HostedControlPlane %s has no %s secret", hcp.Name, field)
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, crclient.ObjectKey{Namespace: hcp.Namespace, Name: name}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, common.NewValidationError("%s secret %s/%s of HostedControlPlane %s not found", field, hcp.Namespace, name, hcp.Name)
+		}
+		return nil, fmt.Errorf("error getting %s secret %s/%s: %w", field, hcp.Namespace, name, err)
+	}
+	return secret, nil
+}
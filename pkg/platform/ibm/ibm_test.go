@@ -0,0 +1,125 @@
+package ibm
+
+// Test scenario names follow: "When <action or context>, It Should <expected outcome>".
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateCredentials(t *testing.T) {
+	credentials := []string{"cloud-controller-creds", "node-management-creds", "ingress-creds", "storage-creds", "image-registry-creds"}
+	newPowerVSHCP := func(resourceGroup string) *hyperv1.HostedControlPlane {
+		return &hyperv1.HostedControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "clusters-test"},
+			Spec: hyperv1.HostedControlPlaneSpec{
+				Platform: hyperv1.PlatformSpec{
+					Type: hyperv1.PowerVSPlatform,
+					PowerVS: &hyperv1.PowerVSPlatformSpec{
+						ResourceGroup:                   resourceGroup,
+						KubeCloudControllerCreds:        corev1.LocalObjectReference{Name: credentials[0]},
+						NodePoolManagementCreds:         corev1.LocalObjectReference{Name: credentials[1]},
+						IngressOperatorCloudCreds:       corev1.LocalObjectReference{Name: credentials[2]},
+						StorageOperatorCloudCreds:       corev1.LocalObjectReference{Name: credentials[3]},
+						ImageRegistryOperatorCloudCreds: corev1.LocalObjectReference{Name: credentials[4]},
+					},
+				},
+			},
+		}
+	}
+	newSecrets := func(names []string, key string) []crclient.Object {
+		var secrets []crclient.Object
+		for _, name := range names {
+			secrets = append(secrets, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "clusters-test"},
+				Data:       map[string][]byte{key: []byte("api-key")},
+			})
+		}
+		return secrets
+	}
+	kmsHCP := &hyperv1.HostedControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "clusters-test"},
+		Spec: hyperv1.HostedControlPlaneSpec{
+			Platform: hyperv1.PlatformSpec{Type: hyperv1.IBMCloudPlatform, IBMCloud: &hyperv1.IBMCloudPlatformSpec{}},
+			SecretEncryption: &hyperv1.SecretEncryptionSpec{
+				Type: hyperv1.KMS,
+				KMS: &hyperv1.KMSSpec{
+					Provider: hyperv1.IBMCloud,
+					IBMCloud: &hyperv1.IBMCloudKMSSpec{
+						Region: "us-south",
+						Auth: hyperv1.IBMCloudKMSAuthSpec{
+							Type:      hyperv1.IBMCloudKMSUnmanagedAuth,
+							Unmanaged: &hyperv1.IBMCloudKMSUnmanagedAuthSpec{Credentials: corev1.LocalObjectReference{Name: "kp-creds"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		hcp       *hyperv1.HostedControlPlane
+		objects   []crclient.Object
+		errSubstr string
+	}{
+		{
+			name:    "When a PowerVS cluster has all its API keys, It Should return no error",
+			hcp:     newPowerVSHCP("hypershift"),
+			objects: newSecrets(credentials, APIKeyKey),
+		},
+		{
+			name:      "When a PowerVS cluster has no resource group, It Should return a validation error",
+			hcp:       newPowerVSHCP(""),
+			objects:   newSecrets(credentials, APIKeyKey),
+			errSubstr: "has no resource group",
+		},
+		{
+			name:      "When an API key secret is missing, It Should return a validation error",
+			hcp:       newPowerVSHCP("hypershift"),
+			objects:   newSecrets(credentials[:4], APIKeyKey),
+			errSubstr: "imageRegistryOperatorCloudCreds secret clusters-test/image-registry-creds of HostedControlPlane test not found",
+		},
+		{
+			name:      "When an API key secret has no API key, It Should return a validation error",
+			hcp:       newPowerVSHCP("hypershift"),
+			objects:   newSecrets(credentials, "apikey"),
+			errSubstr: `has no "ibmcloud_api_key" key`,
+		},
+		{
+			name:    "When an IBM Cloud VPC cluster has its Key Protect credentials, It Should return no error",
+			hcp:     kmsHCP,
+			objects: newSecrets([]string{"kp-creds"}, "apiKey"),
+		},
+		{
+			name:      "When the Key Protect credentials are missing, It Should return a validation error",
+			hcp:       kmsHCP,
+			errSubstr: "KMS credentials secret clusters-test/kp-creds of HostedControlPlane test not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(tt.objects...).Build()
+
+			err := ValidateCredentials(context.TODO(), c, tt.hcp)
+			if tt.errSubstr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errSubstr)))
+			var validationErr *common.ValidationError
+			g.Expect(errors.As(err, &validationErr)).To(BeTrue())
+		})
+	}
+}
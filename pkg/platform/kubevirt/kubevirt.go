@@ -0,0 +1,59 @@
+package kubevirt
+
+import (
+	"context"
+	"fmt"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// InfraCredentials returns the credentials of the external infra cluster running the VMs of
+// a KubeVirt HostedControlPlane, or nil when the VMs run on the management cluster.
+func InfraCredentials(hcp *hyperv1.HostedControlPlane) *hyperv1.KubevirtPlatformCredentials {
+	if hcp.Spec.Platform.Kubevirt == nil {
+		return nil
+	}
+	credentials := hcp.Spec.Platform.Kubevirt.Credentials
+	if credentials == nil || credentials.InfraKubeConfigSecret == nil {
+		return nil
+	}
+	return credentials
+}
+
+// ValidateInfraKubeconfig checks the infra kubeconfig secret of a KubeVirt HostedControlPlane
+// using an external infra cluster: it must exist in the control plane namespace and hold a
+// kubeconfig with a usable current context. Without it the restored cluster cannot reach
+// its VMs, or create new ones. It only parses the kubeconfig: the infra cluster may not be
+// reachable from the Velero namespace.
+func ValidateInfraKubeconfig(ctx context.Context, c crclient.Client, hcp *hyperv1.HostedControlPlane) error {
+	credentials := InfraCredentials(hcp)
+	if credentials == nil {
+		return nil
+	}
+	ref := credentials.InfraKubeConfigSecret
+
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, crclient.ObjectKey{Namespace: hcp.Namespace, Name: ref.Name}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return common.NewValidationError("infra kubeconfig secret %s/%s of KubeVirt HostedControlPlane %s not found", hcp.Namespace, ref.Name, hcp.Name)
+		}
+		return fmt.Errorf("error getting infra kubeconfig secret %s/%s: %w", hcp.Namespace, ref.Name, err)
+	}
+	data, ok := secret.Data[ref.Key]
+	if !ok {
+		return common.NewValidationError("infra kubeconfig secret %s/%s of KubeVirt HostedControlPlane %s has no %q key", hcp.Namespace, ref.Name, hcp.Name, ref.Key)
+	}
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return common.NewValidationError("infra kubeconfig secret %s/%s of KubeVirt HostedControlPlane %s holds an invalid kubeconfig: %v", hcp.Namespace, ref.Name, hcp.Name, err)
+	}
+	if _, err := clientcmd.NewDefaultClientConfig(*config, nil).ClientConfig(); err != nil {
+		return common.NewValidationError("infra kubeconfig secret %s/%s of KubeVirt HostedControlPlane %s holds an unusable kubeconfig: %v", hcp.Namespace, ref.Name, hcp.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package kubevirt
+
+// Test scenario names follow: "When <action or context>, It Should <expected outcome>".
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const infraKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: infra
+  cluster:
+    server: https://api.infra.example.com:6443
+users:
+- name: hypershift
+  user:
+    token: sha256~token
+contexts:
+- name: infra
+  context:
+    cluster: infra
+    user: hypershift
+current-context: infra
+`
+
+func TestValidateInfraKubeconfig(t *testing.T) {
+	newHCP := func(credentials *hyperv1.KubevirtPlatformCredentials) *hyperv1.HostedControlPlane {
+		return &hyperv1.HostedControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "clusters-test"},
+			Spec: hyperv1.HostedControlPlaneSpec{
+				Platform: hyperv1.PlatformSpec{
+					Type:     hyperv1.KubevirtPlatform,
+					Kubevirt: &hyperv1.KubevirtPlatformSpec{Credentials: credentials},
+				},
+			},
+		}
+	}
+	external := &hyperv1.KubevirtPlatformCredentials{
+		InfraKubeConfigSecret: &hyperv1.KubeconfigSecretRef{Name: "infra-kubeconfig", Key: "kubeconfig"},
+		InfraNamespace:        "vms",
+	}
+	newSecret := func(data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "infra-kubeconfig", Namespace: "clusters-test"}, Data: data}
+	}
+
+	tests := []struct {
+		name      string
+		hcp       *hyperv1.HostedControlPlane
+		objects   []crclient.Object
+		errSubstr string
+	}{
+		{
+			name: "When the VMs run on the management cluster, It Should return no error",
+			hcp:  newHCP(nil),
+		},
+		{
+			name:    "When the infra kubeconfig is usable, It Should return no error",
+			hcp:     newHCP(external),
+			objects: []crclient.Object{newSecret(map[string][]byte{"kubeconfig": []byte(infraKubeconfig)})},
+		},
+		{
+			name:      "When the infra kubeconfig secret is missing, It Should return a validation error",
+			hcp:       newHCP(external),
+			errSubstr: "not found",
+		},
+		{
+			name:      "When the infra kubeconfig secret has no such key, It Should return a validation error",
+			hcp:       newHCP(external),
+			objects:   []crclient.Object{newSecret(map[string][]byte{"value": []byte(infraKubeconfig)})},
+			errSubstr: `has no "kubeconfig" key`,
+		},
+		{
+			name:      "When the infra kubeconfig has no current context, It Should return a validation error",
+			hcp:       newHCP(external),
+			objects:   []crclient.Object{newSecret(map[string][]byte{"kubeconfig": []byte("apiVersion: v1\nkind: Config\n")})},
+			errSubstr: "unusable kubeconfig",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(tt.objects...).Build()
+
+			err := ValidateInfraKubeconfig(context.TODO(), c, tt.hcp)
+			if tt.errSubstr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errSubstr)))
+			var validationErr *common.ValidationError
+			g.Expect(errors.As(err, &validationErr)).To(BeTrue())
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package openstack
+
+import (
+	"context"
+	"fmt"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// CloudsYAMLKey is the key of the identityRef secret holding the clouds.yaml file.
+const CloudsYAMLKey = "clouds.yaml"
+
+// cloudsYAML is the part of a clouds.yaml file the identityRef of a cluster depends on.
+type cloudsYAML struct {
+	Clouds map[string]struct {
+		Auth struct {
+			AuthURL string `json:"auth_url"`
+		} `json:"auth"`
+	} `json:"clouds"`
+}
+
+// ValidateIdentityRef checks the credentials of an OpenStack HostedControlPlane: the secret
+// named by its identityRef must exist in the control plane namespace and hold a clouds.yaml
+// with an entry for the identityRef cloudName that has an auth URL. Without them the
+// restored cluster cannot manage its OpenStack resources.
+func ValidateIdentityRef(ctx context.Context, c crclient.Client, hcp *hyperv1.HostedControlPlane) error {
+	if hcp.Spec.Platform.OpenStack == nil {
+		return common.NewValidationError("OpenStack HostedControlPlane %s has no OpenStack platform configuration", hcp.Name)
+	}
+	ref := hcp.Spec.Platform.OpenStack.IdentityRef
+
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, crclient.ObjectKey{Namespace: hcp.Namespace, Name: ref.Name}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return common.NewValidationError("identityRef secret %s/%s of OpenStack HostedControlPlane %s not found", hcp.Namespace, ref.Name, hcp.Name)
+		}
+		return fmt.Errorf("error getting identityRef secret %s/%s: %w", hcp.Namespace, ref.Name, err)
+	}
+	data, ok := secret.Data[CloudsYAMLKey]
+	if !ok {
+		return common.NewValidationError("identityRef secret %s/%s of OpenStack HostedControlPlane %s has no %q key", hcp.Namespace, ref.Name, hcp.Name, CloudsYAMLKey)
+	}
+
+	clouds := &cloudsYAML{}
+	if err := yaml.Unmarshal(data, clouds); err != nil {
+		return common.NewValidationError("identityRef secret %s/%s of OpenStack HostedControlPlane %s holds an invalid %s: %v", hcp.Namespace, ref.Name, hcp.Name, CloudsYAMLKey, err)
+	}
+	cloud, ok := clouds.Clouds[ref.CloudName]
+	if !ok {
+		return common.NewValidationError("the %s of identityRef secret %s/%s has no cloud %q used by OpenStack HostedControlPlane %s", CloudsYAMLKey, hcp.Namespace, ref.Name, ref.CloudName, hcp.Name)
+	}
+	if cloud.Auth.AuthURL == "" {
+		return common.NewValidationError("cloud %q in the %s of identityRef secret %s/%s has no auth_url", ref.CloudName, CloudsYAMLKey, hcp.Namespace, ref.Name)
+	}
+	return nil
+}
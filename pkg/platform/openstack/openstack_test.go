@@ -0,0 +1,94 @@
+package openstack
+
+// Test scenario names follow: "When <action or context>, It Should <expected outcome>".
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const cloudsYAMLData = `clouds:
+  openstack:
+    auth:
+      auth_url: https://keystone.example.com:5000
+      application_credential_id: 0123
+      application_credential_secret: secret
+    region_name: regionOne
+`
+
+func TestValidateIdentityRef(t *testing.T) {
+	hcp := &hyperv1.HostedControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "clusters-test"},
+		Spec: hyperv1.HostedControlPlaneSpec{
+			Platform: hyperv1.PlatformSpec{
+				Type: hyperv1.OpenStackPlatform,
+				OpenStack: &hyperv1.OpenStackPlatformSpec{
+					IdentityRef: hyperv1.OpenStackIdentityReference{Name: "openstack-credentials", CloudName: "openstack"},
+				},
+			},
+		},
+	}
+	newSecret := func(data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "openstack-credentials", Namespace: "clusters-test"}, Data: data}
+	}
+
+	tests := []struct {
+		name      string
+		objects   []crclient.Object
+		errSubstr string
+	}{
+		{
+			name:    "When the clouds.yaml has the cloud of the identityRef, It Should return no error",
+			objects: []crclient.Object{newSecret(map[string][]byte{CloudsYAMLKey: []byte(cloudsYAMLData)})},
+		},
+		{
+			name:      "When the identityRef secret is missing, It Should return a validation error",
+			errSubstr: "not found",
+		},
+		{
+			name:      "When the identityRef secret has no clouds.yaml, It Should return a validation error",
+			objects:   []crclient.Object{newSecret(map[string][]byte{"cacert": []byte("-----BEGIN CERTIFICATE-----")})},
+			errSubstr: `has no "clouds.yaml" key`,
+		},
+		{
+			name:      "When the clouds.yaml does not parse, It Should return a validation error",
+			objects:   []crclient.Object{newSecret(map[string][]byte{CloudsYAMLKey: []byte("clouds: [")})},
+			errSubstr: "invalid clouds.yaml",
+		},
+		{
+			name:      "When the clouds.yaml has no cloud of that name, It Should return a validation error",
+			objects:   []crclient.Object{newSecret(map[string][]byte{CloudsYAMLKey: []byte("clouds:\n  other:\n    auth:\n      auth_url: https://keystone.example.com:5000\n")})},
+			errSubstr: `has no cloud "openstack"`,
+		},
+		{
+			name:      "When the cloud has no auth URL, It Should return a validation error",
+			objects:   []crclient.Object{newSecret(map[string][]byte{CloudsYAMLKey: []byte("clouds:\n  openstack:\n    region_name: regionOne\n")})},
+			errSubstr: "has no auth_url",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(tt.objects...).Build()
+
+			err := ValidateIdentityRef(context.TODO(), c, hcp)
+			if tt.errSubstr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(MatchError(ContainSubstring(tt.errSubstr)))
+			var validationErr *common.ValidationError
+			g.Expect(errors.As(err, &validationErr)).To(BeTrue())
+		})
+	}
+}
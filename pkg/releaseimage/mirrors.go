@@ -0,0 +1,61 @@
+package releaseimage
+
+import (
+	"context"
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var icspListGVK = schema.GroupVersionKind{
+	Group:   "operator.openshift.io",
+	Version: "v1alpha1",
+	Kind:    "ImageContentSourcePolicyList",
+}
+
+// DiscoverMirrors collects the digest mirrors configured on the cluster through
+// ImageDigestMirrorSets and the older ImageContentSourcePolicies. Clusters that do
+// not serve either API simply contribute no mirrors.
+func DiscoverMirrors(ctx context.Context, c crclient.Client) (map[string][]string, error) {
+	mirrors := map[string][]string{}
+
+	idmsList := &configv1.ImageDigestMirrorSetList{}
+	if err := c.List(ctx, idmsList); err != nil {
+		if !meta.IsNoMatchError(err) {
+			return nil, fmt.Errorf("error listing ImageDigestMirrorSets: %w", err)
+		}
+	}
+	for _, idms := range idmsList.Items {
+		for _, entry := range idms.Spec.ImageDigestMirrors {
+			for _, mirror := range entry.Mirrors {
+				mirrors[entry.Source] = append(mirrors[entry.Source], string(mirror))
+			}
+		}
+	}
+
+	icspList := &unstructured.UnstructuredList{}
+	icspList.SetGroupVersionKind(icspListGVK)
+	if err := c.List(ctx, icspList); err != nil {
+		if !meta.IsNoMatchError(err) {
+			return nil, fmt.Errorf("error listing ImageContentSourcePolicies: %w", err)
+		}
+	}
+	for _, icsp := range icspList.Items {
+		entries, _, _ := unstructured.NestedSlice(icsp.Object, "spec", "repositoryDigestMirrors")
+		for _, e := range entries {
+			entry, ok := e.(map[string]any)
+			if !ok {
+				continue
+			}
+			source, _, _ := unstructured.NestedString(entry, "source")
+			entryMirrors, _, _ := unstructured.NestedStringSlice(entry, "mirrors")
+			mirrors[source] = append(mirrors[source], entryMirrors...)
+		}
+	}
+
+	return mirrors, nil
+}
@@ -0,0 +1,354 @@
+package releaseimage
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRegistry      = "docker.io"
+	defaultDockerHubHost = "registry-1.docker.io"
+	defaultTag           = "latest"
+)
+
+// manifestMediaTypes are the manifest formats accepted when checking a release image.
+// Release payloads are published as manifest lists / OCI indexes, single-arch
+// images as plain manifests.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference is a parsed container image reference.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses an image reference such as
+// quay.io/openshift-release-dev/ocp-release@sha256:... or registry:5000/repo:tag.
+func ParseReference(image string) (*Reference, error) {
+	if image == "" {
+		return nil, fmt.Errorf("empty image reference")
+	}
+
+	ref := &Reference{}
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+		if !strings.Contains(ref.Digest, ":") {
+			return nil, fmt.Errorf("invalid digest in image reference %q", image)
+		}
+	}
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i+1:], "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Registry = parts[0]
+		ref.Repository = parts[1]
+	} else {
+		ref.Registry = defaultRegistry
+		ref.Repository = name
+		if !strings.Contains(name, "/") {
+			ref.Repository = "library/" + name
+		}
+	}
+	if ref.Repository == "" {
+		return nil, fmt.Errorf("missing repository in image reference %q", image)
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+
+	return ref, nil
+}
+
+// Name returns the registry/repository part of the reference.
+func (r *Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the full reference, preferring the digest over the tag.
+func (r *Reference) String() string {
+	if r.Digest != "" {
+		return r.Name() + "@" + r.Digest
+	}
+	return r.Name() + ":" + r.Tag
+}
+
+// manifestRef returns the digest or tag used to address the manifest.
+func (r *Reference) manifestRef() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// Checker verifies that images can be resolved from their registries, honoring
+// digest mirrors declared on the cluster (ImageDigestMirrorSet / ImageContentSourcePolicy).
+type Checker struct {
+	HTTPClient *http.Client
+	// Mirrors maps a source repository to its mirror repositories.
+	Mirrors map[string][]string
+}
+
+// NewChecker creates a Checker with a default HTTP client.
+func NewChecker(mirrors map[string][]string) *Checker {
+	return &Checker{
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		Mirrors:    mirrors,
+	}
+}
+
+// Check returns nil if the image manifest can be fetched from the source registry or
+// any of its mirrors using the credentials in pullSecret (a .dockerconfigjson payload).
+// Mirrors are only consulted for digest references, as the cluster runtime does.
+func (c *Checker) Check(ctx context.Context, image string, pullSecret []byte) error {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return err
+	}
+
+	auths, err := parsePullSecret(pullSecret)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, candidate := range c.candidates(ref) {
+		if err := c.checkManifest(ctx, candidate, auths); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", candidate.String(), err))
+			continue
+		}
+		return nil
+	}
+
+	return fmt.Errorf("image %s is not available: %w", image, errors.Join(errs...))
+}
+
+// candidates returns the mirror references to try (in order) followed by the source reference.
+// The mirrors of the most specific source come first, as the cluster runtime prefers them,
+// sources of the same length in name order, each keeping the order its mirrors were declared in.
+func (c *Checker) candidates(ref *Reference) []*Reference {
+	var refs []*Reference
+	if ref.Digest != "" {
+		name := ref.Name()
+		var sources []string
+		for source := range c.Mirrors {
+			if name == source || strings.HasPrefix(name, source+"/") {
+				sources = append(sources, source)
+			}
+		}
+		slices.SortFunc(sources, func(a, b string) int {
+			return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+		})
+		for _, source := range sources {
+			for _, mirror := range c.Mirrors[source] {
+				mirrored, err := ParseReference(mirror + strings.TrimPrefix(name, source) + "@" + ref.Digest)
+				if err != nil {
+					continue
+				}
+				refs = append(refs, mirrored)
+			}
+		}
+	}
+	return append(refs, ref)
+}
+
+// checkManifest issues a HEAD request for the manifest, following the registry's
+// Basic or Bearer token challenge when the first request is unauthorized.
+func (c *Checker) checkManifest(ctx context.Context, ref *Reference, auths map[string]string) error {
+	host := ref.Registry
+	if host == defaultRegistry {
+		host = defaultDockerHubHost
+	}
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, ref.Repository, ref.manifestRef())
+	basicAuth := lookupAuth(auths, ref)
+
+	resp, err := c.headManifest(ctx, manifestURL, "")
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("Www-Authenticate")
+		authorization, err := c.authorize(ctx, challenge, ref, basicAuth)
+		if err != nil {
+			return err
+		}
+		if resp, err = c.headManifest(ctx, manifestURL, authorization); err != nil {
+			return err
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("access denied by registry (HTTP %d), check the pull secret", resp.StatusCode)
+	case http.StatusNotFound:
+		return fmt.Errorf("manifest not found")
+	default:
+		return fmt.Errorf("unexpected registry response HTTP %d", resp.StatusCode)
+	}
+}
+
+func (c *Checker) headManifest(ctx context.Context, manifestURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("registry request failed: %w", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp, nil
+}
+
+// authorize answers a registry challenge and returns the Authorization header value to use.
+func (c *Checker) authorize(ctx context.Context, challenge string, ref *Reference, basicAuth string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if basicAuth == "" {
+			return "", fmt.Errorf("registry requires credentials but the pull secret has none for %s", ref.Registry)
+		}
+		return "Basic " + basicAuth, nil
+	case "bearer":
+		return c.fetchBearerToken(ctx, params, ref, basicAuth)
+	default:
+		return "", fmt.Errorf("unsupported registry auth challenge %q", challenge)
+	}
+}
+
+func (c *Checker) fetchBearerToken(ctx context.Context, params map[string]string, ref *Reference, basicAuth string) (string, error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry bearer challenge has no realm")
+	}
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid registry auth realm %q: %w", realm, err)
+	}
+	query := tokenURL.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", ref.Repository))
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if basicAuth != "" {
+		req.Header.Set("Authorization", "Basic "+basicAuth)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("registry token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry token request returned HTTP %d, check the pull secret", resp.StatusCode)
+	}
+
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("error decoding registry token: %w", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", fmt.Errorf("registry token response has no token")
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// parseChallenge splits a WWW-Authenticate header such as
+// Bearer realm="https://auth",service="registry" into its scheme and parameters.
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	for _, part := range strings.Split(rest, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		params[strings.ToLower(key)] = strings.Trim(value, `"`)
+	}
+	return scheme, params
+}
+
+// parsePullSecret returns the base64 "user:password" auth per registry key of a
+// .dockerconfigjson payload. An empty payload yields no credentials.
+func parsePullSecret(pullSecret []byte) (map[string]string, error) {
+	auths := map[string]string{}
+	if len(pullSecret) == 0 {
+		return auths, nil
+	}
+
+	config := struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}{}
+	if err := json.Unmarshal(pullSecret, &config); err != nil {
+		return nil, fmt.Errorf("error parsing pull secret: %w", err)
+	}
+
+	for key, entry := range config.Auths {
+		auth := entry.Auth
+		if auth == "" && entry.Username != "" {
+			auth = base64.StdEncoding.EncodeToString([]byte(entry.Username + ":" + entry.Password))
+		}
+		key = strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+		auths[strings.TrimSuffix(key, "/")] = auth
+	}
+	return auths, nil
+}
+
+// lookupAuth returns the most specific pull secret entry for the reference: entries
+// may be keyed by registry or by registry/namespace, as OpenShift allows.
+func lookupAuth(auths map[string]string, ref *Reference) string {
+	name := ref.Name()
+	best, bestLen := "", -1
+	for key, auth := range auths {
+		if (key == ref.Registry || key == name || strings.HasPrefix(name, key+"/")) && len(key) > bestLen {
+			best, bestLen = auth, len(key)
+		}
+	}
+	return best
+}
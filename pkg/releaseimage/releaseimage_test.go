@@ -0,0 +1,257 @@
+package releaseimage
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		name    string
+		image   string
+		want    Reference
+		wantErr bool
+	}{
+		{
+			name:  "release image by digest",
+			image: "quay.io/openshift-release-dev/ocp-release@sha256:abc123",
+			want:  Reference{Registry: "quay.io", Repository: "openshift-release-dev/ocp-release", Digest: "sha256:abc123"},
+		},
+		{
+			name:  "registry with port and tag",
+			image: "registry.local:5000/ocp/release:4.18.0-x86_64",
+			want:  Reference{Registry: "registry.local:5000", Repository: "ocp/release", Tag: "4.18.0-x86_64"},
+		},
+		{
+			name:  "registry with port and no tag defaults to latest",
+			image: "registry.local:5000/ocp/release",
+			want:  Reference{Registry: "registry.local:5000", Repository: "ocp/release", Tag: "latest"},
+		},
+		{
+			name:  "docker hub short name",
+			image: "busybox:1.36",
+			want:  Reference{Registry: "docker.io", Repository: "library/busybox", Tag: "1.36"},
+		},
+		{
+			name:  "localhost registry",
+			image: "localhost/release:dev",
+			want:  Reference{Registry: "localhost", Repository: "release", Tag: "dev"},
+		},
+		{
+			name:    "empty reference",
+			image:   "",
+			wantErr: true,
+		},
+		{
+			name:    "invalid digest",
+			image:   "quay.io/ocp/release@abc123",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReference(tt.image)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+// newRegistry starts a TLS registry serving the given repository manifests. When
+// token is set, manifest requests require the bearer token issued by /token for
+// the given basic credentials.
+func newRegistry(t *testing.T, manifests map[string]bool, basicAuth, token string) *httptest.Server {
+	t.Helper()
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Basic "+basicAuth {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Query().Get("scope"), "repository:") {
+			t.Errorf("unexpected token scope: %q", r.URL.Query().Get("scope"))
+		}
+		fmt.Fprintf(w, `{"token":%q}`, token)
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("expected HEAD, got %s", r.Method)
+		}
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			w.Header().Set("Www-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test-registry"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !manifests[r.URL.Path] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server = httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheck(t *testing.T) {
+	basicAuth := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	digest := "sha256:abc123"
+
+	t.Run("image available with bearer token auth", func(t *testing.T) {
+		server := newRegistry(t, map[string]bool{"/v2/ocp/release/manifests/" + digest: true}, basicAuth, "secret-token")
+		host := strings.TrimPrefix(server.URL, "https://")
+		pullSecret := fmt.Sprintf(`{"auths":{%q:{"auth":%q}}}`, host, basicAuth)
+
+		checker := &Checker{HTTPClient: server.Client()}
+		if err := checker.Check(context.Background(), host+"/ocp/release@"+digest, []byte(pullSecret)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("missing credentials are reported", func(t *testing.T) {
+		server := newRegistry(t, map[string]bool{"/v2/ocp/release/manifests/" + digest: true}, basicAuth, "secret-token")
+		host := strings.TrimPrefix(server.URL, "https://")
+
+		checker := &Checker{HTTPClient: server.Client()}
+		err := checker.Check(context.Background(), host+"/ocp/release@"+digest, nil)
+		if err == nil || !strings.Contains(err.Error(), "pull secret") {
+			t.Fatalf("expected pull secret error, got %v", err)
+		}
+	})
+
+	t.Run("unknown manifest is reported", func(t *testing.T) {
+		server := newRegistry(t, map[string]bool{}, "", "")
+		host := strings.TrimPrefix(server.URL, "https://")
+
+		checker := &Checker{HTTPClient: server.Client()}
+		err := checker.Check(context.Background(), host+"/ocp/release:4.18.0", nil)
+		if err == nil || !strings.Contains(err.Error(), "manifest not found") {
+			t.Fatalf("expected manifest not found error, got %v", err)
+		}
+	})
+
+	t.Run("digest resolved through mirror", func(t *testing.T) {
+		server := newRegistry(t, map[string]bool{"/v2/mirror/ocp/release/manifests/" + digest: true}, "", "")
+		host := strings.TrimPrefix(server.URL, "https://")
+
+		checker := &Checker{
+			HTTPClient: server.Client(),
+			Mirrors:    map[string][]string{"unreachable.invalid/ocp": {host + "/mirror/ocp"}},
+		}
+		if err := checker.Check(context.Background(), "unreachable.invalid/ocp/release@"+digest, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("mirrors are not used for tags", func(t *testing.T) {
+		server := newRegistry(t, map[string]bool{"/v2/mirror/ocp/release/manifests/4.18.0": true}, "", "")
+		host := strings.TrimPrefix(server.URL, "https://")
+
+		checker := &Checker{
+			HTTPClient: server.Client(),
+			Mirrors:    map[string][]string{"unreachable.invalid/ocp": {host + "/mirror/ocp"}},
+		}
+		if err := checker.Check(context.Background(), "unreachable.invalid/ocp/release:4.18.0", nil); err == nil {
+			t.Fatal("expected error for tag reference with unreachable source")
+		}
+	})
+}
+
+func TestCandidates(t *testing.T) {
+	c := NewChecker(map[string][]string{
+		"quay.io/openshift-release-dev":             {"mirror-b.example.com/ocp", "mirror-a.example.com/ocp"},
+		"quay.io/openshift-release-dev/ocp-release": {"mirror.example.com/release"},
+		"quay.io/other":                             {"mirror.example.com/other"},
+		"quay.io/openshift-release-dev/ocp-v4.0":    {"mirror.example.com/v4"},
+	})
+
+	tests := []struct {
+		name  string
+		image string
+		want  []string
+	}{
+		{
+			name:  "When several sources match a digest, It Should try the mirrors of the most specific first in declaration order",
+			image: "quay.io/openshift-release-dev/ocp-release@sha256:abc123",
+			want: []string{
+				"mirror.example.com/release@sha256:abc123",
+				"mirror-b.example.com/ocp/ocp-release@sha256:abc123",
+				"mirror-a.example.com/ocp/ocp-release@sha256:abc123",
+				"quay.io/openshift-release-dev/ocp-release@sha256:abc123",
+			},
+		},
+		{
+			name:  "When a tag is checked, It Should only try the source",
+			image: "quay.io/openshift-release-dev/ocp-release:4.18.0-x86_64",
+			want:  []string{"quay.io/openshift-release-dev/ocp-release:4.18.0-x86_64"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := ParseReference(tt.image)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// Map iteration varies between runs, the order must not
+			for i := 0; i < 10; i++ {
+				var got []string
+				for _, candidate := range c.candidates(ref) {
+					got = append(got, candidate.String())
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Fatalf("got candidates %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLookupAuth(t *testing.T) {
+	auths := map[string]string{
+		"quay.io":                       "registry-auth",
+		"quay.io/openshift-release-dev": "namespace-auth",
+	}
+
+	tests := []struct {
+		name  string
+		image string
+		want  string
+	}{
+		{name: "namespace entry wins", image: "quay.io/openshift-release-dev/ocp-release:4.18", want: "namespace-auth"},
+		{name: "registry entry fallback", image: "quay.io/other/image:latest", want: "registry-auth"},
+		{name: "no matching entry", image: "registry.redhat.io/ubi9:latest", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := ParseReference(tt.image)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := lookupAuth(auths, ref); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
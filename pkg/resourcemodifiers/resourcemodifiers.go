@@ -0,0 +1,180 @@
+package resourcemodifiers
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	"github.com/vmware-tanzu/velero/pkg/label"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ConfigMapPrefix prefixes the name of the resource modifier ConfigMap of a HostedCluster.
+	ConfigMapPrefix = "hcp-migration-"
+
+	// DataKey holds the rules. Velero reads the only key of the ConfigMap, whatever its name.
+	DataKey = "modifiers.yaml"
+)
+
+// Options are the values of the source cluster that change on the target. Empty values
+// are left as backed up.
+type Options struct {
+	InfraID string
+	// Region is the AWS region or the Azure location of the target.
+	Region string
+	// Hosts maps the hostnames the services of the HostedCluster are published on to the
+	// target ones.
+	Hosts map[string]string
+}
+
+// Rules are Velero resource modifier rules, in the version v1 format Velero reads from the
+// ConfigMap referenced by spec.resourceModifier of a Restore.
+type Rules struct {
+	Version               string `json:"version"`
+	ResourceModifierRules []Rule `json:"resourceModifierRules"`
+}
+
+type Rule struct {
+	Conditions Conditions  `json:"conditions"`
+	Patches    []JSONPatch `json:"patches"`
+}
+
+type Conditions struct {
+	GroupResource     string   `json:"groupResource"`
+	ResourceNameRegex string   `json:"resourceNameRegex,omitempty"`
+	Namespaces        []string `json:"namespaces,omitempty"`
+	Matches           []Match  `json:"matches,omitempty"`
+}
+
+// Match restricts a rule to the objects whose field at the JSON pointer path has the value.
+type Match struct {
+	Path  string `json:"path"`
+	Value string `json:"value"`
+}
+
+// JSONPatch is a JSON patch operation. Velero quotes the value unless it is a number, a
+// boolean, null, or a JSON object or array.
+type JSONPatch struct {
+	Operation string `json:"operation"`
+	Path      string `json:"path"`
+	Value     string `json:"value,omitempty"`
+}
+
+// NewRules returns the rules rewriting the HostedCluster and its HostedControlPlane for the
+// target: the infraID, the region and the hostnames of the published services. Both objects
+// carry the same spec fields, at the same paths. The Routes of the control plane published
+// on a rewritten hostname are rewritten too, so they do not claim the source one.
+func NewRules(hc *hyperv1.HostedCluster, opts Options) (*Rules, error) {
+	var patches []JSONPatch
+	if opts.InfraID != "" {
+		patches = append(patches, JSONPatch{Operation: "replace", Path: "/spec/infraID", Value: opts.InfraID})
+	}
+	if opts.Region != "" {
+		switch {
+		case hc.Spec.Platform.AWS != nil:
+			patches = append(patches, JSONPatch{Operation: "replace", Path: "/spec/platform/aws/region", Value: opts.Region})
+		case hc.Spec.Platform.Azure != nil:
+			patches = append(patches, JSONPatch{Operation: "replace", Path: "/spec/platform/azure/location", Value: opts.Region})
+		default:
+			return nil, fmt.Errorf("the region of %s platform clusters cannot be changed, only AWS and Azure ones", hc.Spec.Platform.Type)
+		}
+	}
+
+	replaced := map[string]bool{}
+	for i, service := range hc.Spec.Services {
+		strategy := service.ServicePublishingStrategy
+		var field, host string
+		switch {
+		case strategy.Route != nil:
+			field, host = "route/hostname", strategy.Route.Hostname
+		case strategy.LoadBalancer != nil:
+			field, host = "loadBalancer/hostname", strategy.LoadBalancer.Hostname
+		case strategy.NodePort != nil:
+			field, host = "nodePort/address", strategy.NodePort.Address
+		}
+		target, ok := opts.Hosts[host]
+		if host == "" || !ok {
+			continue
+		}
+		patches = append(patches, JSONPatch{Operation: "replace", Path: fmt.Sprintf("/spec/services/%d/servicePublishingStrategy/%s", i, field), Value: target})
+		replaced[host] = true
+	}
+	for _, host := range slices.Sorted(maps.Keys(opts.Hosts)) {
+		if !replaced[host] {
+			return nil, fmt.Errorf("no service of HostedCluster %s/%s is published on %s", hc.Namespace, hc.Name, host)
+		}
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("nothing to modify, set the infraID, region or hosts of the target")
+	}
+
+	name := "^" + regexp.QuoteMeta(hc.Name) + "$"
+	hcpNamespace := common.GetHCPNamespace(hc.Name, hc.Namespace)
+	rules := &Rules{
+		Version: "v1",
+		ResourceModifierRules: []Rule{
+			{
+				Conditions: Conditions{GroupResource: "hostedclusters." + hyperv1.GroupVersion.Group, ResourceNameRegex: name, Namespaces: []string{hc.Namespace}},
+				Patches:    patches,
+			},
+			{
+				Conditions: Conditions{GroupResource: "hostedcontrolplanes." + hyperv1.GroupVersion.Group, ResourceNameRegex: name, Namespaces: []string{hcpNamespace}},
+				Patches:    patches,
+			},
+		},
+	}
+	for _, host := range slices.Sorted(maps.Keys(opts.Hosts)) {
+		rules.ResourceModifierRules = append(rules.ResourceModifierRules, Rule{
+			Conditions: Conditions{GroupResource: "routes.route.openshift.io", Namespaces: []string{hcpNamespace}, Matches: []Match{{Path: "/spec/host", Value: host}}},
+			Patches:    []JSONPatch{{Operation: "replace", Path: "/spec/host", Value: opts.Hosts[host]}},
+		})
+	}
+	return rules, nil
+}
+
+// NewConfigMap returns the ConfigMap holding the rules, in the Velero namespace, to be
+// referenced by the spec.resourceModifier of the migration Restore.
+func NewConfigMap(hc *hyperv1.HostedCluster, namespace string, rules *Rules) (*corev1.ConfigMap, error) {
+	data, err := yaml.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding resource modifier rules: %w", err)
+	}
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      label.GetValidName(ConfigMapPrefix + strings.ToLower(hc.Name)),
+			Namespace: namespace,
+			Labels:    map[string]string{common.HostedClusterLabel: hc.Name},
+		},
+		Data: map[string]string{DataKey: string(data)},
+	}, nil
+}
+
+// Register creates the ConfigMap, or replaces the rules of an existing one, and returns
+// the reference to set as the spec.resourceModifier of the Restore.
+func Register(ctx context.Context, c crclient.Client, cm *corev1.ConfigMap) (*corev1.TypedLocalObjectReference, error) {
+	if err := c.Create(ctx, cm); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("error creating resource modifier ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+		}
+		existing := &corev1.ConfigMap{}
+		if err := c.Get(ctx, crclient.ObjectKeyFromObject(cm), existing); err != nil {
+			return nil, fmt.Errorf("error getting resource modifier ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+		}
+		existing.Labels, existing.Data = cm.Labels, cm.Data
+		if err := c.Update(ctx, existing); err != nil {
+			return nil, fmt.Errorf("error updating resource modifier ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+		}
+	}
+	return &corev1.TypedLocalObjectReference{Kind: "ConfigMap", Name: cm.Name}, nil
+}
@@ -0,0 +1,152 @@
+package resourcemodifiers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func newHostedCluster(platform hyperv1.PlatformSpec) *hyperv1.HostedCluster {
+	return &hyperv1.HostedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-hc", Namespace: "clusters"},
+		Spec: hyperv1.HostedClusterSpec{
+			Platform: platform,
+			Services: []hyperv1.ServicePublishingStrategyMapping{
+				{Service: hyperv1.APIServer, ServicePublishingStrategy: hyperv1.ServicePublishingStrategy{
+					Type:         hyperv1.LoadBalancer,
+					LoadBalancer: &hyperv1.LoadBalancerPublishingStrategy{Hostname: "api.source.example.com"},
+				}},
+				{Service: hyperv1.OAuthServer, ServicePublishingStrategy: hyperv1.ServicePublishingStrategy{
+					Type:  hyperv1.Route,
+					Route: &hyperv1.RoutePublishingStrategy{Hostname: "oauth.source.example.com"},
+				}},
+			},
+		},
+	}
+}
+
+func TestNewRules(t *testing.T) {
+	aws := hyperv1.PlatformSpec{Type: hyperv1.AWSPlatform, AWS: &hyperv1.AWSPlatformSpec{Region: "us-east-1"}}
+	tests := []struct {
+		name     string
+		platform hyperv1.PlatformSpec
+		opts     Options
+		patches  []JSONPatch
+		routes   []Rule
+		err      string
+	}{
+		{
+			name:     "infraID and AWS region",
+			platform: aws,
+			opts:     Options{InfraID: "my-hc-x7k2p", Region: "us-west-2"},
+			patches: []JSONPatch{
+				{Operation: "replace", Path: "/spec/infraID", Value: "my-hc-x7k2p"},
+				{Operation: "replace", Path: "/spec/platform/aws/region", Value: "us-west-2"},
+			},
+		},
+		{
+			name:     "Azure location",
+			platform: hyperv1.PlatformSpec{Type: hyperv1.AzurePlatform, Azure: &hyperv1.AzurePlatformSpec{Location: "eastus"}},
+			opts:     Options{Region: "westeurope"},
+			patches:  []JSONPatch{{Operation: "replace", Path: "/spec/platform/azure/location", Value: "westeurope"}},
+		},
+		{
+			name:     "hosts",
+			platform: aws,
+			opts:     Options{Hosts: map[string]string{"oauth.source.example.com": "oauth.target.example.com", "api.source.example.com": "api.target.example.com"}},
+			patches: []JSONPatch{
+				{Operation: "replace", Path: "/spec/services/0/servicePublishingStrategy/loadBalancer/hostname", Value: "api.target.example.com"},
+				{Operation: "replace", Path: "/spec/services/1/servicePublishingStrategy/route/hostname", Value: "oauth.target.example.com"},
+			},
+			routes: []Rule{
+				{
+					Conditions: Conditions{GroupResource: "routes.route.openshift.io", Namespaces: []string{"clusters-my-hc"}, Matches: []Match{{Path: "/spec/host", Value: "api.source.example.com"}}},
+					Patches:    []JSONPatch{{Operation: "replace", Path: "/spec/host", Value: "api.target.example.com"}},
+				},
+				{
+					Conditions: Conditions{GroupResource: "routes.route.openshift.io", Namespaces: []string{"clusters-my-hc"}, Matches: []Match{{Path: "/spec/host", Value: "oauth.source.example.com"}}},
+					Patches:    []JSONPatch{{Operation: "replace", Path: "/spec/host", Value: "oauth.target.example.com"}},
+				},
+			},
+		},
+		{
+			name:     "unknown host",
+			platform: aws,
+			opts:     Options{Hosts: map[string]string{"ignition.source.example.com": "ignition.target.example.com"}},
+			err:      "no service of HostedCluster clusters/my-hc is published on ignition.source.example.com",
+		},
+		{
+			name:     "region of a KubeVirt cluster",
+			platform: hyperv1.PlatformSpec{Type: hyperv1.KubevirtPlatform, Kubevirt: &hyperv1.KubevirtPlatformSpec{}},
+			opts:     Options{Region: "us-west-2"},
+			err:      "the region of KubeVirt platform clusters cannot be changed",
+		},
+		{
+			name:     "nothing to modify",
+			platform: aws,
+			err:      "nothing to modify",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			rules, err := NewRules(newHostedCluster(tt.platform), tt.opts)
+			if tt.err != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.err)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(rules.Version).To(Equal("v1"))
+			g.Expect(rules.ResourceModifierRules).To(HaveLen(2 + len(tt.routes)))
+			g.Expect(rules.ResourceModifierRules[0].Conditions).To(Equal(Conditions{
+				GroupResource: "hostedclusters.hypershift.openshift.io", ResourceNameRegex: "^my-hc$", Namespaces: []string{"clusters"},
+			}))
+			g.Expect(rules.ResourceModifierRules[1].Conditions).To(Equal(Conditions{
+				GroupResource: "hostedcontrolplanes.hypershift.openshift.io", ResourceNameRegex: "^my-hc$", Namespaces: []string{"clusters-my-hc"},
+			}))
+			for _, rule := range rules.ResourceModifierRules[:2] {
+				g.Expect(rule.Patches).To(Equal(tt.patches))
+			}
+			g.Expect(rules.ResourceModifierRules[2:]).To(HaveExactElements(tt.routes))
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	hc := newHostedCluster(hyperv1.PlatformSpec{Type: hyperv1.AWSPlatform, AWS: &hyperv1.AWSPlatformSpec{}})
+	c := fake.NewClientBuilder().WithScheme(common.CustomScheme).Build()
+
+	rules, err := NewRules(hc, Options{InfraID: "first"})
+	g.Expect(err).NotTo(HaveOccurred())
+	cm, err := NewConfigMap(hc, "openshift-adp", rules)
+	g.Expect(err).NotTo(HaveOccurred())
+	ref, err := Register(ctx, c, cm)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(*ref).To(Equal(corev1.TypedLocalObjectReference{Kind: "ConfigMap", Name: "hcp-migration-my-hc"}))
+
+	// Registering again replaces the rules
+	rules, err = NewRules(hc, Options{InfraID: "second"})
+	g.Expect(err).NotTo(HaveOccurred())
+	cm, err = NewConfigMap(hc, "openshift-adp", rules)
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = Register(ctx, c, cm)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	saved := &corev1.ConfigMap{}
+	g.Expect(c.Get(ctx, crclient.ObjectKey{Namespace: "openshift-adp", Name: ref.Name}, saved)).To(Succeed())
+	g.Expect(saved.Data).To(HaveLen(1))
+	g.Expect(saved.Labels).To(HaveKeyWithValue(common.HostedClusterLabel, "my-hc"))
+	decoded := &Rules{}
+	g.Expect(yaml.Unmarshal([]byte(saved.Data[DataKey]), decoded)).To(Succeed())
+	g.Expect(decoded.ResourceModifierRules[0].Patches).To(Equal([]JSONPatch{{Operation: "replace", Path: "/spec/infraID", Value: "second"}}))
+}
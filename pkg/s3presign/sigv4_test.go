@@ -0,0 +1,57 @@
+package s3presign
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignRequest(t *testing.T) {
+	origNow := nowFunc
+	nowFunc = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+	t.Cleanup(func() { nowFunc = origNow })
+
+	t.Run("signature matches the get-vanilla case of the AWS SigV4 test suite", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		creds := AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+		if err := SignRequest(req, nil, creds, "us-east-1", "service"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+		if got := req.Header.Get("Authorization"); got != want {
+			t.Errorf("unexpected Authorization header:\n got %s\nwant %s", got, want)
+		}
+		if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+			t.Errorf("unexpected X-Amz-Date header %q", got)
+		}
+	})
+
+	t.Run("session token is sent and signed", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "https://kms.us-east-1.amazonaws.com/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		creds := AWSCredentials{AccessKeyID: "ASIAEXAMPLE", SecretAccessKey: "secret", SessionToken: "token"}
+		if err := SignRequest(req, []byte(`{}`), creds, "us-east-1", "kms"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := req.Header.Get("X-Amz-Security-Token"); got != "token" {
+			t.Errorf("unexpected X-Amz-Security-Token header %q", got)
+		}
+		if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "SignedHeaders=host;x-amz-date;x-amz-security-token,") {
+			t.Errorf("expected the session token to be signed, got %s", auth)
+		}
+	})
+
+	t.Run("missing credentials return an error", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+		if err := SignRequest(req, nil, AWSCredentials{}, "us-east-1", "kms"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
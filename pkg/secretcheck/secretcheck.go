@@ -0,0 +1,316 @@
+package secretcheck
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/label"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ConfigMapPrefix prefixes the name of the ConfigMap holding the report of a restore.
+	ConfigMapPrefix = "hcp-restore-report-"
+
+	// ResultsKey lists the results, one per line, and PassedKey is "true" when all passed.
+	ResultsKey = "secrets"
+	PassedKey  = "passed"
+
+	// DefaultExpiryThreshold is how long before its expiry a certificate counts as expiring.
+	DefaultExpiryThreshold = 30 * 24 * time.Hour
+
+	signingKeySecret = "sa-signing-key"
+	kubeconfigKey    = "kubeconfig"
+)
+
+// kubeconfigSecrets are the kubeconfigs of the control plane namespace that clients of the
+// hosted cluster and the HyperShift components bootstrap from.
+var kubeconfigSecrets = []string{"admin-kubeconfig", "service-network-admin-kubeconfig"}
+
+// Result is the outcome of the check of a restored Secret.
+type Result struct {
+	// Check names the Secret and its role, e.g. pull secret clusters/pull-secret.
+	Check   string
+	Passed  bool
+	Message string
+}
+
+func (r Result) String() string {
+	status := "PASS"
+	if !r.Passed {
+		status = "FAIL"
+	}
+	if r.Message == "" {
+		return fmt.Sprintf("%s %s", status, r.Check)
+	}
+	return fmt.Sprintf("%s %s: %s", status, r.Check, r.Message)
+}
+
+// Verify checks that the critical Secrets of the restored HostedCluster exist and are well
+// formed: the pull secret, the SSH key, the service account signing keys and the admin
+// kubeconfigs, whose certificates must not have expired at now.
+func Verify(ctx context.Context, c crclient.Client, hc *hyperv1.HostedCluster, now time.Time) []Result {
+	hcpNamespace := common.GetHCPNamespace(hc.Name, hc.Namespace)
+	results := []Result{
+		check(ctx, c, "pull secret", hc.Namespace, hc.Spec.PullSecret.Name, checkPullSecret),
+	}
+	if hc.Spec.SSHKey.Name != "" {
+		results = append(results, check(ctx, c, "ssh key", hc.Namespace, hc.Spec.SSHKey.Name, checkSSHKey))
+	}
+	if hc.Spec.ServiceAccountSigningKey != nil {
+		results = append(results, check(ctx, c, "signing key", hc.Namespace, hc.Spec.ServiceAccountSigningKey.Name, func(secret *corev1.Secret) (string, error) {
+			return "", checkPrivateKey(secret.Data, "key")
+		}))
+	}
+	results = append(results, check(ctx, c, "signing key", hcpNamespace, signingKeySecret, checkSigningKeys))
+	for _, name := range kubeconfigSecrets {
+		results = append(results, check(ctx, c, "kubeconfig", hcpNamespace, name, func(secret *corev1.Secret) (string, error) {
+			return checkKubeconfig(secret.Data[kubeconfigKey], now)
+		}))
+	}
+	return results
+}
+
+// check gets the Secret and runs the check on it. A passing check may return a message.
+func check(ctx context.Context, c crclient.Client, role, namespace, name string, fn func(*corev1.Secret) (string, error)) Result {
+	result := Result{Check: fmt.Sprintf("%s %s/%s", role, namespace, name)}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, crclient.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			result.Message = "not found"
+		} else {
+			result.Message = err.Error()
+		}
+		return result
+	}
+	message, err := fn(secret)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Passed, result.Message = true, message
+	return result
+}
+
+func checkPullSecret(secret *corev1.Secret) (string, error) {
+	data, ok := secret.Data[corev1.DockerConfigJsonKey]
+	if !ok {
+		return "", fmt.Errorf("missing key %s", corev1.DockerConfigJsonKey)
+	}
+	var config struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", fmt.Errorf("invalid %s: %w", corev1.DockerConfigJsonKey, err)
+	}
+	if len(config.Auths) == 0 {
+		return "", fmt.Errorf("%s has no registry credentials", corev1.DockerConfigJsonKey)
+	}
+	return "", nil
+}
+
+// checkSSHKey checks every line of the key is an authorized key: a type and a base64 blob.
+func checkSSHKey(secret *corev1.Secret) (string, error) {
+	data := strings.TrimSpace(string(secret.Data["id_rsa.pub"]))
+	if data == "" {
+		return "", errors.New("missing key id_rsa.pub")
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return "", fmt.Errorf("invalid public key %q", line)
+		}
+		if _, err := base64.StdEncoding.DecodeString(fields[1]); err != nil {
+			return "", fmt.Errorf("invalid %s public key: %w", fields[0], err)
+		}
+	}
+	return "", nil
+}
+
+func checkSigningKeys(secret *corev1.Secret) (string, error) {
+	if err := checkPrivateKey(secret.Data, "service-account.key"); err != nil {
+		return "", err
+	}
+	block, _ := pem.Decode(secret.Data["service-account.pub"])
+	if block == nil {
+		return "", errors.New("service-account.pub is not a PEM public key")
+	}
+	if _, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+		if _, err := x509.ParsePKCS1PublicKey(block.Bytes); err != nil {
+			return "", fmt.Errorf("invalid service-account.pub: %w", err)
+		}
+	}
+	return "", nil
+}
+
+func checkPrivateKey(data map[string][]byte, key string) error {
+	block, _ := pem.Decode(data[key])
+	if block == nil {
+		return fmt.Errorf("%s is not a PEM private key", key)
+	}
+	if _, err := x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		if _, err := x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// checkKubeconfig parses the kubeconfig and checks its embedded CA and client certificates
+// are valid at now. It returns the earliest certificate expiry.
+func checkKubeconfig(data []byte, now time.Time) (string, error) {
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	if len(config.Clusters) == 0 || len(config.AuthInfos) == 0 {
+		return "", errors.New("kubeconfig has no cluster or user")
+	}
+	var pemData [][]byte
+	for _, cluster := range config.Clusters {
+		pemData = append(pemData, cluster.CertificateAuthorityData)
+	}
+	for _, user := range config.AuthInfos {
+		pemData = append(pemData, user.ClientCertificateData)
+	}
+
+	var earliest *x509.Certificate
+	for _, data := range pemData {
+		certs, err := ParseCertificates(data)
+		if err != nil {
+			return "", err
+		}
+		for _, cert := range certs {
+			if now.After(cert.NotAfter) {
+				return "", fmt.Errorf("certificate %s expired on %s", cert.Subject.CommonName, cert.NotAfter.UTC().Format(time.RFC3339))
+			}
+			if earliest == nil || cert.NotAfter.Before(earliest.NotAfter) {
+				earliest = cert
+			}
+		}
+	}
+	if earliest == nil {
+		return "", nil
+	}
+	return fmt.Sprintf("certificates valid until %s", earliest.NotAfter.UTC().Format(time.RFC3339)), nil
+}
+
+// ParseCertificates parses the PEM encoded certificates in data, skipping other blocks
+// such as private keys.
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// Expiry is a certificate of a Secret that has expired or expires soon.
+type Expiry struct {
+	// Key is the Secret key holding the certificate, e.g. tls.crt.
+	Key      string
+	Subject  string
+	NotAfter time.Time
+	Expired  bool
+}
+
+func (e Expiry) String() string {
+	verb := "expires"
+	if e.Expired {
+		verb = "expired"
+	}
+	return fmt.Sprintf("%s certificate %s %s on %s", e.Key, e.Subject, verb, e.NotAfter.UTC().Format(time.RFC3339))
+}
+
+// ExpiringCertificates returns the certificates of the Secret keys ending in .crt, like
+// tls.crt and ca.crt, that have expired at now or expire within threshold, by key.
+func ExpiringCertificates(data map[string][]byte, now time.Time, threshold time.Duration) ([]Expiry, error) {
+	var expiring []Expiry
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		if !strings.HasSuffix(key, ".crt") {
+			continue
+		}
+		certs, err := ParseCertificates(data[key])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		for _, cert := range certs {
+			if now.Add(threshold).Before(cert.NotAfter) {
+				continue
+			}
+			expiring = append(expiring, Expiry{Key: key, Subject: cert.Subject.CommonName, NotAfter: cert.NotAfter, Expired: now.After(cert.NotAfter)})
+		}
+	}
+	return expiring, nil
+}
+
+// Passed reports whether every check passed.
+func Passed(results []Result) bool {
+	for _, r := range results {
+		if !r.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Save stores the results in a ConfigMap next to the Restore and owned by it, so it is
+// deleted with the Restore. A report saved earlier, e.g. by a previous run, is replaced.
+func Save(ctx context.Context, c crclient.Client, restore *velerov1.Restore, results []Result) (string, error) {
+	lines := make([]string, 0, len(results))
+	for _, r := range results {
+		lines = append(lines, r.String())
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      label.GetValidName(ConfigMapPrefix + restore.Name),
+			Namespace: restore.Namespace,
+			Labels:    map[string]string{velerov1.RestoreNameLabel: label.GetValidName(restore.Name)},
+		},
+		Data: map[string]string{
+			ResultsKey: strings.Join(lines, "\n") + "\n",
+			PassedKey:  fmt.Sprint(Passed(results)),
+		},
+	}
+	if restore.UID != "" {
+		cm.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: velerov1.SchemeGroupVersion.String(),
+			Kind:       "Restore",
+			Name:       restore.Name,
+			UID:        restore.UID,
+		}}
+	}
+
+	if err := c.Create(ctx, cm); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return "", fmt.Errorf("error creating restore report ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+		}
+		if err := c.Update(ctx, cm); err != nil {
+			return "", fmt.Errorf("error updating restore report ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+		}
+	}
+	return cm.Name, nil
+}
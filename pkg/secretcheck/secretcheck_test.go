@@ -0,0 +1,179 @@
+package secretcheck
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var now = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+func newCertificate(t *testing.T, name string, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func newKubeconfig(t *testing.T, clientNotAfter time.Time) []byte {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["cluster"] = &clientcmdapi.Cluster{Server: "https://kube-apiserver:6443", CertificateAuthorityData: newCertificate(t, "root-ca", now.Add(10*365*24*time.Hour))}
+	config.AuthInfos["admin"] = &clientcmdapi.AuthInfo{ClientCertificateData: newCertificate(t, "system:admin", clientNotAfter)}
+	config.Contexts["admin"] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: "admin"}
+	config.CurrentContext = "admin"
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func newSigningKeys(t *testing.T) map[string][]byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string][]byte{
+		"service-account.key": pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		"service-account.pub": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}),
+	}
+}
+
+func newSecret(namespace, name string, data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}, Data: data}
+}
+
+func TestVerify(t *testing.T) {
+	hc := &hyperv1.HostedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-hc", Namespace: "clusters"},
+		Spec: hyperv1.HostedClusterSpec{
+			PullSecret: corev1.LocalObjectReference{Name: "pull-secret"},
+			SSHKey:     corev1.LocalObjectReference{Name: "ssh-key"},
+		},
+	}
+	pullSecret := newSecret("clusters", "pull-secret", map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"quay.io":{"auth":"dXNlcjpwYXNz"}}}`)})
+	sshKey := newSecret("clusters", "ssh-key", map[string][]byte{"id_rsa.pub": []byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl user@host\n")})
+	signingKey := newSecret("clusters-my-hc", "sa-signing-key", newSigningKeys(t))
+	valid := newKubeconfig(t, now.Add(30*24*time.Hour))
+
+	t.Run("When the Secrets are well formed, It Should pass every check", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(pullSecret, sshKey, signingKey,
+			newSecret("clusters-my-hc", "admin-kubeconfig", map[string][]byte{"kubeconfig": valid}),
+			newSecret("clusters-my-hc", "service-network-admin-kubeconfig", map[string][]byte{"kubeconfig": valid}),
+		).Build()
+
+		results := Verify(context.TODO(), c, hc, now)
+		g.Expect(results).To(HaveLen(5))
+		g.Expect(Passed(results)).To(BeTrue(), "%v", results)
+		g.Expect(results[3].String()).To(Equal("PASS kubeconfig clusters-my-hc/admin-kubeconfig: certificates valid until 2026-10-31T00:00:00Z"))
+	})
+
+	t.Run("When Secrets are missing, malformed or expired, It Should fail those checks", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(sshKey, signingKey,
+			newSecret("clusters-my-hc", "admin-kubeconfig", map[string][]byte{"kubeconfig": newKubeconfig(t, now.Add(-time.Hour))}),
+			newSecret("clusters-my-hc", "service-network-admin-kubeconfig", map[string][]byte{"kubeconfig": []byte("not a kubeconfig")}),
+		).Build()
+
+		results := Verify(context.TODO(), c, hc, now)
+		g.Expect(Passed(results)).To(BeFalse())
+		g.Expect(results[0].String()).To(Equal("FAIL pull secret clusters/pull-secret: not found"))
+		g.Expect(results[1].Passed).To(BeTrue())
+		g.Expect(results[2].Passed).To(BeTrue())
+		g.Expect(results[3].String()).To(Equal("FAIL kubeconfig clusters-my-hc/admin-kubeconfig: certificate system:admin expired on 2026-09-30T23:00:00Z"))
+		g.Expect(results[4].Message).To(HavePrefix("invalid kubeconfig"))
+	})
+}
+
+func TestCheckSecrets(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := checkPullSecret(newSecret("clusters", "pull-secret", map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)}))
+	g.Expect(err).To(MatchError(ContainSubstring("no registry credentials")))
+
+	_, err = checkSSHKey(newSecret("clusters", "ssh-key", map[string][]byte{"id_rsa.pub": []byte("ssh-rsa not-base64!")}))
+	g.Expect(err).To(MatchError(HavePrefix("invalid ssh-rsa public key")))
+
+	keys := newSigningKeys(t)
+	keys["service-account.key"] = []byte("garbage")
+	_, err = checkSigningKeys(newSecret("clusters-my-hc", "sa-signing-key", keys))
+	g.Expect(err).To(MatchError("service-account.key is not a PEM private key"))
+}
+
+func TestSave(t *testing.T) {
+	g := NewWithT(t)
+	restore := &velerov1.Restore{ObjectMeta: metav1.ObjectMeta{Name: "my-restore", Namespace: "openshift-adp", UID: "restore-uid"}}
+	c := fake.NewClientBuilder().WithScheme(common.CustomScheme).Build()
+
+	_, err := Save(context.TODO(), c, restore, []Result{{Check: "pull secret clusters/pull-secret", Message: "not found"}})
+	g.Expect(err).NotTo(HaveOccurred())
+	// A second run replaces the report
+	name, err := Save(context.TODO(), c, restore, []Result{{Check: "pull secret clusters/pull-secret", Passed: true}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(name).To(Equal("hcp-restore-report-my-restore"))
+
+	cm := &corev1.ConfigMap{}
+	g.Expect(c.Get(context.TODO(), crclient.ObjectKey{Namespace: "openshift-adp", Name: name}, cm)).To(Succeed())
+	g.Expect(cm.Data).To(Equal(map[string]string{ResultsKey: "PASS pull secret clusters/pull-secret\n", PassedKey: "true"}))
+	g.Expect(cm.OwnerReferences).To(HaveLen(1))
+	g.Expect(cm.OwnerReferences[0].Kind).To(Equal("Restore"))
+}
+
+func TestExpiringCertificates(t *testing.T) {
+	g := NewWithT(t)
+	chain := append(newCertificate(t, "kube-apiserver", now.Add(24*time.Hour)), newCertificate(t, "root-ca", now.Add(365*24*time.Hour))...)
+	data := map[string][]byte{
+		"ca.crt":  newCertificate(t, "root-ca", now.Add(-time.Hour)),
+		"tls.crt": chain,
+		"tls.key": []byte("not a certificate"),
+	}
+
+	expiring, err := ExpiringCertificates(data, now, DefaultExpiryThreshold)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(expiring).To(HaveLen(2))
+	g.Expect(expiring[0].String()).To(Equal("ca.crt certificate root-ca expired on 2026-09-30T23:00:00Z"))
+	g.Expect(expiring[1].String()).To(Equal("tls.crt certificate kube-apiserver expires on 2026-10-02T00:00:00Z"))
+
+	// A shorter threshold only reports the expired certificate
+	expiring, err = ExpiringCertificates(data, now, time.Hour)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(expiring).To(HaveLen(1))
+
+	_, err = ExpiringCertificates(map[string][]byte{"ca.crt": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")})}, now, time.Hour)
+	g.Expect(err).To(MatchError(HavePrefix("ca.crt: invalid certificate")))
+}
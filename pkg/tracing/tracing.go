@@ -0,0 +1,104 @@
+// Package tracing instruments the plugin with OpenTelemetry spans, so operators can see
+// where a hosted control plane backup or restore spends its time.
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"os"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// ServiceName is the service.name resource attribute of the exported spans.
+	ServiceName = "hypershift-oadp-plugin"
+
+	tracerName = "github.com/openshift/hypershift-oadp-plugin"
+)
+
+var (
+	setupOnce sync.Once
+	setupErr  error
+)
+
+// Setup installs an OTLP/HTTP exporter as the global tracer provider. The endpoint is an
+// URL such as http://otel-collector.observability:4318; when it is empty, the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_TRACES_ENDPOINT variables are used.
+// When neither is set tracing stays disabled and spans cost nothing. Backup and restore
+// plugins share the process, so only the first call has an effect.
+func Setup(ctx context.Context, endpoint string) error {
+	setupOnce.Do(func() {
+		if endpoint == "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+			return
+		}
+
+		var opts []otlptracehttp.Option
+		if endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			setupErr = err
+			return
+		}
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", ServiceName))),
+		))
+	})
+	return setupErr
+}
+
+// Start starts a span named after the operation, as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error, if any, on the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Flush exports the ended spans. The plugin process may be killed once Velero is done
+// with it, so Execute flushes before returning instead of relying on the batch timer.
+func Flush(ctx context.Context) {
+	if tp, ok := otel.GetTracerProvider().(interface{ ForceFlush(context.Context) error }); ok {
+		_ = tp.ForceFlush(ctx)
+	}
+}
+
+// WithBackupTrace returns a context whose parent span is derived from the UID of the
+// Velero Backup or Restore. Velero calls Execute once per item, in a plugin process that
+// may restart, so every call starts a root span; sharing the trace ID puts all of them in
+// one trace per backup.
+func WithBackupTrace(ctx context.Context, uid types.UID) context.Context {
+	raw, err := hex.DecodeString(strings.ReplaceAll(string(uid), "-", ""))
+	if err != nil || len(raw) != len(trace.TraceID{}) {
+		return ctx
+	}
+	var traceID trace.TraceID
+	copy(traceID[:], raw)
+	// The span ID only has to be valid; the parent span is never exported
+	spanID := trace.SpanID{}
+	copy(spanID[:], raw[len(raw)-len(spanID):])
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+}
@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestWithBackupTrace(t *testing.T) {
+	g := NewWithT(t)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	uid := types.UID("0f8e3c52-6a3b-4c1d-9e2f-7a1b2c3d4e5f")
+	for _, item := range []string{"HostedCluster clusters/hc", "Secret clusters/pull-secret"} {
+		_, span := Start(WithBackupTrace(context.TODO(), uid), "BackupPlugin.Execute", attribute.String("item", item))
+		End(span, nil)
+	}
+	_, span := Start(WithBackupTrace(context.TODO(), uid), "BackupPlugin.Execute")
+	End(span, errors.New("error getting HostedControlPlane"))
+
+	spans := recorder.Ended()
+	g.Expect(spans).To(HaveLen(3))
+	// Every Execute call of the backup lands in the trace named after its UID
+	for _, span := range spans {
+		g.Expect(span.SpanContext().TraceID().String()).To(Equal("0f8e3c526a3b4c1d9e2f7a1b2c3d4e5f"))
+	}
+	g.Expect(spans[0].Status().Code).To(Equal(codes.Unset))
+	g.Expect(spans[2].Status().Code).To(Equal(codes.Error))
+	g.Expect(spans[2].Events()).To(HaveLen(1))
+
+	// Without a valid UID, e.g. in unit tests, the span starts its own trace
+	g.Expect(trace.SpanContextFromContext(WithBackupTrace(context.TODO(), "")).IsValid()).To(BeFalse())
+}
@@ -0,0 +1,69 @@
+package volumebackup
+
+import (
+	"context"
+	"fmt"
+
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	velerov2alpha1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v2alpha1"
+	"github.com/vmware-tanzu/velero/pkg/label"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	KindDataDownload     = "DataDownload"
+	KindPodVolumeRestore = "PodVolumeRestore"
+)
+
+// GetRestore returns the volume restores Velero created for the restore in its namespace, the
+// DataDownloads of the CSI data mover and the PodVolumeRestores of fs-backup, one per PVC. Restores running next to it in
+// the Velero namespace create their own: only the objects of this Restore count, so it
+// neither waits on nor fails because of unrelated ones.
+func GetRestore(ctx context.Context, c crclient.Client, veleroNamespace string, restore *velerov1.Restore) (Progress, error) {
+	selector := crclient.MatchingLabelsSelector{Selector: label.NewSelectorForRestore(restore.Name)}
+	volumes := newCollector()
+
+	downloads := &velerov2alpha1.DataDownloadList{}
+	if err := c.List(ctx, downloads, crclient.InNamespace(veleroNamespace), selector); err != nil {
+		return Progress{}, fmt.Errorf("error listing the DataDownloads of restore %s: %w", restore.Name, err)
+	}
+	for i := range downloads.Items {
+		dd := &downloads.Items[i]
+		if !common.BelongsToRestore(dd, restore) {
+			continue
+		}
+		volumes.add(Volume{
+			PVC:     dd.Spec.TargetVolume.Namespace + "/" + dd.Spec.TargetVolume.PVC,
+			Kind:    KindDataDownload,
+			Name:    dd.Name,
+			Phase:   string(dd.Status.Phase),
+			Message: dd.Status.Message,
+		}, dd)
+	}
+
+	podVolumeRestores := &velerov1.PodVolumeRestoreList{}
+	if err := c.List(ctx, podVolumeRestores, crclient.InNamespace(veleroNamespace), selector); err != nil {
+		return Progress{}, fmt.Errorf("error listing the PodVolumeRestores of restore %s: %w", restore.Name, err)
+	}
+	for i := range podVolumeRestores.Items {
+		pvr := &podVolumeRestores.Items[i]
+		if !common.BelongsToRestore(pvr, restore) {
+			continue
+		}
+		pvc, err := podVolumeClaim(ctx, c, pvr.Spec.Pod, pvr.Spec.Volume)
+		if err != nil {
+			return Progress{}, err
+		}
+		volumes.add(Volume{
+			PVC:     pvc,
+			Kind:    KindPodVolumeRestore,
+			Name:    pvr.Name,
+			Phase:   string(pvr.Status.Phase),
+			Message: pvr.Status.Message,
+		}, pvr)
+	}
+	progress := volumes.progress(nil)
+	progress.restore = true
+	return progress, nil
+}
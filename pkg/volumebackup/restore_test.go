@@ -0,0 +1,77 @@
+package volumebackup
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	velerov2alpha1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v2alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newDataDownload(name, restore, uid, pvc string, phase velerov2alpha1.DataDownloadPhase) *velerov2alpha1.DataDownload {
+	return &velerov2alpha1.DataDownload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: "openshift-adp",
+			Labels: map[string]string{velerov1.RestoreNameLabel: restore, velerov1.RestoreUIDLabel: uid},
+		},
+		Spec:   velerov2alpha1.DataDownloadSpec{TargetVolume: velerov2alpha1.TargetVolumeSpec{Namespace: "clusters-hc", PVC: pvc}},
+		Status: velerov2alpha1.DataDownloadStatus{Phase: phase},
+	}
+}
+
+func TestGetRestore(t *testing.T) {
+	restore := &velerov1.Restore{ObjectMeta: metav1.ObjectMeta{Name: "dr", Namespace: "openshift-adp", UID: "restore-uid"}}
+	tests := []struct {
+		name     string
+		objects  []crclient.Object
+		volumes  []string
+		finished bool
+		progress string
+	}{
+		{
+			name: "When the etcd volumes are still downloading, It Should not be finished",
+			objects: []crclient.Object{
+				newDataDownload("dr-a", "dr", "restore-uid", "data-etcd-0", velerov2alpha1.DataDownloadPhaseCompleted),
+				newDataDownload("dr-b", "dr", "restore-uid", "data-etcd-1", velerov2alpha1.DataDownloadPhaseInProgress),
+			},
+			volumes:  []string{"clusters-hc/data-etcd-0", "clusters-hc/data-etcd-1"},
+			progress: "1/2 volumes restored",
+		},
+		{
+			name: "When other restores run in the namespace, It Should ignore their volume restores",
+			objects: []crclient.Object{
+				newDataDownload("dr-a", "dr", "restore-uid", "data-etcd-0", velerov2alpha1.DataDownloadPhaseCompleted),
+				newDataDownload("other-a", "other", "other-uid", "data-etcd-1", velerov2alpha1.DataDownloadPhaseInProgress),
+				newDataDownload("dr-b", "dr", "deleted-restore-uid", "data-etcd-2", velerov2alpha1.DataDownloadPhaseFailed),
+				&velerov1.PodVolumeRestore{ObjectMeta: metav1.ObjectMeta{
+					Name: "other-p", Namespace: "openshift-adp", Labels: map[string]string{velerov1.RestoreNameLabel: "other"},
+				}},
+			},
+			volumes:  []string{"clusters-hc/data-etcd-0"},
+			finished: true,
+			progress: "1/1 volumes restored",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(tt.objects...).Build()
+
+			progress, err := GetRestore(context.TODO(), c, "openshift-adp", restore)
+			g.Expect(err).NotTo(HaveOccurred())
+			volumes := []string{}
+			for _, v := range progress.Volumes {
+				volumes = append(volumes, v.PVC)
+			}
+			g.Expect(volumes).To(HaveExactElements(tt.volumes))
+			g.Expect(progress.Finished()).To(Equal(tt.finished))
+			g.Expect(progress.String()).To(Equal(tt.progress))
+		})
+	}
+}
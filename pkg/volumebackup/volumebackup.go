@@ -0,0 +1,341 @@
+// Package volumebackup accounts for the volume backups of a Velero backup, the DataUploads
+// of the CSI data mover and the PodVolumeBackups of fs-backup, one per PVC, so a backup of a
+// highly available control plane is only considered done when the volumes of every member
+// are.
+package volumebackup
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v8/apis/volumesnapshot/v1"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	velerov2alpha1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v2alpha1"
+	"github.com/vmware-tanzu/velero/pkg/label"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	KindDataUpload      = "DataUpload"
+	KindPodVolumeBackup = "PodVolumeBackup"
+
+	// nodeAgentPodLabel selects the pods of the node-agent DaemonSet running the data mover
+	nodeAgentPodLabel = "name"
+	nodeAgent         = "node-agent"
+
+	// etcdStatefulSet runs the etcd members of a control plane with managed etcd
+	etcdStatefulSet = "etcd"
+
+	// Terminal phases, shared by DataUploads and PodVolumeBackups
+	PhaseCompleted = "Completed"
+	PhaseFailed    = "Failed"
+	PhaseCanceled  = "Canceled"
+)
+
+// Volume is the backup of a PVC by a DataUpload or a PodVolumeBackup, or its restore by a
+// DataDownload or a PodVolumeRestore. The Kind of an expected PVC that no volume backup was
+// created for yet is empty.
+type Volume struct {
+	// PVC is the namespace/name of the PVC, or namespace/pod/volume when the pod of a
+	// PodVolumeBackup is gone.
+	PVC     string
+	Kind    string
+	Name    string
+	Phase   string
+	Message string
+	// Details of a failed DataUpload: the state of the node-agent pod that ran it and of
+	// the VolumeSnapshotContent it uploaded.
+	Details []string
+}
+
+// Finished reports whether the volume backup reached a terminal phase.
+func (v Volume) Finished() bool {
+	return v.Phase == PhaseCompleted || v.Phase == PhaseFailed || v.Phase == PhaseCanceled
+}
+
+func (v Volume) String() string {
+	if v.Kind == "" {
+		return fmt.Sprintf("PVC %s not backed up yet", v.PVC)
+	}
+	phase := v.Phase
+	if phase == "" {
+		phase = "New"
+	}
+	s := fmt.Sprintf("PVC %s (%s %s) %s", v.PVC, v.Kind, v.Name, phase)
+	if v.Message != "" {
+		s += ": " + v.Message
+	}
+	if len(v.Details) > 0 {
+		s += " (" + strings.Join(v.Details, "; ") + ")"
+	}
+	return s
+}
+
+// Progress is the state of the volume backups of a Velero backup, or of the volume restores
+// of a Velero restore, one per PVC.
+type Progress struct {
+	Volumes []Volume
+	// restore tells the volume restores of a Velero restore apart
+	restore bool
+}
+
+// Finished reports whether every volume backup reached a terminal phase. A single volume
+// backup completing does not finish the others.
+func (p Progress) Finished() bool {
+	for _, v := range p.Volumes {
+		if !v.Finished() {
+			return false
+		}
+	}
+	return true
+}
+
+// Completed counts the volume backups that completed.
+func (p Progress) Completed() int {
+	completed := 0
+	for _, v := range p.Volumes {
+		if v.Phase == PhaseCompleted {
+			completed++
+		}
+	}
+	return completed
+}
+
+// Missing returns the expected PVCs that no volume backup was created for.
+func (p Progress) Missing() []string {
+	missing := []string{}
+	for _, v := range p.Volumes {
+		if v.Kind == "" {
+			missing = append(missing, v.PVC)
+		}
+	}
+	return missing
+}
+
+// Unsuccessful returns the volume backups that failed or were canceled.
+func (p Progress) Unsuccessful() []Volume {
+	unsuccessful := []Volume{}
+	for _, v := range p.Volumes {
+		if v.Finished() && v.Phase != PhaseCompleted {
+			unsuccessful = append(unsuccessful, v)
+		}
+	}
+	return unsuccessful
+}
+
+func (p Progress) String() string {
+	if p.restore {
+		return fmt.Sprintf("%d/%d volumes restored", p.Completed(), len(p.Volumes))
+	}
+	return fmt.Sprintf("%d/%d volumes backed up", p.Completed(), len(p.Volumes))
+}
+
+// ExpectedPVCs returns the namespace/name of the PVCs a backup of the control plane namespace
+// has to back up, known before Velero creates any volume backup: the data PVC of every etcd
+// member. A control plane without managed etcd has none.
+func ExpectedPVCs(ctx context.Context, c crclient.Client, hcpNamespace string) ([]string, error) {
+	sts := &appsv1.StatefulSet{}
+	if err := c.Get(ctx, crclient.ObjectKey{Namespace: hcpNamespace, Name: etcdStatefulSet}, sts); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting the etcd StatefulSet of %s: %w", hcpNamespace, err)
+	}
+	replicas := 1
+	if sts.Spec.Replicas != nil {
+		replicas = int(*sts.Spec.Replicas)
+	}
+	expected := []string{}
+	for i := range replicas {
+		expected = append(expected, hcpNamespace+"/"+common.EtcdPVCPrefix+strconv.Itoa(i))
+	}
+	return expected, nil
+}
+
+// Get returns the volume backups Velero created for the backup in its namespace, and the
+// expected PVCs without one as not started, so the backup is only finished once each of
+// them is. Objects left by a deleted backup of the same name are ignored and, when a PVC was
+// backed up more than once, its latest volume backup counts.
+func Get(ctx context.Context, c crclient.Client, veleroNamespace string, backup *velerov1.Backup, expected []string) (Progress, error) {
+	selector := crclient.MatchingLabelsSelector{Selector: label.NewSelectorForBackup(backup.Name)}
+	volumes := newCollector()
+	uploads := &velerov2alpha1.DataUploadList{}
+	if err := c.List(ctx, uploads, crclient.InNamespace(veleroNamespace), selector); err != nil {
+		return Progress{}, fmt.Errorf("error listing the DataUploads of backup %s: %w", backup.Name, err)
+	}
+	for i := range uploads.Items {
+		du := &uploads.Items[i]
+		if !common.BelongsToBackup(du, backup) {
+			continue
+		}
+		volume := Volume{
+			PVC:     du.Spec.SourceNamespace + "/" + du.Spec.SourcePVC,
+			Kind:    KindDataUpload,
+			Name:    du.Name,
+			Phase:   string(du.Status.Phase),
+			Message: du.Status.Message,
+		}
+		if du.Status.Phase == velerov2alpha1.DataUploadPhaseFailed {
+			volume.Details = uploadFailureDetails(ctx, c, du)
+		}
+		volumes.add(volume, du)
+	}
+
+	podVolumeBackups := &velerov1.PodVolumeBackupList{}
+	if err := c.List(ctx, podVolumeBackups, crclient.InNamespace(veleroNamespace), selector); err != nil {
+		return Progress{}, fmt.Errorf("error listing the PodVolumeBackups of backup %s: %w", backup.Name, err)
+	}
+	for i := range podVolumeBackups.Items {
+		pvb := &podVolumeBackups.Items[i]
+		if !common.BelongsToBackup(pvb, backup) {
+			continue
+		}
+		pvc, err := podVolumeClaim(ctx, c, pvb.Spec.Pod, pvb.Spec.Volume)
+		if err != nil {
+			return Progress{}, err
+		}
+		volumes.add(Volume{
+			PVC:     pvc,
+			Kind:    KindPodVolumeBackup,
+			Name:    pvb.Name,
+			Phase:   string(pvb.Status.Phase),
+			Message: pvb.Status.Message,
+		}, pvb)
+	}
+	return volumes.progress(expected), nil
+}
+
+// collector gathers the volume backups or restores, keeping the latest one of each PVC.
+type collector struct {
+	volumes []Volume
+	index   map[string]int
+	created map[string]metav1.Time
+}
+
+func newCollector() *collector {
+	return &collector{index: map[string]int{}, created: map[string]metav1.Time{}}
+}
+
+func (c *collector) add(v Volume, obj crclient.Object) {
+	i, ok := c.index[v.PVC]
+	if !ok {
+		c.index[v.PVC] = len(c.volumes)
+		c.volumes = append(c.volumes, v)
+		c.created[v.PVC] = obj.GetCreationTimestamp()
+		return
+	}
+	previous := c.created[v.PVC]
+	if timestamp := obj.GetCreationTimestamp(); !timestamp.Before(&previous) {
+		c.volumes[i] = v
+		c.created[v.PVC] = timestamp
+	}
+}
+
+// progress returns the collected volumes and the expected PVCs missing from them, by PVC.
+func (c *collector) progress(expected []string) Progress {
+	volumes := slices.Clone(c.volumes)
+	for _, pvc := range expected {
+		if _, ok := c.index[pvc]; !ok {
+			volumes = append(volumes, Volume{PVC: pvc})
+		}
+	}
+	slices.SortFunc(volumes, func(a, b Volume) int { return strings.Compare(a.PVC, b.PVC) })
+	return Progress{Volumes: volumes}
+}
+
+// podVolumeClaim returns the namespace/name of the PVC backing the volume of a pod, or
+// namespace/pod/volume when the pod or the volume is gone.
+func podVolumeClaim(ctx context.Context, c crclient.Client, podRef corev1.ObjectReference, volumeName string) (string, error) {
+	key := crclient.ObjectKey{Namespace: podRef.Namespace, Name: podRef.Name}
+	fallback := key.String() + "/" + volumeName
+	pod := &corev1.Pod{}
+	if err := c.Get(ctx, key, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fallback, nil
+		}
+		return "", fmt.Errorf("error getting pod %s: %w", key, err)
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == volumeName && volume.PersistentVolumeClaim != nil {
+			return pod.Namespace + "/" + volume.PersistentVolumeClaim.ClaimName, nil
+		}
+	}
+	return fallback, nil
+}
+
+// uploadFailureDetails describes the node-agent pod that ran a failed DataUpload and the
+// VolumeSnapshotContent it uploaded, the usual places the root cause of a failure shows.
+// What cannot be read is described too rather than failing.
+func uploadFailureDetails(ctx context.Context, c crclient.Client, du *velerov2alpha1.DataUpload) []string {
+	details := []string{}
+	if node := cmp.Or(du.Status.Node, du.Status.AcceptedByNode); node != "" {
+		details = append(details, nodeAgentState(ctx, c, du.Namespace, node))
+	}
+	if du.Spec.CSISnapshot != nil && du.Spec.CSISnapshot.VolumeSnapshot != "" {
+		details = append(details, snapshotContentState(ctx, c, du.Spec.SourceNamespace, du.Spec.CSISnapshot.VolumeSnapshot))
+	}
+	return details
+}
+
+// nodeAgentState describes the readiness of the node-agent pod of the node.
+func nodeAgentState(ctx context.Context, c crclient.Client, namespace, node string) string {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, crclient.InNamespace(namespace), crclient.MatchingLabels{nodeAgentPodLabel: nodeAgent}); err != nil {
+		return fmt.Sprintf("error listing the %s pods: %v", nodeAgent, err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != node {
+			continue
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady {
+				state := fmt.Sprintf("%s pod %s on node %s Ready=%s", nodeAgent, pod.Name, node, condition.Status)
+				if condition.Status != corev1.ConditionTrue && condition.Message != "" {
+					state += ": " + condition.Message
+				}
+				return state
+			}
+		}
+		return fmt.Sprintf("%s pod %s on node %s is %s", nodeAgent, pod.Name, node, pod.Status.Phase)
+	}
+	return fmt.Sprintf("no %s pod on node %s", nodeAgent, node)
+}
+
+// snapshotContentState describes the VolumeSnapshotContent bound to the VolumeSnapshot.
+func snapshotContentState(ctx context.Context, c crclient.Client, namespace, name string) string {
+	vs := &snapshotv1.VolumeSnapshot{}
+	if err := c.Get(ctx, crclient.ObjectKey{Namespace: namespace, Name: name}, vs); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("VolumeSnapshot %s/%s not found", namespace, name)
+		}
+		return fmt.Sprintf("error getting VolumeSnapshot %s/%s: %v", namespace, name, err)
+	}
+	if vs.Status == nil || vs.Status.BoundVolumeSnapshotContentName == nil {
+		return fmt.Sprintf("VolumeSnapshot %s/%s is not bound to a VolumeSnapshotContent", namespace, name)
+	}
+	vscName := *vs.Status.BoundVolumeSnapshotContentName
+	vsc := &snapshotv1.VolumeSnapshotContent{}
+	if err := c.Get(ctx, crclient.ObjectKey{Name: vscName}, vsc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("VolumeSnapshotContent %s not found", vscName)
+		}
+		return fmt.Sprintf("error getting VolumeSnapshotContent %s: %v", vscName, err)
+	}
+	if vsc.Status == nil {
+		return fmt.Sprintf("VolumeSnapshotContent %s has no status", vscName)
+	}
+	if vsc.Status.Error != nil && vsc.Status.Error.Message != nil {
+		return fmt.Sprintf("VolumeSnapshotContent %s failed: %s", vscName, *vsc.Status.Error.Message)
+	}
+	return fmt.Sprintf("VolumeSnapshotContent %s readyToUse=%t", vscName, ptr.Deref(vsc.Status.ReadyToUse, false))
+}
@@ -0,0 +1,289 @@
+package volumebackup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v8/apis/volumesnapshot/v1"
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	velerov2alpha1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v2alpha1"
+	"github.com/vmware-tanzu/velero/pkg/label"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var created = time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func testBackup() *velerov1.Backup {
+	return &velerov1.Backup{ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: "openshift-adp", UID: "backup-uid"}}
+}
+
+func newDataUpload(name, pvc string, phase velerov2alpha1.DataUploadPhase, uid string, age time.Duration) *velerov2alpha1.DataUpload {
+	return &velerov2alpha1.DataUpload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: "openshift-adp",
+			Labels:            map[string]string{velerov1.BackupNameLabel: "daily", velerov1.BackupUIDLabel: uid},
+			CreationTimestamp: metav1.NewTime(created.Add(-age)),
+		},
+		Spec:   velerov2alpha1.DataUploadSpec{SourceNamespace: "clusters-hc", SourcePVC: pvc},
+		Status: velerov2alpha1.DataUploadStatus{Phase: phase},
+	}
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name         string
+		objects      []crclient.Object
+		expected     []string
+		volumes      []string
+		finished     bool
+		completed    int
+		unsuccessful []string
+	}{
+		{
+			name:     "When the backup has no volume backups, It Should be finished",
+			finished: true,
+		},
+		{
+			name: "When one of the etcd members completed, It Should wait for the others",
+			objects: []crclient.Object{
+				newDataUpload("daily-a", "data-etcd-0", velerov2alpha1.DataUploadPhaseCompleted, "backup-uid", 0),
+				newDataUpload("daily-b", "data-etcd-1", velerov2alpha1.DataUploadPhaseInProgress, "backup-uid", 0),
+				newDataUpload("daily-c", "data-etcd-2", velerov2alpha1.DataUploadPhaseAccepted, "backup-uid", 0),
+			},
+			volumes:   []string{"clusters-hc/data-etcd-0", "clusters-hc/data-etcd-1", "clusters-hc/data-etcd-2"},
+			completed: 1,
+		},
+		{
+			name: "When every member finished, It Should report the failed ones",
+			objects: []crclient.Object{
+				newDataUpload("daily-a", "data-etcd-0", velerov2alpha1.DataUploadPhaseCompleted, "backup-uid", 0),
+				newDataUpload("daily-b", "data-etcd-1", velerov2alpha1.DataUploadPhaseFailed, "backup-uid", 0),
+			},
+			volumes:      []string{"clusters-hc/data-etcd-0", "clusters-hc/data-etcd-1"},
+			finished:     true,
+			completed:    1,
+			unsuccessful: []string{"daily-b"},
+		},
+		{
+			name: "When a PVC was backed up twice, It Should count its latest volume backup",
+			objects: []crclient.Object{
+				newDataUpload("daily-a", "data-etcd-0", velerov2alpha1.DataUploadPhaseCompleted, "backup-uid", 0),
+				newDataUpload("daily-b", "data-etcd-0", velerov2alpha1.DataUploadPhaseFailed, "backup-uid", time.Minute),
+			},
+			volumes:   []string{"clusters-hc/data-etcd-0"},
+			finished:  true,
+			completed: 1,
+		},
+		{
+			name: "When a volume backup belongs to a deleted backup of the same name, It Should be ignored",
+			objects: []crclient.Object{
+				newDataUpload("daily-a", "data-etcd-0", velerov2alpha1.DataUploadPhaseCompleted, "backup-uid", 0),
+				newDataUpload("daily-b", "data-etcd-1", velerov2alpha1.DataUploadPhaseFailed, "deleted-backup-uid", 0),
+			},
+			volumes:   []string{"clusters-hc/data-etcd-0"},
+			finished:  true,
+			completed: 1,
+		},
+		{
+			name: "When a PodVolumeBackup backs up a pod volume, It Should be counted by PVC",
+			objects: []crclient.Object{
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "etcd-0", Namespace: "clusters-hc"},
+					Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+						Name:         "data",
+						VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data-etcd-0"}},
+					}}},
+				},
+				&velerov1.PodVolumeBackup{
+					ObjectMeta: metav1.ObjectMeta{Name: "daily-p", Namespace: "openshift-adp", Labels: map[string]string{velerov1.BackupNameLabel: "daily"}},
+					Spec:       velerov1.PodVolumeBackupSpec{Pod: corev1.ObjectReference{Namespace: "clusters-hc", Name: "etcd-0"}, Volume: "data"},
+					Status:     velerov1.PodVolumeBackupStatus{Phase: velerov1.PodVolumeBackupPhaseInProgress},
+				},
+				&velerov1.PodVolumeBackup{
+					ObjectMeta: metav1.ObjectMeta{Name: "daily-q", Namespace: "openshift-adp", Labels: map[string]string{velerov1.BackupNameLabel: "daily"}},
+					Spec:       velerov1.PodVolumeBackupSpec{Pod: corev1.ObjectReference{Namespace: "clusters-hc", Name: "etcd-1"}, Volume: "data"},
+					Status:     velerov1.PodVolumeBackupStatus{Phase: velerov1.PodVolumeBackupPhaseCompleted},
+				},
+			},
+			volumes:   []string{"clusters-hc/data-etcd-0", "clusters-hc/etcd-1/data"},
+			completed: 1,
+		},
+		{
+			name: "When an expected PVC has no volume backup yet, It Should not be finished",
+			objects: []crclient.Object{
+				newDataUpload("daily-a", "data-etcd-0", velerov2alpha1.DataUploadPhaseCompleted, "backup-uid", 0),
+			},
+			expected:  []string{"clusters-hc/data-etcd-0", "clusters-hc/data-etcd-1"},
+			volumes:   []string{"clusters-hc/data-etcd-0", "clusters-hc/data-etcd-1"},
+			completed: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(tt.objects...).Build()
+
+			progress, err := Get(context.TODO(), c, "openshift-adp", testBackup(), tt.expected)
+			g.Expect(err).NotTo(HaveOccurred())
+			volumes := []string{}
+			for _, v := range progress.Volumes {
+				volumes = append(volumes, v.PVC)
+			}
+			g.Expect(volumes).To(HaveExactElements(tt.volumes))
+			g.Expect(progress.Finished()).To(Equal(tt.finished))
+			g.Expect(progress.Completed()).To(Equal(tt.completed))
+			unsuccessful := []string{}
+			for _, v := range progress.Unsuccessful() {
+				unsuccessful = append(unsuccessful, v.Name)
+			}
+			g.Expect(unsuccessful).To(ConsistOf(tt.unsuccessful))
+		})
+	}
+}
+
+func TestGetScheduledBackups(t *testing.T) {
+	// A Schedule whose runs overlap: the second run starts while the first one still waits
+	// on its DataUploads, and both back up the same PVCs. Velero names each run after the
+	// Schedule and the start time, truncating and hashing the label values of long names.
+	schedule := "hourly-hosted-cluster-backup-of-clusters-hc-on-the-management-cluster"
+	run := func(name, uid string, started time.Time) *velerov1.Backup {
+		return &velerov1.Backup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "openshift-adp", UID: types.UID(uid),
+				Labels: map[string]string{velerov1.ScheduleNameLabel: label.GetValidName(schedule)},
+			},
+			Spec: velerov1.BackupSpec{IncludedNamespaces: []string{"clusters", "clusters-hc"}},
+			Status: velerov1.BackupStatus{
+				Phase:          velerov1.BackupPhaseInProgress,
+				StartTimestamp: &metav1.Time{Time: started},
+			},
+		}
+	}
+	upload := func(name, pvc string, backup *velerov1.Backup, phase velerov2alpha1.DataUploadPhase) *velerov2alpha1.DataUpload {
+		du := newDataUpload(name, pvc, phase, string(backup.UID), 0)
+		du.Labels[velerov1.BackupNameLabel] = label.GetValidName(backup.Name)
+		du.CreationTimestamp = *backup.Status.StartTimestamp
+		return du
+	}
+	first := run(schedule+"-20260101100000", "first-uid", created)
+	second := run(schedule+"-20260101110000", "second-uid", created.Add(time.Hour))
+	objects := []crclient.Object{
+		first, second,
+		upload("first-a", "data-etcd-0", first, velerov2alpha1.DataUploadPhaseCompleted),
+		upload("first-b", "data-etcd-1", first, velerov2alpha1.DataUploadPhaseInProgress),
+		upload("second-a", "data-etcd-0", second, velerov2alpha1.DataUploadPhaseFailed),
+	}
+	expected := []string{"clusters-hc/data-etcd-0", "clusters-hc/data-etcd-1"}
+
+	t.Run("When the runs of a Schedule overlap, It Should count the volume backups of each run only", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(objects...).Build()
+
+		progress, err := Get(context.TODO(), c, "openshift-adp", first, expected)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(progress.Finished()).To(BeFalse())
+		g.Expect(progress.Completed()).To(Equal(1))
+		g.Expect(progress.Unsuccessful()).To(BeEmpty())
+
+		progress, err = Get(context.TODO(), c, "openshift-adp", second, expected)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(progress.Finished()).To(BeFalse())
+		g.Expect(progress.Missing()).To(ConsistOf("clusters-hc/data-etcd-1"))
+		g.Expect(progress.Unsuccessful()).To(HaveLen(1))
+		g.Expect(progress.Unsuccessful()[0].Name).To(Equal("second-a"))
+	})
+
+	t.Run("When the runs of a Schedule overlap, It Should have the later run wait for the earlier one only", func(t *testing.T) {
+		g := NewWithT(t)
+		c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(objects...).Build()
+
+		earlier, err := common.EarlierBackupsOf(context.TODO(), c, second, "clusters-hc")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(earlier).To(ConsistOf("openshift-adp/" + first.Name))
+
+		earlier, err = common.EarlierBackupsOf(context.TODO(), c, first, "clusters-hc")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(earlier).To(BeEmpty())
+	})
+}
+
+func TestExpectedPVCs(t *testing.T) {
+	tests := []struct {
+		name     string
+		objects  []crclient.Object
+		expected []string
+	}{
+		{
+			name: "When etcd runs three members, It Should expect the PVC of each",
+			objects: []crclient.Object{&appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "etcd", Namespace: "clusters-hc"},
+				Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To[int32](3)},
+			}},
+			expected: []string{"clusters-hc/data-etcd-0", "clusters-hc/data-etcd-1", "clusters-hc/data-etcd-2"},
+		},
+		{
+			name: "When the replicas are unset, It Should expect a single member",
+			objects: []crclient.Object{&appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "etcd", Namespace: "clusters-hc"},
+			}},
+			expected: []string{"clusters-hc/data-etcd-0"},
+		},
+		{
+			name: "When etcd is not managed, It Should expect nothing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(tt.objects...).Build()
+
+			expected, err := ExpectedPVCs(context.TODO(), c, "clusters-hc")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(expected).To(ConsistOf(tt.expected))
+		})
+	}
+}
+
+func TestGetFailedUploadDetails(t *testing.T) {
+	g := NewWithT(t)
+	du := newDataUpload("daily-a", "data-etcd-0", velerov2alpha1.DataUploadPhaseFailed, "backup-uid", 0)
+	du.Spec.CSISnapshot = &velerov2alpha1.CSISnapshotSpec{VolumeSnapshot: "velero-data-etcd-0-x7k2p"}
+	du.Status.Message = "error to expose snapshot"
+	du.Status.Node = "worker-1"
+	c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(
+		du,
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-agent-abcde", Namespace: "openshift-adp", Labels: map[string]string{"name": "node-agent"}},
+			Spec:       corev1.PodSpec{NodeName: "worker-1"},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionFalse, Message: "containers with unready status: [node-agent]"},
+			}},
+		},
+		&snapshotv1.VolumeSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: "velero-data-etcd-0-x7k2p", Namespace: "clusters-hc"},
+			Status:     &snapshotv1.VolumeSnapshotStatus{BoundVolumeSnapshotContentName: ptr.To("snapcontent-1")},
+		},
+		&snapshotv1.VolumeSnapshotContent{
+			ObjectMeta: metav1.ObjectMeta{Name: "snapcontent-1"},
+			Status:     &snapshotv1.VolumeSnapshotContentStatus{Error: &snapshotv1.VolumeSnapshotError{Message: ptr.To("snapshot quota exceeded")}},
+		},
+	).Build()
+
+	progress, err := Get(context.TODO(), c, "openshift-adp", testBackup(), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(progress.Unsuccessful()).To(HaveLen(1))
+	g.Expect(progress.Unsuccessful()[0].String()).To(Equal("PVC clusters-hc/data-etcd-0 (DataUpload daily-a) Failed: error to expose snapshot " +
+		"(node-agent pod node-agent-abcde on node worker-1 Ready=False: containers with unready status: [node-agent]; " +
+		"VolumeSnapshotContent snapcontent-1 failed: snapshot quota exceeded)"))
+}
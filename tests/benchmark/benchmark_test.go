@@ -0,0 +1,83 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/openshift/hypershift-oadp-plugin/pkg/diagnostics"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/volumebackup"
+	"github.com/openshift/hypershift-oadp-plugin/tests/benchmark/fakecluster"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// sizes are the numbers of VolumeSnapshots, DataUploads and DataDownloads of the simulated
+// clusters.
+var sizes = []int{100, 1000, 5000}
+
+// run benchmarks op against a simulated cluster of each size, reporting the API calls it
+// makes per operation next to its latency.
+func run(b *testing.B, op func(ctx context.Context, c crclient.Client, spec fakecluster.Spec) error) {
+	for _, size := range sizes {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			spec := fakecluster.New(size)
+			c, calls := fakecluster.NewClient(spec.Objects()...)
+			ctx := context.Background()
+
+			calls.Reset()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if err := op(ctx, c, spec); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(calls.Total())/float64(b.N), "apicalls/op")
+		})
+	}
+}
+
+// BenchmarkBackupProgress measures a poll of the volume backups of a backup, which the
+// backup command repeats until every DataUpload and PodVolumeBackup finished.
+func BenchmarkBackupProgress(b *testing.B) {
+	run(b, func(ctx context.Context, c crclient.Client, spec fakecluster.Spec) error {
+		expected, err := volumebackup.ExpectedPVCs(ctx, c, spec.HCPNamespace())
+		if err != nil {
+			return err
+		}
+		progress, err := volumebackup.Get(ctx, c, spec.VeleroNamespace, spec.Backup(), expected)
+		if err != nil {
+			return err
+		}
+		if want := spec.DataUploads + spec.PodVolumeBackups + spec.EtcdMembers; len(progress.Volumes) != want {
+			return fmt.Errorf("got %d volumes, want %d", len(progress.Volumes), want)
+		}
+		return nil
+	})
+}
+
+// BenchmarkRestoreProgress measures a poll of the volume restores of a restore.
+func BenchmarkRestoreProgress(b *testing.B) {
+	run(b, func(ctx context.Context, c crclient.Client, spec fakecluster.Spec) error {
+		progress, err := volumebackup.GetRestore(ctx, c, spec.VeleroNamespace, spec.Restore())
+		if err != nil {
+			return err
+		}
+		if len(progress.Volumes) != spec.DataDownloads {
+			return fmt.Errorf("got %d volumes, want %d", len(progress.Volumes), spec.DataDownloads)
+		}
+		return nil
+	})
+}
+
+// BenchmarkDiagnostics measures the collection of the diagnostics bundle of a failed backup,
+// which reads the status of every VolumeSnapshot and VolumeSnapshotContent.
+func BenchmarkDiagnostics(b *testing.B) {
+	cause := errors.New("benchmark")
+	run(b, func(ctx context.Context, c crclient.Client, spec fakecluster.Spec) error {
+		diagnostics.Collect(ctx, c, spec.Backup(), spec.VeleroNamespace, spec.HCPNamespace(), cause)
+		return nil
+	})
+}
@@ -0,0 +1,253 @@
+// Package fakecluster generates simulated management clusters for the plugin benchmarks: a
+// hosted cluster with the VolumeSnapshots, VolumeSnapshotContents and volume backups Velero
+// creates for its backups and restores, at the scale of large fleets, served by a fake client
+// that counts the API calls made against it.
+package fakecluster
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v8/apis/volumesnapshot/v1"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	velerov2alpha1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v2alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const (
+	// BackupName and RestoreName are the Backup and Restore the volume objects are created for
+	BackupName  = "bench-backup"
+	RestoreName = "bench-restore"
+
+	backupUID  = "bench-backup-uid"
+	restoreUID = "bench-restore-uid"
+)
+
+var created = time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+// Spec describes the simulated cluster.
+type Spec struct {
+	// Namespace and Name of the HostedCluster, its control plane runs in Namespace-Name
+	Namespace string
+	Name      string
+	// VeleroNamespace is where Velero creates the DataUploads, PodVolumeBackups and
+	// DataDownloads
+	VeleroNamespace string
+	// EtcdMembers is the replicas of the etcd StatefulSet
+	EtcdMembers int
+	// VolumeSnapshots of the control plane namespace, each bound to a VolumeSnapshotContent
+	VolumeSnapshots int
+	// DataUploads and PodVolumeBackups of the Backup, and DataDownloads of the Restore
+	DataUploads      int
+	PodVolumeBackups int
+	DataDownloads    int
+	// Unrelated is the number of DataUploads and DataDownloads of other backups and restores
+	// of the same names, left by deleted ones, which the plugin has to filter out
+	Unrelated int
+}
+
+// New returns the Spec of a cluster with size VolumeSnapshots, DataUploads and DataDownloads,
+// a tenth as many PodVolumeBackups and unrelated objects, and three etcd members.
+func New(size int) Spec {
+	return Spec{
+		Namespace:        "clusters",
+		Name:             "bench",
+		VeleroNamespace:  "openshift-adp",
+		EtcdMembers:      3,
+		VolumeSnapshots:  size,
+		DataUploads:      size,
+		PodVolumeBackups: size / 10,
+		DataDownloads:    size,
+		Unrelated:        size / 10,
+	}
+}
+
+// HCPNamespace is the namespace of the control plane.
+func (s Spec) HCPNamespace() string {
+	return common.GetHCPNamespace(s.Name, s.Namespace)
+}
+
+// Backup returns the Backup of the hosted cluster the volume backups belong to.
+func (s Spec) Backup() *velerov1.Backup {
+	return &velerov1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: BackupName, Namespace: s.VeleroNamespace, UID: backupUID},
+		Spec: velerov1.BackupSpec{
+			IncludedNamespaces: []string{s.Namespace, s.HCPNamespace()},
+		},
+	}
+}
+
+// Restore returns the Restore of the Backup the volume restores belong to.
+func (s Spec) Restore() *velerov1.Restore {
+	return &velerov1.Restore{
+		ObjectMeta: metav1.ObjectMeta{Name: RestoreName, Namespace: s.VeleroNamespace, UID: restoreUID},
+		Spec:       velerov1.RestoreSpec{BackupName: BackupName},
+	}
+}
+
+// HostedControlPlane returns the HostedControlPlane of the hosted cluster.
+func (s Spec) HostedControlPlane() *hyperv1.HostedControlPlane {
+	return &hyperv1.HostedControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: s.Name, Namespace: s.HCPNamespace()},
+		Spec:       hyperv1.HostedControlPlaneSpec{Platform: hyperv1.PlatformSpec{Type: hyperv1.AWSPlatform}},
+	}
+}
+
+// Objects generates the objects of the cluster, the Backup included.
+func (s Spec) Objects() []crclient.Object {
+	hcpNamespace := s.HCPNamespace()
+	objects := []crclient.Object{
+		&apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "hostedcontrolplanes.hypershift.openshift.io"}},
+		&hyperv1.HostedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: s.Name, Namespace: s.Namespace},
+			Spec:       hyperv1.HostedClusterSpec{Platform: hyperv1.PlatformSpec{Type: hyperv1.AWSPlatform}},
+		},
+		s.HostedControlPlane(),
+		s.Backup(),
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "etcd", Namespace: hcpNamespace},
+			Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To(int32(s.EtcdMembers))},
+		},
+	}
+
+	for i := range s.VolumeSnapshots {
+		content := fmt.Sprintf("snapcontent-%d", i)
+		objects = append(objects,
+			&snapshotv1.VolumeSnapshot{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("velero-pvc-%d", i), Namespace: hcpNamespace},
+				Status:     &snapshotv1.VolumeSnapshotStatus{BoundVolumeSnapshotContentName: ptr.To(content), ReadyToUse: ptr.To(true)},
+			},
+			&snapshotv1.VolumeSnapshotContent{
+				ObjectMeta: metav1.ObjectMeta{Name: content},
+				Spec: snapshotv1.VolumeSnapshotContentSpec{
+					VolumeSnapshotRef: corev1.ObjectReference{Namespace: hcpNamespace, Name: fmt.Sprintf("velero-pvc-%d", i)},
+					Driver:            "ebs.csi.aws.com",
+				},
+				Status: &snapshotv1.VolumeSnapshotContentStatus{ReadyToUse: ptr.To(true)},
+			},
+		)
+	}
+
+	for i := range s.DataUploads {
+		objects = append(objects, s.dataUpload(fmt.Sprintf("%s-du-%d", BackupName, i), fmt.Sprintf("pvc-%d", i), backupUID))
+	}
+	for i := range s.Unrelated {
+		objects = append(objects, s.dataUpload(fmt.Sprintf("%s-deleted-du-%d", BackupName, i), fmt.Sprintf("pvc-%d", i), "deleted-"+backupUID))
+	}
+
+	for i := range s.PodVolumeBackups {
+		pod := fmt.Sprintf("pod-%d", i)
+		objects = append(objects,
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: pod, Namespace: hcpNamespace},
+				Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+					Name:         "data",
+					VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "fs-" + pod}},
+				}}},
+			},
+			&velerov1.PodVolumeBackup{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: fmt.Sprintf("%s-pvb-%d", BackupName, i), Namespace: s.VeleroNamespace,
+					Labels: map[string]string{velerov1.BackupNameLabel: BackupName, velerov1.BackupUIDLabel: backupUID},
+				},
+				Spec:   velerov1.PodVolumeBackupSpec{Pod: corev1.ObjectReference{Namespace: hcpNamespace, Name: pod}, Volume: "data"},
+				Status: velerov1.PodVolumeBackupStatus{Phase: velerov1.PodVolumeBackupPhaseCompleted},
+			},
+		)
+	}
+
+	for i := range s.DataDownloads {
+		objects = append(objects, s.dataDownload(fmt.Sprintf("%s-dd-%d", RestoreName, i), fmt.Sprintf("pvc-%d", i), restoreUID))
+	}
+	for i := range s.Unrelated {
+		objects = append(objects, s.dataDownload(fmt.Sprintf("%s-deleted-dd-%d", RestoreName, i), fmt.Sprintf("pvc-%d", i), "deleted-"+restoreUID))
+	}
+	return objects
+}
+
+func (s Spec) dataUpload(name, pvc, uid string) *velerov2alpha1.DataUpload {
+	return &velerov2alpha1.DataUpload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: s.VeleroNamespace,
+			Labels:            map[string]string{velerov1.BackupNameLabel: BackupName, velerov1.BackupUIDLabel: uid},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec:   velerov2alpha1.DataUploadSpec{SourceNamespace: s.HCPNamespace(), SourcePVC: pvc},
+		Status: velerov2alpha1.DataUploadStatus{Phase: velerov2alpha1.DataUploadPhaseCompleted},
+	}
+}
+
+func (s Spec) dataDownload(name, pvc, uid string) *velerov2alpha1.DataDownload {
+	return &velerov2alpha1.DataDownload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: s.VeleroNamespace,
+			Labels:            map[string]string{velerov1.RestoreNameLabel: RestoreName, velerov1.RestoreUIDLabel: uid},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: velerov2alpha1.DataDownloadSpec{
+			TargetVolume: velerov2alpha1.TargetVolumeSpec{Namespace: s.HCPNamespace(), PVC: pvc},
+		},
+		Status: velerov2alpha1.DataDownloadStatus{Phase: velerov2alpha1.DataDownloadPhaseCompleted},
+	}
+}
+
+// Calls counts the API calls made through a client, by verb.
+type Calls struct {
+	Get, List, Create, Update, Patch, Delete atomic.Int64
+}
+
+// Total is the number of API calls counted.
+func (c *Calls) Total() int64 {
+	return c.Get.Load() + c.List.Load() + c.Create.Load() + c.Update.Load() + c.Patch.Load() + c.Delete.Load()
+}
+
+// Reset starts counting over.
+func (c *Calls) Reset() {
+	for _, counter := range []*atomic.Int64{&c.Get, &c.List, &c.Create, &c.Update, &c.Patch, &c.Delete} {
+		counter.Store(0)
+	}
+}
+
+// NewClient returns a fake client serving the objects, and the API calls made through it.
+func NewClient(objects ...crclient.Object) (crclient.Client, *Calls) {
+	calls := &Calls{}
+	c := fake.NewClientBuilder().WithScheme(common.CustomScheme).WithObjects(objects...).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c crclient.WithWatch, key types.NamespacedName, obj crclient.Object, opts ...crclient.GetOption) error {
+			calls.Get.Add(1)
+			return c.Get(ctx, key, obj, opts...)
+		},
+		List: func(ctx context.Context, c crclient.WithWatch, list crclient.ObjectList, opts ...crclient.ListOption) error {
+			calls.List.Add(1)
+			return c.List(ctx, list, opts...)
+		},
+		Create: func(ctx context.Context, c crclient.WithWatch, obj crclient.Object, opts ...crclient.CreateOption) error {
+			calls.Create.Add(1)
+			return c.Create(ctx, obj, opts...)
+		},
+		Update: func(ctx context.Context, c crclient.WithWatch, obj crclient.Object, opts ...crclient.UpdateOption) error {
+			calls.Update.Add(1)
+			return c.Update(ctx, obj, opts...)
+		},
+		Patch: func(ctx context.Context, c crclient.WithWatch, obj crclient.Object, patch crclient.Patch, opts ...crclient.PatchOption) error {
+			calls.Patch.Add(1)
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+		Delete: func(ctx context.Context, c crclient.WithWatch, obj crclient.Object, opts ...crclient.DeleteOption) error {
+			calls.Delete.Add(1)
+			return c.Delete(ctx, obj, opts...)
+		},
+	}).Build()
+	return c, calls
+}
@@ -0,0 +1,26 @@
+package fakecluster
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	velerov2alpha1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v2alpha1"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestNewClient(t *testing.T) {
+	g := NewWithT(t)
+	spec := New(100)
+	c, calls := NewClient(spec.Objects()...)
+
+	uploads := &velerov2alpha1.DataUploadList{}
+	g.Expect(c.List(context.TODO(), uploads, crclient.InNamespace(spec.VeleroNamespace))).To(Succeed())
+	g.Expect(uploads.Items).To(HaveLen(spec.DataUploads + spec.Unrelated))
+	g.Expect(c.Get(context.TODO(), crclient.ObjectKeyFromObject(spec.Backup()), spec.Backup())).To(Succeed())
+	g.Expect(calls.List.Load()).To(Equal(int64(1)))
+	g.Expect(calls.Total()).To(Equal(int64(2)))
+
+	calls.Reset()
+	g.Expect(calls.Total()).To(BeZero())
+}
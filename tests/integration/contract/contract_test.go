@@ -0,0 +1,51 @@
+package contract
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/core"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/plugin/velero"
+	biav1 "github.com/vmware-tanzu/velero/pkg/plugin/velero/backupitemaction/v1"
+	riav1 "github.com/vmware-tanzu/velero/pkg/plugin/velero/restoreitemaction/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// The plugin framework serves the plugins as these interfaces. A Velero release changing
+// them fails to build this package, see the contract build tag.
+var (
+	_ biav1.BackupItemAction  = (*core.BackupPlugin)(nil)
+	_ riav1.RestoreItemAction = (*core.RestorePlugin)(nil)
+)
+
+// TestPluginFrameworkTypes uses the plugin framework types the way the plugins do, so a
+// field renamed or retyped by a Velero release is caught here rather than at runtime.
+func TestPluginFrameworkTypes(t *testing.T) {
+	t.Run("When the backup plugin is served, It Should apply to every resource", func(t *testing.T) {
+		g := NewWithT(t)
+		var action biav1.BackupItemAction = &core.BackupPlugin{}
+		selector, err := action.AppliesTo()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(selector).To(Equal(velero.ResourceSelector{}))
+	})
+
+	t.Run("When a restore item is executed, It Should carry the items and the Restore", func(t *testing.T) {
+		g := NewWithT(t)
+		item := &unstructured.Unstructured{Object: map[string]any{"apiVersion": "v1", "kind": "ConfigMap"}}
+		var updated runtime.Unstructured = item
+		input := &velero.RestoreItemActionExecuteInput{
+			Item:           item,
+			ItemFromBackup: item.DeepCopy(),
+			Restore:        &velerov1.Restore{Spec: velerov1.RestoreSpec{BackupName: "daily"}},
+		}
+		output := velero.NewRestoreItemActionExecuteOutput(updated).WithoutRestore()
+		output.AdditionalItems = []velero.ResourceIdentifier{{Namespace: "clusters", Name: "hc"}}
+
+		g.Expect(input.Restore.Spec.BackupName).To(Equal("daily"))
+		g.Expect(output.UpdatedItem).To(Equal(updated))
+		g.Expect(output.SkipRestore).To(BeTrue())
+		g.Expect(output.AdditionalItems).To(HaveLen(1))
+	})
+}
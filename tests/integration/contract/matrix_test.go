@@ -0,0 +1,136 @@
+//go:build contract
+
+package contract
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// oadpRelease is an OADP release the plugin is shipped with, and the branch of the
+// OpenShift fork of Velero it builds.
+type oadpRelease struct {
+	Name   string
+	Velero string
+}
+
+// supportedReleases are the OADP releases the plugin is checked against. CONTRACT_OADP_RELEASES
+// narrows them down, e.g. CONTRACT_OADP_RELEASES=1.5.
+var supportedReleases = []oadpRelease{
+	{Name: "1.3", Velero: "oadp-1.3"},
+	{Name: "1.4", Velero: "oadp-1.4"},
+	{Name: "1.5", Velero: "oadp-1.5"},
+}
+
+const (
+	veleroModule     = "github.com/vmware-tanzu/velero"
+	veleroAPIsModule = "github.com/vmware-tanzu/velero/pkg/apis"
+	veleroFork       = "github.com/openshift/velero"
+)
+
+// TestPluginFrameworkContract builds the plugin against the plugin framework of each
+// supported OADP release and runs the contract and core tests with it, so an interface or
+// API type the release changed fails here rather than in the Velero pod.
+func TestPluginFrameworkContract(t *testing.T) {
+	rootDir, err := findProjectRoot()
+	NewWithT(t).Expect(err).NotTo(HaveOccurred(), "Should be able to find project root")
+
+	for _, release := range selectedReleases() {
+		t.Run("OADP "+release.Name, func(t *testing.T) {
+			g := NewWithT(t)
+			dir := t.TempDir()
+			g.Expect(copyModule(rootDir, dir)).To(Succeed())
+
+			// The release ships its API types in the Velero module itself, or as their own module
+			// next to it: tidy resolves them from the release either way
+			run(t, dir, "go", "mod", "edit", "-droprequire", veleroAPIsModule, "-dropreplace", veleroAPIsModule,
+				"-replace", veleroModule+"="+veleroFork+"@"+release.Velero)
+			run(t, dir, "go", "mod", "tidy")
+			run(t, dir, "go", "build", "./...")
+			run(t, dir, "go", "vet", "./tests/integration/contract/", "./pkg/core/...")
+			run(t, dir, "go", "test", "-count=1", "./tests/integration/contract/", "./pkg/core/...")
+		})
+	}
+}
+
+// selectedReleases returns the supported releases named in CONTRACT_OADP_RELEASES, all of
+// them when it is unset.
+func selectedReleases() []oadpRelease {
+	names := os.Getenv("CONTRACT_OADP_RELEASES")
+	if names == "" {
+		return supportedReleases
+	}
+	var selected []oadpRelease
+	for _, release := range supportedReleases {
+		for _, name := range strings.Split(names, ",") {
+			if strings.TrimSpace(name) == release.Name {
+				selected = append(selected, release)
+			}
+		}
+	}
+	return selected
+}
+
+// run runs the command in the module copy, with the module cache rather than the vendor
+// directory, and fails the test with its output.
+func run(t *testing.T, dir, name string, args ...string) {
+	t.Helper()
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s %s: %v\n%s", name, strings.Join(args, " "), err, output)
+	}
+}
+
+// copyModule copies the sources of the module to dir, leaving out the vendor directory,
+// which pins the current Velero release, and the build outputs.
+func copyModule(rootDir, dir string) error {
+	return filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			switch rel {
+			case ".git", "vendor", "bin", "dist":
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(dir, rel), 0o755)
+		}
+		if !d.Type().IsRegular() || (filepath.Dir(rel) == "." && filepath.Ext(rel) == "") {
+			return nil // the plugin binary and the root files the build does not read
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, rel), data, 0o644)
+	})
+}
+
+func findProjectRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", os.ErrNotExist
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,431 @@
+//go:build envtest
+
+// Package pause_integration tests the pause and resume of a HostedCluster against a real API
+// server started by envtest, with the HyperShift CRDs of the API version the plugin builds
+// against. It covers what the fake client of the unit tests does not: the CRD validation of
+// spec.pausedUntil, merge patches racing with other writers, and the propagation of the
+// pause by the HyperShift operator, played here by the test.
+//
+// Requirements:
+//   - The envtest binaries (kube-apiserver, etcd) in KUBEBUILDER_ASSETS, or network access
+//     to download them
+//   - The github.com/openshift/hypershift/api module in the module cache for its CRDs, or
+//     the directory of their manifests in HYPERSHIFT_CRDS
+//
+// The package is a module of its own, so envtest stays out of the plugin module.
+//
+// Run: make test-envtest
+package pause_integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	common "github.com/openshift/hypershift-oadp-plugin/pkg/common"
+	"github.com/openshift/hypershift-oadp-plugin/pkg/common/clocktest"
+	hyperv1 "github.com/openshift/hypershift/api/hypershift/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/yaml"
+)
+
+// hypershiftCRDs are the CRDs the pause flows act on, from the generated manifests of the
+// HyperShift API module, without feature gated fields.
+var hypershiftCRDs = []string{
+	"hostedclusters.hypershift.openshift.io",
+	"hostedcontrolplanes.hypershift.openshift.io",
+	"nodepools.hypershift.openshift.io",
+}
+
+var (
+	cfg       *rest.Config
+	k8sClient crclient.Client
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	paths, err := crdPaths()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	env := &envtest.Environment{
+		CRDDirectoryPaths:     append(paths, filepath.Join("testdata", "capi-crds.yaml")),
+		ErrorIfCRDPathMissing: true,
+		DownloadBinaryAssets:  os.Getenv("KUBEBUILDER_ASSETS") == "",
+	}
+	if cfg, err = env.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "error starting envtest: %v\n", err)
+		return 1
+	}
+	defer func() {
+		if err := env.Stop(); err != nil {
+			fmt.Fprintf(os.Stderr, "error stopping envtest: %v\n", err)
+		}
+	}()
+	if k8sClient, err = crclient.New(cfg, crclient.Options{Scheme: common.CustomScheme}); err != nil {
+		fmt.Fprintf(os.Stderr, "error creating the client: %v\n", err)
+		return 1
+	}
+	return m.Run()
+}
+
+// crdPaths returns the manifests of hypershiftCRDs, from HYPERSHIFT_CRDS or the HyperShift API
+// module in the module cache.
+func crdPaths() ([]string, error) {
+	dir := os.Getenv("HYPERSHIFT_CRDS")
+	if dir == "" {
+		output, err := exec.Command("go", "list", "-mod=mod", "-m", "-f", "{{.Dir}}", "github.com/openshift/hypershift/api").Output()
+		if err != nil {
+			return nil, fmt.Errorf("error locating the github.com/openshift/hypershift/api module: %w", err)
+		}
+		dir = filepath.Join(strings.TrimSpace(string(output)), "hypershift", "v1beta1", "zz_generated.featuregated-crd-manifests")
+	}
+	var paths []string
+	for _, crd := range hypershiftCRDs {
+		paths = append(paths, filepath.Join(dir, crd, "AAA_ungated.yaml"))
+	}
+	return paths, nil
+}
+
+// hostedCluster is a HostedCluster passing the validation of its CRD.
+const hostedCluster = `
+apiVersion: hypershift.openshift.io/v1beta1
+kind: HostedCluster
+metadata:
+  name: hc
+  namespace: %s
+spec:
+  release:
+    image: quay.io/openshift-release-dev/ocp-release:4.18.0-x86_64
+  pullSecret:
+    name: pull-secret
+  platform:
+    type: None
+  etcd:
+    managementType: Managed
+    managed:
+      storage:
+        type: PersistentVolume
+  networking:
+    networkType: OVNKubernetes
+    clusterNetwork:
+    - cidr: 10.132.0.0/14
+    serviceNetwork:
+    - cidr: 172.31.0.0/16
+  services:
+  - service: APIServer
+    servicePublishingStrategy:
+      type: LoadBalancer
+  - service: OAuthServer
+    servicePublishingStrategy:
+      type: Route
+  - service: Konnectivity
+    servicePublishingStrategy:
+      type: Route
+  - service: Ignition
+    servicePublishingStrategy:
+      type: Route
+`
+
+// hostedControlPlane is the HostedControlPlane of hostedCluster, in its control plane namespace.
+const hostedControlPlane = `
+apiVersion: hypershift.openshift.io/v1beta1
+kind: HostedControlPlane
+metadata:
+  name: hc
+  namespace: %s
+spec:
+  releaseImage: quay.io/openshift-release-dev/ocp-release:4.18.0-x86_64
+  pullSecret:
+    name: pull-secret
+  sshKey:
+    name: ssh-key
+  infraID: hc-x7k2p
+  issuerURL: https://kubernetes.default.svc
+  dns:
+    baseDomain: example.com
+  platform:
+    type: None
+  etcd:
+    managementType: Managed
+    managed:
+      storage:
+        type: PersistentVolume
+  services:
+  - service: APIServer
+    servicePublishingStrategy:
+      type: LoadBalancer
+  - service: OAuthServer
+    servicePublishingStrategy:
+      type: Route
+  - service: Konnectivity
+    servicePublishingStrategy:
+      type: Route
+  - service: Ignition
+    servicePublishingStrategy:
+      type: Route
+`
+
+// nodePool is a NodePool of hostedCluster.
+const nodePool = `
+apiVersion: hypershift.openshift.io/v1beta1
+kind: NodePool
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  clusterName: hc
+  release:
+    image: quay.io/openshift-release-dev/ocp-release:4.18.0-x86_64
+  management:
+    upgradeType: Replace
+  platform:
+    type: None
+  replicas: 2
+`
+
+// create creates the object of the manifest, formatted with the args.
+func create(t *testing.T, manifest string, args ...any) *unstructured.Unstructured {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(fmt.Sprintf(manifest, args...)), &obj.Object); err != nil {
+		t.Fatal(err)
+	}
+	if err := k8sClient.Create(context.TODO(), obj); err != nil {
+		t.Fatalf("error creating %s %s: %v", obj.GetKind(), obj.GetName(), err)
+	}
+	return obj
+}
+
+// newCAPIObject creates a cluster-api object in the namespace, owned by the NodePool when not
+// empty.
+func newCAPIObject(t *testing.T, kind, name, namespace, nodePool string) *unstructured.Unstructured {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("cluster.x-k8s.io/v1beta1")
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	if nodePool != "" {
+		obj.SetAnnotations(map[string]string{common.NodePoolAnnotation: nodePool})
+	}
+	if err := k8sClient.Create(context.TODO(), obj); err != nil {
+		t.Fatalf("error creating %s %s: %v", kind, name, err)
+	}
+	return obj
+}
+
+// newNamespaces creates the namespace of a hosted cluster named hc and its control plane
+// namespace, returning both.
+func newNamespaces(t *testing.T, namespace string) (string, string) {
+	t.Helper()
+	hcpNamespace := common.GetHCPNamespace("hc", namespace)
+	for _, name := range []string{namespace, hcpNamespace} {
+		if err := k8sClient.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return namespace, hcpNamespace
+}
+
+// propagate plays the HyperShift operator propagating the pause, or resume, of the hosted
+// cluster to its HostedControlPlane, cluster-api Cluster and the MachineDeployment of the
+// workers NodePool, once the delay elapsed.
+func propagate(t *testing.T, hcpNamespace string, paused bool, delay time.Duration) {
+	pausedUntil, clusterPaused, mdAnnotations := `null`, `false`, fmt.Sprintf(`{%q:null}`, common.CAPIPausedAnnotation)
+	if paused {
+		pausedUntil, clusterPaused, mdAnnotations = `"true"`, `true`, fmt.Sprintf(`{%q:"true"}`, common.CAPIPausedAnnotation)
+	}
+	patches := []struct {
+		obj   crclient.Object
+		patch string
+	}{
+		{&hyperv1.HostedControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "hc", Namespace: hcpNamespace}}, `{"spec":{"pausedUntil":` + pausedUntil + `}}`},
+		{capiRef("Cluster", "hc", hcpNamespace), `{"spec":{"paused":` + clusterPaused + `}}`},
+		{capiRef("MachineDeployment", "workers", hcpNamespace), `{"metadata":{"annotations":` + mdAnnotations + `}}`},
+	}
+	done := make(chan struct{})
+	t.Cleanup(func() { <-done })
+	go func() {
+		defer close(done)
+		time.Sleep(delay)
+		for _, p := range patches {
+			if err := k8sClient.Patch(context.TODO(), p.obj, crclient.RawPatch(types.MergePatchType, []byte(p.patch))); err != nil {
+				t.Errorf("error propagating to %s: %v", p.obj.GetName(), err)
+			}
+		}
+	}()
+}
+
+func capiRef(kind, name, namespace string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("cluster.x-k8s.io/v1beta1")
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	return obj
+}
+
+func TestPauseAndResume(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	namespace, hcpNamespace := newNamespaces(t, "pause-resume")
+	create(t, hostedCluster, namespace)
+	create(t, nodePool, "workers", namespace)
+	infra := create(t, nodePool, "infra", namespace)
+	g.Expect(unstructured.SetNestedField(infra.Object, "2030-01-01T00:00:00Z", "spec", "pausedUntil")).To(Succeed())
+	g.Expect(k8sClient.Update(ctx, infra)).To(Succeed())
+	newCAPIObject(t, "MachineHealthCheck", "workers", hcpNamespace, namespace+"/workers")
+
+	get := func(obj crclient.Object, name string) {
+		g.Expect(k8sClient.Get(ctx, crclient.ObjectKey{Namespace: namespace, Name: name}, obj)).To(Succeed())
+	}
+
+	t.Run("When the hosted cluster is paused, It Should be accepted by the CRDs and leave the NodePool paused by someone else", func(t *testing.T) {
+		g := NewWithT(t)
+		pausedElsewhere, err := common.PauseHostedCluster(ctx, k8sClient, namespace, "hc", "daily")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(pausedElsewhere).To(ConsistOf("NodePool " + namespace + "/infra (pausedUntil 2030-01-01T00:00:00Z)"))
+
+		hc := &hyperv1.HostedCluster{}
+		get(hc, "hc")
+		g.Expect(hc.Spec.PausedUntil).To(HaveValue(Equal("true")))
+		g.Expect(hc.Annotations).To(HaveKeyWithValue(common.PausedForBackupAnnotation, "daily"))
+		g.Expect(hc.Annotations).To(HaveKeyWithValue(common.BackupInProgressAnnotation, "daily"))
+		workers := &hyperv1.NodePool{}
+		get(workers, "workers")
+		g.Expect(workers.Spec.PausedUntil).To(HaveValue(Equal("true")))
+
+		mhc := capiRef("MachineHealthCheck", "workers", hcpNamespace)
+		g.Expect(k8sClient.Get(ctx, crclient.ObjectKeyFromObject(mhc), mhc)).To(Succeed())
+		g.Expect(mhc.GetAnnotations()).To(HaveKey(common.CAPIPausedAnnotation))
+	})
+
+	t.Run("When the pause is retried, It Should keep the first pause", func(t *testing.T) {
+		g := NewWithT(t)
+		hc := &hyperv1.HostedCluster{}
+		get(hc, "hc")
+		pausedAt := hc.Annotations[common.BackupPausedAtAnnotation]
+
+		pausedElsewhere, err := common.PauseHostedCluster(ctx, k8sClient, namespace, "hc", "daily")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(pausedElsewhere).To(ConsistOf("NodePool " + namespace + "/infra (pausedUntil 2030-01-01T00:00:00Z)"))
+		get(hc, "hc")
+		g.Expect(hc.Annotations).To(HaveKeyWithValue(common.BackupPausedAtAnnotation, pausedAt))
+	})
+
+	t.Run("When the hosted cluster is resumed, It Should only resume what the backup paused", func(t *testing.T) {
+		g := NewWithT(t)
+		pausedElsewhere, err := common.UnpauseHostedCluster(ctx, k8sClient, namespace, "hc")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(pausedElsewhere).To(BeEmpty())
+
+		hc := &hyperv1.HostedCluster{}
+		get(hc, "hc")
+		g.Expect(hc.Spec.PausedUntil).To(BeNil())
+		g.Expect(hc.Annotations).NotTo(HaveKey(common.PausedForBackupAnnotation))
+		g.Expect(hc.Annotations).NotTo(HaveKey(common.BackupInProgressAnnotation))
+		workers, infra := &hyperv1.NodePool{}, &hyperv1.NodePool{}
+		get(workers, "workers")
+		get(infra, "infra")
+		g.Expect(workers.Spec.PausedUntil).To(BeNil())
+		g.Expect(infra.Spec.PausedUntil).To(HaveValue(Equal("2030-01-01T00:00:00Z")))
+	})
+}
+
+func TestPauseWithConcurrentWriters(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	namespace, _ := newNamespaces(t, "pause-concurrent")
+	create(t, hostedCluster, namespace)
+	create(t, nodePool, "workers", namespace)
+
+	// Another controller changes the HostedCluster and NodePool between the reads of the
+	// plugin and its patches, moving their resourceVersion
+	withWriter, err := crclient.NewWithWatch(cfg, crclient.Options{Scheme: common.CustomScheme})
+	g.Expect(err).NotTo(HaveOccurred())
+	written := map[string]bool{}
+	c := interceptor.NewClient(withWriter, interceptor.Funcs{
+		Patch: func(ctx context.Context, c crclient.WithWatch, obj crclient.Object, patch crclient.Patch, opts ...crclient.PatchOption) error {
+			key := fmt.Sprintf("%T/%s", obj, obj.GetName())
+			if !written[key] {
+				written[key] = true
+				label := []byte(`{"metadata":{"labels":{"concurrent-writer":"true"}}}`)
+				if err := c.Patch(ctx, obj.DeepCopyObject().(crclient.Object), crclient.RawPatch(types.MergePatchType, label)); err != nil {
+					return err
+				}
+			}
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	})
+
+	t.Run("When other writers change the objects meanwhile, It Should pause them without conflicts or lost changes", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := common.PauseHostedCluster(ctx, c, namespace, "hc", "daily")
+		g.Expect(err).NotTo(HaveOccurred())
+
+		hc := &hyperv1.HostedCluster{}
+		g.Expect(k8sClient.Get(ctx, crclient.ObjectKey{Namespace: namespace, Name: "hc"}, hc)).To(Succeed())
+		g.Expect(hc.Spec.PausedUntil).To(HaveValue(Equal("true")))
+		g.Expect(hc.Labels).To(HaveKeyWithValue("concurrent-writer", "true"))
+		np := &hyperv1.NodePool{}
+		g.Expect(k8sClient.Get(ctx, crclient.ObjectKey{Namespace: namespace, Name: "workers"}, np)).To(Succeed())
+		g.Expect(np.Spec.PausedUntil).To(HaveValue(Equal("true")))
+		g.Expect(np.Labels).To(HaveKeyWithValue("concurrent-writer", "true"))
+	})
+}
+
+func TestPausePropagation(t *testing.T) {
+	ctx := context.TODO()
+	namespace, hcpNamespace := newNamespaces(t, "pause-propagation")
+	create(t, hostedCluster, namespace)
+	create(t, hostedControlPlane, hcpNamespace)
+	create(t, nodePool, "workers", namespace)
+	newCAPIObject(t, "Cluster", "hc", hcpNamespace, "")
+	newCAPIObject(t, "MachineDeployment", "workers", hcpNamespace, namespace+"/workers")
+	timeouts := common.Timeouts{PausePropagation: 30 * time.Second, PausePropagationPoll: 100 * time.Millisecond}
+
+	t.Run("When the operator has not propagated the pause, It Should time out naming the HostedControlPlane", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := common.PauseHostedCluster(ctx, k8sClient, namespace, "hc", "daily")
+		g.Expect(err).NotTo(HaveOccurred())
+
+		err = common.WaitForPausedPropagated(ctx, k8sClient, namespace, "hc", common.Timeouts{Clock: clocktest.New(t, time.Second)})
+		g.Expect(err).To(MatchError(ContainSubstring("the pause of HostedControlPlane " + hcpNamespace + "/hc")))
+	})
+
+	t.Run("When the operator propagates the pause, It Should return once every object is paused", func(t *testing.T) {
+		g := NewWithT(t)
+		propagate(t, hcpNamespace, true, time.Second)
+		g.Expect(common.WaitForPausedPropagated(ctx, k8sClient, namespace, "hc", timeouts)).To(Succeed())
+
+		md := capiRef("MachineDeployment", "workers", hcpNamespace)
+		g.Expect(k8sClient.Get(ctx, crclient.ObjectKeyFromObject(md), md)).To(Succeed())
+		g.Expect(md.GetAnnotations()).To(HaveKey(common.CAPIPausedAnnotation))
+	})
+
+	t.Run("When the operator propagates the resume, It Should return once every object is resumed", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := common.UnpauseHostedCluster(ctx, k8sClient, namespace, "hc")
+		g.Expect(err).NotTo(HaveOccurred())
+		propagate(t, hcpNamespace, false, time.Second)
+		g.Expect(common.WaitForUnpausedPropagated(ctx, k8sClient, namespace, "hc", timeouts)).To(Succeed())
+	})
+}